@@ -17,15 +17,18 @@ import (
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"github.com/unitechio/einfra-be/internal/auth"
+	"github.com/unitechio/einfra-be/internal/cache"
 	"github.com/unitechio/einfra-be/internal/config"
 	"github.com/unitechio/einfra-be/internal/http/handler"
 	"github.com/unitechio/einfra-be/internal/http/router"
 	"github.com/unitechio/einfra-be/internal/infrastructure/database"
 	storage "github.com/unitechio/einfra-be/internal/infrastructure/filestorage"
 	"github.com/unitechio/einfra-be/internal/logger"
+	"github.com/unitechio/einfra-be/internal/messaging"
 	"github.com/unitechio/einfra-be/internal/repository"
 	"github.com/unitechio/einfra-be/internal/usecase"
 	"github.com/unitechio/einfra-be/pkg/docker"
+	"github.com/unitechio/einfra-be/pkg/k8s"
 	"github.com/unitechio/einfra-be/pkg/security"
 	"github.com/unitechio/einfra-be/pkg/ssh"
 )
@@ -44,7 +47,7 @@ func main() {
 	}
 
 	// --- Logger ---
-	_ = logger.NewZapLogger(logger.LoggerConfig{
+	appLogger := logger.NewZapLogger(logger.LoggerConfig{
 		Level:      logger.LogLevel(cfg.Logging.Level),
 		OutputPath: cfg.Logging.FilePath,
 		DevMode:    cfg.Server.Mode == "debug",
@@ -82,6 +85,27 @@ func main() {
 	}
 	credentialAuditor := security.NewSimpleAuditor()
 
+	// In-memory cache for short-lived, expensive reads
+	appCache, err := cache.NewLRUCache(1024)
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize cache: %v", err)
+	}
+
+	// Message broker, disabled when no Kafka brokers are configured
+	var publisher messaging.Publisher
+	if len(cfg.Kafka.Brokers) > 0 {
+		kafkaAdapter := messaging.NewKafkaAdapter(messaging.KafkaConfig{
+			Brokers:      cfg.Kafka.Brokers,
+			GroupID:      cfg.Kafka.GroupID,
+			DefaultTopic: cfg.Kafka.HarborEventsTopic,
+			DLQTopic:     cfg.Kafka.DLQTopic,
+			MaxRetries:   cfg.Kafka.MaxRetries,
+			RetryBackoff: cfg.Kafka.RetryBackoff,
+		}, appLogger)
+		defer kafkaAdapter.Close()
+		publisher = kafkaAdapter
+	}
+
 	// Repositories
 	userRepo := repository.NewUserRepository(db)
 	roleRepo := repository.NewRoleRepository(db)
@@ -100,10 +124,15 @@ func main() {
 	// Infrastructure Repositories
 	serverRepo := repository.NewServerRepository(db, encryptionService, credentialAuditor)
 	dockerRepo := repository.NewDockerHostRepository(db)
-	dockerStackRepo := repository.NewDockerStackRepository(db)
+	dockerStackRepo := repository.NewDockerStackRepository(db, encryptionService)
+	stackTemplateRepo := repository.NewStackTemplateRepository(db)
+	containerMetricRepo := repository.NewContainerMetricRepository(db)
+	alertRepo := repository.NewAlertRepository(db)
+	alertReceiverRepo := repository.NewAlertReceiverRepository(db, encryptionService)
 	k8sRepo := repository.NewK8sClusterRepository(db)
-	harborRepo := repository.NewHarborRegistryRepository(db)
-	k8sBackupRepo := repository.NewK8sBackupRepository(db)
+	harborRepo := repository.NewHarborRegistryRepository(db, encryptionService)
+	k8sBackupRepo := repository.NewK8sBackupRepository(db, encryptionService)
+	k8sBackupScheduleRepo := repository.NewK8sBackupScheduleRepository(db)
 	imageDeploymentRepo := repository.NewImageDeploymentRepository(db)
 	emailRepo := repository.NewEmailRepository(db)
 	notificationRepo := repository.NewNotificationRepository(db)
@@ -111,6 +140,9 @@ func main() {
 	notificationPrefRepo := repository.NewNotificationPreferenceRepository(db)
 	licenseRepo := repository.NewLicenseRepository(db)
 	imageRepo := repository.NewImageRepository(db)
+	vulnerabilityPolicyRepo := repository.NewVulnerabilityPolicyRepository(db)
+	harborPromotionRepo := repository.NewHarborPromotionRepository(db)
+	harborRetentionRepo := repository.NewHarborRetentionRepository(db)
 
 	// Server Feature Repositories
 	serverBackupRepo := repository.NewServerBackupRepository(db)
@@ -149,48 +181,71 @@ func main() {
 	// Tunnel Manager
 	tunnelManager := ssh.NewTunnelManager()
 
+	// Kubernetes client cache
+	k8sClients := k8s.NewClientManager()
+
+	// Docker client cache, one client per registered host; requests without a host use the local daemon
+	dockerClients := docker.NewClientManager()
+	defer dockerClients.CloseAll()
+	dockerClientRegistry := usecase.NewDockerClientRegistry(dockerRepo, serverRepo, dockerClients, "unix:///var/run/docker.sock")
+
 	// Infrastructure Usecases
 	serverUsecase := usecase.NewServerUsecase(serverRepo, tunnelManager)
-	dockerUsecase := usecase.NewDockerUsecase(dockerRepo)
-	kubernetesUsecase := usecase.NewKubernetesUsecase(k8sRepo)
+	dockerUsecase := usecase.NewDockerUsecase(dockerRepo, dockerClientRegistry)
 	harborUsecase := usecase.NewHarborUsecase(harborRepo)
-	k8sBackupUsecase := usecase.NewK8sBackupUsecase(k8sBackupRepo, k8sRepo)
-	imageDeploymentUsecase := usecase.NewImageDeploymentUsecase(imageDeploymentRepo, kubernetesUsecase)
-
-	// Docker Client
-	dockerClient, err := docker.NewClient("unix:///var/run/docker.sock")
-	if err != nil {
-		log.Printf("⚠️  Warning: Failed to create Docker client: %v", err)
-		// Continue without Docker support
-	}
-	if dockerClient != nil {
-		defer dockerClient.Close()
-	}
+	harborPromotionUsecase := usecase.NewHarborPromotionUsecase(harborPromotionRepo, harborRepo, harborUsecase, userRepo, roleRepo, auditUsecase)
+	harborRetentionUsecase := usecase.NewHarborRetentionUsecase(harborRetentionRepo, harborRepo, harborUsecase, imageDeploymentRepo, auditUsecase)
+	harborWebhookUsecase := usecase.NewHarborWebhookUsecase(harborRepo, userRepo, notificationUsecase, auditUsecase, publisher, cfg.Kafka.HarborEventsTopic)
+	vulnerabilityGateUsecase := usecase.NewVulnerabilityGateUsecase(vulnerabilityPolicyRepo, environmentRepo, k8sRepo, harborUsecase, authorizationUsecase)
+	kubernetesUsecase := usecase.NewKubernetesUsecase(k8sRepo, k8sClients, vulnerabilityGateUsecase)
+	k8sWatchUsecase := usecase.NewK8sWatchUsecase(k8sRepo, k8sClients, authorizationUsecase)
+	k8sBackupUsecase := usecase.NewK8sBackupUsecase(k8sBackupRepo, k8sBackupScheduleRepo, k8sRepo, k8sClients)
+	imageDeploymentUsecase := usecase.NewImageDeploymentUsecase(imageDeploymentRepo, kubernetesUsecase, vulnerabilityGateUsecase)
+	k8sRolloutUsecase := usecase.NewK8sRolloutUsecase(k8sRepo, k8sClients, imageDeploymentUsecase)
+	imageDeploymentTracker := usecase.NewImageDeploymentTracker(k8sRepo, k8sClients, imageDeploymentUsecase, harborUsecase, dockerClientRegistry)
+	k8sRBACUsecase := usecase.NewK8sRBACUsecase(authorizationRepo, k8sRepo, k8sClients)
+	k8sCapacityUsecase := usecase.NewK8sCapacityUsecase(k8sRepo, k8sClients, appCache)
+	k8sHelmUsecase := usecase.NewK8sHelmUsecase(k8sRepo, k8sClients, storage, auditUsecase)
+
+	// Start scheduled K8s backups
+	k8sBackupUsecase.StartScheduler(context.Background())
+	harborRetentionUsecase.StartScheduler(context.Background())
+
+	// Record image deployments from workload changes in every cluster and container changes on every Docker host
+	go imageDeploymentTracker.Start(context.Background())
+
+	// Keep Kubernetes RBAC in sync with platform grants
+	go k8sRBACUsecase.StartSync(context.Background())
+
+	// Keep the status and version of Docker hosts current
+	go dockerClientRegistry.StartHealthCheck(context.Background())
+
+	imageSearchUsecase := usecase.NewImageSearchUsecase(imageDeploymentRepo, k8sRepo, k8sClients, dockerRepo, dockerClientRegistry, environmentRepo, harborUsecase)
 
 	// Docker Exec & Stats Usecases
-	var dockerExecUsecase usecase.DockerExecUsecase
-	var dockerStatsUsecase usecase.DockerStatsUsecase
-	var dockerNetworkUsecase usecase.DockerNetworkUsecase
-	var dockerImageUsecase usecase.DockerImageUsecase
-	var logUsecase usecase.LogUsecase
-	var eventUsecase usecase.EventUsecase
-	var alertUsecase usecase.AlertUsecase
-	if dockerClient != nil {
-		dockerExecUsecase = usecase.NewDockerExecUsecase(dockerClient)
-		dockerStatsUsecase = usecase.NewDockerStatsUsecase(dockerClient)
-		dockerNetworkUsecase = usecase.NewDockerNetworkUsecase(dockerClient)
-		dockerImageUsecase = usecase.NewDockerImageUsecase(dockerClient)
-		logUsecase = usecase.NewLogUsecase(dockerClient)
-		eventUsecase = usecase.NewEventUsecase(dockerClient)
-		alertUsecase = usecase.NewAlertUsecase(dockerClient, notificationUsecase)
-
-		// Start Alert Monitoring
-		go alertUsecase.StartMonitoring(context.Background())
-	}
+	dockerExecUsecase := usecase.NewDockerExecUsecase(dockerClientRegistry)
+	dockerStatsUsecase := usecase.NewDockerStatsUsecase(dockerClientRegistry)
+	dockerNetworkUsecase := usecase.NewDockerNetworkUsecase(dockerClientRegistry)
+	dockerImageUsecase := usecase.NewDockerImageUsecase(dockerClientRegistry)
+	logUsecase := usecase.NewLogUsecase(dockerClientRegistry)
+	eventUsecase := usecase.NewEventUsecase(dockerClientRegistry)
+	alertReceiverUsecase := usecase.NewAlertReceiverUsecase(alertReceiverRepo, emailUsecase)
+	alertUsecase := usecase.NewAlertUsecase(alertRepo, dockerClientRegistry, notificationUsecase, serverUsecase, k8sRepo, k8sCapacityUsecase, dockerStackRepo, alertReceiverUsecase)
+
+	// Start Alert Monitoring
+	go alertUsecase.StartMonitoring(context.Background())
+
+	// Start Container Metrics Sampler
+	containerMetricsUsecase := usecase.NewContainerMetricsUsecase(containerMetricRepo, dockerStackRepo, dockerClientRegistry)
+	go containerMetricsUsecase.StartSampler(context.Background())
 
 	// Docker Stack & File Browser Usecases
-	dockerStackUsecase := usecase.NewDockerStackUsecase(dockerStackRepo)
-	fileBrowserUsecase := usecase.NewFileBrowserUsecase()
+	dockerStackUsecase := usecase.NewDockerStackUsecase(dockerStackRepo, userRepo, vulnerabilityGateUsecase, dockerClientRegistry)
+	stackTemplateUsecase := usecase.NewStackTemplateUsecase(stackTemplateRepo, dockerStackUsecase)
+	fileBrowserUsecase := usecase.NewFileBrowserUsecase(dockerClientRegistry, auditUsecase)
+
+	// Start Git-backed Stack Poller
+	go dockerStackUsecase.StartGitPoller(context.Background())
 
 	// Server Feature Usecases (with tunnel support)
 	serverUsecase = usecase.NewServerUsecase(serverRepo, tunnelManager)
@@ -230,27 +285,28 @@ func main() {
 		serverIPTableUsecase,
 	)
 	dockerHandler := handler.NewDockerHandler(dockerUsecase)
-	kubernetesHandler := handler.NewKubernetesHandler(kubernetesUsecase, k8sBackupUsecase)
+	helmHandler := handler.NewHelmHandler(k8sHelmUsecase)
+	kubernetesHandler := handler.NewKubernetesHandler(kubernetesUsecase, k8sBackupUsecase, k8sWatchUsecase, k8sRolloutUsecase, k8sRBACUsecase, k8sCapacityUsecase)
 	harborHandler := handler.NewHarborHandler(harborUsecase, imageDeploymentUsecase)
+	harborWebhookHandler := handler.NewHarborWebhookHandler(harborWebhookUsecase)
+	harborPromotionHandler := handler.NewHarborPromotionHandler(harborPromotionUsecase)
+	harborRetentionHandler := handler.NewHarborRetentionHandler(harborRetentionUsecase)
+	imageSearchHandler := handler.NewImageSearchHandler(imageSearchUsecase)
+	vulnerabilityGateHandler := handler.NewVulnerabilityGateHandler(vulnerabilityGateUsecase)
 
 	// Docker Exec & Stats Handlers
-	var dockerExecHandler *handler.DockerExecHandler
-	var dockerStatsHandler *handler.DockerStatsHandler
-	var dockerNetworkHandler *handler.DockerNetworkHandler
-	var dockerImageHandler *handler.DockerImageHandler
-	var logHandler *handler.LogHandler
-	var eventHandler *handler.EventHandler
-	if dockerExecUsecase != nil {
-		dockerExecHandler = handler.NewDockerExecHandler(dockerExecUsecase)
-		dockerStatsHandler = handler.NewDockerStatsHandler(dockerStatsUsecase)
-		dockerNetworkHandler = handler.NewDockerNetworkHandler(dockerNetworkUsecase)
-		dockerImageHandler = handler.NewDockerImageHandler(dockerImageUsecase)
-		logHandler = handler.NewLogHandler(logUsecase)
-		eventHandler = handler.NewEventHandler(eventUsecase)
-	}
+	dockerExecHandler := handler.NewDockerExecHandler(dockerExecUsecase)
+	dockerStatsHandler := handler.NewDockerStatsHandler(dockerStatsUsecase)
+	containerMetricsHandler := handler.NewContainerMetricsHandler(containerMetricsUsecase)
+	alertHandler := handler.NewAlertHandler(alertUsecase, alertReceiverUsecase)
+	dockerNetworkHandler := handler.NewDockerNetworkHandler(dockerNetworkUsecase)
+	dockerImageHandler := handler.NewDockerImageHandler(dockerImageUsecase)
+	logHandler := handler.NewLogHandler(logUsecase)
+	eventHandler := handler.NewEventHandler(eventUsecase)
 
 	// Docker Stack & File Browser Handlers
 	dockerStackHandler := handler.NewDockerStackHandler(dockerStackUsecase)
+	stackTemplateHandler := handler.NewStackTemplateHandler(stackTemplateUsecase)
 	fileBrowserHandler := handler.NewFileBrowserHandler(fileBrowserUsecase)
 
 	// Tunnel & Kubeconfig Handlers
@@ -272,14 +328,23 @@ func main() {
 		dockerHandler,
 		dockerExecHandler,
 		dockerStatsHandler,
+		containerMetricsHandler,
+		alertHandler,
 		dockerStackHandler,
+		stackTemplateHandler,
 		dockerNetworkHandler,
 		dockerImageHandler,
 		fileBrowserHandler,
 		logHandler,
 		eventHandler,
 		kubernetesHandler,
+		helmHandler,
 		harborHandler,
+		harborWebhookHandler,
+		harborPromotionHandler,
+		harborRetentionHandler,
+		imageSearchHandler,
+		vulnerabilityGateHandler,
 		pingHandler,
 		emailHandler,
 		notificationHandler,
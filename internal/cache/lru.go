@@ -1,8 +1,8 @@
-
 package cache
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	lru "github.com/hashicorp/golang-lru/v2"
@@ -11,32 +11,54 @@ import (
 // LRUCache is an in-memory LRU implementation of the Cache interface.
 // It uses the golang-lru library.
 type LRUCache struct {
-	lru *lru.Cache[string, string]
+	lru *lru.Cache[string, lruEntry]
+}
+
+// lruEntry is a cached value with its optional expiry.
+type lruEntry struct {
+	value     string
+	expiresAt time.Time
 }
 
 // NewLRUCache creates a new LRUCache with the given size.
 func NewLRUCache(size int) (*LRUCache, error) {
-	l, err := lru.New[string, string](size)
+	l, err := lru.New[string, lruEntry](size)
 	if err != nil {
 		return nil, err
 	}
 	return &LRUCache{lru: l}, nil
 }
 
-// Get retrieves a value from the cache.
+// Get retrieves a value from the cache. Expired entries are treated as a miss.
 func (c *LRUCache) Get(ctx context.Context, key string) (string, error) {
 	// The context is not used in this implementation.
-	val, ok := c.lru.Get(key)
+	entry, ok := c.lru.Get(key)
 	if !ok {
 		return "", nil // Cache miss
 	}
-	return val, nil
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.lru.Remove(key)
+		return "", nil
+	}
+	return entry.value, nil
 }
 
-// Set adds a value to the cache. The TTL is ignored in this implementation.
+// Set adds a value to the cache. A zero TTL keeps the value until it is evicted.
 func (c *LRUCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	// The context and TTL are not used in this implementation.
-	c.lru.Add(key, value.(string)) // This implementation only supports string values
+	// The context is not used in this implementation.
+	entry := lruEntry{}
+	switch v := value.(type) {
+	case string:
+		entry.value = v
+	case []byte:
+		entry.value = string(v)
+	default:
+		return fmt.Errorf("unsupported cache value type %T", value)
+	}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	c.lru.Add(key, entry)
 	return nil
 }
 
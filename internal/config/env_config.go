@@ -26,6 +26,7 @@ type Config struct {
 	Redis          RedisConfig
 	Minio          MinioConfig
 	Encryption     EncryptionConfig
+	Kafka          KafkaConfig
 	ELK            ELKConfig
 }
 
@@ -178,6 +179,16 @@ type MinioConfig struct {
 	BucketName      string `example:"einfra-crm"`
 }
 
+// KafkaConfig holds the message broker configuration; publishing is disabled without brokers
+type KafkaConfig struct {
+	Brokers           []string      `example:"localhost:9092"`
+	GroupID           string        `example:"einfra"`
+	DLQTopic          string        `example:"einfra.dlq"`
+	MaxRetries        int           `example:"3"`
+	RetryBackoff      time.Duration `example:"1s"`
+	HarborEventsTopic string        `example:"einfra.harbor.events"`
+}
+
 // EncryptionConfig holds encryption configuration for sensitive data
 type EncryptionConfig struct {
 	Key     string `validate:"required"`
@@ -306,6 +317,14 @@ func LoadConfig(configPath string) (*Config, error) {
 			Key:     getEnv("ENCRYPTION_KEY", ""),
 			Version: getIntEnv("ENCRYPTION_KEY_VERSION", 1),
 		},
+		Kafka: KafkaConfig{
+			Brokers:           getSliceEnv("KAFKA_BROKERS", nil),
+			GroupID:           getEnv("KAFKA_GROUP_ID", "einfra"),
+			DLQTopic:          getEnv("KAFKA_DLQ_TOPIC", "einfra.dlq"),
+			MaxRetries:        getIntEnv("KAFKA_MAX_RETRIES", 3),
+			RetryBackoff:      getDurationEnv("KAFKA_RETRY_BACKOFF", time.Second),
+			HarborEventsTopic: getEnv("KAFKA_HARBOR_EVENTS_TOPIC", "einfra.harbor.events"),
+		},
 		ELK: ELKConfig{
 			ElasticAPMEndpoint: getEnv("ELASTIC_APM_ENDPOINT", "http://localhost:8200"),
 		},
@@ -4,46 +4,238 @@ import (
 	"time"
 )
 
-// AlertRule represents a rule for resource alerting
+// AlertRule fires when a metric of the subjects in its scope breaches a threshold for a duration
 type AlertRule struct {
-	ID          string    `json:"id"`
-	ContainerID string    `json:"container_id"`
-	Metric      string    `json:"metric"` // cpu, memory
-	Threshold   float64   `json:"threshold"`
-	Duration    string    `json:"duration"` // e.g., "5m"
-	Enabled     bool      `json:"enabled"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string            `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Name        string            `json:"name" gorm:"type:varchar(255);not null"`
+	Description string            `json:"description,omitempty" gorm:"type:text"`
+	Scope       AlertScope        `json:"scope" gorm:"type:varchar(20);not null"`
+	Target      string            `json:"target,omitempty" gorm:"type:varchar(500)"` // Subject ID or name, label selector for label scope; empty for all
+	Metric      AlertMetric       `json:"metric" gorm:"type:varchar(50);not null"`
+	Operator    AlertOperator     `json:"operator" gorm:"type:varchar(10);not null"`
+	Threshold   float64           `json:"threshold"`
+	Duration    string            `json:"duration,omitempty" gorm:"type:varchar(20)"` // "for" window, e.g. "5m"; fires on the first breach when empty
+	Severity    AlertSeverity     `json:"severity" gorm:"type:varchar(20);not null"`
+	Labels      map[string]string `json:"labels,omitempty" gorm:"type:jsonb;serializer:json"` // Attached to its alerts for routing
+	Enabled     bool              `json:"enabled" gorm:"default:true"`
+	CreatedBy   *string           `json:"created_by,omitempty" gorm:"type:uuid"` // Notified when the rule fires and resolves
+	CreatedAt   time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
-// AlertHistory represents a history of triggered alerts
+// TableName overrides the table name
+func (AlertRule) TableName() string {
+	return "alert_rules"
+}
+
+// AlertScope is the kind of subject a rule evaluates
+type AlertScope string
+
+const (
+	AlertScopeContainer AlertScope = "container" // Each running container of the Docker hosts
+	AlertScopeStack     AlertScope = "stack"     // Containers of a Docker stack, summed
+	AlertScopeHost      AlertScope = "host"      // Containers of a Docker host, summed
+	AlertScopeServer    AlertScope = "server"
+	AlertScopeCluster   AlertScope = "cluster"
+	AlertScopeLabel     AlertScope = "label" // Each container whose labels match the selector in Target
+)
+
+// AlertMetric is the value a rule compares with its threshold
+type AlertMetric string
+
+const (
+	AlertMetricCPU            AlertMetric = "cpu"                  // Percent; 100 is one core for Docker scopes
+	AlertMetricMemory         AlertMetric = "memory"               // Percent of the limit or capacity
+	AlertMetricMemoryBytes    AlertMetric = "memory_bytes"         // Docker scopes only
+	AlertMetricDisk           AlertMetric = "disk"                 // Percent, servers only
+	AlertMetricCPURequests    AlertMetric = "cpu_requests"         // Percent of allocatable, clusters only
+	AlertMetricMemoryRequests AlertMetric = "memory_requests"      // Percent of allocatable, clusters only
+	AlertMetricNotReadyNodes  AlertMetric = "not_ready_nodes"      // Clusters only
+	AlertMetricContainers     AlertMetric = "running_containers"   // Stacks and hosts only
+	AlertMetricUnhealthy      AlertMetric = "unhealthy_containers" // Stacks and hosts only
+)
+
+// AlertOperator compares a metric with the threshold of a rule
+type AlertOperator string
+
+const (
+	AlertOperatorGT  AlertOperator = "gt"
+	AlertOperatorGTE AlertOperator = "gte"
+	AlertOperatorLT  AlertOperator = "lt"
+	AlertOperatorLTE AlertOperator = "lte"
+	AlertOperatorEQ  AlertOperator = "eq"
+	AlertOperatorNE  AlertOperator = "ne"
+)
+
+// Compare reports whether value breaches threshold
+func (o AlertOperator) Compare(value, threshold float64) bool {
+	switch o {
+	case AlertOperatorGT:
+		return value > threshold
+	case AlertOperatorGTE:
+		return value >= threshold
+	case AlertOperatorLT:
+		return value < threshold
+	case AlertOperatorLTE:
+		return value <= threshold
+	case AlertOperatorEQ:
+		return value == threshold
+	case AlertOperatorNE:
+		return value != threshold
+	}
+	return false
+}
+
+// AlertSeverity represents how urgent an alert is
+type AlertSeverity string
+
+const (
+	AlertSeverityInfo     AlertSeverity = "info"
+	AlertSeverityWarning  AlertSeverity = "warning"
+	AlertSeverityCritical AlertSeverity = "critical"
+)
+
+// AlertHistory records one firing of a rule for one subject, from the first breach to its resolution
 type AlertHistory struct {
-	ID          string    `json:"id"`
-	RuleID      string    `json:"rule_id"`
-	ContainerID string    `json:"container_id"`
-	Metric      string    `json:"metric"`
-	Value       float64   `json:"value"`
-	Threshold   float64   `json:"threshold"`
-	TriggeredAt time.Time `json:"triggered_at"`
-	ResolvedAt  time.Time `json:"resolved_at,omitempty"`
-}
-
-// DefaultAlertRules returns default rules applied to all containers
-func DefaultAlertRules() []AlertRule {
-	return []AlertRule{
-		{
-			ID:        "default-cpu-high",
-			Metric:    "cpu",
-			Threshold: 80.0, // 80%
-			Duration:  "1m",
-			Enabled:   true,
-		},
-		{
-			ID:        "default-memory-high",
-			Metric:    "memory",
-			Threshold: 90.0, // 90%
-			Duration:  "1m",
-			Enabled:   true,
-		},
+	ID          string            `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	RuleID      string            `json:"rule_id" gorm:"type:uuid;not null;index"`
+	RuleName    string            `json:"rule_name" gorm:"type:varchar(255)"`
+	Scope       AlertScope        `json:"scope" gorm:"type:varchar(20);not null"`
+	Subject     string            `json:"subject" gorm:"type:varchar(500);not null"` // ID of the container, stack, host, server or cluster
+	SubjectName string            `json:"subject_name" gorm:"type:varchar(255)"`
+	Metric      AlertMetric       `json:"metric" gorm:"type:varchar(50);not null"`
+	Value       float64           `json:"value"` // When the alert fired
+	Threshold   float64           `json:"threshold"`
+	Severity    AlertSeverity     `json:"severity" gorm:"type:varchar(20)"`
+	Labels      map[string]string `json:"labels,omitempty" gorm:"type:jsonb;serializer:json"` // Of the rule when the alert fired
+	Status      AlertStatus       `json:"status" gorm:"type:varchar(20);not null;index"`
+	Silenced    bool              `json:"silenced"`   // Notifications were suppressed by a silence
+	StartedAt   time.Time         `json:"started_at"` // First breach; the rule fired once it lasted the duration
+	TriggeredAt time.Time         `json:"triggered_at" gorm:"index"`
+	ResolvedAt  *time.Time        `json:"resolved_at,omitempty"`
+}
+
+// TableName overrides the table name
+func (AlertHistory) TableName() string {
+	return "alert_history"
+}
+
+// AlertStatus represents the state of a recorded alert
+type AlertStatus string
+
+const (
+	AlertStatusFiring   AlertStatus = "firing"
+	AlertStatusResolved AlertStatus = "resolved"
+)
+
+// AlertSilence suppresses notifications of the alerts it matches until it ends. Alerts still fire and
+// are recorded. Empty matchers match everything, but at least one must be set.
+type AlertSilence struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	RuleID    *string    `json:"rule_id,omitempty" gorm:"type:uuid"`
+	Scope     AlertScope `json:"scope,omitempty" gorm:"type:varchar(20)"`
+	Subject   string     `json:"subject,omitempty" gorm:"type:varchar(500)"`
+	Comment   string     `json:"comment,omitempty" gorm:"type:text"`
+	StartsAt  time.Time  `json:"starts_at"`
+	EndsAt    time.Time  `json:"ends_at" gorm:"index"`
+	CreatedBy *string    `json:"created_by,omitempty" gorm:"type:uuid"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName overrides the table name
+func (AlertSilence) TableName() string {
+	return "alert_silences"
+}
+
+// Matches reports whether the silence covers an alert at a time
+func (s *AlertSilence) Matches(ruleID string, scope AlertScope, subject string, at time.Time) bool {
+	if at.Before(s.StartsAt) || !at.Before(s.EndsAt) {
+		return false
+	}
+	return (s.RuleID == nil || *s.RuleID == ruleID) &&
+		(s.Scope == "" || s.Scope == scope) &&
+		(s.Subject == "" || s.Subject == subject)
+}
+
+// AlertMaintenanceWindow pauses alerting for the subjects it covers: rules are not evaluated for them,
+// so nothing fires or resolves. Weekly windows repeat every seven days from their first occurrence.
+type AlertMaintenanceWindow struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Name      string     `json:"name" gorm:"type:varchar(255);not null"`
+	Scope     AlertScope `json:"scope,omitempty" gorm:"type:varchar(20)"`    // Empty for every scope
+	Subject   string     `json:"subject,omitempty" gorm:"type:varchar(500)"` // Empty for every subject of the scope
+	StartsAt  time.Time  `json:"starts_at"`
+	EndsAt    time.Time  `json:"ends_at"`
+	Weekly    bool       `json:"weekly"`
+	CreatedBy *string    `json:"created_by,omitempty" gorm:"type:uuid"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName overrides the table name
+func (AlertMaintenanceWindow) TableName() string {
+	return "alert_maintenance_windows"
+}
+
+// Covers reports whether the window pauses alerting for a subject at a time
+func (w *AlertMaintenanceWindow) Covers(scope AlertScope, subject string, at time.Time) bool {
+	if (w.Scope != "" && w.Scope != scope) || (w.Subject != "" && w.Subject != subject) {
+		return false
+	}
+	if at.Before(w.StartsAt) {
+		return false
+	}
+	if w.Weekly {
+		const week = 7 * 24 * time.Hour
+		weeks := at.Sub(w.StartsAt) / week
+		start := w.StartsAt.Add(weeks * week)
+		return at.Before(start.Add(w.EndsAt.Sub(w.StartsAt)))
 	}
+	return at.Before(w.EndsAt)
+}
+
+// AlertRuleRequest represents a request to create or update an alert rule
+type AlertRuleRequest struct {
+	Name        string            `json:"name" binding:"required" example:"High CPU"`
+	Description string            `json:"description,omitempty"`
+	Scope       AlertScope        `json:"scope" binding:"required,oneof=container stack host server cluster label" example:"container"`
+	Target      string            `json:"target,omitempty" example:"com.docker.compose.project=shop"`
+	Metric      AlertMetric       `json:"metric" binding:"required" example:"cpu"`
+	Operator    AlertOperator     `json:"operator" binding:"required,oneof=gt gte lt lte eq ne" example:"gt"`
+	Threshold   float64           `json:"threshold" example:"80"`
+	Duration    string            `json:"duration,omitempty" example:"5m"`
+	Severity    AlertSeverity     `json:"severity,omitempty" binding:"omitempty,oneof=info warning critical" example:"warning"` // Defaults to warning
+	Labels      map[string]string `json:"labels,omitempty"`                                                                     // Routing labels, e.g. team
+	Enabled     *bool             `json:"enabled,omitempty"`                                                                    // Defaults to true
+}
+
+// AlertSilenceRequest represents a request to silence alerts
+type AlertSilenceRequest struct {
+	RuleID   *string    `json:"rule_id,omitempty"`
+	Scope    AlertScope `json:"scope,omitempty" binding:"omitempty,oneof=container stack host server cluster label"`
+	Subject  string     `json:"subject,omitempty"`
+	Comment  string     `json:"comment,omitempty"`
+	StartsAt *time.Time `json:"starts_at,omitempty"` // Defaults to now
+	EndsAt   time.Time  `json:"ends_at" binding:"required"`
+}
+
+// AlertMaintenanceWindowRequest represents a request to schedule a maintenance window
+type AlertMaintenanceWindowRequest struct {
+	Name     string     `json:"name" binding:"required" example:"Sunday patching"`
+	Scope    AlertScope `json:"scope,omitempty" binding:"omitempty,oneof=container stack host server cluster label"`
+	Subject  string     `json:"subject,omitempty"`
+	StartsAt time.Time  `json:"starts_at" binding:"required"`
+	EndsAt   time.Time  `json:"ends_at" binding:"required"`
+	Weekly   bool       `json:"weekly,omitempty"`
+}
+
+// AlertHistoryFilter represents filters for alert history queries
+type AlertHistoryFilter struct {
+	RuleID   string      `form:"rule_id"`
+	Scope    AlertScope  `form:"scope"`
+	Subject  string      `form:"subject"`
+	Status   AlertStatus `form:"status" binding:"omitempty,oneof=firing resolved"`
+	From     *time.Time  `form:"from" time_format:"2006-01-02T15:04:05Z07:00"` // Fired at or after
+	To       *time.Time  `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`   // Fired before
+	Page     int         `form:"page" binding:"omitempty,min=1"`
+	PageSize int         `form:"page_size" binding:"omitempty,min=1,max=100"`
 }
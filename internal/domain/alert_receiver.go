@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"time"
+)
+
+// AlertReceiver is a destination alert notifications are routed to
+type AlertReceiver struct {
+	ID           string            `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Name         string            `json:"name" gorm:"type:varchar(255);not null;uniqueIndex"`
+	Type         AlertReceiverType `json:"type" gorm:"type:varchar(20);not null"`
+	URL          string            `json:"url,omitempty" gorm:"type:varchar(1000)"`            // Webhook, Slack incoming webhook or PagerDuty events endpoint
+	Secret       string            `json:"-" gorm:"type:text"`                                 // Encrypted at rest; HMAC key of webhooks, routing key of PagerDuty
+	HasSecret    bool              `json:"has_secret" gorm:"-"`                                // Whether a secret is stored
+	Emails       []string          `json:"emails,omitempty" gorm:"type:jsonb;serializer:json"` // Recipients of email receivers
+	SendResolved bool              `json:"send_resolved"`
+	CreatedBy    *string           `json:"created_by,omitempty" gorm:"type:uuid"`
+	CreatedAt    time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName overrides the table name
+func (AlertReceiver) TableName() string {
+	return "alert_receivers"
+}
+
+// AlertReceiverType is how a receiver is notified
+type AlertReceiverType string
+
+const (
+	AlertReceiverWebhook   AlertReceiverType = "webhook"   // JSON POST signed with HMAC-SHA256 when a secret is set
+	AlertReceiverSlack     AlertReceiverType = "slack"     // Slack-compatible incoming webhook
+	AlertReceiverPagerDuty AlertReceiverType = "pagerduty" // PagerDuty Events API v2
+	AlertReceiverEmail     AlertReceiverType = "email"
+)
+
+// AlertRoute sends the alerts it matches to a receiver, in groups. Alerts are matched against the
+// child routes first; the first matching child takes them unless it continues to its siblings. Unset
+// receiver, grouping and intervals are inherited from the parent route.
+type AlertRoute struct {
+	Receiver       string            `json:"receiver,omitempty"`        // Receiver ID
+	Match          map[string]string `json:"match,omitempty"`           // Label values the alerts must have
+	MatchRegex     map[string]string `json:"match_regex,omitempty"`     // Regular expressions label values must fully match
+	GroupBy        []string          `json:"group_by,omitempty"`        // Labels alerts are grouped by; none puts all in one group
+	GroupWait      string            `json:"group_wait,omitempty"`      // Before the first notification of a group, default 30s
+	GroupInterval  string            `json:"group_interval,omitempty"`  // Between notifications of a changed group, default 5m
+	RepeatInterval string            `json:"repeat_interval,omitempty"` // Before firing alerts are sent again, default 4h
+	Continue       bool              `json:"continue,omitempty"`        // Keep matching siblings after this route
+	Routes         []AlertRoute      `json:"routes,omitempty"`
+}
+
+// AlertRouting is the routing tree of alert notifications
+type AlertRouting struct {
+	ID        int        `json:"-" gorm:"primaryKey"` // Always 1
+	Route     AlertRoute `json:"route" gorm:"type:jsonb;serializer:json"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName overrides the table name
+func (AlertRouting) TableName() string {
+	return "alert_routing"
+}
+
+// RoutingLabels returns the labels routes match an alert by: the labels of its rule and alertname,
+// rule_id, severity, scope, subject, subject_name and metric
+func (h *AlertHistory) RoutingLabels() map[string]string {
+	labels := make(map[string]string, len(h.Labels)+7)
+	for k, v := range h.Labels {
+		labels[k] = v
+	}
+	labels["alertname"] = h.RuleName
+	labels["rule_id"] = h.RuleID
+	labels["severity"] = string(h.Severity)
+	labels["scope"] = string(h.Scope)
+	labels["subject"] = h.Subject
+	labels["subject_name"] = h.SubjectName
+	labels["metric"] = string(h.Metric)
+	return labels
+}
+
+// AlertReceiverRequest represents a request to create or update an alert receiver
+type AlertReceiverRequest struct {
+	Name         string            `json:"name" binding:"required" example:"ops-slack"`
+	Type         AlertReceiverType `json:"type" binding:"required,oneof=webhook slack pagerduty email" example:"slack"`
+	URL          string            `json:"url,omitempty" example:"https://hooks.slack.com/services/T000/B000/XXXX"` // Defaults to the PagerDuty events endpoint
+	Secret       *string           `json:"secret,omitempty"`                                                        // Kept when omitted on update
+	Emails       []string          `json:"emails,omitempty" binding:"omitempty,dive,email"`
+	SendResolved *bool             `json:"send_resolved,omitempty"` // Defaults to true
+}
+
+// AlertWebhookPayload is the body POSTed to webhook receivers, one per notification of a group
+type AlertWebhookPayload struct {
+	Version     string            `json:"version"`
+	GroupKey    string            `json:"group_key"`
+	Status      AlertStatus       `json:"status"` // Firing while any alert of the group fires
+	Receiver    string            `json:"receiver"`
+	GroupLabels map[string]string `json:"group_labels"`
+	Alerts      []*AlertHistory   `json:"alerts"`
+}
@@ -0,0 +1,86 @@
+package domain
+
+import "time"
+
+// ContainerMetric is the resource usage of a container aggregated over one bucket of a resolution.
+// Network and block IO are the bytes transferred during the bucket.
+type ContainerMetric struct {
+	HostID        string                    `json:"host_id" gorm:"primaryKey;type:uuid"`
+	ContainerID   string                    `json:"container_id" gorm:"primaryKey;type:varchar(64)"`
+	Resolution    ContainerMetricResolution `json:"resolution" gorm:"primaryKey;type:varchar(10)"`
+	Timestamp     time.Time                 `json:"timestamp" gorm:"primaryKey"` // Start of the bucket
+	ContainerName string                    `json:"container_name" gorm:"type:varchar(255)"`
+	Project       string                    `json:"project,omitempty" gorm:"type:varchar(255);index"` // Compose project, the ProjectName of a DockerStack
+	Samples       int                       `json:"samples" gorm:"not null"`
+	CPUPercent    float64                   `json:"cpu_percent"` // Average; 100 is one full core
+	CPUPercentMax float64                   `json:"cpu_percent_max"`
+	MemoryUsage   uint64                    `json:"memory_usage"` // Average bytes, without page cache
+	MemoryMax     uint64                    `json:"memory_max"`
+	MemoryLimit   uint64                    `json:"memory_limit"`
+	NetworkRx     uint64                    `json:"network_rx"`
+	NetworkTx     uint64                    `json:"network_tx"`
+	BlockRead     uint64                    `json:"block_read"`
+	BlockWrite    uint64                    `json:"block_write"`
+}
+
+// TableName overrides the table name
+func (ContainerMetric) TableName() string {
+	return "container_metrics"
+}
+
+// ContainerMetricResolution is the bucket size of container metrics
+type ContainerMetricResolution string
+
+const (
+	ContainerMetricResolution1m ContainerMetricResolution = "1m"
+	ContainerMetricResolution5m ContainerMetricResolution = "5m"
+	ContainerMetricResolution1h ContainerMetricResolution = "1h"
+)
+
+// Duration returns the bucket size of a resolution, 0 when it is unknown
+func (r ContainerMetricResolution) Duration() time.Duration {
+	switch r {
+	case ContainerMetricResolution1m:
+		return time.Minute
+	case ContainerMetricResolution5m:
+		return 5 * time.Minute
+	case ContainerMetricResolution1h:
+		return time.Hour
+	}
+	return 0
+}
+
+// ContainerMetricFilter selects stored metrics; empty fields match everything
+type ContainerMetricFilter struct {
+	HostID      string
+	ContainerID string
+	Project     string
+	Resolution  ContainerMetricResolution
+	From        time.Time
+	To          time.Time
+}
+
+// ContainerMetricQuery represents a range query for charts. Without a resolution the finest one
+// still retained for the whole range is used.
+type ContainerMetricQuery struct {
+	From       time.Time                 `form:"from" time_format:"2006-01-02T15:04:05Z07:00"` // Defaults to one hour before to
+	To         time.Time                 `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`   // Defaults to now
+	Resolution ContainerMetricResolution `form:"resolution" binding:"omitempty,oneof=1m 5m 1h"`
+}
+
+// ContainerMetricSeries holds the metrics of one container in a range
+type ContainerMetricSeries struct {
+	HostID        string             `json:"host_id"`
+	ContainerID   string             `json:"container_id"`
+	ContainerName string             `json:"container_name"`
+	Project       string             `json:"project,omitempty"`
+	Points        []*ContainerMetric `json:"points"`
+}
+
+// ContainerMetricsResponse represents the result of a range query
+type ContainerMetricsResponse struct {
+	Resolution ContainerMetricResolution `json:"resolution"`
+	From       time.Time                 `json:"from"`
+	To         time.Time                 `json:"to"`
+	Series     []ContainerMetricSeries   `json:"series"`
+}
@@ -23,21 +23,31 @@ const (
 	ContainerStatusDead ContainerStatus = "dead"
 )
 
+// Docker host health statuses
+const (
+	DockerHostStatusUnknown = "unknown"
+	DockerHostStatusOnline  = "online"
+	DockerHostStatusOffline = "offline"
+)
+
 // DockerHost represents a Docker host/daemon in the infrastructure
 // @Description Docker host configuration and connection details
 type DockerHost struct {
-	ID          string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()" example:"550e8400-e29b-41d4-a716-446655440000"`
-	Name        string     `json:"name" gorm:"type:varchar(255);not null;uniqueIndex" validate:"required,min=3,max=255" example:"docker-host-01"`
-	Description string     `json:"description" gorm:"type:text" example:"Production Docker host"`
-	Endpoint    string     `json:"endpoint" gorm:"type:varchar(500);not null" validate:"required" example:"tcp://192.168.1.100:2376"`
-	TLSEnabled  bool       `json:"tls_enabled" gorm:"type:boolean;default:true" example:"true"`
-	CertPath    string     `json:"cert_path,omitempty" gorm:"type:varchar(500)" example:"/etc/docker/certs"`
-	Version     string     `json:"version" gorm:"type:varchar(50)" example:"24.0.7"`
-	ServerID    *string    `json:"server_id,omitempty" gorm:"type:uuid;index" example:"550e8400-e29b-41d4-a716-446655440000"`
-	IsActive    bool       `json:"is_active" gorm:"type:boolean;default:true;index" example:"true"`
-	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime" example:"2024-01-01T00:00:00Z"`
-	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime" example:"2024-01-01T00:00:00Z"`
-	DeletedAt   *time.Time `json:"deleted_at,omitempty" gorm:"index" swaggertype:"string" example:"2024-01-01T00:00:00Z"`
+	ID            string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name          string     `json:"name" gorm:"type:varchar(255);not null;uniqueIndex" validate:"required,min=3,max=255" example:"docker-host-01"`
+	Description   string     `json:"description" gorm:"type:text" example:"Production Docker host"`
+	Endpoint      string     `json:"endpoint" gorm:"type:varchar(500);not null" validate:"required" example:"tcp://192.168.1.100:2376"`
+	TLSEnabled    bool       `json:"tls_enabled" gorm:"type:boolean;default:true" example:"true"`
+	CertPath      string     `json:"cert_path,omitempty" gorm:"type:varchar(500)" example:"/etc/docker/certs"`
+	Version       string     `json:"version" gorm:"type:varchar(50)" example:"24.0.7"`
+	Status        string     `json:"status" gorm:"type:varchar(20);default:'unknown'" example:"online"` // online, offline or unknown until checked
+	LastError     string     `json:"last_error,omitempty" gorm:"type:text" example:"connection refused"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	ServerID      *string    `json:"server_id,omitempty" gorm:"type:uuid;index" example:"550e8400-e29b-41d4-a716-446655440000"`
+	IsActive      bool       `json:"is_active" gorm:"type:boolean;default:true;index" example:"true"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"autoUpdateTime" example:"2024-01-01T00:00:00Z"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" gorm:"index" swaggertype:"string" example:"2024-01-01T00:00:00Z"`
 }
 
 // TableName specifies the table name for DockerHost model
@@ -144,6 +154,9 @@ type DockerHostRepository interface {
 	// List retrieves all Docker hosts with filtering
 	List(ctx context.Context, filter DockerHostFilter) ([]*DockerHost, int64, error)
 
+	// UpdateHealth records the outcome of a daemon health check
+	UpdateHealth(ctx context.Context, id, status, version, lastError string, checkedAt time.Time) error
+
 	// Update updates a Docker host
 	Update(ctx context.Context, host *DockerHost) error
 
@@ -167,6 +180,8 @@ type DockerUsecase interface {
 	ListDockerHosts(ctx context.Context, filter DockerHostFilter) ([]*DockerHost, int64, error)
 	UpdateDockerHost(ctx context.Context, host *DockerHost) error
 	DeleteDockerHost(ctx context.Context, id string) error
+	// RefreshDockerHost checks the daemon of a host and records its status and version
+	RefreshDockerHost(ctx context.Context, id string) (*DockerHost, error)
 
 	// Container Management
 	ListContainers(ctx context.Context, hostID string, all bool) ([]*Container, error)
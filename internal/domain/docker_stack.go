@@ -1,20 +1,35 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrStackBusy is returned when a stack is changed while a deploy or update of it is still running
+var ErrStackBusy = errors.New("stack is being deployed")
 
 // DockerStack represents a Docker Compose stack
 type DockerStack struct {
-	ID          string            `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	Name        string            `json:"name" gorm:"type:varchar(255);not null;uniqueIndex"`
-	ComposeFile string            `json:"compose_file" gorm:"type:text;not null"` // YAML content
-	EnvVars     map[string]string `json:"env_vars" gorm:"type:jsonb"`
-	Status      StackStatus       `json:"status" gorm:"type:varchar(50);not null"`
-	DockerHost  string            `json:"docker_host" gorm:"type:varchar(255)"`
-	ProjectName string            `json:"project_name" gorm:"type:varchar(255)"` // Docker Compose project name
-	CreatedBy   string            `json:"created_by" gorm:"type:uuid"`
-	CreatedAt   time.Time         `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt   *time.Time        `json:"deleted_at,omitempty" gorm:"index"`
+	ID            string            `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Name          string            `json:"name" gorm:"type:varchar(255);not null;uniqueIndex"`
+	ComposeFile   string            `json:"compose_file" gorm:"type:text;not null"` // YAML content
+	EnvVars       map[string]string `json:"env_vars" gorm:"type:jsonb;serializer:json"`
+	SecretEnvVars map[string]string `json:"-" gorm:"type:jsonb;serializer:json"` // Encrypted at rest; compose files reference them as ${NAME}
+	Status        StackStatus       `json:"status" gorm:"type:varchar(50);not null"`
+	DockerHost    string            `json:"docker_host" gorm:"type:varchar(255)"`            // DockerHost ID, empty for the local daemon
+	ProjectName   string            `json:"project_name" gorm:"type:varchar(255)"`           // Docker Compose project name
+	Revision      int               `json:"revision" gorm:"type:int;default:0"`              // StackRevision the compose file belongs to
+	EnvironmentID *string           `json:"environment_id,omitempty" gorm:"type:uuid;index"` // Vulnerability policy of this environment gates deploys
+	CreatedBy     string            `json:"created_by" gorm:"type:uuid"`
+	CreatedAt     time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt     *time.Time        `json:"deleted_at,omitempty" gorm:"index"`
+
+	TemplateID      *string `json:"template_id,omitempty" gorm:"type:varchar(255);index"` // StackTemplate the stack was deployed from
+	TemplateVersion int     `json:"template_version,omitempty" gorm:"type:int"`
+
+	VulnerabilityGate *VulnerabilityGateReport `json:"vulnerability_gate,omitempty" gorm:"-"` // Result of the last deploy check
+	SecretNames       []string                 `json:"secret_env_vars,omitempty" gorm:"-"`    // Names of SecretEnvVars, the values are never returned
 }
 
 // StackStatus represents the status of a stack
@@ -36,13 +51,94 @@ type StackService struct {
 	Image       string     `json:"image" gorm:"type:varchar(500)"`
 	Replicas    int        `json:"replicas" gorm:"type:int;default:1"`
 	Status      string     `json:"status" gorm:"type:varchar(50)"`
-	Ports       []string   `json:"ports" gorm:"type:jsonb"`
-	Environment []string   `json:"environment" gorm:"type:jsonb"`
+	Ports       []string   `json:"ports" gorm:"type:jsonb;serializer:json"`
+	Environment []string   `json:"environment" gorm:"type:jsonb;serializer:json"`
 	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt   *time.Time `json:"deleted_at,omitempty" gorm:"index"`
 }
 
+// StackRevision is a version of the compose file and variables of a stack, kept for history and rollback
+type StackRevision struct {
+	ID          string              `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	StackID     string              `json:"stack_id" gorm:"type:uuid;not null;uniqueIndex:idx_stack_revisions_stack_revision"`
+	Revision    int                 `json:"revision" gorm:"not null;uniqueIndex:idx_stack_revisions_stack_revision"`
+	ComposeFile string              `json:"compose_file" gorm:"type:text;not null"`
+	EnvVars     map[string]string   `json:"env_vars" gorm:"type:jsonb;serializer:json"`
+	Status      StackRevisionStatus `json:"status" gorm:"type:varchar(50);not null"`
+	Changes     []StackServiceDiff  `json:"changes" gorm:"type:jsonb;serializer:json"`    // Against the revision it replaced
+	Error       string              `json:"error,omitempty" gorm:"type:text"`             // Why the rollout failed
+	RollbackOf  *int                `json:"rollback_of,omitempty"`                        // Revision restored by a manual rollback
+	GitCommit   string              `json:"git_commit,omitempty" gorm:"type:varchar(64)"` // Commit the compose file was read at, for git-backed stacks
+	CreatedBy   *string             `json:"created_by,omitempty" gorm:"type:uuid"`        // Empty for revisions detected by the git poller
+	ApprovedBy  *string             `json:"approved_by,omitempty" gorm:"type:uuid"`
+	CreatedAt   time.Time           `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// StackRevisionStatus represents the rollout state of a revision
+type StackRevisionStatus string
+
+const (
+	StackRevisionDeploying  StackRevisionStatus = "deploying"
+	StackRevisionActive     StackRevisionStatus = "active"
+	StackRevisionSuperseded StackRevisionStatus = "superseded"
+	StackRevisionRolledBack StackRevisionStatus = "rolled_back" // Failed its healthchecks, the previous revision was restored
+	StackRevisionFailed     StackRevisionStatus = "failed"      // Failed and could not be rolled back
+	StackRevisionPending    StackRevisionStatus = "pending_approval"
+	StackRevisionRejected   StackRevisionStatus = "rejected"
+)
+
+// StackGitSource is the repository a git-backed stack deploys its compose file from
+type StackGitSource struct {
+	StackID        string             `json:"stack_id" gorm:"primaryKey;type:uuid"`
+	URL            string             `json:"url" gorm:"type:varchar(500);not null"`
+	Branch         string             `json:"branch" gorm:"type:varchar(255);not null"`
+	Path           string             `json:"path" gorm:"type:varchar(500);not null"`
+	DeployKey      string             `json:"-" gorm:"type:text"`                                // Encrypted at rest
+	HasDeployKey   bool               `json:"has_deploy_key" gorm:"-"`                           // Whether a deploy key is stored
+	WebhookSecret  string             `json:"webhook_secret,omitempty" gorm:"type:varchar(500)"` // Encrypted at rest; signs or authenticates webhook calls
+	DeployMode     StackGitDeployMode `json:"deploy_mode" gorm:"type:varchar(20);not null"`
+	ApprovalRoleID *string            `json:"approval_role_id,omitempty" gorm:"type:uuid"`   // Role whose members may approve revisions; any user but the requester when empty
+	LastCommit     string             `json:"last_commit,omitempty" gorm:"type:varchar(64)"` // Newest commit seen on the branch
+	LastCheckedAt  *time.Time         `json:"last_checked_at,omitempty"`
+	LastError      string             `json:"last_error,omitempty" gorm:"type:text"`
+	CreatedAt      time.Time          `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time          `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// StackGitDeployMode decides what happens when a new commit changes the compose file
+type StackGitDeployMode string
+
+const (
+	StackGitDeployAuto     StackGitDeployMode = "auto"     // Rolled out right away
+	StackGitDeployApproval StackGitDeployMode = "approval" // Recorded as a revision pending approval
+)
+
+// StackServiceAction is what an update does to a service
+type StackServiceAction string
+
+const (
+	StackServiceCreate    StackServiceAction = "create"
+	StackServiceUpdate    StackServiceAction = "update"
+	StackServiceRemove    StackServiceAction = "remove"
+	StackServiceUnchanged StackServiceAction = "unchanged"
+)
+
+// StackServiceDiff describes how an update changes one service of a stack
+type StackServiceDiff struct {
+	Service string             `json:"service" example:"web"`
+	Action  StackServiceAction `json:"action" example:"update"`
+	Changes []StackFieldChange `json:"changes,omitempty"`
+}
+
+// StackFieldChange is a changed setting of a service; fields are image, environment.<NAME>, ports,
+// volumes, or config for any other setting
+type StackFieldChange struct {
+	Field  string `json:"field" example:"image"`
+	Before string `json:"before,omitempty" example:"nginx:1.26"`
+	After  string `json:"after,omitempty" example:"nginx:1.27"`
+}
+
 // TableName specifies the table name for DockerStack
 func (DockerStack) TableName() string {
 	return "docker_stacks"
@@ -53,18 +149,57 @@ func (StackService) TableName() string {
 	return "stack_services"
 }
 
+// TableName specifies the table name for StackRevision
+func (StackRevision) TableName() string {
+	return "stack_revisions"
+}
+
+// TableName specifies the table name for StackGitSource
+func (StackGitSource) TableName() string {
+	return "stack_git_sources"
+}
+
 // StackDeployRequest represents a request to deploy a stack
 type StackDeployRequest struct {
-	Name        string            `json:"name" binding:"required" example:"my-app"`
-	ComposeFile string            `json:"compose_file" binding:"required"` // YAML content
-	EnvVars     map[string]string `json:"env_vars,omitempty"`
-	DockerHost  string            `json:"docker_host,omitempty" example:"unix:///var/run/docker.sock"`
+	Name          string            `json:"name" binding:"required" example:"my-app"`
+	ComposeFile   string            `json:"compose_file" binding:"required_without=Git"` // YAML content
+	Git           *StackGitRequest  `json:"git,omitempty"`                               // Deploy the compose file from a repository instead
+	EnvVars       map[string]string `json:"env_vars,omitempty"`
+	DockerHost    string            `json:"docker_host,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"` // DockerHost ID, empty for the local daemon
+	EnvironmentID *string           `json:"environment_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+
+	// Set by the template catalog for stacks rendered from a StackTemplate
+	TemplateID      *string           `json:"-"`
+	TemplateVersion int               `json:"-"`
+	SecretEnvVars   map[string]string `json:"-"` // Values of secret parameters
 }
 
 // StackUpdateRequest represents a request to update a stack
 type StackUpdateRequest struct {
-	ComposeFile string            `json:"compose_file" binding:"required"`
-	EnvVars     map[string]string `json:"env_vars,omitempty"`
+	ComposeFile   string            `json:"compose_file" binding:"required"`
+	EnvVars       map[string]string `json:"env_vars,omitempty"`
+	HealthTimeout int               `json:"health_timeout,omitempty" binding:"omitempty,min=1,max=1800" example:"120"` // Seconds each recreated container gets to become healthy
+}
+
+// StackGitRequest links a stack to a compose file in a git repository
+type StackGitRequest struct {
+	URL            string             `json:"url" binding:"required" example:"git@github.com:acme/app.git"`
+	Branch         string             `json:"branch" binding:"required" example:"main"`
+	Path           string             `json:"path,omitempty" example:"deploy/docker-compose.yml"` // Defaults to docker-compose.yml
+	DeployKey      string             `json:"deploy_key,omitempty"`                               // SSH private key; kept when omitted on update
+	DeployMode     StackGitDeployMode `json:"deploy_mode,omitempty" binding:"omitempty,oneof=auto approval" example:"auto"`
+	ApprovalRoleID *string            `json:"approval_role_id,omitempty"` // Role allowed to approve revisions of the approval mode
+}
+
+// StackRevisionApproveRequest represents the approval of a revision pending approval
+type StackRevisionApproveRequest struct {
+	HealthTimeout int `json:"health_timeout,omitempty" binding:"omitempty,min=1,max=1800" example:"120"` // Seconds each recreated container gets to become healthy
+}
+
+// StackRollbackRequest represents a request to redeploy an earlier revision of a stack
+type StackRollbackRequest struct {
+	Revision      int `json:"revision,omitempty" example:"3"`                                            // Defaults to the revision before the current one
+	HealthTimeout int `json:"health_timeout,omitempty" binding:"omitempty,min=1,max=1800" example:"120"` // Seconds each recreated container gets to become healthy
 }
 
 // StackInfo represents detailed stack information
@@ -1,6 +1,19 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrFileNotFound    = errors.New("file not found")
+	ErrFileExists      = errors.New("file already exists")
+	ErrInvalidFilePath = errors.New("invalid file path")
+	ErrFileTooLarge    = errors.New("file is too large")
+	ErrFileNotText     = errors.New("file is not a text file")
+	ErrFileChanged     = errors.New("file was changed since it was read")
+)
 
 // FileInfo represents file/directory information
 type FileInfo struct {
@@ -8,10 +21,39 @@ type FileInfo struct {
 	Path        string    `json:"path"`
 	Size        int64     `json:"size"`
 	IsDir       bool      `json:"is_dir"`
+	IsSymlink   bool      `json:"is_symlink,omitempty"`
 	ModTime     time.Time `json:"mod_time"`
 	Permissions string    `json:"permissions"`
-	Owner       string    `json:"owner,omitempty"`
-	Group       string    `json:"group,omitempty"`
+	Owner       string    `json:"owner,omitempty"` // Numeric user ID
+	Group       string    `json:"group,omitempty"` // Numeric group ID
+}
+
+// FileDownload is a file or an archive of a directory being downloaded
+type FileDownload struct {
+	Name        string
+	ContentType string
+	Size        int64 // -1 when unknown, e.g. for archives
+	Content     io.ReadCloser
+}
+
+// FileContent is a text file of a container with the hash its edits are checked against
+type FileContent struct {
+	Path        string    `json:"path"`
+	Content     string    `json:"content"`
+	Hash        string    `json:"hash"` // SHA-256 of Content in hex
+	Size        int64     `json:"size"`
+	Permissions string    `json:"permissions"`
+	Owner       string    `json:"owner"` // Numeric user ID
+	Group       string    `json:"group"` // Numeric group ID
+	ModTime     time.Time `json:"mod_time"`
+}
+
+// FileWriteRequest represents a request to write a text file of a container. Hash is the hash of the
+// content the edit is based on; an empty hash creates a new file.
+type FileWriteRequest struct {
+	Path    string `json:"path" binding:"required"`
+	Content string `json:"content"`
+	Hash    string `json:"hash"`
 }
 
 // FileBrowserRequest represents a request to browse files
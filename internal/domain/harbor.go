@@ -8,18 +8,20 @@ import (
 // HarborRegistry represents a Harbor container registry
 // @Description Harbor registry configuration and connection details
 type HarborRegistry struct {
-	ID          string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()" example:"550e8400-e29b-41d4-a716-446655440000"`
-	Name        string     `json:"name" gorm:"type:varchar(255);not null;uniqueIndex" validate:"required,min=3,max=255" example:"prod-harbor"`
-	Description string     `json:"description" gorm:"type:text" example:"Production Harbor registry"`
-	URL         string     `json:"url" gorm:"type:varchar(500);not null" validate:"required,url" example:"https://harbor.example.com"`
-	Username    string     `json:"username" gorm:"type:varchar(255);not null" validate:"required" example:"admin"`
-	Password    string     `json:"password,omitempty" gorm:"type:varchar(500)" validate:"required" example:"Harbor12345"` // Should be encrypted
-	Version     string     `json:"version" gorm:"type:varchar(50)" example:"v2.9.0"`
-	IsDefault   bool       `json:"is_default" gorm:"type:boolean;default:false" example:"false"`
-	IsActive    bool       `json:"is_active" gorm:"type:boolean;default:true;index" example:"true"`
-	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime" example:"2024-01-01T00:00:00Z"`
-	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime" example:"2024-01-01T00:00:00Z"`
-	DeletedAt   *time.Time `json:"deleted_at,omitempty" gorm:"index" swaggertype:"string" example:"2024-01-01T00:00:00Z"`
+	ID               string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name             string     `json:"name" gorm:"type:varchar(255);not null;uniqueIndex" validate:"required,min=3,max=255" example:"prod-harbor"`
+	Description      string     `json:"description" gorm:"type:text" example:"Production Harbor registry"`
+	URL              string     `json:"url" gorm:"type:varchar(500);not null" validate:"required,url" example:"https://harbor.example.com"`
+	Username         string     `json:"username" gorm:"type:varchar(255);not null" validate:"required" example:"admin"`
+	Password         string     `json:"-" gorm:"type:text"` // Encrypted at rest; never returned
+	Version          string     `json:"version" gorm:"type:varchar(50)" example:"v2.9.0"`
+	WebhookSecret    string     `json:"-" gorm:"type:text"`                         // Encrypted at rest; auth header Harbor sends with webhook events
+	HasWebhookSecret bool       `json:"has_webhook_secret" gorm:"-" example:"true"` // Whether a webhook secret is stored
+	IsDefault        bool       `json:"is_default" gorm:"type:boolean;default:false" example:"false"`
+	IsActive         bool       `json:"is_active" gorm:"type:boolean;default:true;index" example:"true"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"autoCreateTime" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt        time.Time  `json:"updated_at" gorm:"autoUpdateTime" example:"2024-01-01T00:00:00Z"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty" gorm:"index" swaggertype:"string" example:"2024-01-01T00:00:00Z"`
 }
 
 // TableName specifies the table name for HarborRegistry model
@@ -27,6 +29,22 @@ func (HarborRegistry) TableName() string {
 	return "harbor_registries"
 }
 
+// HarborRegistryRequest is a registry as clients send it. The password and webhook secret are write-only:
+// responses never include them, and empty values on update keep the stored ones.
+type HarborRegistryRequest struct {
+	HarborRegistry
+	Password      string `json:"password,omitempty" example:"Harbor12345"`  // Password of the registry user
+	WebhookSecret string `json:"webhook_secret,omitempty" example:"s3cr3t"` // Auth header Harbor sends with webhook events
+}
+
+// ToRegistry returns the registry with its password and webhook secret
+func (r *HarborRegistryRequest) ToRegistry() *HarborRegistry {
+	registry := r.HarborRegistry
+	registry.Password = r.Password
+	registry.WebhookSecret = r.WebhookSecret
+	return &registry
+}
+
 // HarborProject represents a Harbor project
 // @Description Harbor project with metadata and quotas
 type HarborProject struct {
@@ -123,6 +141,19 @@ type HarborVulnerability struct {
 	CVSSScore   float64  `json:"cvss_score" example:"7.5"`
 }
 
+// HarborCVEArtifact is an artifact affected by a CVE
+// @Description Artifact affected by a vulnerability
+type HarborCVEArtifact struct {
+	RegistryID string   `json:"registry_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Repository string   `json:"repository" example:"library/nginx"`
+	Digest     string   `json:"digest" example:"sha256:abc123..."`
+	Tags       []string `json:"tags" example:"1.25"`
+	Package    string   `json:"package" example:"openssl"`
+	Version    string   `json:"version" example:"1.1.1k"`
+	FixVersion string   `json:"fix_version" example:"1.1.1w"`
+	Severity   string   `json:"severity" example:"High"`
+}
+
 // HarborLabel represents a label for artifacts
 // @Description Harbor label
 type HarborLabel struct {
@@ -147,6 +178,34 @@ type HarborQuota struct {
 	UpdatedAt time.Time              `json:"updated_at" example:"2024-01-01T00:00:00Z"`
 }
 
+// HarborRegistryStatus represents the result of a registry connection test
+// @Description Harbor version and component health
+type HarborRegistryStatus struct {
+	RegistryID string            `json:"registry_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Connected  bool              `json:"connected" example:"true"`
+	Version    string            `json:"version,omitempty" example:"v2.9.0-5b1a3f1c"`
+	Health     string            `json:"health,omitempty" example:"healthy"` // healthy, unhealthy
+	Components map[string]string `json:"components,omitempty"`               // Component name -> status
+	Error      string            `json:"error,omitempty"`
+	CheckedAt  time.Time         `json:"checked_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// HarborWebhookEvent is a Harbor webhook event normalized to a single artifact
+// @Description Artifact event received from a Harbor webhook
+type HarborWebhookEvent struct {
+	ID           string              `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	RegistryID   string              `json:"registry_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Type         string              `json:"type" example:"PUSH_ARTIFACT"` // PUSH_ARTIFACT, SCANNING_COMPLETED, DELETE_ARTIFACT, QUOTA_EXCEED
+	OccurredAt   time.Time           `json:"occurred_at" example:"2024-01-01T00:00:00Z"`
+	Operator     string              `json:"operator" example:"admin"`
+	Repository   string              `json:"repository" example:"library/nginx"`
+	Digest       string              `json:"digest,omitempty" example:"sha256:abcd1234..."`
+	Tag          string              `json:"tag,omitempty" example:"1.25"`
+	ResourceURL  string              `json:"resource_url,omitempty" example:"harbor.example.com/library/nginx:1.25"`
+	ScanOverview *HarborScanOverview `json:"scan_overview,omitempty"`
+	Details      string              `json:"details,omitempty" example:"will exceed the configured upper limit of 10.0 GiB"`
+}
+
 // HarborRegistryRepository defines the interface for Harbor registry data persistence
 type HarborRegistryRepository interface {
 	// Create creates a new Harbor registry record
@@ -184,7 +243,7 @@ type HarborUsecase interface {
 	ListRegistries(ctx context.Context, filter HarborRegistryFilter) ([]*HarborRegistry, int64, error)
 	UpdateRegistry(ctx context.Context, registry *HarborRegistry) error
 	DeleteRegistry(ctx context.Context, id string) error
-	TestRegistryConnection(ctx context.Context, id string) (bool, error)
+	TestRegistryConnection(ctx context.Context, id string) (*HarborRegistryStatus, error)
 
 	// Project Management
 	ListProjects(ctx context.Context, registryID string, public *bool) ([]*HarborProject, error)
@@ -208,6 +267,8 @@ type HarborUsecase interface {
 	ScanArtifact(ctx context.Context, registryID, repositoryName, reference string) error
 	GetScanReport(ctx context.Context, registryID, repositoryName, reference string) (*HarborScanOverview, error)
 	GetVulnerabilities(ctx context.Context, registryID, repositoryName, reference string) ([]*HarborVulnerability, error)
+	// ListArtifactsByCVE lists the artifacts of a registry affected by a CVE, as far as they were scanned
+	ListArtifactsByCVE(ctx context.Context, registryID, cveID string) ([]*HarborCVEArtifact, error)
 
 	// Label Management
 	ListLabels(ctx context.Context, registryID string, scope string, projectID *int64) ([]*HarborLabel, error)
@@ -219,4 +280,15 @@ type HarborUsecase interface {
 	// Quota Management
 	GetProjectQuota(ctx context.Context, registryID string, projectID int64) (*HarborQuota, error)
 	UpdateProjectQuota(ctx context.Context, registryID string, projectID int64, storageLimit int64) error
+
+	// Garbage Collection
+	// RunGarbageCollection starts a registry-wide GC that frees the storage of deleted artifacts and returns its job ID
+	RunGarbageCollection(ctx context.Context, registryID string, deleteUntagged bool) (int64, error)
+}
+
+// HarborWebhookUsecase handles events pushed by Harbor webhook policies
+type HarborWebhookUsecase interface {
+	// HandleWebhook verifies the auth header against the registry's webhook secret and turns the
+	// payload into notifications, audit entries and published events. Unsupported event types yield no events.
+	HandleWebhook(ctx context.Context, registryID, authorization string, body []byte) ([]*HarborWebhookEvent, error)
 }
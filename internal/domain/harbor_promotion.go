@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// HarborPromotionStatus represents the state of an artifact promotion
+type HarborPromotionStatus string
+
+const (
+	HarborPromotionStatusPendingApproval HarborPromotionStatus = "pending_approval"
+	HarborPromotionStatusCompleted       HarborPromotionStatus = "completed"
+	HarborPromotionStatusRejected        HarborPromotionStatus = "rejected"
+	HarborPromotionStatusFailed          HarborPromotionStatus = "failed"
+)
+
+// HarborPromotionRule promotes artifacts from one Harbor project to the next
+// @Description Promotion rule between two projects of a Harbor registry, e.g. staging -> prod
+type HarborPromotionRule struct {
+	ID             string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()" example:"550e8400-e29b-41d4-a716-446655440000"`
+	RegistryID     string    `json:"registry_id" gorm:"type:uuid;not null;index" validate:"required" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name           string    `json:"name" gorm:"type:varchar(255);not null;uniqueIndex" validate:"required" example:"staging-to-prod"`
+	Description    string    `json:"description" gorm:"type:text" example:"Promote release candidates to production"`
+	SourceProject  string    `json:"source_project" gorm:"type:varchar(255);not null" validate:"required" example:"staging"`
+	TargetProject  string    `json:"target_project" gorm:"type:varchar(255);not null" validate:"required" example:"prod"`
+	Labels         []string  `json:"labels" gorm:"type:jsonb;serializer:json" example:"promoted,prod"`                           // Labels added to the promoted artifact
+	AllowUnscanned bool      `json:"allow_unscanned" gorm:"type:boolean;default:false" example:"false"`                          // Skip the scan requirement
+	MaxSeverity    string    `json:"max_severity" gorm:"type:varchar(20);default:'Medium'" example:"Medium"`                     // Highest scan severity allowed: None, Low, Medium, High, Critical
+	ApprovalRoleID *string   `json:"approval_role_id,omitempty" gorm:"type:uuid" example:"550e8400-e29b-41d4-a716-446655440000"` // Nil promotes without approval
+	IsActive       bool      `json:"is_active" gorm:"type:boolean;default:true" example:"true"`
+	CreatedBy      string    `json:"created_by" gorm:"type:varchar(255)"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime" example:"2024-01-01T00:00:00Z"`
+}
+
+// TableName specifies the table name for HarborPromotionRule model
+func (HarborPromotionRule) TableName() string {
+	return "harbor_promotion_rules"
+}
+
+// HarborPromotion records the promotion of an artifact through a rule
+// @Description Who promoted which artifact digest, when, and with which scan result
+type HarborPromotion struct {
+	ID               string                `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()" example:"550e8400-e29b-41d4-a716-446655440000"`
+	RuleID           string                `json:"rule_id" gorm:"type:uuid;not null;index" example:"550e8400-e29b-41d4-a716-446655440000"`
+	RegistryID       string                `json:"registry_id" gorm:"type:uuid;not null" example:"550e8400-e29b-41d4-a716-446655440000"`
+	SourceRepository string                `json:"source_repository" gorm:"type:varchar(500);not null" example:"staging/api"`
+	TargetRepository string                `json:"target_repository" gorm:"type:varchar(500);not null" example:"prod/api"`
+	Digest           string                `json:"digest" gorm:"type:varchar(255);not null;index" example:"sha256:abcd1234..."`
+	Tags             []string              `json:"tags" gorm:"type:jsonb;serializer:json" example:"v1.2.0"`
+	ScanStatus       string                `json:"scan_status,omitempty" gorm:"type:varchar(50)" example:"Success"`
+	ScanSeverity     string                `json:"scan_severity,omitempty" gorm:"type:varchar(20)" example:"Low"`
+	Status           HarborPromotionStatus `json:"status" gorm:"type:varchar(30);not null;index" example:"completed"`
+	RequestedBy      string                `json:"requested_by" gorm:"type:varchar(255)"`
+	ReviewedBy       *string               `json:"reviewed_by,omitempty" gorm:"type:varchar(255)"`
+	ReviewedAt       *time.Time            `json:"reviewed_at,omitempty"`
+	PromotedAt       *time.Time            `json:"promoted_at,omitempty"`
+	ErrorMessage     string                `json:"error_message,omitempty" gorm:"type:text"`
+	CreatedAt        time.Time             `json:"created_at" gorm:"autoCreateTime" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt        time.Time             `json:"updated_at" gorm:"autoUpdateTime" example:"2024-01-01T00:00:00Z"`
+}
+
+// TableName specifies the table name for HarborPromotion model
+func (HarborPromotion) TableName() string {
+	return "harbor_promotions"
+}
+
+// HarborPromotionRequest asks to promote an artifact of the rule's source project
+type HarborPromotionRequest struct {
+	Repository string `json:"repository" binding:"required" example:"api"`   // Repository name within the source project
+	Reference  string `json:"reference" binding:"required" example:"v1.2.0"` // Tag or digest
+}
+
+// HarborPromotionFilter represents filtering options for promotion queries
+type HarborPromotionFilter struct {
+	RuleID   string                `json:"rule_id,omitempty"`
+	Digest   string                `json:"digest,omitempty"`
+	Status   HarborPromotionStatus `json:"status,omitempty"`
+	Page     int                   `json:"page" validate:"min=1"`
+	PageSize int                   `json:"page_size" validate:"min=1,max=100"`
+}
+
+// HarborPromotionRepository defines data persistence for promotion rules and promotions
+type HarborPromotionRepository interface {
+	CreateRule(ctx context.Context, rule *HarborPromotionRule) error
+	GetRule(ctx context.Context, id string) (*HarborPromotionRule, error)
+	ListRules(ctx context.Context, registryID string) ([]*HarborPromotionRule, error)
+	UpdateRule(ctx context.Context, rule *HarborPromotionRule) error
+	DeleteRule(ctx context.Context, id string) error
+
+	CreatePromotion(ctx context.Context, promotion *HarborPromotion) error
+	GetPromotion(ctx context.Context, id string) (*HarborPromotion, error)
+	UpdatePromotion(ctx context.Context, promotion *HarborPromotion) error
+	ListPromotions(ctx context.Context, filter HarborPromotionFilter) ([]*HarborPromotion, int64, error)
+}
+
+// HarborPromotionUsecase defines the business logic for promoting artifacts between Harbor projects
+type HarborPromotionUsecase interface {
+	// Rule Management
+	CreateRule(ctx context.Context, rule *HarborPromotionRule, userID string) error
+	GetRule(ctx context.Context, id string) (*HarborPromotionRule, error)
+	ListRules(ctx context.Context, registryID string) ([]*HarborPromotionRule, error)
+	UpdateRule(ctx context.Context, rule *HarborPromotionRule) error
+	DeleteRule(ctx context.Context, id string) error
+
+	// Promote copies an artifact to the rule's target project once its scan passes the rule's severity limit. Rules with an
+	// approval role return a pending promotion that is carried out when approved.
+	Promote(ctx context.Context, ruleID string, req HarborPromotionRequest, userID string) (*HarborPromotion, error)
+	// ApprovePromotion requires the approver to hold the rule's approval role and not be the requester
+	ApprovePromotion(ctx context.Context, id, userID string) (*HarborPromotion, error)
+	RejectPromotion(ctx context.Context, id, userID string) (*HarborPromotion, error)
+	GetPromotion(ctx context.Context, id string) (*HarborPromotion, error)
+	ListPromotions(ctx context.Context, filter HarborPromotionFilter) ([]*HarborPromotion, int64, error)
+}
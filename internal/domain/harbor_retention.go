@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// HarborRetentionRunStatus represents the state of a retention run
+type HarborRetentionRunStatus string
+
+const (
+	HarborRetentionRunStatusRunning   HarborRetentionRunStatus = "running"
+	HarborRetentionRunStatusCompleted HarborRetentionRunStatus = "completed"
+	HarborRetentionRunStatusFailed    HarborRetentionRunStatus = "failed"
+)
+
+// HarborRetentionAction is what a retention run does with an artifact
+type HarborRetentionAction string
+
+const (
+	HarborRetentionActionRetain HarborRetentionAction = "retain"
+	HarborRetentionActionDelete HarborRetentionAction = "delete"
+)
+
+// HarborRetentionPolicy prunes the artifacts of a Harbor project on a schedule. Artifacts that are
+// active in a tracked cluster are always retained. A policy only reports what it would delete until it is
+// enforced, which requires a dry run after its last change.
+// @Description Tag retention policy of a Harbor project
+type HarborRetentionPolicy struct {
+	ID                   string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()" example:"550e8400-e29b-41d4-a716-446655440000"`
+	RegistryID           string     `json:"registry_id" gorm:"type:uuid;not null;index" validate:"required" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ProjectName          string     `json:"project_name" gorm:"type:varchar(255);not null" validate:"required" example:"ci"`
+	Name                 string     `json:"name" gorm:"type:varchar(255);not null" validate:"required" example:"prune-ci-builds"`
+	RepositoryPattern    string     `json:"repository_pattern" gorm:"type:varchar(255);default:'*'" example:"*"` // Glob on the repository name within the project; "*" does not cross "/"
+	TagPattern           string     `json:"tag_pattern" gorm:"type:varchar(255);default:'*'" example:"ci-*"`     // Only artifacts whose tags all match are pruned by count
+	KeepLast             int        `json:"keep_last" gorm:"type:int;default:10" example:"10"`                   // Matching artifacts to keep per repository; 0 disables pruning by count
+	KeepDeployed         bool       `json:"keep_deployed" gorm:"type:boolean" example:"true"`                    // Keep artifacts that were ever deployed according to image deployment records
+	UntaggedDays         *int       `json:"untagged_days,omitempty" gorm:"type:int" example:"7"`                 // Delete untagged artifacts older than this; nil keeps them
+	RunGarbageCollection bool       `json:"run_garbage_collection" gorm:"type:boolean" example:"false"`          // Trigger Harbor GC after an enforced run deleted artifacts
+	CronExpression       string     `json:"cron_expression" gorm:"type:varchar(100);not null" validate:"required" example:"0 3 * * *"`
+	Enforced             bool       `json:"enforced" gorm:"type:boolean;default:false" example:"false"` // Set through Enforce; false means scheduled runs are dry runs
+	IsActive             bool       `json:"is_active" gorm:"type:boolean;default:true;index" example:"true"`
+	ConfiguredAt         time.Time  `json:"configured_at" example:"2024-01-01T00:00:00Z"` // Last change of the rules; later dry runs allow enforcing
+	LastRunAt            *time.Time `json:"last_run_at,omitempty"`
+	NextRunAt            *time.Time `json:"next_run_at,omitempty" gorm:"index"`
+	CreatedBy            string     `json:"created_by" gorm:"type:varchar(255)"`
+	CreatedAt            time.Time  `json:"created_at" gorm:"autoCreateTime" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt            time.Time  `json:"updated_at" gorm:"autoUpdateTime" example:"2024-01-01T00:00:00Z"`
+}
+
+// TableName specifies the table name for HarborRetentionPolicy model
+func (HarborRetentionPolicy) TableName() string {
+	return "harbor_retention_policies"
+}
+
+// HarborRetentionItem is the decision a retention run made about one artifact
+type HarborRetentionItem struct {
+	Repository string                `json:"repository" example:"ci/api"`
+	Digest     string                `json:"digest" example:"sha256:abcd1234..."`
+	Tags       []string              `json:"tags"`
+	PushTime   time.Time             `json:"push_time" example:"2024-01-01T00:00:00Z"`
+	Action     HarborRetentionAction `json:"action" example:"delete"`
+	Reason     string                `json:"reason" example:"beyond the last 10 artifacts matching ci-*"`
+	Error      string                `json:"error,omitempty"` // Set when the deletion failed
+}
+
+// HarborRetentionRun is the report of a retention run
+// @Description Artifacts a retention run deleted, or would delete when dry-run
+type HarborRetentionRun struct {
+	ID            string                   `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()" example:"550e8400-e29b-41d4-a716-446655440000"`
+	PolicyID      string                   `json:"policy_id" gorm:"type:uuid;not null;index" example:"550e8400-e29b-41d4-a716-446655440000"`
+	DryRun        bool                     `json:"dry_run" example:"true"`
+	Status        HarborRetentionRunStatus `json:"status" gorm:"type:varchar(20);not null" example:"completed"`
+	TriggeredBy   string                   `json:"triggered_by" gorm:"type:varchar(255)" example:"scheduler"`
+	Items         []HarborRetentionItem    `json:"items,omitempty" gorm:"type:jsonb;serializer:json"`
+	RetainedCount int                      `json:"retained_count" example:"25"`
+	DeleteCount   int                      `json:"delete_count" example:"40"` // Deleted, or to be deleted when dry-run
+	FailedCount   int                      `json:"failed_count" example:"0"`
+	GCTriggered   bool                     `json:"gc_triggered" example:"false"`
+	ErrorMessage  string                   `json:"error_message,omitempty" gorm:"type:text"`
+	StartedAt     time.Time                `json:"started_at" example:"2024-01-01T00:00:00Z"`
+	FinishedAt    *time.Time               `json:"finished_at,omitempty"`
+}
+
+// TableName specifies the table name for HarborRetentionRun model
+func (HarborRetentionRun) TableName() string {
+	return "harbor_retention_runs"
+}
+
+// HarborRetentionRepository defines data persistence for retention policies and their runs
+type HarborRetentionRepository interface {
+	CreatePolicy(ctx context.Context, policy *HarborRetentionPolicy) error
+	GetPolicy(ctx context.Context, id string) (*HarborRetentionPolicy, error)
+	ListPolicies(ctx context.Context, registryID string) ([]*HarborRetentionPolicy, error)
+	// ListDuePolicies returns active policies whose next run is at or before now
+	ListDuePolicies(ctx context.Context, now time.Time) ([]*HarborRetentionPolicy, error)
+	UpdatePolicy(ctx context.Context, policy *HarborRetentionPolicy) error
+	// RecordRun stores the last and next run times without touching the rest of the policy
+	RecordRun(ctx context.Context, id string, lastRunAt, nextRunAt time.Time) error
+	DeletePolicy(ctx context.Context, id string) error
+
+	CreateRun(ctx context.Context, run *HarborRetentionRun) error
+	GetRun(ctx context.Context, id string) (*HarborRetentionRun, error)
+	UpdateRun(ctx context.Context, run *HarborRetentionRun) error
+	// ListRuns returns the runs of a policy without their items, newest first
+	ListRuns(ctx context.Context, policyID string, limit int) ([]*HarborRetentionRun, error)
+}
+
+// HarborRetentionUsecase defines the business logic for Harbor tag retention
+type HarborRetentionUsecase interface {
+	// Policy Management
+	CreatePolicy(ctx context.Context, policy *HarborRetentionPolicy, userID string) error
+	GetPolicy(ctx context.Context, id string) (*HarborRetentionPolicy, error)
+	ListPolicies(ctx context.Context, registryID string) ([]*HarborRetentionPolicy, error)
+	// UpdatePolicy replaces a policy; a changed policy must be dry-run and enforced again
+	UpdatePolicy(ctx context.Context, policy *HarborRetentionPolicy) error
+	DeletePolicy(ctx context.Context, id string) error
+	// EnforcePolicy lets scheduled runs delete artifacts once a dry run completed after the last change
+	EnforcePolicy(ctx context.Context, id string) (*HarborRetentionPolicy, error)
+
+	// RunPolicy starts a run in the background and returns its report, which is updated as it progresses.
+	// Runs that delete require an enforced policy.
+	RunPolicy(ctx context.Context, id string, dryRun bool, userID string) (*HarborRetentionRun, error)
+	GetRun(ctx context.Context, id string) (*HarborRetentionRun, error)
+	ListRuns(ctx context.Context, policyID string) ([]*HarborRetentionRun, error)
+
+	// StartScheduler starts the background job that runs due policies
+	StartScheduler(ctx context.Context)
+}
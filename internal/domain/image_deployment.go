@@ -5,18 +5,27 @@ import (
 	"time"
 )
 
-// ImageDeployment represents a deployment of a Harbor image to a Kubernetes cluster
+// Image deployment platforms
+const (
+	ImageDeploymentPlatformKubernetes = "kubernetes"
+	ImageDeploymentPlatformDocker     = "docker"
+)
+
+// ImageDeployment represents a deployment of a Harbor image to a Kubernetes cluster or Docker host
 type ImageDeployment struct {
 	ID              string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	ClusterID       string    `json:"cluster_id" gorm:"type:uuid;not null"`
-	Namespace       string    `json:"namespace" gorm:"type:varchar(255);not null"`
+	Platform        string    `json:"platform" gorm:"type:varchar(20);default:'kubernetes'"` // kubernetes or docker
+	ClusterID       string    `json:"cluster_id" gorm:"type:uuid;not null"`                  // Docker host ID for the docker platform
+	Namespace       string    `json:"namespace" gorm:"type:varchar(255);not null"`           // Compose project for the docker platform
+	WorkloadKind    string    `json:"workload_kind,omitempty" gorm:"type:varchar(50)"`       // Deployment, StatefulSet, DaemonSet or Container
 	DeploymentName  string    `json:"deployment_name" gorm:"type:varchar(255);not null"`
 	ContainerName   string    `json:"container_name" gorm:"type:varchar(255);not null"`
 	ImageRepository string    `json:"image_repository" gorm:"type:varchar(500);not null"` // e.g., harbor.example.com/project/image
 	ImageTag        string    `json:"image_tag" gorm:"type:varchar(255);not null"`        // e.g., v1.0.0
+	ImageDigest     string    `json:"image_digest,omitempty" gorm:"type:varchar(255)"`    // Digest the tag resolved to when deployed
 	DeployedAt      time.Time `json:"deployed_at" gorm:"autoCreateTime"`
 	DeployedBy      string    `json:"deployed_by" gorm:"type:varchar(255)"` // User who triggered deployment
-	Status          string    `json:"status" gorm:"type:varchar(50)"`       // active, rolled_back, replaced
+	Status          string    `json:"status" gorm:"type:varchar(50)"`       // active, rolled_back, replaced, removed
 }
 
 // ImageDeploymentHistory tracks the history of image deployments
@@ -30,19 +39,98 @@ type ImageDeploymentHistory struct {
 	Reason       string    `json:"reason" gorm:"type:text"`
 }
 
+// TableName overrides the table name
+func (ImageDeploymentHistory) TableName() string {
+	return "image_deployment_history"
+}
+
+// ImageObservation is an image the deployment tracker saw in a workload or container
+type ImageObservation struct {
+	Platform     string
+	ClusterID    string
+	Namespace    string
+	WorkloadKind string
+	WorkloadName string
+	Container    string
+	Image        string
+	Digest       string // Empty when the tag could not be resolved
+	ChangedBy    string
+	Reason       string
+}
+
+// ImageSearchQuery selects the images to locate. Repository may omit the registry host, Tag requires a
+// Repository and CVEID matches the artifacts Harbor found the CVE in
+type ImageSearchQuery struct {
+	Repository      string `json:"repository,omitempty" form:"repository"`
+	Tag             string `json:"tag,omitempty" form:"tag"`
+	Digest          string `json:"digest,omitempty" form:"digest"`
+	CVEID           string `json:"cve_id,omitempty" form:"cve_id"`
+	IncludeInactive bool   `json:"include_inactive,omitempty" form:"include_inactive"` // Also return replaced, rolled back and removed deployments
+}
+
+// Image location sources
+const (
+	ImageLocationSourceLive   = "live"   // Seen running, enriched from its deployment record when there is one
+	ImageLocationSourceRecord = "record" // Only known from a deployment record
+)
+
+// ImageLocation is a workload running, or having run, a searched image
+type ImageLocation struct {
+	Platform        string    `json:"platform"`     // kubernetes or docker
+	ClusterID       string    `json:"cluster_id"`   // Docker host ID for the docker platform
+	ClusterName     string    `json:"cluster_name"` // Docker host name for the docker platform
+	EnvironmentID   string    `json:"environment_id,omitempty"`
+	EnvironmentName string    `json:"environment_name,omitempty"`
+	Namespace       string    `json:"namespace"` // Compose project for the docker platform
+	WorkloadKind    string    `json:"workload_kind"`
+	WorkloadName    string    `json:"workload_name"`
+	Container       string    `json:"container"`
+	Image           string    `json:"image"`
+	ImageDigest     string    `json:"image_digest,omitempty"`
+	Replicas        int       `json:"replicas"` // Running pods or containers; 0 for record-only locations
+	Owner           string    `json:"owner,omitempty"`
+	DeployedAt      time.Time `json:"deployed_at"`
+	Status          string    `json:"status"` // running for live locations, else the deployment status
+	Source          string    `json:"source"` // live or record
+}
+
+// ImageSearchResult lists where the searched images are deployed
+type ImageSearchResult struct {
+	Query      ImageSearchQuery `json:"query"`
+	Digests    []string         `json:"digests,omitempty"` // Digests a CVE search resolved to
+	Locations  []*ImageLocation `json:"locations"`
+	Warnings   []string         `json:"warnings,omitempty"` // Clusters, hosts and registries that could not be searched
+	SearchedAt time.Time        `json:"searched_at"`
+}
+
 // ImageDeploymentRepository defines operations for managing image deployments
 type ImageDeploymentRepository interface {
 	Create(ctx context.Context, deployment *ImageDeployment) error
 	GetByID(ctx context.Context, id string) (*ImageDeployment, error)
 	List(ctx context.Context, clusterID, namespace string) ([]*ImageDeployment, error)
+	// ListByImageRepository returns the deployments of a repository in any cluster, with or without registry host prefix
+	ListByImageRepository(ctx context.Context, repository string) ([]*ImageDeployment, error)
+	ListByImageDigests(ctx context.Context, digests []string) ([]*ImageDeployment, error)
 	GetActiveDeployment(ctx context.Context, clusterID, namespace, deploymentName, containerName string) (*ImageDeployment, error)
 	UpdateStatus(ctx context.Context, id, status string) error
+	UpdateDigest(ctx context.Context, id, digest string) error
+	CreateHistory(ctx context.Context, history *ImageDeploymentHistory) error
+	ListHistory(ctx context.Context, deploymentIDs []string) ([]*ImageDeploymentHistory, error)
 }
 
 // ImageDeploymentUsecase defines business logic for image deployments
 type ImageDeploymentUsecase interface {
-	TrackDeployment(ctx context.Context, clusterID, namespace, deploymentName, containerName, imageRepo, imageTag, user string) error
+	// TrackDeployment rejects images that violate the environment's vulnerability policy with a *VulnerabilityGateError
+	TrackDeployment(ctx context.Context, clusterID, namespace, deploymentName, containerName, imageRepo, imageTag, user string) (*VulnerabilityGateReport, error)
 	GetDeploymentHistory(ctx context.Context, clusterID, namespace, deploymentName string) ([]*ImageDeployment, error)
 	GetCurrentDeployments(ctx context.Context, clusterID string) ([]*ImageDeployment, error)
 	SyncDeploymentsFromK8s(ctx context.Context, clusterID string) error
+	// RecordRolloutAction records a workload lifecycle action; containerImages maps container name to its image after the action
+	RecordRolloutAction(ctx context.Context, clusterID, namespace, deploymentName string, containerImages map[string]string, action, user, reason string) error
+	GetChangeHistory(ctx context.Context, clusterID, namespace, deploymentName string) ([]*ImageDeploymentHistory, error)
+	// RecordObservedImage records an image seen by the deployment tracker unless it is already the active one.
+	// Observed images are not gated; they already run.
+	RecordObservedImage(ctx context.Context, observation ImageObservation) error
+	// RecordWorkloadRemoved marks the active deployments of a deleted workload as removed
+	RecordWorkloadRemoved(ctx context.Context, clusterID, namespace, workloadName, user string) error
 }
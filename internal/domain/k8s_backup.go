@@ -44,7 +44,9 @@ type K8sBackup struct {
 	Namespace     string              `json:"namespace" gorm:"type:varchar(255)"` // Optional: if backing up specific namespace
 	ResourceCount int                 `json:"resource_count" gorm:"type:int"`
 	SizeBytes     int64               `json:"size_bytes" gorm:"type:bigint"`
-	Status        string              `json:"status" gorm:"type:varchar(50)"` // pending, completed, failed
+	Status        string              `json:"status" gorm:"type:varchar(50)"`               // pending, completed, failed
+	ScheduleID    *string             `json:"schedule_id,omitempty" gorm:"type:uuid;index"` // Set when created by a backup schedule
+	CreatedBy     string              `json:"created_by" gorm:"type:varchar(255)"`
 	CreatedAt     time.Time           `json:"created_at" gorm:"autoCreateTime"`
 	Resources     []K8sBackupResource `json:"resources" gorm:"foreignKey:BackupID"`
 }
@@ -60,11 +62,80 @@ type K8sBackupResource struct {
 	CreatedAt time.Time       `json:"created_at" gorm:"autoCreateTime"`
 }
 
+// K8sBackupSchedule runs namespace backups on a cron expression and keeps the last N of them
+type K8sBackupSchedule struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	ClusterID      string     `json:"cluster_id" gorm:"type:uuid;not null;index"`
+	Namespace      string     `json:"namespace" gorm:"type:varchar(255)"` // Empty means all namespaces
+	Name           string     `json:"name" gorm:"type:varchar(255);not null" validate:"required"`
+	CronExpression string     `json:"cron_expression" gorm:"type:varchar(100);not null" validate:"required" example:"0 2 * * *"`
+	KeepLast       int        `json:"keep_last" gorm:"type:int" example:"7"` // 0 keeps every backup
+	IsActive       bool       `json:"is_active" gorm:"type:boolean;index"`
+	LastRunAt      *time.Time `json:"last_run_at,omitempty"`
+	NextRunAt      *time.Time `json:"next_run_at,omitempty" gorm:"index"`
+	LastStatus     string     `json:"last_status,omitempty" gorm:"type:varchar(50)"` // completed, failed
+	LastError      string     `json:"last_error,omitempty" gorm:"type:text"`
+	CreatedBy      string     `json:"created_by" gorm:"type:varchar(255)"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for K8sBackupSchedule model
+func (K8sBackupSchedule) TableName() string {
+	return "k8s_backup_schedules"
+}
+
+// K8sBackupScheduleRequest represents a request to create or update a backup schedule
+type K8sBackupScheduleRequest struct {
+	Name           string `json:"name" binding:"required"`
+	Namespace      string `json:"namespace,omitempty"` // Empty means all namespaces
+	CronExpression string `json:"cron_expression" binding:"required" example:"0 2 * * *"`
+	KeepLast       *int   `json:"keep_last,omitempty" example:"7"` // Defaults to 7; 0 keeps every backup
+	IsActive       *bool  `json:"is_active,omitempty"`             // Defaults to true
+}
+
+// K8sBackupResourceRef identifies a resource inside a backup
+type K8sBackupResourceRef struct {
+	Kind      K8sResourceType `json:"kind"`
+	Namespace string          `json:"namespace"`
+	Name      string          `json:"name"`
+}
+
+// K8sBackupResourceChange describes a resource whose manifest differs between two backups
+type K8sBackupResourceChange struct {
+	K8sBackupResourceRef
+	Diff string `json:"diff"` // Unified diff of the YAML manifests
+}
+
+// K8sBackupDiff is the resource-by-resource comparison of two backups
+type K8sBackupDiff struct {
+	FromBackupID string                    `json:"from_backup_id"`
+	ToBackupID   string                    `json:"to_backup_id"`
+	FromTime     time.Time                 `json:"from_time"`
+	ToTime       time.Time                 `json:"to_time"`
+	Added        []K8sBackupResourceRef    `json:"added"`
+	Removed      []K8sBackupResourceRef    `json:"removed"`
+	Changed      []K8sBackupResourceChange `json:"changed"`
+	Unchanged    int                       `json:"unchanged"`
+}
+
 // K8sBackupRepository defines operations for managing backups
 type K8sBackupRepository interface {
 	Create(ctx context.Context, backup *K8sBackup) error
 	GetByID(ctx context.Context, id string) (*K8sBackup, error)
 	List(ctx context.Context, clusterID, namespace string) ([]*K8sBackup, error)
+	ListBySchedule(ctx context.Context, scheduleID string) ([]*K8sBackup, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// K8sBackupScheduleRepository defines operations for managing backup schedules
+type K8sBackupScheduleRepository interface {
+	Create(ctx context.Context, schedule *K8sBackupSchedule) error
+	GetByID(ctx context.Context, id string) (*K8sBackupSchedule, error)
+	List(ctx context.Context, clusterID string) ([]*K8sBackupSchedule, error)
+	// ListDue returns active schedules whose next run is at or before now
+	ListDue(ctx context.Context, now time.Time) ([]*K8sBackupSchedule, error)
+	Update(ctx context.Context, schedule *K8sBackupSchedule) error
 	Delete(ctx context.Context, id string) error
 }
 
@@ -75,4 +146,14 @@ type K8sBackupUsecase interface {
 	ListBackups(ctx context.Context, clusterID, namespace string) ([]*K8sBackup, error)
 	GetBackup(ctx context.Context, id string) (*K8sBackup, error)
 	DeleteBackup(ctx context.Context, id string) error
+	DiffBackups(ctx context.Context, fromID, toID string) (*K8sBackupDiff, error)
+
+	// Schedules
+	CreateSchedule(ctx context.Context, clusterID string, req K8sBackupScheduleRequest, user string) (*K8sBackupSchedule, error)
+	GetSchedule(ctx context.Context, id string) (*K8sBackupSchedule, error)
+	ListSchedules(ctx context.Context, clusterID string) ([]*K8sBackupSchedule, error)
+	UpdateSchedule(ctx context.Context, id string, req K8sBackupScheduleRequest) (*K8sBackupSchedule, error)
+	DeleteSchedule(ctx context.Context, id string) error
+	RunSchedule(ctx context.Context, id string) (*K8sBackup, error)
+	StartScheduler(ctx context.Context)
 }
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// K8sResourceSummary accounts CPU (millicores), memory (bytes) and pods of a node, namespace or cluster
+type K8sResourceSummary struct {
+	CPUAllocatable    int64  `json:"cpu_allocatable,omitempty" example:"8000"`
+	CPURequests       int64  `json:"cpu_requests" example:"2500"`
+	CPULimits         int64  `json:"cpu_limits" example:"6000"`
+	CPUUsage          *int64 `json:"cpu_usage,omitempty" example:"1200"` // Only when metrics-server is available
+	MemoryAllocatable int64  `json:"memory_allocatable,omitempty" example:"33554432000"`
+	MemoryRequests    int64  `json:"memory_requests" example:"4294967296"`
+	MemoryLimits      int64  `json:"memory_limits" example:"8589934592"`
+	MemoryUsage       *int64 `json:"memory_usage,omitempty" example:"3221225472"` // Only when metrics-server is available
+	Pods              int    `json:"pods" example:"42"`
+	PodCapacity       int64  `json:"pod_capacity,omitempty" example:"110"`
+}
+
+// K8sTaint represents a node taint
+type K8sTaint struct {
+	Key    string `json:"key" example:"node-role.kubernetes.io/control-plane"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect" example:"NoSchedule"`
+}
+
+// K8sNodeCapacity is the capacity view of a single node
+type K8sNodeCapacity struct {
+	Name             string             `json:"name" example:"node-1"`
+	Status           string             `json:"status" example:"Ready"`
+	Roles            []string           `json:"roles" example:"control-plane"`
+	Unschedulable    bool               `json:"unschedulable"`
+	KubeletVersion   string             `json:"kubelet_version" example:"v1.30.2"`
+	ContainerRuntime string             `json:"container_runtime" example:"containerd://1.7.2"`
+	Taints           []K8sTaint         `json:"taints"`
+	Conditions       []K8sCondition     `json:"conditions"`
+	Resources        K8sResourceSummary `json:"resources"`
+}
+
+// K8sNamespaceCapacity is the resource consumption of a namespace
+type K8sNamespaceCapacity struct {
+	Name      string             `json:"name" example:"production"`
+	Resources K8sResourceSummary `json:"resources"`
+}
+
+// K8sClusterOverview is the inventory and capacity overview of a cluster
+// @Description Requested vs. allocatable resources per node and namespace
+type K8sClusterOverview struct {
+	ClusterID        string                 `json:"cluster_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	NodeCount        int                    `json:"node_count" example:"5"`
+	ReadyNodes       int                    `json:"ready_nodes" example:"5"`
+	KubeletVersions  map[string]int         `json:"kubelet_versions"` // Kubelet version -> node count
+	MetricsAvailable bool                   `json:"metrics_available"`
+	Totals           K8sResourceSummary     `json:"totals"`
+	Nodes            []K8sNodeCapacity      `json:"nodes"`
+	Namespaces       []K8sNamespaceCapacity `json:"namespaces"`
+	GeneratedAt      time.Time              `json:"generated_at"`
+}
+
+// K8sCapacityUsecase computes cluster capacity overviews
+type K8sCapacityUsecase interface {
+	// GetOverview returns the cached overview of a cluster; refresh bypasses the cache
+	GetOverview(ctx context.Context, clusterID string, refresh bool) (*K8sClusterOverview, error)
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// HelmRelease represents a revision of a Helm release
+type HelmRelease struct {
+	Name         string    `json:"name" example:"ingress-nginx"`
+	Namespace    string    `json:"namespace" example:"ingress"`
+	Revision     int       `json:"revision" example:"4"`
+	Status       string    `json:"status" example:"deployed"` // deployed, failed, superseded, uninstalled, pending-install, ...
+	Chart        string    `json:"chart" example:"ingress-nginx"`
+	ChartVersion string    `json:"chart_version" example:"4.11.2"`
+	AppVersion   string    `json:"app_version,omitempty" example:"1.11.2"`
+	Description  string    `json:"description,omitempty" example:"Upgrade complete"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// HelmReleaseDetail is a release revision with its values and rendered manifest
+type HelmReleaseDetail struct {
+	HelmRelease
+	Values   map[string]interface{} `json:"values"` // User-supplied values, or all computed values when requested
+	Manifest string                 `json:"manifest"`
+	Notes    string                 `json:"notes,omitempty"`
+}
+
+// HelmChartSource selects where a chart is loaded from: an archive previously uploaded
+// to object storage, a chart repository, or an OCI registry (oci:// chart reference)
+type HelmChartSource struct {
+	ArchivePath string `json:"archive_path,omitempty" example:"helm_chart/0/1718000000_ingress-nginx-4.11.2.tgz"`
+	RepoURL     string `json:"repo_url,omitempty" example:"https://kubernetes.github.io/ingress-nginx"`
+	Chart       string `json:"chart,omitempty" example:"ingress-nginx"`
+	Version     string `json:"version,omitempty" example:"4.11.2"`
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty"`
+}
+
+// HelmInstallRequest represents an install or upgrade of a release
+type HelmInstallRequest struct {
+	HelmChartSource
+	ReleaseName     string                 `json:"release_name" example:"ingress-nginx"` // Taken from the path on upgrade
+	Namespace       string                 `json:"namespace" example:"ingress"`          // Taken from the path on upgrade
+	Values          map[string]interface{} `json:"values,omitempty"`
+	ValuesYAML      string                 `json:"values_yaml,omitempty"` // Merged under Values
+	CreateNamespace bool                   `json:"create_namespace,omitempty"`
+	ReuseValues     bool                   `json:"reuse_values,omitempty"` // Upgrade only
+	Wait            bool                   `json:"wait,omitempty"`
+	Atomic          bool                   `json:"atomic,omitempty"`
+	TimeoutSeconds  int                    `json:"timeout_seconds,omitempty" example:"300"`
+	DryRun          bool                   `json:"dry_run,omitempty"`
+	Description     string                 `json:"description,omitempty"`
+}
+
+// HelmRollbackRequest represents a rollback to a release revision
+type HelmRollbackRequest struct {
+	Revision       int  `json:"revision" example:"3"` // 0 rolls back to the previous revision
+	Wait           bool `json:"wait,omitempty"`
+	TimeoutSeconds int  `json:"timeout_seconds,omitempty" example:"300"`
+}
+
+// K8sHelmUsecase manages Helm releases on registered clusters
+type K8sHelmUsecase interface {
+	// ListReleases lists releases in a namespace, or in all namespaces when namespace is empty
+	ListReleases(ctx context.Context, clusterID, namespace string) ([]*HelmRelease, error)
+	// GetRelease returns a revision of a release; revision 0 is the latest
+	GetRelease(ctx context.Context, clusterID, namespace, name string, revision int, allValues bool) (*HelmReleaseDetail, error)
+	GetHistory(ctx context.Context, clusterID, namespace, name string) ([]*HelmRelease, error)
+	Install(ctx context.Context, clusterID string, req HelmInstallRequest) (*HelmReleaseDetail, error)
+	Upgrade(ctx context.Context, clusterID, namespace, name string, req HelmInstallRequest) (*HelmReleaseDetail, error)
+	Rollback(ctx context.Context, clusterID, namespace, name string, req HelmRollbackRequest) (*HelmRelease, error)
+	Uninstall(ctx context.Context, clusterID, namespace, name string, keepHistory bool) error
+	// UploadChart stores a packaged chart in object storage and returns its path for HelmChartSource.ArchivePath
+	UploadChart(ctx context.Context, filename string, content io.Reader) (string, error)
+}
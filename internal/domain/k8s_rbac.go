@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// K8sRBACRule is a Kubernetes policy rule derived from platform grants
+type K8sRBACRule struct {
+	APIGroups []string `json:"api_groups" example:"apps"`
+	Resources []string `json:"resources" example:"deployments"`
+	Verbs     []string `json:"verbs" example:"get,list,watch"`
+}
+
+// K8sRBACBinding is the access a user gets in a cluster (empty namespace) or a namespace
+type K8sRBACBinding struct {
+	UserID    string        `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Username  string        `json:"username" example:"john.doe"`
+	Subject   string        `json:"subject" example:"einfra:john.doe"` // Kubernetes user name
+	Namespace string        `json:"namespace,omitempty" example:"production"`
+	Rules     []K8sRBACRule `json:"rules"`
+	Sources   []string      `json:"sources"` // Grants the rules come from, e.g. "role:developer"
+}
+
+// K8sRBACSyncResult reports the outcome of syncing platform grants to a cluster
+type K8sRBACSyncResult struct {
+	ClusterID string           `json:"cluster_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	DryRun    bool             `json:"dry_run"`
+	Bindings  []K8sRBACBinding `json:"bindings"`
+	Created   []string         `json:"created"`
+	Updated   []string         `json:"updated"`
+	Deleted   []string         `json:"deleted"`
+	Errors    []string         `json:"errors,omitempty"`
+	SyncedAt  time.Time        `json:"synced_at"`
+}
+
+// K8sRBACUsecase mirrors platform grants on clusters and namespaces into Kubernetes RBAC
+type K8sRBACUsecase interface {
+	// Sync reconciles the managed Roles/RoleBindings of a cluster; with dryRun only the desired state is computed
+	Sync(ctx context.Context, clusterID string, dryRun bool) (*K8sRBACSyncResult, error)
+	SyncAll(ctx context.Context) error
+	StartSync(ctx context.Context)
+}
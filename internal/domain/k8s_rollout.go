@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Rollout actions recorded in the image deployment history
+const (
+	K8sRolloutActionRollback = "rollback"
+	K8sRolloutActionRestart  = "restart"
+	K8sRolloutActionPause    = "pause"
+	K8sRolloutActionResume   = "resume"
+)
+
+// K8sRolloutRevision represents one ReplicaSet revision of a Deployment
+type K8sRolloutRevision struct {
+	Revision    int64             `json:"revision" example:"3"`
+	ReplicaSet  string            `json:"replica_set" example:"nginx-deployment-7c5ddbdf54"`
+	Images      map[string]string `json:"images"` // Container name -> image
+	ChangeCause string            `json:"change_cause,omitempty"`
+	Replicas    int32             `json:"replicas" example:"3"`
+	Current     bool              `json:"current"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Diff        string            `json:"diff,omitempty"` // Unified diff of the pod template against the previous revision
+}
+
+// K8sRolloutStatus represents the progress of a Deployment rollout
+type K8sRolloutStatus struct {
+	Namespace         string `json:"namespace"`
+	Name              string `json:"name"`
+	Revision          int64  `json:"revision"`
+	Paused            bool   `json:"paused"`
+	Complete          bool   `json:"complete"`
+	Failed            bool   `json:"failed"`
+	TimedOut          bool   `json:"timed_out"`
+	Message           string `json:"message" example:"deployment \"nginx\" successfully rolled out"`
+	Replicas          int32  `json:"replicas"`
+	UpdatedReplicas   int32  `json:"updated_replicas"`
+	AvailableReplicas int32  `json:"available_replicas"`
+}
+
+// K8sRollbackRequest represents a rollback to a Deployment revision
+type K8sRollbackRequest struct {
+	Revision int64  `json:"revision" example:"2"` // 0 rolls back to the previous revision
+	Reason   string `json:"reason,omitempty"`
+}
+
+// K8sRolloutUsecase manages the lifecycle of Deployment rollouts
+type K8sRolloutUsecase interface {
+	GetHistory(ctx context.Context, clusterID, namespace, name string) ([]*K8sRolloutRevision, error)
+	Rollback(ctx context.Context, clusterID, namespace, name string, req K8sRollbackRequest, user string) (*K8sRolloutRevision, error)
+	Restart(ctx context.Context, clusterID, namespace, name, user string) error
+	Pause(ctx context.Context, clusterID, namespace, name, user string) error
+	Resume(ctx context.Context, clusterID, namespace, name, user string) error
+	// GetStatus returns the rollout status; with a positive timeout it waits until the rollout completes, fails or times out
+	GetStatus(ctx context.Context, clusterID, namespace, name string, timeout time.Duration) (*K8sRolloutStatus, error)
+}
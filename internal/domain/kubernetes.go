@@ -2,24 +2,31 @@ package domain
 
 import (
 	"context"
+	"errors"
+	"io"
 	"time"
 )
 
+// ErrK8sNamespaceForbidden is returned when a user is not allowed to access a namespace
+var ErrK8sNamespaceForbidden = errors.New("access to namespace is forbidden")
+
 // K8sCluster represents a Kubernetes cluster
 // @Description Kubernetes cluster configuration and connection details
 type K8sCluster struct {
-	ID          string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()" example:"550e8400-e29b-41d4-a716-446655440000"`
-	Name        string     `json:"name" gorm:"type:varchar(255);not null;uniqueIndex" validate:"required,min=3,max=255" example:"prod-k8s-cluster"`
-	Description string     `json:"description" gorm:"type:text" example:"Production Kubernetes cluster"`
-	APIServer   string     `json:"api_server" gorm:"type:varchar(500);not null" validate:"required,url" example:"https://k8s.example.com:6443"`
-	Version     string     `json:"version" gorm:"type:varchar(50)" example:"v1.28.0"`
-	Provider    string     `json:"provider" gorm:"type:varchar(100)" example:"EKS"` // EKS, GKE, AKS, self-hosted
-	Region      string     `json:"region" gorm:"type:varchar(100)" example:"us-east-1"`
-	ConfigPath  string     `json:"config_path" gorm:"type:varchar(500)" example:"/etc/k8s/config"`
-	IsActive    bool       `json:"is_active" gorm:"type:boolean;default:true;index" example:"true"`
-	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime" example:"2024-01-01T00:00:00Z"`
-	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime" example:"2024-01-01T00:00:00Z"`
-	DeletedAt   *time.Time `json:"deleted_at,omitempty" gorm:"index" swaggertype:"string" example:"2024-01-01T00:00:00Z"`
+	ID               string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name             string     `json:"name" gorm:"type:varchar(255);not null;uniqueIndex" validate:"required,min=3,max=255" example:"prod-k8s-cluster"`
+	Description      string     `json:"description" gorm:"type:text" example:"Production Kubernetes cluster"`
+	APIServer        string     `json:"api_server" gorm:"type:varchar(500);not null" validate:"required,url" example:"https://k8s.example.com:6443"`
+	Version          string     `json:"version" gorm:"type:varchar(50)" example:"v1.28.0"`
+	Provider         string     `json:"provider" gorm:"type:varchar(100)" example:"EKS"` // EKS, GKE, AKS, self-hosted
+	Region           string     `json:"region" gorm:"type:varchar(100)" example:"us-east-1"`
+	ConfigPath       string     `json:"config_path" gorm:"type:varchar(500)" example:"/etc/k8s/config"`
+	EnvironmentID    *string    `json:"environment_id,omitempty" gorm:"type:uuid;index" example:"550e8400-e29b-41d4-a716-446655440000"` // Environment roles in this environment apply to the cluster
+	ImpersonateUsers bool       `json:"impersonate_users" gorm:"type:boolean;default:false" example:"false"`                            // Calls made on a user's behalf impersonate that user
+	IsActive         bool       `json:"is_active" gorm:"type:boolean;default:true;index" example:"true"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"autoCreateTime" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt        time.Time  `json:"updated_at" gorm:"autoUpdateTime" example:"2024-01-01T00:00:00Z"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty" gorm:"index" swaggertype:"string" example:"2024-01-01T00:00:00Z"`
 }
 
 // TableName specifies the table name for K8sCluster model
@@ -254,6 +261,109 @@ type K8sNode struct {
 	CreatedAt        time.Time         `json:"created_at" example:"2024-01-01T00:00:00Z"`
 }
 
+// K8sApplyRequest represents a server-side apply of arbitrary manifests
+// @Description Multi-document YAML or JSON manifests applied with server-side apply
+type K8sApplyRequest struct {
+	Manifest     string `json:"manifest" binding:"required"`              // Multi-document YAML or JSON
+	Namespace    string `json:"namespace,omitempty" example:"default"`    // Used for namespaced objects without a namespace
+	FieldManager string `json:"field_manager,omitempty" example:"einfra"` // Defaults to "einfra"
+	Force        bool   `json:"force,omitempty" example:"false"`          // Take over fields owned by other managers
+	DryRun       bool   `json:"dry_run,omitempty" example:"false"`        // Server-side dry run
+}
+
+// K8sApplyObjectResult is the outcome of applying a single object
+// @Description Result of applying one object from a manifest
+type K8sApplyObjectResult struct {
+	APIVersion string                 `json:"api_version" example:"apps/v1"`
+	Kind       string                 `json:"kind" example:"Deployment"`
+	Namespace  string                 `json:"namespace,omitempty" example:"production"`
+	Name       string                 `json:"name" example:"nginx-deployment"`
+	Action     string                 `json:"action" example:"configured"` // created, configured, unchanged, failed
+	Error      string                 `json:"error,omitempty"`
+	Diff       string                 `json:"diff,omitempty"`   // Unified diff against live state (dry run only)
+	Object     map[string]interface{} `json:"object,omitempty"` // Resulting object (dry run only)
+}
+
+// K8sApplyResult is the outcome of applying a manifest
+// @Description Per-object results of a manifest apply
+type K8sApplyResult struct {
+	ClusterID    string                 `json:"cluster_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	FieldManager string                 `json:"field_manager" example:"einfra"`
+	DryRun       bool                   `json:"dry_run" example:"false"`
+	Succeeded    int                    `json:"succeeded" example:"3"`
+	Failed       int                    `json:"failed" example:"0"`
+	Objects      []K8sApplyObjectResult `json:"objects"`
+
+	VulnerabilityGate *VulnerabilityGateReport `json:"vulnerability_gate,omitempty"` // Set when the cluster's environment has a vulnerability policy
+}
+
+// K8sPodLogOptions selects the pods and containers to read logs from
+type K8sPodLogOptions struct {
+	PodName       string `json:"pod_name,omitempty"`       // Single pod; when empty LabelSelector selects the pods
+	Container     string `json:"container,omitempty"`      // Empty streams all containers of each pod
+	LabelSelector string `json:"label_selector,omitempty"` // e.g. "app=web"
+	Follow        bool   `json:"follow"`
+	Previous      bool   `json:"previous"` // Logs of the previous, terminated container instance
+	TailLines     int64  `json:"tail_lines,omitempty"`
+	SinceSeconds  int64  `json:"since_seconds,omitempty"`
+}
+
+// K8sPodLogEntry is a single log line of a pod container
+type K8sPodLogEntry struct {
+	Pod       string    `json:"pod"`
+	Container string    `json:"container"`
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message"`
+}
+
+// K8sTerminalSize is the size of an interactive pod terminal
+type K8sTerminalSize struct {
+	Width  uint16 `json:"cols"`
+	Height uint16 `json:"rows"`
+}
+
+// K8sExecSession carries the streams of an interactive pod exec
+type K8sExecSession struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer // Merged into Stdout when TTY is set
+	TTY    bool
+	Resize <-chan K8sTerminalSize
+}
+
+// K8sWatchEventType represents the type of a streamed resource change
+type K8sWatchEventType string
+
+const (
+	K8sWatchEventAdded    K8sWatchEventType = "ADDED"
+	K8sWatchEventModified K8sWatchEventType = "MODIFIED"
+	K8sWatchEventDeleted  K8sWatchEventType = "DELETED"
+	// K8sWatchEventResync tells the client to drop its cached objects of a resource;
+	// a full snapshot of ADDED events follows because the requested resourceVersion could not be resumed
+	K8sWatchEventResync K8sWatchEventType = "RESYNC"
+	// K8sWatchEventSynced marks the end of the initial snapshot or replay
+	K8sWatchEventSynced K8sWatchEventType = "SYNCED"
+)
+
+// K8sWatchRequest selects which resources to stream
+type K8sWatchRequest struct {
+	Resources       []string `json:"resources" example:"pods,deployments.apps"`
+	Namespaces      []string `json:"namespaces,omitempty" example:"default"` // Empty watches all namespaces the user may read
+	ResourceVersion string   `json:"resource_version,omitempty" example:"123456"`
+}
+
+// K8sWatchEvent is a single resource change pushed to watchers
+type K8sWatchEvent struct {
+	ClusterID       string                 `json:"cluster_id"`
+	Type            K8sWatchEventType      `json:"type"`
+	Resource        string                 `json:"resource,omitempty" example:"pods"`
+	Kind            string                 `json:"kind,omitempty" example:"Pod"`
+	Namespace       string                 `json:"namespace,omitempty"`
+	Name            string                 `json:"name,omitempty"`
+	ResourceVersion string                 `json:"resource_version,omitempty"`
+	Object          map[string]interface{} `json:"object,omitempty"`
+}
+
 // K8sClusterRepository defines the interface for Kubernetes cluster data persistence
 type K8sClusterRepository interface {
 	// Create creates a new Kubernetes cluster record
@@ -291,6 +401,9 @@ type KubernetesUsecase interface {
 	DeleteCluster(ctx context.Context, id string) error
 	GetClusterInfo(ctx context.Context, clusterID string) (map[string]interface{}, error)
 
+	// Manifest Management
+	ApplyManifest(ctx context.Context, clusterID string, req K8sApplyRequest) (*K8sApplyResult, error)
+
 	// Namespace Management
 	ListNamespaces(ctx context.Context, clusterID string) ([]*K8sNamespace, error)
 	CreateNamespace(ctx context.Context, clusterID, name string, labels map[string]string) error
@@ -315,7 +428,9 @@ type KubernetesUsecase interface {
 	GetPod(ctx context.Context, clusterID, namespace, name string) (*K8sPod, error)
 	DeletePod(ctx context.Context, clusterID, namespace, name string) error
 	GetPodLogs(ctx context.Context, clusterID, namespace, podName, containerName string, tail int) (string, error)
+	StreamPodLogs(ctx context.Context, clusterID, namespace string, opts K8sPodLogOptions) (<-chan K8sPodLogEntry, <-chan error, error)
 	ExecPodCommand(ctx context.Context, clusterID, namespace, podName, containerName string, command []string) (string, error)
+	ExecPodInteractive(ctx context.Context, clusterID, namespace, podName, containerName string, command []string, session K8sExecSession) (int, error)
 
 	// Node Management
 	ListNodes(ctx context.Context, clusterID string) ([]*K8sNode, error)
@@ -369,3 +484,10 @@ type KubernetesUsecase interface {
 	ListPVCs(ctx context.Context, clusterID, namespace string) ([]*K8sPVC, error)
 	GetPVC(ctx context.Context, clusterID, namespace, name string) (*K8sPVC, error)
 }
+
+// K8sWatchUsecase streams live resource changes from clusters
+type K8sWatchUsecase interface {
+	// Watch subscribes to resource changes visible to the user. The returned channel is closed when
+	// ctx is done or the subscriber falls behind; clients reconnect with the last seen resourceVersion.
+	Watch(ctx context.Context, userID, clusterID string, req K8sWatchRequest) (<-chan K8sWatchEvent, error)
+}
@@ -0,0 +1,70 @@
+package domain
+
+import "time"
+
+// StackTemplate is a compose file with a typed parameter schema that stacks are deployed from.
+// Built-in templates ship with the server, the others are defined by users.
+type StackTemplate struct {
+	ID              string                   `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Name            string                   `json:"name" gorm:"type:varchar(255);not null;uniqueIndex"`
+	Description     string                   `json:"description" gorm:"type:text"`
+	Category        string                   `json:"category" gorm:"type:varchar(100);index"`
+	Version         int                      `json:"version" gorm:"type:int;not null;default:1"` // Incremented on every change
+	ComposeTemplate string                   `json:"compose_template" gorm:"type:text;not null"` // Go template rendering a compose file
+	Parameters      []StackTemplateParameter `json:"parameters" gorm:"type:jsonb;serializer:json"`
+	BuiltIn         bool                     `json:"built_in" gorm:"-"`
+	CreatedBy       *string                  `json:"created_by,omitempty" gorm:"type:uuid"`
+	CreatedAt       time.Time                `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time                `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName overrides the table name
+func (StackTemplate) TableName() string {
+	return "stack_templates"
+}
+
+// StackTemplateParameter describes a form field of a template; its value is available as {{ .name }}
+type StackTemplateParameter struct {
+	Name        string                     `json:"name" binding:"required" example:"http_port"`
+	Label       string                     `json:"label,omitempty" example:"HTTP port"`
+	Description string                     `json:"description,omitempty"`
+	Type        StackTemplateParameterType `json:"type" binding:"required,oneof=string port secret select bool" example:"port"`
+	Required    bool                       `json:"required,omitempty"`
+	Default     string                     `json:"default,omitempty" example:"8080"`
+	Options     []string                   `json:"options,omitempty"` // Allowed values of select parameters
+}
+
+// StackTemplateParameterType represents the type of a template parameter
+type StackTemplateParameterType string
+
+const (
+	StackTemplateParamString StackTemplateParameterType = "string"
+	StackTemplateParamPort   StackTemplateParameterType = "port"   // 1-65535, rendered as a number
+	StackTemplateParamSecret StackTemplateParameterType = "secret" // Generated when left empty and not required
+	StackTemplateParamSelect StackTemplateParameterType = "select"
+	StackTemplateParamBool   StackTemplateParameterType = "bool" // Rendered as a boolean for {{ if }}
+)
+
+// StackTemplateFilter represents filters for listing templates
+type StackTemplateFilter struct {
+	Category string `form:"category"`
+	Search   string `form:"search"` // Matched against name and description
+}
+
+// StackTemplateRequest represents a request to create or update a user-defined template
+type StackTemplateRequest struct {
+	Name            string                   `json:"name" binding:"required" example:"internal-api"`
+	Description     string                   `json:"description,omitempty"`
+	Category        string                   `json:"category,omitempty" example:"web"`
+	ComposeTemplate string                   `json:"compose_template" binding:"required"`
+	Parameters      []StackTemplateParameter `json:"parameters,omitempty" binding:"dive"`
+}
+
+// StackTemplateDeployRequest represents a request to deploy a stack from a template
+type StackTemplateDeployRequest struct {
+	Name          string                 `json:"name" binding:"required" example:"shop-db"`
+	Parameters    map[string]interface{} `json:"parameters,omitempty"` // Strings, numbers or booleans keyed by parameter name
+	EnvVars       map[string]string      `json:"env_vars,omitempty"`
+	DockerHost    string                 `json:"docker_host,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"` // DockerHost ID, empty for the local daemon
+	EnvironmentID *string                `json:"environment_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+}
@@ -0,0 +1,192 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// VulnerabilityGateMode controls what happens when an image violates a policy
+type VulnerabilityGateMode string
+
+const (
+	// VulnerabilityGateModeEnforce rejects deployments that violate the policy
+	VulnerabilityGateModeEnforce VulnerabilityGateMode = "enforce"
+	// VulnerabilityGateModeWarn lets deployments through and reports the violations
+	VulnerabilityGateModeWarn VulnerabilityGateMode = "warn"
+)
+
+// VulnerabilityGateDecision is the outcome of evaluating images against a policy
+type VulnerabilityGateDecision string
+
+const (
+	VulnerabilityGateDecisionPass  VulnerabilityGateDecision = "pass"
+	VulnerabilityGateDecisionWarn  VulnerabilityGateDecision = "warn"
+	VulnerabilityGateDecisionBlock VulnerabilityGateDecision = "block"
+)
+
+// CVEExceptionStatus represents the approval state of a CVE exception
+type CVEExceptionStatus string
+
+const (
+	CVEExceptionStatusPending  CVEExceptionStatus = "pending"
+	CVEExceptionStatusApproved CVEExceptionStatus = "approved"
+	CVEExceptionStatusRejected CVEExceptionStatus = "rejected"
+	CVEExceptionStatusRevoked  CVEExceptionStatus = "revoked"
+)
+
+// MaxCVEExceptionDuration is the longest an exception may stay valid
+const MaxCVEExceptionDuration = 90 * 24 * time.Hour
+
+// PermissionApproveCVEException is required to approve or reject CVE exceptions
+const PermissionApproveCVEException = "harbor.vulnerability.exception.approve"
+
+// VulnerabilityPolicy is the image vulnerability policy of an environment
+// @Description Vulnerability limits that images must meet before they are deployed to an environment
+type VulnerabilityPolicy struct {
+	ID                       string                `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()" example:"550e8400-e29b-41d4-a716-446655440000"`
+	EnvironmentID            string                `json:"environment_id" gorm:"type:uuid;not null;uniqueIndex" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Enabled                  bool                  `json:"enabled" gorm:"type:boolean" example:"true"`
+	Mode                     VulnerabilityGateMode `json:"mode" gorm:"type:varchar(20);not null;default:'enforce'" example:"enforce"`
+	MaxCritical              *int                  `json:"max_critical,omitempty" gorm:"type:int" example:"0"` // Nil means unlimited
+	MaxHigh                  *int                  `json:"max_high,omitempty" gorm:"type:int" example:"5"`
+	MaxMedium                *int                  `json:"max_medium,omitempty" gorm:"type:int"`
+	FixableOnly              bool                  `json:"fixable_only" gorm:"type:boolean;default:false" example:"true"`                // Only count vulnerabilities with a fix available
+	BlockUnscanned           bool                  `json:"block_unscanned" gorm:"type:boolean" example:"true"`                           // Treat images without a completed scan as violations
+	AllowUnmanagedRegistries bool                  `json:"allow_unmanaged_registries" gorm:"type:boolean;default:false" example:"false"` // Skip images from registries not registered as Harbor registries
+	ScanTimeoutSeconds       int                   `json:"scan_timeout_seconds" gorm:"type:int;default:20" example:"20"`                 // How long to wait for a triggered scan
+	UpdatedBy                string                `json:"updated_by" gorm:"type:varchar(255)"`
+	CreatedAt                time.Time             `json:"created_at" gorm:"autoCreateTime" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt                time.Time             `json:"updated_at" gorm:"autoUpdateTime" example:"2024-01-01T00:00:00Z"`
+}
+
+// TableName specifies the table name for VulnerabilityPolicy model
+func (VulnerabilityPolicy) TableName() string {
+	return "vulnerability_policies"
+}
+
+// CVEException exempts a CVE from vulnerability policies until it expires
+// @Description Time-boxed CVE exception approved by an administrator
+type CVEException struct {
+	ID            string             `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()" example:"550e8400-e29b-41d4-a716-446655440000"`
+	EnvironmentID *string            `json:"environment_id,omitempty" gorm:"type:uuid;index" example:"550e8400-e29b-41d4-a716-446655440000"` // Nil applies to every environment
+	CVEID         string             `json:"cve_id" gorm:"type:varchar(100);not null;index" validate:"required" example:"CVE-2024-1234"`
+	Repository    string             `json:"repository,omitempty" gorm:"type:varchar(500)" example:"library/nginx"` // Empty applies to every repository
+	Reason        string             `json:"reason" gorm:"type:text;not null" validate:"required" example:"Not reachable: the vulnerable code path is disabled"`
+	Status        CVEExceptionStatus `json:"status" gorm:"type:varchar(20);not null;index" example:"approved"`
+	RequestedBy   string             `json:"requested_by" gorm:"type:varchar(255)"`
+	ReviewedBy    *string            `json:"reviewed_by,omitempty" gorm:"type:varchar(255)"`
+	ReviewedAt    *time.Time         `json:"reviewed_at,omitempty"`
+	ExpiresAt     time.Time          `json:"expires_at" gorm:"not null" validate:"required" example:"2024-03-01T00:00:00Z"`
+	CreatedAt     time.Time          `json:"created_at" gorm:"autoCreateTime" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt     time.Time          `json:"updated_at" gorm:"autoUpdateTime" example:"2024-01-01T00:00:00Z"`
+}
+
+// TableName specifies the table name for CVEException model
+func (CVEException) TableName() string {
+	return "cve_exceptions"
+}
+
+// IsActive reports whether the exception is approved and not yet expired
+func (e *CVEException) IsActive(now time.Time) bool {
+	return e.Status == CVEExceptionStatusApproved && now.Before(e.ExpiresAt)
+}
+
+// Covers reports whether the exception applies to a CVE in a repository
+func (e *CVEException) Covers(cveID, repository string) bool {
+	if !strings.EqualFold(e.CVEID, cveID) {
+		return false
+	}
+	return e.Repository == "" || e.Repository == repository
+}
+
+// CVEExceptionFilter represents filtering options for CVE exception queries
+type CVEExceptionFilter struct {
+	EnvironmentID string             `json:"environment_id,omitempty"`
+	CVEID         string             `json:"cve_id,omitempty"`
+	Status        CVEExceptionStatus `json:"status,omitempty"`
+	ActiveOnly    bool               `json:"active_only,omitempty"` // Approved and not expired
+}
+
+// VulnerabilityGateImageResult is the evaluation of a single image
+// @Description Vulnerability gate result of one image
+type VulnerabilityGateImageResult struct {
+	Image           string                     `json:"image" example:"harbor.example.com/library/nginx:1.25"`
+	RegistryID      string                     `json:"registry_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Repository      string                     `json:"repository,omitempty" example:"library/nginx"`
+	Reference       string                     `json:"reference,omitempty" example:"1.25"`
+	ScanStatus      string                     `json:"scan_status,omitempty" example:"Success"`
+	Decision        VulnerabilityGateDecision  `json:"decision" example:"block"`
+	Summary         HarborVulnerabilitySummary `json:"summary"` // Counted after exceptions and the fixable filter
+	Violations      []string                   `json:"violations,omitempty"`
+	Vulnerabilities []*HarborVulnerability     `json:"vulnerabilities,omitempty"` // Offending CVEs
+	ExemptedCVEs    []string                   `json:"exempted_cves,omitempty"`
+}
+
+// VulnerabilityGateReport is the evaluation of a set of images against an environment policy
+// @Description Vulnerability gate report
+type VulnerabilityGateReport struct {
+	EnvironmentID string                         `json:"environment_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	PolicyID      string                         `json:"policy_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Mode          VulnerabilityGateMode          `json:"mode" example:"enforce"`
+	Decision      VulnerabilityGateDecision      `json:"decision" example:"block"`
+	Images        []VulnerabilityGateImageResult `json:"images"`
+	EvaluatedAt   time.Time                      `json:"evaluated_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// VulnerabilityGateError is returned when a deployment is rejected by the vulnerability gate
+type VulnerabilityGateError struct {
+	Report *VulnerabilityGateReport
+}
+
+// Error lists the rejected images and their violations
+func (e *VulnerabilityGateError) Error() string {
+	var parts []string
+	for _, image := range e.Report.Images {
+		if image.Decision != VulnerabilityGateDecisionBlock {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", image.Image, strings.Join(image.Violations, "; ")))
+	}
+	return "deployment blocked by vulnerability policy: " + strings.Join(parts, ", ")
+}
+
+// VulnerabilityPolicyRepository defines data persistence for vulnerability policies and CVE exceptions
+type VulnerabilityPolicyRepository interface {
+	// GetPolicyByEnvironment returns nil when the environment has no policy
+	GetPolicyByEnvironment(ctx context.Context, environmentID string) (*VulnerabilityPolicy, error)
+	ListPolicies(ctx context.Context) ([]*VulnerabilityPolicy, error)
+	// SavePolicy creates or replaces the policy of an environment
+	SavePolicy(ctx context.Context, policy *VulnerabilityPolicy) error
+	DeletePolicy(ctx context.Context, environmentID string) error
+
+	CreateException(ctx context.Context, exception *CVEException) error
+	GetException(ctx context.Context, id string) (*CVEException, error)
+	UpdateException(ctx context.Context, exception *CVEException) error
+	ListExceptions(ctx context.Context, filter CVEExceptionFilter) ([]*CVEException, error)
+}
+
+// VulnerabilityGateUsecase defines the business logic for gating deployments on image vulnerabilities
+type VulnerabilityGateUsecase interface {
+	// Policy Management
+	GetPolicy(ctx context.Context, environmentID string) (*VulnerabilityPolicy, error)
+	ListPolicies(ctx context.Context) ([]*VulnerabilityPolicy, error)
+	SavePolicy(ctx context.Context, policy *VulnerabilityPolicy, userID string) error
+	DeletePolicy(ctx context.Context, environmentID string) error
+
+	// CVE Exceptions
+	RequestException(ctx context.Context, exception *CVEException, userID string) error
+	ApproveException(ctx context.Context, id, userID string) (*CVEException, error)
+	RejectException(ctx context.Context, id, userID string) (*CVEException, error)
+	RevokeException(ctx context.Context, id, userID string) (*CVEException, error)
+	ListExceptions(ctx context.Context, filter CVEExceptionFilter) ([]*CVEException, error)
+
+	// Evaluate reports how images fare against an environment policy without rejecting anything
+	Evaluate(ctx context.Context, environmentID string, images []string) (*VulnerabilityGateReport, error)
+	// CheckEnvironment evaluates images before a deployment; it returns a nil report when no policy applies
+	// and a *VulnerabilityGateError when an enforced policy rejects them
+	CheckEnvironment(ctx context.Context, environmentID string, images []string) (*VulnerabilityGateReport, error)
+	// CheckCluster is CheckEnvironment for the environment a Kubernetes cluster belongs to
+	CheckCluster(ctx context.Context, clusterID string, images []string) (*VulnerabilityGateReport, error)
+}
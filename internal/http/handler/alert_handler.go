@@ -0,0 +1,526 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+)
+
+// AlertHandler handles alert rules, silences, maintenance windows, alert history and the receivers
+// alerts are routed to
+type AlertHandler struct {
+	alertUsecase    usecase.AlertUsecase
+	receiverUsecase usecase.AlertReceiverUsecase
+}
+
+// NewAlertHandler creates a new alert handler
+func NewAlertHandler(alertUsecase usecase.AlertUsecase, receiverUsecase usecase.AlertReceiverUsecase) *AlertHandler {
+	return &AlertHandler{
+		alertUsecase:    alertUsecase,
+		receiverUsecase: receiverUsecase,
+	}
+}
+
+// ListRules lists the alert rules
+// @Summary List alert rules
+// @Description Get every alert rule, enabled or not
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Success 200 {array} domain.AlertRule "Alert rules"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/alerts/rules [get]
+// @Security BearerAuth
+func (h *AlertHandler) ListRules(c *gin.Context) {
+	rules, err := h.alertUsecase.ListRules(c.Request.Context())
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to list alert rules"))
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// GetRule gets an alert rule
+// @Summary Get alert rule
+// @Description Get an alert rule by ID
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Success 200 {object} domain.AlertRule "Alert rule"
+// @Failure 404 {object} errorx.Error "Rule not found"
+// @Router /api/v1/alerts/rules/{id} [get]
+// @Security BearerAuth
+func (h *AlertHandler) GetRule(c *gin.Context) {
+	rule, err := h.alertUsecase.GetRule(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeNotFound, "Alert rule not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// CreateRule creates an alert rule
+// @Summary Create alert rule
+// @Description Alert when a metric of the subjects in a scope breaches a threshold for a duration. Scopes are container, stack, host, server, cluster and label; the target narrows a scope to one subject by ID or name, or selects containers by labels
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param request body domain.AlertRuleRequest true "Alert rule"
+// @Success 201 {object} domain.AlertRule "Rule created"
+// @Failure 400 {object} errorx.Error "Invalid rule"
+// @Router /api/v1/alerts/rules [post]
+// @Security BearerAuth
+func (h *AlertHandler) CreateRule(c *gin.Context) {
+	var req domain.AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+		return
+	}
+
+	rule, err := h.alertUsecase.CreateRule(c.Request.Context(), req, c.GetString("user_id"))
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to create alert rule"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule updates an alert rule
+// @Summary Update alert rule
+// @Description Replace an alert rule. Disabling it resolves its firing alerts on the next check
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Param request body domain.AlertRuleRequest true "Alert rule"
+// @Success 200 {object} domain.AlertRule "Rule updated"
+// @Failure 400 {object} errorx.Error "Invalid rule"
+// @Router /api/v1/alerts/rules/{id} [put]
+// @Security BearerAuth
+func (h *AlertHandler) UpdateRule(c *gin.Context) {
+	var req domain.AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+		return
+	}
+
+	rule, err := h.alertUsecase.UpdateRule(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to update alert rule"))
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule deletes an alert rule
+// @Summary Delete alert rule
+// @Description Delete an alert rule; its firing alerts resolve on the next check and its history is kept
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Success 200 {object} map[string]interface{} "Rule deleted"
+// @Failure 404 {object} errorx.Error "Rule not found"
+// @Router /api/v1/alerts/rules/{id} [delete]
+// @Security BearerAuth
+func (h *AlertHandler) DeleteRule(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.alertUsecase.DeleteRule(c.Request.Context(), id); err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeNotFound, "Failed to delete alert rule"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Alert rule deleted successfully",
+		"rule_id": id,
+	})
+}
+
+// ListSilences lists the alert silences
+// @Summary List alert silences
+// @Description Get the silences that have not ended, or every silence with expired=true
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param expired query bool false "Include expired silences"
+// @Success 200 {array} domain.AlertSilence "Silences"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/alerts/silences [get]
+// @Security BearerAuth
+func (h *AlertHandler) ListSilences(c *gin.Context) {
+	silences, err := h.alertUsecase.ListSilences(c.Request.Context(), c.Query("expired") == "true")
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to list silences"))
+		return
+	}
+
+	c.JSON(http.StatusOK, silences)
+}
+
+// CreateSilence silences alerts
+// @Summary Create alert silence
+// @Description Suppress notifications of the alerts matching a rule, scope and subject until the silence ends. Matching alerts still fire and are recorded as silenced
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param request body domain.AlertSilenceRequest true "Silence"
+// @Success 201 {object} domain.AlertSilence "Silence created"
+// @Failure 400 {object} errorx.Error "Invalid silence"
+// @Router /api/v1/alerts/silences [post]
+// @Security BearerAuth
+func (h *AlertHandler) CreateSilence(c *gin.Context) {
+	var req domain.AlertSilenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+		return
+	}
+
+	silence, err := h.alertUsecase.CreateSilence(c.Request.Context(), req, c.GetString("user_id"))
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to create silence"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, silence)
+}
+
+// ExpireSilence ends a silence
+// @Summary Expire alert silence
+// @Description End a silence now; it is kept for reference
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "Silence ID"
+// @Success 200 {object} domain.AlertSilence "Silence expired"
+// @Failure 404 {object} errorx.Error "Silence not found"
+// @Router /api/v1/alerts/silences/{id} [delete]
+// @Security BearerAuth
+func (h *AlertHandler) ExpireSilence(c *gin.Context) {
+	silence, err := h.alertUsecase.ExpireSilence(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeNotFound, "Failed to expire silence"))
+		return
+	}
+
+	c.JSON(http.StatusOK, silence)
+}
+
+// ListMaintenanceWindows lists the maintenance windows
+// @Summary List maintenance windows
+// @Description Get every scheduled maintenance window
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Success 200 {array} domain.AlertMaintenanceWindow "Maintenance windows"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/alerts/maintenance-windows [get]
+// @Security BearerAuth
+func (h *AlertHandler) ListMaintenanceWindows(c *gin.Context) {
+	windows, err := h.alertUsecase.ListMaintenanceWindows(c.Request.Context())
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to list maintenance windows"))
+		return
+	}
+
+	c.JSON(http.StatusOK, windows)
+}
+
+// CreateMaintenanceWindow schedules a maintenance window
+// @Summary Create maintenance window
+// @Description Pause alerting for a scope or subject: rules are not evaluated during the window, so alerts neither fire nor resolve. Weekly windows repeat every seven days
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param request body domain.AlertMaintenanceWindowRequest true "Maintenance window"
+// @Success 201 {object} domain.AlertMaintenanceWindow "Maintenance window created"
+// @Failure 400 {object} errorx.Error "Invalid maintenance window"
+// @Router /api/v1/alerts/maintenance-windows [post]
+// @Security BearerAuth
+func (h *AlertHandler) CreateMaintenanceWindow(c *gin.Context) {
+	var req domain.AlertMaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+		return
+	}
+
+	window, err := h.alertUsecase.CreateMaintenanceWindow(c.Request.Context(), req, c.GetString("user_id"))
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to create maintenance window"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, window)
+}
+
+// DeleteMaintenanceWindow deletes a maintenance window
+// @Summary Delete maintenance window
+// @Description Delete a maintenance window; its subjects are evaluated again on the next check
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "Maintenance window ID"
+// @Success 200 {object} map[string]interface{} "Maintenance window deleted"
+// @Failure 400 {object} errorx.Error "Invalid request"
+// @Router /api/v1/alerts/maintenance-windows/{id} [delete]
+// @Security BearerAuth
+func (h *AlertHandler) DeleteMaintenanceWindow(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.alertUsecase.DeleteMaintenanceWindow(c.Request.Context(), id); err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to delete maintenance window"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Maintenance window deleted successfully",
+		"window_id": id,
+	})
+}
+
+// ListHistory lists fired alerts
+// @Summary List alert history
+// @Description Get the alerts that fired, latest first, with when they started, fired and resolved
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param rule_id query string false "Filter by rule"
+// @Param scope query string false "Filter by scope"
+// @Param subject query string false "Filter by subject ID"
+// @Param status query string false "Filter by status (firing, resolved)"
+// @Param from query string false "Fired at or after (RFC3339 format)"
+// @Param to query string false "Fired before (RFC3339 format)"
+// @Param page query int false "Page number (default: 1)" default(1)
+// @Param page_size query int false "Page size (default: 20, max: 100)" default(20)
+// @Success 200 {object} map[string]interface{} "Alert history"
+// @Failure 400 {object} errorx.Error "Invalid request parameters"
+// @Router /api/v1/alerts/history [get]
+// @Security BearerAuth
+func (h *AlertHandler) ListHistory(c *gin.Context) {
+	var filter domain.AlertHistoryFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid query parameters"))
+		return
+	}
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = 20
+	}
+
+	history, total, err := h.alertUsecase.ListHistory(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to list alert history"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Alert history retrieved successfully",
+		"data":      history,
+		"total":     total,
+		"page":      filter.Page,
+		"page_size": filter.PageSize,
+	})
+}
+
+// ListReceivers lists the alert receivers
+// @Summary List alert receivers
+// @Description Get every alert receiver; secrets are not returned
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Success 200 {array} domain.AlertReceiver "Receivers"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/alerts/receivers [get]
+// @Security BearerAuth
+func (h *AlertHandler) ListReceivers(c *gin.Context) {
+	receivers, err := h.receiverUsecase.ListReceivers(c.Request.Context())
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to list receivers"))
+		return
+	}
+
+	c.JSON(http.StatusOK, receivers)
+}
+
+// GetReceiver gets an alert receiver
+// @Summary Get alert receiver
+// @Description Get an alert receiver by ID; its secret is not returned
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "Receiver ID"
+// @Success 200 {object} domain.AlertReceiver "Receiver"
+// @Failure 404 {object} errorx.Error "Receiver not found"
+// @Router /api/v1/alerts/receivers/{id} [get]
+// @Security BearerAuth
+func (h *AlertHandler) GetReceiver(c *gin.Context) {
+	receiver, err := h.receiverUsecase.GetReceiver(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeNotFound, "Receiver not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, receiver)
+}
+
+// CreateReceiver creates an alert receiver
+// @Summary Create alert receiver
+// @Description Add a receiver: a generic webhook signed with HMAC-SHA256 in X-Einfra-Signature when a secret is set, a Slack-compatible incoming webhook, PagerDuty Events v2 with the routing key as secret, or email recipients
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param request body domain.AlertReceiverRequest true "Receiver"
+// @Success 201 {object} domain.AlertReceiver "Receiver created"
+// @Failure 400 {object} errorx.Error "Invalid receiver"
+// @Router /api/v1/alerts/receivers [post]
+// @Security BearerAuth
+func (h *AlertHandler) CreateReceiver(c *gin.Context) {
+	var req domain.AlertReceiverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+		return
+	}
+
+	receiver, err := h.receiverUsecase.CreateReceiver(c.Request.Context(), req, c.GetString("user_id"))
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to create receiver"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, receiver)
+}
+
+// UpdateReceiver updates an alert receiver
+// @Summary Update alert receiver
+// @Description Replace an alert receiver; the stored secret is kept when the request has none
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "Receiver ID"
+// @Param request body domain.AlertReceiverRequest true "Receiver"
+// @Success 200 {object} domain.AlertReceiver "Receiver updated"
+// @Failure 400 {object} errorx.Error "Invalid receiver"
+// @Router /api/v1/alerts/receivers/{id} [put]
+// @Security BearerAuth
+func (h *AlertHandler) UpdateReceiver(c *gin.Context) {
+	var req domain.AlertReceiverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+		return
+	}
+
+	receiver, err := h.receiverUsecase.UpdateReceiver(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to update receiver"))
+		return
+	}
+
+	c.JSON(http.StatusOK, receiver)
+}
+
+// DeleteReceiver deletes an alert receiver
+// @Summary Delete alert receiver
+// @Description Delete a receiver the routing tree does not use
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "Receiver ID"
+// @Success 200 {object} map[string]interface{} "Receiver deleted"
+// @Failure 400 {object} errorx.Error "Receiver in use"
+// @Router /api/v1/alerts/receivers/{id} [delete]
+// @Security BearerAuth
+func (h *AlertHandler) DeleteReceiver(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.receiverUsecase.DeleteReceiver(c.Request.Context(), id); err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to delete receiver"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Receiver deleted successfully",
+		"receiver_id": id,
+	})
+}
+
+// TestReceiver sends a test alert
+// @Summary Test alert receiver
+// @Description Send a firing test alert to a receiver right away, bypassing routing and grouping
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "Receiver ID"
+// @Success 200 {object} map[string]interface{} "Test alert sent"
+// @Failure 400 {object} errorx.Error "Receiver failed"
+// @Router /api/v1/alerts/receivers/{id}/test [post]
+// @Security BearerAuth
+func (h *AlertHandler) TestReceiver(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.receiverUsecase.TestReceiver(c.Request.Context(), id); err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to send test alert"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Test alert sent successfully",
+		"receiver_id": id,
+	})
+}
+
+// GetRouting gets the alert routing tree
+// @Summary Get alert routing
+// @Description Get the routing tree deciding which receivers alerts go to, grouped how and how often
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Success 200 {object} domain.AlertRouting "Routing tree"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/alerts/routing [get]
+// @Security BearerAuth
+func (h *AlertHandler) GetRouting(c *gin.Context) {
+	routing, err := h.receiverUsecase.GetRouting(c.Request.Context())
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to get alert routing"))
+		return
+	}
+
+	c.JSON(http.StatusOK, routing)
+}
+
+// UpdateRouting replaces the alert routing tree
+// @Summary Update alert routing
+// @Description Replace the routing tree. Alerts are matched by the labels of their rule and alertname, rule_id, severity, scope, subject, subject_name and metric; the root route needs a receiver
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param request body domain.AlertRoute true "Root route"
+// @Success 200 {object} domain.AlertRouting "Routing updated"
+// @Failure 400 {object} errorx.Error "Invalid routing"
+// @Router /api/v1/alerts/routing [put]
+// @Security BearerAuth
+func (h *AlertHandler) UpdateRouting(c *gin.Context) {
+	var route domain.AlertRoute
+	if err := c.ShouldBindJSON(&route); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+		return
+	}
+
+	routing, err := h.receiverUsecase.UpdateRouting(c.Request.Context(), route)
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to update alert routing"))
+		return
+	}
+
+	c.JSON(http.StatusOK, routing)
+}
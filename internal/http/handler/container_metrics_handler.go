@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+)
+
+// ContainerMetricsHandler serves the retained resource usage of containers
+type ContainerMetricsHandler struct {
+	metricsUsecase usecase.ContainerMetricsUsecase
+}
+
+// NewContainerMetricsHandler creates a new container metrics handler
+func NewContainerMetricsHandler(metricsUsecase usecase.ContainerMetricsUsecase) *ContainerMetricsHandler {
+	return &ContainerMetricsHandler{
+		metricsUsecase: metricsUsecase,
+	}
+}
+
+// GetContainerMetrics gets the metric history of a container
+// @Summary Get container metrics
+// @Description Get CPU, memory, network and block IO of a container over a time range. Buckets of 1m are kept for a day, 5m for a week and 1h for 90 days; without a resolution the finest one covering the range is used
+// @Tags docker-metrics
+// @Accept json
+// @Produce json
+// @Param host_id path string true "Docker host ID"
+// @Param container_id path string true "Container ID"
+// @Param from query string false "Start (RFC 3339), defaults to one hour before to"
+// @Param to query string false "End (RFC 3339), defaults to now"
+// @Param resolution query string false "Bucket size" Enums(1m, 5m, 1h)
+// @Success 200 {object} domain.ContainerMetricsResponse "Metrics"
+// @Failure 400 {object} errorx.Error "Invalid request"
+// @Router /api/v1/docker/hosts/{host_id}/containers/{container_id}/metrics [get]
+// @Security BearerAuth
+func (h *ContainerMetricsHandler) GetContainerMetrics(c *gin.Context) {
+	var query domain.ContainerMetricQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid query parameters"))
+		return
+	}
+
+	metrics, err := h.metricsUsecase.GetContainerMetrics(c.Request.Context(), c.Param("host_id"), c.Param("container_id"), query)
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to get container metrics"))
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
+
+// GetHostMetrics gets the metric history of the containers of a host
+// @Summary Get Docker host metrics
+// @Description Get CPU, memory, network and block IO of every container of a host over a time range, one series per container
+// @Tags docker-metrics
+// @Accept json
+// @Produce json
+// @Param host_id path string true "Docker host ID"
+// @Param from query string false "Start (RFC 3339), defaults to one hour before to"
+// @Param to query string false "End (RFC 3339), defaults to now"
+// @Param resolution query string false "Bucket size" Enums(1m, 5m, 1h)
+// @Success 200 {object} domain.ContainerMetricsResponse "Metrics"
+// @Failure 400 {object} errorx.Error "Invalid request"
+// @Router /api/v1/docker/hosts/{host_id}/metrics [get]
+// @Security BearerAuth
+func (h *ContainerMetricsHandler) GetHostMetrics(c *gin.Context) {
+	var query domain.ContainerMetricQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid query parameters"))
+		return
+	}
+
+	metrics, err := h.metricsUsecase.GetHostMetrics(c.Request.Context(), c.Param("host_id"), query)
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to get host metrics"))
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
+
+// GetStackMetrics gets the metric history of the containers of a stack
+// @Summary Get Docker stack metrics
+// @Description Get CPU, memory, network and block IO of the containers of a stack over a time range, one series per container
+// @Tags docker-metrics
+// @Accept json
+// @Produce json
+// @Param id path string true "Stack ID"
+// @Param from query string false "Start (RFC 3339), defaults to one hour before to"
+// @Param to query string false "End (RFC 3339), defaults to now"
+// @Param resolution query string false "Bucket size" Enums(1m, 5m, 1h)
+// @Success 200 {object} domain.ContainerMetricsResponse "Metrics"
+// @Failure 400 {object} errorx.Error "Invalid request"
+// @Router /api/v1/docker/stacks/{id}/metrics [get]
+// @Security BearerAuth
+func (h *ContainerMetricsHandler) GetStackMetrics(c *gin.Context) {
+	var query domain.ContainerMetricQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid query parameters"))
+		return
+	}
+
+	metrics, err := h.metricsUsecase.GetStackMetrics(c.Request.Context(), c.Param("id"), query)
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to get stack metrics"))
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
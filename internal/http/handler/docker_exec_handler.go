@@ -34,6 +34,7 @@ type CreateExecRequest struct {
 // @Produce json
 // @Param id path string true "Container ID"
 // @Param request body CreateExecRequest true "Exec configuration"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 201 {object} map[string]interface{} "Exec instance created"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -48,7 +49,7 @@ func (h *DockerExecHandler) CreateExec(c *gin.Context) {
 		return
 	}
 
-	execID, err := h.execUsecase.CreateExec(c.Request.Context(), containerID, req.Cmd, req.Tty)
+	execID, err := h.execUsecase.CreateExec(c.Request.Context(), c.Query("host_id"), containerID, req.Cmd, req.Tty)
 	if err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to create exec"))
 		return
@@ -73,6 +74,7 @@ type StartExecRequest struct {
 // @Produce json
 // @Param execId path string true "Exec ID"
 // @Param request body StartExecRequest true "Start configuration"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} map[string]interface{} "Exec output"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -87,7 +89,7 @@ func (h *DockerExecHandler) StartExec(c *gin.Context) {
 		return
 	}
 
-	output, err := h.execUsecase.StartExec(c.Request.Context(), execID, req.Tty)
+	output, err := h.execUsecase.StartExec(c.Request.Context(), c.Query("host_id"), execID, req.Tty)
 	if err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to start exec"))
 		return
@@ -105,6 +107,7 @@ func (h *DockerExecHandler) StartExec(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param execId path string true "Exec ID"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} map[string]interface{} "Exec information"
 // @Failure 500 {object} errorx.Error "Internal server error"
 // @Router /api/v1/docker/exec/{execId}/inspect [get]
@@ -112,7 +115,7 @@ func (h *DockerExecHandler) StartExec(c *gin.Context) {
 func (h *DockerExecHandler) InspectExec(c *gin.Context) {
 	execID := c.Param("execId")
 
-	info, err := h.execUsecase.InspectExec(c.Request.Context(), execID)
+	info, err := h.execUsecase.InspectExec(c.Request.Context(), c.Query("host_id"), execID)
 	if err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to inspect exec"))
 		return
@@ -135,6 +138,7 @@ type ResizeExecRequest struct {
 // @Produce json
 // @Param execId path string true "Exec ID"
 // @Param request body ResizeExecRequest true "Resize dimensions"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} map[string]interface{} "TTY resized"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -149,7 +153,7 @@ func (h *DockerExecHandler) ResizeExec(c *gin.Context) {
 		return
 	}
 
-	if err := h.execUsecase.ResizeExec(c.Request.Context(), execID, req.Height, req.Width); err != nil {
+	if err := h.execUsecase.ResizeExec(c.Request.Context(), c.Query("host_id"), execID, req.Height, req.Width); err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to resize exec"))
 		return
 	}
@@ -172,6 +176,7 @@ type ExecuteCommandRequest struct {
 // @Produce json
 // @Param id path string true "Container ID"
 // @Param request body ExecuteCommandRequest true "Command to execute"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} map[string]interface{} "Command result"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -186,7 +191,7 @@ func (h *DockerExecHandler) ExecuteCommand(c *gin.Context) {
 		return
 	}
 
-	result, err := h.execUsecase.ExecuteCommand(c.Request.Context(), containerID, req.Cmd)
+	result, err := h.execUsecase.ExecuteCommand(c.Request.Context(), c.Query("host_id"), containerID, req.Cmd)
 	if err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to execute command"))
 		return
@@ -96,13 +96,13 @@ func (h *DockerHandler) ListHosts(c *gin.Context) {
 // @Tags docker
 // @Accept json
 // @Produce json
-// @Param id path string true "Docker host ID"
+// @Param host_id path string true "Docker host ID"
 // @Success 200 {object} domain.DockerHost
 // @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
-// @Router /api/v1/docker/hosts/{id} [get]
+// @Router /api/v1/docker/hosts/{host_id} [get]
 func (h *DockerHandler) GetHost(c *gin.Context) {
-	id := c.Param("id")
+	id := c.Param("host_id")
 
 	host, err := h.dockerUsecase.GetDockerHost(c.Request.Context(), id)
 	if err != nil {
@@ -119,14 +119,14 @@ func (h *DockerHandler) GetHost(c *gin.Context) {
 // @Tags docker
 // @Accept json
 // @Produce json
-// @Param id path string true "Docker host ID"
+// @Param host_id path string true "Docker host ID"
 // @Param host body domain.DockerHost true "Docker host object"
 // @Success 200 {object} domain.DockerHost
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
-// @Router /api/v1/docker/hosts/{id} [put]
+// @Router /api/v1/docker/hosts/{host_id} [put]
 func (h *DockerHandler) UpdateHost(c *gin.Context) {
-	id := c.Param("id")
+	id := c.Param("host_id")
 
 	var host domain.DockerHost
 	if err := c.ShouldBindJSON(&host); err != nil {
@@ -150,12 +150,12 @@ func (h *DockerHandler) UpdateHost(c *gin.Context) {
 // @Tags docker
 // @Accept json
 // @Produce json
-// @Param id path string true "Docker host ID"
+// @Param host_id path string true "Docker host ID"
 // @Success 204
 // @Failure 500 {object} map[string]interface{}
-// @Router /api/v1/docker/hosts/{id} [delete]
+// @Router /api/v1/docker/hosts/{host_id} [delete]
 func (h *DockerHandler) DeleteHost(c *gin.Context) {
-	id := c.Param("id")
+	id := c.Param("host_id")
 
 	if err := h.dockerUsecase.DeleteDockerHost(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -165,6 +165,28 @@ func (h *DockerHandler) DeleteHost(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// RefreshHost godoc
+// @Summary Refresh Docker host
+// @Description Check the daemon of a Docker host and record its status and version
+// @Tags docker
+// @Accept json
+// @Produce json
+// @Param host_id path string true "Docker host ID"
+// @Success 200 {object} domain.DockerHost
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/docker/hosts/{host_id}/refresh [post]
+func (h *DockerHandler) RefreshHost(c *gin.Context) {
+	id := c.Param("host_id")
+
+	host, err := h.dockerUsecase.RefreshDockerHost(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, host)
+}
+
 // Container Management
 
 // ListContainers godoc
@@ -191,6 +213,60 @@ func (h *DockerHandler) ListContainers(c *gin.Context) {
 	c.JSON(http.StatusOK, containers)
 }
 
+// GetContainer godoc
+// @Summary Get container
+// @Description Get the details of a Docker container
+// @Tags docker
+// @Accept json
+// @Produce json
+// @Param host_id path string true "Docker host ID"
+// @Param container_id path string true "Container ID"
+// @Success 200 {object} domain.Container
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/docker/hosts/{host_id}/containers/{container_id} [get]
+func (h *DockerHandler) GetContainer(c *gin.Context) {
+	hostID := c.Param("host_id")
+	containerID := c.Param("container_id")
+
+	container, err := h.dockerUsecase.GetContainer(c.Request.Context(), hostID, containerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, container)
+}
+
+// CreateContainer godoc
+// @Summary Create container
+// @Description Create a Docker container
+// @Tags docker
+// @Accept json
+// @Produce json
+// @Param host_id path string true "Docker host ID"
+// @Param request body docker.ContainerCreateConfig true "Container configuration"
+// @Success 201 {object} domain.Container
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/docker/hosts/{host_id}/containers [post]
+func (h *DockerHandler) CreateContainer(c *gin.Context) {
+	hostID := c.Param("host_id")
+
+	var config map[string]interface{}
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	container, err := h.dockerUsecase.CreateContainer(c.Request.Context(), hostID, config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, container)
+}
+
 // StartContainer godoc
 // @Summary Start container
 // @Description Start a Docker container
@@ -239,6 +315,80 @@ func (h *DockerHandler) StopContainer(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Container stopped successfully"})
 }
 
+// RestartContainer godoc
+// @Summary Restart container
+// @Description Restart a Docker container
+// @Tags docker
+// @Accept json
+// @Produce json
+// @Param host_id path string true "Docker host ID"
+// @Param container_id path string true "Container ID"
+// @Param timeout query int false "Timeout in seconds" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/docker/hosts/{host_id}/containers/{container_id}/restart [post]
+func (h *DockerHandler) RestartContainer(c *gin.Context) {
+	hostID := c.Param("host_id")
+	containerID := c.Param("container_id")
+	timeout, _ := strconv.Atoi(c.DefaultQuery("timeout", "10"))
+
+	if err := h.dockerUsecase.RestartContainer(c.Request.Context(), hostID, containerID, timeout); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Container restarted successfully"})
+}
+
+// RemoveContainer godoc
+// @Summary Remove container
+// @Description Remove a Docker container
+// @Tags docker
+// @Accept json
+// @Produce json
+// @Param host_id path string true "Docker host ID"
+// @Param container_id path string true "Container ID"
+// @Param force query boolean false "Remove a running container" default(false)
+// @Success 204
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/docker/hosts/{host_id}/containers/{container_id} [delete]
+func (h *DockerHandler) RemoveContainer(c *gin.Context) {
+	hostID := c.Param("host_id")
+	containerID := c.Param("container_id")
+	force := c.DefaultQuery("force", "false") == "true"
+
+	if err := h.dockerUsecase.RemoveContainer(c.Request.Context(), hostID, containerID, force); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetContainerStats godoc
+// @Summary Get container stats
+// @Description Get the resource usage of a Docker container
+// @Tags docker
+// @Accept json
+// @Produce json
+// @Param host_id path string true "Docker host ID"
+// @Param container_id path string true "Container ID"
+// @Success 200 {object} domain.ContainerStats
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/docker/hosts/{host_id}/containers/{container_id}/stats [get]
+func (h *DockerHandler) GetContainerStats(c *gin.Context) {
+	hostID := c.Param("host_id")
+	containerID := c.Param("container_id")
+
+	stats, err := h.dockerUsecase.GetContainerStats(c.Request.Context(), hostID, containerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
 // GetContainerLogs godoc
 // @Summary Get container logs
 // @Description Get logs from a Docker container
@@ -320,3 +470,134 @@ func (h *DockerHandler) PullImage(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Image pulled successfully"})
 }
+
+// RemoveImage godoc
+// @Summary Remove image
+// @Description Remove a Docker image
+// @Tags docker
+// @Accept json
+// @Produce json
+// @Param host_id path string true "Docker host ID"
+// @Param image_id path string true "Image ID"
+// @Param force query boolean false "Force removal" default(false)
+// @Success 204
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/docker/hosts/{host_id}/images/{image_id} [delete]
+func (h *DockerHandler) RemoveImage(c *gin.Context) {
+	hostID := c.Param("host_id")
+	imageID := c.Param("image_id")
+	force := c.DefaultQuery("force", "false") == "true"
+
+	if err := h.dockerUsecase.RemoveImage(c.Request.Context(), hostID, imageID, force); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Network Management
+
+// ListNetworks godoc
+// @Summary List networks
+// @Description List all networks on a Docker host
+// @Tags docker
+// @Accept json
+// @Produce json
+// @Param host_id path string true "Docker host ID"
+// @Success 200 {array} domain.Network
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/docker/hosts/{host_id}/networks [get]
+func (h *DockerHandler) ListNetworks(c *gin.Context) {
+	hostID := c.Param("host_id")
+
+	networks, err := h.dockerUsecase.ListNetworks(c.Request.Context(), hostID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, networks)
+}
+
+// Volume Management
+
+// ListVolumes godoc
+// @Summary List volumes
+// @Description List all volumes on a Docker host
+// @Tags docker
+// @Accept json
+// @Produce json
+// @Param host_id path string true "Docker host ID"
+// @Success 200 {array} domain.Volume
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/docker/hosts/{host_id}/volumes [get]
+func (h *DockerHandler) ListVolumes(c *gin.Context) {
+	hostID := c.Param("host_id")
+
+	volumes, err := h.dockerUsecase.ListVolumes(c.Request.Context(), hostID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, volumes)
+}
+
+// CreateVolume godoc
+// @Summary Create volume
+// @Description Create a volume on a Docker host
+// @Tags docker
+// @Accept json
+// @Produce json
+// @Param host_id path string true "Docker host ID"
+// @Param request body map[string]string true "Volume name and driver"
+// @Success 201 {object} domain.Volume
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/docker/hosts/{host_id}/volumes [post]
+func (h *DockerHandler) CreateVolume(c *gin.Context) {
+	hostID := c.Param("host_id")
+
+	var req struct {
+		Name   string `json:"name" binding:"required"`
+		Driver string `json:"driver"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	volume, err := h.dockerUsecase.CreateVolume(c.Request.Context(), hostID, req.Name, req.Driver)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, volume)
+}
+
+// RemoveVolume godoc
+// @Summary Remove volume
+// @Description Remove a volume from a Docker host
+// @Tags docker
+// @Accept json
+// @Produce json
+// @Param host_id path string true "Docker host ID"
+// @Param volume_name path string true "Volume name"
+// @Param force query boolean false "Remove a volume in use" default(false)
+// @Success 204
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/docker/hosts/{host_id}/volumes/{volume_name} [delete]
+func (h *DockerHandler) RemoveVolume(c *gin.Context) {
+	hostID := c.Param("host_id")
+	volumeName := c.Param("volume_name")
+	force := c.DefaultQuery("force", "false") == "true"
+
+	if err := h.dockerUsecase.RemoveVolume(c.Request.Context(), hostID, volumeName, force); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -31,6 +31,7 @@ func NewDockerImageHandler(imageUsecase usecase.DockerImageUsecase) *DockerImage
 // @Param file formData file true "Build context (tar/tar.gz)"
 // @Param dockerfile formData string false "Dockerfile path" default:"Dockerfile"
 // @Param tags formData string false "Image tags (comma separated)"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} map[string]interface{} "Build started"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -47,7 +48,7 @@ func (h *DockerImageHandler) BuildImage(c *gin.Context) {
 	dockerfile := c.DefaultPostForm("dockerfile", "Dockerfile")
 	tags := c.PostFormArray("tags")
 
-	reader, err := h.imageUsecase.BuildImage(c.Request.Context(), dockerfile, file, tags)
+	reader, err := h.imageUsecase.BuildImage(c.Request.Context(), c.Query("host_id"), dockerfile, file, tags)
 	if err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to start build"))
 		return
@@ -75,6 +76,7 @@ type PushImageRequest struct {
 // @Accept json
 // @Produce application/json
 // @Param request body PushImageRequest true "Push request"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} map[string]interface{} "Push started"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -93,7 +95,7 @@ func (h *DockerImageHandler) PushImage(c *gin.Context) {
 		ServerAddress: req.Server,
 	}
 
-	reader, err := h.imageUsecase.PushImage(c.Request.Context(), req.Image, authConfig)
+	reader, err := h.imageUsecase.PushImage(c.Request.Context(), c.Query("host_id"), req.Image, authConfig)
 	if err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to start push"))
 		return
@@ -113,6 +115,7 @@ func (h *DockerImageHandler) PushImage(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Image ID"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} map[string]interface{} "Image details"
 // @Failure 500 {object} errorx.Error "Internal server error"
 // @Router /api/v1/docker/images/{id} [get]
@@ -120,7 +123,7 @@ func (h *DockerImageHandler) PushImage(c *gin.Context) {
 func (h *DockerImageHandler) InspectImage(c *gin.Context) {
 	imageID := c.Param("id")
 
-	info, err := h.imageUsecase.InspectImage(c.Request.Context(), imageID)
+	info, err := h.imageUsecase.InspectImage(c.Request.Context(), c.Query("host_id"), imageID)
 	if err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to inspect image"))
 		return
@@ -137,6 +140,7 @@ func (h *DockerImageHandler) InspectImage(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Image ID"
 // @Param force query bool false "Force removal"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} map[string]interface{} "Image removed"
 // @Failure 500 {object} errorx.Error "Internal server error"
 // @Router /api/v1/docker/images/{id} [delete]
@@ -145,7 +149,7 @@ func (h *DockerImageHandler) RemoveImage(c *gin.Context) {
 	imageID := c.Param("id")
 	force := c.Query("force") == "true"
 
-	deleted, err := h.imageUsecase.RemoveImage(c.Request.Context(), imageID, force)
+	deleted, err := h.imageUsecase.RemoveImage(c.Request.Context(), c.Query("host_id"), imageID, force)
 	if err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to remove image"))
 		return
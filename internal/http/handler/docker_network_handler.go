@@ -33,6 +33,7 @@ type ConnectContainerRequest struct {
 // @Produce json
 // @Param id path string true "Network ID"
 // @Param request body ConnectContainerRequest true "Connection request"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} map[string]interface{} "Connected successfully"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -47,7 +48,7 @@ func (h *DockerNetworkHandler) ConnectContainer(c *gin.Context) {
 		return
 	}
 
-	if err := h.networkUsecase.ConnectContainer(c.Request.Context(), networkID, req.ContainerID); err != nil {
+	if err := h.networkUsecase.ConnectContainer(c.Request.Context(), c.Query("host_id"), networkID, req.ContainerID); err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to connect container"))
 		return
 	}
@@ -72,6 +73,7 @@ type DisconnectContainerRequest struct {
 // @Produce json
 // @Param id path string true "Network ID"
 // @Param request body DisconnectContainerRequest true "Disconnection request"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} map[string]interface{} "Disconnected successfully"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -86,7 +88,7 @@ func (h *DockerNetworkHandler) DisconnectContainer(c *gin.Context) {
 		return
 	}
 
-	if err := h.networkUsecase.DisconnectContainer(c.Request.Context(), networkID, req.ContainerID); err != nil {
+	if err := h.networkUsecase.DisconnectContainer(c.Request.Context(), c.Query("host_id"), networkID, req.ContainerID); err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to disconnect container"))
 		return
 	}
@@ -111,6 +113,7 @@ type CreateNetworkRequest struct {
 // @Accept json
 // @Produce json
 // @Param request body CreateNetworkRequest true "Network creation request"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 201 {object} map[string]interface{} "Network created successfully"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -123,7 +126,7 @@ func (h *DockerNetworkHandler) CreateNetwork(c *gin.Context) {
 		return
 	}
 
-	id, err := h.networkUsecase.CreateNetwork(c.Request.Context(), req.Name, req.Driver)
+	id, err := h.networkUsecase.CreateNetwork(c.Request.Context(), c.Query("host_id"), req.Name, req.Driver)
 	if err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to create network"))
 		return
@@ -144,6 +147,7 @@ func (h *DockerNetworkHandler) CreateNetwork(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Network ID"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} map[string]interface{} "Network removed successfully"
 // @Failure 500 {object} errorx.Error "Internal server error"
 // @Router /api/v1/docker/networks/{id} [delete]
@@ -151,7 +155,7 @@ func (h *DockerNetworkHandler) CreateNetwork(c *gin.Context) {
 func (h *DockerNetworkHandler) RemoveNetwork(c *gin.Context) {
 	networkID := c.Param("id")
 
-	if err := h.networkUsecase.RemoveNetwork(c.Request.Context(), networkID); err != nil {
+	if err := h.networkUsecase.RemoveNetwork(c.Request.Context(), c.Query("host_id"), networkID); err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to remove network"))
 		return
 	}
@@ -169,6 +173,7 @@ func (h *DockerNetworkHandler) RemoveNetwork(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Network ID"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} map[string]interface{} "Network details"
 // @Failure 500 {object} errorx.Error "Internal server error"
 // @Router /api/v1/docker/networks/{id} [get]
@@ -176,7 +181,7 @@ func (h *DockerNetworkHandler) RemoveNetwork(c *gin.Context) {
 func (h *DockerNetworkHandler) InspectNetwork(c *gin.Context) {
 	networkID := c.Param("id")
 
-	info, err := h.networkUsecase.InspectNetwork(c.Request.Context(), networkID)
+	info, err := h.networkUsecase.InspectNetwork(c.Request.Context(), c.Query("host_id"), networkID)
 	if err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to inspect network"))
 		return
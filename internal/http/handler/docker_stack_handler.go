@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/unitechio/einfra-be/internal/domain"
@@ -9,6 +12,9 @@ import (
 	"github.com/unitechio/einfra-be/pkg/errorx"
 )
 
+// maxGitWebhookBodySize bounds push webhook payloads; forges cap the commits listed per push, so they stay well below it
+const maxGitWebhookBodySize = 5 << 20
+
 // DockerStackHandler handles Docker stack operations
 type DockerStackHandler struct {
 	stackUsecase usecase.DockerStackUsecase
@@ -23,13 +29,14 @@ func NewDockerStackHandler(stackUsecase usecase.DockerStackUsecase) *DockerStack
 
 // DeployStack deploys a new Docker Compose stack
 // @Summary Deploy Docker stack
-// @Description Deploy a new Docker Compose stack from YAML
+// @Description Deploy a new Docker Compose stack from YAML. Images are checked against the vulnerability policy of the stack's environment
 // @Tags docker-stacks
 // @Accept json
 // @Produce json
 // @Param request body domain.StackDeployRequest true "Stack deployment configuration"
 // @Success 201 {object} domain.DockerStack "Stack deployed successfully"
 // @Failure 400 {object} errorx.Error "Invalid request"
+// @Failure 403 {object} map[string]interface{} "Blocked by vulnerability policy"
 // @Failure 409 {object} errorx.Error "Stack already exists"
 // @Failure 500 {object} errorx.Error "Internal server error"
 // @Router /api/v1/docker/stacks [post]
@@ -47,6 +54,9 @@ func (h *DockerStackHandler) DeployStack(c *gin.Context) {
 
 	stack, err := h.stackUsecase.DeployStack(c.Request.Context(), req, userIDStr)
 	if err != nil {
+		if respondVulnerabilityGateError(c, err) {
+			return
+		}
 		if err.Error() == "stack with name "+req.Name+" already exists" {
 			c.Error(errorx.New(errorx.CodeConflict, err.Error()))
 		} else {
@@ -110,15 +120,17 @@ func (h *DockerStackHandler) GetStack(c *gin.Context) {
 
 // UpdateStack updates an existing stack
 // @Summary Update Docker stack
-// @Description Update a Docker stack with new compose file
+// @Description Roll out a new compose file as a new revision. Changed services are recreated one container at a time; when a new container fails its healthcheck the previous revision is restored
 // @Tags docker-stacks
 // @Accept json
 // @Produce json
 // @Param id path string true "Stack ID"
 // @Param request body domain.StackUpdateRequest true "Stack update configuration"
-// @Success 200 {object} map[string]interface{} "Stack updated successfully"
+// @Success 202 {object} map[string]interface{} "Stack update initiated"
 // @Failure 400 {object} errorx.Error "Invalid request"
+// @Failure 403 {object} map[string]interface{} "Blocked by vulnerability policy"
 // @Failure 404 {object} errorx.Error "Stack not found"
+// @Failure 409 {object} errorx.Error "Stack is being deployed"
 // @Failure 500 {object} errorx.Error "Internal server error"
 // @Router /api/v1/docker/stacks/{id} [put]
 // @Security BearerAuth
@@ -131,17 +143,354 @@ func (h *DockerStackHandler) UpdateStack(c *gin.Context) {
 		return
 	}
 
-	if err := h.stackUsecase.UpdateStack(c.Request.Context(), stackID, req); err != nil {
-		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to update stack"))
+	revision, err := h.stackUsecase.UpdateStack(c.Request.Context(), stackID, req, c.GetString("user_id"))
+	if err != nil {
+		h.respondRolloutError(c, err, "Failed to update stack")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "Stack update initiated",
+		"stack_id": stackID,
+		"revision": revision,
+	})
+}
+
+// DiffStack previews an update of a stack
+// @Summary Diff Docker stack
+// @Description Compare the services of a stack with an updated compose file without deploying it
+// @Tags docker-stacks
+// @Accept json
+// @Produce json
+// @Param id path string true "Stack ID"
+// @Param request body domain.StackUpdateRequest true "Updated stack configuration"
+// @Success 200 {array} domain.StackServiceDiff "Per-service changes"
+// @Failure 400 {object} errorx.Error "Invalid request"
+// @Failure 404 {object} errorx.Error "Stack not found"
+// @Router /api/v1/docker/stacks/{id}/diff [post]
+// @Security BearerAuth
+func (h *DockerStackHandler) DiffStack(c *gin.Context) {
+	stackID := c.Param("id")
+
+	var req domain.StackUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+		return
+	}
+
+	diff, err := h.stackUsecase.DiffStack(c.Request.Context(), stackID, req)
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to diff stack"))
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// ListStackRevisions lists the revisions of a stack
+// @Summary List Docker stack revisions
+// @Description Get the compose file history of a stack, newest first
+// @Tags docker-stacks
+// @Accept json
+// @Produce json
+// @Param id path string true "Stack ID"
+// @Success 200 {array} domain.StackRevision "Stack revisions"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/docker/stacks/{id}/revisions [get]
+// @Security BearerAuth
+func (h *DockerStackHandler) ListStackRevisions(c *gin.Context) {
+	stackID := c.Param("id")
+
+	revisions, err := h.stackUsecase.ListRevisions(c.Request.Context(), stackID)
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to list stack revisions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// RollbackStack redeploys an earlier revision of a stack
+// @Summary Roll back Docker stack
+// @Description Roll out an earlier revision again as a new revision; without a revision the one before the current is used
+// @Tags docker-stacks
+// @Accept json
+// @Produce json
+// @Param id path string true "Stack ID"
+// @Param request body domain.StackRollbackRequest false "Revision to restore"
+// @Success 202 {object} map[string]interface{} "Stack rollback initiated"
+// @Failure 400 {object} errorx.Error "Invalid request"
+// @Failure 403 {object} map[string]interface{} "Blocked by vulnerability policy"
+// @Failure 404 {object} errorx.Error "Stack not found"
+// @Failure 409 {object} errorx.Error "Stack is being deployed"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/docker/stacks/{id}/rollback [post]
+// @Security BearerAuth
+func (h *DockerStackHandler) RollbackStack(c *gin.Context) {
+	stackID := c.Param("id")
+
+	var req domain.StackRollbackRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+			return
+		}
+	}
+
+	revision, err := h.stackUsecase.RollbackStack(c.Request.Context(), stackID, req, c.GetString("user_id"))
+	if err != nil {
+		h.respondRolloutError(c, err, "Failed to roll back stack")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "Stack rollback initiated",
+		"stack_id": stackID,
+		"revision": revision,
+	})
+}
+
+// ApproveStackRevision rolls out a revision pending approval
+// @Summary Approve Docker stack revision
+// @Description Roll out a commit of a git-backed stack that waits for approval
+// @Tags docker-stacks
+// @Accept json
+// @Produce json
+// @Param id path string true "Stack ID"
+// @Param revision path int true "Revision number"
+// @Param request body domain.StackRevisionApproveRequest false "Rollout options"
+// @Success 202 {object} map[string]interface{} "Stack update initiated"
+// @Failure 400 {object} errorx.Error "Invalid request"
+// @Failure 403 {object} map[string]interface{} "Blocked by vulnerability policy"
+// @Failure 409 {object} errorx.Error "Stack is being deployed"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/docker/stacks/{id}/revisions/{revision}/approve [post]
+// @Security BearerAuth
+func (h *DockerStackHandler) ApproveStackRevision(c *gin.Context) {
+	stackID := c.Param("id")
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid revision"))
+		return
+	}
+
+	var req domain.StackRevisionApproveRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+			return
+		}
+	}
+
+	approved, err := h.stackUsecase.ApproveRevision(c.Request.Context(), stackID, revision, req, c.GetString("user_id"))
+	if err != nil {
+		h.respondRolloutError(c, err, "Failed to approve revision")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
 		"message":  "Stack update initiated",
 		"stack_id": stackID,
+		"revision": approved,
 	})
 }
 
+// RejectStackRevision discards a revision pending approval
+// @Summary Reject Docker stack revision
+// @Description Discard a commit of a git-backed stack that waits for approval
+// @Tags docker-stacks
+// @Accept json
+// @Produce json
+// @Param id path string true "Stack ID"
+// @Param revision path int true "Revision number"
+// @Success 200 {object} domain.StackRevision "Rejected revision"
+// @Failure 400 {object} errorx.Error "Invalid request"
+// @Router /api/v1/docker/stacks/{id}/revisions/{revision}/reject [post]
+// @Security BearerAuth
+func (h *DockerStackHandler) RejectStackRevision(c *gin.Context) {
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid revision"))
+		return
+	}
+
+	rejected, err := h.stackUsecase.RejectRevision(c.Request.Context(), c.Param("id"), revision, c.GetString("user_id"))
+	if err != nil {
+		if errorx.GetCode(err) != 0 {
+			c.Error(err)
+			return
+		}
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to reject revision"))
+		return
+	}
+
+	c.JSON(http.StatusOK, rejected)
+}
+
+// ConfigureStackGit links a stack to a repository
+// @Summary Configure Docker stack git source
+// @Description Deploy the compose file of a stack from a branch of a git repository. New commits are found by polling or webhook and rolled out automatically or after approval. The response holds the webhook secret
+// @Tags docker-stacks
+// @Accept json
+// @Produce json
+// @Param id path string true "Stack ID"
+// @Param request body domain.StackGitRequest true "Repository"
+// @Success 200 {object} domain.StackGitSource "Git source"
+// @Failure 400 {object} errorx.Error "Invalid request"
+// @Router /api/v1/docker/stacks/{id}/git [put]
+// @Security BearerAuth
+func (h *DockerStackHandler) ConfigureStackGit(c *gin.Context) {
+	var req domain.StackGitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+		return
+	}
+
+	source, err := h.stackUsecase.ConfigureGitSource(c.Request.Context(), c.Param("id"), req, c.GetString("user_id"))
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to configure git source"))
+		return
+	}
+
+	c.JSON(http.StatusOK, source)
+}
+
+// GetStackGit gets the repository of a stack
+// @Summary Get Docker stack git source
+// @Description Get the repository a stack deploys from and the outcome of its last check
+// @Tags docker-stacks
+// @Accept json
+// @Produce json
+// @Param id path string true "Stack ID"
+// @Success 200 {object} domain.StackGitSource "Git source"
+// @Failure 404 {object} errorx.Error "Stack is not deployed from git"
+// @Router /api/v1/docker/stacks/{id}/git [get]
+// @Security BearerAuth
+func (h *DockerStackHandler) GetStackGit(c *gin.Context) {
+	source, err := h.stackUsecase.GetGitSource(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeNotFound, "Git source not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, source)
+}
+
+// RemoveStackGit detaches a stack from its repository
+// @Summary Remove Docker stack git source
+// @Description Stop deploying a stack from git; the deployed compose file is kept
+// @Tags docker-stacks
+// @Accept json
+// @Produce json
+// @Param id path string true "Stack ID"
+// @Success 200 {object} map[string]interface{} "Git source removed"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/docker/stacks/{id}/git [delete]
+// @Security BearerAuth
+func (h *DockerStackHandler) RemoveStackGit(c *gin.Context) {
+	stackID := c.Param("id")
+
+	if err := h.stackUsecase.RemoveGitSource(c.Request.Context(), stackID); err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to remove git source"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Git source removed successfully",
+		"stack_id": stackID,
+	})
+}
+
+// SyncStackGit checks the repository of a stack for a new commit
+// @Summary Sync Docker stack from git
+// @Description Check the branch of a git-backed stack now; a new commit is rolled out or recorded for approval
+// @Tags docker-stacks
+// @Accept json
+// @Produce json
+// @Param id path string true "Stack ID"
+// @Success 200 {object} map[string]interface{} "Created revision, null when the stack is up to date"
+// @Failure 403 {object} map[string]interface{} "Blocked by vulnerability policy"
+// @Failure 409 {object} errorx.Error "Stack is being deployed"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/docker/stacks/{id}/git/sync [post]
+// @Security BearerAuth
+func (h *DockerStackHandler) SyncStackGit(c *gin.Context) {
+	stackID := c.Param("id")
+
+	revision, err := h.stackUsecase.SyncGitStack(c.Request.Context(), stackID, c.GetString("user_id"))
+	if err != nil {
+		h.respondRolloutError(c, err, "Failed to sync stack from git")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stack_id": stackID,
+		"revision": revision,
+	})
+}
+
+// ReceiveGitWebhook godoc
+// @Summary Receive git push webhook
+// @Description Endpoint for push webhooks of GitHub, Gitea or GitLab. Sign the payload with the stack's webhook_secret (X-Hub-Signature-256 or X-Gitea-Signature), or send it as X-Gitlab-Token or Authorization header. The branch is checked in the background
+// @Tags docker-stacks
+// @Accept json
+// @Produce json
+// @Param id path string true "Stack ID"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/docker/webhooks/stacks/{id} [post]
+func (h *DockerStackHandler) ReceiveGitWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxGitWebhookBodySize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(body) > maxGitWebhookBodySize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "webhook payload too large"})
+		return
+	}
+
+	signature := c.GetHeader("X-Hub-Signature-256")
+	if signature == "" {
+		signature = c.GetHeader("X-Gitea-Signature")
+	}
+	token := c.GetHeader("X-Gitlab-Token")
+	if token == "" {
+		token = c.GetHeader("Authorization")
+	}
+
+	if err := h.stackUsecase.HandleGitWebhook(c.Request.Context(), c.Param("id"), signature, token, body); err != nil {
+		c.JSON(gitWebhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Push received"})
+}
+
+// gitWebhookErrorStatus maps errorx codes from the webhook check (bad signature, unknown stack) to HTTP statuses
+func gitWebhookErrorStatus(err error) int {
+	if code := errorx.GetCode(err); code != 0 {
+		return code
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *DockerStackHandler) respondRolloutError(c *gin.Context, err error, message string) {
+	if respondVulnerabilityGateError(c, err) {
+		return
+	}
+	if errors.Is(err, domain.ErrStackBusy) {
+		c.Error(errorx.New(errorx.CodeConflict, err.Error()))
+		return
+	}
+	if errorx.GetCode(err) != 0 {
+		c.Error(err)
+		return
+	}
+	c.Error(errorx.Wrap(err, errorx.CodeInternalError, message))
+}
+
 // RemoveStack removes a stack
 // @Summary Remove Docker stack
 // @Description Remove a Docker stack and all its services
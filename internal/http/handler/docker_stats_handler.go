@@ -37,6 +37,7 @@ func NewDockerStatsHandler(statsUsecase usecase.DockerStatsUsecase) *DockerStats
 // @Accept json
 // @Produce json
 // @Param id path string true "Container ID"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 101 {object} docker.ContainerStats "Switching to WebSocket"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -55,7 +56,7 @@ func (h *DockerStatsHandler) GetStatsStream(c *gin.Context) {
 
 	// Get stats stream
 	ctx := c.Request.Context()
-	statsChan, errChan, err := h.statsUsecase.GetStatsStream(ctx, containerID)
+	statsChan, errChan, err := h.statsUsecase.GetStatsStream(ctx, c.Query("host_id"), containerID)
 	if err != nil {
 		conn.WriteJSON(map[string]interface{}{
 			"error": err.Error(),
@@ -96,6 +97,7 @@ func (h *DockerStatsHandler) GetStatsStream(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Container ID"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} docker.ContainerStats "Container stats"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -104,7 +106,7 @@ func (h *DockerStatsHandler) GetStatsStream(c *gin.Context) {
 func (h *DockerStatsHandler) GetStatsOnce(c *gin.Context) {
 	containerID := c.Param("id")
 
-	stats, err := h.statsUsecase.GetStatsOnce(c.Request.Context(), containerID)
+	stats, err := h.statsUsecase.GetStatsOnce(c.Request.Context(), c.Query("host_id"), containerID)
 	if err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to get stats"))
 		return
@@ -120,6 +122,7 @@ func (h *DockerStatsHandler) GetStatsOnce(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Container ID"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} map[string]interface{} "Container paused"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -128,7 +131,7 @@ func (h *DockerStatsHandler) GetStatsOnce(c *gin.Context) {
 func (h *DockerStatsHandler) PauseContainer(c *gin.Context) {
 	containerID := c.Param("id")
 
-	if err := h.statsUsecase.PauseContainer(c.Request.Context(), containerID); err != nil {
+	if err := h.statsUsecase.PauseContainer(c.Request.Context(), c.Query("host_id"), containerID); err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to pause container"))
 		return
 	}
@@ -146,6 +149,7 @@ func (h *DockerStatsHandler) PauseContainer(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Container ID"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 200 {object} map[string]interface{} "Container unpaused"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -154,7 +158,7 @@ func (h *DockerStatsHandler) PauseContainer(c *gin.Context) {
 func (h *DockerStatsHandler) UnpauseContainer(c *gin.Context) {
 	containerID := c.Param("id")
 
-	if err := h.statsUsecase.UnpauseContainer(c.Request.Context(), containerID); err != nil {
+	if err := h.statsUsecase.UnpauseContainer(c.Request.Context(), c.Query("host_id"), containerID); err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to unpause container"))
 		return
 	}
@@ -184,6 +188,7 @@ type CommitContainerRequest struct {
 // @Produce json
 // @Param id path string true "Container ID"
 // @Param request body CommitContainerRequest true "Commit configuration"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 201 {object} map[string]interface{} "Image created"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -209,7 +214,7 @@ func (h *DockerStatsHandler) CommitContainer(c *gin.Context) {
 		Config:      nil, // TODO: Map map[string]string to *container.Config if needed
 	}
 
-	imageID, err := h.statsUsecase.CommitContainer(c.Request.Context(), config)
+	imageID, err := h.statsUsecase.CommitContainer(c.Request.Context(), c.Query("host_id"), config)
 	if err != nil {
 		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to commit container"))
 		return
@@ -1,8 +1,6 @@
 package handler
 
 import (
-	"context"
-
 	"github.com/gin-gonic/gin"
 	"github.com/unitechio/einfra-be/internal/usecase"
 	"github.com/unitechio/einfra-be/pkg/errorx"
@@ -15,8 +13,6 @@ type EventHandler struct {
 
 // NewEventHandler creates a new event handler
 func NewEventHandler(eventUsecase usecase.EventUsecase) *EventHandler {
-	// Start monitoring in background
-	go eventUsecase.MonitorEvents(context.Background())
 	return &EventHandler{
 		eventUsecase: eventUsecase,
 	}
@@ -28,6 +24,7 @@ func NewEventHandler(eventUsecase usecase.EventUsecase) *EventHandler {
 // @Tags events
 // @Accept json
 // @Produce json
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 101 {object} map[string]interface{} "Switching to WebSocket"
 // @Failure 500 {object} errorx.Error "Internal server error"
 // @Router /api/v1/events/stream [get]
@@ -39,7 +36,8 @@ func (h *EventHandler) StreamEvents(c *gin.Context) {
 		return
 	}
 
-	h.eventUsecase.Subscribe(conn)
+	hostID := c.Query("host_id")
+	h.eventUsecase.Subscribe(hostID, conn)
 
 	// Keep connection open until client disconnects
 	// The write loop is handled by the usecase
@@ -47,7 +45,7 @@ func (h *EventHandler) StreamEvents(c *gin.Context) {
 	for {
 		_, _, err := conn.ReadMessage()
 		if err != nil {
-			h.eventUsecase.Unsubscribe(conn)
+			h.eventUsecase.Unsubscribe(hostID, conn)
 			break
 		}
 	}
@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
+	"mime"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/unitechio/einfra-be/internal/domain"
 	"github.com/unitechio/einfra-be/internal/usecase"
 	"github.com/unitechio/einfra-be/pkg/errorx"
 )
@@ -22,14 +25,16 @@ func NewFileBrowserHandler(fileBrowserUsecase usecase.FileBrowserUsecase) *FileB
 
 // ListFiles lists files in a volume
 // @Summary List files in volume
-// @Description List all files and directories in a Docker volume path
+// @Description List the files and directories of a Docker volume path, directories first. The volume is read through a short-lived helper container
 // @Tags file-browser
 // @Accept json
 // @Produce json
 // @Param volume_name path string true "Volume name"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Param path query string false "Path within volume" default:"/"
 // @Success 200 {array} domain.FileInfo "List of files"
 // @Failure 400 {object} errorx.Error "Invalid request"
+// @Failure 404 {object} errorx.Error "Volume or path not found"
 // @Failure 500 {object} errorx.Error "Internal server error"
 // @Router /api/v1/volumes/{volume_name}/browse [get]
 // @Security BearerAuth
@@ -37,9 +42,9 @@ func (h *FileBrowserHandler) ListFiles(c *gin.Context) {
 	volumeName := c.Param("volume_name")
 	path := c.DefaultQuery("path", "/")
 
-	files, err := h.fileBrowserUsecase.ListFiles(c.Request.Context(), volumeName, path)
+	files, err := h.fileBrowserUsecase.ListFiles(c.Request.Context(), c.Query("host_id"), volumeName, path)
 	if err != nil {
-		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to list files"))
+		respondFileBrowserError(c, err, "Failed to list files")
 		return
 	}
 
@@ -53,11 +58,12 @@ func (h *FileBrowserHandler) ListFiles(c *gin.Context) {
 
 // UploadFile uploads a file to a volume
 // @Summary Upload file to volume
-// @Description Upload a file to a Docker volume
+// @Description Upload a file of at most 100MiB into a directory of a Docker volume, replacing a file of the same name
 // @Tags file-browser
 // @Accept multipart/form-data
 // @Produce json
 // @Param volume_name path string true "Volume name"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Param path formData string true "Destination path"
 // @Param file formData file true "File to upload"
 // @Success 200 {object} map[string]interface{} "File uploaded successfully"
@@ -76,8 +82,8 @@ func (h *FileBrowserHandler) UploadFile(c *gin.Context) {
 	}
 	defer file.Close()
 
-	if err := h.fileBrowserUsecase.UploadFile(c.Request.Context(), volumeName, path, header.Filename, file); err != nil {
-		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to upload file"))
+	if err := h.fileBrowserUsecase.UploadFile(c.Request.Context(), c.Query("host_id"), volumeName, path, header.Filename, header.Size, file); err != nil {
+		respondFileBrowserError(c, err, "Failed to upload file")
 		return
 	}
 
@@ -91,12 +97,14 @@ func (h *FileBrowserHandler) UploadFile(c *gin.Context) {
 
 // DownloadFile downloads a file from a volume
 // @Summary Download file from volume
-// @Description Download a file from a Docker volume
+// @Description Download a file from a Docker volume, or a directory as a tar or zip archive. Downloads stop at 1GiB
 // @Tags file-browser
 // @Accept json
 // @Produce application/octet-stream
 // @Param volume_name path string true "Volume name"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Param path query string true "File path"
+// @Param format query string false "Archive format of directories" Enums(tar, zip) default(tar)
 // @Success 200 {file} binary "File content"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 404 {object} errorx.Error "File not found"
@@ -112,25 +120,26 @@ func (h *FileBrowserHandler) DownloadFile(c *gin.Context) {
 		return
 	}
 
-	reader, err := h.fileBrowserUsecase.DownloadFile(c.Request.Context(), volumeName, path)
+	download, err := h.fileBrowserUsecase.DownloadFile(c.Request.Context(), c.Query("host_id"), volumeName, path, c.Query("format"))
 	if err != nil {
-		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to download file"))
+		respondFileBrowserError(c, err, "Failed to download file")
 		return
 	}
-	defer reader.Close()
+	defer download.Content.Close()
 
-	c.Header("Content-Disposition", "attachment; filename="+path)
-	c.Header("Content-Type", "application/octet-stream")
-	c.DataFromReader(http.StatusOK, -1, "application/octet-stream", reader, nil)
+	c.DataFromReader(http.StatusOK, download.Size, download.ContentType, download.Content, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": download.Name}),
+	})
 }
 
 // DeleteFile deletes a file from a volume
 // @Summary Delete file from volume
-// @Description Delete a file or directory from a Docker volume
+// @Description Delete a file, or a directory with its content, from a Docker volume
 // @Tags file-browser
 // @Accept json
 // @Produce json
 // @Param volume_name path string true "Volume name"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Param path query string true "File path"
 // @Success 200 {object} map[string]interface{} "File deleted successfully"
 // @Failure 400 {object} errorx.Error "Invalid request"
@@ -146,8 +155,8 @@ func (h *FileBrowserHandler) DeleteFile(c *gin.Context) {
 		return
 	}
 
-	if err := h.fileBrowserUsecase.DeleteFile(c.Request.Context(), volumeName, path); err != nil {
-		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to delete file"))
+	if err := h.fileBrowserUsecase.DeleteFile(c.Request.Context(), c.Query("host_id"), volumeName, path); err != nil {
+		respondFileBrowserError(c, err, "Failed to delete file")
 		return
 	}
 
@@ -171,6 +180,7 @@ type CreateFolderRequest struct {
 // @Accept json
 // @Produce json
 // @Param volume_name path string true "Volume name"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Param request body CreateFolderRequest true "Folder creation request"
 // @Success 201 {object} map[string]interface{} "Folder created successfully"
 // @Failure 400 {object} errorx.Error "Invalid request"
@@ -186,8 +196,8 @@ func (h *FileBrowserHandler) CreateFolder(c *gin.Context) {
 		return
 	}
 
-	if err := h.fileBrowserUsecase.CreateFolder(c.Request.Context(), volumeName, req.Path, req.FolderName); err != nil {
-		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to create folder"))
+	if err := h.fileBrowserUsecase.CreateFolder(c.Request.Context(), c.Query("host_id"), volumeName, req.Path, req.FolderName); err != nil {
+		respondFileBrowserError(c, err, "Failed to create folder")
 		return
 	}
 
@@ -201,11 +211,12 @@ func (h *FileBrowserHandler) CreateFolder(c *gin.Context) {
 
 // ReadFile reads a text file from a volume
 // @Summary Read file content
-// @Description Read the content of a text file from a Docker volume
+// @Description Read the content of a text file of at most 1MiB from a Docker volume
 // @Tags file-browser
 // @Accept json
 // @Produce json
 // @Param volume_name path string true "Volume name"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Param path query string true "File path"
 // @Success 200 {object} map[string]interface{} "File content"
 // @Failure 400 {object} errorx.Error "Invalid request"
@@ -221,9 +232,9 @@ func (h *FileBrowserHandler) ReadFile(c *gin.Context) {
 		return
 	}
 
-	content, err := h.fileBrowserUsecase.ReadFile(c.Request.Context(), volumeName, path)
+	content, err := h.fileBrowserUsecase.ReadFile(c.Request.Context(), c.Query("host_id"), volumeName, path)
 	if err != nil {
-		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to read file"))
+		respondFileBrowserError(c, err, "Failed to read file")
 		return
 	}
 
@@ -233,3 +244,189 @@ func (h *FileBrowserHandler) ReadFile(c *gin.Context) {
 		"content": content,
 	})
 }
+
+// ListContainerFiles lists files in a container
+// @Summary List files in container
+// @Description List the files and directories of a container path, directories first. Directories whose archive exceeds 256MiB cannot be listed
+// @Tags file-browser
+// @Accept json
+// @Produce json
+// @Param id path string true "Container ID"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
+// @Param path query string false "Path within container" default:"/"
+// @Success 200 {array} domain.FileInfo "List of files"
+// @Failure 400 {object} errorx.Error "Invalid request"
+// @Failure 404 {object} errorx.Error "Container or path not found"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/docker/containers/{id}/files [get]
+// @Security BearerAuth
+func (h *FileBrowserHandler) ListContainerFiles(c *gin.Context) {
+	containerID := c.Param("id")
+	path := c.DefaultQuery("path", "/")
+
+	files, err := h.fileBrowserUsecase.ListContainerFiles(c.Request.Context(), c.Query("host_id"), containerID, path)
+	if err != nil {
+		respondFileBrowserError(c, err, "Failed to list files")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"container": containerID,
+		"path":      path,
+		"files":     files,
+		"count":     len(files),
+	})
+}
+
+// DownloadContainerFile downloads a file from a container
+// @Summary Download file from container
+// @Description Download a file from a container, or a directory as a tar or zip archive. Downloads stop at 1GiB
+// @Tags file-browser
+// @Accept json
+// @Produce application/octet-stream
+// @Param id path string true "Container ID"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
+// @Param path query string true "File path"
+// @Param format query string false "Archive format of directories" Enums(tar, zip) default(tar)
+// @Success 200 {file} binary "File content"
+// @Failure 400 {object} errorx.Error "Invalid request"
+// @Failure 404 {object} errorx.Error "File not found"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/docker/containers/{id}/files/download [get]
+// @Security BearerAuth
+func (h *FileBrowserHandler) DownloadContainerFile(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Path is required"))
+		return
+	}
+
+	download, err := h.fileBrowserUsecase.DownloadContainerFile(c.Request.Context(), c.Query("host_id"), c.Param("id"), path, c.Query("format"))
+	if err != nil {
+		respondFileBrowserError(c, err, "Failed to download file")
+		return
+	}
+	defer download.Content.Close()
+
+	c.DataFromReader(http.StatusOK, download.Size, download.ContentType, download.Content, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": download.Name}),
+	})
+}
+
+// UploadContainerFile uploads a file to a container
+// @Summary Upload file to container
+// @Description Upload a file of at most 100MiB into a directory of a container, replacing a file of the same name. The file is owned by root
+// @Tags file-browser
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Container ID"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
+// @Param path formData string true "Destination path"
+// @Param file formData file true "File to upload"
+// @Success 200 {object} map[string]interface{} "File uploaded successfully"
+// @Failure 400 {object} errorx.Error "Invalid request"
+// @Failure 404 {object} errorx.Error "Container or path not found"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/docker/containers/{id}/files/upload [post]
+// @Security BearerAuth
+func (h *FileBrowserHandler) UploadContainerFile(c *gin.Context) {
+	containerID := c.Param("id")
+	path := c.PostForm("path")
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "File is required"))
+		return
+	}
+	defer file.Close()
+
+	if err := h.fileBrowserUsecase.UploadContainerFile(c.Request.Context(), c.Query("host_id"), containerID, path, header.Filename, header.Size, file); err != nil {
+		respondFileBrowserError(c, err, "Failed to upload file")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "File uploaded successfully",
+		"container": containerID,
+		"path":      path,
+		"filename":  header.Filename,
+	})
+}
+
+// ReadContainerFile reads a text file from a container
+// @Summary Read container file content
+// @Description Read a text file of at most 1MiB from a container, with its mode, owner and the hash to edit it with. Binary files are refused
+// @Tags file-browser
+// @Accept json
+// @Produce json
+// @Param id path string true "Container ID"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
+// @Param path query string true "File path"
+// @Success 200 {object} domain.FileContent "File content"
+// @Failure 400 {object} errorx.Error "Invalid request or binary file"
+// @Failure 404 {object} errorx.Error "File not found"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/docker/containers/{id}/files/content [get]
+// @Security BearerAuth
+func (h *FileBrowserHandler) ReadContainerFile(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Path is required"))
+		return
+	}
+
+	content, err := h.fileBrowserUsecase.ReadContainerFile(c.Request.Context(), c.Query("host_id"), c.Param("id"), path)
+	if err != nil {
+		respondFileBrowserError(c, err, "Failed to read file")
+		return
+	}
+
+	c.JSON(http.StatusOK, content)
+}
+
+// WriteContainerFile writes a text file of a container
+// @Summary Edit container file
+// @Description Write a text file of a container, keeping its mode and owner. The hash read with the file must be sent back; the write is refused with 409 when the file changed since. An empty hash creates a new file
+// @Tags file-browser
+// @Accept json
+// @Produce json
+// @Param id path string true "Container ID"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
+// @Param request body domain.FileWriteRequest true "File content"
+// @Success 200 {object} domain.FileContent "File written"
+// @Failure 400 {object} errorx.Error "Invalid request or binary file"
+// @Failure 404 {object} errorx.Error "File not found"
+// @Failure 409 {object} errorx.Error "File changed or already exists"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/docker/containers/{id}/files/content [put]
+// @Security BearerAuth
+func (h *FileBrowserHandler) WriteContainerFile(c *gin.Context) {
+	var req domain.FileWriteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+		return
+	}
+
+	content, err := h.fileBrowserUsecase.WriteContainerFile(c.Request.Context(), c.Query("host_id"), c.Param("id"), req)
+	if err != nil {
+		respondFileBrowserError(c, err, "Failed to write file")
+		return
+	}
+
+	c.JSON(http.StatusOK, content)
+}
+
+// respondFileBrowserError reports missing files as not found, conflicting writes as conflicts and
+// refused paths, names and sizes as bad requests
+func respondFileBrowserError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, domain.ErrFileNotFound):
+		c.Error(errorx.Wrap(err, errorx.CodeNotFound, message))
+	case errors.Is(err, domain.ErrFileExists), errors.Is(err, domain.ErrFileChanged):
+		c.Error(errorx.Wrap(err, errorx.CodeConflict, message))
+	case errors.Is(err, domain.ErrInvalidFilePath), errors.Is(err, domain.ErrFileTooLarge), errors.Is(err, domain.ErrFileNotText):
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, message))
+	default:
+		c.Error(errorx.Wrap(err, errorx.CodeInternalError, message))
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/errorx"
 )
 
 type HarborHandler struct {
@@ -32,19 +33,20 @@ func NewHarborHandler(
 // @Tags harbor
 // @Accept json
 // @Produce json
-// @Param registry body domain.HarborRegistry true "Harbor registry object"
+// @Param registry body domain.HarborRegistryRequest true "Harbor registry object"
 // @Success 201 {object} domain.HarborRegistry
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/harbor/registries [post]
 func (h *HarborHandler) CreateRegistry(c *gin.Context) {
-	var registry domain.HarborRegistry
-	if err := c.ShouldBindJSON(&registry); err != nil {
+	var req domain.HarborRegistryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.harborUsecase.CreateRegistry(c.Request.Context(), &registry); err != nil {
+	registry := req.ToRegistry()
+	if err := h.harborUsecase.CreateRegistry(c.Request.Context(), registry); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -126,7 +128,7 @@ func (h *HarborHandler) GetRegistry(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Registry ID"
-// @Param registry body domain.HarborRegistry true "Harbor registry object"
+// @Param registry body domain.HarborRegistryRequest true "Harbor registry object"
 // @Success 200 {object} domain.HarborRegistry
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -134,15 +136,16 @@ func (h *HarborHandler) GetRegistry(c *gin.Context) {
 func (h *HarborHandler) UpdateRegistry(c *gin.Context) {
 	id := c.Param("id")
 
-	var registry domain.HarborRegistry
-	if err := c.ShouldBindJSON(&registry); err != nil {
+	var req domain.HarborRegistryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	registry := req.ToRegistry()
 	registry.ID = id
 
-	if err := h.harborUsecase.UpdateRegistry(c.Request.Context(), &registry); err != nil {
+	if err := h.harborUsecase.UpdateRegistry(c.Request.Context(), registry); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -171,6 +174,58 @@ func (h *HarborHandler) DeleteRegistry(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// TestRegistryConnection godoc
+// @Summary Test Harbor registry connection
+// @Description Connect to a registry with its stored credentials and report its version and health
+// @Tags harbor
+// @Produce json
+// @Param id path string true "Registry ID"
+// @Success 200 {object} domain.HarborRegistryStatus
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/harbor/registries/{id}/test [post]
+func (h *HarborHandler) TestRegistryConnection(c *gin.Context) {
+	status, err := h.harborUsecase.TestRegistryConnection(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// RunGarbageCollection godoc
+// @Summary Run Harbor garbage collection
+// @Description Start a registry-wide garbage collection that frees the storage of deleted artifacts. Requires an administrator account on the registry
+// @Tags harbor
+// @Accept json
+// @Produce json
+// @Param id path string true "Registry ID"
+// @Param request body map[string]bool false "Options, e.g. {\"delete_untagged\": true}"
+// @Success 202 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/harbor/registries/{id}/gc [post]
+func (h *HarborHandler) RunGarbageCollection(c *gin.Context) {
+	var req struct {
+		DeleteUntagged bool `json:"delete_untagged"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	jobID, err := h.harborUsecase.RunGarbageCollection(c.Request.Context(), c.Param("id"), req.DeleteUntagged)
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Garbage collection started", "job_id": jobID})
+}
+
 // Project Management
 
 // ListProjects godoc
@@ -195,7 +250,7 @@ func (h *HarborHandler) ListProjects(c *gin.Context) {
 
 	projects, err := h.harborUsecase.ListProjects(c.Request.Context(), registryID, public)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -221,7 +276,7 @@ func (h *HarborHandler) ListRepositories(c *gin.Context) {
 
 	repositories, err := h.harborUsecase.ListRepositories(c.Request.Context(), registryID, projectName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -247,7 +302,7 @@ func (h *HarborHandler) ListArtifacts(c *gin.Context) {
 
 	artifacts, err := h.harborUsecase.ListArtifacts(c.Request.Context(), registryID, repositoryName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -282,7 +337,7 @@ func (h *HarborHandler) ScanArtifact(c *gin.Context) {
 	}
 
 	if err := h.harborUsecase.ScanArtifact(c.Request.Context(), registryID, req.RepositoryName, req.Reference); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -308,7 +363,7 @@ func (h *HarborHandler) GetScanReport(c *gin.Context) {
 
 	report, err := h.harborUsecase.GetScanReport(c.Request.Context(), registryID, repositoryName, reference)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -319,13 +374,14 @@ func (h *HarborHandler) GetScanReport(c *gin.Context) {
 
 // TrackDeployment godoc
 // @Summary Track image deployment
-// @Description Record a new image deployment to a cluster
+// @Description Record a new image deployment to a cluster; images violating the environment's vulnerability policy are rejected
 // @Tags harbor
 // @Accept json
 // @Produce json
 // @Param request body map[string]string true "Deployment details"
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/harbor/deployments [post]
 func (h *HarborHandler) TrackDeployment(c *gin.Context) {
@@ -346,7 +402,7 @@ func (h *HarborHandler) TrackDeployment(c *gin.Context) {
 	// TODO: Get user from context
 	user := "system"
 
-	if err := h.imageDeploymentUsecase.TrackDeployment(
+	report, err := h.imageDeploymentUsecase.TrackDeployment(
 		c.Request.Context(),
 		req.ClusterID,
 		req.Namespace,
@@ -355,12 +411,16 @@ func (h *HarborHandler) TrackDeployment(c *gin.Context) {
 		req.ImageRepo,
 		req.ImageTag,
 		user,
-	); err != nil {
+	)
+	if err != nil {
+		if respondVulnerabilityGateError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Deployment tracked successfully"})
+	c.JSON(http.StatusCreated, gin.H{"message": "Deployment tracked successfully", "vulnerability_gate": report})
 }
 
 // GetDeploymentHistory godoc
@@ -389,6 +449,32 @@ func (h *HarborHandler) GetDeploymentHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, history)
 }
 
+// GetDeploymentChanges godoc
+// @Summary Get deployment change history
+// @Description Get recorded image changes and rollout actions (rollback, restart, pause, resume) for a workload
+// @Tags harbor
+// @Accept json
+// @Produce json
+// @Param cluster_id query string true "Cluster ID"
+// @Param namespace query string true "Namespace"
+// @Param deployment_name query string true "Deployment Name"
+// @Success 200 {array} domain.ImageDeploymentHistory
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/harbor/deployments/changes [get]
+func (h *HarborHandler) GetDeploymentChanges(c *gin.Context) {
+	clusterID := c.Query("cluster_id")
+	namespace := c.Query("namespace")
+	deploymentName := c.Query("deployment_name")
+
+	changes, err := h.imageDeploymentUsecase.GetChangeHistory(c.Request.Context(), clusterID, namespace, deploymentName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
 // GetCurrentDeployments godoc
 // @Summary Get current deployments
 // @Description Get all currently active image deployments for a cluster
@@ -431,3 +517,11 @@ func (h *HarborHandler) SyncDeployments(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Sync triggered successfully"})
 }
+
+// harborErrorStatus maps errorx codes from the Harbor client to HTTP statuses
+func harborErrorStatus(err error) int {
+	if code := errorx.GetCode(err); code != 0 {
+		return code
+	}
+	return http.StatusInternalServerError
+}
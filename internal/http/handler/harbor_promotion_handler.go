@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unitechio/einfra-be/internal/domain"
+)
+
+type HarborPromotionHandler struct {
+	promotionUsecase domain.HarborPromotionUsecase
+}
+
+// NewHarborPromotionHandler creates a new Harbor promotion handler instance
+func NewHarborPromotionHandler(promotionUsecase domain.HarborPromotionUsecase) *HarborPromotionHandler {
+	return &HarborPromotionHandler{
+		promotionUsecase: promotionUsecase,
+	}
+}
+
+// respondPromotion answers with the promotion, or with the error and the failed promotion record
+func respondPromotion(c *gin.Context, status int, promotion *domain.HarborPromotion, err error) {
+	if err != nil {
+		body := gin.H{"error": err.Error()}
+		if promotion != nil {
+			body["promotion"] = promotion
+		}
+		c.JSON(harborErrorStatus(err), body)
+		return
+	}
+	c.JSON(status, promotion)
+}
+
+// Promotion Rules
+
+// CreateRule godoc
+// @Summary Create promotion rule
+// @Description Create a rule promoting artifacts from a source project to a target project of a registry
+// @Tags harbor-promotion
+// @Accept json
+// @Produce json
+// @Param rule body domain.HarborPromotionRule true "Promotion rule"
+// @Success 201 {object} domain.HarborPromotionRule
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/harbor/promotion-rules [post]
+func (h *HarborPromotionHandler) CreateRule(c *gin.Context) {
+	var rule domain.HarborPromotionRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.promotionUsecase.CreateRule(c.Request.Context(), &rule, c.GetString("user_id")); err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// ListRules godoc
+// @Summary List promotion rules
+// @Description List promotion rules, optionally of a single registry
+// @Tags harbor-promotion
+// @Produce json
+// @Param registry_id query string false "Registry ID"
+// @Success 200 {array} domain.HarborPromotionRule
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/harbor/promotion-rules [get]
+func (h *HarborPromotionHandler) ListRules(c *gin.Context) {
+	rules, err := h.promotionUsecase.ListRules(c.Request.Context(), c.Query("registry_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// GetRule godoc
+// @Summary Get promotion rule
+// @Description Get a promotion rule by ID
+// @Tags harbor-promotion
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Success 200 {object} domain.HarborPromotionRule
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/harbor/promotion-rules/{id} [get]
+func (h *HarborPromotionHandler) GetRule(c *gin.Context) {
+	rule, err := h.promotionUsecase.GetRule(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// UpdateRule godoc
+// @Summary Update promotion rule
+// @Description Replace a promotion rule
+// @Tags harbor-promotion
+// @Accept json
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Param rule body domain.HarborPromotionRule true "Promotion rule"
+// @Success 200 {object} domain.HarborPromotionRule
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/harbor/promotion-rules/{id} [put]
+func (h *HarborPromotionHandler) UpdateRule(c *gin.Context) {
+	var rule domain.HarborPromotionRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule.ID = c.Param("id")
+
+	if err := h.promotionUsecase.UpdateRule(c.Request.Context(), &rule); err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule godoc
+// @Summary Delete promotion rule
+// @Description Delete a promotion rule; promotions made through it stay on record
+// @Tags harbor-promotion
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/harbor/promotion-rules/{id} [delete]
+func (h *HarborPromotionHandler) DeleteRule(c *gin.Context) {
+	if err := h.promotionUsecase.DeleteRule(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promotion rule deleted successfully"})
+}
+
+// Promotions
+
+// Promote godoc
+// @Summary Promote artifact
+// @Description Copy an artifact from the rule's source project to its target project and label it. The artifact's scan must pass the rule's severity limit.
+// @Description Rules with an approval role answer 202 with a pending promotion instead
+// @Tags harbor-promotion
+// @Accept json
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Param request body domain.HarborPromotionRequest true "Artifact to promote"
+// @Success 201 {object} domain.HarborPromotion
+// @Success 202 {object} domain.HarborPromotion
+// @Failure 400 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/harbor/promotion-rules/{id}/promote [post]
+func (h *HarborPromotionHandler) Promote(c *gin.Context) {
+	var req domain.HarborPromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promotion, err := h.promotionUsecase.Promote(c.Request.Context(), c.Param("id"), req, c.GetString("user_id"))
+	status := http.StatusCreated
+	if promotion != nil && promotion.Status == domain.HarborPromotionStatusPendingApproval {
+		status = http.StatusAccepted
+	}
+	respondPromotion(c, status, promotion, err)
+}
+
+// ListPromotions godoc
+// @Summary List promotions
+// @Description List promotions, newest first
+// @Tags harbor-promotion
+// @Produce json
+// @Param rule_id query string false "Rule ID"
+// @Param digest query string false "Artifact digest"
+// @Param status query string false "pending_approval, completed, rejected or failed"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/harbor/promotions [get]
+func (h *HarborPromotionHandler) ListPromotions(c *gin.Context) {
+	h.listPromotions(c, domain.HarborPromotionFilter{
+		RuleID: c.Query("rule_id"),
+		Digest: c.Query("digest"),
+		Status: domain.HarborPromotionStatus(c.Query("status")),
+	})
+}
+
+// GetArtifactPromotions godoc
+// @Summary Get promotion history of an artifact
+// @Description List who promoted an artifact digest, where and when
+// @Tags harbor-promotion
+// @Produce json
+// @Param digest path string true "Artifact digest, e.g. sha256:abcd..."
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/harbor/artifacts/{digest}/promotions [get]
+func (h *HarborPromotionHandler) GetArtifactPromotions(c *gin.Context) {
+	h.listPromotions(c, domain.HarborPromotionFilter{Digest: c.Param("digest")})
+}
+
+func (h *HarborPromotionHandler) listPromotions(c *gin.Context, filter domain.HarborPromotionFilter) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	filter.Page = page
+	filter.PageSize = pageSize
+
+	promotions, total, err := h.promotionUsecase.ListPromotions(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      promotions,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetPromotion godoc
+// @Summary Get promotion
+// @Description Get a promotion by ID
+// @Tags harbor-promotion
+// @Produce json
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} domain.HarborPromotion
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/harbor/promotions/{id} [get]
+func (h *HarborPromotionHandler) GetPromotion(c *gin.Context) {
+	promotion, err := h.promotionUsecase.GetPromotion(c.Request.Context(), c.Param("id"))
+	respondPromotion(c, http.StatusOK, promotion, err)
+}
+
+// ApprovePromotion godoc
+// @Summary Approve promotion
+// @Description Approve a pending promotion and carry it out. Requires the rule's approval role; requesters cannot approve their own promotions
+// @Tags harbor-promotion
+// @Produce json
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} domain.HarborPromotion
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/harbor/promotions/{id}/approve [post]
+func (h *HarborPromotionHandler) ApprovePromotion(c *gin.Context) {
+	promotion, err := h.promotionUsecase.ApprovePromotion(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	respondPromotion(c, http.StatusOK, promotion, err)
+}
+
+// RejectPromotion godoc
+// @Summary Reject promotion
+// @Description Reject a pending promotion. Requires the rule's approval role
+// @Tags harbor-promotion
+// @Produce json
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} domain.HarborPromotion
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/harbor/promotions/{id}/reject [post]
+func (h *HarborPromotionHandler) RejectPromotion(c *gin.Context) {
+	promotion, err := h.promotionUsecase.RejectPromotion(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	respondPromotion(c, http.StatusOK, promotion, err)
+}
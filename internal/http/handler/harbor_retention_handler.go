@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unitechio/einfra-be/internal/domain"
+)
+
+type HarborRetentionHandler struct {
+	retentionUsecase domain.HarborRetentionUsecase
+}
+
+// NewHarborRetentionHandler creates a new Harbor retention handler instance
+func NewHarborRetentionHandler(retentionUsecase domain.HarborRetentionUsecase) *HarborRetentionHandler {
+	return &HarborRetentionHandler{
+		retentionUsecase: retentionUsecase,
+	}
+}
+
+// Retention Policies
+
+// CreatePolicy godoc
+// @Summary Create retention policy
+// @Description Create a tag retention policy for a Harbor project. New policies only report what they would delete until enforced
+// @Tags harbor-retention
+// @Accept json
+// @Produce json
+// @Param policy body domain.HarborRetentionPolicy true "Retention policy"
+// @Success 201 {object} domain.HarborRetentionPolicy
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/harbor/retention-policies [post]
+func (h *HarborRetentionHandler) CreatePolicy(c *gin.Context) {
+	var policy domain.HarborRetentionPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.retentionUsecase.CreatePolicy(c.Request.Context(), &policy, c.GetString("user_id")); err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+// ListPolicies godoc
+// @Summary List retention policies
+// @Description List retention policies, optionally of a single registry
+// @Tags harbor-retention
+// @Produce json
+// @Param registry_id query string false "Registry ID"
+// @Success 200 {array} domain.HarborRetentionPolicy
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/harbor/retention-policies [get]
+func (h *HarborRetentionHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.retentionUsecase.ListPolicies(c.Request.Context(), c.Query("registry_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// GetPolicy godoc
+// @Summary Get retention policy
+// @Description Get a retention policy by ID
+// @Tags harbor-retention
+// @Produce json
+// @Param id path string true "Policy ID"
+// @Success 200 {object} domain.HarborRetentionPolicy
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/harbor/retention-policies/{id} [get]
+func (h *HarborRetentionHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.retentionUsecase.GetPolicy(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdatePolicy godoc
+// @Summary Update retention policy
+// @Description Replace a retention policy. The policy is no longer enforced and must be dry-run and enforced again
+// @Tags harbor-retention
+// @Accept json
+// @Produce json
+// @Param id path string true "Policy ID"
+// @Param policy body domain.HarborRetentionPolicy true "Retention policy"
+// @Success 200 {object} domain.HarborRetentionPolicy
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/harbor/retention-policies/{id} [put]
+func (h *HarborRetentionHandler) UpdatePolicy(c *gin.Context) {
+	var policy domain.HarborRetentionPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	policy.ID = c.Param("id")
+
+	if err := h.retentionUsecase.UpdatePolicy(c.Request.Context(), &policy); err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeletePolicy godoc
+// @Summary Delete retention policy
+// @Description Delete a retention policy and its run reports
+// @Tags harbor-retention
+// @Produce json
+// @Param id path string true "Policy ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/harbor/retention-policies/{id} [delete]
+func (h *HarborRetentionHandler) DeletePolicy(c *gin.Context) {
+	if err := h.retentionUsecase.DeletePolicy(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Retention policy deleted successfully"})
+}
+
+// EnforcePolicy godoc
+// @Summary Enforce retention policy
+// @Description Let the policy delete artifacts. Requires a completed dry run after the policy's last change
+// @Tags harbor-retention
+// @Produce json
+// @Param id path string true "Policy ID"
+// @Success 200 {object} domain.HarborRetentionPolicy
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/harbor/retention-policies/{id}/enforce [post]
+func (h *HarborRetentionHandler) EnforcePolicy(c *gin.Context) {
+	policy, err := h.retentionUsecase.EnforcePolicy(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// Retention Runs
+
+// RunPolicy godoc
+// @Summary Run retention policy
+// @Description Start a retention run in the background. Poll the returned run for its report. Runs that delete require an enforced policy
+// @Tags harbor-retention
+// @Produce json
+// @Param id path string true "Policy ID"
+// @Param dry_run query bool false "Only report what would be deleted" default(true)
+// @Success 202 {object} domain.HarborRetentionRun
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/harbor/retention-policies/{id}/run [post]
+func (h *HarborRetentionHandler) RunPolicy(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "true"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+		return
+	}
+
+	run, err := h.retentionUsecase.RunPolicy(c.Request.Context(), c.Param("id"), dryRun, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, run)
+}
+
+// ListRuns godoc
+// @Summary List retention runs
+// @Description List the latest runs of a retention policy without their items, newest first
+// @Tags harbor-retention
+// @Produce json
+// @Param id path string true "Policy ID"
+// @Success 200 {array} domain.HarborRetentionRun
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/harbor/retention-policies/{id}/runs [get]
+func (h *HarborRetentionHandler) ListRuns(c *gin.Context) {
+	runs, err := h.retentionUsecase.ListRuns(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// GetRun godoc
+// @Summary Get retention run
+// @Description Get a retention run with the decision made about each artifact
+// @Tags harbor-retention
+// @Produce json
+// @Param id path string true "Run ID"
+// @Success 200 {object} domain.HarborRetentionRun
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/harbor/retention-runs/{id} [get]
+func (h *HarborRetentionHandler) GetRun(c *gin.Context) {
+	run, err := h.retentionUsecase.GetRun(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unitechio/einfra-be/internal/domain"
+)
+
+// maxHarborWebhookBodySize bounds webhook payloads; scan events with many artifacts stay well below it
+const maxHarborWebhookBodySize = 1 << 20
+
+type HarborWebhookHandler struct {
+	webhookUsecase domain.HarborWebhookUsecase
+}
+
+// NewHarborWebhookHandler creates a new Harbor webhook handler instance
+func NewHarborWebhookHandler(webhookUsecase domain.HarborWebhookUsecase) *HarborWebhookHandler {
+	return &HarborWebhookHandler{
+		webhookUsecase: webhookUsecase,
+	}
+}
+
+// ReceiveEvent godoc
+// @Summary Receive Harbor webhook event
+// @Description Endpoint for Harbor HTTP webhook policies. Configure the policy's auth header with the registry's webhook_secret.
+// @Description PUSH_ARTIFACT, SCANNING_COMPLETED, DELETE_ARTIFACT and QUOTA_EXCEED events are audited, notified and published; other types are ignored
+// @Tags harbor
+// @Accept json
+// @Produce json
+// @Param registry_id path string true "Registry ID"
+// @Param Authorization header string true "Webhook secret"
+// @Success 200 {array} domain.HarborWebhookEvent
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /api/v1/harbor/webhooks/{registry_id} [post]
+func (h *HarborWebhookHandler) ReceiveEvent(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxHarborWebhookBodySize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(body) > maxHarborWebhookBodySize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "webhook payload too large"})
+		return
+	}
+
+	events, err := h.webhookUsecase.HandleWebhook(c.Request.Context(), c.Param("registry_id"), c.GetHeader("Authorization"), body)
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unitechio/einfra-be/internal/domain"
+)
+
+type HelmHandler struct {
+	helmUsecase domain.K8sHelmUsecase
+}
+
+// NewHelmHandler creates a new Helm handler instance
+func NewHelmHandler(helmUsecase domain.K8sHelmUsecase) *HelmHandler {
+	return &HelmHandler{
+		helmUsecase: helmUsecase,
+	}
+}
+
+// ListReleases godoc
+// @Summary List Helm releases
+// @Description List the latest revision of each Helm release, across all namespaces unless one is given
+// @Tags helm
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace query string false "Namespace"
+// @Success 200 {array} domain.HelmRelease
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/helm/releases [get]
+func (h *HelmHandler) ListReleases(c *gin.Context) {
+	releases, err := h.helmUsecase.ListReleases(c.Request.Context(), c.Param("cluster_id"), c.Query("namespace"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, releases)
+}
+
+// GetRelease godoc
+// @Summary Get Helm release
+// @Description Get the values and manifest of a release revision
+// @Tags helm
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Release name"
+// @Param revision query int false "Revision (default: latest)"
+// @Param all query bool false "Return all computed values instead of user-supplied values"
+// @Success 200 {object} domain.HelmReleaseDetail
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/helm/releases/{name} [get]
+func (h *HelmHandler) GetRelease(c *gin.Context) {
+	revision := 0
+	if value := c.Query("revision"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+			return
+		}
+		revision = parsed
+	}
+
+	release, err := h.helmUsecase.GetRelease(c.Request.Context(), c.Param("cluster_id"), c.Param("namespace"), c.Param("name"), revision, c.Query("all") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, release)
+}
+
+// GetReleaseHistory godoc
+// @Summary Get Helm release history
+// @Description List all revisions of a release, newest first
+// @Tags helm
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Release name"
+// @Success 200 {array} domain.HelmRelease
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/helm/releases/{name}/history [get]
+func (h *HelmHandler) GetReleaseHistory(c *gin.Context) {
+	history, err := h.helmUsecase.GetHistory(c.Request.Context(), c.Param("cluster_id"), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// InstallRelease godoc
+// @Summary Install Helm chart
+// @Description Install a chart from an uploaded archive, a chart repository or an OCI registry
+// @Tags helm
+// @Accept json
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param request body domain.HelmInstallRequest true "Install request"
+// @Success 201 {object} domain.HelmReleaseDetail
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/helm/releases [post]
+func (h *HelmHandler) InstallRelease(c *gin.Context) {
+	var req domain.HelmInstallRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	release, err := h.helmUsecase.Install(c.Request.Context(), c.Param("cluster_id"), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, release)
+}
+
+// UpgradeRelease godoc
+// @Summary Upgrade Helm release
+// @Description Upgrade a release to a chart from an uploaded archive, a chart repository or an OCI registry
+// @Tags helm
+// @Accept json
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Release name"
+// @Param request body domain.HelmInstallRequest true "Upgrade request"
+// @Success 200 {object} domain.HelmReleaseDetail
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/helm/releases/{name} [put]
+func (h *HelmHandler) UpgradeRelease(c *gin.Context) {
+	var req domain.HelmInstallRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	release, err := h.helmUsecase.Upgrade(c.Request.Context(), c.Param("cluster_id"), c.Param("namespace"), c.Param("name"), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, release)
+}
+
+// RollbackRelease godoc
+// @Summary Roll back Helm release
+// @Description Roll a release back to a revision; revision 0 rolls back to the previous one
+// @Tags helm
+// @Accept json
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Release name"
+// @Param request body domain.HelmRollbackRequest false "Rollback target"
+// @Success 200 {object} domain.HelmRelease
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/helm/releases/{name}/rollback [post]
+func (h *HelmHandler) RollbackRelease(c *gin.Context) {
+	var req domain.HelmRollbackRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	release, err := h.helmUsecase.Rollback(c.Request.Context(), c.Param("cluster_id"), c.Param("namespace"), c.Param("name"), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, release)
+}
+
+// UninstallRelease godoc
+// @Summary Uninstall Helm release
+// @Description Uninstall a release, optionally keeping its history for a later rollback
+// @Tags helm
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Release name"
+// @Param keepHistory query bool false "Keep release history"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/helm/releases/{name} [delete]
+func (h *HelmHandler) UninstallRelease(c *gin.Context) {
+	keepHistory := c.Query("keepHistory") == "true"
+
+	if err := h.helmUsecase.Uninstall(c.Request.Context(), c.Param("cluster_id"), c.Param("namespace"), c.Param("name"), keepHistory); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Release uninstalled successfully"})
+}
+
+// UploadChart godoc
+// @Summary Upload Helm chart
+// @Description Upload a packaged chart (.tgz) to object storage for later installs and upgrades
+// @Tags helm
+// @Accept multipart/form-data
+// @Produce json
+// @Param chart formData file true "Chart archive"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/helm/charts [post]
+func (h *HelmHandler) UploadChart(c *gin.Context) {
+	file, err := c.FormFile("chart")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "chart file is required"})
+		return
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer content.Close()
+
+	path, err := h.helmUsecase.UploadChart(c.Request.Context(), file.Filename, content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"archive_path": path})
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+)
+
+type ImageSearchHandler struct {
+	searchUsecase usecase.ImageSearchUsecase
+}
+
+// NewImageSearchHandler creates a new image search handler instance
+func NewImageSearchHandler(searchUsecase usecase.ImageSearchUsecase) *ImageSearchHandler {
+	return &ImageSearchHandler{
+		searchUsecase: searchUsecase,
+	}
+}
+
+// SearchImageLocations godoc
+// @Summary Find where an image runs
+// @Description Find the workloads running an image across Kubernetes clusters and Docker hosts, from live pods and containers plus deployment records. Search by repository (optionally with tag), digest or CVE ID
+// @Tags harbor
+// @Produce json
+// @Param repository query string false "Image repository, with or without registry host"
+// @Param tag query string false "Image tag; requires repository"
+// @Param digest query string false "Image digest"
+// @Param cve_id query string false "CVE ID, matched against Harbor scan results"
+// @Param include_inactive query bool false "Also return replaced, rolled back and removed deployments"
+// @Success 200 {object} domain.ImageSearchResult
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/harbor/image-locations [get]
+func (h *ImageSearchHandler) SearchImageLocations(c *gin.Context) {
+	var query domain.ImageSearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.searchUsecase.Search(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExportImageLocations godoc
+// @Summary Export where an image runs
+// @Description Export the result of an image location search to an Excel file
+// @Tags harbor
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param repository query string false "Image repository, with or without registry host"
+// @Param tag query string false "Image tag; requires repository"
+// @Param digest query string false "Image digest"
+// @Param cve_id query string false "CVE ID, matched against Harbor scan results"
+// @Param include_inactive query bool false "Also return replaced, rolled back and removed deployments"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/harbor/image-locations/export [get]
+func (h *ImageSearchHandler) ExportImageLocations(c *gin.Context) {
+	var query domain.ImageSearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, filename, err := h.searchUsecase.ExportToExcel(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Header("Content-Transfer-Encoding", "binary")
+
+	if err := file.Write(c.Writer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write file: " + err.Error()})
+	}
+}
@@ -1,26 +1,48 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/socket"
 )
 
 type KubernetesHandler struct {
-	k8sUsecase    domain.KubernetesUsecase
-	backupUsecase domain.K8sBackupUsecase
+	k8sUsecase      domain.KubernetesUsecase
+	backupUsecase   domain.K8sBackupUsecase
+	watchUsecase    domain.K8sWatchUsecase
+	rolloutUsecase  domain.K8sRolloutUsecase
+	rbacUsecase     domain.K8sRBACUsecase
+	capacityUsecase domain.K8sCapacityUsecase
 }
 
 // NewKubernetesHandler creates a new Kubernetes handler instance
 func NewKubernetesHandler(
 	k8sUsecase domain.KubernetesUsecase,
 	backupUsecase domain.K8sBackupUsecase,
+	watchUsecase domain.K8sWatchUsecase,
+	rolloutUsecase domain.K8sRolloutUsecase,
+	rbacUsecase domain.K8sRBACUsecase,
+	capacityUsecase domain.K8sCapacityUsecase,
 ) *KubernetesHandler {
 	return &KubernetesHandler{
-		k8sUsecase:    k8sUsecase,
-		backupUsecase: backupUsecase,
+		k8sUsecase:      k8sUsecase,
+		backupUsecase:   backupUsecase,
+		watchUsecase:    watchUsecase,
+		rolloutUsecase:  rolloutUsecase,
+		rbacUsecase:     rbacUsecase,
+		capacityUsecase: capacityUsecase,
 	}
 }
 
@@ -256,6 +278,149 @@ func (h *KubernetesHandler) ScaleDeployment(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Deployment scaled successfully"})
 }
 
+// Rollout Management
+
+// GetRolloutHistory godoc
+// @Summary Get deployment rollout history
+// @Description List the ReplicaSet revisions of a deployment with images and a pod template diff per revision
+// @Tags kubernetes
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Deployment name"
+// @Success 200 {array} domain.K8sRolloutRevision
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/deployments/{name}/rollout/history [get]
+func (h *KubernetesHandler) GetRolloutHistory(c *gin.Context) {
+	history, err := h.rolloutUsecase.GetHistory(c.Request.Context(), c.Param("cluster_id"), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// RollbackDeployment godoc
+// @Summary Roll back deployment
+// @Description Roll back a deployment to a revision; revision 0 rolls back to the previous one
+// @Tags kubernetes
+// @Accept json
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Deployment name"
+// @Param request body domain.K8sRollbackRequest false "Rollback target"
+// @Success 200 {object} domain.K8sRolloutRevision
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/deployments/{name}/rollout/rollback [post]
+func (h *KubernetesHandler) RollbackDeployment(c *gin.Context) {
+	var req domain.K8sRollbackRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	revision, err := h.rolloutUsecase.Rollback(c.Request.Context(), c.Param("cluster_id"), c.Param("namespace"), c.Param("name"), req, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// RestartDeployment godoc
+// @Summary Restart deployment
+// @Description Trigger a rolling restart of all pods of a deployment
+// @Tags kubernetes
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Deployment name"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/deployments/{name}/rollout/restart [post]
+func (h *KubernetesHandler) RestartDeployment(c *gin.Context) {
+	if err := h.rolloutUsecase.Restart(c.Request.Context(), c.Param("cluster_id"), c.Param("namespace"), c.Param("name"), c.GetString("username")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deployment restart triggered"})
+}
+
+// PauseDeployment godoc
+// @Summary Pause deployment rollout
+// @Description Pause a deployment so template changes are not rolled out
+// @Tags kubernetes
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Deployment name"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/deployments/{name}/rollout/pause [post]
+func (h *KubernetesHandler) PauseDeployment(c *gin.Context) {
+	if err := h.rolloutUsecase.Pause(c.Request.Context(), c.Param("cluster_id"), c.Param("namespace"), c.Param("name"), c.GetString("username")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deployment rollout paused"})
+}
+
+// ResumeDeployment godoc
+// @Summary Resume deployment rollout
+// @Description Resume a paused deployment rollout
+// @Tags kubernetes
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Deployment name"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/deployments/{name}/rollout/resume [post]
+func (h *KubernetesHandler) ResumeDeployment(c *gin.Context) {
+	if err := h.rolloutUsecase.Resume(c.Request.Context(), c.Param("cluster_id"), c.Param("namespace"), c.Param("name"), c.GetString("username")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deployment rollout resumed"})
+}
+
+// GetRolloutStatus godoc
+// @Summary Get deployment rollout status
+// @Description Get the rollout status of a deployment; with wait=true the request blocks until the rollout completes, fails or times out
+// @Tags kubernetes
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Deployment name"
+// @Param wait query boolean false "Wait for the rollout to finish"
+// @Param timeout query int false "Wait timeout in seconds, at most 600" default(300)
+// @Success 200 {object} domain.K8sRolloutStatus
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/deployments/{name}/rollout/status [get]
+func (h *KubernetesHandler) GetRolloutStatus(c *gin.Context) {
+	var timeout time.Duration
+	if c.Query("wait") == "true" {
+		seconds, _ := strconv.Atoi(c.DefaultQuery("timeout", "300"))
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	status, err := h.rolloutUsecase.GetStatus(c.Request.Context(), c.Param("cluster_id"), c.Param("namespace"), c.Param("name"), timeout)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
 // Pod Management
 
 // ListPods godoc
@@ -284,15 +449,18 @@ func (h *KubernetesHandler) ListPods(c *gin.Context) {
 
 // GetPodLogs godoc
 // @Summary Get pod logs
-// @Description Get logs from a Kubernetes pod
+// @Description Get logs from a Kubernetes pod. With a WebSocket upgrade the logs are streamed, use follow=true to keep following
 // @Tags kubernetes
 // @Accept json
 // @Produce json
 // @Param cluster_id path string true "Cluster ID"
 // @Param namespace path string true "Namespace"
 // @Param pod_name path string true "Pod name"
-// @Param container query string false "Container name"
+// @Param container query string false "Container name (default: all containers when streaming)"
 // @Param tail query int false "Number of lines to show from the end of the logs" default(100)
+// @Param follow query boolean false "Follow the logs (WebSocket only)"
+// @Param previous query boolean false "Logs of the previous container instance"
+// @Param since_seconds query int false "Only logs newer than this many seconds"
 // @Success 200 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/pods/{pod_name}/logs [get]
@@ -303,6 +471,31 @@ func (h *KubernetesHandler) GetPodLogs(c *gin.Context) {
 	container := c.DefaultQuery("container", "")
 	tail, _ := strconv.Atoi(c.DefaultQuery("tail", "100"))
 
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		opts := parsePodLogOptions(c)
+		opts.PodName = podName
+		h.streamPodLogs(c, clusterID, namespace, opts)
+		return
+	}
+
+	if c.Query("previous") == "true" {
+		opts := parsePodLogOptions(c)
+		opts.PodName = podName
+		opts.Follow = false
+		entries, err := h.readPodLogs(c.Request.Context(), clusterID, namespace, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		var logs strings.Builder
+		for _, entry := range entries {
+			logs.WriteString(entry.Message)
+			logs.WriteByte('\n')
+		}
+		c.JSON(http.StatusOK, gin.H{"logs": logs.String()})
+		return
+	}
+
 	logs, err := h.k8sUsecase.GetPodLogs(c.Request.Context(), clusterID, namespace, podName, container, tail)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -312,6 +505,291 @@ func (h *KubernetesHandler) GetPodLogs(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"logs": logs})
 }
 
+// GetSelectorLogs godoc
+// @Summary Get logs of pods by label selector
+// @Description Aggregate logs of all pods matching a label selector. With a WebSocket upgrade the logs are streamed, use follow=true to keep following
+// @Tags kubernetes
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace path string true "Namespace"
+// @Param selector query string true "Label selector, e.g. app=web"
+// @Param container query string false "Container name (default: all containers)"
+// @Param tail query int false "Number of lines per container" default(100)
+// @Param follow query boolean false "Follow the logs (WebSocket only)"
+// @Param previous query boolean false "Logs of the previous container instances"
+// @Param since_seconds query int false "Only logs newer than this many seconds"
+// @Success 200 {array} domain.K8sPodLogEntry
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/logs [get]
+func (h *KubernetesHandler) GetSelectorLogs(c *gin.Context) {
+	clusterID := c.Param("cluster_id")
+	namespace := c.Param("namespace")
+
+	opts := parsePodLogOptions(c)
+	opts.LabelSelector = c.Query("selector")
+	if opts.LabelSelector == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "selector is required"})
+		return
+	}
+
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		h.streamPodLogs(c, clusterID, namespace, opts)
+		return
+	}
+
+	opts.Follow = false
+	h.collectPodLogs(c, clusterID, namespace, opts)
+}
+
+// parsePodLogOptions parses the shared log query parameters
+func parsePodLogOptions(c *gin.Context) domain.K8sPodLogOptions {
+	tail, _ := strconv.ParseInt(c.DefaultQuery("tail", "100"), 10, 64)
+	since, _ := strconv.ParseInt(c.DefaultQuery("since_seconds", "0"), 10, 64)
+	return domain.K8sPodLogOptions{
+		Container:    c.Query("container"),
+		Follow:       c.Query("follow") == "true",
+		Previous:     c.Query("previous") == "true",
+		TailLines:    tail,
+		SinceSeconds: since,
+	}
+}
+
+// collectPodLogs returns the logs of finished streams as a JSON array
+func (h *KubernetesHandler) collectPodLogs(c *gin.Context, clusterID, namespace string, opts domain.K8sPodLogOptions) {
+	entries, err := h.readPodLogs(c.Request.Context(), clusterID, namespace, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// readPodLogs reads non-following log streams to the end
+func (h *KubernetesHandler) readPodLogs(ctx context.Context, clusterID, namespace string, opts domain.K8sPodLogOptions) ([]domain.K8sPodLogEntry, error) {
+	logChan, errChan, err := h.k8sUsecase.StreamPodLogs(ctx, clusterID, namespace, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]domain.K8sPodLogEntry, 0)
+	for entry := range logChan {
+		entries = append(entries, entry)
+	}
+	if err := <-errChan; err != nil {
+		return nil, err
+	}
+
+	// Interleave lines of multiple containers by time
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	return entries, nil
+}
+
+// streamPodLogs upgrades to WebSocket and streams log entries until the client leaves or all streams end
+func (h *KubernetesHandler) streamPodLogs(c *gin.Context, clusterID, namespace string, opts domain.K8sPodLogOptions) {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	logChan, errChan, err := h.k8sUsecase.StreamPodLogs(ctx, clusterID, namespace, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry, ok := <-logChan:
+			if !ok {
+				conn.WriteJSON(gin.H{"type": "close", "data": "Log stream ended"})
+				return
+			}
+			if err := conn.WriteJSON(gin.H{"type": "log", "data": entry}); err != nil {
+				return
+			}
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			// One container failing (e.g. no previous instance) does not end the other streams
+			if err := conn.WriteJSON(gin.H{"type": "error", "data": err.Error()}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ExecPodCommandRequest represents a non-interactive command executed in a pod
+type ExecPodCommandRequest struct {
+	Container string   `json:"container" example:"app"`
+	Command   []string `json:"command" binding:"required" example:"ls,-la"`
+}
+
+// ExecPodCommand godoc
+// @Summary Execute command in pod
+// @Description Execute a command in a pod container and return its output
+// @Tags kubernetes
+// @Accept json
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace path string true "Namespace"
+// @Param pod_name path string true "Pod name"
+// @Param request body ExecPodCommandRequest true "Command to execute"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/pods/{pod_name}/exec [post]
+func (h *KubernetesHandler) ExecPodCommand(c *gin.Context) {
+	clusterID := c.Param("cluster_id")
+	namespace := c.Param("namespace")
+	podName := c.Param("pod_name")
+
+	var req ExecPodCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	output, err := h.k8sUsecase.ExecPodCommand(c.Request.Context(), clusterID, namespace, podName, req.Container, req.Command)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "output": output})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"output": output})
+}
+
+// podTerminalMessage is exchanged over the pod terminal WebSocket.
+// Client: {"type":"stdin","data":"ls\n"} and {"type":"resize","cols":120,"rows":40}.
+// Server: "stdout", "stderr", "error" with data, and "exit" with code.
+type podTerminalMessage struct {
+	Type string `json:"type"`
+	Data string `json:"data,omitempty"`
+	Cols uint16 `json:"cols,omitempty"`
+	Rows uint16 `json:"rows,omitempty"`
+	Code *int   `json:"code,omitempty"`
+}
+
+// podTerminalWriter forwards an output stream as terminal messages
+type podTerminalWriter struct {
+	conn   *websocket.Conn
+	mu     *sync.Mutex
+	stream string
+}
+
+func (w *podTerminalWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.conn.WriteJSON(podTerminalMessage{Type: w.stream, Data: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// defaultPodShell starts bash when available and falls back to sh
+var defaultPodShell = []string{"/bin/sh", "-c", "TERM=xterm-256color; export TERM; [ -x /bin/bash ] && exec /bin/bash || exec /bin/sh"}
+
+// ExecPodTerminal godoc
+// @Summary Interactive pod terminal
+// @Description Open an interactive terminal in a pod container over WebSocket. Send {"type":"stdin","data":"..."} and {"type":"resize","cols":80,"rows":24}; receive stdout, stderr and exit messages
+// @Tags kubernetes
+// @Param cluster_id path string true "Cluster ID"
+// @Param namespace path string true "Namespace"
+// @Param pod_name path string true "Pod name"
+// @Param container query string false "Container name"
+// @Param command query []string false "Command to run (default: bash or sh)" collectionFormat(multi)
+// @Param tty query boolean false "Allocate a TTY" default(true)
+// @Success 101 {string} string "Switching Protocols"
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/namespaces/{namespace}/pods/{pod_name}/exec [get]
+func (h *KubernetesHandler) ExecPodTerminal(c *gin.Context) {
+	clusterID := c.Param("cluster_id")
+	namespace := c.Param("namespace")
+	podName := c.Param("pod_name")
+	container := c.Query("container")
+	tty := c.DefaultQuery("tty", "true") == "true"
+
+	command := c.QueryArray("command")
+	if len(command) == 0 {
+		command = defaultPodShell
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	stdinReader, stdinWriter := io.Pipe()
+	resize := make(chan domain.K8sTerminalSize, 1)
+	var writeMu sync.Mutex
+
+	go func() {
+		defer cancel()
+		defer stdinWriter.Close()
+		for {
+			var msg podTerminalMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			switch msg.Type {
+			case "stdin":
+				if _, err := stdinWriter.Write([]byte(msg.Data)); err != nil {
+					return
+				}
+			case "resize":
+				if msg.Cols == 0 || msg.Rows == 0 {
+					continue
+				}
+				// Only the latest size matters
+				select {
+				case <-resize:
+				default:
+				}
+				resize <- domain.K8sTerminalSize{Width: msg.Cols, Height: msg.Rows}
+			}
+		}
+	}()
+
+	session := domain.K8sExecSession{
+		Stdin:  stdinReader,
+		Stdout: &podTerminalWriter{conn: conn, mu: &writeMu, stream: "stdout"},
+		Stderr: &podTerminalWriter{conn: conn, mu: &writeMu, stream: "stderr"},
+		TTY:    tty,
+		Resize: resize,
+	}
+
+	exitCode, err := h.k8sUsecase.ExecPodInteractive(ctx, clusterID, namespace, podName, container, command, session)
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	if err != nil {
+		conn.WriteJSON(podTerminalMessage{Type: "error", Data: err.Error()})
+		return
+	}
+	conn.WriteJSON(podTerminalMessage{Type: "exit", Code: &exitCode})
+}
+
 // Node Management
 
 // ListNodes godoc
@@ -336,6 +814,48 @@ func (h *KubernetesHandler) ListNodes(c *gin.Context) {
 	c.JSON(http.StatusOK, nodes)
 }
 
+// GetNode godoc
+// @Summary Get node
+// @Description Get a node of a Kubernetes cluster
+// @Tags kubernetes
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param name path string true "Node name"
+// @Success 200 {object} domain.K8sNode
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/nodes/{name} [get]
+func (h *KubernetesHandler) GetNode(c *gin.Context) {
+	node, err := h.k8sUsecase.GetNode(c.Request.Context(), c.Param("cluster_id"), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, node)
+}
+
+// GetClusterOverview godoc
+// @Summary Get cluster overview
+// @Description Inventory and capacity of a cluster: requested vs. allocatable CPU/memory and pods per node and namespace, and actual usage when metrics-server is installed
+// @Tags kubernetes
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param refresh query bool false "Bypass the cache"
+// @Success 200 {object} domain.K8sClusterOverview
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/overview [get]
+func (h *KubernetesHandler) GetClusterOverview(c *gin.Context) {
+	refresh := c.Query("refresh") == "true"
+
+	overview, err := h.capacityUsecase.GetOverview(c.Request.Context(), c.Param("cluster_id"), refresh)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, overview)
+}
+
 // ConfigMap Management
 
 // ListConfigMaps godoc
@@ -414,6 +934,164 @@ func (h *KubernetesHandler) ListIngresses(c *gin.Context) {
 	c.JSON(http.StatusOK, ingresses)
 }
 
+// Manifest Management
+
+// ApplyManifest godoc
+// @Summary Apply Kubernetes manifest
+// @Description Server-side apply multi-document YAML or JSON. Send raw YAML with a yaml Content-Type or a JSON request body. Use dryRun=server to preview the resulting objects and a diff against live state. Container images are checked against the vulnerability policy of the cluster's environment
+// @Tags kubernetes
+// @Accept json,application/yaml
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param request body domain.K8sApplyRequest true "Apply request"
+// @Param dryRun query string false "Set to 'server' for a server-side dry run"
+// @Param fieldManager query string false "Field manager name" default(einfra)
+// @Param namespace query string false "Default namespace for namespaced objects"
+// @Param force query boolean false "Force conflicts"
+// @Success 200 {object} domain.K8sApplyResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/manifests/apply [post]
+func (h *KubernetesHandler) ApplyManifest(c *gin.Context) {
+	clusterID := c.Param("cluster_id")
+
+	var req domain.K8sApplyRequest
+	if strings.Contains(c.ContentType(), "yaml") {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Manifest = string(body)
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if dryRun := c.Query("dryRun"); dryRun != "" {
+		if dryRun != "server" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dryRun must be 'server'"})
+			return
+		}
+		req.DryRun = true
+	}
+	if fieldManager := c.Query("fieldManager"); fieldManager != "" {
+		req.FieldManager = fieldManager
+	}
+	if namespace := c.Query("namespace"); namespace != "" {
+		req.Namespace = namespace
+	}
+	if c.Query("force") == "true" {
+		req.Force = true
+	}
+
+	result, err := h.k8sUsecase.ApplyManifest(c.Request.Context(), clusterID, req)
+	if err != nil {
+		if respondVulnerabilityGateError(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Resource Watch
+
+// WatchResources godoc
+// @Summary Watch Kubernetes resources
+// @Description Stream ADDED/MODIFIED/DELETED events over WebSocket. Events are wrapped in "k8s_event" messages and
+// @Description filtered by the user's namespace permissions. Reconnect with the last seen resourceVersion to resume.
+// @Tags kubernetes
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param resources query string true "Comma-separated resources, e.g. pods,deployments.apps"
+// @Param namespaces query string false "Comma-separated namespaces (default: all permitted)"
+// @Param resourceVersion query string false "Resume after this resourceVersion"
+// @Success 101 {object} domain.K8sWatchEvent "Switching to WebSocket"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/watch [get]
+func (h *KubernetesHandler) WatchResources(c *gin.Context) {
+	clusterID := c.Param("cluster_id")
+
+	req := domain.K8sWatchRequest{
+		Resources:       splitQueryList(c.Query("resources")),
+		Namespaces:      splitQueryList(c.Query("namespaces")),
+		ResourceVersion: c.Query("resourceVersion"),
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	events, err := h.watchUsecase.Watch(ctx, c.GetString("user_id"), clusterID, req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, domain.ErrK8sNamespaceForbidden) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// The client only sends pings; a read error means it went away
+	pongs := make(chan struct{}, 1)
+	go func() {
+		defer cancel()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg socket.Message
+			if json.Unmarshal(data, &msg) == nil && msg.Type == socket.MessageTypePing {
+				select {
+				case pongs <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-pongs:
+			if err := conn.WriteJSON(socket.Message{Type: socket.MessageTypePong, Timestamp: time.Now()}); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				conn.WriteJSON(socket.NewSystemMessage("watch_closed", "Watch closed, reconnect with the last resourceVersion", nil))
+				return
+			}
+			if err := conn.WriteJSON(socket.NewK8sEventMessage(event)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// splitQueryList splits a comma-separated query value, dropping empty items
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // Backup Management
 
 // CreateBackup godoc
@@ -500,3 +1178,219 @@ func (h *KubernetesHandler) RestoreBackup(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Backup restore triggered successfully"})
 }
+
+// DiffBackups godoc
+// @Summary Diff K8s backups
+// @Description Compare two backups resource by resource (added, removed and changed with a YAML diff)
+// @Tags kubernetes
+// @Accept json
+// @Produce json
+// @Param from query string true "Older backup ID"
+// @Param to query string true "Newer backup ID"
+// @Success 200 {object} domain.K8sBackupDiff
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/backups/diff [get]
+func (h *KubernetesHandler) DiffBackups(c *gin.Context) {
+	from := c.Query("from")
+	to := c.Query("to")
+	if from == "" || to == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to backup IDs are required"})
+		return
+	}
+
+	diff, err := h.backupUsecase.DiffBackups(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// Backup Schedule Management
+
+// CreateBackupSchedule godoc
+// @Summary Create K8s backup schedule
+// @Description Schedule recurring backups for a cluster or namespace with keep-last-N retention
+// @Tags kubernetes
+// @Accept json
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param schedule body domain.K8sBackupScheduleRequest true "Backup schedule"
+// @Success 201 {object} domain.K8sBackupSchedule
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/backup-schedules [post]
+func (h *KubernetesHandler) CreateBackupSchedule(c *gin.Context) {
+	var req domain.K8sBackupScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule, err := h.backupUsecase.CreateSchedule(c.Request.Context(), c.Param("cluster_id"), req, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// ListBackupSchedules godoc
+// @Summary List K8s backup schedules
+// @Description List all backup schedules for a cluster
+// @Tags kubernetes
+// @Accept json
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Success 200 {array} domain.K8sBackupSchedule
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/backup-schedules [get]
+func (h *KubernetesHandler) ListBackupSchedules(c *gin.Context) {
+	clusterID := c.Param("cluster_id")
+
+	schedules, err := h.backupUsecase.ListSchedules(c.Request.Context(), clusterID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+// GetBackupSchedule godoc
+// @Summary Get K8s backup schedule
+// @Description Get a backup schedule by ID
+// @Tags kubernetes
+// @Accept json
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Success 200 {object} domain.K8sBackupSchedule
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/backup-schedules/{id} [get]
+func (h *KubernetesHandler) GetBackupSchedule(c *gin.Context) {
+	id := c.Param("id")
+
+	schedule, err := h.backupUsecase.GetSchedule(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// UpdateBackupSchedule godoc
+// @Summary Update K8s backup schedule
+// @Description Update the cron expression, retention or state of a backup schedule
+// @Tags kubernetes
+// @Accept json
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Param schedule body domain.K8sBackupScheduleRequest true "Backup schedule; omitted keep_last and is_active are left unchanged"
+// @Success 200 {object} domain.K8sBackupSchedule
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/backup-schedules/{id} [put]
+func (h *KubernetesHandler) UpdateBackupSchedule(c *gin.Context) {
+	var req domain.K8sBackupScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule, err := h.backupUsecase.UpdateSchedule(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteBackupSchedule godoc
+// @Summary Delete K8s backup schedule
+// @Description Delete a backup schedule (existing backups are kept)
+// @Tags kubernetes
+// @Accept json
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Success 204
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/backup-schedules/{id} [delete]
+func (h *KubernetesHandler) DeleteBackupSchedule(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.backupUsecase.DeleteSchedule(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RunBackupSchedule godoc
+// @Summary Run K8s backup schedule now
+// @Description Trigger a backup schedule immediately and apply its retention
+// @Tags kubernetes
+// @Accept json
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Success 201 {object} domain.K8sBackup
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/backup-schedules/{id}/run [post]
+func (h *KubernetesHandler) RunBackupSchedule(c *gin.Context) {
+	id := c.Param("id")
+
+	backup, err := h.backupUsecase.RunSchedule(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, backup)
+}
+
+// RBAC Sync
+
+// GetRBAC godoc
+// @Summary Preview Kubernetes RBAC
+// @Description Compute the Roles and RoleBindings that platform grants map to on a cluster, without applying them
+// @Tags kubernetes
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Success 200 {object} domain.K8sRBACSyncResult
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/rbac [get]
+func (h *KubernetesHandler) GetRBAC(c *gin.Context) {
+	result, err := h.rbacUsecase.Sync(c.Request.Context(), c.Param("cluster_id"), true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// SyncRBAC godoc
+// @Summary Sync Kubernetes RBAC
+// @Description Reconcile the managed Roles and RoleBindings of a cluster with platform grants
+// @Tags kubernetes
+// @Produce json
+// @Param cluster_id path string true "Cluster ID"
+// @Param dryRun query bool false "Only compute the desired state"
+// @Success 200 {object} domain.K8sRBACSyncResult
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kubernetes/clusters/{cluster_id}/rbac/sync [post]
+func (h *KubernetesHandler) SyncRBAC(c *gin.Context) {
+	dryRun := c.Query("dryRun") == "true"
+
+	result, err := h.rbacUsecase.Sync(c.Request.Context(), c.Param("cluster_id"), dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -26,6 +26,7 @@ func NewLogHandler(logUsecase usecase.LogUsecase) *LogHandler {
 // @Produce json
 // @Param id path string true "Container ID"
 // @Param tail query string false "Number of lines to tail" default:"100"
+// @Param host_id query string false "Docker host ID; the local daemon when omitted"
 // @Success 101 {object} logstream.LogMessage "Switching to WebSocket"
 // @Failure 400 {object} errorx.Error "Invalid request"
 // @Failure 500 {object} errorx.Error "Internal server error"
@@ -45,7 +46,7 @@ func (h *LogHandler) StreamContainerLogs(c *gin.Context) {
 
 	// Get log stream
 	ctx := c.Request.Context()
-	logChan, errChan, err := h.logUsecase.StreamContainerLogs(ctx, containerID, tail)
+	logChan, errChan, err := h.logUsecase.StreamContainerLogs(ctx, c.Query("host_id"), containerID, tail)
 	if err != nil {
 		conn.WriteJSON(map[string]interface{}{
 			"error": err.Error(),
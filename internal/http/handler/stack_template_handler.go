@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+)
+
+// StackTemplateHandler handles the catalog of stack templates
+type StackTemplateHandler struct {
+	templateUsecase usecase.StackTemplateUsecase
+}
+
+// NewStackTemplateHandler creates a new stack template handler
+func NewStackTemplateHandler(templateUsecase usecase.StackTemplateUsecase) *StackTemplateHandler {
+	return &StackTemplateHandler{
+		templateUsecase: templateUsecase,
+	}
+}
+
+// ListTemplates lists the stack templates
+// @Summary List stack templates
+// @Description Get the built-in and user-defined app templates, each a compose template with a typed parameter schema
+// @Tags stack-templates
+// @Accept json
+// @Produce json
+// @Param category query string false "Category"
+// @Param search query string false "Search in name and description"
+// @Success 200 {array} domain.StackTemplate "Templates"
+// @Failure 400 {object} errorx.Error "Invalid request"
+// @Failure 500 {object} errorx.Error "Internal server error"
+// @Router /api/v1/docker/templates [get]
+// @Security BearerAuth
+func (h *StackTemplateHandler) ListTemplates(c *gin.Context) {
+	var filter domain.StackTemplateFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid query parameters"))
+		return
+	}
+
+	templates, err := h.templateUsecase.ListTemplates(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeInternalError, "Failed to list templates"))
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// GetTemplate gets a stack template
+// @Summary Get stack template
+// @Description Get a built-in template by name or a user-defined one by ID, with the parameters its form asks for
+// @Tags stack-templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} domain.StackTemplate "Template"
+// @Failure 404 {object} errorx.Error "Template not found"
+// @Router /api/v1/docker/templates/{id} [get]
+// @Security BearerAuth
+func (h *StackTemplateHandler) GetTemplate(c *gin.Context) {
+	tpl, err := h.templateUsecase.GetTemplate(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeNotFound, "Template not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, tpl)
+}
+
+// CreateTemplate creates a stack template
+// @Summary Create stack template
+// @Description Add a user-defined template. The compose template is a Go template; parameters are available as {{ .name }} and {{ quote .name }} renders a value as a string compose does not interpolate
+// @Tags stack-templates
+// @Accept json
+// @Produce json
+// @Param request body domain.StackTemplateRequest true "Template"
+// @Success 201 {object} domain.StackTemplate "Template created"
+// @Failure 400 {object} errorx.Error "Invalid template"
+// @Router /api/v1/docker/templates [post]
+// @Security BearerAuth
+func (h *StackTemplateHandler) CreateTemplate(c *gin.Context) {
+	var req domain.StackTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+		return
+	}
+
+	tpl, err := h.templateUsecase.CreateTemplate(c.Request.Context(), req, c.GetString("user_id"))
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to create template"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, tpl)
+}
+
+// UpdateTemplate updates a stack template
+// @Summary Update stack template
+// @Description Replace a user-defined template and bump its version; stacks keep the version they were deployed from
+// @Tags stack-templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Param request body domain.StackTemplateRequest true "Template"
+// @Success 200 {object} domain.StackTemplate "Template updated"
+// @Failure 400 {object} errorx.Error "Invalid template"
+// @Router /api/v1/docker/templates/{id} [put]
+// @Security BearerAuth
+func (h *StackTemplateHandler) UpdateTemplate(c *gin.Context) {
+	var req domain.StackTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+		return
+	}
+
+	tpl, err := h.templateUsecase.UpdateTemplate(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to update template"))
+		return
+	}
+
+	c.JSON(http.StatusOK, tpl)
+}
+
+// DeleteTemplate deletes a stack template
+// @Summary Delete stack template
+// @Description Delete a user-defined template; stacks deployed from it are kept
+// @Tags stack-templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} map[string]interface{} "Template deleted"
+// @Failure 400 {object} errorx.Error "Invalid request"
+// @Router /api/v1/docker/templates/{id} [delete]
+// @Security BearerAuth
+func (h *StackTemplateHandler) DeleteTemplate(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.templateUsecase.DeleteTemplate(c.Request.Context(), id); err != nil {
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to delete template"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Template deleted successfully",
+		"template_id": id,
+	})
+}
+
+// DeployTemplate deploys a stack from a template
+// @Summary Deploy stack template
+// @Description Validate the parameters against the schema of a template, render it and deploy it as a new stack recording the template version. Optional secrets left empty are generated
+// @Tags stack-templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Param request body domain.StackTemplateDeployRequest true "Stack name and parameters"
+// @Success 201 {object} map[string]interface{} "Stack deployment initiated"
+// @Failure 400 {object} errorx.Error "Invalid parameters"
+// @Failure 403 {object} map[string]interface{} "Blocked by vulnerability policy"
+// @Router /api/v1/docker/templates/{id}/deploy [post]
+// @Security BearerAuth
+func (h *StackTemplateHandler) DeployTemplate(c *gin.Context) {
+	var req domain.StackTemplateDeployRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errorx.New(errorx.CodeBadRequest, "Invalid request body"))
+		return
+	}
+
+	stack, err := h.templateUsecase.DeployTemplate(c.Request.Context(), c.Param("id"), req, c.GetString("user_id"))
+	if err != nil {
+		if respondVulnerabilityGateError(c, err) {
+			return
+		}
+		c.Error(errorx.Wrap(err, errorx.CodeBadRequest, "Failed to deploy template"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Stack deployment initiated",
+		"stack":   stack,
+	})
+}
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unitechio/einfra-be/internal/domain"
+)
+
+type VulnerabilityGateHandler struct {
+	gateUsecase domain.VulnerabilityGateUsecase
+}
+
+// NewVulnerabilityGateHandler creates a new vulnerability gate handler instance
+func NewVulnerabilityGateHandler(gateUsecase domain.VulnerabilityGateUsecase) *VulnerabilityGateHandler {
+	return &VulnerabilityGateHandler{
+		gateUsecase: gateUsecase,
+	}
+}
+
+// respondVulnerabilityGateError answers 403 with the gate report when err is a vulnerability gate rejection
+func respondVulnerabilityGateError(c *gin.Context, err error) bool {
+	var gateErr *domain.VulnerabilityGateError
+	if !errors.As(err, &gateErr) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":              gateErr.Error(),
+		"vulnerability_gate": gateErr.Report,
+	})
+	return true
+}
+
+// Policy Management
+
+// ListPolicies godoc
+// @Summary List vulnerability policies
+// @Description List the vulnerability policies of all environments
+// @Tags vulnerability-gate
+// @Produce json
+// @Success 200 {array} domain.VulnerabilityPolicy
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/harbor/vulnerability-policies [get]
+func (h *VulnerabilityGateHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.gateUsecase.ListPolicies(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// GetPolicy godoc
+// @Summary Get vulnerability policy
+// @Description Get the vulnerability policy of an environment
+// @Tags vulnerability-gate
+// @Produce json
+// @Param environment_id path string true "Environment ID"
+// @Success 200 {object} domain.VulnerabilityPolicy
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/harbor/vulnerability-policies/{environment_id} [get]
+func (h *VulnerabilityGateHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.gateUsecase.GetPolicy(c.Request.Context(), c.Param("environment_id"))
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// SavePolicy godoc
+// @Summary Save vulnerability policy
+// @Description Create or replace the vulnerability policy of an environment
+// @Tags vulnerability-gate
+// @Accept json
+// @Produce json
+// @Param environment_id path string true "Environment ID"
+// @Param policy body domain.VulnerabilityPolicy true "Policy"
+// @Success 200 {object} domain.VulnerabilityPolicy
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/harbor/vulnerability-policies/{environment_id} [put]
+func (h *VulnerabilityGateHandler) SavePolicy(c *gin.Context) {
+	var policy domain.VulnerabilityPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	policy.EnvironmentID = c.Param("environment_id")
+
+	if err := h.gateUsecase.SavePolicy(c.Request.Context(), &policy, c.GetString("user_id")); err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeletePolicy godoc
+// @Summary Delete vulnerability policy
+// @Description Remove the vulnerability policy of an environment; deployments to it are no longer gated
+// @Tags vulnerability-gate
+// @Produce json
+// @Param environment_id path string true "Environment ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/harbor/vulnerability-policies/{environment_id} [delete]
+func (h *VulnerabilityGateHandler) DeletePolicy(c *gin.Context) {
+	if err := h.gateUsecase.DeletePolicy(c.Request.Context(), c.Param("environment_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vulnerability policy deleted successfully"})
+}
+
+// EvaluatePolicy godoc
+// @Summary Evaluate images against a vulnerability policy
+// @Description Report how images fare against an environment's policy without deploying anything. Unscanned images are scanned first
+// @Tags vulnerability-gate
+// @Accept json
+// @Produce json
+// @Param environment_id path string true "Environment ID"
+// @Param request body map[string][]string true "Images, e.g. {\"images\": [\"harbor.example.com/library/nginx:1.25\"]}"
+// @Success 200 {object} domain.VulnerabilityGateReport
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/harbor/vulnerability-policies/{environment_id}/evaluate [post]
+func (h *VulnerabilityGateHandler) EvaluatePolicy(c *gin.Context) {
+	var req struct {
+		Images []string `json:"images" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.gateUsecase.Evaluate(c.Request.Context(), c.Param("environment_id"), req.Images)
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// CVE Exceptions
+
+// ListExceptions godoc
+// @Summary List CVE exceptions
+// @Description List CVE exceptions; an environment filter includes exceptions that apply to every environment
+// @Tags vulnerability-gate
+// @Produce json
+// @Param environment_id query string false "Environment ID"
+// @Param cve_id query string false "CVE ID"
+// @Param status query string false "pending, approved, rejected or revoked"
+// @Param active query bool false "Only approved, unexpired exceptions"
+// @Success 200 {array} domain.CVEException
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/harbor/cve-exceptions [get]
+func (h *VulnerabilityGateHandler) ListExceptions(c *gin.Context) {
+	filter := domain.CVEExceptionFilter{
+		EnvironmentID: c.Query("environment_id"),
+		CVEID:         c.Query("cve_id"),
+		Status:        domain.CVEExceptionStatus(c.Query("status")),
+		ActiveOnly:    c.Query("active") == "true",
+	}
+
+	exceptions, err := h.gateUsecase.ListExceptions(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, exceptions)
+}
+
+// RequestException godoc
+// @Summary Request CVE exception
+// @Description Request a time-boxed exception for a CVE; it takes effect once an admin approves it
+// @Tags vulnerability-gate
+// @Accept json
+// @Produce json
+// @Param exception body domain.CVEException true "Exception"
+// @Success 201 {object} domain.CVEException
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/harbor/cve-exceptions [post]
+func (h *VulnerabilityGateHandler) RequestException(c *gin.Context) {
+	var exception domain.CVEException
+	if err := c.ShouldBindJSON(&exception); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.gateUsecase.RequestException(c.Request.Context(), &exception, c.GetString("user_id")); err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, exception)
+}
+
+// ApproveException godoc
+// @Summary Approve CVE exception
+// @Description Approve a pending CVE exception. Requires the harbor.vulnerability.exception.approve permission
+// @Tags vulnerability-gate
+// @Produce json
+// @Param id path string true "Exception ID"
+// @Success 200 {object} domain.CVEException
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/harbor/cve-exceptions/{id}/approve [post]
+func (h *VulnerabilityGateHandler) ApproveException(c *gin.Context) {
+	exception, err := h.gateUsecase.ApproveException(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, exception)
+}
+
+// RejectException godoc
+// @Summary Reject CVE exception
+// @Description Reject a pending CVE exception. Requires the harbor.vulnerability.exception.approve permission
+// @Tags vulnerability-gate
+// @Produce json
+// @Param id path string true "Exception ID"
+// @Success 200 {object} domain.CVEException
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/harbor/cve-exceptions/{id}/reject [post]
+func (h *VulnerabilityGateHandler) RejectException(c *gin.Context) {
+	exception, err := h.gateUsecase.RejectException(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, exception)
+}
+
+// RevokeException godoc
+// @Summary Revoke CVE exception
+// @Description Withdraw a pending or approved CVE exception before it expires
+// @Tags vulnerability-gate
+// @Produce json
+// @Param id path string true "Exception ID"
+// @Success 200 {object} domain.CVEException
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/harbor/cve-exceptions/{id}/revoke [post]
+func (h *VulnerabilityGateHandler) RevokeException(c *gin.Context) {
+	exception, err := h.gateUsecase.RevokeException(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(harborErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, exception)
+}
@@ -95,3 +95,12 @@ func SetUserContext(c *gin.Context, ctx context.Context) context.Context {
 	}
 	return ctx
 }
+
+// UserContextMiddleware copies the authenticated user into the request context so
+// usecases can act on the user's behalf (e.g. Kubernetes impersonation)
+func UserContextMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(SetUserContext(c, c.Request.Context()))
+		c.Next()
+	}
+}
@@ -43,12 +43,13 @@ func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
 	}
 }
 
-// TimeoutMiddlewareWithConfig creates timeout middleware with custom configuration
+// TimeoutMiddlewareWithConfig creates timeout middleware with custom configuration. Skip paths match
+// the request path or the route pattern, e.g. "/api/v1/items/:id/stream", for long-lived streams.
 func TimeoutMiddlewareWithConfig(timeout time.Duration, skipPaths []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Check if path should skip timeout
 		for _, path := range skipPaths {
-			if c.Request.URL.Path == path {
+			if c.Request.URL.Path == path || c.FullPath() == path {
 				c.Next()
 				return
 			}
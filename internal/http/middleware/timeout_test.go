@@ -0,0 +1,33 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/unitechio/einfra-be/internal/http/middleware"
+)
+
+// TestTimeoutMiddlewareSkipsStreams tests that a stream route keeps its context past the request timeout
+func TestTimeoutMiddlewareSkipsStreams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.TimeoutMiddlewareWithConfig(20*time.Millisecond, []string{"/clusters/:cluster_id/watch"}))
+
+	router.GET("/clusters/:cluster_id/watch", func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+			c.String(http.StatusRequestTimeout, "cancelled")
+		case <-time.After(60 * time.Millisecond):
+			c.String(http.StatusOK, "still streaming")
+		}
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/clusters/cluster-1/watch", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "still streaming", w.Body.String())
+}
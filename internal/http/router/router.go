@@ -27,14 +27,23 @@ func InitRouter(
 	dockerHandler *handler.DockerHandler,
 	dockerExecHandler *handler.DockerExecHandler,
 	dockerStatsHandler *handler.DockerStatsHandler,
+	containerMetricsHandler *handler.ContainerMetricsHandler,
+	alertHandler *handler.AlertHandler,
 	dockerStackHandler *handler.DockerStackHandler,
+	stackTemplateHandler *handler.StackTemplateHandler,
 	dockerNetworkHandler *handler.DockerNetworkHandler,
 	dockerImageHandler *handler.DockerImageHandler,
 	fileBrowserHandler *handler.FileBrowserHandler,
 	logHandler *handler.LogHandler,
 	eventHandler *handler.EventHandler,
 	kubernetesHandler *handler.KubernetesHandler,
+	helmHandler *handler.HelmHandler,
 	harborHandler *handler.HarborHandler,
+	harborWebhookHandler *handler.HarborWebhookHandler,
+	harborPromotionHandler *handler.HarborPromotionHandler,
+	harborRetentionHandler *handler.HarborRetentionHandler,
+	imageSearchHandler *handler.ImageSearchHandler,
+	vulnerabilityGateHandler *handler.VulnerabilityGateHandler,
 	pingHandler *handler.PingHandler,
 	emailHandler *handler.EmailHandler,
 	notificationHandler *handler.NotificationHandler,
@@ -54,7 +63,14 @@ func InitRouter(
 	router.Use(gin.Logger())
 	router.Use(middleware.RecoveryMiddleware())
 	router.Use(middleware.RequestIDMiddleware())
-	router.Use(middleware.TimeoutMiddleware(30 * time.Second))
+	// WebSocket and follow streams outlive any request timeout; rollout status waits up to its own bound
+	router.Use(middleware.TimeoutMiddlewareWithConfig(30*time.Second, []string{
+		"/api/v1/kubernetes/clusters/:cluster_id/watch",
+		"/api/v1/kubernetes/clusters/:cluster_id/namespaces/:namespace/pods/:pod_name/logs",
+		"/api/v1/kubernetes/clusters/:cluster_id/namespaces/:namespace/pods/:pod_name/exec",
+		"/api/v1/kubernetes/clusters/:cluster_id/namespaces/:namespace/logs",
+		"/api/v1/kubernetes/clusters/:cluster_id/namespaces/:namespace/deployments/:name/rollout/status",
+	}))
 
 	// Health Check
 	router.GET("/health", healthHandler.HealthCheck)
@@ -81,6 +97,18 @@ func InitRouter(
 		auth.POST("/verify-email", authHandler.VerifyEmail)
 	}
 
+	// Webhook Routes - authenticated by a per-registry shared secret instead of a user token
+	webhooks := v1.Group("/harbor/webhooks")
+	{
+		webhooks.POST("/:registry_id", harborWebhookHandler.ReceiveEvent)
+	}
+
+	// Git push webhooks of git-backed stacks - authenticated by a per-stack shared secret
+	stackWebhooks := v1.Group("/docker/webhooks")
+	{
+		stackWebhooks.POST("/stacks/:id", dockerStackHandler.ReceiveGitWebhook)
+	}
+
 	// Protected Routes
 	protected := v1.Group("")
 	// protected.Use(middleware.AuthMiddleware(cfg.JWT.Secret)) // Uncomment when middleware is ready
@@ -272,6 +300,13 @@ func InitRouter(
 			dockerContainers.POST("/:id/pause", dockerStatsHandler.PauseContainer)
 			dockerContainers.POST("/:id/unpause", dockerStatsHandler.UnpauseContainer)
 			dockerContainers.POST("/:id/commit", dockerStatsHandler.CommitContainer)
+
+			// Container Files
+			dockerContainers.GET("/:id/files", fileBrowserHandler.ListContainerFiles)
+			dockerContainers.GET("/:id/files/download", fileBrowserHandler.DownloadContainerFile)
+			dockerContainers.POST("/:id/files/upload", fileBrowserHandler.UploadContainerFile)
+			dockerContainers.GET("/:id/files/content", fileBrowserHandler.ReadContainerFile)
+			dockerContainers.PUT("/:id/files/content", fileBrowserHandler.WriteContainerFile)
 		}
 
 		// Docker Exec Management
@@ -293,6 +328,52 @@ func InitRouter(
 			dockerStacks.GET("/:id/logs", dockerStackHandler.GetStackLogs)
 			dockerStacks.POST("/:id/start", dockerStackHandler.StartStack)
 			dockerStacks.POST("/:id/stop", dockerStackHandler.StopStack)
+			dockerStacks.POST("/:id/diff", dockerStackHandler.DiffStack)
+			dockerStacks.GET("/:id/revisions", dockerStackHandler.ListStackRevisions)
+			dockerStacks.POST("/:id/rollback", dockerStackHandler.RollbackStack)
+			dockerStacks.POST("/:id/revisions/:revision/approve", dockerStackHandler.ApproveStackRevision)
+			dockerStacks.POST("/:id/revisions/:revision/reject", dockerStackHandler.RejectStackRevision)
+			dockerStacks.GET("/:id/git", dockerStackHandler.GetStackGit)
+			dockerStacks.PUT("/:id/git", dockerStackHandler.ConfigureStackGit)
+			dockerStacks.DELETE("/:id/git", dockerStackHandler.RemoveStackGit)
+			dockerStacks.POST("/:id/git/sync", dockerStackHandler.SyncStackGit)
+			dockerStacks.GET("/:id/metrics", containerMetricsHandler.GetStackMetrics)
+		}
+
+		// Stack Template Catalog
+		stackTemplates := protected.Group("/docker/templates")
+		{
+			stackTemplates.GET("", stackTemplateHandler.ListTemplates)
+			stackTemplates.POST("", stackTemplateHandler.CreateTemplate)
+			stackTemplates.GET("/:id", stackTemplateHandler.GetTemplate)
+			stackTemplates.PUT("/:id", stackTemplateHandler.UpdateTemplate)
+			stackTemplates.DELETE("/:id", stackTemplateHandler.DeleteTemplate)
+			stackTemplates.POST("/:id/deploy", stackTemplateHandler.DeployTemplate)
+		}
+
+		// Alerting
+		alerts := protected.Group("/alerts")
+		{
+			alerts.GET("/rules", alertHandler.ListRules)
+			alerts.POST("/rules", alertHandler.CreateRule)
+			alerts.GET("/rules/:id", alertHandler.GetRule)
+			alerts.PUT("/rules/:id", alertHandler.UpdateRule)
+			alerts.DELETE("/rules/:id", alertHandler.DeleteRule)
+			alerts.GET("/silences", alertHandler.ListSilences)
+			alerts.POST("/silences", alertHandler.CreateSilence)
+			alerts.DELETE("/silences/:id", alertHandler.ExpireSilence)
+			alerts.GET("/maintenance-windows", alertHandler.ListMaintenanceWindows)
+			alerts.POST("/maintenance-windows", alertHandler.CreateMaintenanceWindow)
+			alerts.DELETE("/maintenance-windows/:id", alertHandler.DeleteMaintenanceWindow)
+			alerts.GET("/history", alertHandler.ListHistory)
+			alerts.GET("/receivers", alertHandler.ListReceivers)
+			alerts.POST("/receivers", alertHandler.CreateReceiver)
+			alerts.GET("/receivers/:id", alertHandler.GetReceiver)
+			alerts.PUT("/receivers/:id", alertHandler.UpdateReceiver)
+			alerts.DELETE("/receivers/:id", alertHandler.DeleteReceiver)
+			alerts.POST("/receivers/:id/test", alertHandler.TestReceiver)
+			alerts.GET("/routing", alertHandler.GetRouting)
+			alerts.PUT("/routing", alertHandler.UpdateRouting)
 		}
 
 		// Docker Network Management
@@ -351,23 +432,39 @@ func InitRouter(
 			// Docker Hosts
 			docker.POST("/hosts", dockerHandler.CreateHost)
 			docker.GET("/hosts", dockerHandler.ListHosts)
-			docker.GET("/hosts/:id", dockerHandler.GetHost)
-			docker.PUT("/hosts/:id", dockerHandler.UpdateHost)
-			docker.DELETE("/hosts/:id", dockerHandler.DeleteHost)
+			docker.GET("/hosts/:host_id", dockerHandler.GetHost)
+			docker.PUT("/hosts/:host_id", dockerHandler.UpdateHost)
+			docker.DELETE("/hosts/:host_id", dockerHandler.DeleteHost)
+			docker.POST("/hosts/:host_id/refresh", dockerHandler.RefreshHost)
+			docker.GET("/hosts/:host_id/metrics", containerMetricsHandler.GetHostMetrics)
 
 			// Containers
 			docker.GET("/hosts/:host_id/containers", dockerHandler.ListContainers)
+			docker.POST("/hosts/:host_id/containers", dockerHandler.CreateContainer)
+			docker.GET("/hosts/:host_id/containers/:container_id", dockerHandler.GetContainer)
+			docker.DELETE("/hosts/:host_id/containers/:container_id", dockerHandler.RemoveContainer)
 			docker.POST("/hosts/:host_id/containers/:container_id/start", dockerHandler.StartContainer)
 			docker.POST("/hosts/:host_id/containers/:container_id/stop", dockerHandler.StopContainer)
+			docker.POST("/hosts/:host_id/containers/:container_id/restart", dockerHandler.RestartContainer)
 			docker.GET("/hosts/:host_id/containers/:container_id/logs", dockerHandler.GetContainerLogs)
+			docker.GET("/hosts/:host_id/containers/:container_id/stats", dockerHandler.GetContainerStats)
+			docker.GET("/hosts/:host_id/containers/:container_id/metrics", containerMetricsHandler.GetContainerMetrics)
 
 			// Images
 			docker.GET("/hosts/:host_id/images", dockerHandler.ListImages)
 			docker.POST("/hosts/:host_id/images/pull", dockerHandler.PullImage)
+			docker.DELETE("/hosts/:host_id/images/:image_id", dockerHandler.RemoveImage)
+
+			// Networks and Volumes
+			docker.GET("/hosts/:host_id/networks", dockerHandler.ListNetworks)
+			docker.GET("/hosts/:host_id/volumes", dockerHandler.ListVolumes)
+			docker.POST("/hosts/:host_id/volumes", dockerHandler.CreateVolume)
+			docker.DELETE("/hosts/:host_id/volumes/:volume_name", dockerHandler.RemoveVolume)
 		}
 
 		// Kubernetes Management Routes
 		k8s := protected.Group("/kubernetes")
+		k8s.Use(middleware.UserContextMiddleware())
 		{
 			// Clusters
 			k8s.POST("/clusters", kubernetesHandler.CreateCluster)
@@ -375,6 +472,7 @@ func InitRouter(
 			k8s.GET("/clusters/:id", kubernetesHandler.GetCluster)
 			k8s.PUT("/clusters/:id", kubernetesHandler.UpdateCluster)
 			k8s.DELETE("/clusters/:id", kubernetesHandler.DeleteCluster)
+			k8s.GET("/clusters/:cluster_id/overview", kubernetesHandler.GetClusterOverview)
 
 			// Namespaces
 			k8s.GET("/clusters/:cluster_id/namespaces", kubernetesHandler.ListNamespaces)
@@ -382,13 +480,37 @@ func InitRouter(
 			// Deployments
 			k8s.GET("/clusters/:cluster_id/deployments", kubernetesHandler.ListDeployments)
 			k8s.POST("/clusters/:cluster_id/namespaces/:namespace/deployments/:name/scale", kubernetesHandler.ScaleDeployment)
+			k8s.GET("/clusters/:cluster_id/namespaces/:namespace/deployments/:name/rollout/history", kubernetesHandler.GetRolloutHistory)
+			k8s.GET("/clusters/:cluster_id/namespaces/:namespace/deployments/:name/rollout/status", kubernetesHandler.GetRolloutStatus)
+			k8s.POST("/clusters/:cluster_id/namespaces/:namespace/deployments/:name/rollout/rollback", kubernetesHandler.RollbackDeployment)
+			k8s.POST("/clusters/:cluster_id/namespaces/:namespace/deployments/:name/rollout/restart", kubernetesHandler.RestartDeployment)
+			k8s.POST("/clusters/:cluster_id/namespaces/:namespace/deployments/:name/rollout/pause", kubernetesHandler.PauseDeployment)
+			k8s.POST("/clusters/:cluster_id/namespaces/:namespace/deployments/:name/rollout/resume", kubernetesHandler.ResumeDeployment)
+
+			// RBAC sync
+			k8s.GET("/clusters/:cluster_id/rbac", kubernetesHandler.GetRBAC)
+			k8s.POST("/clusters/:cluster_id/rbac/sync", kubernetesHandler.SyncRBAC)
+
+			// Helm
+			k8s.POST("/helm/charts", helmHandler.UploadChart)
+			k8s.GET("/clusters/:cluster_id/helm/releases", helmHandler.ListReleases)
+			k8s.POST("/clusters/:cluster_id/helm/releases", helmHandler.InstallRelease)
+			k8s.GET("/clusters/:cluster_id/namespaces/:namespace/helm/releases/:name", helmHandler.GetRelease)
+			k8s.PUT("/clusters/:cluster_id/namespaces/:namespace/helm/releases/:name", helmHandler.UpgradeRelease)
+			k8s.DELETE("/clusters/:cluster_id/namespaces/:namespace/helm/releases/:name", helmHandler.UninstallRelease)
+			k8s.GET("/clusters/:cluster_id/namespaces/:namespace/helm/releases/:name/history", helmHandler.GetReleaseHistory)
+			k8s.POST("/clusters/:cluster_id/namespaces/:namespace/helm/releases/:name/rollback", helmHandler.RollbackRelease)
 
 			// Pods
 			k8s.GET("/clusters/:cluster_id/pods", kubernetesHandler.ListPods)
 			k8s.GET("/clusters/:cluster_id/namespaces/:namespace/pods/:pod_name/logs", kubernetesHandler.GetPodLogs)
+			k8s.GET("/clusters/:cluster_id/namespaces/:namespace/pods/:pod_name/exec", kubernetesHandler.ExecPodTerminal)
+			k8s.POST("/clusters/:cluster_id/namespaces/:namespace/pods/:pod_name/exec", kubernetesHandler.ExecPodCommand)
+			k8s.GET("/clusters/:cluster_id/namespaces/:namespace/logs", kubernetesHandler.GetSelectorLogs)
 
 			// Nodes
 			k8s.GET("/clusters/:cluster_id/nodes", kubernetesHandler.ListNodes)
+			k8s.GET("/clusters/:cluster_id/nodes/:name", kubernetesHandler.GetNode)
 			// ConfigMaps
 			k8s.GET("/clusters/:cluster_id/configmaps", kubernetesHandler.ListConfigMaps)
 
@@ -398,10 +520,25 @@ func InitRouter(
 			// Ingresses
 			k8s.GET("/clusters/:cluster_id/ingresses", kubernetesHandler.ListIngresses)
 
+			// Watch
+			k8s.GET("/clusters/:cluster_id/watch", kubernetesHandler.WatchResources)
+
+			// Manifests
+			k8s.POST("/clusters/:cluster_id/manifests/apply", kubernetesHandler.ApplyManifest)
+
 			// Backups
 			k8s.POST("/clusters/:cluster_id/backups", kubernetesHandler.CreateBackup)
 			k8s.GET("/clusters/:cluster_id/backups", kubernetesHandler.ListBackups)
 			k8s.POST("/backups/:id/restore", kubernetesHandler.RestoreBackup)
+			k8s.GET("/backups/diff", kubernetesHandler.DiffBackups)
+
+			// Backup Schedules
+			k8s.POST("/clusters/:cluster_id/backup-schedules", kubernetesHandler.CreateBackupSchedule)
+			k8s.GET("/clusters/:cluster_id/backup-schedules", kubernetesHandler.ListBackupSchedules)
+			k8s.GET("/backup-schedules/:id", kubernetesHandler.GetBackupSchedule)
+			k8s.PUT("/backup-schedules/:id", kubernetesHandler.UpdateBackupSchedule)
+			k8s.DELETE("/backup-schedules/:id", kubernetesHandler.DeleteBackupSchedule)
+			k8s.POST("/backup-schedules/:id/run", kubernetesHandler.RunBackupSchedule)
 		}
 
 		// Harbor Management Routes
@@ -413,6 +550,8 @@ func InitRouter(
 			harbor.GET("/registries/:id", harborHandler.GetRegistry)
 			harbor.PUT("/registries/:id", harborHandler.UpdateRegistry)
 			harbor.DELETE("/registries/:id", harborHandler.DeleteRegistry)
+			harbor.POST("/registries/:id/test", harborHandler.TestRegistryConnection)
+			harbor.POST("/registries/:id/gc", harborHandler.RunGarbageCollection)
 
 			// Projects
 			harbor.GET("/registries/:registry_id/projects", harborHandler.ListProjects)
@@ -430,8 +569,47 @@ func InitRouter(
 			// Image Deployment Tracking
 			harbor.POST("/deployments", harborHandler.TrackDeployment)
 			harbor.GET("/deployments/history", harborHandler.GetDeploymentHistory)
+			harbor.GET("/deployments/changes", harborHandler.GetDeploymentChanges)
 			harbor.GET("/deployments/:cluster_id/active", harborHandler.GetCurrentDeployments)
 			harbor.POST("/deployments/:cluster_id/sync", harborHandler.SyncDeployments)
+			harbor.GET("/image-locations", imageSearchHandler.SearchImageLocations)
+			harbor.GET("/image-locations/export", imageSearchHandler.ExportImageLocations)
+
+			// Vulnerability Gate
+			harbor.GET("/vulnerability-policies", vulnerabilityGateHandler.ListPolicies)
+			harbor.GET("/vulnerability-policies/:environment_id", vulnerabilityGateHandler.GetPolicy)
+			harbor.PUT("/vulnerability-policies/:environment_id", vulnerabilityGateHandler.SavePolicy)
+			harbor.DELETE("/vulnerability-policies/:environment_id", vulnerabilityGateHandler.DeletePolicy)
+			harbor.POST("/vulnerability-policies/:environment_id/evaluate", vulnerabilityGateHandler.EvaluatePolicy)
+			harbor.GET("/cve-exceptions", vulnerabilityGateHandler.ListExceptions)
+			harbor.POST("/cve-exceptions", vulnerabilityGateHandler.RequestException)
+			harbor.POST("/cve-exceptions/:id/approve", vulnerabilityGateHandler.ApproveException)
+			harbor.POST("/cve-exceptions/:id/reject", vulnerabilityGateHandler.RejectException)
+			harbor.POST("/cve-exceptions/:id/revoke", vulnerabilityGateHandler.RevokeException)
+
+			// Promotions
+			harbor.POST("/promotion-rules", harborPromotionHandler.CreateRule)
+			harbor.GET("/promotion-rules", harborPromotionHandler.ListRules)
+			harbor.GET("/promotion-rules/:id", harborPromotionHandler.GetRule)
+			harbor.PUT("/promotion-rules/:id", harborPromotionHandler.UpdateRule)
+			harbor.DELETE("/promotion-rules/:id", harborPromotionHandler.DeleteRule)
+			harbor.POST("/promotion-rules/:id/promote", harborPromotionHandler.Promote)
+			harbor.GET("/promotions", harborPromotionHandler.ListPromotions)
+			harbor.GET("/promotions/:id", harborPromotionHandler.GetPromotion)
+			harbor.POST("/promotions/:id/approve", harborPromotionHandler.ApprovePromotion)
+			harbor.POST("/promotions/:id/reject", harborPromotionHandler.RejectPromotion)
+			harbor.GET("/artifacts/:digest/promotions", harborPromotionHandler.GetArtifactPromotions)
+
+			// Tag retention
+			harbor.POST("/retention-policies", harborRetentionHandler.CreatePolicy)
+			harbor.GET("/retention-policies", harborRetentionHandler.ListPolicies)
+			harbor.GET("/retention-policies/:id", harborRetentionHandler.GetPolicy)
+			harbor.PUT("/retention-policies/:id", harborRetentionHandler.UpdatePolicy)
+			harbor.DELETE("/retention-policies/:id", harborRetentionHandler.DeletePolicy)
+			harbor.POST("/retention-policies/:id/enforce", harborRetentionHandler.EnforcePolicy)
+			harbor.POST("/retention-policies/:id/run", harborRetentionHandler.RunPolicy)
+			harbor.GET("/retention-policies/:id/runs", harborRetentionHandler.ListRuns)
+			harbor.GET("/retention-runs/:id", harborRetentionHandler.GetRun)
 		}
 
 		// Email Routes
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/security"
+	"gorm.io/gorm"
+)
+
+// AlertReceiverRepository handles database operations for alert receivers and the routing tree.
+// Receiver secrets are encrypted at rest.
+type AlertReceiverRepository interface {
+	Create(ctx context.Context, receiver *domain.AlertReceiver) error
+	GetByID(ctx context.Context, id string) (*domain.AlertReceiver, error)
+	GetByName(ctx context.Context, name string) (*domain.AlertReceiver, error)
+	List(ctx context.Context) ([]*domain.AlertReceiver, error)
+	Update(ctx context.Context, receiver *domain.AlertReceiver) error
+	Delete(ctx context.Context, id string) error
+	// GetRouting retrieves the routing tree; nil when none was saved
+	GetRouting(ctx context.Context) (*domain.AlertRouting, error)
+	SaveRouting(ctx context.Context, routing *domain.AlertRouting) error
+}
+
+type alertReceiverRepository struct {
+	db         *gorm.DB
+	encryption security.EncryptionService
+}
+
+// NewAlertReceiverRepository creates a new alert receiver repository
+func NewAlertReceiverRepository(db *gorm.DB, encryption security.EncryptionService) AlertReceiverRepository {
+	return &alertReceiverRepository{db: db, encryption: encryption}
+}
+
+// Create creates a new receiver
+func (r *alertReceiverRepository) Create(ctx context.Context, receiver *domain.AlertReceiver) error {
+	return r.save(ctx, receiver, true)
+}
+
+// GetByID retrieves a receiver by ID
+func (r *alertReceiverRepository) GetByID(ctx context.Context, id string) (*domain.AlertReceiver, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+// GetByName retrieves a receiver by name
+func (r *alertReceiverRepository) GetByName(ctx context.Context, name string) (*domain.AlertReceiver, error) {
+	return r.first(ctx, "name = ?", name)
+}
+
+// List retrieves every receiver, ordered by name
+func (r *alertReceiverRepository) List(ctx context.Context) ([]*domain.AlertReceiver, error) {
+	var receivers []*domain.AlertReceiver
+	if err := r.db.WithContext(ctx).Order("name ASC").Find(&receivers).Error; err != nil {
+		return nil, err
+	}
+	for _, receiver := range receivers {
+		if err := r.decrypt(receiver); err != nil {
+			return nil, err
+		}
+	}
+	return receivers, nil
+}
+
+// Update updates a receiver
+func (r *alertReceiverRepository) Update(ctx context.Context, receiver *domain.AlertReceiver) error {
+	return r.save(ctx, receiver, false)
+}
+
+// Delete deletes a receiver
+func (r *alertReceiverRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&domain.AlertReceiver{}, "id = ?", id).Error
+}
+
+// GetRouting retrieves the routing tree; nil when none was saved
+func (r *alertReceiverRepository) GetRouting(ctx context.Context) (*domain.AlertRouting, error) {
+	var routing domain.AlertRouting
+	err := r.db.WithContext(ctx).Where("id = ?", 1).First(&routing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &routing, nil
+}
+
+// SaveRouting creates or replaces the routing tree
+func (r *alertReceiverRepository) SaveRouting(ctx context.Context, routing *domain.AlertRouting) error {
+	routing.ID = 1
+	return r.db.WithContext(ctx).Save(routing).Error
+}
+
+func (r *alertReceiverRepository) first(ctx context.Context, query string, arg string) (*domain.AlertReceiver, error) {
+	var receiver domain.AlertReceiver
+	if err := r.db.WithContext(ctx).Where(query, arg).First(&receiver).Error; err != nil {
+		return nil, err
+	}
+	if err := r.decrypt(&receiver); err != nil {
+		return nil, err
+	}
+	return &receiver, nil
+}
+
+func (r *alertReceiverRepository) save(ctx context.Context, receiver *domain.AlertReceiver, create bool) error {
+	stored := *receiver
+	if stored.Secret != "" {
+		var err error
+		if stored.Secret, err = r.encryption.Encrypt(stored.Secret); err != nil {
+			return fmt.Errorf("failed to encrypt receiver secret: %w", err)
+		}
+	}
+
+	db := r.db.WithContext(ctx)
+	var err error
+	if create {
+		err = db.Create(&stored).Error
+	} else {
+		err = db.Save(&stored).Error
+	}
+	if err != nil {
+		return err
+	}
+	receiver.ID, receiver.CreatedAt, receiver.UpdatedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt
+	receiver.HasSecret = receiver.Secret != ""
+	return nil
+}
+
+func (r *alertReceiverRepository) decrypt(receiver *domain.AlertReceiver) error {
+	if receiver.Secret != "" {
+		secret, err := r.encryption.Decrypt(receiver.Secret)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt receiver secret: %w", err)
+		}
+		receiver.Secret = secret
+	}
+	receiver.HasSecret = receiver.Secret != ""
+	return nil
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"gorm.io/gorm"
+)
+
+// AlertRepository handles database operations for alert rules, their history, silences and maintenance windows
+type AlertRepository interface {
+	CreateRule(ctx context.Context, rule *domain.AlertRule) error
+	GetRule(ctx context.Context, id string) (*domain.AlertRule, error)
+	ListRules(ctx context.Context) ([]*domain.AlertRule, error)
+	UpdateRule(ctx context.Context, rule *domain.AlertRule) error
+	DeleteRule(ctx context.Context, id string) error
+
+	CreateHistory(ctx context.Context, history *domain.AlertHistory) error
+	UpdateHistory(ctx context.Context, history *domain.AlertHistory) error
+	// ListHistory retrieves a page of the alerts matching a filter, latest first, with the total count
+	ListHistory(ctx context.Context, filter domain.AlertHistoryFilter) ([]*domain.AlertHistory, int64, error)
+	// ListFiringHistory retrieves every alert that has not resolved
+	ListFiringHistory(ctx context.Context) ([]*domain.AlertHistory, error)
+
+	CreateSilence(ctx context.Context, silence *domain.AlertSilence) error
+	GetSilence(ctx context.Context, id string) (*domain.AlertSilence, error)
+	// ListSilences retrieves the silences ending after a time, latest first
+	ListSilences(ctx context.Context, endsAfter time.Time) ([]*domain.AlertSilence, error)
+	UpdateSilence(ctx context.Context, silence *domain.AlertSilence) error
+
+	CreateMaintenanceWindow(ctx context.Context, window *domain.AlertMaintenanceWindow) error
+	ListMaintenanceWindows(ctx context.Context) ([]*domain.AlertMaintenanceWindow, error)
+	DeleteMaintenanceWindow(ctx context.Context, id string) error
+}
+
+type alertRepository struct {
+	db *gorm.DB
+}
+
+// NewAlertRepository creates a new alert repository
+func NewAlertRepository(db *gorm.DB) AlertRepository {
+	return &alertRepository{db: db}
+}
+
+// CreateRule creates a new rule
+func (r *alertRepository) CreateRule(ctx context.Context, rule *domain.AlertRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+// GetRule retrieves a rule by ID
+func (r *alertRepository) GetRule(ctx context.Context, id string) (*domain.AlertRule, error) {
+	var rule domain.AlertRule
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ListRules retrieves every rule, ordered by name
+func (r *alertRepository) ListRules(ctx context.Context) ([]*domain.AlertRule, error) {
+	var rules []*domain.AlertRule
+	err := r.db.WithContext(ctx).Order("name ASC").Find(&rules).Error
+	return rules, err
+}
+
+// UpdateRule updates a rule
+func (r *alertRepository) UpdateRule(ctx context.Context, rule *domain.AlertRule) error {
+	return r.db.WithContext(ctx).Save(rule).Error
+}
+
+// DeleteRule deletes a rule; its history is kept
+func (r *alertRepository) DeleteRule(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&domain.AlertRule{}, "id = ?", id).Error
+}
+
+// CreateHistory records a fired alert
+func (r *alertRepository) CreateHistory(ctx context.Context, history *domain.AlertHistory) error {
+	return r.db.WithContext(ctx).Create(history).Error
+}
+
+// UpdateHistory updates a recorded alert
+func (r *alertRepository) UpdateHistory(ctx context.Context, history *domain.AlertHistory) error {
+	return r.db.WithContext(ctx).Save(history).Error
+}
+
+// ListHistory retrieves a page of the alerts matching a filter, latest first, with the total count
+func (r *alertRepository) ListHistory(ctx context.Context, filter domain.AlertHistoryFilter) ([]*domain.AlertHistory, int64, error) {
+	query := r.db.WithContext(ctx).Model(&domain.AlertHistory{})
+	if filter.RuleID != "" {
+		query = query.Where("rule_id = ?", filter.RuleID)
+	}
+	if filter.Scope != "" {
+		query = query.Where("scope = ?", filter.Scope)
+	}
+	if filter.Subject != "" {
+		query = query.Where("subject = ?", filter.Subject)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("triggered_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("triggered_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var history []*domain.AlertHistory
+	err := query.Order("triggered_at DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&history).Error
+	return history, total, err
+}
+
+// ListFiringHistory retrieves every alert that has not resolved
+func (r *alertRepository) ListFiringHistory(ctx context.Context) ([]*domain.AlertHistory, error) {
+	var history []*domain.AlertHistory
+	err := r.db.WithContext(ctx).Where("status = ?", domain.AlertStatusFiring).Find(&history).Error
+	return history, err
+}
+
+// CreateSilence creates a new silence
+func (r *alertRepository) CreateSilence(ctx context.Context, silence *domain.AlertSilence) error {
+	return r.db.WithContext(ctx).Create(silence).Error
+}
+
+// GetSilence retrieves a silence by ID
+func (r *alertRepository) GetSilence(ctx context.Context, id string) (*domain.AlertSilence, error) {
+	var silence domain.AlertSilence
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&silence).Error
+	if err != nil {
+		return nil, err
+	}
+	return &silence, nil
+}
+
+// ListSilences retrieves the silences ending after a time, latest first
+func (r *alertRepository) ListSilences(ctx context.Context, endsAfter time.Time) ([]*domain.AlertSilence, error) {
+	var silences []*domain.AlertSilence
+	err := r.db.WithContext(ctx).Where("ends_at > ?", endsAfter).Order("starts_at DESC").Find(&silences).Error
+	return silences, err
+}
+
+// UpdateSilence updates a silence
+func (r *alertRepository) UpdateSilence(ctx context.Context, silence *domain.AlertSilence) error {
+	return r.db.WithContext(ctx).Save(silence).Error
+}
+
+// CreateMaintenanceWindow creates a new maintenance window
+func (r *alertRepository) CreateMaintenanceWindow(ctx context.Context, window *domain.AlertMaintenanceWindow) error {
+	return r.db.WithContext(ctx).Create(window).Error
+}
+
+// ListMaintenanceWindows retrieves every maintenance window, ordered by start
+func (r *alertRepository) ListMaintenanceWindows(ctx context.Context) ([]*domain.AlertMaintenanceWindow, error) {
+	var windows []*domain.AlertMaintenanceWindow
+	err := r.db.WithContext(ctx).Order("starts_at ASC").Find(&windows).Error
+	return windows, err
+}
+
+// DeleteMaintenanceWindow deletes a maintenance window
+func (r *alertRepository) DeleteMaintenanceWindow(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&domain.AlertMaintenanceWindow{}, "id = ?", id).Error
+}
@@ -13,12 +13,14 @@ type AuthorizationRepository interface {
 	GetUserEnvironmentRoles(ctx context.Context, userID string) ([]*domain.UserEnvironmentRole, error)
 	GetUserEnvironmentRolesByEnv(ctx context.Context, userID, environmentID string) ([]*domain.UserEnvironmentRole, error)
 	DeleteUserEnvironmentRole(ctx context.Context, id string) error
+	ListEnvironmentRoleAssignments(ctx context.Context) ([]*domain.UserEnvironmentRole, error)
 
 	// ResourcePermission operations
 	CreateResourcePermission(ctx context.Context, rp *domain.ResourcePermission) error
 	GetResourcePermission(ctx context.Context, id string) (*domain.ResourcePermission, error)
 	GetUserResourcePermissions(ctx context.Context, userID string) ([]*domain.ResourcePermission, error)
 	GetResourcePermissions(ctx context.Context, resourceType domain.ResourceType, resourceID string) ([]*domain.ResourcePermission, error)
+	ListResourcePermissionsByType(ctx context.Context, resourceType domain.ResourceType) ([]*domain.ResourcePermission, error)
 	GetUserResourcePermissionByResource(ctx context.Context, userID string, resourceType domain.ResourceType, resourceID string) (*domain.ResourcePermission, error)
 	UpdateResourcePermission(ctx context.Context, rp *domain.ResourcePermission) error
 	DeleteResourcePermission(ctx context.Context, id string) error
@@ -26,6 +28,7 @@ type AuthorizationRepository interface {
 
 	// Permission checking
 	GetUserPermissions(ctx context.Context, userID string) (*domain.UserPermissions, error)
+	ListUsersWithGlobalRole(ctx context.Context) ([]*domain.User, error)
 }
 type authorizationRepository struct {
 	db *gorm.DB
@@ -65,6 +68,18 @@ func (r *authorizationRepository) DeleteUserEnvironmentRole(ctx context.Context,
 	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&domain.UserEnvironmentRole{}).Error
 }
 
+// ListEnvironmentRoleAssignments lists the environment roles of active users, with the roles' permissions
+func (r *authorizationRepository) ListEnvironmentRoleAssignments(ctx context.Context) ([]*domain.UserEnvironmentRole, error) {
+	var roles []*domain.UserEnvironmentRole
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Preload("Role").
+		Preload("Role.Permissions").
+		Where("user_id IN (?)", r.activeUserIDs(ctx)).
+		Find(&roles).Error
+	return roles, err
+}
+
 // ResourcePermission operations
 
 func (r *authorizationRepository) CreateResourcePermission(ctx context.Context, rp *domain.ResourcePermission) error {
@@ -103,6 +118,17 @@ func (r *authorizationRepository) GetResourcePermissions(ctx context.Context, re
 	return permissions, err
 }
 
+// ListResourcePermissionsByType lists the unexpired permissions of active users on resources of a type
+func (r *authorizationRepository) ListResourcePermissionsByType(ctx context.Context, resourceType domain.ResourceType) ([]*domain.ResourcePermission, error) {
+	var permissions []*domain.ResourcePermission
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("resource_type = ? AND (expires_at IS NULL OR expires_at > ?)", resourceType, time.Now()).
+		Where("user_id IN (?)", r.activeUserIDs(ctx)).
+		Find(&permissions).Error
+	return permissions, err
+}
+
 func (r *authorizationRepository) GetUserResourcePermissionByResource(ctx context.Context, userID string, resourceType domain.ResourceType, resourceID string) (*domain.ResourcePermission, error) {
 	var rp domain.ResourcePermission
 	err := r.db.WithContext(ctx).
@@ -195,3 +221,19 @@ func (r *authorizationRepository) GetUserPermissions(ctx context.Context, userID
 
 	return userPerms, nil
 }
+
+// ListUsersWithGlobalRole lists active users that have a global role, with the role's permissions
+func (r *authorizationRepository) ListUsersWithGlobalRole(ctx context.Context) ([]*domain.User, error) {
+	var users []*domain.User
+	err := r.db.WithContext(ctx).
+		Preload("Role").
+		Preload("Role.Permissions").
+		Where("is_active = ? AND role_id IS NOT NULL", true).
+		Find(&users).Error
+	return users, err
+}
+
+// activeUserIDs is a subquery selecting the IDs of active users
+func (r *authorizationRepository) activeUserIDs(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&domain.User{}).Select("id").Where("is_active = ?", true)
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ContainerMetricRepository handles database operations for container metric rollups
+type ContainerMetricRepository interface {
+	// SaveMetrics stores buckets, replacing those already stored for the same container and time
+	SaveMetrics(ctx context.Context, metrics []*domain.ContainerMetric) error
+	// ListMetrics retrieves the buckets matching a filter, ordered by container and time
+	ListMetrics(ctx context.Context, filter domain.ContainerMetricFilter) ([]*domain.ContainerMetric, error)
+	// DeleteMetricsBefore removes the buckets of a resolution older than a time
+	DeleteMetricsBefore(ctx context.Context, resolution domain.ContainerMetricResolution, before time.Time) (int64, error)
+}
+
+// containerMetricBatchSize bounds the rows inserted per statement
+const containerMetricBatchSize = 500
+
+type containerMetricRepository struct {
+	db *gorm.DB
+}
+
+// NewContainerMetricRepository creates a new container metric repository
+func NewContainerMetricRepository(db *gorm.DB) ContainerMetricRepository {
+	return &containerMetricRepository{db: db}
+}
+
+// SaveMetrics stores buckets, replacing those already stored for the same container and time
+func (r *containerMetricRepository) SaveMetrics(ctx context.Context, metrics []*domain.ContainerMetric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "host_id"}, {Name: "container_id"}, {Name: "resolution"}, {Name: "timestamp"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"container_name", "project", "samples", "cpu_percent", "cpu_percent_max", "memory_usage",
+			"memory_max", "memory_limit", "network_rx", "network_tx", "block_read", "block_write",
+		}),
+	}).CreateInBatches(metrics, containerMetricBatchSize).Error
+}
+
+// ListMetrics retrieves the buckets matching a filter, ordered by container and time
+func (r *containerMetricRepository) ListMetrics(ctx context.Context, filter domain.ContainerMetricFilter) ([]*domain.ContainerMetric, error) {
+	query := r.db.WithContext(ctx).Where("resolution = ?", filter.Resolution)
+	if filter.HostID != "" {
+		query = query.Where("host_id = ?", filter.HostID)
+	}
+	if filter.ContainerID != "" {
+		query = query.Where("container_id = ?", filter.ContainerID)
+	}
+	if filter.Project != "" {
+		query = query.Where("project = ?", filter.Project)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("timestamp >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("timestamp < ?", filter.To)
+	}
+
+	var metrics []*domain.ContainerMetric
+	err := query.Order("host_id, container_id, timestamp").Find(&metrics).Error
+	return metrics, err
+}
+
+// DeleteMetricsBefore removes the buckets of a resolution older than a time
+func (r *containerMetricRepository) DeleteMetricsBefore(ctx context.Context, resolution domain.ContainerMetricResolution, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("resolution = ? AND timestamp < ?", resolution, before).
+		Delete(&domain.ContainerMetric{})
+	return result.RowsAffected, result.Error
+}
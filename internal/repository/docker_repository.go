@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/unitechio/einfra-be/internal/domain"
 	"gorm.io/gorm"
@@ -69,6 +70,23 @@ func (r *dockerHostRepository) List(ctx context.Context, filter domain.DockerHos
 	return hosts, total, nil
 }
 
+// UpdateHealth records the outcome of a daemon health check without touching the host's settings
+func (r *dockerHostRepository) UpdateHealth(ctx context.Context, id, status, version, lastError string, checkedAt time.Time) error {
+	updates := map[string]interface{}{
+		"status":          status,
+		"last_error":      lastError,
+		"last_checked_at": checkedAt,
+	}
+	// Keep the last known version while the daemon is unreachable
+	if version != "" {
+		updates["version"] = version
+	}
+	return r.db.WithContext(ctx).
+		Model(&domain.DockerHost{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		UpdateColumns(updates).Error
+}
+
 // Update updates a Docker host
 func (r *dockerHostRepository) Update(ctx context.Context, host *domain.DockerHost) error {
 	result := r.db.WithContext(ctx).
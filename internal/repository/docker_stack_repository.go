@@ -2,8 +2,12 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/security"
 	"gorm.io/gorm"
 )
 
@@ -16,25 +20,51 @@ type DockerStackRepository interface {
 	Update(ctx context.Context, stack *domain.DockerStack) error
 	Delete(ctx context.Context, id string) error
 	UpdateStatus(ctx context.Context, id string, status domain.StackStatus) error
+	MarkUpdating(ctx context.Context, id string) (bool, error)
 
 	// Service operations
 	CreateService(ctx context.Context, service *domain.StackService) error
 	GetServices(ctx context.Context, stackID string) ([]*domain.StackService, error)
 	DeleteServices(ctx context.Context, stackID string) error
+
+	// Revision operations
+	CreateRevision(ctx context.Context, revision *domain.StackRevision) error
+	GetRevision(ctx context.Context, stackID string, revision int) (*domain.StackRevision, error)
+	ListRevisions(ctx context.Context, stackID string) ([]*domain.StackRevision, error)
+	LatestRevision(ctx context.Context, stackID string) (int, error)
+	UpdateRevision(ctx context.Context, revision *domain.StackRevision) error
+	UpdateRevisionStatus(ctx context.Context, stackID string, revision int, status domain.StackRevisionStatus, errMsg string) error
+
+	// Git source operations; deploy keys and webhook secrets are encrypted at rest
+	SaveGitSource(ctx context.Context, source *domain.StackGitSource) error
+	GetGitSource(ctx context.Context, stackID string) (*domain.StackGitSource, error)
+	ListGitSources(ctx context.Context) ([]*domain.StackGitSource, error)
+	DeleteGitSource(ctx context.Context, stackID string) error
+	UpdateGitSourceState(ctx context.Context, stackID, lastCommit, lastError string, checkedAt time.Time) error
 }
 
 type dockerStackRepository struct {
-	db *gorm.DB
+	db         *gorm.DB
+	encryption security.EncryptionService
 }
 
 // NewDockerStackRepository creates a new Docker stack repository
-func NewDockerStackRepository(db *gorm.DB) DockerStackRepository {
-	return &dockerStackRepository{db: db}
+func NewDockerStackRepository(db *gorm.DB, encryption security.EncryptionService) DockerStackRepository {
+	return &dockerStackRepository{db: db, encryption: encryption}
 }
 
 // Create creates a new Docker stack
 func (r *dockerStackRepository) Create(ctx context.Context, stack *domain.DockerStack) error {
-	return r.db.WithContext(ctx).Create(stack).Error
+	stored, err := r.encryptStack(stack)
+	if err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Create(stored).Error; err != nil {
+		return err
+	}
+	stack.ID, stack.CreatedAt, stack.UpdatedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt
+	stack.SecretNames = stored.SecretNames
+	return nil
 }
 
 // GetByID retrieves a stack by ID
@@ -44,6 +74,9 @@ func (r *dockerStackRepository) GetByID(ctx context.Context, id string) (*domain
 	if err != nil {
 		return nil, err
 	}
+	if err := r.decryptStack(&stack); err != nil {
+		return nil, err
+	}
 	return &stack, nil
 }
 
@@ -54,19 +87,37 @@ func (r *dockerStackRepository) GetByName(ctx context.Context, name string) (*do
 	if err != nil {
 		return nil, err
 	}
+	if err := r.decryptStack(&stack); err != nil {
+		return nil, err
+	}
 	return &stack, nil
 }
 
 // List retrieves all stacks
 func (r *dockerStackRepository) List(ctx context.Context) ([]*domain.DockerStack, error) {
 	var stacks []*domain.DockerStack
-	err := r.db.WithContext(ctx).Order("created_at DESC").Find(&stacks).Error
-	return stacks, err
+	if err := r.db.WithContext(ctx).Order("created_at DESC").Find(&stacks).Error; err != nil {
+		return nil, err
+	}
+	for _, stack := range stacks {
+		if err := r.decryptStack(stack); err != nil {
+			return nil, err
+		}
+	}
+	return stacks, nil
 }
 
 // Update updates a stack
 func (r *dockerStackRepository) Update(ctx context.Context, stack *domain.DockerStack) error {
-	return r.db.WithContext(ctx).Save(stack).Error
+	stored, err := r.encryptStack(stack)
+	if err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Save(stored).Error; err != nil {
+		return err
+	}
+	stack.UpdatedAt = stored.UpdatedAt
+	return nil
 }
 
 // Delete deletes a stack
@@ -81,6 +132,19 @@ func (r *dockerStackRepository) UpdateStatus(ctx context.Context, id string, sta
 		Update("status", status).Error
 }
 
+// MarkUpdating sets a stack updating unless a deploy or update of it is already running, and
+// reports whether it did. The check and the write are one statement, so concurrent rollouts
+// of a stack cannot both proceed.
+func (r *dockerStackRepository) MarkUpdating(ctx context.Context, id string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.DockerStack{}).
+		Where("id = ? AND status NOT IN ?", id, []domain.StackStatus{domain.StackStatusDeploying, domain.StackStatusUpdating}).
+		Update("status", domain.StackStatusUpdating)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
 // CreateService creates a new stack service
 func (r *dockerStackRepository) CreateService(ctx context.Context, service *domain.StackService) error {
 	return r.db.WithContext(ctx).Create(service).Error
@@ -97,3 +161,161 @@ func (r *dockerStackRepository) GetServices(ctx context.Context, stackID string)
 func (r *dockerStackRepository) DeleteServices(ctx context.Context, stackID string) error {
 	return r.db.WithContext(ctx).Delete(&domain.StackService{}, "stack_id = ?", stackID).Error
 }
+
+// CreateRevision creates a new stack revision
+func (r *dockerStackRepository) CreateRevision(ctx context.Context, revision *domain.StackRevision) error {
+	return r.db.WithContext(ctx).Create(revision).Error
+}
+
+// GetRevision retrieves a revision of a stack by its number
+func (r *dockerStackRepository) GetRevision(ctx context.Context, stackID string, revision int) (*domain.StackRevision, error) {
+	var rev domain.StackRevision
+	err := r.db.WithContext(ctx).Where("stack_id = ? AND revision = ?", stackID, revision).First(&rev).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// ListRevisions retrieves the revisions of a stack, newest first
+func (r *dockerStackRepository) ListRevisions(ctx context.Context, stackID string) ([]*domain.StackRevision, error) {
+	var revisions []*domain.StackRevision
+	err := r.db.WithContext(ctx).Where("stack_id = ?", stackID).Order("revision DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+// LatestRevision returns the highest revision number of a stack, 0 when it has none
+func (r *dockerStackRepository) LatestRevision(ctx context.Context, stackID string) (int, error) {
+	var latest int
+	err := r.db.WithContext(ctx).Model(&domain.StackRevision{}).
+		Where("stack_id = ?", stackID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&latest).Error
+	return latest, err
+}
+
+// UpdateRevision updates a revision
+func (r *dockerStackRepository) UpdateRevision(ctx context.Context, revision *domain.StackRevision) error {
+	return r.db.WithContext(ctx).Save(revision).Error
+}
+
+// UpdateRevisionStatus updates the rollout status of a revision
+func (r *dockerStackRepository) UpdateRevisionStatus(ctx context.Context, stackID string, revision int, status domain.StackRevisionStatus, errMsg string) error {
+	return r.db.WithContext(ctx).Model(&domain.StackRevision{}).
+		Where("stack_id = ? AND revision = ?", stackID, revision).
+		Updates(map[string]interface{}{"status": status, "error": errMsg}).Error
+}
+
+// SaveGitSource creates or replaces the git source of a stack
+func (r *dockerStackRepository) SaveGitSource(ctx context.Context, source *domain.StackGitSource) error {
+	stored := *source
+	var err error
+	if stored.DeployKey != "" {
+		if stored.DeployKey, err = r.encryption.Encrypt(stored.DeployKey); err != nil {
+			return fmt.Errorf("failed to encrypt deploy key: %w", err)
+		}
+	}
+	if stored.WebhookSecret != "" {
+		if stored.WebhookSecret, err = r.encryption.Encrypt(stored.WebhookSecret); err != nil {
+			return fmt.Errorf("failed to encrypt webhook secret: %w", err)
+		}
+	}
+	if err := r.db.WithContext(ctx).Save(&stored).Error; err != nil {
+		return err
+	}
+	source.CreatedAt, source.UpdatedAt = stored.CreatedAt, stored.UpdatedAt
+	source.HasDeployKey = source.DeployKey != ""
+	return nil
+}
+
+// GetGitSource retrieves the git source of a stack
+func (r *dockerStackRepository) GetGitSource(ctx context.Context, stackID string) (*domain.StackGitSource, error) {
+	var source domain.StackGitSource
+	if err := r.db.WithContext(ctx).Where("stack_id = ?", stackID).First(&source).Error; err != nil {
+		return nil, err
+	}
+	if err := r.decryptGitSource(&source); err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+// ListGitSources retrieves the git sources of all stacks
+func (r *dockerStackRepository) ListGitSources(ctx context.Context) ([]*domain.StackGitSource, error) {
+	var sources []*domain.StackGitSource
+	if err := r.db.WithContext(ctx).Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		if err := r.decryptGitSource(source); err != nil {
+			return nil, err
+		}
+	}
+	return sources, nil
+}
+
+// DeleteGitSource detaches a stack from its repository
+func (r *dockerStackRepository) DeleteGitSource(ctx context.Context, stackID string) error {
+	return r.db.WithContext(ctx).Delete(&domain.StackGitSource{}, "stack_id = ?", stackID).Error
+}
+
+// UpdateGitSourceState records the outcome of checking the repository of a stack
+func (r *dockerStackRepository) UpdateGitSourceState(ctx context.Context, stackID, lastCommit, lastError string, checkedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.StackGitSource{}).
+		Where("stack_id = ?", stackID).
+		UpdateColumns(map[string]interface{}{
+			"last_commit":     lastCommit,
+			"last_error":      lastError,
+			"last_checked_at": checkedAt,
+		}).Error
+}
+
+func (r *dockerStackRepository) decryptGitSource(source *domain.StackGitSource) error {
+	var err error
+	if source.DeployKey != "" {
+		if source.DeployKey, err = r.encryption.Decrypt(source.DeployKey); err != nil {
+			return fmt.Errorf("failed to decrypt deploy key: %w", err)
+		}
+	}
+	if source.WebhookSecret != "" {
+		if source.WebhookSecret, err = r.encryption.Decrypt(source.WebhookSecret); err != nil {
+			return fmt.Errorf("failed to decrypt webhook secret: %w", err)
+		}
+	}
+	source.HasDeployKey = source.DeployKey != ""
+	return nil
+}
+
+// encryptStack returns a copy of a stack with its secret variables encrypted for storage
+func (r *dockerStackRepository) encryptStack(stack *domain.DockerStack) (*domain.DockerStack, error) {
+	stored := *stack
+	stored.SecretEnvVars = nil
+	stored.SecretNames = nil
+	if len(stack.SecretEnvVars) > 0 {
+		stored.SecretEnvVars = make(map[string]string, len(stack.SecretEnvVars))
+	}
+	for name, value := range stack.SecretEnvVars {
+		encrypted, err := r.encryption.Encrypt(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt stack secret %s: %w", name, err)
+		}
+		stored.SecretEnvVars[name] = encrypted
+		stored.SecretNames = append(stored.SecretNames, name)
+	}
+	sort.Strings(stored.SecretNames)
+	return &stored, nil
+}
+
+func (r *dockerStackRepository) decryptStack(stack *domain.DockerStack) error {
+	stack.SecretNames = nil
+	for name, value := range stack.SecretEnvVars {
+		decrypted, err := r.encryption.Decrypt(value)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt stack secret %s: %w", name, err)
+		}
+		stack.SecretEnvVars[name] = decrypted
+		stack.SecretNames = append(stack.SecretNames, name)
+	}
+	sort.Strings(stack.SecretNames)
+	return nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"gorm.io/gorm"
+)
+
+type harborPromotionRepository struct {
+	db *gorm.DB
+}
+
+// NewHarborPromotionRepository creates a new Harbor promotion repository instance
+func NewHarborPromotionRepository(db *gorm.DB) domain.HarborPromotionRepository {
+	return &harborPromotionRepository{db: db}
+}
+
+// CreateRule creates a new promotion rule
+func (r *harborPromotionRepository) CreateRule(ctx context.Context, rule *domain.HarborPromotionRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+// GetRule retrieves a promotion rule by ID
+func (r *harborPromotionRepository) GetRule(ctx context.Context, id string) (*domain.HarborPromotionRule, error) {
+	var rule domain.HarborPromotionRule
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&rule).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("promotion rule not found")
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ListRules retrieves the promotion rules of a registry, or of all registries when registryID is empty
+func (r *harborPromotionRepository) ListRules(ctx context.Context, registryID string) ([]*domain.HarborPromotionRule, error) {
+	var rules []*domain.HarborPromotionRule
+
+	query := r.db.WithContext(ctx).Model(&domain.HarborPromotionRule{})
+	if registryID != "" {
+		query = query.Where("registry_id = ?", registryID)
+	}
+
+	if err := query.Order("name ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// UpdateRule updates a promotion rule
+func (r *harborPromotionRepository) UpdateRule(ctx context.Context, rule *domain.HarborPromotionRule) error {
+	return r.db.WithContext(ctx).Save(rule).Error
+}
+
+// DeleteRule removes a promotion rule; its promotion history is kept
+func (r *harborPromotionRepository) DeleteRule(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&domain.HarborPromotionRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("promotion rule not found")
+	}
+	return nil
+}
+
+// CreatePromotion records a new promotion
+func (r *harborPromotionRepository) CreatePromotion(ctx context.Context, promotion *domain.HarborPromotion) error {
+	return r.db.WithContext(ctx).Create(promotion).Error
+}
+
+// GetPromotion retrieves a promotion by ID
+func (r *harborPromotionRepository) GetPromotion(ctx context.Context, id string) (*domain.HarborPromotion, error) {
+	var promotion domain.HarborPromotion
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&promotion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("promotion not found")
+		}
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// UpdatePromotion updates a promotion
+func (r *harborPromotionRepository) UpdatePromotion(ctx context.Context, promotion *domain.HarborPromotion) error {
+	return r.db.WithContext(ctx).Save(promotion).Error
+}
+
+// ListPromotions retrieves promotions with filtering, newest first
+func (r *harborPromotionRepository) ListPromotions(ctx context.Context, filter domain.HarborPromotionFilter) ([]*domain.HarborPromotion, int64, error) {
+	var promotions []*domain.HarborPromotion
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.HarborPromotion{})
+
+	// Apply filters
+	if filter.RuleID != "" {
+		query = query.Where("rule_id = ?", filter.RuleID)
+	}
+	if filter.Digest != "" {
+		query = query.Where("digest = ?", filter.Digest)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	// Count total
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Apply pagination
+	if filter.Page > 0 && filter.PageSize > 0 {
+		offset := (filter.Page - 1) * filter.PageSize
+		query = query.Offset(offset).Limit(filter.PageSize)
+	}
+
+	if err := query.Order("created_at DESC").Find(&promotions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return promotions, total, nil
+}
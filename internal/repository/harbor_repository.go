@@ -3,23 +3,36 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/security"
 	"gorm.io/gorm"
 )
 
 type harborRegistryRepository struct {
-	db *gorm.DB
+	db         *gorm.DB
+	encryption security.EncryptionService
 }
 
-// NewHarborRegistryRepository creates a new Harbor registry repository instance
-func NewHarborRegistryRepository(db *gorm.DB) domain.HarborRegistryRepository {
-	return &harborRegistryRepository{db: db}
+// NewHarborRegistryRepository creates a new Harbor registry repository instance.
+// Passwords and webhook secrets are encrypted at rest with the encryption service.
+func NewHarborRegistryRepository(db *gorm.DB, encryption security.EncryptionService) domain.HarborRegistryRepository {
+	return &harborRegistryRepository{db: db, encryption: encryption}
 }
 
 // Create creates a new Harbor registry record
 func (r *harborRegistryRepository) Create(ctx context.Context, registry *domain.HarborRegistry) error {
-	return r.db.WithContext(ctx).Create(registry).Error
+	stored, err := r.encrypt(registry)
+	if err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Create(stored).Error; err != nil {
+		return err
+	}
+	registry.ID, registry.CreatedAt, registry.UpdatedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt
+	registry.HasWebhookSecret = registry.WebhookSecret != ""
+	return nil
 }
 
 // GetByID retrieves a registry by ID
@@ -32,6 +45,9 @@ func (r *harborRegistryRepository) GetByID(ctx context.Context, id string) (*dom
 		}
 		return nil, err
 	}
+	if err := r.decrypt(&registry); err != nil {
+		return nil, err
+	}
 	return &registry, nil
 }
 
@@ -65,15 +81,24 @@ func (r *harborRegistryRepository) List(ctx context.Context, filter domain.Harbo
 	if err := query.Order("created_at DESC").Find(&registries).Error; err != nil {
 		return nil, 0, err
 	}
+	for _, registry := range registries {
+		if err := r.decrypt(registry); err != nil {
+			return nil, 0, err
+		}
+	}
 
 	return registries, total, nil
 }
 
-// Update updates a registry
+// Update updates the non-zero fields of a registry, so an empty password or webhook secret keeps the stored one
 func (r *harborRegistryRepository) Update(ctx context.Context, registry *domain.HarborRegistry) error {
+	stored, err := r.encrypt(registry)
+	if err != nil {
+		return err
+	}
 	result := r.db.WithContext(ctx).
 		Where("id = ? AND deleted_at IS NULL", registry.ID).
-		Updates(registry)
+		Updates(stored)
 
 	if result.Error != nil {
 		return result.Error
@@ -81,6 +106,9 @@ func (r *harborRegistryRepository) Update(ctx context.Context, registry *domain.
 	if result.RowsAffected == 0 {
 		return errors.New("harbor registry not found or already deleted")
 	}
+	if registry.WebhookSecret != "" {
+		registry.HasWebhookSecret = true
+	}
 	return nil
 }
 
@@ -113,5 +141,44 @@ func (r *harborRegistryRepository) GetDefault(ctx context.Context) (*domain.Harb
 		}
 		return nil, err
 	}
+	if err := r.decrypt(&registry); err != nil {
+		return nil, err
+	}
 	return &registry, nil
 }
+
+// encrypt returns a copy of the registry with its password and webhook secret encrypted
+func (r *harborRegistryRepository) encrypt(registry *domain.HarborRegistry) (*domain.HarborRegistry, error) {
+	stored := *registry
+	var err error
+	if stored.Password != "" {
+		if stored.Password, err = r.encryption.Encrypt(stored.Password); err != nil {
+			return nil, fmt.Errorf("failed to encrypt password: %w", err)
+		}
+	}
+	if stored.WebhookSecret != "" {
+		if stored.WebhookSecret, err = r.encryption.Encrypt(stored.WebhookSecret); err != nil {
+			return nil, fmt.Errorf("failed to encrypt webhook secret: %w", err)
+		}
+	}
+	return &stored, nil
+}
+
+func (r *harborRegistryRepository) decrypt(registry *domain.HarborRegistry) error {
+	if registry.Password != "" {
+		password, err := r.encryption.Decrypt(registry.Password)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt password: %w", err)
+		}
+		registry.Password = password
+	}
+	if registry.WebhookSecret != "" {
+		secret, err := r.encryption.Decrypt(registry.WebhookSecret)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt webhook secret: %w", err)
+		}
+		registry.WebhookSecret = secret
+	}
+	registry.HasWebhookSecret = registry.WebhookSecret != ""
+	return nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"gorm.io/gorm"
+)
+
+type harborRetentionRepository struct {
+	db *gorm.DB
+}
+
+// NewHarborRetentionRepository creates a new Harbor retention repository instance
+func NewHarborRetentionRepository(db *gorm.DB) domain.HarborRetentionRepository {
+	return &harborRetentionRepository{db: db}
+}
+
+// CreatePolicy creates a new retention policy
+func (r *harborRetentionRepository) CreatePolicy(ctx context.Context, policy *domain.HarborRetentionPolicy) error {
+	return r.db.WithContext(ctx).Create(policy).Error
+}
+
+// GetPolicy retrieves a retention policy by ID
+func (r *harborRetentionRepository) GetPolicy(ctx context.Context, id string) (*domain.HarborRetentionPolicy, error) {
+	var policy domain.HarborRetentionPolicy
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&policy).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("retention policy not found")
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// ListPolicies retrieves the retention policies of a registry, or of all registries when registryID is empty
+func (r *harborRetentionRepository) ListPolicies(ctx context.Context, registryID string) ([]*domain.HarborRetentionPolicy, error) {
+	var policies []*domain.HarborRetentionPolicy
+
+	query := r.db.WithContext(ctx).Model(&domain.HarborRetentionPolicy{})
+	if registryID != "" {
+		query = query.Where("registry_id = ?", registryID)
+	}
+
+	if err := query.Order("project_name ASC, name ASC").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// ListDuePolicies retrieves the active policies whose next run is due
+func (r *harborRetentionRepository) ListDuePolicies(ctx context.Context, now time.Time) ([]*domain.HarborRetentionPolicy, error) {
+	var policies []*domain.HarborRetentionPolicy
+	if err := r.db.WithContext(ctx).
+		Where("is_active = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").
+		Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// UpdatePolicy updates a retention policy
+func (r *harborRetentionRepository) UpdatePolicy(ctx context.Context, policy *domain.HarborRetentionPolicy) error {
+	return r.db.WithContext(ctx).Save(policy).Error
+}
+
+// RecordRun updates the run times of a retention policy
+func (r *harborRetentionRepository) RecordRun(ctx context.Context, id string, lastRunAt, nextRunAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.HarborRetentionPolicy{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"last_run_at": lastRunAt, "next_run_at": nextRunAt}).Error
+}
+
+// DeletePolicy removes a retention policy and its runs
+func (r *harborRetentionRepository) DeletePolicy(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("policy_id = ?", id).Delete(&domain.HarborRetentionRun{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&domain.HarborRetentionPolicy{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("retention policy not found")
+		}
+		return nil
+	})
+}
+
+// CreateRun records a new retention run
+func (r *harborRetentionRepository) CreateRun(ctx context.Context, run *domain.HarborRetentionRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// GetRun retrieves a retention run with its items
+func (r *harborRetentionRepository) GetRun(ctx context.Context, id string) (*domain.HarborRetentionRun, error) {
+	var run domain.HarborRetentionRun
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&run).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("retention run not found")
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+// UpdateRun updates a retention run
+func (r *harborRetentionRepository) UpdateRun(ctx context.Context, run *domain.HarborRetentionRun) error {
+	return r.db.WithContext(ctx).Save(run).Error
+}
+
+// ListRuns retrieves the latest runs of a policy; items are left out to keep the list small
+func (r *harborRetentionRepository) ListRuns(ctx context.Context, policyID string, limit int) ([]*domain.HarborRetentionRun, error) {
+	var runs []*domain.HarborRetentionRun
+
+	query := r.db.WithContext(ctx).Omit("items").Where("policy_id = ?", policyID).Order("started_at DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if err := query.Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
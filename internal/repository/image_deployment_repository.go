@@ -49,6 +49,34 @@ func (r *imageDeploymentRepository) List(ctx context.Context, clusterID, namespa
 	return deployments, nil
 }
 
+// ListByImageRepository retrieves the deployments of a repository across clusters, matching
+// "project/image" as well as "registry.example.com/project/image"
+func (r *imageDeploymentRepository) ListByImageRepository(ctx context.Context, repository string) ([]*domain.ImageDeployment, error) {
+	var deployments []*domain.ImageDeployment
+	if err := r.db.WithContext(ctx).
+		Where("image_repository = ? OR image_repository LIKE ?", repository, "%/"+repository).
+		Order("deployed_at DESC").
+		Find(&deployments).Error; err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
+// ListByImageDigests retrieves the deployments of any of the given image digests across clusters
+func (r *imageDeploymentRepository) ListByImageDigests(ctx context.Context, digests []string) ([]*domain.ImageDeployment, error) {
+	var deployments []*domain.ImageDeployment
+	if len(digests) == 0 {
+		return deployments, nil
+	}
+	if err := r.db.WithContext(ctx).
+		Where("image_digest IN ?", digests).
+		Order("deployed_at DESC").
+		Find(&deployments).Error; err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
 // GetActiveDeployment retrieves the currently active deployment for a container
 func (r *imageDeploymentRepository) GetActiveDeployment(ctx context.Context, clusterID, namespace, deploymentName, containerName string) (*domain.ImageDeployment, error) {
 	var deployment domain.ImageDeployment
@@ -71,3 +99,28 @@ func (r *imageDeploymentRepository) GetActiveDeployment(ctx context.Context, clu
 func (r *imageDeploymentRepository) UpdateStatus(ctx context.Context, id, status string) error {
 	return r.db.WithContext(ctx).Model(&domain.ImageDeployment{}).Where("id = ?", id).Update("status", status).Error
 }
+
+// UpdateDigest records the digest a deployment's tag resolved to
+func (r *imageDeploymentRepository) UpdateDigest(ctx context.Context, id, digest string) error {
+	return r.db.WithContext(ctx).Model(&domain.ImageDeployment{}).Where("id = ?", id).Update("image_digest", digest).Error
+}
+
+// CreateHistory records a change of a deployment
+func (r *imageDeploymentRepository) CreateHistory(ctx context.Context, history *domain.ImageDeploymentHistory) error {
+	return r.db.WithContext(ctx).Create(history).Error
+}
+
+// ListHistory retrieves the changes of the given deployments, newest first
+func (r *imageDeploymentRepository) ListHistory(ctx context.Context, deploymentIDs []string) ([]*domain.ImageDeploymentHistory, error) {
+	var history []*domain.ImageDeploymentHistory
+	if len(deploymentIDs) == 0 {
+		return history, nil
+	}
+	if err := r.db.WithContext(ctx).
+		Where("deployment_id IN ?", deploymentIDs).
+		Order("changed_at DESC").
+		Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
+}
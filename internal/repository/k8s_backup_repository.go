@@ -3,33 +3,59 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/security"
 	"gorm.io/gorm"
 )
 
 type k8sBackupRepository struct {
-	db *gorm.DB
+	db         *gorm.DB
+	encryption security.EncryptionService
 }
 
-// NewK8sBackupRepository creates a new K8s backup repository
-func NewK8sBackupRepository(db *gorm.DB) domain.K8sBackupRepository {
+// NewK8sBackupRepository creates a new K8s backup repository. Manifests of Secrets are stored encrypted.
+func NewK8sBackupRepository(db *gorm.DB, encryption security.EncryptionService) domain.K8sBackupRepository {
 	return &k8sBackupRepository{
-		db: db,
+		db:         db,
+		encryption: encryption,
 	}
 }
 
 // Create creates a new backup record
 func (r *k8sBackupRepository) Create(ctx context.Context, backup *domain.K8sBackup) error {
+	resources := make([]domain.K8sBackupResource, len(backup.Resources))
+	for i, resource := range backup.Resources {
+		if resource.Kind == domain.K8sResourceSecret {
+			manifest, err := r.encryption.Encrypt(resource.Manifest)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt secret %s/%s: %w", resource.Namespace, resource.Name, err)
+			}
+			resource.Manifest = manifest
+		}
+		resources[i] = resource
+	}
+
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(backup).Error; err != nil {
+		// Resources are saved from their encrypted copies
+		stored := *backup
+		stored.Resources = nil
+		if err := tx.Create(&stored).Error; err != nil {
 			return err
 		}
+		backup.ID, backup.CreatedAt = stored.ID, stored.CreatedAt
 
-		if len(backup.Resources) > 0 {
-			if err := tx.Create(&backup.Resources).Error; err != nil {
+		if len(resources) > 0 {
+			for i := range resources {
+				resources[i].BackupID = backup.ID
+			}
+			if err := tx.Create(&resources).Error; err != nil {
 				return err
 			}
+			for i := range resources {
+				backup.Resources[i].ID, backup.Resources[i].BackupID = resources[i].ID, backup.ID
+			}
 		}
 
 		return nil
@@ -45,6 +71,16 @@ func (r *k8sBackupRepository) GetByID(ctx context.Context, id string) (*domain.K
 		}
 		return nil, err
 	}
+	for i, resource := range backup.Resources {
+		if resource.Kind != domain.K8sResourceSecret {
+			continue
+		}
+		manifest, err := r.encryption.Decrypt(resource.Manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret %s/%s: %w", resource.Namespace, resource.Name, err)
+		}
+		backup.Resources[i].Manifest = manifest
+	}
 	return &backup, nil
 }
 
@@ -61,7 +97,24 @@ func (r *k8sBackupRepository) List(ctx context.Context, clusterID, namespace str
 	return backups, nil
 }
 
-// Delete deletes a backup
+// ListBySchedule retrieves all backups created by a schedule, newest first
+func (r *k8sBackupRepository) ListBySchedule(ctx context.Context, scheduleID string) ([]*domain.K8sBackup, error) {
+	var backups []*domain.K8sBackup
+	if err := r.db.WithContext(ctx).
+		Where("schedule_id = ?", scheduleID).
+		Order("created_at DESC").
+		Find(&backups).Error; err != nil {
+		return nil, err
+	}
+	return backups, nil
+}
+
+// Delete deletes a backup together with its resources
 func (r *k8sBackupRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Delete(&domain.K8sBackup{}, "id = ?", id).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&domain.K8sBackupResource{}, "backup_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.K8sBackup{}, "id = ?", id).Error
+	})
 }
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"gorm.io/gorm"
+)
+
+type k8sBackupScheduleRepository struct {
+	db *gorm.DB
+}
+
+// NewK8sBackupScheduleRepository creates a new K8s backup schedule repository
+func NewK8sBackupScheduleRepository(db *gorm.DB) domain.K8sBackupScheduleRepository {
+	return &k8sBackupScheduleRepository{
+		db: db,
+	}
+}
+
+// Create creates a new backup schedule
+func (r *k8sBackupScheduleRepository) Create(ctx context.Context, schedule *domain.K8sBackupSchedule) error {
+	return r.db.WithContext(ctx).Create(schedule).Error
+}
+
+// GetByID retrieves a backup schedule by ID
+func (r *k8sBackupScheduleRepository) GetByID(ctx context.Context, id string) (*domain.K8sBackupSchedule, error) {
+	var schedule domain.K8sBackupSchedule
+	if err := r.db.WithContext(ctx).First(&schedule, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// List retrieves all backup schedules for a cluster
+func (r *k8sBackupScheduleRepository) List(ctx context.Context, clusterID string) ([]*domain.K8sBackupSchedule, error) {
+	var schedules []*domain.K8sBackupSchedule
+	if err := r.db.WithContext(ctx).
+		Where("cluster_id = ?", clusterID).
+		Order("created_at DESC").
+		Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// ListDue retrieves active schedules that should run at or before now
+func (r *k8sBackupScheduleRepository) ListDue(ctx context.Context, now time.Time) ([]*domain.K8sBackupSchedule, error) {
+	var schedules []*domain.K8sBackupSchedule
+	if err := r.db.WithContext(ctx).
+		Where("is_active = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").
+		Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// Update updates a backup schedule
+func (r *k8sBackupScheduleRepository) Update(ctx context.Context, schedule *domain.K8sBackupSchedule) error {
+	return r.db.WithContext(ctx).Save(schedule).Error
+}
+
+// Delete deletes a backup schedule
+func (r *k8sBackupScheduleRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&domain.K8sBackupSchedule{}, "id = ?", id).Error
+}
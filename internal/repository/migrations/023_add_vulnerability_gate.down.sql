@@ -0,0 +1,11 @@
+DELETE FROM permissions WHERE name = 'harbor.vulnerability.exception.approve';
+
+DROP INDEX IF EXISTS idx_docker_stacks_environment_id;
+ALTER TABLE docker_stacks DROP COLUMN IF EXISTS environment_id;
+
+DROP INDEX IF EXISTS idx_cve_exceptions_status;
+DROP INDEX IF EXISTS idx_cve_exceptions_cve_id;
+DROP INDEX IF EXISTS idx_cve_exceptions_environment_id;
+
+DROP TABLE IF EXISTS cve_exceptions;
+DROP TABLE IF EXISTS vulnerability_policies;
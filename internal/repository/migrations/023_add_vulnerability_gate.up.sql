@@ -0,0 +1,48 @@
+-- Vulnerability policies, one per environment
+CREATE TABLE IF NOT EXISTS vulnerability_policies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    environment_id UUID NOT NULL UNIQUE REFERENCES environments(id) ON DELETE CASCADE,
+    enabled BOOLEAN DEFAULT true,
+    mode VARCHAR(20) NOT NULL DEFAULT 'enforce',
+    max_critical INT,
+    max_high INT,
+    max_medium INT,
+    fixable_only BOOLEAN DEFAULT false,
+    block_unscanned BOOLEAN DEFAULT true,
+    allow_unmanaged_registries BOOLEAN DEFAULT false,
+    scan_timeout_seconds INT DEFAULT 20,
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Time-boxed CVE exceptions
+CREATE TABLE IF NOT EXISTS cve_exceptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    environment_id UUID REFERENCES environments(id) ON DELETE CASCADE,
+    cve_id VARCHAR(100) NOT NULL,
+    repository VARCHAR(500),
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    requested_by VARCHAR(255),
+    reviewed_by VARCHAR(255),
+    reviewed_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_cve_exceptions_environment_id ON cve_exceptions(environment_id);
+CREATE INDEX IF NOT EXISTS idx_cve_exceptions_cve_id ON cve_exceptions(cve_id);
+CREATE INDEX IF NOT EXISTS idx_cve_exceptions_status ON cve_exceptions(status);
+
+-- Stacks belong to an environment so its policy can gate them
+ALTER TABLE docker_stacks ADD COLUMN IF NOT EXISTS environment_id UUID REFERENCES environments(id);
+CREATE INDEX IF NOT EXISTS idx_docker_stacks_environment_id ON docker_stacks(environment_id);
+
+INSERT INTO permissions (name, resource, sub_resource, action, scope, description, is_system) VALUES
+    ('harbor.vulnerability.exception.approve', 'harbor', 'vulnerability', 'exception.approve', 'global', 'Approve or reject CVE exceptions', true)
+ON CONFLICT (name) DO NOTHING;
+
+COMMENT ON TABLE vulnerability_policies IS 'Image vulnerability limits enforced on deployments per environment';
+COMMENT ON TABLE cve_exceptions IS 'CVEs exempted from vulnerability policies until they expire';
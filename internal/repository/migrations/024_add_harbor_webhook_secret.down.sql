@@ -0,0 +1 @@
+ALTER TABLE harbor_registries DROP COLUMN IF EXISTS webhook_secret;
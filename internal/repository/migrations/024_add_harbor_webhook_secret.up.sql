@@ -0,0 +1,2 @@
+-- Shared secret Harbor webhook policies send as their auth header
+ALTER TABLE harbor_registries ADD COLUMN IF NOT EXISTS webhook_secret VARCHAR(255);
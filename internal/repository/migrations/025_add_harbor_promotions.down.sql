@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS harbor_promotions;
+DROP TABLE IF EXISTS harbor_promotion_rules;
@@ -0,0 +1,44 @@
+-- Promotion rules between projects of a Harbor registry
+CREATE TABLE IF NOT EXISTS harbor_promotion_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    registry_id UUID NOT NULL REFERENCES harbor_registries(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    source_project VARCHAR(255) NOT NULL,
+    target_project VARCHAR(255) NOT NULL,
+    labels JSONB,
+    allow_unscanned BOOLEAN DEFAULT false,
+    max_severity VARCHAR(20) DEFAULT 'Medium',
+    approval_role_id UUID REFERENCES roles(id) ON DELETE SET NULL,
+    is_active BOOLEAN DEFAULT true,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_harbor_promotion_rules_registry_id ON harbor_promotion_rules(registry_id);
+
+-- Promotion history; kept when the rule is deleted
+CREATE TABLE IF NOT EXISTS harbor_promotions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rule_id UUID NOT NULL,
+    registry_id UUID NOT NULL,
+    source_repository VARCHAR(500) NOT NULL,
+    target_repository VARCHAR(500) NOT NULL,
+    digest VARCHAR(255) NOT NULL,
+    tags JSONB,
+    scan_status VARCHAR(50),
+    scan_severity VARCHAR(20),
+    status VARCHAR(30) NOT NULL,
+    requested_by VARCHAR(255),
+    reviewed_by VARCHAR(255),
+    reviewed_at TIMESTAMP,
+    promoted_at TIMESTAMP,
+    error_message TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_harbor_promotions_rule_id ON harbor_promotions(rule_id);
+CREATE INDEX IF NOT EXISTS idx_harbor_promotions_digest ON harbor_promotions(digest);
+CREATE INDEX IF NOT EXISTS idx_harbor_promotions_status ON harbor_promotions(status);
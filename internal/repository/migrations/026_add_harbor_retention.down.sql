@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS harbor_retention_runs;
+DROP TABLE IF EXISTS harbor_retention_policies;
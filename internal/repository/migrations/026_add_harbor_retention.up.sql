@@ -0,0 +1,44 @@
+-- Tag retention policies of Harbor projects
+CREATE TABLE IF NOT EXISTS harbor_retention_policies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    registry_id UUID NOT NULL REFERENCES harbor_registries(id) ON DELETE CASCADE,
+    project_name VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    repository_pattern VARCHAR(255) DEFAULT '*',
+    tag_pattern VARCHAR(255) DEFAULT '*',
+    keep_last INT DEFAULT 10,
+    keep_deployed BOOLEAN DEFAULT false,
+    untagged_days INT,
+    run_garbage_collection BOOLEAN DEFAULT false,
+    cron_expression VARCHAR(100) NOT NULL,
+    enforced BOOLEAN DEFAULT false,
+    is_active BOOLEAN DEFAULT true,
+    configured_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_run_at TIMESTAMP,
+    next_run_at TIMESTAMP,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_harbor_retention_policies_registry_id ON harbor_retention_policies(registry_id);
+CREATE INDEX IF NOT EXISTS idx_harbor_retention_policies_next_run_at ON harbor_retention_policies(next_run_at) WHERE is_active = true;
+
+-- Reports of retention runs, with the decision made about each artifact
+CREATE TABLE IF NOT EXISTS harbor_retention_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    policy_id UUID NOT NULL REFERENCES harbor_retention_policies(id) ON DELETE CASCADE,
+    dry_run BOOLEAN DEFAULT false,
+    status VARCHAR(20) NOT NULL,
+    triggered_by VARCHAR(255),
+    items JSONB,
+    retained_count INT DEFAULT 0,
+    delete_count INT DEFAULT 0,
+    failed_count INT DEFAULT 0,
+    gc_triggered BOOLEAN DEFAULT false,
+    error_message TEXT,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_harbor_retention_runs_policy_id ON harbor_retention_runs(policy_id, started_at DESC);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_image_deployments_image_digest;
+DROP INDEX IF EXISTS idx_image_deployments_active_workload;
+
+DELETE FROM image_deployments WHERE platform = 'docker';
+
+ALTER TABLE image_deployments DROP COLUMN IF EXISTS image_digest;
+ALTER TABLE image_deployments DROP COLUMN IF EXISTS workload_kind;
+ALTER TABLE image_deployments DROP COLUMN IF EXISTS platform;
+
+ALTER TABLE image_deployments ADD CONSTRAINT image_deployments_cluster_id_fkey FOREIGN KEY (cluster_id) REFERENCES k8s_clusters(id);
@@ -0,0 +1,9 @@
+-- Deployments are tracked on Docker hosts too, so cluster_id no longer always refers to a Kubernetes cluster
+ALTER TABLE image_deployments DROP CONSTRAINT IF EXISTS image_deployments_cluster_id_fkey;
+
+ALTER TABLE image_deployments ADD COLUMN IF NOT EXISTS platform VARCHAR(20) DEFAULT 'kubernetes';
+ALTER TABLE image_deployments ADD COLUMN IF NOT EXISTS workload_kind VARCHAR(50);
+ALTER TABLE image_deployments ADD COLUMN IF NOT EXISTS image_digest VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_image_deployments_active_workload ON image_deployments(cluster_id, namespace, deployment_name, container_name) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_image_deployments_image_digest ON image_deployments(image_digest);
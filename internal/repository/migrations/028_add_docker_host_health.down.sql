@@ -0,0 +1,3 @@
+ALTER TABLE docker_hosts DROP COLUMN IF EXISTS last_checked_at;
+ALTER TABLE docker_hosts DROP COLUMN IF EXISTS last_error;
+ALTER TABLE docker_hosts DROP COLUMN IF EXISTS status;
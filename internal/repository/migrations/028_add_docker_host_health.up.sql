@@ -0,0 +1,4 @@
+-- Health of Docker hosts as last checked by the client registry
+ALTER TABLE docker_hosts ADD COLUMN IF NOT EXISTS status VARCHAR(20) DEFAULT 'unknown';
+ALTER TABLE docker_hosts ADD COLUMN IF NOT EXISTS last_error TEXT;
+ALTER TABLE docker_hosts ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMP;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS stack_revisions;
+
+ALTER TABLE docker_stacks DROP COLUMN IF EXISTS revision;
@@ -0,0 +1,21 @@
+ALTER TABLE docker_stacks ADD COLUMN IF NOT EXISTS revision INT DEFAULT 0;
+
+-- Create stack_revisions table
+CREATE TABLE IF NOT EXISTS stack_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    stack_id UUID NOT NULL REFERENCES docker_stacks(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    compose_file TEXT NOT NULL,
+    env_vars JSONB,
+    status VARCHAR(50) NOT NULL,
+    changes JSONB,
+    error TEXT,
+    rollback_of INT,
+    created_by UUID,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stack_revisions_stack_revision ON stack_revisions(stack_id, revision);
+
+COMMENT ON TABLE stack_revisions IS 'Compose file history of Docker stacks, used for rollbacks';
+COMMENT ON COLUMN stack_revisions.changes IS 'Per-service diff against the replaced revision';
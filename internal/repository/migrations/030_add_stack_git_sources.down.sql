@@ -0,0 +1,4 @@
+ALTER TABLE stack_revisions DROP COLUMN IF EXISTS approved_by;
+ALTER TABLE stack_revisions DROP COLUMN IF EXISTS git_commit;
+
+DROP TABLE IF EXISTS stack_git_sources;
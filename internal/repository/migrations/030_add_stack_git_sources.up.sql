@@ -0,0 +1,22 @@
+-- Create stack_git_sources table
+CREATE TABLE IF NOT EXISTS stack_git_sources (
+    stack_id UUID PRIMARY KEY REFERENCES docker_stacks(id) ON DELETE CASCADE,
+    url VARCHAR(500) NOT NULL,
+    branch VARCHAR(255) NOT NULL,
+    path VARCHAR(500) NOT NULL,
+    deploy_key TEXT,
+    webhook_secret VARCHAR(500),
+    deploy_mode VARCHAR(20) NOT NULL DEFAULT 'auto',
+    last_commit VARCHAR(64),
+    last_checked_at TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE stack_revisions ADD COLUMN IF NOT EXISTS git_commit VARCHAR(64);
+ALTER TABLE stack_revisions ADD COLUMN IF NOT EXISTS approved_by UUID;
+
+COMMENT ON TABLE stack_git_sources IS 'Repositories git-backed Docker stacks deploy their compose file from';
+COMMENT ON COLUMN stack_git_sources.deploy_key IS 'Encrypted SSH private key';
+COMMENT ON COLUMN stack_git_sources.webhook_secret IS 'Encrypted secret authenticating push webhooks';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_docker_stacks_template_id;
+ALTER TABLE docker_stacks DROP COLUMN IF EXISTS template_version;
+ALTER TABLE docker_stacks DROP COLUMN IF EXISTS template_id;
+
+DROP TABLE IF EXISTS stack_templates;
@@ -0,0 +1,24 @@
+-- Create stack_templates table
+CREATE TABLE IF NOT EXISTS stack_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    category VARCHAR(100),
+    version INT NOT NULL DEFAULT 1,
+    compose_template TEXT NOT NULL,
+    parameters JSONB,
+    created_by UUID,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_stack_templates_category ON stack_templates(category);
+
+ALTER TABLE docker_stacks ADD COLUMN IF NOT EXISTS template_id VARCHAR(255);
+ALTER TABLE docker_stacks ADD COLUMN IF NOT EXISTS template_version INT;
+
+CREATE INDEX IF NOT EXISTS idx_docker_stacks_template_id ON docker_stacks(template_id);
+
+COMMENT ON TABLE stack_templates IS 'User-defined compose templates stacks are deployed from; built-in templates ship with the server';
+COMMENT ON COLUMN stack_templates.parameters IS 'Typed parameter schema rendered into the compose template';
+COMMENT ON COLUMN docker_stacks.template_id IS 'Template the stack was deployed from, a built-in name or a stack_templates id';
@@ -0,0 +1,27 @@
+-- Create container_metrics table
+CREATE TABLE IF NOT EXISTS container_metrics (
+    host_id UUID NOT NULL REFERENCES docker_hosts(id) ON DELETE CASCADE,
+    container_id VARCHAR(64) NOT NULL,
+    resolution VARCHAR(10) NOT NULL,
+    timestamp TIMESTAMP NOT NULL,
+    container_name VARCHAR(255),
+    project VARCHAR(255),
+    samples INT NOT NULL,
+    cpu_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+    cpu_percent_max DOUBLE PRECISION NOT NULL DEFAULT 0,
+    memory_usage BIGINT NOT NULL DEFAULT 0,
+    memory_max BIGINT NOT NULL DEFAULT 0,
+    memory_limit BIGINT NOT NULL DEFAULT 0,
+    network_rx BIGINT NOT NULL DEFAULT 0,
+    network_tx BIGINT NOT NULL DEFAULT 0,
+    block_read BIGINT NOT NULL DEFAULT 0,
+    block_write BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (host_id, container_id, resolution, timestamp)
+);
+
+CREATE INDEX IF NOT EXISTS idx_container_metrics_project ON container_metrics(host_id, project, resolution, timestamp);
+CREATE INDEX IF NOT EXISTS idx_container_metrics_resolution_timestamp ON container_metrics(resolution, timestamp);
+
+COMMENT ON TABLE container_metrics IS 'Container resource usage rolled up into 1m, 5m and 1h buckets';
+COMMENT ON COLUMN container_metrics.project IS 'Docker Compose project the container belongs to';
+COMMENT ON COLUMN container_metrics.network_rx IS 'Bytes received during the bucket';
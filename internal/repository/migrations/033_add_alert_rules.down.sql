@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS alert_maintenance_windows;
+DROP TABLE IF EXISTS alert_silences;
+DROP TABLE IF EXISTS alert_history;
+DROP TABLE IF EXISTS alert_rules;
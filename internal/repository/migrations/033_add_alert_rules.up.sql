@@ -0,0 +1,84 @@
+-- Create alert_rules table
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    scope VARCHAR(20) NOT NULL,
+    target VARCHAR(500),
+    metric VARCHAR(50) NOT NULL,
+    operator VARCHAR(10) NOT NULL,
+    threshold DOUBLE PRECISION NOT NULL DEFAULT 0,
+    duration VARCHAR(20),
+    severity VARCHAR(20) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON COLUMN alert_rules.target IS 'Subject ID or name, or a label selector for the label scope; empty for every subject';
+COMMENT ON COLUMN alert_rules.duration IS 'How long the threshold must be breached before the rule fires';
+
+-- Rules previously hard-coded for every container
+INSERT INTO alert_rules (name, description, scope, metric, operator, threshold, duration, severity) VALUES
+    ('Container CPU high', 'CPU usage of a container above 80% of a core', 'container', 'cpu', 'gt', 80, '1m', 'warning'),
+    ('Container memory high', 'Memory usage of a container above 90% of its limit', 'container', 'memory', 'gt', 90, '1m', 'critical');
+
+-- Create alert_history table
+CREATE TABLE IF NOT EXISTS alert_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rule_id UUID NOT NULL,
+    rule_name VARCHAR(255),
+    scope VARCHAR(20) NOT NULL,
+    subject VARCHAR(500) NOT NULL,
+    subject_name VARCHAR(255),
+    metric VARCHAR(50) NOT NULL,
+    value DOUBLE PRECISION NOT NULL DEFAULT 0,
+    threshold DOUBLE PRECISION NOT NULL DEFAULT 0,
+    severity VARCHAR(20),
+    status VARCHAR(20) NOT NULL,
+    silenced BOOLEAN NOT NULL DEFAULT FALSE,
+    started_at TIMESTAMP NOT NULL,
+    triggered_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_history_rule_id ON alert_history(rule_id);
+CREATE INDEX IF NOT EXISTS idx_alert_history_status ON alert_history(status);
+CREATE INDEX IF NOT EXISTS idx_alert_history_triggered_at ON alert_history(triggered_at);
+CREATE INDEX IF NOT EXISTS idx_alert_history_subject ON alert_history(scope, subject);
+
+COMMENT ON TABLE alert_history IS 'One firing of a rule for one subject; kept when the rule is deleted';
+
+-- Create alert_silences table
+CREATE TABLE IF NOT EXISTS alert_silences (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rule_id UUID REFERENCES alert_rules(id) ON DELETE CASCADE,
+    scope VARCHAR(20),
+    subject VARCHAR(500),
+    comment TEXT,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_silences_ends_at ON alert_silences(ends_at);
+
+COMMENT ON TABLE alert_silences IS 'Suppress notifications of matching alerts; the alerts are still recorded';
+
+-- Create alert_maintenance_windows table
+CREATE TABLE IF NOT EXISTS alert_maintenance_windows (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    scope VARCHAR(20),
+    subject VARCHAR(500),
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    weekly BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE alert_maintenance_windows IS 'Pause rule evaluation for the covered subjects';
+COMMENT ON COLUMN alert_maintenance_windows.weekly IS 'Repeat every seven days from the first occurrence';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS alert_routing;
+DROP TABLE IF EXISTS alert_receivers;
+ALTER TABLE alert_history DROP COLUMN IF EXISTS labels;
+ALTER TABLE alert_rules DROP COLUMN IF EXISTS labels;
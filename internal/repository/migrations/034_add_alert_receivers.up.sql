@@ -0,0 +1,28 @@
+-- Labels of alert rules, attached to their alerts for routing
+ALTER TABLE alert_rules ADD COLUMN IF NOT EXISTS labels JSONB;
+ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS labels JSONB;
+
+-- Create alert_receivers table
+CREATE TABLE IF NOT EXISTS alert_receivers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    type VARCHAR(20) NOT NULL,
+    url VARCHAR(1000),
+    secret TEXT,
+    emails JSONB,
+    send_resolved BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON COLUMN alert_receivers.secret IS 'Encrypted HMAC key of webhooks or routing key of PagerDuty';
+
+-- Create alert_routing table, holding the single routing tree
+CREATE TABLE IF NOT EXISTS alert_routing (
+    id INT PRIMARY KEY CHECK (id = 1),
+    route JSONB NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON COLUMN alert_routing.route IS 'Root route; receivers are referenced by ID';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_k8s_clusters_environment_id;
+ALTER TABLE k8s_clusters
+    DROP COLUMN IF EXISTS impersonate_users,
+    DROP COLUMN IF EXISTS environment_id;
@@ -0,0 +1,6 @@
+-- Environment whose roles apply to a cluster, and whether calls impersonate the platform user
+ALTER TABLE k8s_clusters
+    ADD COLUMN IF NOT EXISTS environment_id UUID REFERENCES environments(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS impersonate_users BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_k8s_clusters_environment_id ON k8s_clusters(environment_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_k8s_backups_schedule_id;
+ALTER TABLE k8s_backups
+    DROP COLUMN IF EXISTS created_by,
+    DROP COLUMN IF EXISTS schedule_id;
+DROP TABLE IF EXISTS k8s_backup_schedules;
@@ -0,0 +1,32 @@
+-- Create k8s_backup_schedules table
+CREATE TABLE IF NOT EXISTS k8s_backup_schedules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    cluster_id UUID NOT NULL REFERENCES k8s_clusters(id) ON DELETE CASCADE,
+    namespace VARCHAR(255),
+    name VARCHAR(255) NOT NULL,
+    cron_expression VARCHAR(100) NOT NULL,
+    keep_last INTEGER NOT NULL DEFAULT 7,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    last_run_at TIMESTAMP,
+    next_run_at TIMESTAMP,
+    last_status VARCHAR(50),
+    last_error TEXT,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_k8s_backup_schedules_cluster_id ON k8s_backup_schedules(cluster_id);
+CREATE INDEX IF NOT EXISTS idx_k8s_backup_schedules_due ON k8s_backup_schedules(next_run_at) WHERE is_active;
+
+COMMENT ON COLUMN k8s_backup_schedules.namespace IS 'Empty backs up every namespace';
+COMMENT ON COLUMN k8s_backup_schedules.keep_last IS 'Number of scheduled backups kept; 0 keeps all';
+
+-- Backups record the schedule that produced them and who requested them
+ALTER TABLE k8s_backups
+    ADD COLUMN IF NOT EXISTS schedule_id UUID REFERENCES k8s_backup_schedules(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS created_by VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_k8s_backups_schedule_id ON k8s_backups(schedule_id);
+
+COMMENT ON COLUMN k8s_backup_resources.manifest IS 'YAML manifest; encrypted for Secrets';
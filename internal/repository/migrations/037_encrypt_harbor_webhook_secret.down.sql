@@ -0,0 +1,4 @@
+-- Encrypted secrets are meaningless once the application stops decrypting them
+UPDATE harbor_registries SET webhook_secret = NULL WHERE webhook_secret IS NOT NULL;
+ALTER TABLE harbor_registries ALTER COLUMN webhook_secret TYPE VARCHAR(255);
+COMMENT ON COLUMN harbor_registries.webhook_secret IS NULL;
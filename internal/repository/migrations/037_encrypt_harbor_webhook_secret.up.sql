@@ -0,0 +1,6 @@
+-- Webhook secrets are now encrypted by the application, and ciphertext outgrows VARCHAR(255).
+-- Secrets stored before were plaintext and cannot be decrypted, so they are cleared and must be set again.
+ALTER TABLE harbor_registries ALTER COLUMN webhook_secret TYPE TEXT;
+UPDATE harbor_registries SET webhook_secret = NULL WHERE webhook_secret IS NOT NULL;
+
+COMMENT ON COLUMN harbor_registries.webhook_secret IS 'Encrypted auth header Harbor webhook policies send';
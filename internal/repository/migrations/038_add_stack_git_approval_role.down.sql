@@ -0,0 +1 @@
+ALTER TABLE stack_git_sources DROP COLUMN IF EXISTS approval_role_id;
@@ -0,0 +1,4 @@
+-- Role whose members may approve revisions of git-backed stacks
+ALTER TABLE stack_git_sources ADD COLUMN IF NOT EXISTS approval_role_id UUID REFERENCES roles(id) ON DELETE SET NULL;
+
+COMMENT ON COLUMN stack_git_sources.approval_role_id IS 'Role allowed to approve pending revisions; any user but the requester when NULL';
@@ -0,0 +1 @@
+ALTER TABLE docker_stacks DROP COLUMN IF EXISTS secret_env_vars;
@@ -0,0 +1,4 @@
+-- Values of secret template parameters, encrypted by the application; compose files reference them as ${NAME}
+ALTER TABLE docker_stacks ADD COLUMN IF NOT EXISTS secret_env_vars JSONB;
+
+COMMENT ON COLUMN docker_stacks.secret_env_vars IS 'Encrypted variables interpolated into the compose file, never returned by the API';
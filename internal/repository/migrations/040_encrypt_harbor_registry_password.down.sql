@@ -0,0 +1,4 @@
+-- Encrypted passwords are meaningless once the application stops decrypting them
+UPDATE harbor_registries SET password = NULL WHERE password IS NOT NULL;
+ALTER TABLE harbor_registries ALTER COLUMN password TYPE VARCHAR(500);
+COMMENT ON COLUMN harbor_registries.password IS NULL;
@@ -0,0 +1,6 @@
+-- Registry passwords are now encrypted by the application, and ciphertext outgrows VARCHAR(500).
+-- Passwords stored before were plaintext and cannot be decrypted, so they are cleared and must be set again.
+ALTER TABLE harbor_registries ALTER COLUMN password TYPE TEXT;
+UPDATE harbor_registries SET password = NULL WHERE password IS NOT NULL;
+
+COMMENT ON COLUMN harbor_registries.password IS 'Encrypted password of the registry user';
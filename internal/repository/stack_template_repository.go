@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"gorm.io/gorm"
+)
+
+// StackTemplateRepository handles database operations for user-defined stack templates
+type StackTemplateRepository interface {
+	Create(ctx context.Context, template *domain.StackTemplate) error
+	GetByID(ctx context.Context, id string) (*domain.StackTemplate, error)
+	GetByName(ctx context.Context, name string) (*domain.StackTemplate, error)
+	List(ctx context.Context, filter domain.StackTemplateFilter) ([]*domain.StackTemplate, error)
+	Update(ctx context.Context, template *domain.StackTemplate) error
+	Delete(ctx context.Context, id string) error
+}
+
+type stackTemplateRepository struct {
+	db *gorm.DB
+}
+
+// NewStackTemplateRepository creates a new stack template repository
+func NewStackTemplateRepository(db *gorm.DB) StackTemplateRepository {
+	return &stackTemplateRepository{db: db}
+}
+
+// Create creates a new template
+func (r *stackTemplateRepository) Create(ctx context.Context, template *domain.StackTemplate) error {
+	return r.db.WithContext(ctx).Create(template).Error
+}
+
+// GetByID retrieves a template by ID
+func (r *stackTemplateRepository) GetByID(ctx context.Context, id string) (*domain.StackTemplate, error) {
+	var template domain.StackTemplate
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetByName retrieves a template by name
+func (r *stackTemplateRepository) GetByName(ctx context.Context, name string) (*domain.StackTemplate, error) {
+	var template domain.StackTemplate
+	err := r.db.WithContext(ctx).Where("name = ?", name).First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// List retrieves the templates matching a filter, ordered by name
+func (r *stackTemplateRepository) List(ctx context.Context, filter domain.StackTemplateFilter) ([]*domain.StackTemplate, error) {
+	query := r.db.WithContext(ctx)
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.Search != "" {
+		search := "%" + filter.Search + "%"
+		query = query.Where("name ILIKE ? OR description ILIKE ?", search, search)
+	}
+
+	var templates []*domain.StackTemplate
+	err := query.Order("name ASC").Find(&templates).Error
+	return templates, err
+}
+
+// Update updates a template
+func (r *stackTemplateRepository) Update(ctx context.Context, template *domain.StackTemplate) error {
+	return r.db.WithContext(ctx).Save(template).Error
+}
+
+// Delete deletes a template
+func (r *stackTemplateRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&domain.StackTemplate{}, "id = ?", id).Error
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type vulnerabilityPolicyRepository struct {
+	db *gorm.DB
+}
+
+// NewVulnerabilityPolicyRepository creates a new vulnerability policy repository instance
+func NewVulnerabilityPolicyRepository(db *gorm.DB) domain.VulnerabilityPolicyRepository {
+	return &vulnerabilityPolicyRepository{db: db}
+}
+
+// GetPolicyByEnvironment retrieves the policy of an environment, or nil if it has none
+func (r *vulnerabilityPolicyRepository) GetPolicyByEnvironment(ctx context.Context, environmentID string) (*domain.VulnerabilityPolicy, error) {
+	var policy domain.VulnerabilityPolicy
+	err := r.db.WithContext(ctx).Where("environment_id = ?", environmentID).First(&policy).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// ListPolicies retrieves all vulnerability policies
+func (r *vulnerabilityPolicyRepository) ListPolicies(ctx context.Context) ([]*domain.VulnerabilityPolicy, error) {
+	var policies []*domain.VulnerabilityPolicy
+	if err := r.db.WithContext(ctx).Order("created_at ASC").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// SavePolicy creates the policy of an environment or replaces the existing one
+func (r *vulnerabilityPolicyRepository) SavePolicy(ctx context.Context, policy *domain.VulnerabilityPolicy) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "environment_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"enabled", "mode", "max_critical", "max_high", "max_medium", "fixable_only",
+			"block_unscanned", "allow_unmanaged_registries", "scan_timeout_seconds", "updated_by", "updated_at",
+		}),
+	}).Create(policy).Error
+}
+
+// DeletePolicy removes the policy of an environment
+func (r *vulnerabilityPolicyRepository) DeletePolicy(ctx context.Context, environmentID string) error {
+	result := r.db.WithContext(ctx).Where("environment_id = ?", environmentID).Delete(&domain.VulnerabilityPolicy{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("vulnerability policy not found")
+	}
+	return nil
+}
+
+// CreateException creates a new CVE exception
+func (r *vulnerabilityPolicyRepository) CreateException(ctx context.Context, exception *domain.CVEException) error {
+	return r.db.WithContext(ctx).Create(exception).Error
+}
+
+// GetException retrieves a CVE exception by ID
+func (r *vulnerabilityPolicyRepository) GetException(ctx context.Context, id string) (*domain.CVEException, error) {
+	var exception domain.CVEException
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&exception).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("CVE exception not found")
+		}
+		return nil, err
+	}
+	return &exception, nil
+}
+
+// UpdateException updates a CVE exception
+func (r *vulnerabilityPolicyRepository) UpdateException(ctx context.Context, exception *domain.CVEException) error {
+	return r.db.WithContext(ctx).Save(exception).Error
+}
+
+// ListExceptions retrieves CVE exceptions with filtering. An environment filter also matches
+// exceptions that apply to every environment.
+func (r *vulnerabilityPolicyRepository) ListExceptions(ctx context.Context, filter domain.CVEExceptionFilter) ([]*domain.CVEException, error) {
+	var exceptions []*domain.CVEException
+
+	query := r.db.WithContext(ctx).Model(&domain.CVEException{})
+	if filter.EnvironmentID != "" {
+		query = query.Where("environment_id = ? OR environment_id IS NULL", filter.EnvironmentID)
+	}
+	if filter.CVEID != "" {
+		query = query.Where("UPPER(cve_id) = UPPER(?)", filter.CVEID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ActiveOnly {
+		query = query.Where("status = ? AND expires_at > ?", domain.CVEExceptionStatusApproved, time.Now())
+	}
+
+	if err := query.Order("created_at DESC").Find(&exceptions).Error; err != nil {
+		return nil, err
+	}
+	return exceptions, nil
+}
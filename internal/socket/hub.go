@@ -40,6 +40,8 @@ const (
 	MessageTypePong MessageType = "pong"
 	// MessageTypeError represents an error message
 	MessageTypeError MessageType = "error"
+	// MessageTypeK8sEvent represents a Kubernetes resource change
+	MessageTypeK8sEvent MessageType = "k8s_event"
 )
 
 // Message represents a WebSocket message
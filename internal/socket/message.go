@@ -77,3 +77,12 @@ func NewErrorMessage(code, message string) Message {
 		Timestamp: time.Now(),
 	}
 }
+
+// NewK8sEventMessage creates a Message from a Kubernetes resource change
+func NewK8sEventMessage(event domain.K8sWatchEvent) Message {
+	return Message{
+		Type:      MessageTypeK8sEvent,
+		Data:      event,
+		Timestamp: time.Now(),
+	}
+}
@@ -0,0 +1,639 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/repository"
+	"gorm.io/gorm"
+)
+
+// AlertReceiverUsecase routes alert notifications to receivers
+type AlertReceiverUsecase interface {
+	ListReceivers(ctx context.Context) ([]*domain.AlertReceiver, error)
+	GetReceiver(ctx context.Context, id string) (*domain.AlertReceiver, error)
+	CreateReceiver(ctx context.Context, req domain.AlertReceiverRequest, userID string) (*domain.AlertReceiver, error)
+	UpdateReceiver(ctx context.Context, id string, req domain.AlertReceiverRequest) (*domain.AlertReceiver, error)
+	// DeleteReceiver deletes a receiver the routing tree does not use
+	DeleteReceiver(ctx context.Context, id string) error
+	// TestReceiver sends a firing test alert to a receiver right away
+	TestReceiver(ctx context.Context, id string) error
+
+	// GetRouting returns the routing tree; alerts only go to receivers once one is saved
+	GetRouting(ctx context.Context) (*domain.AlertRouting, error)
+	UpdateRouting(ctx context.Context, route domain.AlertRoute) (*domain.AlertRouting, error)
+
+	// Route adds a fired or resolved alert to the groups of the routes it matches
+	Route(ctx context.Context, alert *domain.AlertHistory, now time.Time)
+	// Flush notifies the receivers of the groups that are due
+	Flush(ctx context.Context, now time.Time)
+}
+
+const (
+	defaultAlertGroupWait      = 30 * time.Second
+	defaultAlertGroupInterval  = 5 * time.Minute
+	defaultAlertRepeatInterval = 4 * time.Hour
+
+	// pagerDutyEventsURL is where PagerDuty receivers send events unless they name another endpoint
+	pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	// alertWebhookSignatureHeader carries the HMAC-SHA256 of the body, as sha256=<hex>
+	alertWebhookSignatureHeader = "X-Einfra-Signature"
+	// alertReceiverTimeout bounds each request to a receiver
+	alertReceiverTimeout = 10 * time.Second
+)
+
+// alertRouteMatch is a route an alert matched, with the settings it inherited
+type alertRouteMatch struct {
+	path           string
+	receiver       string
+	groupBy        []string
+	groupWait      time.Duration
+	groupInterval  time.Duration
+	repeatInterval time.Duration
+}
+
+// alertGroup collects the alerts of a route sharing the values of its group_by labels
+type alertGroup struct {
+	key       string
+	route     alertRouteMatch
+	labels    map[string]string
+	alerts    map[string]*domain.AlertHistory // Keyed by alert ID
+	createdAt time.Time
+	flushedAt time.Time // Zero until the first notification
+	changed   bool      // Alerts were added or resolved since the last notification
+}
+
+type alertReceiverUsecase struct {
+	receiverRepo repository.AlertReceiverRepository
+	emailUsecase EmailUsecase
+	httpClient   *http.Client
+
+	mu     sync.Mutex
+	groups map[string]*alertGroup
+}
+
+// NewAlertReceiverUsecase creates a new alert receiver usecase
+func NewAlertReceiverUsecase(receiverRepo repository.AlertReceiverRepository, emailUsecase EmailUsecase) AlertReceiverUsecase {
+	return &alertReceiverUsecase{
+		receiverRepo: receiverRepo,
+		emailUsecase: emailUsecase,
+		httpClient:   &http.Client{Timeout: alertReceiverTimeout},
+		groups:       make(map[string]*alertGroup),
+	}
+}
+
+// ListReceivers lists every receiver
+func (u *alertReceiverUsecase) ListReceivers(ctx context.Context) ([]*domain.AlertReceiver, error) {
+	return u.receiverRepo.List(ctx)
+}
+
+// GetReceiver gets a receiver
+func (u *alertReceiverUsecase) GetReceiver(ctx context.Context, id string) (*domain.AlertReceiver, error) {
+	return u.receiverRepo.GetByID(ctx, id)
+}
+
+// CreateReceiver creates a receiver
+func (u *alertReceiverUsecase) CreateReceiver(ctx context.Context, req domain.AlertReceiverRequest, userID string) (*domain.AlertReceiver, error) {
+	if existing, err := u.receiverRepo.GetByName(ctx, req.Name); err == nil && existing != nil {
+		return nil, fmt.Errorf("receiver %s already exists", req.Name)
+	}
+
+	receiver := &domain.AlertReceiver{CreatedBy: optionalUser(userID)}
+	if err := u.applyReceiverRequest(receiver, req); err != nil {
+		return nil, err
+	}
+	if err := u.receiverRepo.Create(ctx, receiver); err != nil {
+		return nil, fmt.Errorf("failed to create receiver: %w", err)
+	}
+	return receiver, nil
+}
+
+// UpdateReceiver replaces a receiver; its secret is kept unless the request sets one
+func (u *alertReceiverUsecase) UpdateReceiver(ctx context.Context, id string, req domain.AlertReceiverRequest) (*domain.AlertReceiver, error) {
+	receiver, err := u.receiverRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("receiver not found: %w", err)
+	}
+	if req.Name != receiver.Name {
+		if existing, err := u.receiverRepo.GetByName(ctx, req.Name); err == nil && existing != nil {
+			return nil, fmt.Errorf("receiver %s already exists", req.Name)
+		}
+	}
+	if err := u.applyReceiverRequest(receiver, req); err != nil {
+		return nil, err
+	}
+	if err := u.receiverRepo.Update(ctx, receiver); err != nil {
+		return nil, fmt.Errorf("failed to update receiver: %w", err)
+	}
+	return receiver, nil
+}
+
+// applyReceiverRequest validates a request and copies it into a receiver
+func (u *alertReceiverUsecase) applyReceiverRequest(receiver *domain.AlertReceiver, req domain.AlertReceiverRequest) error {
+	receiver.Name = req.Name
+	receiver.Type = req.Type
+	receiver.URL = req.URL
+	receiver.Emails = req.Emails
+	receiver.SendResolved = req.SendResolved == nil || *req.SendResolved
+	if req.Secret != nil {
+		receiver.Secret = *req.Secret
+	}
+
+	switch receiver.Type {
+	case domain.AlertReceiverWebhook, domain.AlertReceiverSlack, domain.AlertReceiverPagerDuty:
+		if receiver.Type == domain.AlertReceiverPagerDuty {
+			if receiver.URL == "" {
+				receiver.URL = pagerDutyEventsURL
+			}
+			if receiver.Secret == "" {
+				return fmt.Errorf("pagerduty receivers need the routing key as secret")
+			}
+		}
+		parsed, err := url.Parse(receiver.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("%s receivers need an http or https url", receiver.Type)
+		}
+		receiver.Emails = nil
+	case domain.AlertReceiverEmail:
+		if len(receiver.Emails) == 0 {
+			return fmt.Errorf("email receivers need at least one recipient")
+		}
+		if u.emailUsecase == nil {
+			return fmt.Errorf("email is not configured")
+		}
+		receiver.URL, receiver.Secret = "", ""
+	default:
+		return fmt.Errorf("unknown receiver type %q", receiver.Type)
+	}
+	receiver.HasSecret = receiver.Secret != ""
+	return nil
+}
+
+// DeleteReceiver deletes a receiver the routing tree does not use
+func (u *alertReceiverUsecase) DeleteReceiver(ctx context.Context, id string) error {
+	if _, err := u.receiverRepo.GetByID(ctx, id); err != nil {
+		return fmt.Errorf("receiver not found: %w", err)
+	}
+	routing, err := u.receiverRepo.GetRouting(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get routing: %w", err)
+	}
+	if routing != nil && routeUsesReceiver(routing.Route, id) {
+		return fmt.Errorf("receiver is used by the routing tree")
+	}
+	return u.receiverRepo.Delete(ctx, id)
+}
+
+func routeUsesReceiver(route domain.AlertRoute, id string) bool {
+	if route.Receiver == id {
+		return true
+	}
+	for _, child := range route.Routes {
+		if routeUsesReceiver(child, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestReceiver sends a firing test alert to a receiver right away
+func (u *alertReceiverUsecase) TestReceiver(ctx context.Context, id string) error {
+	receiver, err := u.receiverRepo.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("receiver not found: %w", err)
+	}
+
+	now := time.Now().UTC()
+	alert := &domain.AlertHistory{
+		ID:          "test-" + receiver.ID,
+		RuleName:    "Test alert",
+		Scope:       domain.AlertScopeContainer,
+		Subject:     "test",
+		SubjectName: "test",
+		Metric:      domain.AlertMetricCPU,
+		Severity:    domain.AlertSeverityInfo,
+		Status:      domain.AlertStatusFiring,
+		StartedAt:   now,
+		TriggeredAt: now,
+	}
+	group := &alertGroup{
+		key:    "test",
+		labels: map[string]string{"alertname": alert.RuleName},
+		alerts: map[string]*domain.AlertHistory{alert.ID: alert},
+	}
+	return u.send(ctx, receiver, group, []*domain.AlertHistory{alert})
+}
+
+// GetRouting returns the routing tree
+func (u *alertReceiverUsecase) GetRouting(ctx context.Context) (*domain.AlertRouting, error) {
+	routing, err := u.receiverRepo.GetRouting(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if routing == nil {
+		return &domain.AlertRouting{}, nil
+	}
+	return routing, nil
+}
+
+// UpdateRouting validates and replaces the routing tree. Groups of the previous tree are still notified.
+func (u *alertReceiverUsecase) UpdateRouting(ctx context.Context, route domain.AlertRoute) (*domain.AlertRouting, error) {
+	if route.Receiver == "" {
+		return nil, fmt.Errorf("the root route needs a receiver")
+	}
+	if err := u.validateRoute(ctx, route, "route"); err != nil {
+		return nil, err
+	}
+
+	routing := &domain.AlertRouting{Route: route}
+	if err := u.receiverRepo.SaveRouting(ctx, routing); err != nil {
+		return nil, fmt.Errorf("failed to save routing: %w", err)
+	}
+	return routing, nil
+}
+
+func (u *alertReceiverUsecase) validateRoute(ctx context.Context, route domain.AlertRoute, path string) error {
+	if route.Receiver != "" {
+		if _, err := u.receiverRepo.GetByID(ctx, route.Receiver); err != nil {
+			return fmt.Errorf("%s: receiver %s not found", path, route.Receiver)
+		}
+	}
+	for name, value := range map[string]string{
+		"group_wait":      route.GroupWait,
+		"group_interval":  route.GroupInterval,
+		"repeat_interval": route.RepeatInterval,
+	} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return fmt.Errorf("%s: invalid %s %q", path, name, value)
+		}
+	}
+	for label, expr := range route.MatchRegex {
+		if _, err := compileAlertMatcher(expr); err != nil {
+			return fmt.Errorf("%s: invalid regex for %s: %w", path, label, err)
+		}
+	}
+	for i, child := range route.Routes {
+		if err := u.validateRoute(ctx, child, fmt.Sprintf("%s.routes[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileAlertMatcher anchors a regex so it matches whole label values
+func compileAlertMatcher(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// Route adds a fired or resolved alert to the groups of the routes it matches
+func (u *alertReceiverUsecase) Route(ctx context.Context, alert *domain.AlertHistory, now time.Time) {
+	routing, err := u.receiverRepo.GetRouting(ctx)
+	if err != nil {
+		log.Printf("Error getting alert routing: %v", err)
+		return
+	}
+	if routing == nil {
+		return
+	}
+
+	labels := alert.RoutingLabels()
+	root := alertRouteMatch{
+		groupWait:      defaultAlertGroupWait,
+		groupInterval:  defaultAlertGroupInterval,
+		repeatInterval: defaultAlertRepeatInterval,
+	}
+	matches := matchAlertRoute(routing.Route, root, "0", labels)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, match := range matches {
+		groupLabels := make(map[string]string, len(match.groupBy))
+		parts := []string{match.path, match.receiver}
+		for _, name := range match.groupBy {
+			groupLabels[name] = labels[name]
+			parts = append(parts, name+"="+labels[name])
+		}
+		key := strings.Join(parts, "|")
+
+		group := u.groups[key]
+		if group == nil {
+			// A resolution nobody was told about the firing of is not news
+			if alert.Status == domain.AlertStatusResolved {
+				continue
+			}
+			group = &alertGroup{key: key, route: match, labels: groupLabels, alerts: make(map[string]*domain.AlertHistory), createdAt: now}
+			u.groups[key] = group
+		}
+		snapshot := *alert
+		group.alerts[alert.ID] = &snapshot
+		group.changed = true
+	}
+}
+
+// matchAlertRoute returns the routes of a tree that take an alert
+func matchAlertRoute(route domain.AlertRoute, parent alertRouteMatch, path string, labels map[string]string) []alertRouteMatch {
+	for name, value := range route.Match {
+		if labels[name] != value {
+			return nil
+		}
+	}
+	for name, expr := range route.MatchRegex {
+		re, err := compileAlertMatcher(expr)
+		if err != nil || !re.MatchString(labels[name]) {
+			return nil
+		}
+	}
+
+	current := parent
+	current.path = path
+	if route.Receiver != "" {
+		current.receiver = route.Receiver
+	}
+	if len(route.GroupBy) > 0 {
+		current.groupBy = route.GroupBy
+	}
+	for _, setting := range []struct {
+		value  string
+		target *time.Duration
+	}{
+		{route.GroupWait, &current.groupWait},
+		{route.GroupInterval, &current.groupInterval},
+		{route.RepeatInterval, &current.repeatInterval},
+	} {
+		if d, err := time.ParseDuration(setting.value); err == nil {
+			*setting.target = d
+		}
+	}
+
+	var matches []alertRouteMatch
+	for i, child := range route.Routes {
+		childMatches := matchAlertRoute(child, current, fmt.Sprintf("%s.%d", path, i), labels)
+		if len(childMatches) == 0 {
+			continue
+		}
+		matches = append(matches, childMatches...)
+		if !child.Continue {
+			break
+		}
+	}
+	if len(matches) == 0 {
+		matches = []alertRouteMatch{current}
+	}
+	return matches
+}
+
+// Flush notifies the receivers of the groups that are due. A new group waits group_wait for more
+// alerts, a changed one group_interval since its last notification, and an unchanged one with firing
+// alerts repeat_interval. Failed notifications are retried on the next flush.
+func (u *alertReceiverUsecase) Flush(ctx context.Context, now time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for key, group := range u.groups {
+		firing := false
+		for _, alert := range group.alerts {
+			firing = firing || alert.Status == domain.AlertStatusFiring
+		}
+
+		var due bool
+		switch {
+		case group.flushedAt.IsZero():
+			due = !now.Before(group.createdAt.Add(group.route.groupWait))
+		case group.changed:
+			due = !now.Before(group.flushedAt.Add(group.route.groupInterval))
+		default:
+			due = firing && !now.Before(group.flushedAt.Add(group.route.repeatInterval))
+		}
+		if !due {
+			continue
+		}
+
+		receiver, err := u.receiverRepo.GetByID(ctx, group.route.receiver)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			delete(u.groups, key)
+			continue
+		}
+		if err != nil {
+			log.Printf("Error getting alert receiver %s: %v", group.route.receiver, err)
+			continue
+		}
+
+		alerts := make([]*domain.AlertHistory, 0, len(group.alerts))
+		for _, alert := range group.alerts {
+			if alert.Status == domain.AlertStatusFiring || receiver.SendResolved {
+				alerts = append(alerts, alert)
+			}
+		}
+		sort.Slice(alerts, func(i, j int) bool { return alerts[i].TriggeredAt.Before(alerts[j].TriggeredAt) })
+
+		if len(alerts) > 0 {
+			if err := u.send(ctx, receiver, group, alerts); err != nil {
+				log.Printf("Error notifying alert receiver %s: %v", receiver.Name, err)
+				continue
+			}
+		}
+
+		group.flushedAt = now
+		group.changed = false
+		for id, alert := range group.alerts {
+			if alert.Status == domain.AlertStatusResolved {
+				delete(group.alerts, id)
+			}
+		}
+		if len(group.alerts) == 0 {
+			delete(u.groups, key)
+		}
+	}
+}
+
+// send notifies a receiver of the alerts of a group
+func (u *alertReceiverUsecase) send(ctx context.Context, receiver *domain.AlertReceiver, group *alertGroup, alerts []*domain.AlertHistory) error {
+	status := domain.AlertStatusResolved
+	for _, alert := range alerts {
+		if alert.Status == domain.AlertStatusFiring {
+			status = domain.AlertStatusFiring
+		}
+	}
+
+	switch receiver.Type {
+	case domain.AlertReceiverWebhook:
+		body, err := json.Marshal(domain.AlertWebhookPayload{
+			Version:     "1",
+			GroupKey:    group.key,
+			Status:      status,
+			Receiver:    receiver.Name,
+			GroupLabels: group.labels,
+			Alerts:      alerts,
+		})
+		if err != nil {
+			return err
+		}
+		headers := map[string]string{}
+		if receiver.Secret != "" {
+			mac := hmac.New(sha256.New, []byte(receiver.Secret))
+			mac.Write(body)
+			headers[alertWebhookSignatureHeader] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		}
+		return u.postJSON(ctx, receiver.URL, body, headers)
+
+	case domain.AlertReceiverSlack:
+		color := "good"
+		if status == domain.AlertStatusFiring {
+			color = "warning"
+			for _, alert := range alerts {
+				if alert.Status == domain.AlertStatusFiring && alert.Severity == domain.AlertSeverityCritical {
+					color = "danger"
+				}
+			}
+		}
+		title := alertGroupTitle(group, status, alerts)
+		body, err := json.Marshal(map[string]interface{}{
+			"text": title,
+			"attachments": []map[string]interface{}{{
+				"color":    color,
+				"fallback": title,
+				"text":     strings.Join(alertSummaries(alerts), "\n"),
+			}},
+		})
+		if err != nil {
+			return err
+		}
+		return u.postJSON(ctx, receiver.URL, body, nil)
+
+	case domain.AlertReceiverPagerDuty:
+		// PagerDuty tracks alerts one by one; the dedup key ties a resolution to its trigger
+		for _, alert := range alerts {
+			event := map[string]interface{}{
+				"routing_key":  receiver.Secret,
+				"event_action": "trigger",
+				"dedup_key":    alert.ID,
+			}
+			if alert.Status == domain.AlertStatusResolved {
+				event["event_action"] = "resolve"
+			} else {
+				source := alert.SubjectName
+				if source == "" {
+					source = alert.Subject
+				}
+				event["payload"] = map[string]interface{}{
+					"summary":        alertSummary(alert),
+					"source":         source,
+					"severity":       pagerDutySeverity(alert.Severity),
+					"timestamp":      alert.TriggeredAt.Format(time.RFC3339),
+					"component":      string(alert.Scope),
+					"group":          alert.RuleName,
+					"custom_details": alert.RoutingLabels(),
+				}
+			}
+			body, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			if err := u.postJSON(ctx, receiver.URL, body, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case domain.AlertReceiverEmail:
+		if u.emailUsecase == nil {
+			return fmt.Errorf("email is not configured")
+		}
+		return u.emailUsecase.SendEmail(ctx, receiver.Emails, alertGroupTitle(group, status, alerts), strings.Join(alertSummaries(alerts), "\n"))
+	}
+	return fmt.Errorf("unknown receiver type %q", receiver.Type)
+}
+
+// postJSON posts a body and fails on responses other than 2xx
+func (u *alertReceiverUsecase) postJSON(ctx context.Context, target string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("receiver returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// alertGroupTitle titles a notification, e.g. "[FIRING:2] High CPU"
+func alertGroupTitle(group *alertGroup, status domain.AlertStatus, alerts []*domain.AlertHistory) string {
+	count := 0
+	for _, alert := range alerts {
+		if alert.Status == status {
+			count++
+		}
+	}
+
+	var names []string
+	for _, value := range group.labels {
+		if value != "" {
+			names = append(names, value)
+		}
+	}
+	sort.Strings(names)
+	name := strings.Join(names, " ")
+	if name == "" {
+		name = alerts[0].RuleName
+	}
+	return fmt.Sprintf("[%s:%d] %s", strings.ToUpper(string(status)), count, name)
+}
+
+func alertSummaries(alerts []*domain.AlertHistory) []string {
+	lines := make([]string, len(alerts))
+	for i, alert := range alerts {
+		lines[i] = "- " + alertSummary(alert)
+	}
+	return lines
+}
+
+func alertSummary(alert *domain.AlertHistory) string {
+	subject := alert.SubjectName
+	if subject == "" {
+		subject = alert.Subject
+	}
+	if alert.Status == domain.AlertStatusResolved {
+		return fmt.Sprintf("%s: %s %s resolved", alert.RuleName, alert.Scope, subject)
+	}
+	return fmt.Sprintf("%s: %s %s %s is %.2f, threshold %g", alert.RuleName, alert.Scope, subject, alert.Metric, alert.Value, alert.Threshold)
+}
+
+func pagerDutySeverity(severity domain.AlertSeverity) string {
+	switch severity {
+	case domain.AlertSeverityCritical:
+		return "critical"
+	case domain.AlertSeverityInfo:
+		return "info"
+	}
+	return "warning"
+}
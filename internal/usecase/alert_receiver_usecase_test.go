@@ -0,0 +1,419 @@
+package usecase_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"gorm.io/gorm"
+)
+
+// MockAlertReceiverRepository is a mock implementation of AlertReceiverRepository
+type MockAlertReceiverRepository struct {
+	mock.Mock
+}
+
+func (m *MockAlertReceiverRepository) Create(ctx context.Context, receiver *domain.AlertReceiver) error {
+	args := m.Called(ctx, receiver)
+	return args.Error(0)
+}
+
+func (m *MockAlertReceiverRepository) GetByID(ctx context.Context, id string) (*domain.AlertReceiver, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AlertReceiver), args.Error(1)
+}
+
+func (m *MockAlertReceiverRepository) GetByName(ctx context.Context, name string) (*domain.AlertReceiver, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AlertReceiver), args.Error(1)
+}
+
+func (m *MockAlertReceiverRepository) List(ctx context.Context) ([]*domain.AlertReceiver, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.AlertReceiver), args.Error(1)
+}
+
+func (m *MockAlertReceiverRepository) Update(ctx context.Context, receiver *domain.AlertReceiver) error {
+	args := m.Called(ctx, receiver)
+	return args.Error(0)
+}
+
+func (m *MockAlertReceiverRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockAlertReceiverRepository) GetRouting(ctx context.Context) (*domain.AlertRouting, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AlertRouting), args.Error(1)
+}
+
+func (m *MockAlertReceiverRepository) SaveRouting(ctx context.Context, routing *domain.AlertRouting) error {
+	args := m.Called(ctx, routing)
+	return args.Error(0)
+}
+
+// MockEmailUsecase is a mock implementation of EmailUsecase; only plain emails are mocked
+type MockEmailUsecase struct {
+	usecase.EmailUsecase
+	mock.Mock
+}
+
+func (m *MockEmailUsecase) SendEmail(ctx context.Context, to []string, subject, body string) error {
+	args := m.Called(ctx, to, subject, body)
+	return args.Error(0)
+}
+
+// expectReceiverCreated lets a receiver be created with an id; it is then found by that id
+func expectReceiverCreated(repo *MockAlertReceiverRepository, ctx context.Context, name, id string) {
+	repo.On("GetByName", ctx, name).Return(nil, gorm.ErrRecordNotFound).Once()
+	repo.On("Create", ctx, mock.MatchedBy(func(r *domain.AlertReceiver) bool { return r.Name == name })).
+		Run(func(args mock.Arguments) {
+			receiver := args.Get(1).(*domain.AlertReceiver)
+			receiver.ID = id
+			repo.On("GetByID", ctx, id).Return(receiver, nil)
+		}).
+		Return(nil).Once()
+}
+
+// expectRoutingSaved lets the routing tree be saved; it is then returned to the alerts being routed
+func expectRoutingSaved(repo *MockAlertReceiverRepository, ctx context.Context) {
+	repo.On("SaveRouting", ctx, mock.AnythingOfType("*domain.AlertRouting")).
+		Run(func(args mock.Arguments) {
+			repo.On("GetRouting", ctx).Return(args.Get(1).(*domain.AlertRouting), nil)
+		}).
+		Return(nil).Once()
+}
+
+// receiverStandIn is a local HTTP stand-in for a webhook, Slack or PagerDuty endpoint
+type receiverStandIn struct {
+	*httptest.Server
+	mu       sync.Mutex
+	bodies   [][]byte
+	headers  []http.Header
+	failWith int
+}
+
+func newReceiverStandIn(t *testing.T) *receiverStandIn {
+	s := &receiverStandIn{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.failWith != 0 {
+			http.Error(w, "nope", s.failWith)
+			return
+		}
+		s.bodies = append(s.bodies, body)
+		s.headers = append(s.headers, r.Header.Clone())
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *receiverStandIn) requests() ([][]byte, []http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.bodies...), append([]http.Header(nil), s.headers...)
+}
+
+func firingAlert(id, rule string, severity domain.AlertSeverity, at time.Time) *domain.AlertHistory {
+	return &domain.AlertHistory{
+		ID:          id,
+		RuleID:      "rule-" + rule,
+		RuleName:    rule,
+		Scope:       domain.AlertScopeContainer,
+		Subject:     "c-" + id,
+		SubjectName: "web-" + id,
+		Metric:      domain.AlertMetricCPU,
+		Value:       95,
+		Threshold:   80,
+		Severity:    severity,
+		Labels:      map[string]string{"team": "shop"},
+		Status:      domain.AlertStatusFiring,
+		StartedAt:   at,
+		TriggeredAt: at,
+	}
+}
+
+func resolvedAlert(alert *domain.AlertHistory, at time.Time) *domain.AlertHistory {
+	resolved := *alert
+	resolved.Status = domain.AlertStatusResolved
+	resolved.ResolvedAt = &at
+	return &resolved
+}
+
+func TestAlertReceiverWebhookGrouping(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAlertReceiverRepository)
+	uc := usecase.NewAlertReceiverUsecase(repo, nil)
+	hook := newReceiverStandIn(t)
+	expectReceiverCreated(repo, ctx, "ops-hook", "receiver-hook")
+	expectRoutingSaved(repo, ctx)
+
+	secret := "s3cret"
+	receiver, err := uc.CreateReceiver(ctx, domain.AlertReceiverRequest{
+		Name:   "ops-hook",
+		Type:   domain.AlertReceiverWebhook,
+		URL:    hook.URL,
+		Secret: &secret,
+	}, "")
+	require.NoError(t, err)
+	assert.True(t, receiver.HasSecret)
+
+	_, err = uc.UpdateRouting(ctx, domain.AlertRoute{
+		Receiver:       receiver.ID,
+		GroupBy:        []string{"alertname"},
+		GroupWait:      "30s",
+		GroupInterval:  "1m",
+		RepeatInterval: "1h",
+	})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	first := firingAlert("a1", "High CPU", domain.AlertSeverityWarning, start)
+	second := firingAlert("a2", "High CPU", domain.AlertSeverityWarning, start.Add(10*time.Second))
+	uc.Route(ctx, first, start)
+	uc.Route(ctx, second, start.Add(10*time.Second))
+
+	// Both alerts wait for group_wait and go out in one notification
+	uc.Flush(ctx, start.Add(20*time.Second))
+	bodies, headers := hook.requests()
+	assert.Empty(t, bodies)
+
+	uc.Flush(ctx, start.Add(30*time.Second))
+	bodies, headers = hook.requests()
+	require.Len(t, bodies, 1)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(bodies[0])
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), headers[0].Get("X-Einfra-Signature"))
+
+	var payload domain.AlertWebhookPayload
+	require.NoError(t, json.Unmarshal(bodies[0], &payload))
+	assert.Equal(t, domain.AlertStatusFiring, payload.Status)
+	assert.Equal(t, "ops-hook", payload.Receiver)
+	assert.Equal(t, map[string]string{"alertname": "High CPU"}, payload.GroupLabels)
+	require.Len(t, payload.Alerts, 2)
+	assert.Equal(t, "a1", payload.Alerts[0].ID)
+	assert.Equal(t, "a2", payload.Alerts[1].ID)
+
+	// Unchanged firing groups are only repeated after repeat_interval
+	uc.Flush(ctx, start.Add(30*time.Minute))
+	bodies, _ = hook.requests()
+	assert.Len(t, bodies, 1)
+
+	uc.Flush(ctx, start.Add(90*time.Minute))
+	bodies, _ = hook.requests()
+	require.Len(t, bodies, 2)
+
+	// Resolutions are sent after group_interval
+	resolvedAt := start.Add(90*time.Minute + 30*time.Second)
+	uc.Route(ctx, resolvedAlert(first, resolvedAt), resolvedAt)
+	uc.Flush(ctx, resolvedAt)
+	bodies, _ = hook.requests()
+	assert.Len(t, bodies, 2)
+
+	uc.Flush(ctx, start.Add(92*time.Minute))
+	bodies, _ = hook.requests()
+	require.Len(t, bodies, 3)
+	require.NoError(t, json.Unmarshal(bodies[2], &payload))
+	require.Len(t, payload.Alerts, 2)
+	assert.Equal(t, domain.AlertStatusResolved, payload.Alerts[0].Status)
+	assert.Equal(t, domain.AlertStatusFiring, payload.Alerts[1].Status)
+
+	// The resolved alert is dropped from the group once sent
+	uc.Flush(ctx, start.Add(3*time.Hour))
+	bodies, _ = hook.requests()
+	require.Len(t, bodies, 4)
+	require.NoError(t, json.Unmarshal(bodies[3], &payload))
+	require.Len(t, payload.Alerts, 1)
+	assert.Equal(t, "a2", payload.Alerts[0].ID)
+}
+
+func TestAlertReceiverRoutingBySeverity(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAlertReceiverRepository)
+	email := new(MockEmailUsecase)
+	uc := usecase.NewAlertReceiverUsecase(repo, email)
+	slack := newReceiverStandIn(t)
+	pagerDuty := newReceiverStandIn(t)
+	expectReceiverCreated(repo, ctx, "ops-slack", "receiver-slack")
+	expectReceiverCreated(repo, ctx, "on-call", "receiver-pagerduty")
+	expectReceiverCreated(repo, ctx, "shop-team", "receiver-email")
+	expectRoutingSaved(repo, ctx)
+	email.On("SendEmail", ctx, []string{"shop@example.com"}, "[FIRING:1] Memory", mock.Anything).Return(nil).Once()
+
+	slackReceiver, err := uc.CreateReceiver(ctx, domain.AlertReceiverRequest{
+		Name: "ops-slack",
+		Type: domain.AlertReceiverSlack,
+		URL:  slack.URL,
+	}, "")
+	require.NoError(t, err)
+
+	routingKey := "R0UT1NGKEY"
+	pagerDutyReceiver, err := uc.CreateReceiver(ctx, domain.AlertReceiverRequest{
+		Name:   "on-call",
+		Type:   domain.AlertReceiverPagerDuty,
+		URL:    pagerDuty.URL,
+		Secret: &routingKey,
+	}, "")
+	require.NoError(t, err)
+
+	emailReceiver, err := uc.CreateReceiver(ctx, domain.AlertReceiverRequest{
+		Name:   "shop-team",
+		Type:   domain.AlertReceiverEmail,
+		Emails: []string{"shop@example.com"},
+	}, "")
+	require.NoError(t, err)
+
+	_, err = uc.UpdateRouting(ctx, domain.AlertRoute{
+		Receiver:  slackReceiver.ID,
+		GroupWait: "0s",
+		Routes: []domain.AlertRoute{
+			{Receiver: pagerDutyReceiver.ID, Match: map[string]string{"severity": "critical"}, Continue: true},
+			{Receiver: emailReceiver.ID, MatchRegex: map[string]string{"team": "shop|cart"}},
+		},
+	})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	critical := firingAlert("a1", "Memory", domain.AlertSeverityCritical, start)
+	warning := firingAlert("a2", "High CPU", domain.AlertSeverityWarning, start)
+	warning.Labels = map[string]string{"team": "infra"}
+	uc.Route(ctx, critical, start)
+	uc.Route(ctx, warning, start)
+	uc.Flush(ctx, start)
+
+	// The critical alert pages and, continuing, mails the shop team; the other falls through to Slack
+	pages, _ := pagerDuty.requests()
+	require.Len(t, pages, 1)
+	var event map[string]interface{}
+	require.NoError(t, json.Unmarshal(pages[0], &event))
+	assert.Equal(t, routingKey, event["routing_key"])
+	assert.Equal(t, "trigger", event["event_action"])
+	assert.Equal(t, "a1", event["dedup_key"])
+	payload := event["payload"].(map[string]interface{})
+	assert.Equal(t, "critical", payload["severity"])
+	assert.Equal(t, "web-a1", payload["source"])
+
+	email.AssertExpectations(t)
+
+	messages, _ := slack.requests()
+	require.Len(t, messages, 1)
+	var message struct {
+		Text        string `json:"text"`
+		Attachments []struct {
+			Color string `json:"color"`
+			Text  string `json:"text"`
+		} `json:"attachments"`
+	}
+	require.NoError(t, json.Unmarshal(messages[0], &message))
+	assert.Equal(t, "[FIRING:1] High CPU", message.Text)
+	require.Len(t, message.Attachments, 1)
+	assert.Equal(t, "warning", message.Attachments[0].Color)
+	assert.Contains(t, message.Attachments[0].Text, "web-a2")
+
+	// The resolution shares the dedup key of the trigger
+	email.On("SendEmail", ctx, []string{"shop@example.com"}, "[RESOLVED:1] Memory", mock.Anything).Return(nil).Once()
+	uc.Route(ctx, resolvedAlert(critical, start.Add(10*time.Minute)), start.Add(10*time.Minute))
+	uc.Flush(ctx, start.Add(10*time.Minute))
+	pages, _ = pagerDuty.requests()
+	require.Len(t, pages, 2)
+	require.NoError(t, json.Unmarshal(pages[1], &event))
+	assert.Equal(t, "resolve", event["event_action"])
+	assert.Equal(t, "a1", event["dedup_key"])
+	email.AssertExpectations(t)
+
+	// Receivers the routing tree uses cannot be deleted
+	assert.Error(t, uc.DeleteReceiver(ctx, pagerDutyReceiver.ID))
+	repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestAlertReceiverTest(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAlertReceiverRepository)
+	uc := usecase.NewAlertReceiverUsecase(repo, nil)
+	hook := newReceiverStandIn(t)
+	expectReceiverCreated(repo, ctx, "ops-hook", "receiver-hook")
+
+	receiver, err := uc.CreateReceiver(ctx, domain.AlertReceiverRequest{
+		Name: "ops-hook",
+		Type: domain.AlertReceiverWebhook,
+		URL:  hook.URL,
+	}, "")
+	require.NoError(t, err)
+
+	require.NoError(t, uc.TestReceiver(ctx, receiver.ID))
+	bodies, headers := hook.requests()
+	require.Len(t, bodies, 1)
+	assert.Empty(t, headers[0].Get("X-Einfra-Signature"))
+	var payload domain.AlertWebhookPayload
+	require.NoError(t, json.Unmarshal(bodies[0], &payload))
+	require.Len(t, payload.Alerts, 1)
+	assert.Equal(t, "Test alert", payload.Alerts[0].RuleName)
+
+	hook.mu.Lock()
+	hook.failWith = http.StatusForbidden
+	hook.mu.Unlock()
+	err = uc.TestReceiver(ctx, receiver.ID)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
+
+func TestCreateAlertReceiverValidation(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAlertReceiverRepository)
+	repo.On("GetByName", ctx, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("GetByID", ctx, "missing").Return(nil, gorm.ErrRecordNotFound)
+	uc := usecase.NewAlertReceiverUsecase(repo, nil)
+
+	tests := []struct {
+		name string
+		req  domain.AlertReceiverRequest
+	}{
+		{"webhook without url", domain.AlertReceiverRequest{Name: "a", Type: domain.AlertReceiverWebhook}},
+		{"slack with other scheme", domain.AlertReceiverRequest{Name: "b", Type: domain.AlertReceiverSlack, URL: "ftp://hooks.example.com"}},
+		{"pagerduty without routing key", domain.AlertReceiverRequest{Name: "c", Type: domain.AlertReceiverPagerDuty}},
+		{"email without recipients", domain.AlertReceiverRequest{Name: "d", Type: domain.AlertReceiverEmail}},
+		{"email without email usecase", domain.AlertReceiverRequest{Name: "e", Type: domain.AlertReceiverEmail, Emails: []string{"ops@example.com"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := uc.CreateReceiver(ctx, tt.req, "")
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := uc.UpdateRouting(ctx, domain.AlertRoute{})
+	assert.Error(t, err)
+	_, err = uc.UpdateRouting(ctx, domain.AlertRoute{Receiver: "missing"})
+	assert.Error(t, err)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "SaveRouting", mock.Anything, mock.Anything)
+}
@@ -4,43 +4,136 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/repository"
 	"github.com/unitechio/einfra-be/pkg/docker"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // AlertUsecase handles resource alerting
 type AlertUsecase interface {
+	// StartMonitoring evaluates the rules every alertCheckInterval until ctx is done
 	StartMonitoring(ctx context.Context)
-	CheckResources(ctx context.Context)
+	// CheckResources evaluates every enabled rule against the current metrics of its subjects. A breach
+	// fires once it lasted the duration of the rule and resolves when the metric recovers, the subject
+	// disappears or the rule is disabled or deleted.
+	CheckResources(ctx context.Context, now time.Time)
+
+	ListRules(ctx context.Context) ([]*domain.AlertRule, error)
+	GetRule(ctx context.Context, id string) (*domain.AlertRule, error)
+	CreateRule(ctx context.Context, req domain.AlertRuleRequest, userID string) (*domain.AlertRule, error)
+	UpdateRule(ctx context.Context, id string, req domain.AlertRuleRequest) (*domain.AlertRule, error)
+	DeleteRule(ctx context.Context, id string) error
+
+	ListSilences(ctx context.Context, includeExpired bool) ([]*domain.AlertSilence, error)
+	CreateSilence(ctx context.Context, req domain.AlertSilenceRequest, userID string) (*domain.AlertSilence, error)
+	// ExpireSilence ends a silence now
+	ExpireSilence(ctx context.Context, id string) (*domain.AlertSilence, error)
+
+	ListMaintenanceWindows(ctx context.Context) ([]*domain.AlertMaintenanceWindow, error)
+	CreateMaintenanceWindow(ctx context.Context, req domain.AlertMaintenanceWindowRequest, userID string) (*domain.AlertMaintenanceWindow, error)
+	DeleteMaintenanceWindow(ctx context.Context, id string) error
+
+	ListHistory(ctx context.Context, filter domain.AlertHistoryFilter) ([]*domain.AlertHistory, int64, error)
+}
+
+// alertCheckInterval is how often rules are evaluated; durations shorter than it fire on the next check
+const alertCheckInterval = 30 * time.Second
+
+// alertScopeMetrics lists the metrics rules of each scope can use
+var alertScopeMetrics = map[domain.AlertScope][]domain.AlertMetric{
+	domain.AlertScopeContainer: {domain.AlertMetricCPU, domain.AlertMetricMemory, domain.AlertMetricMemoryBytes},
+	domain.AlertScopeLabel:     {domain.AlertMetricCPU, domain.AlertMetricMemory, domain.AlertMetricMemoryBytes},
+	domain.AlertScopeStack: {domain.AlertMetricCPU, domain.AlertMetricMemory, domain.AlertMetricMemoryBytes,
+		domain.AlertMetricContainers, domain.AlertMetricUnhealthy},
+	domain.AlertScopeHost: {domain.AlertMetricCPU, domain.AlertMetricMemory, domain.AlertMetricMemoryBytes,
+		domain.AlertMetricContainers, domain.AlertMetricUnhealthy},
+	domain.AlertScopeServer: {domain.AlertMetricCPU, domain.AlertMetricMemory, domain.AlertMetricDisk},
+	domain.AlertScopeCluster: {domain.AlertMetricCPU, domain.AlertMetricMemory, domain.AlertMetricCPURequests,
+		domain.AlertMetricMemoryRequests, domain.AlertMetricNotReadyNodes},
+}
+
+// alertSample is the current metrics of one subject
+type alertSample struct {
+	subject string
+	name    string
+	source  string            // Host, server or cluster the metrics were read from, as kind:id
+	labels  map[string]string // Container labels, matched by label selectors
+	values  map[domain.AlertMetric]float64
+}
+
+// alertState tracks a rule for one subject from its first breach until it resolves
+type alertState struct {
+	ruleID  string
+	subject string
+	source  string               // Empty when restored from history and not seen since
+	since   time.Time            // First breach
+	history *domain.AlertHistory // Set once the rule fired
+}
+
+// alertSourceFailures holds the sources whose metrics could not be read, as kind:id, or a kind alone
+// when none of its sources could be listed. States of failed sources are kept as they are.
+type alertSourceFailures map[string]bool
+
+func (f alertSourceFailures) failed(source string) bool {
+	if source == "" {
+		return len(f) > 0
+	}
+	kind, _, _ := strings.Cut(source, ":")
+	return f[source] || f[kind]
 }
 
 type alertUsecase struct {
-	dockerClient        *docker.Client
+	alertRepo           repository.AlertRepository
+	clients             DockerClientRegistry
 	notificationUsecase NotificationUsecase
-	rules               []domain.AlertRule
-	mu                  sync.RWMutex
+	serverUsecase       domain.ServerUsecase
+	k8sRepo             domain.K8sClusterRepository
+	capacityUsecase     domain.K8sCapacityUsecase
+	stackRepo           repository.DockerStackRepository
+	receiverUsecase     AlertReceiverUsecase
+
+	mu       sync.Mutex
+	states   map[string]*alertState // Keyed by rule ID and subject
+	restored bool                   // Firing alerts were loaded from history
 }
 
 // NewAlertUsecase creates a new alert usecase
-func NewAlertUsecase(dockerClient *docker.Client, notificationUsecase NotificationUsecase) AlertUsecase {
+func NewAlertUsecase(
+	alertRepo repository.AlertRepository,
+	clients DockerClientRegistry,
+	notificationUsecase NotificationUsecase,
+	serverUsecase domain.ServerUsecase,
+	k8sRepo domain.K8sClusterRepository,
+	capacityUsecase domain.K8sCapacityUsecase,
+	stackRepo repository.DockerStackRepository,
+	receiverUsecase AlertReceiverUsecase,
+) AlertUsecase {
 	return &alertUsecase{
-		dockerClient:        dockerClient,
+		alertRepo:           alertRepo,
+		clients:             clients,
 		notificationUsecase: notificationUsecase,
-		rules:               domain.DefaultAlertRules(),
+		serverUsecase:       serverUsecase,
+		k8sRepo:             k8sRepo,
+		capacityUsecase:     capacityUsecase,
+		stackRepo:           stackRepo,
+		receiverUsecase:     receiverUsecase,
+		states:              make(map[string]*alertState),
 	}
 }
 
 // StartMonitoring starts the background monitoring job
 func (u *alertUsecase) StartMonitoring(ctx context.Context) {
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(alertCheckInterval)
 	go func() {
 		for {
 			select {
-			case <-ticker.C:
-				u.CheckResources(ctx)
+			case now := <-ticker.C:
+				u.CheckResources(ctx, now)
 			case <-ctx.Done():
 				ticker.Stop()
 				return
@@ -49,99 +142,785 @@ func (u *alertUsecase) StartMonitoring(ctx context.Context) {
 	}()
 }
 
-// CheckResources checks container resources against rules
-func (u *alertUsecase) CheckResources(ctx context.Context) {
-	// List all running containers
-	containers, err := u.dockerClient.ContainerList(ctx, false) // false = only running
+// CheckResources evaluates every enabled rule against the current metrics of its subjects
+func (u *alertUsecase) CheckResources(ctx context.Context, now time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	now = now.UTC()
+
+	if !u.restored {
+		if err := u.restoreFiring(ctx); err != nil {
+			log.Printf("Error loading firing alerts: %v", err)
+			return
+		}
+		u.restored = true
+	}
+
+	rules, err := u.alertRepo.ListRules(ctx)
 	if err != nil {
-		log.Printf("Error listing containers for alert check: %v", err)
+		log.Printf("Error listing alert rules: %v", err)
 		return
 	}
+	silences, err := u.alertRepo.ListSilences(ctx, now)
+	if err != nil {
+		log.Printf("Error listing alert silences: %v", err)
+	}
+	windows, err := u.alertRepo.ListMaintenanceWindows(ctx)
+	if err != nil {
+		log.Printf("Error listing alert maintenance windows: %v", err)
+	}
 
-	for _, container := range containers {
-		stats, err := u.dockerClient.ContainerStatsOnce(ctx, container.ID)
-		if err != nil {
-			log.Printf("Error getting stats for container %s: %v", container.ID[:12], err)
+	all := make(map[string]*domain.AlertRule)
+	enabled := make(map[string]*domain.AlertRule)
+	for _, rule := range rules {
+		all[rule.ID] = rule
+		if rule.Enabled {
+			enabled[rule.ID] = rule
+		}
+	}
+	samples, failures := u.collectSamples(ctx, enabled)
+
+	tracked := make(map[string]bool)
+	for _, rule := range enabled {
+		u.evaluateRule(ctx, rule, samples[rule.Scope], silences, windows, now, tracked)
+	}
+
+	// What was not evaluated resolves, unless its metrics could not be read
+	for key, state := range u.states {
+		if tracked[key] {
 			continue
 		}
+		if enabled[state.ruleID] != nil && failures.failed(state.source) {
+			continue
+		}
+		if state.history != nil {
+			u.resolve(ctx, all[state.ruleID], state, now)
+		}
+		delete(u.states, key)
+	}
+
+	if u.receiverUsecase != nil {
+		u.receiverUsecase.Flush(ctx, now)
+	}
+}
+
+// restoreFiring picks up the alerts that were firing when the process stopped, so they resolve
+// instead of firing again
+func (u *alertUsecase) restoreFiring(ctx context.Context) error {
+	firing, err := u.alertRepo.ListFiringHistory(ctx)
+	if err != nil {
+		return err
+	}
+	for _, history := range firing {
+		u.states[alertStateKey(history.RuleID, history.Subject)] = &alertState{
+			ruleID:  history.RuleID,
+			subject: history.Subject,
+			since:   history.StartedAt,
+			history: history,
+		}
+	}
+	return nil
+}
+
+func alertStateKey(ruleID, subject string) string {
+	return ruleID + "/" + subject
+}
+
+// evaluateRule moves the states of a rule for the samples it matches, recording in tracked the states
+// that are still current
+func (u *alertUsecase) evaluateRule(ctx context.Context, rule *domain.AlertRule, samples []*alertSample, silences []*domain.AlertSilence, windows []*domain.AlertMaintenanceWindow, now time.Time, tracked map[string]bool) {
+	matches, err := alertTargetMatcher(rule)
+	if err != nil {
+		log.Printf("Error evaluating alert rule %s: %v", rule.Name, err)
+		return
+	}
+	duration, _ := time.ParseDuration(rule.Duration)
+
+	for _, sample := range samples {
+		if !matches(sample) {
+			continue
+		}
+		key := alertStateKey(rule.ID, sample.subject)
+		state := u.states[key]
+
+		if inMaintenance(windows, rule.Scope, sample.subject, now) {
+			// Firing alerts wait for the window to end; pending breaches start over
+			if state != nil && state.history != nil {
+				tracked[key] = true
+			}
+			continue
+		}
+
+		value, ok := sample.values[rule.Metric]
+		if !ok {
+			// Not readable this time, e.g. the stats of a container that is stopping
+			if state != nil {
+				tracked[key] = true
+			}
+			continue
+		}
+
+		if !rule.Operator.Compare(value, rule.Threshold) {
+			if state != nil && state.history != nil {
+				u.resolve(ctx, rule, state, now)
+			}
+			delete(u.states, key)
+			continue
+		}
+
+		if state == nil {
+			state = &alertState{ruleID: rule.ID, subject: sample.subject, since: now}
+			u.states[key] = state
+		}
+		state.source = sample.source
+		tracked[key] = true
+
+		if state.history == nil && now.Sub(state.since) >= duration {
+			u.fire(ctx, rule, state, sample, value, silences, now)
+		}
+	}
+}
+
+// alertTargetMatcher returns whether a sample is a subject of a rule
+func alertTargetMatcher(rule *domain.AlertRule) (func(*alertSample) bool, error) {
+	if rule.Scope == domain.AlertScopeLabel {
+		selector, err := labels.Parse(rule.Target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector: %w", err)
+		}
+		return func(s *alertSample) bool {
+			return selector.Matches(labels.Set(s.labels))
+		}, nil
+	}
+
+	target := rule.Target
+	return func(s *alertSample) bool {
+		if target == "" || s.subject == target || s.name == target {
+			return true
+		}
+		// Containers are also matched by their short ID
+		return rule.Scope == domain.AlertScopeContainer && len(target) >= 12 && strings.HasPrefix(s.subject, target)
+	}, nil
+}
+
+func inMaintenance(windows []*domain.AlertMaintenanceWindow, scope domain.AlertScope, subject string, now time.Time) bool {
+	for _, window := range windows {
+		if window.Covers(scope, subject, now) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSilenced(silences []*domain.AlertSilence, ruleID string, scope domain.AlertScope, subject string, now time.Time) bool {
+	for _, silence := range silences {
+		if silence.Matches(ruleID, scope, subject, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// fire records that a rule fired for a subject and notifies unless it is silenced. A failed record is
+// retried on the next check.
+func (u *alertUsecase) fire(ctx context.Context, rule *domain.AlertRule, state *alertState, sample *alertSample, value float64, silences []*domain.AlertSilence, now time.Time) {
+	history := &domain.AlertHistory{
+		RuleID:      rule.ID,
+		RuleName:    rule.Name,
+		Scope:       rule.Scope,
+		Subject:     sample.subject,
+		SubjectName: sample.name,
+		Metric:      rule.Metric,
+		Value:       value,
+		Threshold:   rule.Threshold,
+		Severity:    rule.Severity,
+		Labels:      rule.Labels,
+		Status:      domain.AlertStatusFiring,
+		Silenced:    isSilenced(silences, rule.ID, rule.Scope, sample.subject, now),
+		StartedAt:   state.since,
+		TriggeredAt: now,
+	}
+	if err := u.alertRepo.CreateHistory(ctx, history); err != nil {
+		log.Printf("Error recording alert %s for %s: %v", rule.Name, sample.name, err)
+		return
+	}
+	state.history = history
+
+	if !history.Silenced {
+		u.notify(ctx, rule, history, now)
+	}
+}
+
+// resolve records that a fired alert resolved. Whoever was told it fired is told it resolved, even if
+// it was silenced since. rule is nil when the rule was deleted.
+func (u *alertUsecase) resolve(ctx context.Context, rule *domain.AlertRule, state *alertState, now time.Time) {
+	history := state.history
+	history.Status = domain.AlertStatusResolved
+	history.ResolvedAt = &now
+	if err := u.alertRepo.UpdateHistory(ctx, history); err != nil {
+		log.Printf("Error recording resolution of alert %s for %s: %v", history.RuleName, history.SubjectName, err)
+	}
+
+	if !history.Silenced {
+		u.notify(ctx, rule, history, now)
+	}
+}
+
+// notify logs an alert change, routes it to the receivers and sends it to the creator of the rule
+func (u *alertUsecase) notify(ctx context.Context, rule *domain.AlertRule, history *domain.AlertHistory, now time.Time) {
+	notification := alertNotification(history)
+	log.Printf("ALERT %s: %s", strings.ToUpper(string(history.Status)), notification.Message)
+
+	if u.receiverUsecase != nil {
+		u.receiverUsecase.Route(ctx, history, now)
+	}
+
+	if u.notificationUsecase == nil || rule == nil || rule.CreatedBy == nil {
+		return
+	}
+	notification.UserID = *rule.CreatedBy
+	if err := u.notificationUsecase.SendNotification(ctx, notification); err != nil {
+		log.Printf("Error sending notification of alert %s: %v", history.RuleName, err)
+	}
+}
+
+func alertNotification(history *domain.AlertHistory) *domain.Notification {
+	subject := history.SubjectName
+	if subject == "" {
+		subject = history.Subject
+	}
+
+	notification := &domain.Notification{
+		Channel: domain.NotificationChannelInApp,
+		Data:    history,
+	}
+	if history.Status == domain.AlertStatusResolved {
+		notification.Type = domain.NotificationTypeSuccess
+		notification.Priority = domain.NotificationPriorityNormal
+		notification.Title = fmt.Sprintf("Resolved: %s", history.RuleName)
+		notification.Message = fmt.Sprintf("%s %s: %s is back within the threshold of %g",
+			history.Scope, subject, history.Metric, history.Threshold)
+		return notification
+	}
+
+	notification.Type = domain.NotificationTypeWarning
+	notification.Priority = domain.NotificationPriorityNormal
+	switch history.Severity {
+	case domain.AlertSeverityCritical:
+		notification.Type = domain.NotificationTypeError
+		notification.Priority = domain.NotificationPriorityHigh
+	case domain.AlertSeverityInfo:
+		notification.Type = domain.NotificationTypeInfo
+		notification.Priority = domain.NotificationPriorityLow
+	}
+	notification.Title = fmt.Sprintf("Firing: %s", history.RuleName)
+	notification.Message = fmt.Sprintf("%s %s: %s is %.2f, threshold %g, since %s",
+		history.Scope, subject, history.Metric, history.Value, history.Threshold, history.StartedAt.Format(time.RFC3339))
+	return notification
+}
+
+// collectSamples reads the metrics of the subjects of the scopes the rules evaluate
+func (u *alertUsecase) collectSamples(ctx context.Context, rules map[string]*domain.AlertRule) (map[domain.AlertScope][]*alertSample, alertSourceFailures) {
+	samples := make(map[domain.AlertScope][]*alertSample)
+	failures := make(alertSourceFailures)
+
+	scopes := make(map[domain.AlertScope]bool)
+	withStats := false
+	for _, rule := range rules {
+		scopes[rule.Scope] = true
+		// Container counts of stacks and hosts need no stats, which take a second per container
+		if rule.Scope != domain.AlertScopeServer && rule.Scope != domain.AlertScopeCluster &&
+			rule.Metric != domain.AlertMetricContainers && rule.Metric != domain.AlertMetricUnhealthy {
+			withStats = true
+		}
+	}
 
-		u.evaluateRules(ctx, container.ID, container.Names[0], stats)
+	if scopes[domain.AlertScopeContainer] || scopes[domain.AlertScopeLabel] || scopes[domain.AlertScopeStack] || scopes[domain.AlertScopeHost] {
+		u.collectDockerSamples(ctx, scopes, withStats, samples, failures)
 	}
+	if scopes[domain.AlertScopeServer] {
+		u.collectServerSamples(ctx, samples, failures)
+	}
+	if scopes[domain.AlertScopeCluster] {
+		u.collectClusterSamples(ctx, samples, failures)
+	}
+	return samples, failures
 }
 
-func (u *alertUsecase) evaluateRules(ctx context.Context, containerID, containerName string, stats *docker.ContainerStats) {
-	u.mu.RLock()
-	defer u.mu.RUnlock()
+// dockerContainerReading is a running container with its stats, if they could be read
+type dockerContainerReading struct {
+	id        string
+	name      string
+	labels    map[string]string
+	unhealthy bool
+	stats     *docker.ContainerStats
+}
 
-	// Calculate CPU Usage
-	// Docker stats calculation is complex. Simplified version:
-	// cpu_delta = cpu_total_usage - precpu_total_usage
-	// system_cpu_delta = system_cpu_usage - precpu_system_cpu_usage
-	// number_cpus = len(percpu_usage) or online_cpus
-	// cpu_usage = (cpu_delta / system_cpu_delta) * number_cpus * 100.0
+// collectDockerSamples samples the containers of every active Docker host. Stacks of the local daemon
+// are not registered hosts and are not evaluated.
+func (u *alertUsecase) collectDockerSamples(ctx context.Context, scopes map[domain.AlertScope]bool, withStats bool, samples map[domain.AlertScope][]*alertSample, failures alertSourceFailures) {
+	hosts, err := u.clients.ActiveHosts(ctx)
+	if err != nil {
+		log.Printf("Error listing docker hosts for alert check: %v", err)
+		failures["docker"] = true
+		return
+	}
 
-	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PrecpuStats.CPUUsage.TotalUsage)
-	systemDelta := float64(stats.CPUStats.SystemCPUUsage) - float64(stats.PrecpuStats.SystemCPUUsage)
-	onlineCPUs := float64(stats.CPUStats.OnlineCPUs)
-	if onlineCPUs == 0.0 {
-		onlineCPUs = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	stacksByHost := make(map[string][]*domain.DockerStack)
+	if scopes[domain.AlertScopeStack] {
+		stacks, err := u.stackRepo.List(ctx)
+		if err != nil {
+			log.Printf("Error listing docker stacks for alert check: %v", err)
+			failures["docker"] = true
+			return
+		}
+		for _, stack := range stacks {
+			stacksByHost[stack.DockerHost] = append(stacksByHost[stack.DockerHost], stack)
+		}
 	}
 
-	var cpuUsage float64
-	if systemDelta > 0.0 && cpuDelta > 0.0 {
-		cpuUsage = (cpuDelta / systemDelta) * onlineCPUs * 100.0
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, host := range hosts {
+		source := "docker:" + host.ID
+		// Offline hosts are reported by the health check
+		if host.Status == domain.DockerHostStatusOffline {
+			failures[source] = true
+			continue
+		}
+		wg.Add(1)
+		go func(host *domain.DockerHost) {
+			defer wg.Done()
+			readings, err := u.readDockerHost(ctx, host.ID, withStats)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("Error reading containers of docker host %s for alert check: %v", host.Name, err)
+				failures[source] = true
+				return
+			}
+
+			for _, r := range readings {
+				sample := &alertSample{subject: r.id, name: r.name, source: source, labels: r.labels, values: make(map[domain.AlertMetric]float64)}
+				if r.stats != nil {
+					sample.values[domain.AlertMetricCPU] = r.stats.CPUPercent()
+					sample.values[domain.AlertMetricMemory] = r.stats.MemoryPercent()
+					sample.values[domain.AlertMetricMemoryBytes] = float64(r.stats.MemoryUsage())
+				}
+				samples[domain.AlertScopeContainer] = append(samples[domain.AlertScopeContainer], sample)
+				samples[domain.AlertScopeLabel] = append(samples[domain.AlertScopeLabel], sample)
+			}
+			samples[domain.AlertScopeHost] = append(samples[domain.AlertScopeHost], aggregateContainerReadings(host.ID, host.Name, source, readings))
+
+			for _, stack := range stacksByHost[host.ID] {
+				var members []*dockerContainerReading
+				for _, r := range readings {
+					if r.labels[docker.ComposeProjectLabel] == stack.ProjectName {
+						members = append(members, r)
+					}
+				}
+				samples[domain.AlertScopeStack] = append(samples[domain.AlertScopeStack], aggregateContainerReadings(stack.ID, stack.Name, source, members))
+			}
+		}(host)
 	}
+	wg.Wait()
+}
 
-	// Calculate Memory Usage
-	// usage = memory_stats.usage - memory_stats.stats.cache (if available)
-	// percent = (usage / limit) * 100
-	memUsageBytes := float64(stats.MemoryStats.Usage)
-	// Subtract cache if available (Docker CLI does this)
-	if stats.MemoryStats.Stats.Cache > 0 {
-		memUsageBytes -= float64(stats.MemoryStats.Stats.Cache)
+// readDockerHost lists the running containers of a host, reading their stats if asked to
+func (u *alertUsecase) readDockerHost(ctx context.Context, hostID string, withStats bool) ([]*dockerContainerReading, error) {
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := client.ContainerList(ctx, false) // false = only running
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
 	}
 
-	memLimit := float64(stats.MemoryStats.Limit)
-	var memUsagePercent float64
-	if memLimit > 0 {
-		memUsagePercent = (memUsageBytes / memLimit) * 100.0
+	readings := make([]*dockerContainerReading, len(containers))
+	var (
+		wg    sync.WaitGroup
+		slots = make(chan struct{}, containerMetricConcurrency)
+	)
+	for i, c := range containers {
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		reading := &dockerContainerReading{
+			id:        c.ID,
+			name:      name,
+			labels:    c.Labels,
+			unhealthy: strings.Contains(c.Status, "(unhealthy)"),
+		}
+		readings[i] = reading
+		if !withStats {
+			continue
+		}
+
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			stats, err := client.ContainerStatsOnce(ctx, reading.id)
+			if err != nil {
+				// The container may have stopped since it was listed
+				log.Printf("Error getting stats for container %s: %v", reading.name, err)
+				return
+			}
+			reading.stats = stats
+		}()
 	}
+	wg.Wait()
+	return readings, nil
+}
+
+// aggregateContainerReadings sums the usage of the containers of a stack or host. Memory percent is
+// relative to the sum of their limits. Usage is only known if the stats of every container were read.
+func aggregateContainerReadings(subject, name, source string, readings []*dockerContainerReading) *alertSample {
+	sample := &alertSample{subject: subject, name: name, source: source, values: make(map[domain.AlertMetric]float64)}
 
-	for _, rule := range u.rules {
-		if !rule.Enabled {
+	var (
+		unhealthy          int
+		cpu                float64
+		memory, limit      uint64
+		complete, withStat = true, false
+	)
+	for _, r := range readings {
+		if r.unhealthy {
+			unhealthy++
+		}
+		if r.stats == nil {
+			complete = false
 			continue
 		}
+		withStat = true
+		cpu += r.stats.CPUPercent()
+		memory += r.stats.MemoryUsage()
+		limit += r.stats.MemoryStats.Limit
+	}
+	sample.values[domain.AlertMetricContainers] = float64(len(readings))
+	sample.values[domain.AlertMetricUnhealthy] = float64(unhealthy)
+
+	if complete && (withStat || len(readings) == 0) {
+		sample.values[domain.AlertMetricCPU] = cpu
+		sample.values[domain.AlertMetricMemoryBytes] = float64(memory)
+		if limit > 0 {
+			sample.values[domain.AlertMetricMemory] = float64(memory) / float64(limit) * 100
+		}
+	}
+	return sample
+}
 
-		triggered := false
-		var value float64
+// collectServerSamples reads the metrics of every server that is online
+func (u *alertUsecase) collectServerSamples(ctx context.Context, samples map[domain.AlertScope][]*alertSample, failures alertSourceFailures) {
+	filter := domain.ServerFilter{Page: 1, PageSize: 100}
+	for {
+		servers, total, err := u.serverUsecase.ListServers(ctx, filter)
+		if err != nil {
+			log.Printf("Error listing servers for alert check: %v", err)
+			failures["server"] = true
+			return
+		}
 
-		switch rule.Metric {
-		case "cpu":
-			if cpuUsage > rule.Threshold {
-				triggered = true
-				value = cpuUsage
+		for _, server := range servers {
+			source := "server:" + server.ID
+			// Servers that are down or under maintenance keep their alerts as they are
+			if server.Status != domain.ServerStatusOnline {
+				failures[source] = true
+				continue
 			}
-		case "memory":
-			if memUsagePercent > rule.Threshold {
-				triggered = true
-				value = memUsagePercent
+			metrics, err := u.serverUsecase.GetServerMetrics(ctx, server.ID)
+			if err != nil {
+				log.Printf("Error getting metrics of server %s for alert check: %v", server.Name, err)
+				failures[source] = true
+				continue
 			}
+			samples[domain.AlertScopeServer] = append(samples[domain.AlertScopeServer], &alertSample{
+				subject: server.ID,
+				name:    server.Name,
+				source:  source,
+				values: map[domain.AlertMetric]float64{
+					domain.AlertMetricCPU:    metrics.CPUUsage,
+					domain.AlertMetricMemory: metrics.MemoryUsage,
+					domain.AlertMetricDisk:   metrics.DiskUsage,
+				},
+			})
 		}
 
-		if triggered {
-			u.triggerAlert(ctx, rule, containerID, containerName, value)
+		if len(servers) == 0 || int64(filter.Page*filter.PageSize) >= total {
+			return
 		}
+		filter.Page++
 	}
 }
 
-func (u *alertUsecase) triggerAlert(ctx context.Context, rule domain.AlertRule, containerID, containerName string, value float64) {
-	message := fmt.Sprintf("ALERT: Container %s (%s) exceeded %s threshold. Current: %.2f%%, Threshold: %.2f%%",
-		containerName, containerID[:12], rule.Metric, value, rule.Threshold)
+// collectClusterSamples reads the capacity of every active cluster
+func (u *alertUsecase) collectClusterSamples(ctx context.Context, samples map[domain.AlertScope][]*alertSample, failures alertSourceFailures) {
+	active := true
+	filter := domain.K8sClusterFilter{IsActive: &active, Page: 1, PageSize: 100}
+	for {
+		clusters, total, err := u.k8sRepo.List(ctx, filter)
+		if err != nil {
+			log.Printf("Error listing clusters for alert check: %v", err)
+			failures["cluster"] = true
+			return
+		}
 
-	log.Println(message)
+		for _, cluster := range clusters {
+			source := "cluster:" + cluster.ID
+			overview, err := u.capacityUsecase.GetOverview(ctx, cluster.ID, false)
+			if err != nil {
+				log.Printf("Error getting capacity of cluster %s for alert check: %v", cluster.Name, err)
+				failures[source] = true
+				continue
+			}
+			samples[domain.AlertScopeCluster] = append(samples[domain.AlertScopeCluster], &alertSample{
+				subject: cluster.ID,
+				name:    cluster.Name,
+				source:  source,
+				values:  clusterAlertValues(overview),
+			})
+		}
 
-	// Send notification
-	if u.notificationUsecase != nil {
-		// u.notificationUsecase.Send(...)
+		if len(clusters) == 0 || int64(filter.Page*filter.PageSize) >= total {
+			return
+		}
+		filter.Page++
+	}
+}
+
+// clusterAlertValues computes the metrics of a cluster; usage is only known with metrics-server
+func clusterAlertValues(overview *domain.K8sClusterOverview) map[domain.AlertMetric]float64 {
+	totals := overview.Totals
+	values := map[domain.AlertMetric]float64{
+		domain.AlertMetricNotReadyNodes: float64(overview.NodeCount - overview.ReadyNodes),
+	}
+	if totals.CPUAllocatable > 0 {
+		values[domain.AlertMetricCPURequests] = float64(totals.CPURequests) / float64(totals.CPUAllocatable) * 100
+		if overview.MetricsAvailable && totals.CPUUsage != nil {
+			values[domain.AlertMetricCPU] = float64(*totals.CPUUsage) / float64(totals.CPUAllocatable) * 100
+		}
+	}
+	if totals.MemoryAllocatable > 0 {
+		values[domain.AlertMetricMemoryRequests] = float64(totals.MemoryRequests) / float64(totals.MemoryAllocatable) * 100
+		if overview.MetricsAvailable && totals.MemoryUsage != nil {
+			values[domain.AlertMetricMemory] = float64(*totals.MemoryUsage) / float64(totals.MemoryAllocatable) * 100
+		}
+	}
+	return values
+}
+
+// ListRules lists every alert rule
+func (u *alertUsecase) ListRules(ctx context.Context) ([]*domain.AlertRule, error) {
+	return u.alertRepo.ListRules(ctx)
+}
+
+// GetRule gets an alert rule
+func (u *alertUsecase) GetRule(ctx context.Context, id string) (*domain.AlertRule, error) {
+	return u.alertRepo.GetRule(ctx, id)
+}
+
+// CreateRule creates an alert rule; its creator is notified when it fires
+func (u *alertUsecase) CreateRule(ctx context.Context, req domain.AlertRuleRequest, userID string) (*domain.AlertRule, error) {
+	rule := &domain.AlertRule{CreatedBy: optionalUser(userID)}
+	if err := applyAlertRuleRequest(rule, req); err != nil {
+		return nil, err
+	}
+	if err := u.alertRepo.CreateRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to create alert rule: %w", err)
+	}
+	return rule, nil
+}
+
+// UpdateRule replaces an alert rule; its alerts are evaluated against the new definition on the next check
+func (u *alertUsecase) UpdateRule(ctx context.Context, id string, req domain.AlertRuleRequest) (*domain.AlertRule, error) {
+	rule, err := u.alertRepo.GetRule(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("alert rule not found: %w", err)
+	}
+	if err := applyAlertRuleRequest(rule, req); err != nil {
+		return nil, err
+	}
+	if err := u.alertRepo.UpdateRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to update alert rule: %w", err)
+	}
+	return rule, nil
+}
+
+// DeleteRule deletes an alert rule; its firing alerts resolve on the next check and its history is kept
+func (u *alertUsecase) DeleteRule(ctx context.Context, id string) error {
+	if _, err := u.alertRepo.GetRule(ctx, id); err != nil {
+		return fmt.Errorf("alert rule not found: %w", err)
+	}
+	return u.alertRepo.DeleteRule(ctx, id)
+}
+
+// applyAlertRuleRequest validates a request and copies it into a rule
+func applyAlertRuleRequest(rule *domain.AlertRule, req domain.AlertRuleRequest) error {
+	metrics, ok := alertScopeMetrics[req.Scope]
+	if !ok {
+		return fmt.Errorf("unknown scope %q", req.Scope)
+	}
+	supported := false
+	for _, metric := range metrics {
+		supported = supported || metric == req.Metric
+	}
+	if !supported {
+		return fmt.Errorf("metric %q is not available for %s rules", req.Metric, req.Scope)
+	}
+	switch req.Operator {
+	case domain.AlertOperatorGT, domain.AlertOperatorGTE, domain.AlertOperatorLT,
+		domain.AlertOperatorLTE, domain.AlertOperatorEQ, domain.AlertOperatorNE:
+	default:
+		return fmt.Errorf("unknown operator %q", req.Operator)
+	}
+	if req.Duration != "" {
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil || duration < 0 {
+			return fmt.Errorf("invalid duration %q", req.Duration)
+		}
+	}
+	for name := range req.Labels {
+		if name == "" {
+			return fmt.Errorf("label names must not be empty")
+		}
+	}
+	if req.Scope == domain.AlertScopeLabel {
+		if req.Target == "" {
+			return fmt.Errorf("label rules need a label selector as target")
+		}
+		if _, err := labels.Parse(req.Target); err != nil {
+			return fmt.Errorf("invalid label selector: %w", err)
+		}
+	}
+
+	rule.Name = req.Name
+	rule.Description = req.Description
+	rule.Scope = req.Scope
+	rule.Target = req.Target
+	rule.Metric = req.Metric
+	rule.Operator = req.Operator
+	rule.Threshold = req.Threshold
+	rule.Duration = req.Duration
+	rule.Severity = req.Severity
+	rule.Labels = req.Labels
+	if rule.Severity == "" {
+		rule.Severity = domain.AlertSeverityWarning
+	}
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	return nil
+}
+
+// ListSilences lists the silences that have not ended, or every silence
+func (u *alertUsecase) ListSilences(ctx context.Context, includeExpired bool) ([]*domain.AlertSilence, error) {
+	endsAfter := time.Now().UTC()
+	if includeExpired {
+		endsAfter = time.Time{}
+	}
+	return u.alertRepo.ListSilences(ctx, endsAfter)
+}
+
+// CreateSilence silences the alerts matching a request until it ends
+func (u *alertUsecase) CreateSilence(ctx context.Context, req domain.AlertSilenceRequest, userID string) (*domain.AlertSilence, error) {
+	if req.RuleID == nil && req.Scope == "" && req.Subject == "" {
+		return nil, fmt.Errorf("a silence needs a rule, scope or subject to match")
+	}
+	now := time.Now().UTC()
+	startsAt := now
+	if req.StartsAt != nil {
+		startsAt = req.StartsAt.UTC()
+	}
+	if !req.EndsAt.After(startsAt) || !req.EndsAt.After(now) {
+		return nil, fmt.Errorf("a silence must end in the future and after it starts")
+	}
+	if req.RuleID != nil {
+		if _, err := u.alertRepo.GetRule(ctx, *req.RuleID); err != nil {
+			return nil, fmt.Errorf("alert rule not found: %w", err)
+		}
+	}
+
+	silence := &domain.AlertSilence{
+		RuleID:    req.RuleID,
+		Scope:     req.Scope,
+		Subject:   req.Subject,
+		Comment:   req.Comment,
+		StartsAt:  startsAt,
+		EndsAt:    req.EndsAt.UTC(),
+		CreatedBy: optionalUser(userID),
+	}
+	if err := u.alertRepo.CreateSilence(ctx, silence); err != nil {
+		return nil, fmt.Errorf("failed to create silence: %w", err)
+	}
+	return silence, nil
+}
+
+// ExpireSilence ends a silence now
+func (u *alertUsecase) ExpireSilence(ctx context.Context, id string) (*domain.AlertSilence, error) {
+	silence, err := u.alertRepo.GetSilence(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("silence not found: %w", err)
+	}
+	now := time.Now().UTC()
+	if !silence.EndsAt.After(now) {
+		return silence, nil
+	}
+	silence.EndsAt = now
+	if silence.StartsAt.After(now) {
+		silence.StartsAt = now
+	}
+	if err := u.alertRepo.UpdateSilence(ctx, silence); err != nil {
+		return nil, fmt.Errorf("failed to expire silence: %w", err)
+	}
+	return silence, nil
+}
+
+// ListMaintenanceWindows lists every maintenance window
+func (u *alertUsecase) ListMaintenanceWindows(ctx context.Context) ([]*domain.AlertMaintenanceWindow, error) {
+	return u.alertRepo.ListMaintenanceWindows(ctx)
+}
+
+// CreateMaintenanceWindow schedules a maintenance window
+func (u *alertUsecase) CreateMaintenanceWindow(ctx context.Context, req domain.AlertMaintenanceWindowRequest, userID string) (*domain.AlertMaintenanceWindow, error) {
+	if !req.EndsAt.After(req.StartsAt) {
+		return nil, fmt.Errorf("a maintenance window must end after it starts")
+	}
+	if req.Weekly && req.EndsAt.Sub(req.StartsAt) >= 7*24*time.Hour {
+		return nil, fmt.Errorf("a weekly maintenance window must be shorter than a week")
+	}
+
+	window := &domain.AlertMaintenanceWindow{
+		Name:      req.Name,
+		Scope:     req.Scope,
+		Subject:   req.Subject,
+		StartsAt:  req.StartsAt.UTC(),
+		EndsAt:    req.EndsAt.UTC(),
+		Weekly:    req.Weekly,
+		CreatedBy: optionalUser(userID),
+	}
+	if err := u.alertRepo.CreateMaintenanceWindow(ctx, window); err != nil {
+		return nil, fmt.Errorf("failed to create maintenance window: %w", err)
+	}
+	return window, nil
+}
+
+// DeleteMaintenanceWindow deletes a maintenance window
+func (u *alertUsecase) DeleteMaintenanceWindow(ctx context.Context, id string) error {
+	return u.alertRepo.DeleteMaintenanceWindow(ctx, id)
+}
+
+// ListHistory lists the alerts matching a filter, latest first
+func (u *alertUsecase) ListHistory(ctx context.Context, filter domain.AlertHistoryFilter) ([]*domain.AlertHistory, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = 20
 	}
+	return u.alertRepo.ListHistory(ctx, filter)
 }
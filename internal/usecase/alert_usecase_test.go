@@ -0,0 +1,368 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+)
+
+// MockAlertRepository is a mock implementation of AlertRepository
+type MockAlertRepository struct {
+	mock.Mock
+}
+
+func (m *MockAlertRepository) CreateRule(ctx context.Context, rule *domain.AlertRule) error {
+	args := m.Called(ctx, rule)
+	return args.Error(0)
+}
+
+func (m *MockAlertRepository) GetRule(ctx context.Context, id string) (*domain.AlertRule, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AlertRule), args.Error(1)
+}
+
+func (m *MockAlertRepository) ListRules(ctx context.Context) ([]*domain.AlertRule, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.AlertRule), args.Error(1)
+}
+
+func (m *MockAlertRepository) UpdateRule(ctx context.Context, rule *domain.AlertRule) error {
+	args := m.Called(ctx, rule)
+	return args.Error(0)
+}
+
+func (m *MockAlertRepository) DeleteRule(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockAlertRepository) CreateHistory(ctx context.Context, history *domain.AlertHistory) error {
+	args := m.Called(ctx, history)
+	return args.Error(0)
+}
+
+func (m *MockAlertRepository) UpdateHistory(ctx context.Context, history *domain.AlertHistory) error {
+	args := m.Called(ctx, history)
+	return args.Error(0)
+}
+
+func (m *MockAlertRepository) ListHistory(ctx context.Context, filter domain.AlertHistoryFilter) ([]*domain.AlertHistory, int64, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*domain.AlertHistory), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockAlertRepository) ListFiringHistory(ctx context.Context) ([]*domain.AlertHistory, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.AlertHistory), args.Error(1)
+}
+
+func (m *MockAlertRepository) CreateSilence(ctx context.Context, silence *domain.AlertSilence) error {
+	args := m.Called(ctx, silence)
+	return args.Error(0)
+}
+
+func (m *MockAlertRepository) GetSilence(ctx context.Context, id string) (*domain.AlertSilence, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AlertSilence), args.Error(1)
+}
+
+func (m *MockAlertRepository) ListSilences(ctx context.Context, endsAfter time.Time) ([]*domain.AlertSilence, error) {
+	args := m.Called(ctx, endsAfter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.AlertSilence), args.Error(1)
+}
+
+func (m *MockAlertRepository) UpdateSilence(ctx context.Context, silence *domain.AlertSilence) error {
+	args := m.Called(ctx, silence)
+	return args.Error(0)
+}
+
+func (m *MockAlertRepository) CreateMaintenanceWindow(ctx context.Context, window *domain.AlertMaintenanceWindow) error {
+	args := m.Called(ctx, window)
+	return args.Error(0)
+}
+
+func (m *MockAlertRepository) ListMaintenanceWindows(ctx context.Context) ([]*domain.AlertMaintenanceWindow, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.AlertMaintenanceWindow), args.Error(1)
+}
+
+func (m *MockAlertRepository) DeleteMaintenanceWindow(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// MockNotificationUsecase is a mock implementation of NotificationUsecase; only sending is mocked
+type MockNotificationUsecase struct {
+	usecase.NotificationUsecase
+	mock.Mock
+}
+
+func (m *MockNotificationUsecase) SendNotification(ctx context.Context, notification *domain.Notification) error {
+	args := m.Called(ctx, notification)
+	return args.Error(0)
+}
+
+// notificationFor matches a notification of a type sent to a user
+func notificationFor(userID string, notificationType domain.NotificationType) interface{} {
+	return mock.MatchedBy(func(n *domain.Notification) bool {
+		return n.UserID == userID && n.Type == notificationType
+	})
+}
+
+// resolvedAlertOf matches an alert of a rule that resolved at a time
+func resolvedAlertOf(ruleID string, at time.Time) interface{} {
+	return mock.MatchedBy(func(h *domain.AlertHistory) bool {
+		return h.RuleID == ruleID && h.Status == domain.AlertStatusResolved && h.ResolvedAt != nil && h.ResolvedAt.Equal(at)
+	})
+}
+
+// TestCheckResources tests the pending, firing and resolved states of alert rules
+func TestCheckResources(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.NewString()
+
+	// The container uses 40% CPU and 200MiB of memory
+	var rx uint64
+	client := newStatsDockerDaemon(t, &rx)
+	host := &domain.DockerHost{ID: "host-1", Name: "edge", Status: domain.DockerHostStatusOnline}
+	mockClients := new(MockDockerClientRegistry)
+	mockClients.On("ActiveHosts", ctx).Return([]*domain.DockerHost{host}, nil)
+	mockClients.On("Get", ctx, "host-1").Return(client, nil)
+
+	cpu := &domain.AlertRule{
+		ID:        "rule-cpu",
+		Name:      "Shop CPU",
+		Scope:     domain.AlertScopeLabel,
+		Target:    "com.docker.compose.project=shop",
+		Metric:    domain.AlertMetricCPU,
+		Operator:  domain.AlertOperatorGT,
+		Threshold: 30,
+		Duration:  "1m",
+		Severity:  domain.AlertSeverityWarning,
+		Enabled:   true,
+		CreatedBy: &userID,
+	}
+	memory := &domain.AlertRule{
+		ID:        "rule-memory",
+		Name:      "Container memory",
+		Scope:     domain.AlertScopeContainer,
+		Target:    "shop-web-1",
+		Metric:    domain.AlertMetricMemoryBytes,
+		Operator:  domain.AlertOperatorGTE,
+		Threshold: 100 * 1024 * 1024,
+		Severity:  domain.AlertSeverityCritical,
+		Enabled:   true,
+		CreatedBy: &userID,
+	}
+
+	repo := new(MockAlertRepository)
+	notifications := new(MockNotificationUsecase)
+	u := usecase.NewAlertUsecase(repo, mockClients, notifications, nil, nil, nil, nil, nil)
+
+	// Alerts are only recorded, resolved or notified where an expectation is set below
+	var fired []*domain.AlertHistory
+	recordFired := func(args mock.Arguments) {
+		fired = append(fired, args.Get(1).(*domain.AlertHistory))
+	}
+	repo.On("ListFiringHistory", ctx).Return([]*domain.AlertHistory{}, nil).Once()
+	rules := repo.On("ListRules", ctx).Return([]*domain.AlertRule{cpu}, nil)
+	silences := repo.On("ListSilences", ctx, mock.Anything).Return([]*domain.AlertSilence{}, nil)
+	windows := repo.On("ListMaintenanceWindows", ctx).Return([]*domain.AlertMaintenanceWindow{}, nil)
+
+	base := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	u.CheckResources(ctx, base)
+	u.CheckResources(ctx, base.Add(30*time.Second))
+	repo.AssertNotCalled(t, "CreateHistory", mock.Anything, mock.Anything)
+
+	repo.On("CreateHistory", ctx, mock.Anything).Run(recordFired).Return(nil).Once()
+	notifications.On("SendNotification", ctx, notificationFor(userID, domain.NotificationTypeWarning)).Return(nil).Once()
+	u.CheckResources(ctx, base.Add(time.Minute))
+	require.Len(t, fired, 1)
+	alert := fired[0]
+	assert.Equal(t, cpu.ID, alert.RuleID)
+	assert.Equal(t, "c1", alert.Subject)
+	assert.Equal(t, "shop-web-1", alert.SubjectName)
+	assert.InDelta(t, 40.0, alert.Value, 0.001)
+	assert.Equal(t, base, alert.StartedAt)
+	assert.Equal(t, domain.AlertStatusFiring, alert.Status)
+	notifications.AssertExpectations(t)
+
+	u.CheckResources(ctx, base.Add(90*time.Second))
+	repo.AssertNumberOfCalls(t, "CreateHistory", 1)
+	notifications.AssertNumberOfCalls(t, "SendNotification", 1)
+
+	t.Run("Maintenance window keeps firing alerts", func(t *testing.T) {
+		windows.Unset()
+		repo.On("ListMaintenanceWindows", ctx).Return([]*domain.AlertMaintenanceWindow{{
+			Name:     "Upgrade",
+			Scope:    domain.AlertScopeLabel,
+			Subject:  "c1",
+			StartsAt: base.Add(2 * time.Minute),
+			EndsAt:   base.Add(3 * time.Minute),
+		}}, nil)
+
+		// The metric recovers during the window; nothing resolves until it ends
+		cpu.Threshold = 50
+		u.CheckResources(ctx, base.Add(2*time.Minute))
+		repo.AssertNotCalled(t, "UpdateHistory", mock.Anything, mock.Anything)
+		assert.Equal(t, domain.AlertStatusFiring, alert.Status)
+	})
+
+	t.Run("Resolves when the metric recovers", func(t *testing.T) {
+		repo.On("UpdateHistory", ctx, resolvedAlertOf(cpu.ID, base.Add(3*time.Minute))).Return(nil).Once()
+		notifications.On("SendNotification", ctx, notificationFor(userID, domain.NotificationTypeSuccess)).Return(nil).Once()
+
+		u.CheckResources(ctx, base.Add(3*time.Minute))
+		assert.Equal(t, domain.AlertStatusResolved, alert.Status)
+		repo.AssertExpectations(t)
+		notifications.AssertExpectations(t)
+	})
+
+	t.Run("Silenced alerts are recorded without notifications", func(t *testing.T) {
+		rules.Unset()
+		rules = repo.On("ListRules", ctx).Return([]*domain.AlertRule{cpu, memory}, nil)
+		silences.Unset()
+		repo.On("ListSilences", ctx, mock.Anything).Return([]*domain.AlertSilence{{
+			RuleID:   &memory.ID,
+			StartsAt: base,
+			EndsAt:   base.Add(time.Hour),
+		}}, nil)
+		repo.On("CreateHistory", ctx, mock.Anything).Run(recordFired).Return(nil).Once()
+
+		now := base.Add(4 * time.Minute)
+		u.CheckResources(ctx, now)
+		require.Len(t, fired, 2)
+		silenced := fired[1]
+		assert.Equal(t, memory.ID, silenced.RuleID)
+		assert.True(t, silenced.Silenced)
+		notifications.AssertNumberOfCalls(t, "SendNotification", 2)
+
+		t.Run("Alerts of unreachable hosts are kept", func(t *testing.T) {
+			host.Status = domain.DockerHostStatusOffline
+			defer func() { host.Status = domain.DockerHostStatusOnline }()
+			u.CheckResources(ctx, now.Add(time.Minute))
+			assert.Equal(t, domain.AlertStatusFiring, silenced.Status)
+			repo.AssertNumberOfCalls(t, "UpdateHistory", 1)
+		})
+
+		t.Run("Deleting the rule resolves its alerts", func(t *testing.T) {
+			rules.Unset()
+			repo.On("ListRules", ctx).Return([]*domain.AlertRule{cpu}, nil)
+			repo.On("UpdateHistory", ctx, resolvedAlertOf(memory.ID, now.Add(2*time.Minute))).Return(nil).Once()
+
+			u.CheckResources(ctx, now.Add(2*time.Minute))
+			assert.Equal(t, domain.AlertStatusResolved, silenced.Status)
+			repo.AssertExpectations(t)
+			notifications.AssertNumberOfCalls(t, "SendNotification", 2)
+		})
+	})
+
+	t.Run("Firing alerts are restored after a restart", func(t *testing.T) {
+		deleted := &domain.AlertHistory{RuleID: uuid.NewString(), RuleName: "Gone", Scope: domain.AlertScopeContainer, Subject: "c1", Status: domain.AlertStatusFiring}
+		restartedRepo := new(MockAlertRepository)
+		restartedRepo.On("ListFiringHistory", ctx).Return([]*domain.AlertHistory{deleted}, nil).Once()
+		restartedRepo.On("ListRules", ctx).Return([]*domain.AlertRule{}, nil)
+		restartedRepo.On("ListSilences", ctx, mock.Anything).Return([]*domain.AlertSilence{}, nil)
+		restartedRepo.On("ListMaintenanceWindows", ctx).Return([]*domain.AlertMaintenanceWindow{}, nil)
+		restartedRepo.On("UpdateHistory", ctx, deleted).Return(nil).Once()
+
+		restarted := usecase.NewAlertUsecase(restartedRepo, mockClients, notifications, nil, nil, nil, nil, nil)
+		restarted.CheckResources(ctx, base.Add(10*time.Minute))
+		assert.Equal(t, domain.AlertStatusResolved, deleted.Status)
+		assert.NotNil(t, deleted.ResolvedAt)
+		restartedRepo.AssertExpectations(t)
+	})
+}
+
+// TestCreateRule tests validating alert rules
+func TestCreateRule(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success - Defaults", func(t *testing.T) {
+		repo := new(MockAlertRepository)
+		repo.On("CreateRule", ctx, mock.AnythingOfType("*domain.AlertRule")).Return(nil).Once()
+		u := usecase.NewAlertUsecase(repo, nil, nil, nil, nil, nil, nil, nil)
+
+		rule, err := u.CreateRule(ctx, domain.AlertRuleRequest{
+			Name: "Disk", Scope: domain.AlertScopeServer, Metric: domain.AlertMetricDisk, Operator: domain.AlertOperatorGT, Threshold: 90,
+		}, "user-1")
+		require.NoError(t, err)
+		assert.Equal(t, domain.AlertSeverityWarning, rule.Severity)
+		assert.True(t, rule.Enabled)
+		require.NotNil(t, rule.CreatedBy)
+		assert.Equal(t, "user-1", *rule.CreatedBy)
+		repo.AssertExpectations(t)
+	})
+
+	for name, tc := range map[string]struct {
+		req domain.AlertRuleRequest
+		err string
+	}{
+		"Metric of another scope": {
+			req: domain.AlertRuleRequest{Name: "r", Scope: domain.AlertScopeContainer, Metric: domain.AlertMetricDisk, Operator: domain.AlertOperatorGT},
+			err: "not available for container rules",
+		},
+		"Invalid duration": {
+			req: domain.AlertRuleRequest{Name: "r", Scope: domain.AlertScopeServer, Metric: domain.AlertMetricDisk, Operator: domain.AlertOperatorGT, Duration: "soon"},
+			err: "invalid duration",
+		},
+		"Label rule without selector": {
+			req: domain.AlertRuleRequest{Name: "r", Scope: domain.AlertScopeLabel, Metric: domain.AlertMetricCPU, Operator: domain.AlertOperatorGT},
+			err: "label selector",
+		},
+		"Invalid label selector": {
+			req: domain.AlertRuleRequest{Name: "r", Scope: domain.AlertScopeLabel, Target: "tier in (web", Metric: domain.AlertMetricCPU, Operator: domain.AlertOperatorGT},
+			err: "invalid label selector",
+		},
+		"Unknown operator": {
+			req: domain.AlertRuleRequest{Name: "r", Scope: domain.AlertScopeCluster, Metric: domain.AlertMetricNotReadyNodes, Operator: "above"},
+			err: "unknown operator",
+		},
+	} {
+		t.Run("Error - "+name, func(t *testing.T) {
+			repo := new(MockAlertRepository)
+			u := usecase.NewAlertUsecase(repo, nil, nil, nil, nil, nil, nil, nil)
+
+			_, err := u.CreateRule(ctx, tc.req, "")
+			assert.ErrorContains(t, err, tc.err)
+			repo.AssertNotCalled(t, "CreateRule", mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("Error - Silence without matchers", func(t *testing.T) {
+		repo := new(MockAlertRepository)
+		u := usecase.NewAlertUsecase(repo, nil, nil, nil, nil, nil, nil, nil)
+
+		_, err := u.CreateSilence(ctx, domain.AlertSilenceRequest{EndsAt: time.Now().Add(time.Hour)}, "")
+		assert.ErrorContains(t, err, "needs a rule, scope or subject")
+		repo.AssertNotCalled(t, "CreateSilence", mock.Anything, mock.Anything)
+	})
+}
@@ -93,3 +93,27 @@ func (u *auditUsecase) CleanupOldLogs(ctx context.Context, retentionDays int) er
 func (u *auditUsecase) ExportAuditLogs(ctx context.Context, filter domain.AuditFilter, format string) (string, error) {
 	return "", fmt.Errorf("not implemented")
 }
+
+// contextAuditLog builds an audit log entry as the user carried by ctx, or as SYSTEM when there is none.
+// opErr marks the entry failed and is recorded as its error.
+func contextAuditLog(ctx context.Context, action domain.AuditAction, resource, resourceID, description string, opErr error) *domain.AuditLog {
+	entry := &domain.AuditLog{
+		Username:    "SYSTEM",
+		Action:      action,
+		Resource:    resource,
+		ResourceID:  &resourceID,
+		Description: description,
+		Success:     opErr == nil,
+		CreatedAt:   time.Now(),
+	}
+	if userID, _ := ctx.Value("user_id").(string); userID != "" {
+		entry.UserID = &userID
+	}
+	if username, _ := ctx.Value("username").(string); username != "" {
+		entry.Username = username
+	}
+	if opErr != nil {
+		entry.ErrorMessage = opErr.Error()
+	}
+	return entry
+}
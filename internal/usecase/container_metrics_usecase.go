@@ -0,0 +1,439 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/repository"
+	"github.com/unitechio/einfra-be/pkg/docker"
+)
+
+// ContainerMetricsUsecase retains container resource usage for charts
+type ContainerMetricsUsecase interface {
+	// StartSampler samples every container of every active Docker host each minute until ctx is done
+	StartSampler(ctx context.Context)
+	// CollectMetrics stores one sample per running container as the 1m bucket of now, then rolls
+	// completed buckets up into coarser resolutions and prunes expired ones
+	CollectMetrics(ctx context.Context, now time.Time)
+	GetContainerMetrics(ctx context.Context, hostID, containerID string, query domain.ContainerMetricQuery) (*domain.ContainerMetricsResponse, error)
+	GetStackMetrics(ctx context.Context, stackID string, query domain.ContainerMetricQuery) (*domain.ContainerMetricsResponse, error)
+	GetHostMetrics(ctx context.Context, hostID string, query domain.ContainerMetricQuery) (*domain.ContainerMetricsResponse, error)
+}
+
+const (
+	// containerMetricSampleInterval is the size of the finest bucket; one sample is taken per bucket
+	containerMetricSampleInterval = time.Minute
+	// containerMetricConcurrency bounds the stats requests in flight per host; each takes about a second
+	// because the daemon waits for a second reading to compute CPU usage
+	containerMetricConcurrency = 8
+	// containerMetricMaxPoints bounds the buckets per container a range query returns
+	containerMetricMaxPoints = 1500
+	// defaultContainerMetricRange is queried when a request gives no start
+	defaultContainerMetricRange = time.Hour
+)
+
+// containerMetricRetention is how long buckets of each resolution are kept
+var containerMetricRetention = map[domain.ContainerMetricResolution]time.Duration{
+	domain.ContainerMetricResolution1m: 24 * time.Hour,
+	domain.ContainerMetricResolution5m: 7 * 24 * time.Hour,
+	domain.ContainerMetricResolution1h: 90 * 24 * time.Hour,
+}
+
+// containerMetricRollups lists the resolutions in rollup order, each built from the one before it
+var containerMetricRollups = []domain.ContainerMetricResolution{
+	domain.ContainerMetricResolution1m,
+	domain.ContainerMetricResolution5m,
+	domain.ContainerMetricResolution1h,
+}
+
+// containerIOCounters are the cumulative IO counters of a container at its last sample
+type containerIOCounters struct {
+	networkRx, networkTx, blockRead, blockWrite uint64
+	sampledAt                                   time.Time
+}
+
+type containerMetricsUsecase struct {
+	metricRepo repository.ContainerMetricRepository
+	stackRepo  repository.DockerStackRepository
+	clients    DockerClientRegistry
+
+	mu       sync.Mutex
+	counters map[string]containerIOCounters                 // Keyed by host and container ID
+	rolledUp map[domain.ContainerMetricResolution]time.Time // End of the last bucket rolled up per resolution
+}
+
+// NewContainerMetricsUsecase creates a new container metrics usecase
+func NewContainerMetricsUsecase(metricRepo repository.ContainerMetricRepository, stackRepo repository.DockerStackRepository, clients DockerClientRegistry) ContainerMetricsUsecase {
+	return &containerMetricsUsecase{
+		metricRepo: metricRepo,
+		stackRepo:  stackRepo,
+		clients:    clients,
+		counters:   make(map[string]containerIOCounters),
+		rolledUp:   make(map[domain.ContainerMetricResolution]time.Time),
+	}
+}
+
+// StartSampler samples every container of every active Docker host each minute until ctx is done.
+// Ticks are aligned to the minute so each sample lands in its own bucket.
+func (u *containerMetricsUsecase) StartSampler(ctx context.Context) {
+	now := time.Now()
+	select {
+	case <-time.After(now.Truncate(containerMetricSampleInterval).Add(containerMetricSampleInterval).Sub(now)):
+	case <-ctx.Done():
+		return
+	}
+
+	ticker := time.NewTicker(containerMetricSampleInterval)
+	defer ticker.Stop()
+
+	u.CollectMetrics(ctx, time.Now())
+	for {
+		select {
+		case now := <-ticker.C:
+			u.CollectMetrics(ctx, now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// CollectMetrics stores one sample per running container as the 1m bucket of now, then rolls
+// completed buckets up into coarser resolutions and prunes expired ones
+func (u *containerMetricsUsecase) CollectMetrics(ctx context.Context, now time.Time) {
+	now = now.UTC()
+	bucket := now.Truncate(containerMetricSampleInterval)
+
+	hosts, err := u.clients.ActiveHosts(ctx)
+	if err != nil {
+		log.Printf("Error listing docker hosts for metrics: %v", err)
+		return
+	}
+
+	// Hosts are sampled concurrently, so one slow daemon does not delay the others
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		metrics []*domain.ContainerMetric
+	)
+	for _, host := range hosts {
+		// Offline hosts are reported by the health check
+		if host.Status == domain.DockerHostStatusOffline {
+			continue
+		}
+		wg.Add(1)
+		go func(host *domain.DockerHost) {
+			defer wg.Done()
+			sampled, err := u.sampleHost(ctx, host.ID, bucket)
+			if err != nil {
+				log.Printf("Error sampling containers of docker host %s: %v", host.Name, err)
+				return
+			}
+			mu.Lock()
+			metrics = append(metrics, sampled...)
+			mu.Unlock()
+		}(host)
+	}
+	wg.Wait()
+
+	if err := u.metricRepo.SaveMetrics(ctx, metrics); err != nil {
+		log.Printf("Error saving container metrics: %v", err)
+	}
+	u.forgetStoppedContainers(bucket)
+
+	u.rollup(ctx, now)
+}
+
+// sampleHost reads the stats of the running containers of a host
+func (u *containerMetricsUsecase) sampleHost(ctx context.Context, hostID string, bucket time.Time) ([]*domain.ContainerMetric, error) {
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := client.ContainerList(ctx, false) // false = only running
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		metrics []*domain.ContainerMetric
+		slots   = make(chan struct{}, containerMetricConcurrency)
+	)
+	for _, c := range containers {
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		project := c.Labels[docker.ComposeProjectLabel]
+
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(containerID string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			stats, err := client.ContainerStatsOnce(ctx, containerID)
+			if err != nil {
+				// The container may have stopped since it was listed
+				log.Printf("Error getting stats for container %s: %v", name, err)
+				return
+			}
+			metric := u.newContainerMetric(hostID, containerID, name, project, bucket, stats)
+			mu.Lock()
+			metrics = append(metrics, metric)
+			mu.Unlock()
+		}(c.ID)
+	}
+	wg.Wait()
+	return metrics, nil
+}
+
+// newContainerMetric turns a stats sample into a 1m bucket. IO counters are cumulative, so the bucket
+// gets the difference to the previous sample; nothing is known about IO before the first one.
+func (u *containerMetricsUsecase) newContainerMetric(hostID, containerID, name, project string, bucket time.Time, stats *docker.ContainerStats) *domain.ContainerMetric {
+	current := containerIOCounters{sampledAt: bucket}
+	current.networkRx, current.networkTx = stats.NetworkIO()
+	current.blockRead, current.blockWrite = stats.BlockIO()
+
+	key := hostID + "/" + containerID
+	u.mu.Lock()
+	previous, seen := u.counters[key]
+	u.counters[key] = current
+	u.mu.Unlock()
+
+	cpu := stats.CPUPercent()
+	memory := stats.MemoryUsage()
+	metric := &domain.ContainerMetric{
+		HostID:        hostID,
+		ContainerID:   containerID,
+		Resolution:    domain.ContainerMetricResolution1m,
+		Timestamp:     bucket,
+		ContainerName: name,
+		Project:       project,
+		Samples:       1,
+		CPUPercent:    cpu,
+		CPUPercentMax: cpu,
+		MemoryUsage:   memory,
+		MemoryMax:     memory,
+		MemoryLimit:   stats.MemoryStats.Limit,
+	}
+	if seen {
+		metric.NetworkRx = counterDelta(previous.networkRx, current.networkRx)
+		metric.NetworkTx = counterDelta(previous.networkTx, current.networkTx)
+		metric.BlockRead = counterDelta(previous.blockRead, current.blockRead)
+		metric.BlockWrite = counterDelta(previous.blockWrite, current.blockWrite)
+	}
+	return metric
+}
+
+// counterDelta returns how much a cumulative counter grew; counters restart from zero with their container
+func counterDelta(previous, current uint64) uint64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+// forgetStoppedContainers drops the IO counters of containers that missed the last few samples
+func (u *containerMetricsUsecase) forgetStoppedContainers(bucket time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for key, counters := range u.counters {
+		if bucket.Sub(counters.sampledAt) > 5*containerMetricSampleInterval {
+			delete(u.counters, key)
+		}
+	}
+}
+
+// rollup aggregates the completed buckets of each resolution into the next coarser one and prunes
+// buckets past their retention. Buckets are replaced when rolled up again, e.g. after a restart.
+func (u *containerMetricsUsecase) rollup(ctx context.Context, now time.Time) {
+	for i := 1; i < len(containerMetricRollups); i++ {
+		source, target := containerMetricRollups[i-1], containerMetricRollups[i]
+		size := target.Duration()
+		end := now.Truncate(size)
+
+		u.mu.Lock()
+		last := u.rolledUp[target]
+		u.mu.Unlock()
+		if !end.After(last) {
+			continue
+		}
+
+		// Catch up on buckets missed while the sampler was stopped, as far as the source still has them
+		start := end.Add(-size)
+		if !last.IsZero() && last.Before(start) {
+			start = last
+		}
+		if oldest := now.Add(-containerMetricRetention[source]).Truncate(size); start.Before(oldest) {
+			start = oldest
+		}
+
+		metrics, err := u.metricRepo.ListMetrics(ctx, domain.ContainerMetricFilter{Resolution: source, From: start, To: end})
+		if err != nil {
+			log.Printf("Error listing %s container metrics for rollup: %v", source, err)
+			continue
+		}
+		if err := u.metricRepo.SaveMetrics(ctx, rollupContainerMetrics(metrics, target)); err != nil {
+			log.Printf("Error saving %s container metrics: %v", target, err)
+			continue
+		}
+
+		u.mu.Lock()
+		u.rolledUp[target] = end
+		u.mu.Unlock()
+
+		// The coarsest rollup runs hourly, a good pace for pruning
+		if i == len(containerMetricRollups)-1 {
+			u.prune(ctx, now)
+		}
+	}
+}
+
+func (u *containerMetricsUsecase) prune(ctx context.Context, now time.Time) {
+	for _, resolution := range containerMetricRollups {
+		if _, err := u.metricRepo.DeleteMetricsBefore(ctx, resolution, now.Add(-containerMetricRetention[resolution])); err != nil {
+			log.Printf("Error pruning %s container metrics: %v", resolution, err)
+		}
+	}
+}
+
+// rollupContainerMetrics aggregates buckets into buckets of a coarser resolution: averages are weighted
+// by samples, maxima kept, IO summed and the limit, name and project of the newest bucket used
+func rollupContainerMetrics(metrics []*domain.ContainerMetric, resolution domain.ContainerMetricResolution) []*domain.ContainerMetric {
+	size := resolution.Duration()
+	buckets := make(map[string]*domain.ContainerMetric)
+	latest := make(map[string]time.Time)
+	var rolled []*domain.ContainerMetric
+
+	for _, m := range metrics {
+		timestamp := m.Timestamp.Truncate(size)
+		key := m.HostID + "/" + m.ContainerID + "/" + timestamp.Format(time.RFC3339)
+		bucket, ok := buckets[key]
+		if !ok {
+			bucket = &domain.ContainerMetric{
+				HostID:      m.HostID,
+				ContainerID: m.ContainerID,
+				Resolution:  resolution,
+				Timestamp:   timestamp,
+			}
+			buckets[key] = bucket
+			rolled = append(rolled, bucket)
+		}
+
+		total := float64(bucket.Samples + m.Samples)
+		bucket.CPUPercent = (bucket.CPUPercent*float64(bucket.Samples) + m.CPUPercent*float64(m.Samples)) / total
+		bucket.MemoryUsage = uint64((float64(bucket.MemoryUsage)*float64(bucket.Samples) + float64(m.MemoryUsage)*float64(m.Samples)) / total)
+		bucket.Samples += m.Samples
+		bucket.CPUPercentMax = max(bucket.CPUPercentMax, m.CPUPercentMax)
+		bucket.MemoryMax = max(bucket.MemoryMax, m.MemoryMax)
+		bucket.NetworkRx += m.NetworkRx
+		bucket.NetworkTx += m.NetworkTx
+		bucket.BlockRead += m.BlockRead
+		bucket.BlockWrite += m.BlockWrite
+
+		if !m.Timestamp.Before(latest[key]) {
+			latest[key] = m.Timestamp
+			bucket.ContainerName = m.ContainerName
+			bucket.Project = m.Project
+			bucket.MemoryLimit = m.MemoryLimit
+		}
+	}
+	return rolled
+}
+
+// GetContainerMetrics returns the metrics of one container
+func (u *containerMetricsUsecase) GetContainerMetrics(ctx context.Context, hostID, containerID string, query domain.ContainerMetricQuery) (*domain.ContainerMetricsResponse, error) {
+	if hostID == "" || containerID == "" {
+		return nil, fmt.Errorf("host ID and container ID are required")
+	}
+	return u.queryMetrics(ctx, domain.ContainerMetricFilter{HostID: hostID, ContainerID: containerID}, query)
+}
+
+// GetStackMetrics returns the metrics of the containers of a stack
+func (u *containerMetricsUsecase) GetStackMetrics(ctx context.Context, stackID string, query domain.ContainerMetricQuery) (*domain.ContainerMetricsResponse, error) {
+	stack, err := u.stackRepo.GetByID(ctx, stackID)
+	if err != nil {
+		return nil, fmt.Errorf("stack not found: %w", err)
+	}
+	if stack.DockerHost == "" {
+		return nil, fmt.Errorf("metrics are only collected on registered Docker hosts, stack %s runs on the local daemon", stack.Name)
+	}
+	return u.queryMetrics(ctx, domain.ContainerMetricFilter{HostID: stack.DockerHost, Project: stack.ProjectName}, query)
+}
+
+// GetHostMetrics returns the metrics of every container of a host
+func (u *containerMetricsUsecase) GetHostMetrics(ctx context.Context, hostID string, query domain.ContainerMetricQuery) (*domain.ContainerMetricsResponse, error) {
+	if hostID == "" {
+		return nil, fmt.Errorf("host ID is required")
+	}
+	return u.queryMetrics(ctx, domain.ContainerMetricFilter{HostID: hostID}, query)
+}
+
+func (u *containerMetricsUsecase) queryMetrics(ctx context.Context, filter domain.ContainerMetricFilter, query domain.ContainerMetricQuery) (*domain.ContainerMetricsResponse, error) {
+	now := time.Now().UTC()
+	to := query.To
+	if to.IsZero() {
+		to = now
+	}
+	from := query.From
+	if from.IsZero() {
+		from = to.Add(-defaultContainerMetricRange)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	resolution := query.Resolution
+	if resolution == "" {
+		resolution = containerMetricResolutionFor(now, from, to)
+	}
+	size := resolution.Duration()
+	if size == 0 {
+		return nil, fmt.Errorf("unknown resolution %q", resolution)
+	}
+	if to.Sub(from)/size > containerMetricMaxPoints {
+		return nil, fmt.Errorf("range holds more than %d buckets of %s, use a coarser resolution", containerMetricMaxPoints, resolution)
+	}
+
+	filter.Resolution = resolution
+	filter.From = from.UTC().Truncate(size)
+	filter.To = to.UTC()
+	metrics, err := u.metricRepo.ListMetrics(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list metrics: %w", err)
+	}
+
+	// Metrics come ordered by container, so each series is a run of them
+	response := &domain.ContainerMetricsResponse{Resolution: resolution, From: filter.From, To: filter.To, Series: []domain.ContainerMetricSeries{}}
+	for _, m := range metrics {
+		n := len(response.Series)
+		if n == 0 || response.Series[n-1].HostID != m.HostID || response.Series[n-1].ContainerID != m.ContainerID {
+			response.Series = append(response.Series, domain.ContainerMetricSeries{HostID: m.HostID, ContainerID: m.ContainerID})
+			n++
+		}
+		series := &response.Series[n-1]
+		series.ContainerName = m.ContainerName
+		series.Project = m.Project
+		series.Points = append(series.Points, m)
+	}
+	return response, nil
+}
+
+// containerMetricResolutionFor picks the finest resolution still retained at from that keeps the range
+// within a few hundred points
+func containerMetricResolutionFor(now, from, to time.Time) domain.ContainerMetricResolution {
+	for _, resolution := range containerMetricRollups {
+		if now.Sub(from) <= containerMetricRetention[resolution] && to.Sub(from)/resolution.Duration() <= 360 {
+			return resolution
+		}
+	}
+	return domain.ContainerMetricResolution1h
+}
@@ -0,0 +1,216 @@
+package usecase_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/docker"
+)
+
+// MockContainerMetricRepository is a mock implementation of ContainerMetricRepository
+type MockContainerMetricRepository struct {
+	mock.Mock
+}
+
+func (m *MockContainerMetricRepository) SaveMetrics(ctx context.Context, metrics []*domain.ContainerMetric) error {
+	args := m.Called(ctx, metrics)
+	return args.Error(0)
+}
+
+func (m *MockContainerMetricRepository) ListMetrics(ctx context.Context, filter domain.ContainerMetricFilter) ([]*domain.ContainerMetric, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.ContainerMetric), args.Error(1)
+}
+
+func (m *MockContainerMetricRepository) DeleteMetricsBefore(ctx context.Context, resolution domain.ContainerMetricResolution, before time.Time) (int64, error) {
+	args := m.Called(ctx, resolution, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// containerMetricsOf matches a non-empty batch of buckets of one resolution
+func containerMetricsOf(resolution domain.ContainerMetricResolution) interface{} {
+	return mock.MatchedBy(func(metrics []*domain.ContainerMetric) bool {
+		return len(metrics) > 0 && metrics[0].Resolution == resolution
+	})
+}
+
+// newStatsDockerDaemon serves one running compose container whose cumulative network counter is read
+// from rx on every stats request
+func newStatsDockerDaemon(t *testing.T, rx *uint64) *docker.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Api-Version", "1.47")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_ping"):
+			w.Write([]byte("OK"))
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			w.Write([]byte(`[{"Id":"c1","Names":["/shop-web-1"],"Labels":{"com.docker.compose.project":"shop"}}]`))
+		case strings.HasSuffix(r.URL.Path, "/containers/c1/stats"):
+			// 0.2s of CPU over 1s of system time on 2 CPUs: 40%; 300MiB used of which 100MiB cache
+			fmt.Fprintf(w, `{
+				"cpu_stats":{"cpu_usage":{"total_usage":1200000000},"system_cpu_usage":11000000000,"online_cpus":2},
+				"precpu_stats":{"cpu_usage":{"total_usage":1000000000},"system_cpu_usage":10000000000},
+				"memory_stats":{"usage":314572800,"limit":1073741824,"stats":{"cache":104857600}},
+				"networks":{"eth0":{"rx_bytes":%d,"tx_bytes":0}},
+				"blkio_stats":{"io_service_bytes_recursive":[{"op":"Read","value":4096},{"op":"Write","value":8192}]}
+			}`, *rx)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := docker.NewClient("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// TestCollectMetrics tests sampling containers and rolling completed buckets up
+func TestCollectMetrics(t *testing.T) {
+	repo := new(MockContainerMetricRepository)
+	mockClients := new(MockDockerClientRegistry)
+	u := usecase.NewContainerMetricsUsecase(repo, new(MockDockerStackRepository), mockClients)
+	ctx := context.Background()
+
+	var rx uint64
+	client := newStatsDockerDaemon(t, &rx)
+	mockClients.On("ActiveHosts", ctx).Return([]*domain.DockerHost{
+		{ID: "host-1", Name: "edge", Status: domain.DockerHostStatusOnline},
+		{ID: "host-2", Name: "down", Status: domain.DockerHostStatusOffline},
+	}, nil)
+	mockClients.On("Get", ctx, "host-1").Return(client, nil)
+
+	var samples []*domain.ContainerMetric
+	repo.On("SaveMetrics", ctx, containerMetricsOf(domain.ContainerMetricResolution1m)).Run(func(args mock.Arguments) {
+		samples = append(samples, args.Get(1).([]*domain.ContainerMetric)...)
+	}).Return(nil)
+	noMetrics := mock.MatchedBy(func(metrics []*domain.ContainerMetric) bool { return len(metrics) == 0 })
+
+	// The first run rolls up the buckets before it, which are empty, and prunes every resolution
+	base := time.Date(2026, 10, 18, 10, 3, 30, 0, time.UTC)
+	repo.On("ListMetrics", ctx, domain.ContainerMetricFilter{
+		Resolution: domain.ContainerMetricResolution1m, From: base.Truncate(5 * time.Minute).Add(-5 * time.Minute), To: base.Truncate(5 * time.Minute),
+	}).Return(nil, nil).Once()
+	repo.On("ListMetrics", ctx, domain.ContainerMetricFilter{
+		Resolution: domain.ContainerMetricResolution5m, From: base.Truncate(time.Hour).Add(-time.Hour), To: base.Truncate(time.Hour),
+	}).Return(nil, nil).Once()
+	repo.On("SaveMetrics", ctx, noMetrics).Return(nil).Twice()
+	repo.On("DeleteMetricsBefore", ctx, mock.Anything, mock.Anything).Return(int64(0), nil).Times(3)
+
+	for i, counter := range []uint64{1000, 4000} {
+		rx = counter
+		u.CollectMetrics(ctx, base.Add(time.Duration(i)*time.Minute))
+	}
+	require.Len(t, samples, 2)
+	assert.Equal(t, "shop-web-1", samples[0].ContainerName)
+	assert.Equal(t, "shop", samples[0].Project)
+	assert.Equal(t, time.Date(2026, 10, 18, 10, 3, 0, 0, time.UTC), samples[0].Timestamp)
+	assert.InDelta(t, 40.0, samples[0].CPUPercent, 0.001)
+	assert.Equal(t, uint64(200*1024*1024), samples[0].MemoryUsage)
+	// Counters are cumulative; the first sample has nothing to compare with
+	assert.Equal(t, uint64(0), samples[0].NetworkRx)
+	assert.Equal(t, uint64(3000), samples[1].NetworkRx)
+	assert.Equal(t, uint64(0), samples[1].BlockRead)
+
+	// Crossing 10:05 completes the 10:00 bucket of 5m
+	repo.On("ListMetrics", ctx, domain.ContainerMetricFilter{
+		Resolution: domain.ContainerMetricResolution1m, From: time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC), To: time.Date(2026, 10, 18, 10, 5, 0, 0, time.UTC),
+	}).Return(samples[:2], nil).Once()
+	var rollup []*domain.ContainerMetric
+	repo.On("SaveMetrics", ctx, containerMetricsOf(domain.ContainerMetricResolution5m)).Run(func(args mock.Arguments) {
+		rollup = args.Get(1).([]*domain.ContainerMetric)
+	}).Return(nil).Once()
+
+	rx = 5000
+	u.CollectMetrics(ctx, base.Add(2*time.Minute))
+	repo.AssertExpectations(t)
+	require.Len(t, samples, 3)
+	assert.Equal(t, uint64(1000), samples[2].NetworkRx)
+
+	require.Len(t, rollup, 1)
+	assert.Equal(t, time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC), rollup[0].Timestamp)
+	assert.Equal(t, 2, rollup[0].Samples)
+	assert.Equal(t, uint64(3000), rollup[0].NetworkRx)
+	assert.InDelta(t, 40.0, rollup[0].CPUPercentMax, 0.001)
+}
+
+// TestGetContainerMetrics tests querying stored buckets as series
+func TestGetContainerMetrics(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	query := domain.ContainerMetricQuery{From: base, To: base.Add(time.Hour), Resolution: domain.ContainerMetricResolution1m}
+	bucket := func(containerID, name string, minute int) *domain.ContainerMetric {
+		return &domain.ContainerMetric{
+			HostID: "host-1", ContainerID: containerID, ContainerName: name, Project: "shop",
+			Resolution: domain.ContainerMetricResolution1m, Timestamp: base.Add(time.Duration(minute) * time.Minute), Samples: 1,
+		}
+	}
+	stored := []*domain.ContainerMetric{bucket("c1", "shop-web-1", 0), bucket("c1", "shop-web-1", 1), bucket("c2", "shop-db-1", 0)}
+
+	t.Run("Success - Container", func(t *testing.T) {
+		repo := new(MockContainerMetricRepository)
+		repo.On("ListMetrics", ctx, domain.ContainerMetricFilter{
+			HostID: "host-1", ContainerID: "c1", Resolution: domain.ContainerMetricResolution1m, From: base, To: base.Add(time.Hour),
+		}).Return(stored[:2], nil)
+		u := usecase.NewContainerMetricsUsecase(repo, new(MockDockerStackRepository), new(MockDockerClientRegistry))
+
+		metrics, err := u.GetContainerMetrics(ctx, "host-1", "c1", query)
+		require.NoError(t, err)
+		require.Len(t, metrics.Series, 1)
+		assert.Equal(t, "shop-web-1", metrics.Series[0].ContainerName)
+		assert.Equal(t, "shop", metrics.Series[0].Project)
+		assert.Len(t, metrics.Series[0].Points, 2)
+	})
+
+	t.Run("Success - Stack", func(t *testing.T) {
+		repo := new(MockContainerMetricRepository)
+		repo.On("ListMetrics", ctx, domain.ContainerMetricFilter{
+			HostID: "host-1", Project: "shop", Resolution: domain.ContainerMetricResolution1m, From: base, To: base.Add(time.Hour),
+		}).Return(stored, nil)
+		mockStackRepo := new(MockDockerStackRepository)
+		mockStackRepo.On("GetByID", ctx, "stack-1").Return(&domain.DockerStack{ID: "stack-1", Name: "shop", ProjectName: "shop", DockerHost: "host-1"}, nil)
+		u := usecase.NewContainerMetricsUsecase(repo, mockStackRepo, new(MockDockerClientRegistry))
+
+		metrics, err := u.GetStackMetrics(ctx, "stack-1", query)
+		require.NoError(t, err)
+		require.Len(t, metrics.Series, 2)
+		assert.Equal(t, "c1", metrics.Series[0].ContainerID)
+		assert.Equal(t, "c2", metrics.Series[1].ContainerID)
+	})
+
+	t.Run("Error - Stack without host", func(t *testing.T) {
+		mockStackRepo := new(MockDockerStackRepository)
+		mockStackRepo.On("GetByID", ctx, "stack-2").Return(&domain.DockerStack{ID: "stack-2", Name: "local", ProjectName: "local"}, nil)
+		u := usecase.NewContainerMetricsUsecase(new(MockContainerMetricRepository), mockStackRepo, new(MockDockerClientRegistry))
+
+		_, err := u.GetStackMetrics(ctx, "stack-2", query)
+		assert.Error(t, err)
+	})
+
+	t.Run("Error - Range too long for resolution", func(t *testing.T) {
+		repo := new(MockContainerMetricRepository)
+		u := usecase.NewContainerMetricsUsecase(repo, new(MockDockerStackRepository), new(MockDockerClientRegistry))
+
+		_, err := u.GetHostMetrics(ctx, "host-1", domain.ContainerMetricQuery{
+			From:       base.Add(-30 * 24 * time.Hour),
+			To:         base,
+			Resolution: domain.ContainerMetricResolution1m,
+		})
+		assert.ErrorContains(t, err, "coarser resolution")
+		repo.AssertNotCalled(t, "ListMetrics", mock.Anything, mock.Anything)
+	})
+}
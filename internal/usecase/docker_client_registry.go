@@ -0,0 +1,260 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/docker"
+	"github.com/unitechio/einfra-be/pkg/ssh"
+)
+
+const (
+	// dockerHealthCheckInterval is how often the daemons of active hosts are checked
+	dockerHealthCheckInterval = time.Minute
+	// dockerHealthCheckTimeout bounds a single daemon check
+	dockerHealthCheckTimeout = 10 * time.Second
+	// localDockerHostID keys the client of the local daemon, used when requests name no host
+	localDockerHostID = "local"
+)
+
+// DockerClientRegistry resolves Docker hosts to cached clients of their daemons
+type DockerClientRegistry interface {
+	// Get returns the client of an active host; an empty host ID selects the local daemon
+	Get(ctx context.Context, hostID string) (*docker.Client, error)
+	// Refresh checks the daemon of a host and records its status and version
+	Refresh(ctx context.Context, hostID string) (*domain.DockerHost, error)
+	// Invalidate drops the cached client of a host, e.g. after its endpoint changes; requests and
+	// streams still using it finish before it is closed
+	Invalidate(hostID string)
+	// ActiveHosts lists the hosts that are not deactivated
+	ActiveHosts(ctx context.Context) ([]*domain.DockerHost, error)
+	// StartHealthCheck periodically refreshes every active host until ctx is done
+	StartHealthCheck(ctx context.Context)
+}
+
+type dockerClientRegistry struct {
+	dockerRepo    domain.DockerHostRepository
+	serverRepo    domain.ServerRepository
+	clients       *docker.ClientManager
+	localEndpoint string
+}
+
+// NewDockerClientRegistry creates a new Docker client registry
+func NewDockerClientRegistry(
+	dockerRepo domain.DockerHostRepository,
+	serverRepo domain.ServerRepository,
+	clients *docker.ClientManager,
+	localEndpoint string,
+) DockerClientRegistry {
+	return &dockerClientRegistry{
+		dockerRepo:    dockerRepo,
+		serverRepo:    serverRepo,
+		clients:       clients,
+		localEndpoint: localEndpoint,
+	}
+}
+
+func (r *dockerClientRegistry) Get(ctx context.Context, hostID string) (*docker.Client, error) {
+	if hostID == "" {
+		if r.localEndpoint == "" {
+			return nil, errors.New("docker host ID is required")
+		}
+		client, err := r.clients.Get(localDockerHostID, docker.Config{Host: r.localEndpoint})
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to local docker daemon: %w", err)
+		}
+		return client, nil
+	}
+
+	_, client, err := r.connect(ctx, hostID)
+	return client, err
+}
+
+func (r *dockerClientRegistry) connect(ctx context.Context, hostID string) (*domain.DockerHost, *docker.Client, error) {
+	host, err := r.dockerRepo.GetByID(ctx, hostID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if host == nil {
+		return nil, nil, errors.New("docker host not found")
+	}
+	if !host.IsActive {
+		return host, nil, fmt.Errorf("docker host %s is not active", host.Name)
+	}
+
+	// The cached client carries the server's SSH credentials, so they are only loaded and decrypted
+	// again after the client was dropped
+	if client, ok := r.clients.Lookup(host.ID); ok {
+		return host, client, nil
+	}
+
+	cfg, err := r.hostConfig(ctx, host)
+	if err != nil {
+		return host, nil, err
+	}
+
+	client, err := r.clients.Get(host.ID, cfg)
+	if err != nil {
+		return host, nil, fmt.Errorf("failed to connect to docker host %s: %w", host.Name, err)
+	}
+	return host, client, nil
+}
+
+// hostConfig builds the client configuration of a host. ssh:// endpoints tunnel through the
+// linked server with its SSH credentials; user, host and port given in the endpoint take precedence.
+func (r *dockerClientRegistry) hostConfig(ctx context.Context, host *domain.DockerHost) (docker.Config, error) {
+	cfg := docker.Config{
+		Host:       host.Endpoint,
+		TLSEnabled: host.TLSEnabled,
+		CertPath:   host.CertPath,
+	}
+
+	endpoint, err := url.Parse(host.Endpoint)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid endpoint of docker host %s: %w", host.Name, err)
+	}
+	if endpoint.Scheme != "ssh" {
+		return cfg, nil
+	}
+
+	if host.ServerID == nil || *host.ServerID == "" {
+		return cfg, fmt.Errorf("docker host %s uses an ssh endpoint but is not linked to a server", host.Name)
+	}
+	server, err := r.serverRepo.GetByID(ctx, *host.ServerID)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to load server of docker host %s: %w", host.Name, err)
+	}
+	if server == nil {
+		return cfg, fmt.Errorf("server of docker host %s not found", host.Name)
+	}
+
+	sshConfig := ssh.Config{
+		Host:     server.IPAddress,
+		Port:     server.SSHPort,
+		User:     server.SSHUser,
+		Password: server.SSHPassword,
+		KeyPath:  server.SSHKeyPath,
+	}
+	if hostname := endpoint.Hostname(); hostname != "" {
+		sshConfig.Host = hostname
+	}
+	if port := endpoint.Port(); port != "" {
+		if sshConfig.Port, err = strconv.Atoi(port); err != nil {
+			return cfg, fmt.Errorf("invalid ssh port of docker host %s: %w", host.Name, err)
+		}
+	}
+	if user := endpoint.User.Username(); user != "" {
+		sshConfig.User = user
+	}
+	if sshConfig.Port == 0 {
+		sshConfig.Port = 22
+	}
+	cfg.SSH = &sshConfig
+	return cfg, nil
+}
+
+func (r *dockerClientRegistry) Refresh(ctx context.Context, hostID string) (*domain.DockerHost, error) {
+	if hostID == "" {
+		return nil, errors.New("docker host ID is required")
+	}
+
+	host, client, err := r.connect(ctx, hostID)
+	if host == nil {
+		return nil, err
+	}
+	if !host.IsActive {
+		return host, err
+	}
+
+	version := ""
+	if err == nil {
+		checkCtx, cancel := context.WithTimeout(ctx, dockerHealthCheckTimeout)
+		version, err = client.ServerVersion(checkCtx)
+		cancel()
+	}
+
+	now := time.Now()
+	host.Status = domain.DockerHostStatusOnline
+	host.LastError = ""
+	if err != nil {
+		// Reconnect on the next request, the tunnel or endpoint may have changed
+		r.Invalidate(host.ID)
+		host.Status = domain.DockerHostStatusOffline
+		host.LastError = err.Error()
+	}
+	if version != "" {
+		host.Version = version
+	}
+	host.LastCheckedAt = &now
+
+	if err := r.dockerRepo.UpdateHealth(ctx, host.ID, host.Status, version, host.LastError, now); err != nil {
+		return nil, fmt.Errorf("failed to record health of docker host %s: %w", host.Name, err)
+	}
+	return host, nil
+}
+
+func (r *dockerClientRegistry) Invalidate(hostID string) {
+	r.clients.Remove(hostID)
+}
+
+func (r *dockerClientRegistry) ActiveHosts(ctx context.Context) ([]*domain.DockerHost, error) {
+	active := true
+	filter := domain.DockerHostFilter{IsActive: &active, Page: 1, PageSize: 100}
+	var hosts []*domain.DockerHost
+	for {
+		page, total, err := r.dockerRepo.List(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, page...)
+		if len(page) == 0 || int64(filter.Page*filter.PageSize) >= total {
+			return hosts, nil
+		}
+		filter.Page++
+	}
+}
+
+func (r *dockerClientRegistry) StartHealthCheck(ctx context.Context) {
+	r.checkHosts(ctx)
+
+	ticker := time.NewTicker(dockerHealthCheckInterval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				r.checkHosts(ctx)
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// checkHosts refreshes every active host concurrently, so one unreachable daemon does not delay the others
+func (r *dockerClientRegistry) checkHosts(ctx context.Context) {
+	hosts, err := r.ActiveHosts(ctx)
+	if err != nil {
+		log.Printf("Failed to list docker hosts for health check: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if _, err := r.Refresh(ctx, id); err != nil {
+				log.Printf("Failed to check docker host %s: %v", id, err)
+			}
+		}(host.ID)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,170 @@
+package usecase_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/docker"
+)
+
+// newVersionDockerDaemon serves a Docker API that only answers pings and version requests
+func newVersionDockerDaemon(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Api-Version", "1.47")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_ping"):
+			w.Write([]byte("OK"))
+		case strings.HasSuffix(r.URL.Path, "/version"):
+			w.Write([]byte(`{"Version":"27.3.1","ApiVersion":"1.47"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "tcp://" + strings.TrimPrefix(server.URL, "http://")
+}
+
+func newTestDockerClientRegistry(t *testing.T) (usecase.DockerClientRegistry, *MockDockerHostRepository, *MockServerRepository) {
+	dockerRepo := new(MockDockerHostRepository)
+	serverRepo := new(MockServerRepository)
+	clients := docker.NewClientManager()
+	t.Cleanup(clients.CloseAll)
+	return usecase.NewDockerClientRegistry(dockerRepo, serverRepo, clients, ""), dockerRepo, serverRepo
+}
+
+// TestDockerClientRegistryGet tests resolving hosts to clients
+func TestDockerClientRegistryGet(t *testing.T) {
+	ctx := context.Background()
+	serverID := "server-1"
+
+	t.Run("Success - SSH credentials are loaded once per client", func(t *testing.T) {
+		registry, dockerRepo, serverRepo := newTestDockerClientRegistry(t)
+		dockerRepo.On("GetByID", ctx, "host-1").Return(&domain.DockerHost{
+			ID: "host-1", Name: "edge", Endpoint: "ssh://edge.example.com", ServerID: &serverID, IsActive: true,
+		}, nil)
+		serverRepo.On("GetByID", ctx, serverID).Return(&domain.Server{
+			IPAddress: "10.0.0.5", SSHPort: 22, SSHUser: "deploy", SSHPassword: "secret",
+		}, nil)
+
+		first, err := registry.Get(ctx, "host-1")
+		require.NoError(t, err)
+		second, err := registry.Get(ctx, "host-1")
+		require.NoError(t, err)
+
+		assert.Same(t, first, second)
+		serverRepo.AssertNumberOfCalls(t, "GetByID", 1)
+	})
+
+	t.Run("Error - Inactive host", func(t *testing.T) {
+		registry, dockerRepo, serverRepo := newTestDockerClientRegistry(t)
+		dockerRepo.On("GetByID", ctx, "host-1").Return(&domain.DockerHost{
+			ID: "host-1", Name: "edge", Endpoint: "tcp://10.0.0.5:2375", IsActive: false,
+		}, nil)
+
+		client, err := registry.Get(ctx, "host-1")
+		assert.Error(t, err)
+		assert.Nil(t, client)
+		serverRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error - SSH endpoint without server", func(t *testing.T) {
+		registry, dockerRepo, _ := newTestDockerClientRegistry(t)
+		dockerRepo.On("GetByID", ctx, "host-1").Return(&domain.DockerHost{
+			ID: "host-1", Name: "edge", Endpoint: "ssh://edge.example.com", IsActive: true,
+		}, nil)
+
+		_, err := registry.Get(ctx, "host-1")
+		assert.ErrorContains(t, err, "not linked to a server")
+	})
+
+	t.Run("Error - No host and no local daemon", func(t *testing.T) {
+		registry, _, _ := newTestDockerClientRegistry(t)
+
+		_, err := registry.Get(ctx, "")
+		assert.Error(t, err)
+	})
+}
+
+// TestDockerClientRegistryRefresh tests recording the health of hosts
+func TestDockerClientRegistryRefresh(t *testing.T) {
+	ctx := context.Background()
+	serverID := "server-1"
+
+	t.Run("Success - Online host", func(t *testing.T) {
+		registry, dockerRepo, _ := newTestDockerClientRegistry(t)
+		dockerRepo.On("GetByID", ctx, "host-1").Return(&domain.DockerHost{
+			ID: "host-1", Name: "build", Endpoint: newVersionDockerDaemon(t), IsActive: true,
+		}, nil)
+		dockerRepo.On("UpdateHealth", ctx, "host-1", domain.DockerHostStatusOnline, "27.3.1", "", mock.Anything).Return(nil)
+
+		host, err := registry.Refresh(ctx, "host-1")
+		require.NoError(t, err)
+		assert.Equal(t, domain.DockerHostStatusOnline, host.Status)
+		assert.Equal(t, "27.3.1", host.Version)
+		assert.NotNil(t, host.LastCheckedAt)
+		dockerRepo.AssertExpectations(t)
+	})
+
+	t.Run("Success - Unreachable host is marked offline and reconnected on the next request", func(t *testing.T) {
+		registry, dockerRepo, serverRepo := newTestDockerClientRegistry(t)
+		dockerRepo.On("GetByID", ctx, "host-1").Return(&domain.DockerHost{
+			ID: "host-1", Name: "edge", Endpoint: "ssh://127.0.0.1:1", ServerID: &serverID, IsActive: true,
+		}, nil)
+		serverRepo.On("GetByID", ctx, serverID).Return(&domain.Server{SSHUser: "deploy", SSHPassword: "secret"}, nil)
+		dockerRepo.On("UpdateHealth", ctx, "host-1", domain.DockerHostStatusOffline, "", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+
+		stale, err := registry.Get(ctx, "host-1")
+		require.NoError(t, err)
+
+		host, err := registry.Refresh(ctx, "host-1")
+		require.NoError(t, err)
+		assert.Equal(t, domain.DockerHostStatusOffline, host.Status)
+		assert.NotEmpty(t, host.LastError)
+
+		fresh, err := registry.Get(ctx, "host-1")
+		require.NoError(t, err)
+		assert.NotSame(t, stale, fresh)
+		serverRepo.AssertNumberOfCalls(t, "GetByID", 2)
+	})
+
+	t.Run("Success - Inactive host is not checked", func(t *testing.T) {
+		registry, dockerRepo, _ := newTestDockerClientRegistry(t)
+		dockerRepo.On("GetByID", ctx, "host-1").Return(&domain.DockerHost{ID: "host-1", Name: "edge", IsActive: false}, nil)
+
+		host, err := registry.Refresh(ctx, "host-1")
+		assert.Error(t, err)
+		assert.False(t, host.IsActive)
+		dockerRepo.AssertNotCalled(t, "UpdateHealth", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// TestDockerClientRegistryActiveHosts tests listing every page of active hosts
+func TestDockerClientRegistryActiveHosts(t *testing.T) {
+	ctx := context.Background()
+	active := true
+	registry, dockerRepo, _ := newTestDockerClientRegistry(t)
+
+	var first, second []*domain.DockerHost
+	for i := 0; i < 100; i++ {
+		first = append(first, &domain.DockerHost{ID: fmt.Sprintf("host-%d", i)})
+	}
+	second = append(second, &domain.DockerHost{ID: "host-100"})
+	dockerRepo.On("List", ctx, domain.DockerHostFilter{IsActive: &active, Page: 1, PageSize: 100}).Return(first, int64(101), nil).Once()
+	dockerRepo.On("List", ctx, domain.DockerHostFilter{IsActive: &active, Page: 2, PageSize: 100}).Return(second, int64(101), nil).Once()
+
+	hosts, err := registry.ActiveHosts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, hosts, 101)
+	assert.Equal(t, "host-100", hosts[100].ID)
+	dockerRepo.AssertExpectations(t)
+}
@@ -9,26 +9,26 @@ import (
 
 // DockerExecUsecase handles container exec operations
 type DockerExecUsecase interface {
-	CreateExec(ctx context.Context, containerID string, cmd []string, tty bool) (string, error)
-	StartExec(ctx context.Context, execID string, tty bool) ([]byte, error)
-	InspectExec(ctx context.Context, execID string) (map[string]interface{}, error)
-	ResizeExec(ctx context.Context, execID string, height, width uint) error
-	ExecuteCommand(ctx context.Context, containerID string, cmd []string) (*docker.ExecResult, error)
+	CreateExec(ctx context.Context, hostID, containerID string, cmd []string, tty bool) (string, error)
+	StartExec(ctx context.Context, hostID, execID string, tty bool) ([]byte, error)
+	InspectExec(ctx context.Context, hostID, execID string) (map[string]interface{}, error)
+	ResizeExec(ctx context.Context, hostID, execID string, height, width uint) error
+	ExecuteCommand(ctx context.Context, hostID, containerID string, cmd []string) (*docker.ExecResult, error)
 }
 
 type dockerExecUsecase struct {
-	dockerClient *docker.Client
+	clients DockerClientRegistry
 }
 
 // NewDockerExecUsecase creates a new docker exec usecase
-func NewDockerExecUsecase(dockerClient *docker.Client) DockerExecUsecase {
+func NewDockerExecUsecase(clients DockerClientRegistry) DockerExecUsecase {
 	return &dockerExecUsecase{
-		dockerClient: dockerClient,
+		clients: clients,
 	}
 }
 
 // CreateExec creates an exec instance in a container
-func (u *dockerExecUsecase) CreateExec(ctx context.Context, containerID string, cmd []string, tty bool) (string, error) {
+func (u *dockerExecUsecase) CreateExec(ctx context.Context, hostID, containerID string, cmd []string, tty bool) (string, error) {
 	if containerID == "" {
 		return "", fmt.Errorf("container ID is required")
 	}
@@ -46,7 +46,12 @@ func (u *dockerExecUsecase) CreateExec(ctx context.Context, containerID string,
 		Tty:          tty,
 	}
 
-	execID, err := u.dockerClient.ContainerExec(ctx, config)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return "", err
+	}
+
+	execID, err := client.ContainerExec(ctx, config)
 	if err != nil {
 		return "", fmt.Errorf("failed to create exec: %w", err)
 	}
@@ -55,7 +60,7 @@ func (u *dockerExecUsecase) CreateExec(ctx context.Context, containerID string,
 }
 
 // StartExec starts an exec instance
-func (u *dockerExecUsecase) StartExec(ctx context.Context, execID string, tty bool) ([]byte, error) {
+func (u *dockerExecUsecase) StartExec(ctx context.Context, hostID, execID string, tty bool) ([]byte, error) {
 	if execID == "" {
 		return nil, fmt.Errorf("exec ID is required")
 	}
@@ -65,7 +70,12 @@ func (u *dockerExecUsecase) StartExec(ctx context.Context, execID string, tty bo
 		Tty:    tty,
 	}
 
-	reader, err := u.dockerClient.ExecStart(ctx, execID, config)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := client.ExecStart(ctx, execID, config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start exec: %w", err)
 	}
@@ -88,18 +98,23 @@ func (u *dockerExecUsecase) StartExec(ctx context.Context, execID string, tty bo
 }
 
 // InspectExec returns information about an exec instance
-func (u *dockerExecUsecase) InspectExec(ctx context.Context, execID string) (map[string]interface{}, error) {
+func (u *dockerExecUsecase) InspectExec(ctx context.Context, hostID, execID string) (map[string]interface{}, error) {
 	if execID == "" {
 		return nil, fmt.Errorf("exec ID is required")
 	}
 
-	inspect, err := u.dockerClient.ExecInspect(ctx, execID)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	inspect, err := client.ExecInspect(ctx, execID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to inspect exec: %w", err)
 	}
 
 	result := map[string]interface{}{
-		"id":           inspect.ID,
+		"id":           inspect.ExecID,
 		"running":      inspect.Running,
 		"exit_code":    inspect.ExitCode,
 		"pid":          inspect.Pid,
@@ -110,7 +125,7 @@ func (u *dockerExecUsecase) InspectExec(ctx context.Context, execID string) (map
 }
 
 // ResizeExec resizes the TTY of an exec instance
-func (u *dockerExecUsecase) ResizeExec(ctx context.Context, execID string, height, width uint) error {
+func (u *dockerExecUsecase) ResizeExec(ctx context.Context, hostID, execID string, height, width uint) error {
 	if execID == "" {
 		return fmt.Errorf("exec ID is required")
 	}
@@ -119,7 +134,12 @@ func (u *dockerExecUsecase) ResizeExec(ctx context.Context, execID string, heigh
 		return fmt.Errorf("height and width must be greater than 0")
 	}
 
-	if err := u.dockerClient.ExecResize(ctx, execID, height, width); err != nil {
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return err
+	}
+
+	if err := client.ExecResize(ctx, execID, height, width); err != nil {
 		return fmt.Errorf("failed to resize exec: %w", err)
 	}
 
@@ -127,7 +147,7 @@ func (u *dockerExecUsecase) ResizeExec(ctx context.Context, execID string, heigh
 }
 
 // ExecuteCommand executes a simple command and returns the result
-func (u *dockerExecUsecase) ExecuteCommand(ctx context.Context, containerID string, cmd []string) (*docker.ExecResult, error) {
+func (u *dockerExecUsecase) ExecuteCommand(ctx context.Context, hostID, containerID string, cmd []string) (*docker.ExecResult, error) {
 	if containerID == "" {
 		return nil, fmt.Errorf("container ID is required")
 	}
@@ -136,7 +156,12 @@ func (u *dockerExecUsecase) ExecuteCommand(ctx context.Context, containerID stri
 		return nil, fmt.Errorf("command is required")
 	}
 
-	result, err := u.dockerClient.ContainerExecSimple(ctx, containerID, cmd)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := client.ContainerExecSimple(ctx, containerID, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute command: %w", err)
 	}
@@ -10,26 +10,26 @@ import (
 
 // DockerImageUsecase handles Docker image operations
 type DockerImageUsecase interface {
-	BuildImage(ctx context.Context, dockerfile string, context io.Reader, tags []string) (io.ReadCloser, error)
-	PushImage(ctx context.Context, image string, authConfig docker.AuthConfig) (io.ReadCloser, error)
-	PullImage(ctx context.Context, image string, authConfig docker.AuthConfig) (io.ReadCloser, error)
-	RemoveImage(ctx context.Context, imageID string, force bool) ([]string, error)
-	InspectImage(ctx context.Context, imageID string) (map[string]interface{}, error)
+	BuildImage(ctx context.Context, hostID, dockerfile string, context io.Reader, tags []string) (io.ReadCloser, error)
+	PushImage(ctx context.Context, hostID, image string, authConfig docker.AuthConfig) (io.ReadCloser, error)
+	PullImage(ctx context.Context, hostID, image string, authConfig docker.AuthConfig) (io.ReadCloser, error)
+	RemoveImage(ctx context.Context, hostID, imageID string, force bool) ([]string, error)
+	InspectImage(ctx context.Context, hostID, imageID string) (map[string]interface{}, error)
 }
 
 type dockerImageUsecase struct {
-	dockerClient *docker.Client
+	clients DockerClientRegistry
 }
 
 // NewDockerImageUsecase creates a new Docker image usecase
-func NewDockerImageUsecase(dockerClient *docker.Client) DockerImageUsecase {
+func NewDockerImageUsecase(clients DockerClientRegistry) DockerImageUsecase {
 	return &dockerImageUsecase{
-		dockerClient: dockerClient,
+		clients: clients,
 	}
 }
 
 // BuildImage builds an image from a Dockerfile
-func (u *dockerImageUsecase) BuildImage(ctx context.Context, dockerfile string, context io.Reader, tags []string) (io.ReadCloser, error) {
+func (u *dockerImageUsecase) BuildImage(ctx context.Context, hostID, dockerfile string, context io.Reader, tags []string) (io.ReadCloser, error) {
 	if context == nil {
 		return nil, fmt.Errorf("build context is required")
 	}
@@ -41,41 +41,66 @@ func (u *dockerImageUsecase) BuildImage(ctx context.Context, dockerfile string,
 		NoCache:    false,
 	}
 
-	return u.dockerClient.ImageBuild(ctx, config)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ImageBuild(ctx, config)
 }
 
 // PushImage pushes an image to a registry
-func (u *dockerImageUsecase) PushImage(ctx context.Context, image string, authConfig docker.AuthConfig) (io.ReadCloser, error) {
+func (u *dockerImageUsecase) PushImage(ctx context.Context, hostID, image string, authConfig docker.AuthConfig) (io.ReadCloser, error) {
 	if image == "" {
 		return nil, fmt.Errorf("image name is required")
 	}
 
-	return u.dockerClient.ImagePush(ctx, image, authConfig)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ImagePush(ctx, image, authConfig)
 }
 
 // PullImage pulls an image from a registry
-func (u *dockerImageUsecase) PullImage(ctx context.Context, image string, authConfig docker.AuthConfig) (io.ReadCloser, error) {
+func (u *dockerImageUsecase) PullImage(ctx context.Context, hostID, image string, authConfig docker.AuthConfig) (io.ReadCloser, error) {
 	if image == "" {
 		return nil, fmt.Errorf("image name is required")
 	}
 
-	return u.dockerClient.ImagePull(ctx, image, authConfig)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ImagePull(ctx, image, authConfig)
 }
 
 // RemoveImage removes an image
-func (u *dockerImageUsecase) RemoveImage(ctx context.Context, imageID string, force bool) ([]string, error) {
+func (u *dockerImageUsecase) RemoveImage(ctx context.Context, hostID, imageID string, force bool) ([]string, error) {
 	if imageID == "" {
 		return nil, fmt.Errorf("image ID is required")
 	}
 
-	return u.dockerClient.ImageRemove(ctx, imageID, force)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ImageRemove(ctx, imageID, force)
 }
 
 // InspectImage inspects an image
-func (u *dockerImageUsecase) InspectImage(ctx context.Context, imageID string) (map[string]interface{}, error) {
+func (u *dockerImageUsecase) InspectImage(ctx context.Context, hostID, imageID string) (map[string]interface{}, error) {
 	if imageID == "" {
 		return nil, fmt.Errorf("image ID is required")
 	}
 
-	return u.dockerClient.ImageInspect(ctx, imageID)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ImageInspect(ctx, imageID)
 }
@@ -3,32 +3,30 @@ package usecase
 import (
 	"context"
 	"fmt"
-
-	"github.com/unitechio/einfra-be/pkg/docker"
 )
 
 // DockerNetworkUsecase handles Docker network operations
 type DockerNetworkUsecase interface {
-	ConnectContainer(ctx context.Context, networkID, containerID string) error
-	DisconnectContainer(ctx context.Context, networkID, containerID string) error
-	CreateNetwork(ctx context.Context, name, driver string) (string, error)
-	RemoveNetwork(ctx context.Context, networkID string) error
-	InspectNetwork(ctx context.Context, networkID string) (map[string]interface{}, error)
+	ConnectContainer(ctx context.Context, hostID, networkID, containerID string) error
+	DisconnectContainer(ctx context.Context, hostID, networkID, containerID string) error
+	CreateNetwork(ctx context.Context, hostID, name, driver string) (string, error)
+	RemoveNetwork(ctx context.Context, hostID, networkID string) error
+	InspectNetwork(ctx context.Context, hostID, networkID string) (map[string]interface{}, error)
 }
 
 type dockerNetworkUsecase struct {
-	dockerClient *docker.Client
+	clients DockerClientRegistry
 }
 
 // NewDockerNetworkUsecase creates a new Docker network usecase
-func NewDockerNetworkUsecase(dockerClient *docker.Client) DockerNetworkUsecase {
+func NewDockerNetworkUsecase(clients DockerClientRegistry) DockerNetworkUsecase {
 	return &dockerNetworkUsecase{
-		dockerClient: dockerClient,
+		clients: clients,
 	}
 }
 
 // ConnectContainer connects a container to a network
-func (u *dockerNetworkUsecase) ConnectContainer(ctx context.Context, networkID, containerID string) error {
+func (u *dockerNetworkUsecase) ConnectContainer(ctx context.Context, hostID, networkID, containerID string) error {
 	if networkID == "" {
 		return fmt.Errorf("network ID is required")
 	}
@@ -36,7 +34,12 @@ func (u *dockerNetworkUsecase) ConnectContainer(ctx context.Context, networkID,
 		return fmt.Errorf("container ID is required")
 	}
 
-	if err := u.dockerClient.NetworkConnect(ctx, networkID, containerID); err != nil {
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return err
+	}
+
+	if err := client.NetworkConnect(ctx, networkID, containerID); err != nil {
 		return fmt.Errorf("failed to connect container to network: %w", err)
 	}
 
@@ -44,7 +47,7 @@ func (u *dockerNetworkUsecase) ConnectContainer(ctx context.Context, networkID,
 }
 
 // DisconnectContainer disconnects a container from a network
-func (u *dockerNetworkUsecase) DisconnectContainer(ctx context.Context, networkID, containerID string) error {
+func (u *dockerNetworkUsecase) DisconnectContainer(ctx context.Context, hostID, networkID, containerID string) error {
 	if networkID == "" {
 		return fmt.Errorf("network ID is required")
 	}
@@ -52,7 +55,12 @@ func (u *dockerNetworkUsecase) DisconnectContainer(ctx context.Context, networkI
 		return fmt.Errorf("container ID is required")
 	}
 
-	if err := u.dockerClient.NetworkDisconnect(ctx, networkID, containerID); err != nil {
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return err
+	}
+
+	if err := client.NetworkDisconnect(ctx, networkID, containerID); err != nil {
 		return fmt.Errorf("failed to disconnect container from network: %w", err)
 	}
 
@@ -60,7 +68,7 @@ func (u *dockerNetworkUsecase) DisconnectContainer(ctx context.Context, networkI
 }
 
 // CreateNetwork creates a new network
-func (u *dockerNetworkUsecase) CreateNetwork(ctx context.Context, name, driver string) (string, error) {
+func (u *dockerNetworkUsecase) CreateNetwork(ctx context.Context, hostID, name, driver string) (string, error) {
 	if name == "" {
 		return "", fmt.Errorf("network name is required")
 	}
@@ -68,7 +76,12 @@ func (u *dockerNetworkUsecase) CreateNetwork(ctx context.Context, name, driver s
 		driver = "bridge"
 	}
 
-	id, err := u.dockerClient.NetworkCreate(ctx, name, driver)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := client.NetworkCreate(ctx, name, driver)
 	if err != nil {
 		return "", fmt.Errorf("failed to create network: %w", err)
 	}
@@ -77,12 +90,17 @@ func (u *dockerNetworkUsecase) CreateNetwork(ctx context.Context, name, driver s
 }
 
 // RemoveNetwork removes a network
-func (u *dockerNetworkUsecase) RemoveNetwork(ctx context.Context, networkID string) error {
+func (u *dockerNetworkUsecase) RemoveNetwork(ctx context.Context, hostID, networkID string) error {
 	if networkID == "" {
 		return fmt.Errorf("network ID is required")
 	}
 
-	if err := u.dockerClient.NetworkRemove(ctx, networkID); err != nil {
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return err
+	}
+
+	if err := client.NetworkRemove(ctx, networkID); err != nil {
 		return fmt.Errorf("failed to remove network: %w", err)
 	}
 
@@ -90,12 +108,17 @@ func (u *dockerNetworkUsecase) RemoveNetwork(ctx context.Context, networkID stri
 }
 
 // InspectNetwork inspects a network
-func (u *dockerNetworkUsecase) InspectNetwork(ctx context.Context, networkID string) (map[string]interface{}, error) {
+func (u *dockerNetworkUsecase) InspectNetwork(ctx context.Context, hostID, networkID string) (map[string]interface{}, error) {
 	if networkID == "" {
 		return nil, fmt.Errorf("network ID is required")
 	}
 
-	info, err := u.dockerClient.NetworkInspect(ctx, networkID)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := client.NetworkInspect(ctx, networkID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to inspect network: %w", err)
 	}
@@ -0,0 +1,437 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/docker"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+	"github.com/unitechio/einfra-be/pkg/git"
+)
+
+const (
+	// gitStackPollInterval is how often the branches of git-backed stacks are checked for new commits
+	gitStackPollInterval = time.Minute
+	// gitStackCheckTimeout bounds fetching the compose file of one stack
+	gitStackCheckTimeout = 2 * time.Minute
+	// defaultStackComposePath is read when a git source names no file
+	defaultStackComposePath = "docker-compose.yml"
+)
+
+// gitBranchName keeps branch names to characters git accepts and that cannot be read as options
+var gitBranchName = regexp.MustCompile(`^[A-Za-z0-9._][A-Za-z0-9._/-]*$`)
+
+// ConfigureGitSource links a stack to a compose file in a repository and deploys its head
+func (u *dockerStackUsecase) ConfigureGitSource(ctx context.Context, stackID string, req domain.StackGitRequest, userID string) (*domain.StackGitSource, error) {
+	if stackID == "" {
+		return nil, fmt.Errorf("stack ID is required")
+	}
+	if _, err := u.stackRepo.GetByID(ctx, stackID); err != nil {
+		return nil, fmt.Errorf("stack not found: %w", err)
+	}
+
+	existing, _ := u.stackRepo.GetGitSource(ctx, stackID)
+	source, err := newStackGitSource(&req, existing)
+	if err != nil {
+		return nil, err
+	}
+	source.StackID = stackID
+
+	// Check access before saving, so a wrong key or path is reported to the caller
+	if _, _, err := git.ReadFile(ctx, toGitSource(source)); err != nil {
+		return nil, fmt.Errorf("failed to read compose file from git: %w", err)
+	}
+	if err := u.stackRepo.SaveGitSource(ctx, source); err != nil {
+		return nil, fmt.Errorf("failed to save git source: %w", err)
+	}
+
+	if _, err := u.checkGitSource(ctx, source, userID); err != nil {
+		log.Printf("Failed to deploy stack %s from git: %v", stackID, err)
+	}
+	return u.stackRepo.GetGitSource(ctx, stackID)
+}
+
+// GetGitSource returns the repository of a git-backed stack
+func (u *dockerStackUsecase) GetGitSource(ctx context.Context, stackID string) (*domain.StackGitSource, error) {
+	source, err := u.stackRepo.GetGitSource(ctx, stackID)
+	if err != nil {
+		return nil, fmt.Errorf("git source not found: %w", err)
+	}
+	return source, nil
+}
+
+// RemoveGitSource detaches a stack from its repository; the deployed compose file is kept
+func (u *dockerStackUsecase) RemoveGitSource(ctx context.Context, stackID string) error {
+	if stackID == "" {
+		return fmt.Errorf("stack ID is required")
+	}
+	return u.stackRepo.DeleteGitSource(ctx, stackID)
+}
+
+// SyncGitStack checks the branch of a git-backed stack now instead of waiting for the poller
+func (u *dockerStackUsecase) SyncGitStack(ctx context.Context, stackID, userID string) (*domain.StackRevision, error) {
+	source, err := u.stackRepo.GetGitSource(ctx, stackID)
+	if err != nil {
+		return nil, fmt.Errorf("git source not found: %w", err)
+	}
+	return u.checkGitSource(ctx, source, userID)
+}
+
+// HandleGitWebhook authenticates a push and checks the branch of the stack in the background, so the
+// sender gets its answer before the clone. Calls are authenticated with the webhook secret, either as an
+// HMAC-SHA256 signature of the body (GitHub, Gitea) or as a token (GitLab, or any sender putting it in
+// the Authorization header).
+func (u *dockerStackUsecase) HandleGitWebhook(ctx context.Context, stackID, signature, token string, body []byte) error {
+	// Unknown stacks and bad credentials get the same answer so the endpoint can't be used to probe IDs
+	source, err := u.stackRepo.GetGitSource(ctx, stackID)
+	if err != nil || !verifyGitWebhook(source.WebhookSecret, signature, token, body) {
+		return errorx.New(errorx.CodeUnauthorized, "invalid webhook credentials")
+	}
+
+	// Pushes to other branches are acknowledged and ignored
+	var payload struct {
+		Ref string `json:"ref"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return errorx.New(errorx.CodeBadRequest, "invalid webhook payload: "+err.Error())
+	}
+	if payload.Ref != "" && payload.Ref != "refs/heads/"+source.Branch {
+		return nil
+	}
+
+	// A check still waiting for its turn reads the head when it runs, so it covers this push too
+	u.gitQueue.Lock()
+	queued := u.gitQueued[stackID]
+	u.gitQueued[stackID] = true
+	u.gitQueue.Unlock()
+	if queued {
+		return nil
+	}
+
+	go func() {
+		checkCtx, cancel := context.WithTimeout(context.Background(), gitStackCheckTimeout)
+		defer cancel()
+		if _, err := u.checkGitSource(checkCtx, source, ""); err != nil {
+			log.Printf("Failed to check git source of stack %s after a push: %v", stackID, err)
+		}
+	}()
+	return nil
+}
+
+// ApproveRevision rolls out a revision pending approval
+func (u *dockerStackUsecase) ApproveRevision(ctx context.Context, stackID string, revision int, req domain.StackRevisionApproveRequest, userID string) (*domain.StackRevision, error) {
+	stack, pending, err := u.reviewableRevision(ctx, stackID, revision, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending.ApprovedBy = optionalUser(userID)
+	return u.rollOut(ctx, stack, pending, req.HealthTimeout)
+}
+
+// RejectRevision discards a revision pending approval
+func (u *dockerStackUsecase) RejectRevision(ctx context.Context, stackID string, revision int, userID string) (*domain.StackRevision, error) {
+	_, pending, err := u.reviewableRevision(ctx, stackID, revision, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending.Status = domain.StackRevisionRejected
+	pending.ApprovedBy = optionalUser(userID)
+	if err := u.stackRepo.UpdateRevision(ctx, pending); err != nil {
+		return nil, fmt.Errorf("failed to reject revision: %w", err)
+	}
+	return pending, nil
+}
+
+// reviewableRevision returns a revision pending approval once the user may review it: someone other
+// than the user who requested it, holding the approval role of the git source when it has one
+func (u *dockerStackUsecase) reviewableRevision(ctx context.Context, stackID string, revision int, userID string) (*domain.DockerStack, *domain.StackRevision, error) {
+	stack, err := u.stackRepo.GetByID(ctx, stackID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("stack not found: %w", err)
+	}
+	pending, err := u.stackRepo.GetRevision(ctx, stackID, revision)
+	if err != nil {
+		return nil, nil, fmt.Errorf("revision %d not found: %w", revision, err)
+	}
+	if pending.Status != domain.StackRevisionPending {
+		return nil, nil, errorx.New(errorx.CodeConflict, fmt.Sprintf("revision %d is %s, not pending approval", revision, pending.Status))
+	}
+
+	if userID == "" {
+		return nil, nil, errorx.New(errorx.CodeUnauthorized, "user ID is required to review revisions")
+	}
+	if pending.CreatedBy != nil && *pending.CreatedBy == userID {
+		return nil, nil, errorx.New(errorx.CodeForbidden, "revisions must be reviewed by someone other than the requester")
+	}
+	source, err := u.stackRepo.GetGitSource(ctx, stackID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("git source not found: %w", err)
+	}
+	if source.ApprovalRoleID != nil {
+		user, err := u.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			return nil, nil, errorx.Wrap(err, errorx.CodeForbidden, "unknown reviewer")
+		}
+		if user.RoleID != *source.ApprovalRoleID {
+			return nil, nil, errorx.New(errorx.CodeForbidden, "reviewer does not hold the stack's approval role")
+		}
+	}
+	return stack, pending, nil
+}
+
+// StartGitPoller periodically checks the branches of git-backed stacks until ctx is done
+func (u *dockerStackUsecase) StartGitPoller(ctx context.Context) {
+	ticker := time.NewTicker(gitStackPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			u.pollGitSources(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (u *dockerStackUsecase) pollGitSources(ctx context.Context) {
+	sources, err := u.stackRepo.ListGitSources(ctx)
+	if err != nil {
+		log.Printf("Failed to list git sources of stacks: %v", err)
+		return
+	}
+
+	// Stacks are checked concurrently, so a slow rollout of one does not hold back the others
+	var wg sync.WaitGroup
+	for _, source := range sources {
+		wg.Add(1)
+		go func(source *domain.StackGitSource) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, gitStackCheckTimeout)
+			defer cancel()
+			if _, err := u.checkGitSource(checkCtx, source, ""); err != nil {
+				log.Printf("Failed to check git source of stack %s: %v", source.StackID, err)
+			}
+		}(source)
+	}
+	wg.Wait()
+}
+
+// checkGitSource looks for a new commit on the branch of a stack. When it changed the compose file,
+// the commit is rolled out right away or recorded as a revision pending approval, per the deploy mode.
+// The poller, webhooks and manual syncs share it, one check of a stack at a time; waiting for the
+// check in progress stops when ctx is done.
+func (u *dockerStackUsecase) checkGitSource(ctx context.Context, source *domain.StackGitSource, userID string) (*domain.StackRevision, error) {
+	unlock, lockErr := u.lockGitStack(ctx, source.StackID)
+
+	// From here on a new push needs a check of its own
+	u.gitQueue.Lock()
+	delete(u.gitQueued, source.StackID)
+	u.gitQueue.Unlock()
+
+	if lockErr != nil {
+		return nil, fmt.Errorf("gave up waiting for the git check in progress: %w", lockErr)
+	}
+	defer unlock()
+
+	revision, err := u.applyGitSource(ctx, source, userID)
+
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	if stateErr := u.stackRepo.UpdateGitSourceState(ctx, source.StackID, source.LastCommit, errMsg, time.Now()); stateErr != nil {
+		log.Printf("Failed to record git check of stack %s: %v", source.StackID, stateErr)
+	}
+	return revision, err
+}
+
+// lockGitStack waits for the git checks of a stack to be free, or for ctx to be done
+func (u *dockerStackUsecase) lockGitStack(ctx context.Context, stackID string) (func(), error) {
+	u.gitMu.Lock()
+	lock, ok := u.gitLocks[stackID]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		u.gitLocks[stackID] = lock
+	}
+	u.gitMu.Unlock()
+
+	select {
+	case lock <- struct{}{}:
+		return func() { <-lock }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (u *dockerStackUsecase) applyGitSource(ctx context.Context, source *domain.StackGitSource, userID string) (*domain.StackRevision, error) {
+	src := toGitSource(source)
+	head, err := git.Head(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	if head == source.LastCommit {
+		return nil, nil
+	}
+
+	stack, err := u.stackRepo.GetByID(ctx, source.StackID)
+	if err != nil {
+		return nil, fmt.Errorf("stack not found: %w", err)
+	}
+
+	content, commit, err := git.ReadFile(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	if string(content) == stack.ComposeFile {
+		// The commit touched other files of the repository
+		source.LastCommit = commit
+		return nil, nil
+	}
+
+	revision := &domain.StackRevision{
+		ComposeFile: string(content),
+		EnvVars:     stack.EnvVars,
+		GitCommit:   commit,
+		CreatedBy:   optionalUser(userID),
+	}
+
+	if source.DeployMode == domain.StackGitDeployApproval {
+		revision, err = u.recordPendingRevision(ctx, stack, revision)
+	} else {
+		revision, err = u.rollOut(ctx, stack, revision, 0)
+	}
+	if err != nil {
+		// Keep the previous commit, so the next check tries again
+		return nil, err
+	}
+	source.LastCommit = commit
+	return revision, nil
+}
+
+// recordPendingRevision stores a commit awaiting approval; older pending revisions are superseded by it
+func (u *dockerStackUsecase) recordPendingRevision(ctx context.Context, stack *domain.DockerStack, revision *domain.StackRevision) (*domain.StackRevision, error) {
+	next, err := docker.LoadComposeProject(ctx, stack.ProjectName, revision.ComposeFile, stackEnv(revision.EnvVars, stack.SecretEnvVars))
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := u.stackRepo.ListRevisions(ctx, stack.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	latest := 0
+	for _, rev := range revisions {
+		if rev.Revision > latest {
+			latest = rev.Revision
+		}
+		if rev.Status == domain.StackRevisionPending {
+			if err := u.stackRepo.UpdateRevisionStatus(ctx, stack.ID, rev.Revision, domain.StackRevisionSuperseded, ""); err != nil {
+				return nil, fmt.Errorf("failed to supersede revision %d: %w", rev.Revision, err)
+			}
+		}
+	}
+
+	revision.StackID = stack.ID
+	revision.Revision = latest + 1
+	revision.Status = domain.StackRevisionPending
+	revision.Changes = diffComposeProjects(u.currentProject(ctx, stack), next)
+	if err := u.stackRepo.CreateRevision(ctx, revision); err != nil {
+		return nil, fmt.Errorf("failed to record stack revision: %w", err)
+	}
+	return revision, nil
+}
+
+// newStackGitSource validates a git request; the deploy key and webhook secret of existing are kept
+// when the request doesn't replace them
+func newStackGitSource(req *domain.StackGitRequest, existing *domain.StackGitSource) (*domain.StackGitSource, error) {
+	if err := git.ValidateURL(req.URL); err != nil {
+		return nil, err
+	}
+	if !gitBranchName.MatchString(req.Branch) || strings.Contains(req.Branch, "..") {
+		return nil, fmt.Errorf("invalid branch name %q", req.Branch)
+	}
+	path := req.Path
+	if path == "" {
+		path = defaultStackComposePath
+	}
+	path, err := git.CleanPath(path)
+	if err != nil {
+		return nil, err
+	}
+	mode := req.DeployMode
+	if mode == "" {
+		mode = domain.StackGitDeployAuto
+	}
+
+	source := &domain.StackGitSource{
+		URL:            req.URL,
+		Branch:         req.Branch,
+		Path:           path,
+		DeployKey:      req.DeployKey,
+		DeployMode:     mode,
+		ApprovalRoleID: req.ApprovalRoleID,
+	}
+	if existing != nil {
+		if source.DeployKey == "" {
+			source.DeployKey = existing.DeployKey
+		}
+		source.WebhookSecret = existing.WebhookSecret
+		source.CreatedAt = existing.CreatedAt
+		// Another branch or file has to be deployed even when its head is the commit seen last
+		if existing.URL == source.URL && existing.Branch == source.Branch && existing.Path == source.Path {
+			source.LastCommit = existing.LastCommit
+		}
+	}
+	if source.WebhookSecret == "" {
+		secret := make([]byte, 24)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		source.WebhookSecret = hex.EncodeToString(secret)
+	}
+	return source, nil
+}
+
+func toGitSource(source *domain.StackGitSource) git.Source {
+	return git.Source{
+		URL:       source.URL,
+		Branch:    source.Branch,
+		Path:      source.Path,
+		DeployKey: source.DeployKey,
+	}
+}
+
+// verifyGitWebhook accepts an HMAC-SHA256 signature of the body, hex encoded with an optional
+// "sha256=" prefix, or the secret itself as a token
+func verifyGitWebhook(secret, signature, token string, body []byte) bool {
+	if secret == "" {
+		return false
+	}
+	if signature != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		expected := hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(strings.TrimPrefix(signature, "sha256=")), []byte(expected))
+	}
+	for _, candidate := range []string{token, strings.TrimPrefix(token, "Bearer ")} {
+		if candidate != "" && subtle.ConstantTimeCompare([]byte(candidate), []byte(secret)) == 1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,382 @@
+package usecase_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+)
+
+// MockUserRepository is a mock implementation of repository.UserRepository
+type MockUserRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserRepository) Create(ctx context.Context, user *domain.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
+	args := m.Called(ctx, username)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) List(ctx context.Context, filter domain.UserFilter) ([]*domain.User, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]*domain.User), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserRepository) Update(ctx context.Context, user *domain.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	args := m.Called(ctx, userID, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateLastLogin(ctx context.Context, userID, ip string) error {
+	args := m.Called(ctx, userID, ip)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) IncrementFailedLogin(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ResetFailedLogin(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) LockAccount(ctx context.Context, userID string, until time.Time) error {
+	args := m.Called(ctx, userID, until)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UnlockAccount(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateSettings(ctx context.Context, userID string, settings domain.UserSettings) error {
+	args := m.Called(ctx, userID, settings)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) CreateBatch(ctx context.Context, users []*domain.User) error {
+	args := m.Called(ctx, users)
+	return args.Error(0)
+}
+
+// newStackRepo creates a bare repository with a main branch and returns it with a function committing
+// a compose file to it
+func newStackRepo(t *testing.T) (string, func(composeFile string) string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	bare := filepath.Join(root, "remote.git")
+	work := filepath.Join(root, "work")
+	gitCmd := func(dir string, args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_CONFIG_NOSYSTEM=1")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+	gitCmd(root, "init", "--quiet", "--bare", "--initial-branch=main", bare)
+	gitCmd(root, "init", "--quiet", "--initial-branch=main", work)
+
+	commit := func(composeFile string) string {
+		require.NoError(t, os.WriteFile(filepath.Join(work, "docker-compose.yml"), []byte(composeFile), 0o644))
+		gitCmd(work, "add", "-A")
+		gitCmd(work, "commit", "--quiet", "--allow-empty", "-m", "update")
+		gitCmd(work, "push", "--quiet", bare, "HEAD:main")
+		return gitCmd(work, "rev-parse", "HEAD")
+	}
+	return bare, commit
+}
+
+// TestSyncGitStack tests recording new commits of a git-backed stack for approval
+func TestSyncGitStack(t *testing.T) {
+	mockRepo := new(MockDockerStackRepository)
+	u := usecase.NewDockerStackUsecase(mockRepo, nil, nil, new(MockDockerClientRegistry))
+	ctx := context.Background()
+
+	bare, commit := newStackRepo(t)
+	first := commit(testComposeFile)
+
+	stack := &domain.DockerStack{ID: "stack-1", ProjectName: "app", ComposeFile: testComposeFile, Status: domain.StackStatusRunning, Revision: 1}
+	source := &domain.StackGitSource{
+		StackID:    stack.ID,
+		URL:        bare,
+		Branch:     "main",
+		Path:       "docker-compose.yml",
+		DeployMode: domain.StackGitDeployApproval,
+		LastCommit: first,
+	}
+
+	t.Run("Up to date", func(t *testing.T) {
+		mockRepo.On("GetGitSource", ctx, stack.ID).Return(source, nil).Once()
+		mockRepo.On("UpdateGitSourceState", ctx, stack.ID, first, "", mock.Anything).Return(nil).Once()
+
+		revision, err := u.SyncGitStack(ctx, stack.ID, "user-123")
+		require.NoError(t, err)
+		assert.Nil(t, revision)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Success - Record pending revision", func(t *testing.T) {
+		second := commit(testComposeFile + "    environment:\n      MODE: blue\n")
+		stale := &domain.StackRevision{StackID: stack.ID, Revision: 2, Status: domain.StackRevisionPending}
+
+		mockRepo.On("GetGitSource", ctx, stack.ID).Return(source, nil).Once()
+		mockRepo.On("GetByID", ctx, stack.ID).Return(stack, nil).Once()
+		mockRepo.On("ListRevisions", ctx, stack.ID).Return([]*domain.StackRevision{stale, {StackID: stack.ID, Revision: 1, Status: domain.StackRevisionActive}}, nil).Once()
+		mockRepo.On("UpdateRevisionStatus", ctx, stack.ID, 2, domain.StackRevisionSuperseded, "").Return(nil).Once()
+		mockRepo.On("CreateRevision", ctx, mock.AnythingOfType("*domain.StackRevision")).Return(nil).Once()
+		mockRepo.On("UpdateGitSourceState", ctx, stack.ID, second, "", mock.Anything).Return(nil).Once()
+
+		revision, err := u.SyncGitStack(ctx, stack.ID, "user-123")
+		require.NoError(t, err)
+		require.NotNil(t, revision)
+		assert.Equal(t, 3, revision.Revision)
+		assert.Equal(t, domain.StackRevisionPending, revision.Status)
+		assert.Equal(t, second, revision.GitCommit)
+		assert.Equal(t, []domain.StackServiceDiff{{
+			Service: "web",
+			Action:  domain.StackServiceUpdate,
+			Changes: []domain.StackFieldChange{{Field: "environment.MODE", After: "blue"}},
+		}}, revision.Changes)
+		// The stack keeps running the deployed compose file until the revision is approved
+		assert.Equal(t, testComposeFile, stack.ComposeFile)
+		assert.Equal(t, second, source.LastCommit)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Error - Broken compose file", func(t *testing.T) {
+		previous := source.LastCommit
+		commit("services: [")
+
+		mockRepo.On("GetGitSource", ctx, stack.ID).Return(source, nil).Once()
+		mockRepo.On("GetByID", ctx, stack.ID).Return(stack, nil).Once()
+		mockRepo.On("UpdateGitSourceState", ctx, stack.ID, previous, mock.MatchedBy(func(msg string) bool {
+			return msg != ""
+		}), mock.Anything).Return(nil).Once()
+
+		revision, err := u.SyncGitStack(ctx, stack.ID, "user-123")
+		assert.Error(t, err)
+		assert.Nil(t, revision)
+		assert.Equal(t, previous, source.LastCommit)
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+// TestSyncGitStackLocking tests that a slow check only holds back checks of the same stack
+func TestSyncGitStackLocking(t *testing.T) {
+	mockRepo := new(MockDockerStackRepository)
+	u := usecase.NewDockerStackUsecase(mockRepo, nil, nil, new(MockDockerClientRegistry))
+	ctx := context.Background()
+
+	bare, commit := newStackRepo(t)
+	head := commit(testComposeFile)
+
+	// The check of stack-1 sees a new commit and stalls loading the stack until released
+	started, release := make(chan struct{}), make(chan struct{})
+	slow := &domain.StackGitSource{StackID: "stack-1", URL: bare, Branch: "main", Path: "docker-compose.yml"}
+	mockRepo.On("GetGitSource", mock.Anything, "stack-1").Return(slow, nil)
+	mockRepo.On("GetByID", mock.Anything, "stack-1").Run(func(mock.Arguments) {
+		close(started)
+		<-release
+	}).Return(nil, errors.New("stack deleted")).Once()
+	mockRepo.On("UpdateGitSourceState", mock.Anything, "stack-1", "", "stack not found: stack deleted", mock.Anything).Return(nil).Once()
+
+	fast := &domain.StackGitSource{StackID: "stack-2", URL: bare, Branch: "main", Path: "docker-compose.yml", LastCommit: head}
+	mockRepo.On("GetGitSource", mock.Anything, "stack-2").Return(fast, nil)
+	mockRepo.On("UpdateGitSourceState", mock.Anything, "stack-2", head, "", mock.Anything).Return(nil).Once()
+
+	slowDone := make(chan error, 1)
+	go func() {
+		_, err := u.SyncGitStack(ctx, "stack-1", "")
+		slowDone <- err
+	}()
+	select {
+	case <-started:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the check of stack-1 did not start")
+	}
+
+	t.Run("Other stacks are not held back", func(t *testing.T) {
+		revision, err := u.SyncGitStack(ctx, "stack-2", "")
+		require.NoError(t, err)
+		assert.Nil(t, revision)
+	})
+
+	t.Run("Error - Waiting gives up when ctx is done", func(t *testing.T) {
+		waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		_, err := u.SyncGitStack(waitCtx, "stack-1", "")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	close(release)
+	assert.ErrorContains(t, <-slowDone, "stack deleted")
+	mockRepo.AssertExpectations(t)
+}
+
+// TestHandleGitWebhook tests authenticating push webhooks of git-backed stacks
+func TestHandleGitWebhook(t *testing.T) {
+	mockRepo := new(MockDockerStackRepository)
+	u := usecase.NewDockerStackUsecase(mockRepo, nil, nil, new(MockDockerClientRegistry))
+	ctx := context.Background()
+
+	bare, commit := newStackRepo(t)
+	head := commit(testComposeFile)
+
+	source := &domain.StackGitSource{StackID: "stack-1", URL: bare, Branch: "main", WebhookSecret: "s3cret", LastCommit: head}
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	mac := hmac.New(sha256.New, []byte(source.WebhookSecret))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	t.Run("Error - Invalid signature", func(t *testing.T) {
+		mockRepo.On("GetGitSource", ctx, source.StackID).Return(source, nil).Once()
+
+		err := u.HandleGitWebhook(ctx, source.StackID, "sha256=00", "", body)
+		assert.Equal(t, errorx.CodeUnauthorized, errorx.GetCode(err))
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Ignore other branches", func(t *testing.T) {
+		mockRepo.On("GetGitSource", ctx, source.StackID).Return(source, nil).Once()
+
+		err := u.HandleGitWebhook(ctx, source.StackID, "", "Bearer s3cret", []byte(`{"ref":"refs/heads/feature"}`))
+		require.NoError(t, err)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Success - Signed push is checked in the background", func(t *testing.T) {
+		checked := make(chan struct{})
+		mockRepo.On("GetGitSource", ctx, source.StackID).Return(source, nil).Once()
+		mockRepo.On("UpdateGitSourceState", mock.Anything, source.StackID, head, "", mock.Anything).Return(nil).Run(func(mock.Arguments) {
+			close(checked)
+		}).Once()
+
+		err := u.HandleGitWebhook(ctx, source.StackID, signature, "", body)
+		require.NoError(t, err)
+
+		select {
+		case <-checked:
+		case <-time.After(10 * time.Second):
+			t.Fatal("the branch was not checked after the push")
+		}
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+// TestReviewStackRevision tests who may approve or reject a revision pending approval
+func TestReviewStackRevision(t *testing.T) {
+	requester := "user-requester"
+	approvers := "role-approvers"
+	tests := []struct {
+		name     string
+		userID   string
+		roleID   *string
+		userRole string
+		wantCode int
+	}{
+		{name: "Error - Anonymous", userID: "", wantCode: errorx.CodeUnauthorized},
+		{name: "Error - Requester", userID: requester, wantCode: errorx.CodeForbidden},
+		{name: "Error - Missing approval role", userID: "user-2", roleID: &approvers, userRole: "role-developers", wantCode: errorx.CodeForbidden},
+		{name: "Success - Approval role", userID: "user-2", roleID: &approvers, userRole: approvers},
+		{name: "Success - Anyone but the requester", userID: "user-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockRepo := new(MockDockerStackRepository)
+			userRepo := new(MockUserRepository)
+			u := usecase.NewDockerStackUsecase(mockRepo, userRepo, nil, new(MockDockerClientRegistry))
+
+			pending := &domain.StackRevision{StackID: "stack-1", Revision: 3, Status: domain.StackRevisionPending, CreatedBy: &requester}
+			mockRepo.On("GetByID", ctx, "stack-1").Return(&domain.DockerStack{ID: "stack-1"}, nil)
+			mockRepo.On("GetRevision", ctx, "stack-1", 3).Return(pending, nil)
+			mockRepo.On("GetGitSource", ctx, "stack-1").Return(&domain.StackGitSource{StackID: "stack-1", ApprovalRoleID: tt.roleID}, nil)
+			userRepo.On("GetByID", ctx, tt.userID).Return(&domain.User{ID: tt.userID, RoleID: tt.userRole}, nil)
+			mockRepo.On("UpdateRevision", ctx, pending).Return(nil)
+
+			rejected, err := u.RejectRevision(ctx, "stack-1", 3, tt.userID)
+			if tt.wantCode != 0 {
+				assert.Equal(t, tt.wantCode, errorx.GetCode(err))
+				mockRepo.AssertNotCalled(t, "UpdateRevision", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, domain.StackRevisionRejected, rejected.Status)
+			assert.Equal(t, tt.userID, *rejected.ApprovedBy)
+		})
+	}
+
+	t.Run("Error - Not pending", func(t *testing.T) {
+		ctx := context.Background()
+		mockRepo := new(MockDockerStackRepository)
+		u := usecase.NewDockerStackUsecase(mockRepo, new(MockUserRepository), nil, new(MockDockerClientRegistry))
+		mockRepo.On("GetByID", ctx, "stack-1").Return(&domain.DockerStack{ID: "stack-1"}, nil)
+		mockRepo.On("GetRevision", ctx, "stack-1", 3).Return(&domain.StackRevision{StackID: "stack-1", Revision: 3, Status: domain.StackRevisionRejected}, nil)
+
+		_, err := u.ApproveRevision(ctx, "stack-1", 3, domain.StackRevisionApproveRequest{}, "user-2")
+		assert.Equal(t, errorx.CodeConflict, errorx.GetCode(err))
+	})
+}
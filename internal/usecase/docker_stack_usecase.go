@@ -3,16 +3,42 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"log"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/docker/api/types/container"
 	"github.com/unitechio/einfra-be/internal/domain"
 	"github.com/unitechio/einfra-be/internal/repository"
+	"github.com/unitechio/einfra-be/pkg/docker"
+	"github.com/unitechio/einfra-be/pkg/git"
 )
 
 // DockerStackUsecase handles Docker stack operations
 type DockerStackUsecase interface {
 	DeployStack(ctx context.Context, req domain.StackDeployRequest, userID string) (*domain.DockerStack, error)
-	UpdateStack(ctx context.Context, stackID string, req domain.StackUpdateRequest) error
+	// UpdateStack rolls out a new revision of a stack, recreating changed services one container at a
+	// time and restoring the previous revision when a new container fails its healthcheck
+	UpdateStack(ctx context.Context, stackID string, req domain.StackUpdateRequest, userID string) (*domain.StackRevision, error)
+	// DiffStack previews the per-service changes an update would make
+	DiffStack(ctx context.Context, stackID string, req domain.StackUpdateRequest) ([]domain.StackServiceDiff, error)
+	ListRevisions(ctx context.Context, stackID string) ([]*domain.StackRevision, error)
+	// RollbackStack rolls out an earlier revision as a new one
+	RollbackStack(ctx context.Context, stackID string, req domain.StackRollbackRequest, userID string) (*domain.StackRevision, error)
+
+	// Git-backed stacks
+	ConfigureGitSource(ctx context.Context, stackID string, req domain.StackGitRequest, userID string) (*domain.StackGitSource, error)
+	GetGitSource(ctx context.Context, stackID string) (*domain.StackGitSource, error)
+	RemoveGitSource(ctx context.Context, stackID string) error
+	// SyncGitStack deploys, or records for approval, a new commit on the branch of a stack; nil when there is none
+	SyncGitStack(ctx context.Context, stackID, userID string) (*domain.StackRevision, error)
+	HandleGitWebhook(ctx context.Context, stackID, signature, token string, body []byte) error
+	ApproveRevision(ctx context.Context, stackID string, revision int, req domain.StackRevisionApproveRequest, userID string) (*domain.StackRevision, error)
+	RejectRevision(ctx context.Context, stackID string, revision int, userID string) (*domain.StackRevision, error)
+	StartGitPoller(ctx context.Context)
 	GetStack(ctx context.Context, stackID string) (*domain.StackInfo, error)
 	ListStacks(ctx context.Context) ([]*domain.DockerStack, error)
 	RemoveStack(ctx context.Context, stackID string) error
@@ -21,14 +47,36 @@ type DockerStackUsecase interface {
 	StopStack(ctx context.Context, stackID string) error
 }
 
+const (
+	// stackStopTimeout is how long stack containers get to exit before they are killed, in seconds
+	stackStopTimeout = 10
+	// stackLogsTail is the number of lines returned per container by GetStackLogs
+	stackLogsTail = "200"
+	// stackHealthTimeout is how long a recreated container gets to become healthy unless the update says otherwise
+	stackHealthTimeout = 2 * time.Minute
+)
+
 type dockerStackUsecase struct {
-	stackRepo repository.DockerStackRepository
+	stackRepo         repository.DockerStackRepository
+	userRepo          repository.UserRepository
+	vulnerabilityGate domain.VulnerabilityGateUsecase
+	dockerClients     DockerClientRegistry
+
+	gitMu     sync.Mutex               // Guards gitLocks
+	gitLocks  map[string]chan struct{} // One-slot semaphores serializing the git checks of each stack
+	gitQueue  sync.Mutex               // Guards gitQueued
+	gitQueued map[string]bool          // Stacks with a webhook check waiting to run
 }
 
 // NewDockerStackUsecase creates a new Docker stack usecase
-func NewDockerStackUsecase(stackRepo repository.DockerStackRepository) DockerStackUsecase {
+func NewDockerStackUsecase(stackRepo repository.DockerStackRepository, userRepo repository.UserRepository, vulnerabilityGate domain.VulnerabilityGateUsecase, dockerClients DockerClientRegistry) DockerStackUsecase {
 	return &dockerStackUsecase{
-		stackRepo: stackRepo,
+		stackRepo:         stackRepo,
+		userRepo:          userRepo,
+		vulnerabilityGate: vulnerabilityGate,
+		dockerClients:     dockerClients,
+		gitLocks:          make(map[string]chan struct{}),
+		gitQueued:         make(map[string]bool),
 	}
 }
 
@@ -39,7 +87,7 @@ func (u *dockerStackUsecase) DeployStack(ctx context.Context, req domain.StackDe
 		return nil, fmt.Errorf("stack name is required")
 	}
 
-	if req.ComposeFile == "" {
+	if req.ComposeFile == "" && req.Git == nil {
 		return nil, fmt.Errorf("compose file is required")
 	}
 
@@ -49,62 +97,440 @@ func (u *dockerStackUsecase) DeployStack(ctx context.Context, req domain.StackDe
 		return nil, fmt.Errorf("stack with name %s already exists", req.Name)
 	}
 
+	// Git-backed stacks start from the head of their branch
+	var gitSource *domain.StackGitSource
+	if req.Git != nil {
+		if gitSource, err = newStackGitSource(req.Git, nil); err != nil {
+			return nil, err
+		}
+		content, commit, err := git.ReadFile(ctx, toGitSource(gitSource))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read compose file from git: %w", err)
+		}
+		req.ComposeFile = string(content)
+		gitSource.LastCommit = commit
+	}
+
+	projectName := sanitizeProjectName(req.Name)
+	project, err := docker.LoadComposeProject(ctx, projectName, req.ComposeFile, stackEnv(req.EnvVars, req.SecretEnvVars))
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := u.checkImages(ctx, req.EnvironmentID, project)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := u.dockerClients.Get(ctx, req.DockerHost)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create stack entity
 	stack := &domain.DockerStack{
-		Name:        req.Name,
-		ComposeFile: req.ComposeFile,
-		EnvVars:     req.EnvVars,
-		Status:      domain.StackStatusDeploying,
-		DockerHost:  req.DockerHost,
-		ProjectName: sanitizeProjectName(req.Name),
-		CreatedBy:   userID,
+		Name:          req.Name,
+		ComposeFile:   req.ComposeFile,
+		EnvVars:       req.EnvVars,
+		SecretEnvVars: req.SecretEnvVars,
+		Status:        domain.StackStatusDeploying,
+		DockerHost:    req.DockerHost,
+		ProjectName:   projectName,
+		Revision:      1,
+		CreatedBy:     userID,
+
+		EnvironmentID:     req.EnvironmentID,
+		VulnerabilityGate: report,
+
+		TemplateID:      req.TemplateID,
+		TemplateVersion: req.TemplateVersion,
 	}
 
 	// Save to database
 	if err := u.stackRepo.Create(ctx, stack); err != nil {
 		return nil, fmt.Errorf("failed to create stack: %w", err)
 	}
+	revision := &domain.StackRevision{
+		StackID:     stack.ID,
+		Revision:    stack.Revision,
+		ComposeFile: stack.ComposeFile,
+		EnvVars:     stack.EnvVars,
+		Status:      domain.StackRevisionDeploying,
+		Changes:     diffComposeProjects(nil, project),
+		CreatedBy:   optionalUser(userID),
+	}
+	if gitSource != nil {
+		revision.GitCommit = gitSource.LastCommit
+		gitSource.StackID = stack.ID
+		if err := u.stackRepo.SaveGitSource(ctx, gitSource); err != nil {
+			return nil, fmt.Errorf("failed to save git source: %w", err)
+		}
+	}
+	if err := u.stackRepo.CreateRevision(ctx, revision); err != nil {
+		return nil, fmt.Errorf("failed to record stack revision: %w", err)
+	}
 
-	// TODO: Deploy to Docker using docker-compose
-	// For now, just update status to running
-	go func() {
-		// Simulate deployment
-		u.stackRepo.UpdateStatus(context.Background(), stack.ID, domain.StackStatusRunning)
-	}()
+	// Pulling images can take minutes, the caller follows the stack status
+	go u.composeUp(stack.ID, stack.Revision, client, project)
 
 	return stack, nil
 }
 
 // UpdateStack updates an existing stack
-func (u *dockerStackUsecase) UpdateStack(ctx context.Context, stackID string, req domain.StackUpdateRequest) error {
+func (u *dockerStackUsecase) UpdateStack(ctx context.Context, stackID string, req domain.StackUpdateRequest, userID string) (*domain.StackRevision, error) {
 	if stackID == "" {
-		return fmt.Errorf("stack ID is required")
+		return nil, fmt.Errorf("stack ID is required")
 	}
 
 	// Get existing stack
 	stack, err := u.stackRepo.GetByID(ctx, stackID)
 	if err != nil {
-		return fmt.Errorf("stack not found: %w", err)
+		return nil, fmt.Errorf("stack not found: %w", err)
+	}
+
+	// The next commit would overwrite the compose file of a git-backed stack
+	if _, err := u.stackRepo.GetGitSource(ctx, stackID); err == nil {
+		return nil, fmt.Errorf("stack is deployed from git, push the change to its repository instead")
+	}
+
+	return u.rollOut(ctx, stack, &domain.StackRevision{
+		ComposeFile: req.ComposeFile,
+		EnvVars:     req.EnvVars,
+		CreatedBy:   optionalUser(userID),
+	}, req.HealthTimeout)
+}
+
+// DiffStack compares the services of a stack with those of an updated compose file
+func (u *dockerStackUsecase) DiffStack(ctx context.Context, stackID string, req domain.StackUpdateRequest) ([]domain.StackServiceDiff, error) {
+	if stackID == "" {
+		return nil, fmt.Errorf("stack ID is required")
+	}
+
+	stack, err := u.stackRepo.GetByID(ctx, stackID)
+	if err != nil {
+		return nil, fmt.Errorf("stack not found: %w", err)
+	}
+
+	next, err := docker.LoadComposeProject(ctx, stack.ProjectName, req.ComposeFile, stackEnv(req.EnvVars, stack.SecretEnvVars))
+	if err != nil {
+		return nil, err
+	}
+	return diffComposeProjects(u.currentProject(ctx, stack), next), nil
+}
+
+// ListRevisions lists the revisions of a stack, newest first
+func (u *dockerStackUsecase) ListRevisions(ctx context.Context, stackID string) ([]*domain.StackRevision, error) {
+	if stackID == "" {
+		return nil, fmt.Errorf("stack ID is required")
+	}
+
+	revisions, err := u.stackRepo.ListRevisions(ctx, stackID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// RollbackStack redeploys the compose file and variables of an earlier revision
+func (u *dockerStackUsecase) RollbackStack(ctx context.Context, stackID string, req domain.StackRollbackRequest, userID string) (*domain.StackRevision, error) {
+	if stackID == "" {
+		return nil, fmt.Errorf("stack ID is required")
+	}
+
+	stack, err := u.stackRepo.GetByID(ctx, stackID)
+	if err != nil {
+		return nil, fmt.Errorf("stack not found: %w", err)
+	}
+
+	target := req.Revision
+	if target == 0 {
+		// The newest revision that was running before the current one
+		revisions, err := u.stackRepo.ListRevisions(ctx, stackID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list revisions: %w", err)
+		}
+		for _, rev := range revisions {
+			if rev.Revision < stack.Revision && rev.Status == domain.StackRevisionSuperseded {
+				target = rev.Revision
+				break
+			}
+		}
+		if target == 0 {
+			return nil, fmt.Errorf("stack has no earlier revision to roll back to")
+		}
+	}
+	if target == stack.Revision {
+		return nil, fmt.Errorf("revision %d is already deployed", target)
+	}
+
+	revision, err := u.stackRepo.GetRevision(ctx, stackID, target)
+	if err != nil {
+		return nil, fmt.Errorf("revision %d not found: %w", target, err)
+	}
+
+	return u.rollOut(ctx, stack, &domain.StackRevision{
+		ComposeFile: revision.ComposeFile,
+		EnvVars:     revision.EnvVars,
+		GitCommit:   revision.GitCommit,
+		RollbackOf:  &target,
+		CreatedBy:   optionalUser(userID),
+	}, req.HealthTimeout)
+}
+
+// rollOut rolls out a revision of a stack in the background. New revisions are numbered and
+// recorded; revisions pending approval are rolled out in place.
+func (u *dockerStackUsecase) rollOut(ctx context.Context, stack *domain.DockerStack, revision *domain.StackRevision, healthTimeout int) (*domain.StackRevision, error) {
+	if stack.Status == domain.StackStatusDeploying || stack.Status == domain.StackStatusUpdating {
+		return nil, domain.ErrStackBusy
+	}
+
+	next, err := docker.LoadComposeProject(ctx, stack.ProjectName, revision.ComposeFile, stackEnv(revision.EnvVars, stack.SecretEnvVars))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := u.checkImages(ctx, stack.EnvironmentID, next); err != nil {
+		return nil, err
+	}
+
+	client, err := u.dockerClients.Get(ctx, stack.DockerHost)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := stackHealthTimeout
+	if healthTimeout > 0 {
+		timeout = time.Duration(healthTimeout) * time.Second
+	}
+
+	// The status read above may be stale; claiming the stack decides between concurrent rollouts
+	claimed, err := u.stackRepo.MarkUpdating(ctx, stack.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update stack status: %w", err)
+	}
+	if !claimed {
+		return nil, domain.ErrStackBusy
+	}
+
+	previous := *stack
+	current := u.currentProject(ctx, stack)
+	if err := u.recordRevision(ctx, stack, revision, current, next); err != nil {
+		// Release the stack again
+		if statusErr := u.stackRepo.UpdateStatus(ctx, stack.ID, previous.Status); statusErr != nil {
+			log.Printf("Failed to update status of stack %s: %v", stack.ID, statusErr)
+		}
+		return nil, err
+	}
+
+	go u.rollingUpdate(&previous, current, revision.Revision, client, next, timeout)
+
+	return revision, nil
+}
+
+// recordRevision records the revision being rolled out and points the stack at it
+func (u *dockerStackUsecase) recordRevision(ctx context.Context, stack *domain.DockerStack, revision *domain.StackRevision, current, next *types.Project) error {
+	revision.StackID = stack.ID
+	revision.Status = domain.StackRevisionDeploying
+	revision.Changes = diffComposeProjects(current, next)
+	if revision.ID == "" {
+		latest, err := u.stackRepo.LatestRevision(ctx, stack.ID)
+		if err != nil {
+			return fmt.Errorf("failed to number stack revision: %w", err)
+		}
+		revision.Revision = latest + 1
+		if err := u.stackRepo.CreateRevision(ctx, revision); err != nil {
+			return fmt.Errorf("failed to record stack revision: %w", err)
+		}
+	} else if err := u.stackRepo.UpdateRevision(ctx, revision); err != nil {
+		return fmt.Errorf("failed to update stack revision: %w", err)
 	}
 
 	// Update stack
-	stack.ComposeFile = req.ComposeFile
-	stack.EnvVars = req.EnvVars
+	stack.ComposeFile = revision.ComposeFile
+	stack.EnvVars = revision.EnvVars
+	stack.Revision = revision.Revision
 	stack.Status = domain.StackStatusUpdating
 
 	if err := u.stackRepo.Update(ctx, stack); err != nil {
 		return fmt.Errorf("failed to update stack: %w", err)
 	}
+	return nil
+}
+
+// currentProject loads the deployed compose file of a stack; nil when it no longer parses
+func (u *dockerStackUsecase) currentProject(ctx context.Context, stack *domain.DockerStack) *types.Project {
+	project, err := docker.LoadComposeProject(ctx, stack.ProjectName, stack.ComposeFile, stackEnv(stack.EnvVars, stack.SecretEnvVars))
+	if err != nil {
+		log.Printf("Failed to load deployed compose file of stack %s: %v", stack.ID, err)
+		return nil
+	}
+	return project
+}
+
+// composeUp converges the containers of a new stack and records the outcome in its status
+func (u *dockerStackUsecase) composeUp(stackID string, revision int, client *docker.Client, project *types.Project) {
+	ctx := context.Background()
+
+	status, revisionStatus, errMsg := domain.StackStatusRunning, domain.StackRevisionActive, ""
+	if err := client.ComposeUp(ctx, project); err != nil {
+		log.Printf("Failed to deploy stack %s: %v", stackID, err)
+		status, revisionStatus, errMsg = domain.StackStatusFailed, domain.StackRevisionFailed, err.Error()
+	}
+	if err := u.syncServices(ctx, stackID, client, project); err != nil {
+		log.Printf("Failed to sync services of stack %s: %v", stackID, err)
+	}
+	if err := u.stackRepo.UpdateRevisionStatus(ctx, stackID, revision, revisionStatus, errMsg); err != nil {
+		log.Printf("Failed to update revision %d of stack %s: %v", revision, stackID, err)
+	}
+	if err := u.stackRepo.UpdateStatus(ctx, stackID, status); err != nil {
+		log.Printf("Failed to update status of stack %s: %v", stackID, err)
+	}
+}
 
-	// TODO: Redeploy to Docker
-	go func() {
-		// Simulate update
-		u.stackRepo.UpdateStatus(context.Background(), stackID, domain.StackStatusRunning)
-	}()
+// rollingUpdate rolls out a revision one container at a time. When a new container fails, the
+// previous revision is rolled out again and restored on the stack.
+func (u *dockerStackUsecase) rollingUpdate(previous *domain.DockerStack, current *types.Project, revision int, client *docker.Client, next *types.Project, timeout time.Duration) {
+	ctx := context.Background()
+	stackID := previous.ID
+
+	err := client.ComposeRollingUpdate(ctx, next, timeout)
+	if err == nil {
+		if err := u.syncServices(ctx, stackID, client, next); err != nil {
+			log.Printf("Failed to sync services of stack %s: %v", stackID, err)
+		}
+		if replaced, err := u.stackRepo.GetRevision(ctx, stackID, previous.Revision); err == nil && replaced.Status == domain.StackRevisionActive {
+			if err := u.stackRepo.UpdateRevisionStatus(ctx, stackID, previous.Revision, domain.StackRevisionSuperseded, ""); err != nil {
+				log.Printf("Failed to update revision %d of stack %s: %v", previous.Revision, stackID, err)
+			}
+		}
+		if err := u.stackRepo.UpdateRevisionStatus(ctx, stackID, revision, domain.StackRevisionActive, ""); err != nil {
+			log.Printf("Failed to update revision %d of stack %s: %v", revision, stackID, err)
+		}
+		if err := u.stackRepo.UpdateStatus(ctx, stackID, domain.StackStatusRunning); err != nil {
+			log.Printf("Failed to update status of stack %s: %v", stackID, err)
+		}
+		return
+	}
+	log.Printf("Failed to roll out revision %d of stack %s, rolling back: %v", revision, stackID, err)
+
+	revisionStatus, errMsg := domain.StackRevisionRolledBack, err.Error()
+	live := next
+	if current == nil {
+		revisionStatus = domain.StackRevisionFailed
+		errMsg += "; previous revision cannot be restored"
+	} else if rollbackErr := client.ComposeRollingUpdate(ctx, current, timeout); rollbackErr != nil {
+		log.Printf("Failed to roll back stack %s to revision %d: %v", stackID, previous.Revision, rollbackErr)
+		revisionStatus = domain.StackRevisionFailed
+		errMsg += "; rollback failed: " + rollbackErr.Error()
+		live = current
+	} else {
+		live = current
+	}
 
+	if err := u.syncServices(ctx, stackID, client, live); err != nil {
+		log.Printf("Failed to sync services of stack %s: %v", stackID, err)
+	}
+	if err := u.stackRepo.UpdateRevisionStatus(ctx, stackID, revision, revisionStatus, errMsg); err != nil {
+		log.Printf("Failed to update revision %d of stack %s: %v", revision, stackID, err)
+	}
+
+	// The stack describes the restored revision again, or the failed one when nothing could be restored
+	if revisionStatus == domain.StackRevisionRolledBack {
+		previous.Status = domain.StackStatusRunning
+		if err := u.stackRepo.Update(ctx, previous); err != nil {
+			log.Printf("Failed to restore stack %s: %v", stackID, err)
+		}
+		return
+	}
+	if err := u.stackRepo.UpdateStatus(ctx, stackID, domain.StackStatusFailed); err != nil {
+		log.Printf("Failed to update status of stack %s: %v", stackID, err)
+	}
+}
+
+// syncServices replaces the service rows of a stack with the state of its live containers
+func (u *dockerStackUsecase) syncServices(ctx context.Context, stackID string, client *docker.Client, project *types.Project) error {
+	containers, err := client.ComposeContainers(ctx, project.Name, "")
+	if err != nil {
+		return err
+	}
+	byService := make(map[string][]container.Summary)
+	for _, ctr := range containers {
+		service := ctr.Labels[docker.ComposeServiceLabel]
+		byService[service] = append(byService[service], ctr)
+	}
+
+	if err := u.stackRepo.DeleteServices(ctx, stackID); err != nil {
+		return fmt.Errorf("failed to delete services: %w", err)
+	}
+
+	for _, name := range project.ServiceNames() {
+		service := project.Services[name]
+		replicas := byService[name]
+
+		var environment []string
+		for k, v := range service.Environment {
+			if v != nil {
+				environment = append(environment, k+"="+*v)
+			}
+		}
+		sort.Strings(environment)
+
+		if err := u.stackRepo.CreateService(ctx, &domain.StackService{
+			StackID:     stackID,
+			Name:        name,
+			Image:       service.Image,
+			Replicas:    len(replicas),
+			Status:      stackServiceStatus(replicas),
+			Ports:       stackServicePorts(replicas),
+			Environment: environment,
+		}); err != nil {
+			return fmt.Errorf("failed to create service %s: %w", name, err)
+		}
+	}
 	return nil
 }
 
+// stackServiceStatus summarizes the states of the containers of a service
+func stackServiceStatus(containers []container.Summary) string {
+	if len(containers) == 0 {
+		return "missing"
+	}
+	running := 0
+	for _, ctr := range containers {
+		if ctr.State == "running" {
+			running++
+		}
+	}
+	switch running {
+	case len(containers):
+		return "running"
+	case 0:
+		return "stopped"
+	default:
+		return "degraded"
+	}
+}
+
+// stackServicePorts formats the published ports of the containers of a service like "0.0.0.0:8080->80/tcp"
+func stackServicePorts(containers []container.Summary) []string {
+	seen := make(map[string]bool)
+	var ports []string
+	for _, ctr := range containers {
+		for _, p := range ctr.Ports {
+			if p.PublicPort == 0 {
+				continue
+			}
+			port := fmt.Sprintf("%s:%d->%d/%s", p.IP, p.PublicPort, p.PrivatePort, p.Type)
+			if !seen[port] {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+	sort.Strings(ports)
+	return ports
+}
+
 // GetStack retrieves stack with services
 func (u *dockerStackUsecase) GetStack(ctx context.Context, stackID string) (*domain.StackInfo, error) {
 	if stackID == "" {
@@ -137,7 +563,7 @@ func (u *dockerStackUsecase) ListStacks(ctx context.Context) ([]*domain.DockerSt
 	return stacks, nil
 }
 
-// RemoveStack removes a stack
+// RemoveStack removes the containers and networks of a stack and deletes it; named volumes are kept
 func (u *dockerStackUsecase) RemoveStack(ctx context.Context, stackID string) error {
 	if stackID == "" {
 		return fmt.Errorf("stack ID is required")
@@ -149,8 +575,13 @@ func (u *dockerStackUsecase) RemoveStack(ctx context.Context, stackID string) er
 		return fmt.Errorf("stack not found: %w", err)
 	}
 
-	// TODO: Remove from Docker
-	// docker-compose down
+	client, err := u.dockerClients.Get(ctx, stack.DockerHost)
+	if err != nil {
+		return err
+	}
+	if err := client.ComposeDown(ctx, stack.ProjectName, false); err != nil {
+		return fmt.Errorf("failed to remove stack from docker: %w", err)
+	}
 
 	// Delete services
 	if err := u.stackRepo.DeleteServices(ctx, stackID); err != nil {
@@ -162,7 +593,6 @@ func (u *dockerStackUsecase) RemoveStack(ctx context.Context, stackID string) er
 		return fmt.Errorf("failed to delete stack: %w", err)
 	}
 
-	_ = stack // Use stack variable
 	return nil
 }
 
@@ -173,15 +603,16 @@ func (u *dockerStackUsecase) GetStackLogs(ctx context.Context, stackID string, s
 	}
 
 	// Get stack
-	_, err := u.stackRepo.GetByID(ctx, stackID)
+	stack, err := u.stackRepo.GetByID(ctx, stackID)
 	if err != nil {
 		return "", fmt.Errorf("stack not found: %w", err)
 	}
 
-	// TODO: Get logs from Docker
-	// docker-compose logs [service]
-
-	return "Stack logs placeholder", nil
+	client, err := u.dockerClients.Get(ctx, stack.DockerHost)
+	if err != nil {
+		return "", err
+	}
+	return client.ComposeLogs(ctx, stack.ProjectName, serviceFilter, stackLogsTail)
 }
 
 // StartStack starts a stopped stack
@@ -190,8 +621,15 @@ func (u *dockerStackUsecase) StartStack(ctx context.Context, stackID string) err
 		return fmt.Errorf("stack ID is required")
 	}
 
-	// TODO: Start stack
-	// docker-compose start
+	stack, err := u.stackRepo.GetByID(ctx, stackID)
+	if err != nil {
+		return fmt.Errorf("stack not found: %w", err)
+	}
+	if err := u.composeRun(ctx, stack, func(client *docker.Client) error {
+		return client.ComposeStart(ctx, stack.ProjectName)
+	}); err != nil {
+		return err
+	}
 
 	return u.stackRepo.UpdateStatus(ctx, stackID, domain.StackStatusRunning)
 }
@@ -202,14 +640,215 @@ func (u *dockerStackUsecase) StopStack(ctx context.Context, stackID string) erro
 		return fmt.Errorf("stack ID is required")
 	}
 
-	// TODO: Stop stack
-	// docker-compose stop
+	stack, err := u.stackRepo.GetByID(ctx, stackID)
+	if err != nil {
+		return fmt.Errorf("stack not found: %w", err)
+	}
+	if err := u.composeRun(ctx, stack, func(client *docker.Client) error {
+		return client.ComposeStop(ctx, stack.ProjectName, stackStopTimeout)
+	}); err != nil {
+		return err
+	}
 
 	return u.stackRepo.UpdateStatus(ctx, stackID, domain.StackStatusStopped)
 }
 
+// composeRun runs an operation against the host of a stack and refreshes its service rows afterwards
+func (u *dockerStackUsecase) composeRun(ctx context.Context, stack *domain.DockerStack, op func(client *docker.Client) error) error {
+	client, err := u.dockerClients.Get(ctx, stack.DockerHost)
+	if err != nil {
+		return err
+	}
+	if err := op(client); err != nil {
+		return err
+	}
+
+	project, err := docker.LoadComposeProject(ctx, stack.ProjectName, stack.ComposeFile, stackEnv(stack.EnvVars, stack.SecretEnvVars))
+	if err != nil {
+		return err
+	}
+	return u.syncServices(ctx, stack.ID, client, project)
+}
+
 // Helper functions
 
+// diffComposeProjects compares the services of two versions of a stack; current is nil for a new stack
+func diffComposeProjects(current, next *types.Project) []domain.StackServiceDiff {
+	names := map[string]bool{}
+	if current != nil {
+		for name := range current.Services {
+			names[name] = true
+		}
+	}
+	for name := range next.Services {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	diffs := make([]domain.StackServiceDiff, 0, len(sorted))
+	for _, name := range sorted {
+		after, inNext := next.Services[name]
+		var before types.ServiceConfig
+		inCurrent := false
+		if current != nil {
+			before, inCurrent = current.Services[name]
+		}
+
+		switch {
+		case !inCurrent:
+			diffs = append(diffs, domain.StackServiceDiff{
+				Service: name,
+				Action:  domain.StackServiceCreate,
+				Changes: []domain.StackFieldChange{{Field: "image", After: after.Image}},
+			})
+		case !inNext:
+			diffs = append(diffs, domain.StackServiceDiff{
+				Service: name,
+				Action:  domain.StackServiceRemove,
+				Changes: []domain.StackFieldChange{{Field: "image", Before: before.Image}},
+			})
+		default:
+			changes := diffComposeServices(before, after)
+			action := domain.StackServiceUpdate
+			if len(changes) == 0 {
+				action = domain.StackServiceUnchanged
+			}
+			diffs = append(diffs, domain.StackServiceDiff{Service: name, Action: action, Changes: changes})
+		}
+	}
+	return diffs
+}
+
+// diffComposeServices lists the changed image, environment, ports and volumes of a service, or a
+// config change when only other settings differ and its containers will still be recreated
+func diffComposeServices(before, after types.ServiceConfig) []domain.StackFieldChange {
+	var changes []domain.StackFieldChange
+	if before.Image != after.Image {
+		changes = append(changes, domain.StackFieldChange{Field: "image", Before: before.Image, After: after.Image})
+	}
+
+	keys := map[string]bool{}
+	for k := range before.Environment {
+		keys[k] = true
+	}
+	for k := range after.Environment {
+		keys[k] = true
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for k := range keys {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+	for _, k := range sortedKeys {
+		b, a := composeEnvValue(before.Environment, k), composeEnvValue(after.Environment, k)
+		if b != a {
+			changes = append(changes, domain.StackFieldChange{Field: "environment." + k, Before: b, After: a})
+		}
+	}
+
+	if b, a := composePorts(before), composePorts(after); b != a {
+		changes = append(changes, domain.StackFieldChange{Field: "ports", Before: b, After: a})
+	}
+	if b, a := composeVolumes(before), composeVolumes(after); b != a {
+		changes = append(changes, domain.StackFieldChange{Field: "volumes", Before: b, After: a})
+	}
+
+	if len(changes) == 0 {
+		beforeHash, _ := docker.ComposeConfigHash(before)
+		afterHash, _ := docker.ComposeConfigHash(after)
+		if beforeHash != afterHash {
+			changes = append(changes, domain.StackFieldChange{Field: "config"})
+		}
+	}
+	return changes
+}
+
+func composeEnvValue(env types.MappingWithEquals, key string) string {
+	if v := env[key]; v != nil {
+		return *v
+	}
+	return ""
+}
+
+// composePorts formats the ports of a service like "127.0.0.1:8080->80/tcp, 9000/tcp"
+func composePorts(service types.ServiceConfig) string {
+	ports := make([]string, 0, len(service.Ports))
+	for _, p := range service.Ports {
+		port := fmt.Sprintf("%d/%s", p.Target, p.Protocol)
+		if p.Published != "" {
+			port = p.Published + "->" + port
+			if p.HostIP != "" {
+				port = p.HostIP + ":" + port
+			}
+		}
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+	return strings.Join(ports, ", ")
+}
+
+// composeVolumes formats the mounts of a service like "data:/var/lib/data, ./conf:/etc/app:ro"
+func composeVolumes(service types.ServiceConfig) string {
+	volumes := make([]string, 0, len(service.Volumes))
+	for _, v := range service.Volumes {
+		volume := v.Source + ":" + v.Target
+		if v.Type == types.VolumeTypeTmpfs {
+			volume = "tmpfs:" + v.Target
+		}
+		if v.ReadOnly {
+			volume += ":ro"
+		}
+		volumes = append(volumes, volume)
+	}
+	sort.Strings(volumes)
+	return strings.Join(volumes, ", ")
+}
+
+// checkImages runs the images of a compose project through the vulnerability policy of the stack's environment
+func (u *dockerStackUsecase) checkImages(ctx context.Context, environmentID *string, project *types.Project) (*domain.VulnerabilityGateReport, error) {
+	if u.vulnerabilityGate == nil || environmentID == nil {
+		return nil, nil
+	}
+
+	// The loaded project has its variables interpolated already
+	var images []string
+	for _, service := range project.Services {
+		if service.Image == "" {
+			continue // Built locally
+		}
+		images = append(images, service.Image)
+	}
+	return u.vulnerabilityGate.CheckEnvironment(ctx, *environmentID, images)
+}
+
+// stackEnv returns the variables the compose file of a stack is interpolated with; secrets take
+// precedence over plain variables of the same name
+func stackEnv(envVars, secrets map[string]string) map[string]string {
+	if len(secrets) == 0 {
+		return envVars
+	}
+	env := make(map[string]string, len(envVars)+len(secrets))
+	for name, value := range envVars {
+		env[name] = value
+	}
+	for name, value := range secrets {
+		env[name] = value
+	}
+	return env
+}
+
+// optionalUser maps the empty user ID of background jobs to NULL
+func optionalUser(userID string) *string {
+	if userID == "" {
+		return nil
+	}
+	return &userID
+}
+
 func sanitizeProjectName(name string) string {
 	// Docker Compose project names must be lowercase and alphanumeric
 	name = strings.ToLower(name)
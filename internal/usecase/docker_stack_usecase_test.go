@@ -2,14 +2,28 @@ package usecase_test
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/unitechio/einfra-be/internal/domain"
 	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/docker"
 )
 
+const testComposeFile = `services:
+  web:
+    image: nginx:${TAG:-latest}
+    ports:
+      - "8080:80"
+`
+
 // MockDockerStackRepository is a mock implementation of DockerStackRepository
 type MockDockerStackRepository struct {
 	mock.Mock
@@ -59,6 +73,11 @@ func (m *MockDockerStackRepository) UpdateStatus(ctx context.Context, id string,
 	return args.Error(0)
 }
 
+func (m *MockDockerStackRepository) MarkUpdating(ctx context.Context, id string) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockDockerStackRepository) CreateService(ctx context.Context, service *domain.StackService) error {
 	args := m.Called(ctx, service)
 	return args.Error(0)
@@ -77,28 +96,223 @@ func (m *MockDockerStackRepository) DeleteServices(ctx context.Context, stackID
 	return args.Error(0)
 }
 
+func (m *MockDockerStackRepository) CreateRevision(ctx context.Context, revision *domain.StackRevision) error {
+	args := m.Called(ctx, revision)
+	return args.Error(0)
+}
+
+func (m *MockDockerStackRepository) GetRevision(ctx context.Context, stackID string, revision int) (*domain.StackRevision, error) {
+	args := m.Called(ctx, stackID, revision)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.StackRevision), args.Error(1)
+}
+
+func (m *MockDockerStackRepository) ListRevisions(ctx context.Context, stackID string) ([]*domain.StackRevision, error) {
+	args := m.Called(ctx, stackID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.StackRevision), args.Error(1)
+}
+
+func (m *MockDockerStackRepository) LatestRevision(ctx context.Context, stackID string) (int, error) {
+	args := m.Called(ctx, stackID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDockerStackRepository) UpdateRevision(ctx context.Context, revision *domain.StackRevision) error {
+	args := m.Called(ctx, revision)
+	return args.Error(0)
+}
+
+func (m *MockDockerStackRepository) UpdateRevisionStatus(ctx context.Context, stackID string, revision int, status domain.StackRevisionStatus, errMsg string) error {
+	args := m.Called(ctx, stackID, revision, status, errMsg)
+	return args.Error(0)
+}
+
+func (m *MockDockerStackRepository) SaveGitSource(ctx context.Context, source *domain.StackGitSource) error {
+	args := m.Called(ctx, source)
+	return args.Error(0)
+}
+
+func (m *MockDockerStackRepository) GetGitSource(ctx context.Context, stackID string) (*domain.StackGitSource, error) {
+	args := m.Called(ctx, stackID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.StackGitSource), args.Error(1)
+}
+
+func (m *MockDockerStackRepository) ListGitSources(ctx context.Context) ([]*domain.StackGitSource, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.StackGitSource), args.Error(1)
+}
+
+func (m *MockDockerStackRepository) DeleteGitSource(ctx context.Context, stackID string) error {
+	args := m.Called(ctx, stackID)
+	return args.Error(0)
+}
+
+func (m *MockDockerStackRepository) UpdateGitSourceState(ctx context.Context, stackID, lastCommit, lastError string, checkedAt time.Time) error {
+	args := m.Called(ctx, stackID, lastCommit, lastError, checkedAt)
+	return args.Error(0)
+}
+
+// MockDockerClientRegistry is a mock implementation of DockerClientRegistry
+type MockDockerClientRegistry struct {
+	mock.Mock
+}
+
+func (m *MockDockerClientRegistry) Get(ctx context.Context, hostID string) (*docker.Client, error) {
+	args := m.Called(ctx, hostID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*docker.Client), args.Error(1)
+}
+
+func (m *MockDockerClientRegistry) Refresh(ctx context.Context, hostID string) (*domain.DockerHost, error) {
+	args := m.Called(ctx, hostID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DockerHost), args.Error(1)
+}
+
+func (m *MockDockerClientRegistry) Invalidate(hostID string) {
+	m.Called(hostID)
+}
+
+func (m *MockDockerClientRegistry) ActiveHosts(ctx context.Context) ([]*domain.DockerHost, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.DockerHost), args.Error(1)
+}
+
+func (m *MockDockerClientRegistry) StartHealthCheck(ctx context.Context) {
+	m.Called(ctx)
+}
+
+// MockVulnerabilityGateUsecase mocks the deployment checks of the vulnerability gate
+type MockVulnerabilityGateUsecase struct {
+	domain.VulnerabilityGateUsecase
+	mock.Mock
+}
+
+func (m *MockVulnerabilityGateUsecase) CheckEnvironment(ctx context.Context, environmentID string, images []string) (*domain.VulnerabilityGateReport, error) {
+	args := m.Called(ctx, environmentID, images)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.VulnerabilityGateReport), args.Error(1)
+}
+
+// newEmptyDockerDaemon serves a Docker API without any containers, networks or volumes
+func newEmptyDockerDaemon(t *testing.T) *docker.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Api-Version", "1.47")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_ping"):
+			w.Write([]byte("OK"))
+		case strings.HasSuffix(r.URL.Path, "/volumes"):
+			w.Write([]byte(`{"Volumes":[]}`))
+		case strings.HasSuffix(r.URL.Path, "/containers/json"), strings.HasSuffix(r.URL.Path, "/networks"):
+			w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := docker.NewClient("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
 // TestDeployStack tests deploying a stack
 func TestDeployStack(t *testing.T) {
 	mockRepo := new(MockDockerStackRepository)
-	u := usecase.NewDockerStackUsecase(mockRepo)
+	mockClients := new(MockDockerClientRegistry)
+	u := usecase.NewDockerStackUsecase(mockRepo, nil, nil, mockClients)
 	ctx := context.Background()
 
+	// Nothing listens there, the background deploy fails and marks the stack failed
+	unreachable, err := docker.NewClient("tcp://127.0.0.1:1")
+	require.NoError(t, err)
+	defer unreachable.Close()
+
 	t.Run("Success - Deploy stack", func(t *testing.T) {
 		req := domain.StackDeployRequest{
 			Name:        "test-stack",
-			ComposeFile: "version: '3'",
+			ComposeFile: testComposeFile,
 		}
 		userID := "user-123"
 
 		mockRepo.On("GetByName", ctx, req.Name).Return(nil, nil).Once()
+		mockClients.On("Get", ctx, "").Return(unreachable, nil).Once()
 		mockRepo.On("Create", ctx, mock.AnythingOfType("*domain.DockerStack")).Return(nil).Once()
-		mockRepo.On("UpdateStatus", mock.Anything, mock.AnythingOfType("string"), domain.StackStatusRunning).Return(nil).Maybe()
+		mockRepo.On("CreateRevision", ctx, mock.MatchedBy(func(rev *domain.StackRevision) bool {
+			return rev.Revision == 1 && len(rev.Changes) == 1 && rev.Changes[0].Action == domain.StackServiceCreate
+		})).Return(nil).Once()
+		mockRepo.On("UpdateRevisionStatus", mock.Anything, mock.Anything, 1, mock.Anything, mock.Anything).Return(nil).Maybe()
+		mockRepo.On("UpdateStatus", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("domain.StackStatus")).Return(nil).Maybe()
 
 		stack, err := u.DeployStack(ctx, req, userID)
 		assert.NoError(t, err)
 		assert.NotNil(t, stack)
 		assert.Equal(t, req.Name, stack.Name)
 		assert.Equal(t, domain.StackStatusDeploying, stack.Status)
+		assert.Equal(t, "test-stack", stack.ProjectName)
+		assert.Equal(t, 1, stack.Revision)
+
+		mockRepo.AssertExpectations(t)
+		mockClients.AssertExpectations(t)
+	})
+
+	t.Run("Error - Rejected by the vulnerability gate", func(t *testing.T) {
+		mockRepo := new(MockDockerStackRepository)
+		gate := new(MockVulnerabilityGateUsecase)
+		u := usecase.NewDockerStackUsecase(mockRepo, nil, gate, mockClients)
+		environmentID := "env-prod"
+		req := domain.StackDeployRequest{
+			Name:          "gated-stack",
+			ComposeFile:   testComposeFile,
+			EnvVars:       map[string]string{"TAG": "1.27"},
+			EnvironmentID: &environmentID,
+		}
+
+		mockRepo.On("GetByName", ctx, req.Name).Return(nil, nil).Once()
+		gate.On("CheckEnvironment", ctx, environmentID, []string{"nginx:1.27"}).Return(nil, errors.New("blocked by policy")).Once()
+
+		stack, err := u.DeployStack(ctx, req, "user-123")
+		assert.EqualError(t, err, "blocked by policy")
+		assert.Nil(t, stack)
+
+		gate.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "Create", ctx, mock.Anything)
+	})
+
+	t.Run("Error - Invalid compose file", func(t *testing.T) {
+		req := domain.StackDeployRequest{
+			Name:        "built-stack",
+			ComposeFile: "services:\n  app:\n    build: .\n",
+		}
+
+		mockRepo.On("GetByName", ctx, req.Name).Return(nil, nil).Once()
+
+		stack, err := u.DeployStack(ctx, req, "user-123")
+		assert.Error(t, err)
+		assert.Nil(t, stack)
+		assert.Contains(t, err.Error(), "build is not supported")
 
 		mockRepo.AssertExpectations(t)
 	})
@@ -106,7 +320,7 @@ func TestDeployStack(t *testing.T) {
 	t.Run("Error - Stack exists", func(t *testing.T) {
 		req := domain.StackDeployRequest{
 			Name:        "existing-stack",
-			ComposeFile: "version: '3'",
+			ComposeFile: testComposeFile,
 		}
 		userID := "user-123"
 		existingStack := &domain.DockerStack{Name: "existing-stack"}
@@ -122,17 +336,126 @@ func TestDeployStack(t *testing.T) {
 	})
 }
 
+// TestUpdateStack tests rolling out a new revision of a stack
+func TestUpdateStack(t *testing.T) {
+	mockRepo := new(MockDockerStackRepository)
+	mockClients := new(MockDockerClientRegistry)
+	u := usecase.NewDockerStackUsecase(mockRepo, nil, nil, mockClients)
+	ctx := context.Background()
+
+	t.Run("Error - Stack busy", func(t *testing.T) {
+		stack := &domain.DockerStack{ID: "stack-busy", ProjectName: "busy", ComposeFile: testComposeFile, Status: domain.StackStatusUpdating}
+		mockRepo.On("GetByID", ctx, stack.ID).Return(stack, nil).Once()
+		mockRepo.On("GetGitSource", ctx, stack.ID).Return(nil, errors.New("record not found")).Once()
+
+		revision, err := u.UpdateStack(ctx, stack.ID, domain.StackUpdateRequest{ComposeFile: testComposeFile}, "user-123")
+		assert.ErrorIs(t, err, domain.ErrStackBusy)
+		assert.Nil(t, revision)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Error - Stack claimed by a concurrent rollout", func(t *testing.T) {
+		stack := &domain.DockerStack{ID: "stack-raced", ProjectName: "raced", ComposeFile: testComposeFile, Status: domain.StackStatusRunning}
+
+		unreachable, err := docker.NewClient("tcp://127.0.0.1:1")
+		require.NoError(t, err)
+		defer unreachable.Close()
+
+		mockRepo.On("GetByID", ctx, stack.ID).Return(stack, nil).Once()
+		mockRepo.On("GetGitSource", ctx, stack.ID).Return(nil, errors.New("record not found")).Once()
+		mockClients.On("Get", ctx, "").Return(unreachable, nil).Once()
+		mockRepo.On("MarkUpdating", ctx, stack.ID).Return(false, nil).Once()
+
+		revision, err := u.UpdateStack(ctx, stack.ID, domain.StackUpdateRequest{ComposeFile: testComposeFile}, "user-123")
+		assert.ErrorIs(t, err, domain.ErrStackBusy)
+		assert.Nil(t, revision)
+
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "LatestRevision", ctx, stack.ID)
+	})
+
+	t.Run("Success - Record revision", func(t *testing.T) {
+		stack := &domain.DockerStack{ID: "stack-1", ProjectName: "app", ComposeFile: testComposeFile, Status: domain.StackStatusRunning, Revision: 2}
+		updated := testComposeFile + "    environment:\n      MODE: blue\n"
+
+		unreachable, err := docker.NewClient("tcp://127.0.0.1:1")
+		require.NoError(t, err)
+		defer unreachable.Close()
+
+		mockRepo.On("GetByID", ctx, stack.ID).Return(stack, nil).Once()
+		mockRepo.On("GetGitSource", ctx, stack.ID).Return(nil, errors.New("record not found")).Once()
+		mockClients.On("Get", ctx, "").Return(unreachable, nil).Once()
+		mockRepo.On("MarkUpdating", ctx, stack.ID).Return(true, nil).Once()
+		mockRepo.On("LatestRevision", ctx, stack.ID).Return(2, nil).Once()
+		mockRepo.On("CreateRevision", ctx, mock.AnythingOfType("*domain.StackRevision")).Return(nil).Once()
+		mockRepo.On("Update", ctx, stack).Return(nil).Once()
+		mockRepo.On("UpdateRevisionStatus", mock.Anything, stack.ID, 3, mock.Anything, mock.Anything).Return(nil).Maybe()
+		mockRepo.On("UpdateStatus", mock.Anything, stack.ID, domain.StackStatusFailed).Return(nil).Maybe()
+
+		revision, err := u.UpdateStack(ctx, stack.ID, domain.StackUpdateRequest{ComposeFile: updated}, "user-123")
+		require.NoError(t, err)
+		assert.Equal(t, 3, revision.Revision)
+		assert.Equal(t, domain.StackRevisionDeploying, revision.Status)
+		assert.Equal(t, []domain.StackServiceDiff{{
+			Service: "web",
+			Action:  domain.StackServiceUpdate,
+			Changes: []domain.StackFieldChange{{Field: "environment.MODE", After: "blue"}},
+		}}, revision.Changes)
+
+		mockRepo.AssertExpectations(t)
+		mockClients.AssertExpectations(t)
+	})
+}
+
+// TestDiffStack tests previewing the changes of an update
+func TestDiffStack(t *testing.T) {
+	mockRepo := new(MockDockerStackRepository)
+	u := usecase.NewDockerStackUsecase(mockRepo, nil, nil, new(MockDockerClientRegistry))
+	ctx := context.Background()
+
+	stack := &domain.DockerStack{ID: "stack-1", ProjectName: "app", ComposeFile: testComposeFile, EnvVars: map[string]string{"TAG": "1.26"}}
+	mockRepo.On("GetByID", ctx, stack.ID).Return(stack, nil).Once()
+
+	updated := `services:
+  web:
+    image: nginx:${TAG}
+    ports:
+      - "127.0.0.1:8080:80"
+    volumes:
+      - html:/usr/share/nginx/html:ro
+  cache:
+    image: redis:7
+volumes:
+  html: {}
+`
+	diff, err := u.DiffStack(ctx, stack.ID, domain.StackUpdateRequest{ComposeFile: updated, EnvVars: map[string]string{"TAG": "1.27"}})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.StackServiceDiff{
+		{Service: "cache", Action: domain.StackServiceCreate, Changes: []domain.StackFieldChange{{Field: "image", After: "redis:7"}}},
+		{Service: "web", Action: domain.StackServiceUpdate, Changes: []domain.StackFieldChange{
+			{Field: "image", Before: "nginx:1.26", After: "nginx:1.27"},
+			{Field: "ports", Before: "8080->80/tcp", After: "127.0.0.1:8080->80/tcp"},
+			{Field: "volumes", After: "html:/usr/share/nginx/html:ro"},
+		}},
+	}, diff)
+
+	mockRepo.AssertExpectations(t)
+}
+
 // TestRemoveStack tests removing a stack
 func TestRemoveStack(t *testing.T) {
 	mockRepo := new(MockDockerStackRepository)
-	u := usecase.NewDockerStackUsecase(mockRepo)
+	mockClients := new(MockDockerClientRegistry)
+	u := usecase.NewDockerStackUsecase(mockRepo, nil, nil, mockClients)
 	ctx := context.Background()
 
 	t.Run("Success - Remove stack", func(t *testing.T) {
 		stackID := "stack-123"
-		stack := &domain.DockerStack{ID: stackID}
+		stack := &domain.DockerStack{ID: stackID, ProjectName: "test-stack", DockerHost: "host-1"}
 
 		mockRepo.On("GetByID", ctx, stackID).Return(stack, nil).Once()
+		mockClients.On("Get", ctx, "host-1").Return(newEmptyDockerDaemon(t), nil).Once()
 		mockRepo.On("DeleteServices", ctx, stackID).Return(nil).Once()
 		mockRepo.On("Delete", ctx, stackID).Return(nil).Once()
 
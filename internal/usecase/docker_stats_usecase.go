@@ -9,31 +9,36 @@ import (
 
 // DockerStatsUsecase handles container stats operations
 type DockerStatsUsecase interface {
-	GetStatsStream(ctx context.Context, containerID string) (<-chan *docker.ContainerStats, <-chan error, error)
-	GetStatsOnce(ctx context.Context, containerID string) (*docker.ContainerStats, error)
-	PauseContainer(ctx context.Context, containerID string) error
-	UnpauseContainer(ctx context.Context, containerID string) error
-	CommitContainer(ctx context.Context, config docker.ContainerCommitConfig) (string, error)
+	GetStatsStream(ctx context.Context, hostID, containerID string) (<-chan *docker.ContainerStats, <-chan error, error)
+	GetStatsOnce(ctx context.Context, hostID, containerID string) (*docker.ContainerStats, error)
+	PauseContainer(ctx context.Context, hostID, containerID string) error
+	UnpauseContainer(ctx context.Context, hostID, containerID string) error
+	CommitContainer(ctx context.Context, hostID string, config docker.ContainerCommitConfig) (string, error)
 }
 
 type dockerStatsUsecase struct {
-	dockerClient *docker.Client
+	clients DockerClientRegistry
 }
 
 // NewDockerStatsUsecase creates a new docker stats usecase
-func NewDockerStatsUsecase(dockerClient *docker.Client) DockerStatsUsecase {
+func NewDockerStatsUsecase(clients DockerClientRegistry) DockerStatsUsecase {
 	return &dockerStatsUsecase{
-		dockerClient: dockerClient,
+		clients: clients,
 	}
 }
 
 // GetStatsStream gets container stats as a stream
-func (u *dockerStatsUsecase) GetStatsStream(ctx context.Context, containerID string) (<-chan *docker.ContainerStats, <-chan error, error) {
+func (u *dockerStatsUsecase) GetStatsStream(ctx context.Context, hostID, containerID string) (<-chan *docker.ContainerStats, <-chan error, error) {
 	if containerID == "" {
 		return nil, nil, fmt.Errorf("container ID is required")
 	}
 
-	statsChan, errChan, err := u.dockerClient.ContainerStatsStream(ctx, containerID)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	statsChan, errChan, err := client.ContainerStatsStream(ctx, containerID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get stats stream: %w", err)
 	}
@@ -42,12 +47,17 @@ func (u *dockerStatsUsecase) GetStatsStream(ctx context.Context, containerID str
 }
 
 // GetStatsOnce gets container stats once (not streaming)
-func (u *dockerStatsUsecase) GetStatsOnce(ctx context.Context, containerID string) (*docker.ContainerStats, error) {
+func (u *dockerStatsUsecase) GetStatsOnce(ctx context.Context, hostID, containerID string) (*docker.ContainerStats, error) {
 	if containerID == "" {
 		return nil, fmt.Errorf("container ID is required")
 	}
 
-	stats, err := u.dockerClient.ContainerStatsOnce(ctx, containerID)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := client.ContainerStatsOnce(ctx, containerID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stats: %w", err)
 	}
@@ -56,12 +66,17 @@ func (u *dockerStatsUsecase) GetStatsOnce(ctx context.Context, containerID strin
 }
 
 // PauseContainer pauses a running container
-func (u *dockerStatsUsecase) PauseContainer(ctx context.Context, containerID string) error {
+func (u *dockerStatsUsecase) PauseContainer(ctx context.Context, hostID, containerID string) error {
 	if containerID == "" {
 		return fmt.Errorf("container ID is required")
 	}
 
-	if err := u.dockerClient.ContainerPause(ctx, containerID); err != nil {
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return err
+	}
+
+	if err := client.ContainerPause(ctx, containerID); err != nil {
 		return fmt.Errorf("failed to pause container: %w", err)
 	}
 
@@ -69,12 +84,17 @@ func (u *dockerStatsUsecase) PauseContainer(ctx context.Context, containerID str
 }
 
 // UnpauseContainer unpauses a paused container
-func (u *dockerStatsUsecase) UnpauseContainer(ctx context.Context, containerID string) error {
+func (u *dockerStatsUsecase) UnpauseContainer(ctx context.Context, hostID, containerID string) error {
 	if containerID == "" {
 		return fmt.Errorf("container ID is required")
 	}
 
-	if err := u.dockerClient.ContainerUnpause(ctx, containerID); err != nil {
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return err
+	}
+
+	if err := client.ContainerUnpause(ctx, containerID); err != nil {
 		return fmt.Errorf("failed to unpause container: %w", err)
 	}
 
@@ -82,7 +102,7 @@ func (u *dockerStatsUsecase) UnpauseContainer(ctx context.Context, containerID s
 }
 
 // CommitContainer commits a container to create a new image
-func (u *dockerStatsUsecase) CommitContainer(ctx context.Context, config docker.ContainerCommitConfig) (string, error) {
+func (u *dockerStatsUsecase) CommitContainer(ctx context.Context, hostID string, config docker.ContainerCommitConfig) (string, error) {
 	if config.ContainerID == "" {
 		return "", fmt.Errorf("container ID is required")
 	}
@@ -95,7 +115,12 @@ func (u *dockerStatsUsecase) CommitContainer(ctx context.Context, config docker.
 		config.Tag = "latest"
 	}
 
-	imageID, err := u.dockerClient.ContainerCommit(ctx, config)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return "", err
+	}
+
+	imageID, err := client.ContainerCommit(ctx, config)
 	if err != nil {
 		return "", fmt.Errorf("failed to commit container: %w", err)
 	}
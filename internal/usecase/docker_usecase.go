@@ -2,21 +2,30 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/docker/docker/api/types/container"
 	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/docker"
 )
 
 type dockerUsecase struct {
 	dockerRepo domain.DockerHostRepository
-	// TODO: Add Docker SDK client
+	clients    DockerClientRegistry
 }
 
 // NewDockerUsecase creates a new Docker use case instance
-func NewDockerUsecase(dockerRepo domain.DockerHostRepository) domain.DockerUsecase {
+func NewDockerUsecase(dockerRepo domain.DockerHostRepository, clients DockerClientRegistry) domain.DockerUsecase {
 	return &dockerUsecase{
 		dockerRepo: dockerRepo,
+		clients:    clients,
 	}
 }
 
@@ -29,7 +38,17 @@ func (u *dockerUsecase) CreateDockerHost(ctx context.Context, host *domain.Docke
 	if host.Endpoint == "" {
 		return errors.New("docker host endpoint is required")
 	}
-	return u.dockerRepo.Create(ctx, host)
+	if err := u.dockerRepo.Create(ctx, host); err != nil {
+		return err
+	}
+
+	// An unreachable daemon does not fail the registration; it is recorded as offline
+	if refreshed, err := u.clients.Refresh(ctx, host.ID); err != nil {
+		log.Printf("Failed to check docker host %s: %v", host.Name, err)
+	} else {
+		*host = *refreshed
+	}
+	return nil
 }
 
 func (u *dockerUsecase) GetDockerHost(ctx context.Context, id string) (*domain.DockerHost, error) {
@@ -56,89 +75,203 @@ func (u *dockerUsecase) UpdateDockerHost(ctx context.Context, host *domain.Docke
 	if host.ID == "" {
 		return errors.New("docker host ID is required")
 	}
-	return u.dockerRepo.Update(ctx, host)
+	if err := u.dockerRepo.Update(ctx, host); err != nil {
+		return err
+	}
+
+	// The endpoint or credentials may have changed
+	u.clients.Invalidate(host.ID)
+	if _, err := u.clients.Refresh(ctx, host.ID); err != nil {
+		log.Printf("Failed to check docker host %s: %v", host.Name, err)
+	}
+	return nil
 }
 
 func (u *dockerUsecase) DeleteDockerHost(ctx context.Context, id string) error {
 	if id == "" {
 		return errors.New("docker host ID is required")
 	}
-	return u.dockerRepo.Delete(ctx, id)
+	if err := u.dockerRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	u.clients.Invalidate(id)
+	return nil
+}
+
+func (u *dockerUsecase) RefreshDockerHost(ctx context.Context, id string) (*domain.DockerHost, error) {
+	if id == "" {
+		return nil, errors.New("docker host ID is required")
+	}
+	return u.clients.Refresh(ctx, id)
 }
 
 // Container Management
-// TODO: Implement with Docker SDK
 
 func (u *dockerUsecase) ListContainers(ctx context.Context, hostID string, all bool) ([]*domain.Container, error) {
 	if hostID == "" {
 		return nil, errors.New("docker host ID is required")
 	}
-	// TODO: Connect to Docker host and list containers
-	return []*domain.Container{}, nil
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := client.ContainerList(ctx, all)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	containers := make([]*domain.Container, 0, len(summaries))
+	for _, summary := range summaries {
+		containers = append(containers, containerFromSummary(hostID, summary))
+	}
+	return containers, nil
 }
 
 func (u *dockerUsecase) GetContainer(ctx context.Context, hostID, containerID string) (*domain.Container, error) {
 	if hostID == "" || containerID == "" {
 		return nil, errors.New("docker host ID and container ID are required")
 	}
-	// TODO: Connect to Docker host and get container details
-	return nil, fmt.Errorf("not implemented")
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	inspect, err := client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	return containerFromInspect(hostID, inspect), nil
 }
 
 func (u *dockerUsecase) CreateContainer(ctx context.Context, hostID string, config interface{}) (*domain.Container, error) {
 	if hostID == "" {
 		return nil, errors.New("docker host ID is required")
 	}
-	// TODO: Connect to Docker host and create container
-	return nil, fmt.Errorf("not implemented")
+
+	// The config arrives as decoded JSON; round-trip it into the client's create config
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid container config: %w", err)
+	}
+	var createConfig docker.ContainerCreateConfig
+	if err := json.Unmarshal(raw, &createConfig); err != nil {
+		return nil, fmt.Errorf("invalid container config: %w", err)
+	}
+	if createConfig.Image == "" {
+		return nil, errors.New("container image is required")
+	}
+
+	// Attribute the container to the requesting user for the image deployment tracker
+	if user := requestUser(ctx); user != "" {
+		if createConfig.Labels == nil {
+			createConfig.Labels = map[string]string{}
+		}
+		createConfig.Labels[changedByLabel] = user
+	}
+
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+	containerID, err := client.ContainerCreate(ctx, createConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+
+	inspect, err := client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect created container: %w", err)
+	}
+	return containerFromInspect(hostID, inspect), nil
 }
 
 func (u *dockerUsecase) StartContainer(ctx context.Context, hostID, containerID string) error {
 	if hostID == "" || containerID == "" {
 		return errors.New("docker host ID and container ID are required")
 	}
-	// TODO: Connect to Docker host and start container
-	return fmt.Errorf("not implemented")
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return err
+	}
+	if err := client.ContainerStart(ctx, containerID); err != nil {
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+	return nil
 }
 
 func (u *dockerUsecase) StopContainer(ctx context.Context, hostID, containerID string, timeout int) error {
 	if hostID == "" || containerID == "" {
 		return errors.New("docker host ID and container ID are required")
 	}
-	// TODO: Connect to Docker host and stop container
-	return fmt.Errorf("not implemented")
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return err
+	}
+	if err := client.ContainerStop(ctx, containerID, timeout); err != nil {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+	return nil
 }
 
 func (u *dockerUsecase) RestartContainer(ctx context.Context, hostID, containerID string, timeout int) error {
 	if hostID == "" || containerID == "" {
 		return errors.New("docker host ID and container ID are required")
 	}
-	// TODO: Connect to Docker host and restart container
-	return fmt.Errorf("not implemented")
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return err
+	}
+	if err := client.ContainerRestart(ctx, containerID, timeout); err != nil {
+		return fmt.Errorf("failed to restart container: %w", err)
+	}
+	return nil
 }
 
 func (u *dockerUsecase) RemoveContainer(ctx context.Context, hostID, containerID string, force bool) error {
 	if hostID == "" || containerID == "" {
 		return errors.New("docker host ID and container ID are required")
 	}
-	// TODO: Connect to Docker host and remove container
-	return fmt.Errorf("not implemented")
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return err
+	}
+	if err := client.ContainerRemove(ctx, containerID, force); err != nil {
+		return fmt.Errorf("failed to remove container: %w", err)
+	}
+	return nil
 }
 
 func (u *dockerUsecase) GetContainerLogs(ctx context.Context, hostID, containerID string, tail int) (string, error) {
 	if hostID == "" || containerID == "" {
 		return "", errors.New("docker host ID and container ID are required")
 	}
-	// TODO: Connect to Docker host and get container logs
-	return "", fmt.Errorf("not implemented")
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return "", err
+	}
+
+	lines := "all"
+	if tail > 0 {
+		lines = strconv.Itoa(tail)
+	}
+	return client.ContainerLogs(ctx, containerID, lines)
 }
 
 func (u *dockerUsecase) GetContainerStats(ctx context.Context, hostID, containerID string) (*domain.ContainerStats, error) {
 	if hostID == "" || containerID == "" {
 		return nil, errors.New("docker host ID and container ID are required")
 	}
-	// TODO: Connect to Docker host and get container stats
-	return nil, fmt.Errorf("not implemented")
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := client.ContainerStatsOnce(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container stats: %w", err)
+	}
+	return containerStatsFromDocker(containerID, stats), nil
 }
 
 // Image Management
@@ -147,24 +280,74 @@ func (u *dockerUsecase) ListImages(ctx context.Context, hostID string) ([]*domai
 	if hostID == "" {
 		return nil, errors.New("docker host ID is required")
 	}
-	// TODO: Connect to Docker host and list images
-	return []*domain.DockerImage{}, nil
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := client.ImageList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+
+	images := make([]*domain.DockerImage, 0, len(summaries))
+	for _, summary := range summaries {
+		images = append(images, &domain.DockerImage{
+			ID:          summary.ID,
+			RepoTags:    summary.RepoTags,
+			RepoDigests: summary.RepoDigests,
+			Size:        summary.Size,
+			Created:     time.Unix(summary.Created, 0),
+			Labels:      summary.Labels,
+			HostID:      hostID,
+		})
+	}
+	return images, nil
 }
 
 func (u *dockerUsecase) PullImage(ctx context.Context, hostID, imageName string) error {
 	if hostID == "" || imageName == "" {
 		return errors.New("docker host ID and image name are required")
 	}
-	// TODO: Connect to Docker host and pull image
-	return fmt.Errorf("not implemented")
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return err
+	}
+
+	progress, err := client.ImagePull(ctx, imageName, docker.AuthConfig{})
+	if err != nil {
+		return err
+	}
+	defer progress.Close()
+
+	// The pull runs until its progress stream is drained; failures are reported inside the stream
+	decoder := json.NewDecoder(progress)
+	for {
+		var message struct {
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&message); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to read pull progress: %w", err)
+		}
+		if message.Error != "" {
+			return fmt.Errorf("failed to pull image: %s", message.Error)
+		}
+	}
 }
 
 func (u *dockerUsecase) RemoveImage(ctx context.Context, hostID, imageID string, force bool) error {
 	if hostID == "" || imageID == "" {
 		return errors.New("docker host ID and image ID are required")
 	}
-	// TODO: Connect to Docker host and remove image
-	return fmt.Errorf("not implemented")
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return err
+	}
+	_, err = client.ImageRemove(ctx, imageID, force)
+	return err
 }
 
 // Network Management
@@ -173,24 +356,67 @@ func (u *dockerUsecase) ListNetworks(ctx context.Context, hostID string) ([]*dom
 	if hostID == "" {
 		return nil, errors.New("docker host ID is required")
 	}
-	// TODO: Connect to Docker host and list networks
-	return []*domain.Network{}, nil
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := client.NetworkList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	networks := make([]*domain.Network, 0, len(summaries))
+	for _, summary := range summaries {
+		networks = append(networks, &domain.Network{
+			ID:      summary.ID,
+			Name:    summary.Name,
+			Driver:  summary.Driver,
+			Scope:   summary.Scope,
+			Labels:  summary.Labels,
+			Options: summary.Options,
+			HostID:  hostID,
+		})
+	}
+	return networks, nil
 }
 
 func (u *dockerUsecase) CreateNetwork(ctx context.Context, hostID, name, driver string) (*domain.Network, error) {
 	if hostID == "" || name == "" {
 		return nil, errors.New("docker host ID and network name are required")
 	}
-	// TODO: Connect to Docker host and create network
-	return nil, fmt.Errorf("not implemented")
+	if driver == "" {
+		driver = "bridge"
+	}
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	networkID, err := client.NetworkCreate(ctx, name, driver)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Network{
+		ID:     networkID,
+		Name:   name,
+		Driver: driver,
+		HostID: hostID,
+	}, nil
 }
 
 func (u *dockerUsecase) RemoveNetwork(ctx context.Context, hostID, networkID string) error {
 	if hostID == "" || networkID == "" {
 		return errors.New("docker host ID and network ID are required")
 	}
-	// TODO: Connect to Docker host and remove network
-	return fmt.Errorf("not implemented")
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return err
+	}
+	if err := client.NetworkRemove(ctx, networkID); err != nil {
+		return fmt.Errorf("failed to remove network: %w", err)
+	}
+	return nil
 }
 
 // Volume Management
@@ -199,22 +425,180 @@ func (u *dockerUsecase) ListVolumes(ctx context.Context, hostID string) ([]*doma
 	if hostID == "" {
 		return nil, errors.New("docker host ID is required")
 	}
-	// TODO: Connect to Docker host and list volumes
-	return []*domain.Volume{}, nil
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := client.VolumeList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	volumes := make([]*domain.Volume, 0, len(summaries))
+	for _, summary := range summaries {
+		volumes = append(volumes, &domain.Volume{
+			Name:       summary.Name,
+			Driver:     summary.Driver,
+			Mountpoint: summary.Mountpoint,
+			Labels:     summary.Labels,
+			Options:    summary.Options,
+			Scope:      summary.Scope,
+			HostID:     hostID,
+		})
+	}
+	return volumes, nil
 }
 
 func (u *dockerUsecase) CreateVolume(ctx context.Context, hostID, name, driver string) (*domain.Volume, error) {
 	if hostID == "" || name == "" {
 		return nil, errors.New("docker host ID and volume name are required")
 	}
-	// TODO: Connect to Docker host and create volume
-	return nil, fmt.Errorf("not implemented")
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := client.VolumeCreate(ctx, name, driver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create volume: %w", err)
+	}
+	return &domain.Volume{
+		Name:       created.Name,
+		Driver:     created.Driver,
+		Mountpoint: created.Mountpoint,
+		Labels:     created.Labels,
+		Options:    created.Options,
+		Scope:      created.Scope,
+		HostID:     hostID,
+	}, nil
 }
 
 func (u *dockerUsecase) RemoveVolume(ctx context.Context, hostID, volumeName string, force bool) error {
 	if hostID == "" || volumeName == "" {
 		return errors.New("docker host ID and volume name are required")
 	}
-	// TODO: Connect to Docker host and remove volume
-	return fmt.Errorf("not implemented")
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return err
+	}
+	if err := client.VolumeRemove(ctx, volumeName, force); err != nil {
+		return fmt.Errorf("failed to remove volume: %w", err)
+	}
+	return nil
+}
+
+// Conversions
+
+func containerFromSummary(hostID string, summary container.Summary) *domain.Container {
+	c := &domain.Container{
+		ID:      summary.ID,
+		Image:   summary.Image,
+		ImageID: summary.ImageID,
+		Command: summary.Command,
+		Created: time.Unix(summary.Created, 0),
+		Status:  domain.ContainerStatus(summary.State),
+		State:   summary.Status,
+		Labels:  summary.Labels,
+		HostID:  hostID,
+	}
+	if len(summary.Names) > 0 {
+		c.Name = strings.TrimPrefix(summary.Names[0], "/")
+	}
+	for _, port := range summary.Ports {
+		mapping := domain.PortMapping{
+			HostIP:        port.IP,
+			ContainerPort: strconv.Itoa(int(port.PrivatePort)),
+			Protocol:      port.Type,
+		}
+		if port.PublicPort != 0 {
+			mapping.HostPort = strconv.Itoa(int(port.PublicPort))
+		}
+		c.Ports = append(c.Ports, mapping)
+	}
+	if summary.NetworkSettings != nil {
+		for name := range summary.NetworkSettings.Networks {
+			c.Networks = append(c.Networks, name)
+		}
+	}
+	for _, mount := range summary.Mounts {
+		c.Mounts = append(c.Mounts, domain.MountPoint{
+			Type:        string(mount.Type),
+			Source:      mount.Source,
+			Destination: mount.Destination,
+			Mode:        mount.Mode,
+		})
+	}
+	return c
+}
+
+func containerFromInspect(hostID string, inspect container.InspectResponse) *domain.Container {
+	c := &domain.Container{HostID: hostID}
+	if inspect.ContainerJSONBase != nil {
+		c.ID = inspect.ID
+		c.Name = strings.TrimPrefix(inspect.Name, "/")
+		c.ImageID = inspect.Image
+		c.Created, _ = time.Parse(time.RFC3339Nano, inspect.Created)
+		if inspect.State != nil {
+			c.Status = domain.ContainerStatus(inspect.State.Status)
+			c.State = inspect.State.Status
+		}
+	}
+	if inspect.Config != nil {
+		c.Image = inspect.Config.Image
+		c.Command = strings.Join(inspect.Config.Cmd, " ")
+		c.Labels = inspect.Config.Labels
+	}
+	if inspect.NetworkSettings != nil {
+		for port, bindings := range inspect.NetworkSettings.Ports {
+			if len(bindings) == 0 {
+				c.Ports = append(c.Ports, domain.PortMapping{ContainerPort: port.Port(), Protocol: port.Proto()})
+			}
+			for _, binding := range bindings {
+				c.Ports = append(c.Ports, domain.PortMapping{
+					HostIP:        binding.HostIP,
+					HostPort:      binding.HostPort,
+					ContainerPort: port.Port(),
+					Protocol:      port.Proto(),
+				})
+			}
+		}
+		for name := range inspect.NetworkSettings.Networks {
+			c.Networks = append(c.Networks, name)
+		}
+	}
+	for _, mount := range inspect.Mounts {
+		c.Mounts = append(c.Mounts, domain.MountPoint{
+			Type:        string(mount.Type),
+			Source:      mount.Source,
+			Destination: mount.Destination,
+			Mode:        mount.Mode,
+		})
+	}
+	return c
+}
+
+// containerStatsFromDocker computes usage the way the Docker CLI does: CPU relative to the
+// previous sample and memory without page cache
+func containerStatsFromDocker(containerID string, stats *docker.ContainerStats) *domain.ContainerStats {
+	result := &domain.ContainerStats{
+		ContainerID: containerID,
+		MemoryLimit: int64(stats.MemoryStats.Limit),
+		PIDs:        int(stats.PidsStats.Current),
+		Timestamp:   stats.Read,
+	}
+
+	result.CPUPercent = stats.CPUPercent()
+	result.MemoryUsage = int64(stats.MemoryUsage())
+	result.MemoryPercent = stats.MemoryPercent()
+
+	rx, tx := stats.NetworkIO()
+	result.NetworkRxBytes, result.NetworkTxBytes = int64(rx), int64(tx)
+	read, write := stats.BlockIO()
+	result.BlockRead, result.BlockWrite = int64(read), int64(write)
+
+	if result.Timestamp.IsZero() {
+		result.Timestamp = time.Now()
+	}
+	return result
 }
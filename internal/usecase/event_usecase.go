@@ -3,90 +3,132 @@ package usecase
 import (
 	"context"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/docker/docker/api/types/events"
 	"github.com/gorilla/websocket"
-	"github.com/unitechio/einfra-be/pkg/docker"
 )
 
+// eventMonitorRetry is the pause before a host's event stream is reopened
+const eventMonitorRetry = 5 * time.Second
+
 // EventUsecase handles Docker event monitoring
 type EventUsecase interface {
-	MonitorEvents(ctx context.Context)
-	Subscribe(conn *websocket.Conn)
-	Unsubscribe(conn *websocket.Conn)
+	// MonitorEvents broadcasts the events of a host until ctx is done; an empty host ID selects the local daemon
+	MonitorEvents(ctx context.Context, hostID string)
+	Subscribe(hostID string, conn *websocket.Conn)
+	Unsubscribe(hostID string, conn *websocket.Conn)
+}
+
+// eventHub is the set of subscribers of one host and the monitor feeding them
+type eventHub struct {
+	clients map[*websocket.Conn]bool
+	cancel  context.CancelFunc
 }
 
 type eventUsecase struct {
-	dockerClient *docker.Client
-	clients      map[*websocket.Conn]bool
-	broadcast    chan events.Message
-	register     chan *websocket.Conn
-	unregister   chan *websocket.Conn
+	clients DockerClientRegistry
+
+	mu   sync.Mutex
+	hubs map[string]*eventHub
 }
 
 // NewEventUsecase creates a new event usecase
-func NewEventUsecase(dockerClient *docker.Client) EventUsecase {
-	u := &eventUsecase{
-		dockerClient: dockerClient,
-		clients:      make(map[*websocket.Conn]bool),
-		broadcast:    make(chan events.Message),
-		register:     make(chan *websocket.Conn),
-		unregister:   make(chan *websocket.Conn),
+func NewEventUsecase(clients DockerClientRegistry) EventUsecase {
+	return &eventUsecase{
+		clients: clients,
+		hubs:    make(map[string]*eventHub),
 	}
-	go u.run()
-	return u
 }
 
-// MonitorEvents starts monitoring Docker events
-func (u *eventUsecase) MonitorEvents(ctx context.Context) {
-	msgs, errs := u.dockerClient.Events(ctx)
-
+// MonitorEvents starts monitoring Docker events, reopening the stream on errors
+func (u *eventUsecase) MonitorEvents(ctx context.Context, hostID string) {
 	for {
-		select {
-		case msg := <-msgs:
-			u.broadcast <- msg
-		case err := <-errs:
-			if err != nil {
-				log.Printf("Error reading docker events: %v", err)
-				return
+		client, err := u.clients.Get(ctx, hostID)
+		if err != nil {
+			log.Printf("Error connecting to docker host %q for events: %v", hostID, err)
+		} else {
+			msgs, errs := client.Events(ctx)
+		stream:
+			for {
+				select {
+				case msg := <-msgs:
+					u.broadcast(hostID, msg)
+				case err := <-errs:
+					if err != nil && ctx.Err() == nil {
+						log.Printf("Error reading docker events of host %q: %v", hostID, err)
+					}
+					break stream
+				case <-ctx.Done():
+					return
+				}
 			}
+		}
+
+		select {
+		case <-time.After(eventMonitorRetry):
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func (u *eventUsecase) run() {
-	for {
-		select {
-		case conn := <-u.register:
-			u.clients[conn] = true
-		case conn := <-u.unregister:
-			if _, ok := u.clients[conn]; ok {
-				delete(u.clients, conn)
-				conn.Close()
-			}
-		case msg := <-u.broadcast:
-			for conn := range u.clients {
-				// Filter relevant events if needed
-				// For now, broadcast everything
-				err := conn.WriteJSON(msg)
-				if err != nil {
-					log.Printf("Error writing to websocket: %v", err)
-					conn.Close()
-					delete(u.clients, conn)
-				}
-			}
+func (u *eventUsecase) broadcast(hostID string, msg events.Message) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	hub, ok := u.hubs[hostID]
+	if !ok {
+		return
+	}
+	for conn := range hub.clients {
+		// Filter relevant events if needed
+		// For now, broadcast everything
+		if err := conn.WriteJSON(msg); err != nil {
+			log.Printf("Error writing to websocket: %v", err)
+			u.removeLocked(hostID, conn)
 		}
 	}
 }
 
-// Subscribe subscribes a client to event updates
-func (u *eventUsecase) Subscribe(conn *websocket.Conn) {
-	u.register <- conn
+// Subscribe subscribes a client to the events of a host, monitoring the host while it has subscribers
+func (u *eventUsecase) Subscribe(hostID string, conn *websocket.Conn) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	hub, ok := u.hubs[hostID]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		hub = &eventHub{clients: make(map[*websocket.Conn]bool), cancel: cancel}
+		u.hubs[hostID] = hub
+		go u.MonitorEvents(ctx, hostID)
+	}
+	hub.clients[conn] = true
 }
 
 // Unsubscribe unsubscribes a client
-func (u *eventUsecase) Unsubscribe(conn *websocket.Conn) {
-	u.unregister <- conn
+func (u *eventUsecase) Unsubscribe(hostID string, conn *websocket.Conn) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.removeLocked(hostID, conn)
+}
+
+// removeLocked closes a subscriber and stops the host's monitor after its last subscriber leaves
+func (u *eventUsecase) removeLocked(hostID string, conn *websocket.Conn) {
+	hub, ok := u.hubs[hostID]
+	if !ok {
+		return
+	}
+	if _, ok := hub.clients[conn]; !ok {
+		return
+	}
+	delete(hub.clients, conn)
+	conn.Close()
+
+	if len(hub.clients) == 0 {
+		hub.cancel()
+		delete(u.hubs, hostID)
+	}
 }
@@ -0,0 +1,404 @@
+package usecase
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/docker"
+)
+
+// Helpers reading and writing files of containers through the Docker archive API, which works on
+// created and stopped containers as well as running ones
+
+const (
+	maxFileUploadSize   = 100 << 20
+	maxFileReadSize     = 1 << 20 // Also the largest file that can be edited
+	maxFileDownloadSize = 1 << 30
+	// maxListScanSize bounds the archive read to list a directory
+	maxListScanSize = 256 << 20
+)
+
+// cleanFilePath resolves a path against the root of a browsed tree; ".." cannot climb above it
+func cleanFilePath(p string) (string, error) {
+	if strings.ContainsRune(p, 0) {
+		return "", fmt.Errorf("%w: %q", domain.ErrInvalidFilePath, p)
+	}
+	return path.Clean("/" + p), nil
+}
+
+// validateFileName checks a name is a single path element
+func validateFileName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\x00") {
+		return fmt.Errorf("%w: invalid name %q", domain.ErrInvalidFilePath, name)
+	}
+	return nil
+}
+
+func withinRoot(p, root string) bool {
+	return root == "/" || p == root || strings.HasPrefix(p, root+"/")
+}
+
+// statArchivePath describes a path of a container and resolves it when it is a symlink. Links leading
+// out of root are refused.
+func statArchivePath(ctx context.Context, cli *docker.Client, containerID, root, target string) (string, container.PathStat, error) {
+	stat, err := cli.ContainerStatPath(ctx, containerID, target)
+	if err != nil {
+		if docker.IsNotFound(err) {
+			return "", stat, fmt.Errorf("%w: %s", domain.ErrFileNotFound, displayPath(target, root))
+		}
+		return "", stat, fmt.Errorf("failed to stat %s: %w", target, err)
+	}
+	if stat.LinkTarget == "" || stat.LinkTarget == target {
+		return target, stat, nil
+	}
+
+	// The daemon resolves links within the container, so the target is already absolute
+	if !withinRoot(stat.LinkTarget, root) {
+		return "", stat, fmt.Errorf("%w: %s links outside of the browsed tree", domain.ErrInvalidFilePath, displayPath(target, root))
+	}
+	resolved, err := cli.ContainerStatPath(ctx, containerID, stat.LinkTarget)
+	if err != nil {
+		if docker.IsNotFound(err) {
+			return "", stat, fmt.Errorf("%w: %s is a broken link", domain.ErrFileNotFound, displayPath(target, root))
+		}
+		return "", stat, fmt.Errorf("failed to stat %s: %w", stat.LinkTarget, err)
+	}
+	return stat.LinkTarget, resolved, nil
+}
+
+// readArchiveFile reads a regular file of a container, refusing files larger than limit. It returns
+// the path the file was found at once symlinks are resolved, and its header with mode and owner.
+func readArchiveFile(ctx context.Context, cli *docker.Client, containerID, root, target string, limit int64) ([]byte, *tar.Header, string, error) {
+	target, stat, err := statArchivePath(ctx, cli, containerID, root, target)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if !stat.Mode.IsRegular() {
+		return nil, nil, "", fmt.Errorf("%w: %s is not a regular file", domain.ErrInvalidFilePath, displayPath(target, root))
+	}
+	if stat.Size > limit {
+		return nil, nil, "", fmt.Errorf("%w: %d bytes, at most %d can be read", domain.ErrFileTooLarge, stat.Size, limit)
+	}
+
+	reader, _, err := cli.CopyFromContainer(ctx, containerID, target)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to copy %s: %w", target, err)
+	}
+	defer reader.Close()
+
+	archive := tar.NewReader(reader)
+	header, err := archive.Next()
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to read archive of %s: %w", target, err)
+	}
+	content, err := io.ReadAll(io.LimitReader(archive, limit+1))
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to read %s: %w", target, err)
+	}
+	if int64(len(content)) > limit {
+		return nil, nil, "", fmt.Errorf("%w: at most %d bytes can be read", domain.ErrFileTooLarge, limit)
+	}
+	return content, header, target, nil
+}
+
+// listArchiveDir lists the entries of a directory of a container from the headers of its archive.
+// The daemon archives the whole tree, so listing stops with ErrFileTooLarge past maxListScanSize.
+func listArchiveDir(ctx context.Context, cli *docker.Client, containerID, root, dir string) ([]domain.FileInfo, error) {
+	target, stat, err := statArchivePath(ctx, cli, containerID, root, dir)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.Mode.IsDir() {
+		return nil, fmt.Errorf("%w: %s is not a directory", domain.ErrInvalidFilePath, displayPath(dir, root))
+	}
+
+	reader, _, err := cli.CopyFromContainer(ctx, containerID, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy %s: %w", target, err)
+	}
+	defer reader.Close()
+
+	archive := tar.NewReader(&cappedReader{r: reader, max: maxListScanSize, err: fmt.Errorf("%w: the directory is too large to list", domain.ErrFileTooLarge)})
+	// The first entry is the directory itself; the others are named after it
+	first, err := archive.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive of %s: %w", target, err)
+	}
+	prefix := strings.TrimSuffix(first.Name, "/")
+
+	files := []domain.FileInfo{}
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := header.Name
+		if prefix == "" || prefix == "." {
+			name = strings.TrimPrefix(name, "./")
+		} else {
+			name = strings.TrimPrefix(name, prefix)
+		}
+		name = strings.Trim(name, "/")
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+		files = append(files, domain.FileInfo{
+			Name:        name,
+			Path:        path.Join(displayPath(dir, root), name),
+			Size:        header.Size,
+			IsDir:       header.Typeflag == tar.TypeDir,
+			IsSymlink:   header.Typeflag == tar.TypeSymlink,
+			ModTime:     header.ModTime.UTC(),
+			Permissions: os.FileMode(header.Mode).Perm().String()[1:],
+			Owner:       strconv.Itoa(header.Uid),
+			Group:       strconv.Itoa(header.Gid),
+		})
+	}
+	sortFileInfos(files)
+	return files, nil
+}
+
+// sortFileInfos sorts directories first, then by name
+func sortFileInfos(files []domain.FileInfo) {
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].IsDir != files[j].IsDir {
+			return files[i].IsDir
+		}
+		return files[i].Name < files[j].Name
+	})
+}
+
+// isTextContent reports whether content can be shown and edited as text
+func isTextContent(content []byte) bool {
+	return utf8.Valid(content) && bytes.IndexByte(content, 0) < 0
+}
+
+// contentHash returns the SHA-256 of content as hex
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// displayPath returns a path of a container relative to the root of the browsed tree
+func displayPath(p, root string) string {
+	if root == "/" {
+		return p
+	}
+	if p == root {
+		return "/"
+	}
+	return strings.TrimPrefix(p, root)
+}
+
+// uploadArchiveFile writes size bytes of file into a directory of a container as a file owned by root
+func uploadArchiveFile(ctx context.Context, cli *docker.Client, containerID, root, dir, filename string, size int64, file io.Reader) error {
+	target, stat, err := statArchivePath(ctx, cli, containerID, root, dir)
+	if err != nil {
+		return err
+	}
+	if !stat.Mode.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", domain.ErrInvalidFilePath, displayPath(dir, root))
+	}
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     filename,
+		Mode:     0o644,
+		Size:     size,
+		ModTime:  time.Now(),
+	}
+	return writeArchiveFile(ctx, cli, containerID, target, header, file)
+}
+
+// writeArchiveFile streams one file into a directory of a container. The file gets the mode and the
+// numeric owner of header, root unless set.
+func writeArchiveFile(ctx context.Context, cli *docker.Client, containerID, dir string, header *tar.Header, content io.Reader) error {
+	reader, writer := io.Pipe()
+	go func() {
+		archive := tar.NewWriter(writer)
+		err := archive.WriteHeader(header)
+		if err == nil {
+			_, err = io.Copy(archive, io.LimitReader(content, header.Size))
+		}
+		if err == nil {
+			// Fails when content was shorter than the header announced
+			err = archive.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	// Copying the UID and GID would hand the file to the user the container runs as instead
+	err := cli.CopyToContainer(ctx, containerID, dir, reader, false)
+	reader.CloseWithError(err)
+	if err != nil {
+		if docker.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", domain.ErrFileNotFound, dir)
+		}
+		return fmt.Errorf("failed to copy %s into %s: %w", header.Name, dir, err)
+	}
+	return nil
+}
+
+// downloadArchivePath streams a regular file as is, or a directory as a tar or zip archive. onClose
+// runs once the download is closed.
+func downloadArchivePath(ctx context.Context, cli *docker.Client, containerID, root, target, name, format string, onClose func()) (*domain.FileDownload, error) {
+	target, stat, err := statArchivePath(ctx, cli, containerID, root, target)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.Mode.IsRegular() && !stat.Mode.IsDir() {
+		return nil, fmt.Errorf("%w: %s is neither a file nor a directory", domain.ErrInvalidFilePath, displayPath(target, root))
+	}
+	if stat.Mode.IsRegular() && stat.Size > maxFileDownloadSize {
+		return nil, fmt.Errorf("%w: %d bytes, at most %d can be downloaded", domain.ErrFileTooLarge, stat.Size, int64(maxFileDownloadSize))
+	}
+
+	reader, _, err := cli.CopyFromContainer(ctx, containerID, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy %s: %w", target, err)
+	}
+	closeAll := func() error {
+		err := reader.Close()
+		onClose()
+		return err
+	}
+
+	if stat.Mode.IsRegular() {
+		archive := tar.NewReader(reader)
+		if _, err := archive.Next(); err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to read archive of %s: %w", target, err)
+		}
+		return &domain.FileDownload{
+			Name:        name,
+			ContentType: "application/octet-stream",
+			Size:        stat.Size,
+			Content:     &downloadReadCloser{Reader: archive, close: closeAll},
+		}, nil
+	}
+
+	if format == "zip" {
+		zipReader, zipWriter := io.Pipe()
+		go func() {
+			zipWriter.CloseWithError(tarToZip(zipWriter, newDownloadCappedReader(reader)))
+		}()
+		return &domain.FileDownload{
+			Name:        name + ".zip",
+			ContentType: "application/zip",
+			Size:        -1,
+			Content: &downloadReadCloser{Reader: zipReader, close: func() error {
+				zipReader.Close()
+				return closeAll()
+			}},
+		}, nil
+	}
+	return &domain.FileDownload{
+		Name:        name + ".tar",
+		ContentType: "application/x-tar",
+		Size:        -1,
+		Content:     &downloadReadCloser{Reader: newDownloadCappedReader(reader), close: closeAll},
+	}, nil
+}
+
+// tarToZip converts a tar stream to a zip archive; links are stored as links, devices and pipes skipped
+func tarToZip(w io.Writer, r io.Reader) error {
+	archive := tar.NewReader(r)
+	zipped := zip.NewWriter(w)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var content io.Reader
+		switch header.Typeflag {
+		case tar.TypeDir:
+			header.Name = strings.TrimSuffix(header.Name, "/") + "/"
+		case tar.TypeReg:
+			content = archive
+		case tar.TypeSymlink:
+			content = strings.NewReader(header.Linkname)
+		default:
+			continue
+		}
+
+		zipHeader, err := zip.FileInfoHeader(header.FileInfo())
+		if err != nil {
+			return err
+		}
+		zipHeader.Name = header.Name
+		if header.Typeflag == tar.TypeReg {
+			zipHeader.Method = zip.Deflate
+		}
+		entry, err := zipped.CreateHeader(zipHeader)
+		if err != nil {
+			return err
+		}
+		if content != nil {
+			if _, err := io.Copy(entry, content); err != nil {
+				return err
+			}
+		}
+	}
+	return zipped.Close()
+}
+
+// cappedReader fails with err once more than max bytes were read
+type cappedReader struct {
+	r    io.Reader
+	max  int64
+	read int64
+	err  error
+}
+
+func newDownloadCappedReader(r io.Reader) *cappedReader {
+	return &cappedReader{r: r, max: maxFileDownloadSize, err: fmt.Errorf("%w: at most %d bytes can be downloaded", domain.ErrFileTooLarge, maxFileDownloadSize)}
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	if c.read > c.max {
+		return n, c.err
+	}
+	return n, err
+}
+
+type downloadReadCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (d *downloadReadCloser) Close() error {
+	return d.close()
+}
+
+// removeHelperContainer removes a helper container, even when the request that created it was cancelled
+func removeHelperContainer(ctx context.Context, cli *docker.Client, containerID string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	if err := cli.ContainerRemove(ctx, containerID, true); err != nil {
+		log.Printf("Failed to remove helper container %s: %v", containerID, err)
+	}
+}
@@ -1,160 +1,538 @@
 package usecase
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
-	"path/filepath"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/docker"
 )
 
-// FileBrowserUsecase handles file browser operations for Docker volumes
+// FileBrowserUsecase handles file browser operations for Docker volumes and containers
 type FileBrowserUsecase interface {
-	ListFiles(ctx context.Context, volumeName, path string) ([]domain.FileInfo, error)
-	UploadFile(ctx context.Context, volumeName, path, filename string, file io.Reader) error
-	DownloadFile(ctx context.Context, volumeName, path string) (io.ReadCloser, error)
-	DeleteFile(ctx context.Context, volumeName, path string) error
-	CreateFolder(ctx context.Context, volumeName, path, folderName string) error
-	ReadFile(ctx context.Context, volumeName, path string) (string, error)
+	ListFiles(ctx context.Context, hostID, volumeName, path string) ([]domain.FileInfo, error)
+	// UploadFile writes size bytes of file into a directory of a volume, replacing a file of that name
+	UploadFile(ctx context.Context, hostID, volumeName, path, filename string, size int64, file io.Reader) error
+	// DownloadFile downloads a file, or a directory as a tar or zip archive
+	DownloadFile(ctx context.Context, hostID, volumeName, path, format string) (*domain.FileDownload, error)
+	DeleteFile(ctx context.Context, hostID, volumeName, path string) error
+	CreateFolder(ctx context.Context, hostID, volumeName, path, folderName string) error
+	ReadFile(ctx context.Context, hostID, volumeName, path string) (string, error)
+
+	// Files of containers, read and written through the archive API without running anything in them
+	ListContainerFiles(ctx context.Context, hostID, containerID, path string) ([]domain.FileInfo, error)
+	DownloadContainerFile(ctx context.Context, hostID, containerID, path, format string) (*domain.FileDownload, error)
+	UploadContainerFile(ctx context.Context, hostID, containerID, path, filename string, size int64, file io.Reader) error
+	// ReadContainerFile reads a text file along with the hash to edit it with
+	ReadContainerFile(ctx context.Context, hostID, containerID, path string) (*domain.FileContent, error)
+	// WriteContainerFile edits a text file, keeping its mode and owner, unless it changed since the
+	// content of req.Hash was read. An empty hash creates the file instead.
+	WriteContainerFile(ctx context.Context, hostID, containerID string, req domain.FileWriteRequest) (*domain.FileContent, error)
 }
 
+const (
+	// volumeHelperImage runs the short-lived containers the volumes are browsed through
+	volumeHelperImage = "busybox:1.36"
+	// volumeMountPath is where helper containers mount the browsed volume
+	volumeMountPath = "/volume"
+	// volumeHelperLabel marks helper containers, with the volume they mount as value
+	volumeHelperLabel = "io.einfra.volume-browser"
+
+	// Exit codes of the helper scripts
+	volumeHelperExitNotFound = 3
+	volumeHelperExitExists   = 4
+)
+
+// Scripts of the helper containers. Paths are passed as arguments, never spliced into the script.
+const (
+	listFilesScript    = `[ -d "$1" ] || exit 3; find "$1" -mindepth 1 -maxdepth 1 -exec stat -c '%f %s %Y %u %g %n' {} +`
+	deleteFileScript   = `[ -e "$1" ] || [ -L "$1" ] || exit 3; rm -rf -- "$1"`
+	createFolderScript = `[ -d "$1" ] || exit 3; [ ! -e "$2" ] && [ ! -L "$2" ] || exit 4; mkdir -- "$2"`
+)
+
 type fileBrowserUsecase struct {
-	// TODO: Add Docker client for volume operations
+	dockerClients DockerClientRegistry
+	auditUsecase  AuditUsecase
 }
 
 // NewFileBrowserUsecase creates a new file browser usecase
-func NewFileBrowserUsecase() FileBrowserUsecase {
-	return &fileBrowserUsecase{}
+func NewFileBrowserUsecase(dockerClients DockerClientRegistry, auditUsecase AuditUsecase) FileBrowserUsecase {
+	return &fileBrowserUsecase{
+		dockerClients: dockerClients,
+		auditUsecase:  auditUsecase,
+	}
 }
 
-// ListFiles lists files in a volume path
-func (u *fileBrowserUsecase) ListFiles(ctx context.Context, volumeName, path string) ([]domain.FileInfo, error) {
-	if volumeName == "" {
-		return nil, fmt.Errorf("volume name is required")
+// ListFiles lists files in a volume path, directories first
+func (u *fileBrowserUsecase) ListFiles(ctx context.Context, hostID, volumeName, dir string) ([]domain.FileInfo, error) {
+	dir, err := cleanFilePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := u.volumeClient(ctx, hostID, volumeName)
+	if err != nil {
+		return nil, err
 	}
 
-	// TODO: Implement actual Docker volume file listing
-	// This would involve:
-	// 1. Creating a temporary container with the volume mounted
-	// 2. Executing 'ls -la' command
-	// 3. Parsing the output
-	// 4. Removing the temporary container
-
-	// Placeholder response
-	files := []domain.FileInfo{
-		{
-			Name:        "example.txt",
-			Path:        filepath.Join(path, "example.txt"),
-			Size:        1024,
-			IsDir:       false,
-			Permissions: "rw-r--r--",
-		},
-		{
-			Name:        "folder",
-			Path:        filepath.Join(path, "folder"),
-			Size:        0,
-			IsDir:       true,
-			Permissions: "rwxr-xr-x",
-		},
+	output, err := runVolumeHelper(ctx, cli, volumeName, true, listFilesScript, path.Join(volumeMountPath, dir))
+	if err != nil {
+		return nil, err
 	}
 
+	files := []domain.FileInfo{}
+	for _, line := range strings.Split(output, "\n") {
+		if file, ok := parseStatLine(line); ok {
+			files = append(files, file)
+		}
+	}
+	sortFileInfos(files)
 	return files, nil
 }
 
-// UploadFile uploads a file to a volume
-func (u *fileBrowserUsecase) UploadFile(ctx context.Context, volumeName, path, filename string, file io.Reader) error {
-	if volumeName == "" {
-		return fmt.Errorf("volume name is required")
+// parseStatLine parses a line of listFilesScript: raw mode in hex, size, mtime, uid, gid and path
+func parseStatLine(line string) (domain.FileInfo, bool) {
+	fields := strings.SplitN(line, " ", 6)
+	if len(fields) != 6 || !strings.HasPrefix(fields[5], volumeMountPath+"/") {
+		return domain.FileInfo{}, false
+	}
+	mode, err := strconv.ParseUint(fields[0], 16, 32)
+	if err != nil {
+		return domain.FileInfo{}, false
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return domain.FileInfo{}, false
+	}
+	mtime, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return domain.FileInfo{}, false
+	}
+
+	const typeMask, typeDir, typeSymlink = 0o170000, 0o040000, 0o120000
+	filePath := strings.TrimPrefix(fields[5], volumeMountPath)
+	return domain.FileInfo{
+		Name:        path.Base(filePath),
+		Path:        filePath,
+		Size:        size,
+		IsDir:       mode&typeMask == typeDir,
+		IsSymlink:   mode&typeMask == typeSymlink,
+		ModTime:     time.Unix(mtime, 0).UTC(),
+		Permissions: os.FileMode(mode).Perm().String()[1:],
+		Owner:       fields[3],
+		Group:       fields[4],
+	}, true
+}
+
+// UploadFile uploads a file to a volume directory
+func (u *fileBrowserUsecase) UploadFile(ctx context.Context, hostID, volumeName, dir, filename string, size int64, file io.Reader) error {
+	dir, err := cleanFilePath(dir)
+	if err != nil {
+		return err
+	}
+	if err := validateFileName(filename); err != nil {
+		return err
+	}
+	if size < 0 || size > maxFileUploadSize {
+		return fmt.Errorf("%w: at most %d bytes can be uploaded", domain.ErrFileTooLarge, maxFileUploadSize)
+	}
+	cli, err := u.volumeClient(ctx, hostID, volumeName)
+	if err != nil {
+		return err
+	}
+
+	err = func() error {
+		helperID, err := createVolumeHelper(ctx, cli, volumeName, false, nil)
+		if err != nil {
+			return err
+		}
+		defer removeHelperContainer(ctx, cli, helperID)
+		return uploadArchiveFile(ctx, cli, helperID, volumeMountPath, path.Join(volumeMountPath, dir), filename, size, file)
+	}()
+	u.audit(ctx, domain.AuditActionCreate, "volume", hostID, volumeName, path.Join(dir, filename), "upload", err)
+	return err
+}
+
+// DownloadFile downloads a file from a volume, or a directory as a tar or zip archive
+func (u *fileBrowserUsecase) DownloadFile(ctx context.Context, hostID, volumeName, filePath, format string) (*domain.FileDownload, error) {
+	filePath, err := cleanFilePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if format != "" && format != "tar" && format != "zip" {
+		return nil, fmt.Errorf("%w: unknown archive format %q", domain.ErrInvalidFilePath, format)
+	}
+	cli, err := u.volumeClient(ctx, hostID, volumeName)
+	if err != nil {
+		return nil, err
 	}
 
-	if filename == "" {
-		return fmt.Errorf("filename is required")
+	helperID, err := createVolumeHelper(ctx, cli, volumeName, true, nil)
+	if err != nil {
+		return nil, err
+	}
+	name := path.Base(filePath)
+	if filePath == "/" {
+		name = volumeName
+	}
+	removeHelper := func() { removeHelperContainer(ctx, cli, helperID) }
+	download, err := downloadArchivePath(ctx, cli, helperID, volumeMountPath, path.Join(volumeMountPath, filePath), name, format, removeHelper)
+	if err != nil {
+		removeHelper()
+		return nil, err
 	}
+	return download, nil
+}
 
-	// TODO: Implement actual file upload
-	// This would involve:
-	// 1. Creating a temporary container with the volume mounted
-	// 2. Copying the file into the container
-	// 3. Removing the temporary container
+// DeleteFile deletes a file or a directory with its content from a volume
+func (u *fileBrowserUsecase) DeleteFile(ctx context.Context, hostID, volumeName, filePath string) error {
+	filePath, err := cleanFilePath(filePath)
+	if err != nil {
+		return err
+	}
+	if filePath == "/" {
+		return fmt.Errorf("%w: the root of a volume cannot be deleted", domain.ErrInvalidFilePath)
+	}
+	cli, err := u.volumeClient(ctx, hostID, volumeName)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	_, err = runVolumeHelper(ctx, cli, volumeName, false, deleteFileScript, path.Join(volumeMountPath, filePath))
+	u.audit(ctx, domain.AuditActionDelete, "volume", hostID, volumeName, filePath, "delete", err)
+	return err
 }
 
-// DownloadFile downloads a file from a volume
-func (u *fileBrowserUsecase) DownloadFile(ctx context.Context, volumeName, path string) (io.ReadCloser, error) {
-	if volumeName == "" {
-		return nil, fmt.Errorf("volume name is required")
+// CreateFolder creates a folder in a volume directory
+func (u *fileBrowserUsecase) CreateFolder(ctx context.Context, hostID, volumeName, dir, folderName string) error {
+	dir, err := cleanFilePath(dir)
+	if err != nil {
+		return err
 	}
+	if err := validateFileName(folderName); err != nil {
+		return err
+	}
+	cli, err := u.volumeClient(ctx, hostID, volumeName)
+	if err != nil {
+		return err
+	}
+
+	parent := path.Join(volumeMountPath, dir)
+	_, err = runVolumeHelper(ctx, cli, volumeName, false, createFolderScript, parent, path.Join(parent, folderName))
+	u.audit(ctx, domain.AuditActionCreate, "volume", hostID, volumeName, path.Join(dir, folderName), "mkdir", err)
+	return err
+}
 
-	if path == "" {
-		return nil, fmt.Errorf("path is required")
+// ReadFile reads a text file from a volume
+func (u *fileBrowserUsecase) ReadFile(ctx context.Context, hostID, volumeName, filePath string) (string, error) {
+	filePath, err := cleanFilePath(filePath)
+	if err != nil {
+		return "", err
+	}
+	cli, err := u.volumeClient(ctx, hostID, volumeName)
+	if err != nil {
+		return "", err
 	}
 
-	// TODO: Implement actual file download
-	// This would involve:
-	// 1. Creating a temporary container with the volume mounted
-	// 2. Copying the file from the container
-	// 3. Returning the file content
-	// 4. Removing the temporary container
+	helperID, err := createVolumeHelper(ctx, cli, volumeName, true, nil)
+	if err != nil {
+		return "", err
+	}
+	defer removeHelperContainer(ctx, cli, helperID)
 
-	return nil, fmt.Errorf("not implemented")
+	content, _, _, err := readArchiveFile(ctx, cli, helperID, volumeMountPath, path.Join(volumeMountPath, filePath), maxFileReadSize)
+	if err != nil {
+		return "", err
+	}
+	if !isTextContent(content) {
+		return "", fmt.Errorf("%w: %s", domain.ErrFileNotText, filePath)
+	}
+	return string(content), nil
 }
 
-// DeleteFile deletes a file from a volume
-func (u *fileBrowserUsecase) DeleteFile(ctx context.Context, volumeName, path string) error {
-	if volumeName == "" {
-		return fmt.Errorf("volume name is required")
+// ListContainerFiles lists files in a container path, directories first
+func (u *fileBrowserUsecase) ListContainerFiles(ctx context.Context, hostID, containerID, dir string) ([]domain.FileInfo, error) {
+	dir, err := cleanFilePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := u.containerClient(ctx, hostID, containerID)
+	if err != nil {
+		return nil, err
+	}
+	return listArchiveDir(ctx, cli, containerID, "/", dir)
+}
+
+// DownloadContainerFile downloads a file from a container, or a directory as a tar or zip archive
+func (u *fileBrowserUsecase) DownloadContainerFile(ctx context.Context, hostID, containerID, filePath, format string) (*domain.FileDownload, error) {
+	filePath, err := cleanFilePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if format != "" && format != "tar" && format != "zip" {
+		return nil, fmt.Errorf("%w: unknown archive format %q", domain.ErrInvalidFilePath, format)
+	}
+	cli, err := u.containerClient(ctx, hostID, containerID)
+	if err != nil {
+		return nil, err
 	}
 
-	if path == "" {
-		return fmt.Errorf("path is required")
+	name := path.Base(filePath)
+	if filePath == "/" {
+		name = "rootfs"
 	}
+	return downloadArchivePath(ctx, cli, containerID, "/", filePath, name, format, func() {})
+}
 
-	// TODO: Implement actual file deletion
-	// This would involve:
-	// 1. Creating a temporary container with the volume mounted
-	// 2. Executing 'rm' command
-	// 3. Removing the temporary container
+// UploadContainerFile uploads a file to a container directory
+func (u *fileBrowserUsecase) UploadContainerFile(ctx context.Context, hostID, containerID, dir, filename string, size int64, file io.Reader) error {
+	dir, err := cleanFilePath(dir)
+	if err != nil {
+		return err
+	}
+	if err := validateFileName(filename); err != nil {
+		return err
+	}
+	if size < 0 || size > maxFileUploadSize {
+		return fmt.Errorf("%w: at most %d bytes can be uploaded", domain.ErrFileTooLarge, maxFileUploadSize)
+	}
+	cli, err := u.containerClient(ctx, hostID, containerID)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	err = uploadArchiveFile(ctx, cli, containerID, "/", dir, filename, size, file)
+	u.audit(ctx, domain.AuditActionCreate, "container", hostID, containerID, path.Join(dir, filename), "upload", err)
+	return err
 }
 
-// CreateFolder creates a folder in a volume
-func (u *fileBrowserUsecase) CreateFolder(ctx context.Context, volumeName, path, folderName string) error {
-	if volumeName == "" {
-		return fmt.Errorf("volume name is required")
+// ReadContainerFile reads a text file from a container
+func (u *fileBrowserUsecase) ReadContainerFile(ctx context.Context, hostID, containerID, filePath string) (*domain.FileContent, error) {
+	filePath, err := cleanFilePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := u.containerClient(ctx, hostID, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	content, header, _, err := readArchiveFile(ctx, cli, containerID, "/", filePath, maxFileReadSize)
+	if err != nil {
+		return nil, err
+	}
+	if !isTextContent(content) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrFileNotText, filePath)
 	}
+	return newFileContent(filePath, content, header), nil
+}
 
-	if folderName == "" {
-		return fmt.Errorf("folder name is required")
+// WriteContainerFile writes a text file of a container. Edits are checked against the hash of the
+// content they are based on; the check and the write are not atomic, so a write racing with another
+// process in the container can still be lost.
+func (u *fileBrowserUsecase) WriteContainerFile(ctx context.Context, hostID, containerID string, req domain.FileWriteRequest) (*domain.FileContent, error) {
+	filePath, err := cleanFilePath(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	if filePath == "/" {
+		return nil, fmt.Errorf("%w: / is not a file", domain.ErrInvalidFilePath)
+	}
+	content := []byte(req.Content)
+	if len(content) > maxFileReadSize {
+		return nil, fmt.Errorf("%w: at most %d bytes can be written", domain.ErrFileTooLarge, maxFileReadSize)
+	}
+	if !isTextContent(content) {
+		return nil, fmt.Errorf("%w: content is not UTF-8 text", domain.ErrFileNotText)
+	}
+	cli, err := u.containerClient(ctx, hostID, containerID)
+	if err != nil {
+		return nil, err
 	}
 
-	// TODO: Implement actual folder creation
-	// This would involve:
-	// 1. Creating a temporary container with the volume mounted
-	// 2. Executing 'mkdir' command
-	// 3. Removing the temporary container
+	var header *tar.Header
+	err = func() error {
+		dir := path.Dir(filePath)
+		header = &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     path.Base(filePath),
+			Mode:     0o644,
+		}
+		if req.Hash == "" {
+			if _, err := cli.ContainerStatPath(ctx, containerID, filePath); err == nil {
+				return fmt.Errorf("%w: %s", domain.ErrFileExists, filePath)
+			} else if !docker.IsNotFound(err) {
+				return fmt.Errorf("failed to stat %s: %w", filePath, err)
+			}
+			target, stat, err := statArchivePath(ctx, cli, containerID, "/", dir)
+			if err != nil {
+				return err
+			}
+			if !stat.Mode.IsDir() {
+				return fmt.Errorf("%w: %s is not a directory", domain.ErrInvalidFilePath, dir)
+			}
+			dir = target
+		} else {
+			current, currentHeader, resolved, err := readArchiveFile(ctx, cli, containerID, "/", filePath, maxFileReadSize)
+			if err != nil {
+				return err
+			}
+			if !isTextContent(current) {
+				return fmt.Errorf("%w: %s", domain.ErrFileNotText, filePath)
+			}
+			if contentHash(current) != req.Hash {
+				return fmt.Errorf("%w: %s", domain.ErrFileChanged, filePath)
+			}
+			// Links are written through, so the file keeps its mode and owner where it really lives
+			dir = path.Dir(resolved)
+			header.Name = path.Base(resolved)
+			header.Mode = currentHeader.Mode
+			header.Uid, header.Gid = currentHeader.Uid, currentHeader.Gid
+			header.Uname, header.Gname = currentHeader.Uname, currentHeader.Gname
+		}
+		header.Size = int64(len(content))
+		header.ModTime = time.Now()
+		return writeArchiveFile(ctx, cli, containerID, dir, header, bytes.NewReader(content))
+	}()
+	action, operation := domain.AuditActionUpdate, "edit"
+	if req.Hash == "" {
+		action, operation = domain.AuditActionCreate, "create"
+	}
+	u.audit(ctx, action, "container", hostID, containerID, filePath, operation, err)
+	if err != nil {
+		return nil, err
+	}
+	return newFileContent(filePath, content, header), nil
+}
 
-	return nil
+// newFileContent describes a text file from its archive header
+func newFileContent(filePath string, content []byte, header *tar.Header) *domain.FileContent {
+	return &domain.FileContent{
+		Path:        filePath,
+		Content:     string(content),
+		Hash:        contentHash(content),
+		Size:        int64(len(content)),
+		Permissions: os.FileMode(header.Mode).Perm().String()[1:],
+		Owner:       strconv.Itoa(header.Uid),
+		Group:       strconv.Itoa(header.Gid),
+		ModTime:     header.ModTime.UTC(),
+	}
 }
 
-// ReadFile reads a text file from a volume
-func (u *fileBrowserUsecase) ReadFile(ctx context.Context, volumeName, path string) (string, error) {
+// containerClient returns the client of a host once the container is known to exist on it
+func (u *fileBrowserUsecase) containerClient(ctx context.Context, hostID, containerID string) (*docker.Client, error) {
+	if containerID == "" {
+		return nil, fmt.Errorf("container ID is required")
+	}
+	cli, err := u.dockerClients.Get(ctx, hostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get docker client: %w", err)
+	}
+	if _, err := cli.ContainerInspect(ctx, containerID); err != nil {
+		if docker.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: container %s", domain.ErrFileNotFound, containerID)
+		}
+		return nil, fmt.Errorf("failed to inspect container %s: %w", containerID, err)
+	}
+	return cli, nil
+}
+
+// volumeClient returns the client of a host once the volume is known to exist on it; mounting a
+// missing volume into a helper would create it
+func (u *fileBrowserUsecase) volumeClient(ctx context.Context, hostID, volumeName string) (*docker.Client, error) {
 	if volumeName == "" {
-		return "", fmt.Errorf("volume name is required")
+		return nil, fmt.Errorf("volume name is required")
+	}
+	cli, err := u.dockerClients.Get(ctx, hostID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get docker client: %w", err)
+	}
+	if _, err := cli.VolumeInspect(ctx, volumeName); err != nil {
+		if docker.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: volume %s", domain.ErrFileNotFound, volumeName)
+		}
+		return nil, fmt.Errorf("failed to inspect volume %s: %w", volumeName, err)
+	}
+	return cli, nil
+}
+
+// createVolumeHelper creates a helper container with the volume mounted. It is left unstarted
+// unless it has a command to run: the archive API works on created containers.
+func createVolumeHelper(ctx context.Context, cli *docker.Client, volumeName string, readOnly bool, cmd []string) (string, error) {
+	if err := cli.ImagePullIfMissing(ctx, volumeHelperImage); err != nil {
+		return "", err
+	}
+	bind := volumeName + ":" + volumeMountPath
+	if readOnly {
+		bind += ":ro"
+	}
+	id, err := cli.ContainerCreate(ctx, docker.ContainerCreateConfig{
+		Image:  volumeHelperImage,
+		Cmd:    cmd,
+		Labels: map[string]string{volumeHelperLabel: volumeName},
+		Binds:  []string{bind},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create helper container: %w", err)
+	}
+	return id, nil
+}
+
+// runVolumeHelper runs a script in a helper container and returns its output
+func runVolumeHelper(ctx context.Context, cli *docker.Client, volumeName string, readOnly bool, script string, args ...string) (string, error) {
+	helperID, err := createVolumeHelper(ctx, cli, volumeName, readOnly, append([]string{"sh", "-c", script, "sh"}, args...))
+	if err != nil {
+		return "", err
+	}
+	defer removeHelperContainer(ctx, cli, helperID)
+
+	if err := cli.ContainerStart(ctx, helperID); err != nil {
+		return "", fmt.Errorf("failed to start helper container: %w", err)
+	}
+	code, err := cli.ContainerWait(ctx, helperID)
+	if err != nil {
+		return "", fmt.Errorf("failed to wait for helper container: %w", err)
+	}
+	output, err := cli.ContainerLogs(ctx, helperID, "all")
+	if err != nil {
+		return "", fmt.Errorf("failed to read output of helper container: %w", err)
 	}
 
-	if path == "" {
-		return "", fmt.Errorf("path is required")
+	switch code {
+	case 0:
+		return output, nil
+	case volumeHelperExitNotFound:
+		return "", fmt.Errorf("%w: %s", domain.ErrFileNotFound, strings.TrimPrefix(args[0], volumeMountPath))
+	case volumeHelperExitExists:
+		return "", fmt.Errorf("%w: %s", domain.ErrFileExists, strings.TrimPrefix(args[len(args)-1], volumeMountPath))
 	}
+	return "", fmt.Errorf("helper container exited with code %d: %s", code, output)
+}
 
-	// TODO: Implement actual file reading
-	// This would involve:
-	// 1. Creating a temporary container with the volume mounted
-	// 2. Executing 'cat' command
-	// 3. Returning the content
-	// 4. Removing the temporary container
+// audit records a write to a volume or container in the audit log as the user carried by ctx
+func (u *fileBrowserUsecase) audit(ctx context.Context, action domain.AuditAction, kind, hostID, name, filePath, operation string, opErr error) {
+	if u.auditUsecase == nil {
+		return
+	}
 
-	return "File content placeholder", nil
+	resourceID := name
+	if hostID != "" {
+		resourceID = hostID + "/" + name
+	}
+	description := fmt.Sprintf("File browser %s of %s in %s %s", operation, filePath, kind, name)
+	entry := contextAuditLog(ctx, action, "docker_"+kind, resourceID, description, opErr)
+	entry.Metadata = map[string]interface{}{
+		"host_id":   hostID,
+		kind:        name,
+		"path":      filePath,
+		"operation": operation,
+	}
+	_ = u.auditUsecase.Log(ctx, entry)
 }
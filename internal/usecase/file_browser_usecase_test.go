@@ -0,0 +1,507 @@
+package usecase_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/docker"
+)
+
+// volumeDaemon is a Docker Engine API whose only volume, "data", is a local directory. Helper
+// containers run their command with the local shell, with /volume mapped to that directory. Its only
+// other container, "app", has another local directory as file system.
+type volumeDaemon struct {
+	mu      sync.Mutex
+	root    string
+	rootfs  string
+	nextID  int
+	helpers map[string]*volumeHelper
+	created []container.CreateRequest
+	removed int
+	copied  []copiedFile
+}
+
+// copiedFile is a file extracted into the "app" container
+type copiedFile struct {
+	header     *tar.Header
+	copyUIDGID bool
+}
+
+type volumeHelper struct {
+	config container.CreateRequest
+	output []byte
+	code   int
+}
+
+var volumeDaemonVersion = regexp.MustCompile(`^/v[0-9.]+`)
+
+func newVolumeDaemon(t *testing.T) (*volumeDaemon, *docker.Client) {
+	d := &volumeDaemon{root: t.TempDir(), rootfs: t.TempDir(), helpers: map[string]*volumeHelper{}}
+	server := httptest.NewServer(d)
+	t.Cleanup(server.Close)
+
+	client, err := docker.NewClient("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return d, client
+}
+
+// local maps a path of a container to the directory backing it: the volume for helper containers
+func (d *volumeDaemon) local(containerID, p string) (string, bool) {
+	if containerID == "app" {
+		return filepath.Join(d.rootfs, p), path.IsAbs(p)
+	}
+	if p != "/volume" && !strings.HasPrefix(p, "/volume/") {
+		return "", false
+	}
+	return filepath.Join(d.root, strings.TrimPrefix(p, "/volume")), true
+}
+
+func (d *volumeDaemon) stat(containerID, p string) (container.PathStat, bool) {
+	local, ok := d.local(containerID, p)
+	if !ok {
+		return container.PathStat{}, false
+	}
+	info, err := os.Lstat(local)
+	if err != nil {
+		return container.PathStat{}, false
+	}
+	stat := container.PathStat{Name: info.Name(), Size: info.Size(), Mode: info.Mode(), Mtime: info.ModTime()}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, _ := os.Readlink(local)
+		if !path.IsAbs(target) {
+			target = path.Join(path.Dir(p), target)
+		}
+		stat.LinkTarget = target
+	}
+	return stat, true
+}
+
+func (d *volumeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p := volumeDaemonVersion.ReplaceAllString(r.URL.Path, "")
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	w.Header().Set("Api-Version", "1.47")
+	switch {
+	case p == "/_ping":
+		w.Write([]byte("OK"))
+	case p == "/volumes/data":
+		json.NewEncoder(w).Encode(map[string]string{"Name": "data"})
+	case parts[0] == "volumes":
+		http.Error(w, `{"message":"no such volume"}`, http.StatusNotFound)
+	case parts[0] == "images" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]string{"Id": "sha256:busybox"})
+
+	case p == "/containers/create":
+		var req container.CreateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		d.nextID++
+		id := fmt.Sprintf("helper-%d", d.nextID)
+		d.helpers[id] = &volumeHelper{config: req}
+		d.created = append(d.created, req)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(container.CreateResponse{ID: id})
+	case len(parts) >= 2 && parts[0] == "containers" && d.helpers[parts[1]] == nil && parts[1] != "app":
+		http.Error(w, `{"message":"no such container"}`, http.StatusNotFound)
+	case r.Method == http.MethodDelete:
+		delete(d.helpers, parts[1])
+		d.removed++
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 3 && parts[2] == "start":
+		d.run(d.helpers[parts[1]])
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 3 && parts[2] == "wait":
+		json.NewEncoder(w).Encode(container.WaitResponse{StatusCode: int64(d.helpers[parts[1]].code)})
+	case len(parts) == 3 && parts[2] == "json":
+		json.NewEncoder(w).Encode(map[string]interface{}{"Id": parts[1], "Config": map[string]bool{"Tty": false}})
+	case len(parts) == 3 && parts[2] == "logs":
+		stdcopy.NewStdWriter(w, stdcopy.Stdout).Write(d.helpers[parts[1]].output)
+	case len(parts) == 3 && parts[2] == "archive":
+		d.archive(w, r, parts[1])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// run runs the command of a helper with /volume paths mapped to the volume directory
+func (d *volumeDaemon) run(helper *volumeHelper) {
+	args := make([]string, len(helper.config.Cmd))
+	for i, arg := range helper.config.Cmd {
+		args[i] = arg
+		if local, ok := d.local("", arg); ok {
+			args[i] = local
+		}
+	}
+	output, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+	helper.output = []byte(strings.ReplaceAll(string(output), d.root, "/volume"))
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		helper.code = exitErr.ExitCode()
+	}
+}
+
+func (d *volumeDaemon) archive(w http.ResponseWriter, r *http.Request, containerID string) {
+	target := r.URL.Query().Get("path")
+	stat, ok := d.stat(containerID, target)
+	if !ok {
+		http.Error(w, `{"message":"no such file"}`, http.StatusNotFound)
+		return
+	}
+	encoded, _ := json.Marshal(stat)
+	w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(encoded))
+
+	local, _ := d.local(containerID, target)
+	switch r.Method {
+	case http.MethodHead:
+	case http.MethodGet:
+		archive := tar.NewWriter(w)
+		filepath.Walk(local, func(file string, info os.FileInfo, err error) error {
+			rel, _ := filepath.Rel(local, file)
+			link, _ := os.Readlink(file)
+			header, _ := tar.FileInfoHeader(info, link)
+			header.Name = path.Join(path.Base(target), filepath.ToSlash(rel))
+			if containerID == "app" {
+				// Files of the container belong to its user
+				header.Uid, header.Gid, header.Uname, header.Gname = 1000, 1000, "app", "app"
+			}
+			archive.WriteHeader(header)
+			if info.Mode().IsRegular() {
+				content, _ := os.ReadFile(file)
+				archive.Write(content)
+			}
+			return nil
+		})
+		archive.Close()
+	case http.MethodPut:
+		if helper := d.helpers[containerID]; helper != nil && strings.HasSuffix(helper.config.HostConfig.Binds[0], ":ro") {
+			http.Error(w, `{"message":"read-only file system"}`, http.StatusInternalServerError)
+			return
+		}
+		archive := tar.NewReader(r.Body)
+		for {
+			header, err := archive.Next()
+			if err != nil {
+				break
+			}
+			content, _ := io.ReadAll(archive)
+			os.WriteFile(filepath.Join(local, header.Name), content, os.FileMode(header.Mode))
+			if containerID == "app" {
+				d.copied = append(d.copied, copiedFile{header, r.URL.Query().Get("copyUIDGID") == "true"})
+			}
+		}
+	}
+}
+
+// auditEntries returns the audit log entries written to a mock, in order
+func auditEntries(audit *MockAuditUsecase) []*domain.AuditLog {
+	var entries []*domain.AuditLog
+	for _, call := range audit.Calls {
+		if call.Method == "Log" {
+			entries = append(entries, call.Arguments.Get(1).(*domain.AuditLog))
+		}
+	}
+	return entries
+}
+
+func newFileBrowser(t *testing.T) (*volumeDaemon, *MockAuditUsecase, usecase.FileBrowserUsecase, context.Context) {
+	daemon, client := newVolumeDaemon(t)
+	ctx := context.WithValue(context.Background(), "user_id", "user-1")
+	clients := new(MockDockerClientRegistry)
+	clients.On("Get", ctx, "host-1").Return(client, nil)
+	audit := new(MockAuditUsecase)
+	audit.On("Log", ctx, mock.AnythingOfType("*domain.AuditLog")).Return(nil)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(daemon.root, "config"), 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(daemon.root, "empty"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(daemon.root, "config", "app.yaml"), []byte("port: 8080\n"), 0o640))
+	require.NoError(t, os.WriteFile(filepath.Join(daemon.root, "data.bin"), []byte{0x7f, 'E', 'L', 'F', 0, 1}, 0o644))
+	require.NoError(t, os.Symlink("/etc/passwd", filepath.Join(daemon.root, "passwd")))
+	require.NoError(t, os.Symlink("config/app.yaml", filepath.Join(daemon.root, "app.yaml")))
+
+	return daemon, audit, usecase.NewFileBrowserUsecase(clients, audit), ctx
+}
+
+// TestVolumeFileBrowserRead tests listing, reading and downloading the files of a volume
+func TestVolumeFileBrowserRead(t *testing.T) {
+	daemon, audit, u, ctx := newFileBrowser(t)
+
+	files, err := u.ListFiles(ctx, "host-1", "data", "/")
+	require.NoError(t, err)
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{"config", "empty", "app.yaml", "data.bin", "passwd"}, names)
+	assert.True(t, files[1].IsDir)
+	assert.Equal(t, "rwx------", files[1].Permissions)
+	assert.Equal(t, "/data.bin", files[3].Path)
+	assert.Equal(t, int64(6), files[3].Size)
+	assert.True(t, files[4].IsSymlink)
+
+	// Climbing above the root of the volume stays in it
+	files, err = u.ListFiles(ctx, "host-1", "data", "../../config")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "/config/app.yaml", files[0].Path)
+	assert.Equal(t, "rw-r-----", files[0].Permissions)
+
+	_, err = u.ListFiles(ctx, "host-1", "data", "/missing")
+	assert.ErrorIs(t, err, domain.ErrFileNotFound)
+	_, err = u.ListFiles(ctx, "host-1", "other", "/")
+	assert.ErrorIs(t, err, domain.ErrFileNotFound)
+
+	content, err := u.ReadFile(ctx, "host-1", "data", "/config/app.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "port: 8080\n", content)
+	content, err = u.ReadFile(ctx, "host-1", "data", "app.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "port: 8080\n", content)
+	_, err = u.ReadFile(ctx, "host-1", "data", "/data.bin")
+	assert.ErrorIs(t, err, domain.ErrFileNotText)
+	_, err = u.ReadFile(ctx, "host-1", "data", "/passwd")
+	assert.ErrorIs(t, err, domain.ErrInvalidFilePath)
+	_, err = u.ReadFile(ctx, "host-1", "data", "/config")
+	assert.ErrorIs(t, err, domain.ErrInvalidFilePath)
+
+	download, err := u.DownloadFile(ctx, "host-1", "data", "/data.bin", "")
+	require.NoError(t, err)
+	raw, err := io.ReadAll(download.Content)
+	require.NoError(t, err)
+	require.NoError(t, download.Content.Close())
+	assert.Equal(t, "data.bin", download.Name)
+	assert.Equal(t, int64(6), download.Size)
+	assert.Equal(t, []byte{0x7f, 'E', 'L', 'F', 0, 1}, raw)
+
+	download, err = u.DownloadFile(ctx, "host-1", "data", "/", "zip")
+	require.NoError(t, err)
+	raw, err = io.ReadAll(download.Content)
+	require.NoError(t, err)
+	require.NoError(t, download.Content.Close())
+	assert.Equal(t, "data.zip", download.Name)
+	zipped, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	require.NoError(t, err)
+	entries := map[string]*zip.File{}
+	for _, file := range zipped.File {
+		entries[file.Name] = file
+	}
+	require.Contains(t, entries, "volume/config/app.yaml")
+	assert.Contains(t, entries, "volume/empty/")
+	assert.Equal(t, os.ModeSymlink, entries["volume/passwd"].Mode()&os.ModeSymlink)
+	entry, err := entries["volume/config/app.yaml"].Open()
+	require.NoError(t, err)
+	raw, _ = io.ReadAll(entry)
+	assert.Equal(t, "port: 8080\n", string(raw))
+
+	download, err = u.DownloadFile(ctx, "host-1", "data", "/config", "")
+	require.NoError(t, err)
+	archive := tar.NewReader(download.Content)
+	var archived []string
+	for header, err := archive.Next(); err == nil; header, err = archive.Next() {
+		archived = append(archived, header.Name)
+	}
+	require.NoError(t, download.Content.Close())
+	assert.Equal(t, "config.tar", download.Name)
+	assert.Equal(t, []string{"config", "config/app.yaml"}, archived)
+
+	_, err = u.DownloadFile(ctx, "host-1", "data", "/config", "rar")
+	assert.ErrorIs(t, err, domain.ErrInvalidFilePath)
+
+	// Reads mount the volume read-only, leave no helper behind and are not audited
+	for _, created := range daemon.created {
+		assert.Equal(t, []string{"data:/volume:ro"}, created.HostConfig.Binds)
+		assert.Equal(t, "data", created.Config.Labels["io.einfra.volume-browser"])
+	}
+	assert.Empty(t, daemon.helpers)
+	assert.Equal(t, len(daemon.created), daemon.removed)
+	audit.AssertNotCalled(t, "Log", mock.Anything, mock.Anything)
+}
+
+// TestVolumeFileBrowserWrite tests uploading, creating folders and deleting in a volume
+func TestVolumeFileBrowserWrite(t *testing.T) {
+	daemon, audit, u, ctx := newFileBrowser(t)
+
+	require.NoError(t, u.UploadFile(ctx, "host-1", "data", "/config", "new.txt", 5, strings.NewReader("hello")))
+	written, err := os.ReadFile(filepath.Join(daemon.root, "config", "new.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(written))
+
+	assert.ErrorIs(t, u.UploadFile(ctx, "host-1", "data", "/config", "../x", 1, strings.NewReader("x")), domain.ErrInvalidFilePath)
+	assert.ErrorIs(t, u.UploadFile(ctx, "host-1", "data", "/", "big", 200<<20, strings.NewReader("x")), domain.ErrFileTooLarge)
+	assert.ErrorIs(t, u.UploadFile(ctx, "host-1", "data", "/data.bin", "x", 1, strings.NewReader("x")), domain.ErrInvalidFilePath)
+	assert.ErrorIs(t, u.UploadFile(ctx, "host-1", "data", "/missing", "x", 1, strings.NewReader("x")), domain.ErrFileNotFound)
+	assert.Error(t, u.UploadFile(ctx, "host-1", "data", "/", "short", 10, strings.NewReader("x")))
+
+	require.NoError(t, u.CreateFolder(ctx, "host-1", "data", "/config", "conf.d"))
+	info, err := os.Stat(filepath.Join(daemon.root, "config", "conf.d"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	assert.ErrorIs(t, u.CreateFolder(ctx, "host-1", "data", "/config", "conf.d"), domain.ErrFileExists)
+	assert.ErrorIs(t, u.CreateFolder(ctx, "host-1", "data", "/missing", "x"), domain.ErrFileNotFound)
+	assert.ErrorIs(t, u.CreateFolder(ctx, "host-1", "data", "/", ".."), domain.ErrInvalidFilePath)
+
+	require.NoError(t, u.DeleteFile(ctx, "host-1", "data", "/config"))
+	_, err = os.Stat(filepath.Join(daemon.root, "config"))
+	assert.True(t, os.IsNotExist(err))
+	require.NoError(t, u.DeleteFile(ctx, "host-1", "data", "/passwd"))
+	_, err = os.Stat("/etc/passwd")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, u.DeleteFile(ctx, "host-1", "data", "/config"), domain.ErrFileNotFound)
+	assert.ErrorIs(t, u.DeleteFile(ctx, "host-1", "data", "/.."), domain.ErrInvalidFilePath)
+
+	// Writes mount the volume read-write and are audited, whether they succeed or not
+	for _, created := range daemon.created {
+		assert.Equal(t, []string{"data:/volume"}, created.HostConfig.Binds)
+	}
+	assert.Empty(t, daemon.helpers)
+
+	type audited struct {
+		action    domain.AuditAction
+		operation string
+		path      string
+		success   bool
+	}
+	var got []audited
+	for _, entry := range auditEntries(audit) {
+		metadata := entry.Metadata.(map[string]interface{})
+		got = append(got, audited{entry.Action, metadata["operation"].(string), metadata["path"].(string), entry.Success})
+		assert.Equal(t, "docker_volume", entry.Resource)
+		assert.Equal(t, "host-1/data", *entry.ResourceID)
+		assert.Equal(t, "user-1", *entry.UserID)
+	}
+	assert.Equal(t, []audited{
+		{domain.AuditActionCreate, "upload", "/config/new.txt", true},
+		{domain.AuditActionCreate, "upload", "/data.bin/x", false},
+		{domain.AuditActionCreate, "upload", "/missing/x", false},
+		{domain.AuditActionCreate, "upload", "/short", false},
+		{domain.AuditActionCreate, "mkdir", "/config/conf.d", true},
+		{domain.AuditActionCreate, "mkdir", "/config/conf.d", false},
+		{domain.AuditActionCreate, "mkdir", "/missing/x", false},
+		{domain.AuditActionDelete, "delete", "/config", true},
+		{domain.AuditActionDelete, "delete", "/passwd", true},
+		{domain.AuditActionDelete, "delete", "/config", false},
+	}, got)
+}
+
+// TestContainerFileBrowser tests browsing and editing the files of a container
+func TestContainerFileBrowser(t *testing.T) {
+	daemon, audit, u, ctx := newFileBrowser(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(daemon.rootfs, "etc"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(daemon.rootfs, "bin"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(daemon.rootfs, "etc", "app.conf"), []byte("listen 80\n"), 0o640))
+	require.NoError(t, os.Symlink("app.conf", filepath.Join(daemon.rootfs, "etc", "current.conf")))
+	require.NoError(t, os.WriteFile(filepath.Join(daemon.rootfs, "bin", "tool"), []byte{0x7f, 'E', 'L', 'F', 0}, 0o755))
+
+	files, err := u.ListContainerFiles(ctx, "host-1", "app", "/")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "/bin", files[0].Path)
+	assert.True(t, files[0].IsDir)
+
+	files, err = u.ListContainerFiles(ctx, "host-1", "app", "/etc")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "/etc/app.conf", files[0].Path)
+	assert.Equal(t, "rw-r-----", files[0].Permissions)
+	assert.Equal(t, "1000", files[0].Owner)
+	assert.True(t, files[1].IsSymlink)
+
+	_, err = u.ListContainerFiles(ctx, "host-1", "gone", "/")
+	assert.ErrorIs(t, err, domain.ErrFileNotFound)
+	_, err = u.ListContainerFiles(ctx, "host-1", "app", "/etc/app.conf")
+	assert.ErrorIs(t, err, domain.ErrInvalidFilePath)
+
+	read, err := u.ReadContainerFile(ctx, "host-1", "app", "/etc/current.conf")
+	require.NoError(t, err)
+	assert.Equal(t, "listen 80\n", read.Content)
+	assert.Equal(t, "rw-r-----", read.Permissions)
+	_, err = u.ReadContainerFile(ctx, "host-1", "app", "/bin/tool")
+	assert.ErrorIs(t, err, domain.ErrFileNotText)
+
+	// Edits go through links and keep the mode and owner of the file
+	written, err := u.WriteContainerFile(ctx, "host-1", "app", domain.FileWriteRequest{Path: "/etc/current.conf", Content: "listen 8080\n", Hash: read.Hash})
+	require.NoError(t, err)
+	assert.NotEqual(t, read.Hash, written.Hash)
+	assert.Equal(t, "1000", written.Owner)
+	content, err := os.ReadFile(filepath.Join(daemon.rootfs, "etc", "app.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "listen 8080\n", string(content))
+	require.Len(t, daemon.copied, 1)
+	assert.Equal(t, "app.conf", daemon.copied[0].header.Name)
+	assert.Equal(t, int64(0o640), daemon.copied[0].header.Mode)
+	assert.Equal(t, 1000, daemon.copied[0].header.Uid)
+	assert.Equal(t, 1000, daemon.copied[0].header.Gid)
+	assert.False(t, daemon.copied[0].copyUIDGID)
+
+	// The file changed since it was first read
+	_, err = u.WriteContainerFile(ctx, "host-1", "app", domain.FileWriteRequest{Path: "/etc/app.conf", Content: "listen 90\n", Hash: read.Hash})
+	assert.ErrorIs(t, err, domain.ErrFileChanged)
+	_, err = u.WriteContainerFile(ctx, "host-1", "app", domain.FileWriteRequest{Path: "/etc/app.conf", Content: "x"})
+	assert.ErrorIs(t, err, domain.ErrFileExists)
+	_, err = u.WriteContainerFile(ctx, "host-1", "app", domain.FileWriteRequest{Path: "/bin/tool", Content: "x", Hash: read.Hash})
+	assert.ErrorIs(t, err, domain.ErrFileNotText)
+	_, err = u.WriteContainerFile(ctx, "host-1", "app", domain.FileWriteRequest{Path: "/etc/new.conf", Content: "a\x00b"})
+	assert.ErrorIs(t, err, domain.ErrFileNotText)
+
+	created, err := u.WriteContainerFile(ctx, "host-1", "app", domain.FileWriteRequest{Path: "/etc/new.conf", Content: "debug\n"})
+	require.NoError(t, err)
+	assert.Equal(t, "rw-r--r--", created.Permissions)
+	assert.Equal(t, "0", created.Owner)
+	require.Len(t, daemon.copied, 2)
+	assert.Equal(t, 0, daemon.copied[1].header.Uid)
+
+	require.NoError(t, u.UploadContainerFile(ctx, "host-1", "app", "/bin", "run.sh", 3, strings.NewReader("ls\n")))
+	content, err = os.ReadFile(filepath.Join(daemon.rootfs, "bin", "run.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, "ls\n", string(content))
+
+	download, err := u.DownloadContainerFile(ctx, "host-1", "app", "/bin/tool", "")
+	require.NoError(t, err)
+	raw, err := io.ReadAll(download.Content)
+	require.NoError(t, err)
+	require.NoError(t, download.Content.Close())
+	assert.Equal(t, "tool", download.Name)
+	assert.Equal(t, []byte{0x7f, 'E', 'L', 'F', 0}, raw)
+
+	// Containers are browsed in place, without helpers
+	assert.Empty(t, daemon.created)
+
+	var operations []string
+	for _, entry := range auditEntries(audit) {
+		metadata := entry.Metadata.(map[string]interface{})
+		operations = append(operations, fmt.Sprintf("%s %s %t", metadata["operation"], metadata["path"], entry.Success))
+		assert.Equal(t, "docker_container", entry.Resource)
+		assert.Equal(t, "host-1/app", *entry.ResourceID)
+	}
+	assert.Equal(t, []string{
+		"edit /etc/current.conf true",
+		"edit /etc/app.conf false",
+		"create /etc/app.conf false",
+		"edit /bin/tool false",
+		"create /etc/new.conf true",
+		"upload /bin/run.sh true",
+	}, operations)
+}
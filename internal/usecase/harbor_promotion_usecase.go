@@ -0,0 +1,409 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/repository"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+)
+
+// harborPromotionAuditResource is the audit log resource of artifact promotions
+const harborPromotionAuditResource = "harbor_promotion"
+
+type harborPromotionUsecase struct {
+	promotionRepo domain.HarborPromotionRepository
+	harborRepo    domain.HarborRegistryRepository
+	harborUsecase domain.HarborUsecase
+	userRepo      repository.UserRepository
+	roleRepo      repository.RoleRepository
+	auditUsecase  AuditUsecase
+}
+
+// NewHarborPromotionUsecase creates a new Harbor promotion use case instance
+func NewHarborPromotionUsecase(
+	promotionRepo domain.HarborPromotionRepository,
+	harborRepo domain.HarborRegistryRepository,
+	harborUsecase domain.HarborUsecase,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	auditUsecase AuditUsecase,
+) domain.HarborPromotionUsecase {
+	return &harborPromotionUsecase{
+		promotionRepo: promotionRepo,
+		harborRepo:    harborRepo,
+		harborUsecase: harborUsecase,
+		userRepo:      userRepo,
+		roleRepo:      roleRepo,
+		auditUsecase:  auditUsecase,
+	}
+}
+
+// Rule Management
+
+func (u *harborPromotionUsecase) CreateRule(ctx context.Context, rule *domain.HarborPromotionRule, userID string) error {
+	if err := u.validateRule(ctx, rule); err != nil {
+		return err
+	}
+	rule.ID = ""
+	rule.CreatedBy = userID
+	return u.promotionRepo.CreateRule(ctx, rule)
+}
+
+func (u *harborPromotionUsecase) GetRule(ctx context.Context, id string) (*domain.HarborPromotionRule, error) {
+	rule, err := u.promotionRepo.GetRule(ctx, id)
+	if err != nil {
+		return nil, errorx.Wrap(err, errorx.CodeNotFound, "promotion rule not found")
+	}
+	return rule, nil
+}
+
+func (u *harborPromotionUsecase) ListRules(ctx context.Context, registryID string) ([]*domain.HarborPromotionRule, error) {
+	return u.promotionRepo.ListRules(ctx, registryID)
+}
+
+func (u *harborPromotionUsecase) UpdateRule(ctx context.Context, rule *domain.HarborPromotionRule) error {
+	existing, err := u.GetRule(ctx, rule.ID)
+	if err != nil {
+		return err
+	}
+	if err := u.validateRule(ctx, rule); err != nil {
+		return err
+	}
+	rule.CreatedBy = existing.CreatedBy
+	rule.CreatedAt = existing.CreatedAt
+	return u.promotionRepo.UpdateRule(ctx, rule)
+}
+
+func (u *harborPromotionUsecase) DeleteRule(ctx context.Context, id string) error {
+	if _, err := u.GetRule(ctx, id); err != nil {
+		return err
+	}
+	return u.promotionRepo.DeleteRule(ctx, id)
+}
+
+// validateRule checks and normalizes a rule before it is stored
+func (u *harborPromotionUsecase) validateRule(ctx context.Context, rule *domain.HarborPromotionRule) error {
+	rule.SourceProject = strings.Trim(rule.SourceProject, "/")
+	rule.TargetProject = strings.Trim(rule.TargetProject, "/")
+	switch {
+	case rule.Name == "":
+		return errorx.New(errorx.CodeBadRequest, "rule name is required")
+	case rule.SourceProject == "" || rule.TargetProject == "":
+		return errorx.New(errorx.CodeBadRequest, "source and target projects are required")
+	case rule.SourceProject == rule.TargetProject:
+		return errorx.New(errorx.CodeBadRequest, "source and target projects must differ")
+	case strings.Contains(rule.SourceProject, "/") || strings.Contains(rule.TargetProject, "/"):
+		return errorx.New(errorx.CodeBadRequest, "projects must be project names, not repositories")
+	}
+
+	if rule.MaxSeverity == "" {
+		rule.MaxSeverity = "Medium"
+	}
+	if promotionSeverityRank(rule.MaxSeverity) < 0 {
+		return errorx.New(errorx.CodeBadRequest, fmt.Sprintf("invalid max severity %q: use None, Low, Medium, High or Critical", rule.MaxSeverity))
+	}
+	rule.MaxSeverity = promotionSeverityName(rule.MaxSeverity)
+	rule.Labels = sortedUnique(rule.Labels)
+
+	if _, err := u.harborRepo.GetByID(ctx, rule.RegistryID); err != nil {
+		return errorx.Wrap(err, errorx.CodeBadRequest, "invalid registry")
+	}
+	if rule.ApprovalRoleID != nil {
+		if *rule.ApprovalRoleID == "" {
+			rule.ApprovalRoleID = nil
+		} else if _, err := u.roleRepo.GetByID(ctx, *rule.ApprovalRoleID); err != nil {
+			return errorx.Wrap(err, errorx.CodeBadRequest, "invalid approval role")
+		}
+	}
+	return nil
+}
+
+// Promotions
+
+func (u *harborPromotionUsecase) Promote(ctx context.Context, ruleID string, req domain.HarborPromotionRequest, userID string) (*domain.HarborPromotion, error) {
+	rule, err := u.GetRule(ctx, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if !rule.IsActive {
+		return nil, errorx.New(errorx.CodeConflict, "promotion rule is disabled")
+	}
+
+	// Accept the repository with or without the source project prefix
+	name := strings.TrimPrefix(strings.Trim(req.Repository, "/"), rule.SourceProject+"/")
+	if name == "" || req.Reference == "" {
+		return nil, errorx.New(errorx.CodeBadRequest, "repository and reference are required")
+	}
+
+	promotion := &domain.HarborPromotion{
+		RuleID:           rule.ID,
+		RegistryID:       rule.RegistryID,
+		SourceRepository: rule.SourceProject + "/" + name,
+		TargetRepository: rule.TargetProject + "/" + name,
+		RequestedBy:      userID,
+	}
+	if err := u.checkArtifact(ctx, rule, promotion, req.Reference); err != nil {
+		return nil, err
+	}
+
+	if rule.ApprovalRoleID != nil {
+		promotion.Status = domain.HarborPromotionStatusPendingApproval
+		if err := u.promotionRepo.CreatePromotion(ctx, promotion); err != nil {
+			return nil, err
+		}
+		u.audit(ctx, promotion, "requested")
+		return promotion, nil
+	}
+
+	promoteErr := u.execute(ctx, rule, promotion)
+	if err := u.promotionRepo.CreatePromotion(ctx, promotion); err != nil {
+		return nil, err
+	}
+	u.audit(ctx, promotion, "promoted")
+	if promoteErr != nil {
+		return promotion, promoteErr
+	}
+	return promotion, nil
+}
+
+func (u *harborPromotionUsecase) ApprovePromotion(ctx context.Context, id, userID string) (*domain.HarborPromotion, error) {
+	promotion, rule, err := u.reviewable(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	promotion.ReviewedBy = &userID
+	promotion.ReviewedAt = &now
+
+	// The scan may have found new vulnerabilities since the promotion was requested
+	if err := u.checkArtifact(ctx, rule, promotion, promotion.Digest); err != nil {
+		return nil, err
+	}
+
+	promoteErr := u.execute(ctx, rule, promotion)
+	if err := u.promotionRepo.UpdatePromotion(ctx, promotion); err != nil {
+		return nil, err
+	}
+	u.audit(ctx, promotion, "approved")
+	if promoteErr != nil {
+		return promotion, promoteErr
+	}
+	return promotion, nil
+}
+
+func (u *harborPromotionUsecase) RejectPromotion(ctx context.Context, id, userID string) (*domain.HarborPromotion, error) {
+	promotion, _, err := u.reviewable(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	promotion.Status = domain.HarborPromotionStatusRejected
+	promotion.ReviewedBy = &userID
+	promotion.ReviewedAt = &now
+	if err := u.promotionRepo.UpdatePromotion(ctx, promotion); err != nil {
+		return nil, err
+	}
+	u.audit(ctx, promotion, "rejected")
+	return promotion, nil
+}
+
+func (u *harborPromotionUsecase) GetPromotion(ctx context.Context, id string) (*domain.HarborPromotion, error) {
+	promotion, err := u.promotionRepo.GetPromotion(ctx, id)
+	if err != nil {
+		return nil, errorx.Wrap(err, errorx.CodeNotFound, "promotion not found")
+	}
+	return promotion, nil
+}
+
+func (u *harborPromotionUsecase) ListPromotions(ctx context.Context, filter domain.HarborPromotionFilter) ([]*domain.HarborPromotion, int64, error) {
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = 20
+	}
+	if filter.PageSize > 100 {
+		filter.PageSize = 100
+	}
+	return u.promotionRepo.ListPromotions(ctx, filter)
+}
+
+// reviewable loads a pending promotion and checks that userID may review it
+func (u *harborPromotionUsecase) reviewable(ctx context.Context, id, userID string) (*domain.HarborPromotion, *domain.HarborPromotionRule, error) {
+	promotion, err := u.GetPromotion(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if promotion.Status != domain.HarborPromotionStatusPendingApproval {
+		return nil, nil, errorx.New(errorx.CodeConflict, fmt.Sprintf("promotion is %s, not pending approval", promotion.Status))
+	}
+	rule, err := u.GetRule(ctx, promotion.RuleID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if userID == "" {
+		return nil, nil, errorx.New(errorx.CodeUnauthorized, "user ID is required to review promotions")
+	}
+	if userID == promotion.RequestedBy {
+		return nil, nil, errorx.New(errorx.CodeForbidden, "promotions must be reviewed by someone other than the requester")
+	}
+	if rule.ApprovalRoleID != nil {
+		user, err := u.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			return nil, nil, errorx.Wrap(err, errorx.CodeForbidden, "unknown reviewer")
+		}
+		if user.RoleID != *rule.ApprovalRoleID {
+			return nil, nil, errorx.New(errorx.CodeForbidden, "reviewer does not hold the rule's approval role")
+		}
+	}
+	return promotion, rule, nil
+}
+
+// checkArtifact resolves the artifact to its digest and checks its scan against the rule
+func (u *harborPromotionUsecase) checkArtifact(ctx context.Context, rule *domain.HarborPromotionRule, promotion *domain.HarborPromotion, reference string) error {
+	artifact, err := u.harborUsecase.GetArtifact(ctx, rule.RegistryID, promotion.SourceRepository, reference)
+	if err != nil {
+		return err
+	}
+
+	promotion.Digest = artifact.Digest
+	promotion.Tags = make([]string, 0, len(artifact.Tags))
+	for _, tag := range artifact.Tags {
+		promotion.Tags = append(promotion.Tags, tag.Name)
+	}
+	promotion.ScanStatus = ""
+	promotion.ScanSeverity = ""
+	if artifact.ScanOverview != nil {
+		promotion.ScanStatus = artifact.ScanOverview.ScanStatus
+		promotion.ScanSeverity = artifact.ScanOverview.Severity
+	}
+
+	if rule.AllowUnscanned && promotion.ScanStatus != "Success" {
+		return nil
+	}
+	if promotion.ScanStatus != "Success" {
+		status := promotion.ScanStatus
+		if status == "" {
+			status = "Not Scanned"
+		}
+		return errorx.New(errorx.CodeUnprocessableEntity, fmt.Sprintf("%s@%s has no successful scan (status: %s)", promotion.SourceRepository, promotion.Digest, status))
+	}
+	if promotionSeverityRank(promotion.ScanSeverity) > promotionSeverityRank(rule.MaxSeverity) {
+		return errorx.New(errorx.CodeUnprocessableEntity, fmt.Sprintf("%s@%s has %s severity vulnerabilities; rule %s allows at most %s",
+			promotion.SourceRepository, promotion.Digest, promotion.ScanSeverity, rule.Name, rule.MaxSeverity))
+	}
+	return nil
+}
+
+// execute copies the artifact to the target project and labels it, recording the outcome on the promotion
+func (u *harborPromotionUsecase) execute(ctx context.Context, rule *domain.HarborPromotionRule, promotion *domain.HarborPromotion) error {
+	// Copying by digest promotes exactly what was scanned; Harbor copies the artifact's tags along with it
+	err := u.harborUsecase.CopyArtifact(ctx, rule.RegistryID, promotion.SourceRepository, promotion.TargetRepository, promotion.Digest)
+	if err == nil {
+		err = u.applyLabels(ctx, rule, promotion)
+	}
+
+	if err != nil {
+		promotion.Status = domain.HarborPromotionStatusFailed
+		promotion.ErrorMessage = err.Error()
+		return err
+	}
+	now := time.Now()
+	promotion.Status = domain.HarborPromotionStatusCompleted
+	promotion.PromotedAt = &now
+	promotion.ErrorMessage = ""
+	return nil
+}
+
+// applyLabels adds the rule's labels to the promoted artifact, creating missing global labels
+func (u *harborPromotionUsecase) applyLabels(ctx context.Context, rule *domain.HarborPromotionRule, promotion *domain.HarborPromotion) error {
+	if len(rule.Labels) == 0 {
+		return nil
+	}
+
+	existing, err := u.harborUsecase.ListLabels(ctx, rule.RegistryID, "global", nil)
+	if err != nil {
+		return err
+	}
+	labelIDs := make(map[string]int64, len(existing))
+	for _, label := range existing {
+		labelIDs[label.Name] = label.ID
+	}
+
+	for _, name := range rule.Labels {
+		id, ok := labelIDs[name]
+		if !ok {
+			label := &domain.HarborLabel{Name: name, Scope: "global", Description: "Added by promotion rules"}
+			if err := u.harborUsecase.CreateLabel(ctx, rule.RegistryID, label); err != nil {
+				return err
+			}
+			id = label.ID
+		}
+		err := u.harborUsecase.AddLabelToArtifact(ctx, rule.RegistryID, promotion.TargetRepository, promotion.Digest, id)
+		// Harbor answers 409 when the label is already attached
+		if err != nil && errorx.GetCode(err) != errorx.CodeConflict {
+			return err
+		}
+	}
+	return nil
+}
+
+// audit records a promotion step in the audit log as the user carried by ctx
+func (u *harborPromotionUsecase) audit(ctx context.Context, promotion *domain.HarborPromotion, operation string) {
+	if u.auditUsecase == nil {
+		return
+	}
+
+	action := domain.AuditActionCreate
+	if operation != "requested" && operation != "promoted" {
+		action = domain.AuditActionUpdate
+	}
+	description := fmt.Sprintf("Promotion of %s@%s to %s %s (%s)",
+		promotion.SourceRepository, promotion.Digest, promotion.TargetRepository, operation, promotion.Status)
+
+	entry := contextAuditLog(ctx, action, harborPromotionAuditResource, promotion.ID, description, nil)
+	entry.Metadata = map[string]interface{}{
+		"rule_id":           promotion.RuleID,
+		"registry_id":       promotion.RegistryID,
+		"source_repository": promotion.SourceRepository,
+		"target_repository": promotion.TargetRepository,
+		"digest":            promotion.Digest,
+		"operation":         operation,
+	}
+	entry.Success = promotion.Status != domain.HarborPromotionStatusFailed
+	entry.ErrorMessage = promotion.ErrorMessage
+	_ = u.auditUsecase.Log(ctx, entry)
+}
+
+// promotionSeverityRank orders Harbor scan severities; it returns -1 for unknown names
+func promotionSeverityRank(severity string) int {
+	switch strings.ToLower(severity) {
+	case "", "none":
+		return 0
+	case "negligible", "unknown", "low":
+		return 1
+	case "medium":
+		return 2
+	case "high":
+		return 3
+	case "critical":
+		return 4
+	default:
+		return -1
+	}
+}
+
+// promotionSeverityName canonicalizes a severity accepted by promotionSeverityRank
+func promotionSeverityName(severity string) string {
+	if strings.EqualFold(severity, "none") {
+		return "None"
+	}
+	return normalizeSeverity(severity)
+}
@@ -0,0 +1,168 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+)
+
+// MockHarborPromotionRepository is a mock implementation of HarborPromotionRepository
+type MockHarborPromotionRepository struct {
+	mock.Mock
+}
+
+func (m *MockHarborPromotionRepository) CreateRule(ctx context.Context, rule *domain.HarborPromotionRule) error {
+	args := m.Called(ctx, rule)
+	return args.Error(0)
+}
+
+func (m *MockHarborPromotionRepository) GetRule(ctx context.Context, id string) (*domain.HarborPromotionRule, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.HarborPromotionRule), args.Error(1)
+}
+
+func (m *MockHarborPromotionRepository) ListRules(ctx context.Context, registryID string) ([]*domain.HarborPromotionRule, error) {
+	args := m.Called(ctx, registryID)
+	return args.Get(0).([]*domain.HarborPromotionRule), args.Error(1)
+}
+
+func (m *MockHarborPromotionRepository) UpdateRule(ctx context.Context, rule *domain.HarborPromotionRule) error {
+	args := m.Called(ctx, rule)
+	return args.Error(0)
+}
+
+func (m *MockHarborPromotionRepository) DeleteRule(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockHarborPromotionRepository) CreatePromotion(ctx context.Context, promotion *domain.HarborPromotion) error {
+	args := m.Called(ctx, promotion)
+	return args.Error(0)
+}
+
+func (m *MockHarborPromotionRepository) GetPromotion(ctx context.Context, id string) (*domain.HarborPromotion, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.HarborPromotion), args.Error(1)
+}
+
+func (m *MockHarborPromotionRepository) UpdatePromotion(ctx context.Context, promotion *domain.HarborPromotion) error {
+	args := m.Called(ctx, promotion)
+	return args.Error(0)
+}
+
+func (m *MockHarborPromotionRepository) ListPromotions(ctx context.Context, filter domain.HarborPromotionFilter) ([]*domain.HarborPromotion, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]*domain.HarborPromotion), args.Get(1).(int64), args.Error(2)
+}
+
+// stagingArtifact serves staging/api:v1.2.0 with a scan of the given severity
+func stagingArtifact(severity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"id":     7,
+			"digest": "sha256:abc",
+			"tags":   []map[string]interface{}{{"id": 1, "name": "v1.2.0"}},
+			"scan_overview": map[string]interface{}{
+				"application/vnd.security.vulnerability.report; version=1.1": map[string]interface{}{
+					"scan_status": "Success",
+					"severity":    severity,
+				},
+			},
+		})
+	}
+}
+
+// TestHarborPromotionPromote tests that a passing artifact is copied by digest and labeled
+func TestHarborPromotionPromote(t *testing.T) {
+	var copiedFrom string
+	var labeled []int64
+	harborRepo, harborUC := newHarborStandIn(t, map[string]http.HandlerFunc{
+		"GET /api/v2.0/projects/staging/repositories/api/artifacts/v1.2.0": stagingArtifact("Low"),
+		"POST /api/v2.0/projects/prod/repositories/api/artifacts": func(w http.ResponseWriter, r *http.Request) {
+			copiedFrom = r.URL.Query().Get("from")
+			w.WriteHeader(http.StatusCreated)
+		},
+		"GET /api/v2.0/labels": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, []map[string]interface{}{{"id": 3, "name": "promoted", "scope": "g"}})
+		},
+		"POST /api/v2.0/labels": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Location", "/api/v2.0/labels/9")
+			w.WriteHeader(http.StatusCreated)
+		},
+		"POST /api/v2.0/projects/prod/repositories/api/artifacts/sha256:abc/labels": func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				ID int64 `json:"id"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			labeled = append(labeled, body.ID)
+			w.WriteHeader(http.StatusOK)
+		},
+	})
+
+	repo := new(MockHarborPromotionRepository)
+	repo.On("GetRule", mock.Anything, "rule-1").Return(&domain.HarborPromotionRule{
+		ID:            "rule-1",
+		RegistryID:    "reg-1",
+		Name:          "staging-to-prod",
+		SourceProject: "staging",
+		TargetProject: "prod",
+		Labels:        []string{"prod", "promoted"},
+		MaxSeverity:   "Medium",
+		IsActive:      true,
+	}, nil)
+	repo.On("CreatePromotion", mock.Anything, mock.AnythingOfType("*domain.HarborPromotion")).Return(nil)
+
+	uc := usecase.NewHarborPromotionUsecase(repo, harborRepo, harborUC, nil, nil, nil)
+	promotion, err := uc.Promote(context.Background(), "rule-1", domain.HarborPromotionRequest{Repository: "staging/api", Reference: "v1.2.0"}, "user-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.HarborPromotionStatusCompleted, promotion.Status)
+	assert.Equal(t, "sha256:abc", promotion.Digest)
+	assert.Equal(t, []string{"v1.2.0"}, promotion.Tags)
+	assert.Equal(t, "prod/api", promotion.TargetRepository)
+	assert.Equal(t, "user-1", promotion.RequestedBy)
+	assert.NotNil(t, promotion.PromotedAt)
+	assert.Equal(t, "staging/api@sha256:abc", copiedFrom)
+	assert.Equal(t, []int64{9, 3}, labeled)
+	repo.AssertExpectations(t)
+}
+
+// TestHarborPromotionScanGate tests that artifacts above the rule's severity are not promoted
+func TestHarborPromotionScanGate(t *testing.T) {
+	harborRepo, harborUC := newHarborStandIn(t, map[string]http.HandlerFunc{
+		"GET /api/v2.0/projects/staging/repositories/api/artifacts/v1.2.0": stagingArtifact("Critical"),
+	})
+
+	repo := new(MockHarborPromotionRepository)
+	repo.On("GetRule", mock.Anything, "rule-1").Return(&domain.HarborPromotionRule{
+		ID:            "rule-1",
+		RegistryID:    "reg-1",
+		Name:          "staging-to-prod",
+		SourceProject: "staging",
+		TargetProject: "prod",
+		MaxSeverity:   "High",
+		IsActive:      true,
+	}, nil)
+
+	uc := usecase.NewHarborPromotionUsecase(repo, harborRepo, harborUC, nil, nil, nil)
+	_, err := uc.Promote(context.Background(), "rule-1", domain.HarborPromotionRequest{Repository: "api", Reference: "v1.2.0"}, "user-1")
+
+	require.Error(t, err)
+	assert.Equal(t, errorx.CodeUnprocessableEntity, errorx.GetCode(err))
+	repo.AssertNotCalled(t, "CreatePromotion", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,470 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+)
+
+const (
+	// harborRetentionAuditResource is the audit log resource of retention runs
+	harborRetentionAuditResource = "harbor_retention"
+	// harborRetentionRunsLimit caps the runs listed for a policy
+	harborRetentionRunsLimit = 50
+	// harborRetentionScheduler is the TriggeredBy of scheduled runs
+	harborRetentionScheduler = "scheduler"
+)
+
+type harborRetentionUsecase struct {
+	retentionRepo  domain.HarborRetentionRepository
+	harborRepo     domain.HarborRegistryRepository
+	harborUsecase  domain.HarborUsecase
+	deploymentRepo domain.ImageDeploymentRepository
+	auditUsecase   AuditUsecase
+
+	mu      sync.Mutex
+	running map[string]bool // Policies with a run in progress
+}
+
+// NewHarborRetentionUsecase creates a new Harbor retention use case instance
+func NewHarborRetentionUsecase(
+	retentionRepo domain.HarborRetentionRepository,
+	harborRepo domain.HarborRegistryRepository,
+	harborUsecase domain.HarborUsecase,
+	deploymentRepo domain.ImageDeploymentRepository,
+	auditUsecase AuditUsecase,
+) domain.HarborRetentionUsecase {
+	return &harborRetentionUsecase{
+		retentionRepo:  retentionRepo,
+		harborRepo:     harborRepo,
+		harborUsecase:  harborUsecase,
+		deploymentRepo: deploymentRepo,
+		auditUsecase:   auditUsecase,
+		running:        make(map[string]bool),
+	}
+}
+
+// Policy Management
+
+func (u *harborRetentionUsecase) CreatePolicy(ctx context.Context, policy *domain.HarborRetentionPolicy, userID string) error {
+	if err := u.validatePolicy(ctx, policy); err != nil {
+		return err
+	}
+	policy.ID = ""
+	policy.CreatedBy = userID
+	policy.Enforced = false
+	policy.LastRunAt = nil
+	policy.ConfiguredAt = time.Now()
+	return u.retentionRepo.CreatePolicy(ctx, policy)
+}
+
+func (u *harborRetentionUsecase) GetPolicy(ctx context.Context, id string) (*domain.HarborRetentionPolicy, error) {
+	policy, err := u.retentionRepo.GetPolicy(ctx, id)
+	if err != nil {
+		return nil, errorx.Wrap(err, errorx.CodeNotFound, "retention policy not found")
+	}
+	return policy, nil
+}
+
+func (u *harborRetentionUsecase) ListPolicies(ctx context.Context, registryID string) ([]*domain.HarborRetentionPolicy, error) {
+	return u.retentionRepo.ListPolicies(ctx, registryID)
+}
+
+func (u *harborRetentionUsecase) UpdatePolicy(ctx context.Context, policy *domain.HarborRetentionPolicy) error {
+	existing, err := u.GetPolicy(ctx, policy.ID)
+	if err != nil {
+		return err
+	}
+	if err := u.validatePolicy(ctx, policy); err != nil {
+		return err
+	}
+	// The dry run that justified enforcing the old rules says nothing about the new ones
+	policy.Enforced = false
+	policy.ConfiguredAt = time.Now()
+	policy.LastRunAt = existing.LastRunAt
+	policy.CreatedBy = existing.CreatedBy
+	policy.CreatedAt = existing.CreatedAt
+	return u.retentionRepo.UpdatePolicy(ctx, policy)
+}
+
+func (u *harborRetentionUsecase) DeletePolicy(ctx context.Context, id string) error {
+	if _, err := u.GetPolicy(ctx, id); err != nil {
+		return err
+	}
+	return u.retentionRepo.DeletePolicy(ctx, id)
+}
+
+func (u *harborRetentionUsecase) EnforcePolicy(ctx context.Context, id string) (*domain.HarborRetentionPolicy, error) {
+	policy, err := u.GetPolicy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if policy.Enforced {
+		return policy, nil
+	}
+
+	runs, err := u.retentionRepo.ListRuns(ctx, id, harborRetentionRunsLimit)
+	if err != nil {
+		return nil, err
+	}
+	reviewed := false
+	for _, run := range runs {
+		if run.DryRun && run.Status == domain.HarborRetentionRunStatusCompleted && run.StartedAt.After(policy.ConfiguredAt) {
+			reviewed = true
+			break
+		}
+	}
+	if !reviewed {
+		return nil, errorx.New(errorx.CodeConflict, "run the policy as a dry run and review its report before enforcing it")
+	}
+
+	policy.Enforced = true
+	if err := u.retentionRepo.UpdatePolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// validatePolicy checks the patterns and schedule of a policy and computes its next run
+func (u *harborRetentionUsecase) validatePolicy(ctx context.Context, policy *domain.HarborRetentionPolicy) error {
+	policy.ProjectName = strings.Trim(policy.ProjectName, "/")
+	if policy.RepositoryPattern == "" {
+		policy.RepositoryPattern = "*"
+	}
+	if policy.TagPattern == "" {
+		policy.TagPattern = "*"
+	}
+	switch {
+	case policy.Name == "":
+		return errorx.New(errorx.CodeBadRequest, "policy name is required")
+	case policy.ProjectName == "" || strings.Contains(policy.ProjectName, "/"):
+		return errorx.New(errorx.CodeBadRequest, "project name is required and must not be a repository")
+	case policy.KeepLast < 0:
+		return errorx.New(errorx.CodeBadRequest, "keep_last must not be negative")
+	case policy.UntaggedDays != nil && *policy.UntaggedDays < 1:
+		return errorx.New(errorx.CodeBadRequest, "untagged_days must be at least 1")
+	}
+	for _, pattern := range []string{policy.RepositoryPattern, policy.TagPattern} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errorx.New(errorx.CodeBadRequest, fmt.Sprintf("invalid pattern %q", pattern))
+		}
+	}
+
+	nextRun, err := nextBackupRun(policy.CronExpression, time.Now())
+	if err != nil {
+		return errorx.Wrap(err, errorx.CodeBadRequest, err.Error())
+	}
+	policy.NextRunAt = &nextRun
+
+	if _, err := u.harborRepo.GetByID(ctx, policy.RegistryID); err != nil {
+		return errorx.Wrap(err, errorx.CodeBadRequest, "invalid registry")
+	}
+	return nil
+}
+
+// Runs
+
+func (u *harborRetentionUsecase) RunPolicy(ctx context.Context, id string, dryRun bool, userID string) (*domain.HarborRetentionRun, error) {
+	policy, err := u.GetPolicy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun && !policy.Enforced {
+		return nil, errorx.New(errorx.CodeConflict, "policy is not enforced; only dry runs are allowed")
+	}
+	return u.start(ctx, policy, dryRun, userID)
+}
+
+func (u *harborRetentionUsecase) GetRun(ctx context.Context, id string) (*domain.HarborRetentionRun, error) {
+	run, err := u.retentionRepo.GetRun(ctx, id)
+	if err != nil {
+		return nil, errorx.Wrap(err, errorx.CodeNotFound, "retention run not found")
+	}
+	return run, nil
+}
+
+func (u *harborRetentionUsecase) ListRuns(ctx context.Context, policyID string) ([]*domain.HarborRetentionRun, error) {
+	if _, err := u.GetPolicy(ctx, policyID); err != nil {
+		return nil, err
+	}
+	return u.retentionRepo.ListRuns(ctx, policyID, harborRetentionRunsLimit)
+}
+
+// start records a run and carries it out in the background, as pruning a project can outlast a request
+func (u *harborRetentionUsecase) start(ctx context.Context, policy *domain.HarborRetentionPolicy, dryRun bool, triggeredBy string) (*domain.HarborRetentionRun, error) {
+	u.mu.Lock()
+	if u.running[policy.ID] {
+		u.mu.Unlock()
+		return nil, errorx.New(errorx.CodeConflict, "a run of this policy is already in progress")
+	}
+	u.running[policy.ID] = true
+	u.mu.Unlock()
+
+	run := &domain.HarborRetentionRun{
+		PolicyID:    policy.ID,
+		DryRun:      dryRun,
+		Status:      domain.HarborRetentionRunStatusRunning,
+		TriggeredBy: triggeredBy,
+		StartedAt:   time.Now(),
+	}
+	if err := u.retentionRepo.CreateRun(ctx, run); err != nil {
+		u.finish(policy.ID)
+		return nil, err
+	}
+
+	report := *run
+	go func() {
+		defer u.finish(policy.ID)
+		u.execute(context.Background(), policy, run)
+	}()
+	return &report, nil
+}
+
+func (u *harborRetentionUsecase) finish(policyID string) {
+	u.mu.Lock()
+	delete(u.running, policyID)
+	u.mu.Unlock()
+}
+
+// execute evaluates and prunes each matching repository of the policy's project and stores the report
+func (u *harborRetentionUsecase) execute(ctx context.Context, policy *domain.HarborRetentionPolicy, run *domain.HarborRetentionRun) {
+	err := u.prune(ctx, policy, run)
+
+	for _, item := range run.Items {
+		switch {
+		case item.Action == domain.HarborRetentionActionRetain:
+			run.RetainedCount++
+		case item.Error != "":
+			run.FailedCount++
+		default:
+			run.DeleteCount++
+		}
+	}
+
+	run.Status = domain.HarborRetentionRunStatusCompleted
+	if err != nil {
+		run.Status = domain.HarborRetentionRunStatusFailed
+		run.ErrorMessage = err.Error()
+	} else if !run.DryRun && policy.RunGarbageCollection && run.DeleteCount > 0 {
+		if _, err := u.harborUsecase.RunGarbageCollection(ctx, policy.RegistryID, false); err != nil {
+			run.ErrorMessage = fmt.Sprintf("garbage collection: %v", err)
+		} else {
+			run.GCTriggered = true
+		}
+	}
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+
+	if err := u.retentionRepo.UpdateRun(ctx, run); err != nil {
+		log.Printf("Error saving retention run %s of policy %s: %v", run.ID, policy.ID, err)
+	}
+	if !run.DryRun {
+		u.audit(ctx, policy, run)
+	}
+}
+
+// prune decides per repository and deletes right away, so deployment records are as fresh as possible
+func (u *harborRetentionUsecase) prune(ctx context.Context, policy *domain.HarborRetentionPolicy, run *domain.HarborRetentionRun) error {
+	repositories, err := u.harborUsecase.ListRepositories(ctx, policy.RegistryID, policy.ProjectName)
+	if err != nil {
+		return err
+	}
+
+	for _, repo := range repositories {
+		name := strings.TrimPrefix(repo.Name, policy.ProjectName+"/")
+		if ok, _ := path.Match(policy.RepositoryPattern, name); !ok {
+			continue
+		}
+
+		deployments, err := u.deploymentRepo.ListByImageRepository(ctx, repo.Name)
+		if err != nil {
+			return fmt.Errorf("listing deployments of %s: %w", repo.Name, err)
+		}
+		artifacts, err := u.harborUsecase.ListArtifacts(ctx, policy.RegistryID, repo.Name)
+		if err != nil {
+			return fmt.Errorf("listing artifacts of %s: %w", repo.Name, err)
+		}
+
+		items := retentionDecisions(policy, repo.Name, artifacts, deployments, time.Now())
+		if !run.DryRun {
+			for i := range items {
+				if items[i].Action != domain.HarborRetentionActionDelete {
+					continue
+				}
+				if err := u.harborUsecase.DeleteArtifact(ctx, policy.RegistryID, repo.Name, items[i].Digest); err != nil {
+					items[i].Error = err.Error()
+				}
+			}
+		}
+		run.Items = append(run.Items, items...)
+	}
+	return nil
+}
+
+// retentionDecisions decides, newest first, which artifacts of a repository a policy keeps. Artifacts that
+// are active in a tracked cluster are always kept, and count towards the policy's keep-last limit.
+func retentionDecisions(policy *domain.HarborRetentionPolicy, repository string, artifacts []*domain.HarborArtifact, deployments []*domain.ImageDeployment, now time.Time) []domain.HarborRetentionItem {
+	active := make(map[string]bool)
+	deployed := make(map[string]bool)
+	for _, deployment := range deployments {
+		// The recorded digest protects the artifact even after its tag moved on
+		for _, ref := range []string{deployment.ImageTag, deployment.ImageDigest} {
+			if ref == "" {
+				continue
+			}
+			deployed[ref] = true
+			if deployment.Status == "active" {
+				active[ref] = true
+			}
+		}
+	}
+	referenced := func(refs map[string]bool, artifact *domain.HarborArtifact, tags []string) bool {
+		if refs[artifact.Digest] {
+			return true
+		}
+		for _, tag := range tags {
+			if refs[tag] {
+				return true
+			}
+		}
+		return false
+	}
+
+	sorted := make([]*domain.HarborArtifact, len(artifacts))
+	copy(sorted, artifacts)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].PushTime.After(sorted[j].PushTime) })
+
+	items := make([]domain.HarborRetentionItem, 0, len(sorted))
+	matched := 0
+	for _, artifact := range sorted {
+		tags := make([]string, 0, len(artifact.Tags))
+		matching := len(artifact.Tags) > 0
+		for _, tag := range artifact.Tags {
+			tags = append(tags, tag.Name)
+			if ok, _ := path.Match(policy.TagPattern, tag.Name); !ok {
+				matching = false
+			}
+		}
+		if matching {
+			matched++
+		}
+
+		item := domain.HarborRetentionItem{
+			Repository: repository,
+			Digest:     artifact.Digest,
+			Tags:       tags,
+			PushTime:   artifact.PushTime,
+			Action:     domain.HarborRetentionActionRetain,
+		}
+		switch {
+		case referenced(active, artifact, tags):
+			item.Reason = "active in a tracked cluster"
+		case len(tags) == 0:
+			item.Reason = "untagged artifacts are kept by this policy"
+			if policy.UntaggedDays != nil && artifact.PushTime.Before(now.AddDate(0, 0, -*policy.UntaggedDays)) {
+				item.Action = domain.HarborRetentionActionDelete
+				item.Reason = fmt.Sprintf("untagged for more than %d days", *policy.UntaggedDays)
+			}
+		case !matching:
+			// Deleting the artifact would take its other tags along
+			item.Reason = fmt.Sprintf("has tags not matching %s", policy.TagPattern)
+		case policy.KeepLast == 0 || matched <= policy.KeepLast:
+			item.Reason = fmt.Sprintf("within the last %d artifacts matching %s", policy.KeepLast, policy.TagPattern)
+		case policy.KeepDeployed && referenced(deployed, artifact, tags):
+			item.Reason = "deployed according to image deployment records"
+		default:
+			item.Action = domain.HarborRetentionActionDelete
+			item.Reason = fmt.Sprintf("beyond the last %d artifacts matching %s", policy.KeepLast, policy.TagPattern)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// Scheduler
+
+// StartScheduler starts a background job that runs due policies; policies that are not enforced are dry-run
+func (u *harborRetentionUsecase) StartScheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				u.runDuePolicies(ctx)
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+func (u *harborRetentionUsecase) runDuePolicies(ctx context.Context) {
+	now := time.Now()
+	policies, err := u.retentionRepo.ListDuePolicies(ctx, now)
+	if err != nil {
+		log.Printf("Error listing due retention policies: %v", err)
+		return
+	}
+
+	for _, policy := range policies {
+		nextRun, err := nextBackupRun(policy.CronExpression, now)
+		if err != nil {
+			log.Printf("Retention policy %s (%s) has an invalid schedule: %v", policy.Name, policy.ID, err)
+			continue
+		}
+		if err := u.retentionRepo.RecordRun(ctx, policy.ID, now, nextRun); err != nil {
+			log.Printf("Error scheduling retention policy %s (%s): %v", policy.Name, policy.ID, err)
+			continue
+		}
+		if _, err := u.start(ctx, policy, !policy.Enforced, harborRetentionScheduler); err != nil {
+			log.Printf("Retention policy %s (%s) failed to start: %v", policy.Name, policy.ID, err)
+		}
+	}
+}
+
+// audit records the deletions of an enforced run in the audit log
+func (u *harborRetentionUsecase) audit(ctx context.Context, policy *domain.HarborRetentionPolicy, run *domain.HarborRetentionRun) {
+	if u.auditUsecase == nil || run.DeleteCount+run.FailedCount == 0 {
+		return
+	}
+
+	deleted := make([]string, 0, run.DeleteCount)
+	for _, item := range run.Items {
+		if item.Action == domain.HarborRetentionActionDelete && item.Error == "" {
+			deleted = append(deleted, item.Repository+"@"+item.Digest)
+		}
+	}
+
+	entry := &domain.AuditLog{
+		Username:    "SYSTEM",
+		Action:      domain.AuditActionDelete,
+		Resource:    harborRetentionAuditResource,
+		ResourceID:  &run.ID,
+		Description: fmt.Sprintf("Retention policy %s deleted %d artifacts from project %s", policy.Name, run.DeleteCount, policy.ProjectName),
+		Metadata: map[string]interface{}{
+			"policy_id":    policy.ID,
+			"registry_id":  policy.RegistryID,
+			"project_name": policy.ProjectName,
+			"deleted":      deleted,
+			"failed":       run.FailedCount,
+			"gc_triggered": run.GCTriggered,
+		},
+		Success:      run.Status == domain.HarborRetentionRunStatusCompleted && run.FailedCount == 0,
+		ErrorMessage: run.ErrorMessage,
+		CreatedAt:    time.Now(),
+	}
+	if run.TriggeredBy != harborRetentionScheduler && run.TriggeredBy != "" {
+		userID := run.TriggeredBy
+		entry.UserID = &userID
+	}
+	_ = u.auditUsecase.Log(ctx, entry)
+}
@@ -0,0 +1,278 @@
+package usecase_test
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+)
+
+// MockHarborRetentionRepository is a mock implementation of HarborRetentionRepository
+type MockHarborRetentionRepository struct {
+	mock.Mock
+}
+
+func (m *MockHarborRetentionRepository) CreatePolicy(ctx context.Context, policy *domain.HarborRetentionPolicy) error {
+	args := m.Called(ctx, policy)
+	return args.Error(0)
+}
+
+func (m *MockHarborRetentionRepository) GetPolicy(ctx context.Context, id string) (*domain.HarborRetentionPolicy, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.HarborRetentionPolicy), args.Error(1)
+}
+
+func (m *MockHarborRetentionRepository) ListPolicies(ctx context.Context, registryID string) ([]*domain.HarborRetentionPolicy, error) {
+	args := m.Called(ctx, registryID)
+	return args.Get(0).([]*domain.HarborRetentionPolicy), args.Error(1)
+}
+
+func (m *MockHarborRetentionRepository) ListDuePolicies(ctx context.Context, now time.Time) ([]*domain.HarborRetentionPolicy, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]*domain.HarborRetentionPolicy), args.Error(1)
+}
+
+func (m *MockHarborRetentionRepository) UpdatePolicy(ctx context.Context, policy *domain.HarborRetentionPolicy) error {
+	args := m.Called(ctx, policy)
+	return args.Error(0)
+}
+
+func (m *MockHarborRetentionRepository) RecordRun(ctx context.Context, id string, lastRunAt, nextRunAt time.Time) error {
+	args := m.Called(ctx, id, lastRunAt, nextRunAt)
+	return args.Error(0)
+}
+
+func (m *MockHarborRetentionRepository) DeletePolicy(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockHarborRetentionRepository) CreateRun(ctx context.Context, run *domain.HarborRetentionRun) error {
+	args := m.Called(ctx, run)
+	return args.Error(0)
+}
+
+func (m *MockHarborRetentionRepository) GetRun(ctx context.Context, id string) (*domain.HarborRetentionRun, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.HarborRetentionRun), args.Error(1)
+}
+
+func (m *MockHarborRetentionRepository) UpdateRun(ctx context.Context, run *domain.HarborRetentionRun) error {
+	args := m.Called(ctx, run)
+	return args.Error(0)
+}
+
+func (m *MockHarborRetentionRepository) ListRuns(ctx context.Context, policyID string, limit int) ([]*domain.HarborRetentionRun, error) {
+	args := m.Called(ctx, policyID, limit)
+	return args.Get(0).([]*domain.HarborRetentionRun), args.Error(1)
+}
+
+// MockImageDeploymentRepository is a mock implementation of ImageDeploymentRepository
+type MockImageDeploymentRepository struct {
+	mock.Mock
+}
+
+func (m *MockImageDeploymentRepository) Create(ctx context.Context, deployment *domain.ImageDeployment) error {
+	args := m.Called(ctx, deployment)
+	return args.Error(0)
+}
+
+func (m *MockImageDeploymentRepository) GetByID(ctx context.Context, id string) (*domain.ImageDeployment, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ImageDeployment), args.Error(1)
+}
+
+func (m *MockImageDeploymentRepository) List(ctx context.Context, clusterID, namespace string) ([]*domain.ImageDeployment, error) {
+	args := m.Called(ctx, clusterID, namespace)
+	return args.Get(0).([]*domain.ImageDeployment), args.Error(1)
+}
+
+func (m *MockImageDeploymentRepository) ListByImageRepository(ctx context.Context, repository string) ([]*domain.ImageDeployment, error) {
+	args := m.Called(ctx, repository)
+	return args.Get(0).([]*domain.ImageDeployment), args.Error(1)
+}
+
+func (m *MockImageDeploymentRepository) ListByImageDigests(ctx context.Context, digests []string) ([]*domain.ImageDeployment, error) {
+	args := m.Called(ctx, digests)
+	return args.Get(0).([]*domain.ImageDeployment), args.Error(1)
+}
+
+func (m *MockImageDeploymentRepository) GetActiveDeployment(ctx context.Context, clusterID, namespace, deploymentName, containerName string) (*domain.ImageDeployment, error) {
+	args := m.Called(ctx, clusterID, namespace, deploymentName, containerName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ImageDeployment), args.Error(1)
+}
+
+func (m *MockImageDeploymentRepository) UpdateStatus(ctx context.Context, id, status string) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
+}
+
+func (m *MockImageDeploymentRepository) UpdateDigest(ctx context.Context, id, digest string) error {
+	args := m.Called(ctx, id, digest)
+	return args.Error(0)
+}
+
+func (m *MockImageDeploymentRepository) CreateHistory(ctx context.Context, history *domain.ImageDeploymentHistory) error {
+	args := m.Called(ctx, history)
+	return args.Error(0)
+}
+
+func (m *MockImageDeploymentRepository) ListHistory(ctx context.Context, deploymentIDs []string) ([]*domain.ImageDeploymentHistory, error) {
+	args := m.Called(ctx, deploymentIDs)
+	return args.Get(0).([]*domain.ImageDeploymentHistory), args.Error(1)
+}
+
+// runRetention runs the ci policy against a project with five artifacts of ci/api and returns the
+// stored report with the digests the stand-in deleted
+func runRetention(t *testing.T, dryRun bool) (*domain.HarborRetentionRun, []string) {
+	t.Helper()
+
+	artifact := func(digest string, age time.Duration, tags ...string) map[string]interface{} {
+		tagList := make([]map[string]interface{}, 0, len(tags))
+		for _, tag := range tags {
+			tagList = append(tagList, map[string]interface{}{"name": tag})
+		}
+		return map[string]interface{}{"digest": digest, "push_time": time.Now().Add(-age), "tags": tagList}
+	}
+
+	var mu sync.Mutex
+	var deleted []string
+	deleteArtifact := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		deleted = append(deleted, r.URL.Path[len("/api/v2.0/projects/ci/repositories/api/artifacts/"):])
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}
+	harborRepo, harborUC := newHarborStandIn(t, map[string]http.HandlerFunc{
+		"GET /api/v2.0/projects/ci/repositories": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, []map[string]interface{}{{"name": "ci/api"}, {"name": "ci/tools/lint"}})
+		},
+		"GET /api/v2.0/projects/ci/repositories/api/artifacts": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, []map[string]interface{}{
+				artifact("sha256:old", 30*24*time.Hour),
+				artifact("sha256:ci1", 3*time.Hour, "ci-1"),
+				artifact("sha256:ci3", time.Hour, "ci-3"),
+				artifact("sha256:ci2", 2*time.Hour, "ci-2"),
+				artifact("sha256:release", 4*time.Hour, "ci-0", "v1.0.0"),
+			})
+		},
+		"DELETE /api/v2.0/projects/ci/repositories/api/artifacts/sha256:old": deleteArtifact,
+		"DELETE /api/v2.0/projects/ci/repositories/api/artifacts/sha256:ci1": deleteArtifact,
+		"DELETE /api/v2.0/projects/ci/repositories/api/artifacts/sha256:ci2": deleteArtifact,
+	})
+
+	untaggedDays := 7
+	repo := new(MockHarborRetentionRepository)
+	repo.On("GetPolicy", mock.Anything, "policy-1").Return(&domain.HarborRetentionPolicy{
+		ID:                "policy-1",
+		RegistryID:        "reg-1",
+		ProjectName:       "ci",
+		Name:              "prune-ci",
+		RepositoryPattern: "*",
+		TagPattern:        "ci-*",
+		KeepLast:          1,
+		UntaggedDays:      &untaggedDays,
+		Enforced:          true,
+		IsActive:          true,
+	}, nil)
+	repo.On("CreateRun", mock.Anything, mock.AnythingOfType("*domain.HarborRetentionRun")).Return(nil)
+	done := make(chan *domain.HarborRetentionRun, 1)
+	repo.On("UpdateRun", mock.Anything, mock.AnythingOfType("*domain.HarborRetentionRun")).
+		Run(func(args mock.Arguments) { done <- args.Get(1).(*domain.HarborRetentionRun) }).
+		Return(nil)
+
+	deploymentRepo := new(MockImageDeploymentRepository)
+	deploymentRepo.On("ListByImageRepository", mock.Anything, "ci/api").Return([]*domain.ImageDeployment{
+		{ImageRepository: "harbor.example.com/ci/api", ImageTag: "ci-2", Status: "active"},
+		{ImageRepository: "harbor.example.com/ci/api", ImageTag: "ci-1", Status: "replaced"},
+	}, nil)
+
+	uc := usecase.NewHarborRetentionUsecase(repo, harborRepo, harborUC, deploymentRepo, nil)
+	started, err := uc.RunPolicy(context.Background(), "policy-1", dryRun, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.HarborRetentionRunStatusRunning, started.Status)
+
+	select {
+	case run := <-done:
+		mu.Lock()
+		defer mu.Unlock()
+		sort.Strings(deleted)
+		return run, deleted
+	case <-time.After(5 * time.Second):
+		t.Fatal("retention run did not finish")
+		return nil, nil
+	}
+}
+
+// TestHarborRetentionRun tests that an enforced run deletes beyond the limit but keeps active artifacts
+func TestHarborRetentionRun(t *testing.T) {
+	run, deleted := runRetention(t, false)
+
+	require.Equal(t, domain.HarborRetentionRunStatusCompleted, run.Status, run.ErrorMessage)
+	assert.Equal(t, []string{"sha256:ci1", "sha256:old"}, deleted)
+	assert.Equal(t, 2, run.DeleteCount)
+	assert.Equal(t, 3, run.RetainedCount)
+	assert.Zero(t, run.FailedCount)
+	assert.NotNil(t, run.FinishedAt)
+
+	actions := make(map[string]domain.HarborRetentionAction, len(run.Items))
+	for _, item := range run.Items {
+		actions[item.Digest] = item.Action
+	}
+	assert.Equal(t, domain.HarborRetentionActionRetain, actions["sha256:ci3"])
+	assert.Equal(t, domain.HarborRetentionActionRetain, actions["sha256:ci2"], "active artifacts must never be deleted")
+	assert.Equal(t, domain.HarborRetentionActionRetain, actions["sha256:release"])
+}
+
+// TestHarborRetentionDryRun tests that dry runs report deletions without deleting
+func TestHarborRetentionDryRun(t *testing.T) {
+	run, deleted := runRetention(t, true)
+
+	require.Equal(t, domain.HarborRetentionRunStatusCompleted, run.Status, run.ErrorMessage)
+	assert.True(t, run.DryRun)
+	assert.Empty(t, deleted)
+	assert.Equal(t, 2, run.DeleteCount)
+}
+
+// TestHarborRetentionRequiresEnforcement tests that a policy without a reviewed dry run cannot delete
+func TestHarborRetentionRequiresEnforcement(t *testing.T) {
+	repo := new(MockHarborRetentionRepository)
+	repo.On("GetPolicy", mock.Anything, "policy-1").Return(&domain.HarborRetentionPolicy{
+		ID:           "policy-1",
+		ConfiguredAt: time.Now(),
+	}, nil)
+	repo.On("ListRuns", mock.Anything, "policy-1", mock.Anything).Return([]*domain.HarborRetentionRun{
+		{DryRun: true, Status: domain.HarborRetentionRunStatusCompleted, StartedAt: time.Now().Add(-time.Hour)},
+	}, nil)
+
+	uc := usecase.NewHarborRetentionUsecase(repo, nil, nil, nil, nil)
+
+	_, err := uc.RunPolicy(context.Background(), "policy-1", false, "user-1")
+	assert.Equal(t, errorx.CodeConflict, errorx.GetCode(err))
+
+	_, err = uc.EnforcePolicy(context.Background(), "policy-1")
+	assert.Equal(t, errorx.CodeConflict, errorx.GetCode(err))
+	repo.AssertNotCalled(t, "CreateRun", mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "UpdatePolicy", mock.Anything, mock.Anything)
+}
@@ -4,13 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+	"github.com/unitechio/einfra-be/pkg/harbor"
 )
 
 type harborUsecase struct {
 	harborRepo domain.HarborRegistryRepository
-	// TODO: Add Harbor Go client
 }
 
 // NewHarborUsecase creates a new Harbor use case instance
@@ -20,6 +24,21 @@ func NewHarborUsecase(harborRepo domain.HarborRegistryRepository) domain.HarborU
 	}
 }
 
+// getClient creates a Harbor API client from a registry's stored credentials
+func (u *harborUsecase) getClient(ctx context.Context, registryID string) (*harbor.Client, error) {
+	if registryID == "" {
+		return nil, errors.New("registry ID is required")
+	}
+	registry, err := u.harborRepo.GetByID(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+	if !registry.IsActive {
+		return nil, errorx.New(errorx.CodeBadRequest, fmt.Sprintf("registry %s is not active", registry.Name))
+	}
+	return harbor.NewClient(registry.URL, registry.Username, registry.Password, nil), nil
+}
+
 // Registry Management
 
 func (u *harborUsecase) CreateRegistry(ctx context.Context, registry *domain.HarborRegistry) error {
@@ -32,7 +51,6 @@ func (u *harborUsecase) CreateRegistry(ctx context.Context, registry *domain.Har
 	if registry.Username == "" {
 		return errors.New("registry username is required")
 	}
-	// TODO: Encrypt password before storing
 	return u.harborRepo.Create(ctx, registry)
 }
 
@@ -70,80 +88,239 @@ func (u *harborUsecase) DeleteRegistry(ctx context.Context, id string) error {
 	return u.harborRepo.Delete(ctx, id)
 }
 
-func (u *harborUsecase) TestRegistryConnection(ctx context.Context, id string) (bool, error) {
-	if id == "" {
-		return false, errors.New("registry ID is required")
+func (u *harborUsecase) TestRegistryConnection(ctx context.Context, id string) (*domain.HarborRegistryStatus, error) {
+	client, err := u.getClient(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	// TODO: Test connection to Harbor registry
-	return false, fmt.Errorf("not implemented")
+
+	status := &domain.HarborRegistryStatus{RegistryID: id, CheckedAt: time.Now()}
+	info, err := client.SystemInfo(ctx)
+	if err != nil {
+		status.Error = err.Error()
+		return status, nil
+	}
+	status.Connected = true
+	status.Version = info.HarborVersion
+
+	// Health needs no admin rights on most versions; a failure here still means the registry is reachable
+	if health, err := client.Health(ctx); err != nil {
+		status.Error = err.Error()
+	} else {
+		status.Health = health.Status
+		status.Components = make(map[string]string, len(health.Components))
+		for _, component := range health.Components {
+			status.Components[component.Name] = component.Status
+		}
+	}
+
+	if info.HarborVersion != "" {
+		if registry, err := u.harborRepo.GetByID(ctx, id); err == nil && registry.Version != info.HarborVersion {
+			registry.Version = info.HarborVersion
+			_ = u.harborRepo.Update(ctx, registry)
+		}
+	}
+	return status, nil
 }
 
 // Project Management
 
 func (u *harborUsecase) ListProjects(ctx context.Context, registryID string, public *bool) ([]*domain.HarborProject, error) {
-	if registryID == "" {
-		return nil, errors.New("registry ID is required")
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return nil, err
 	}
-	// TODO: Connect to Harbor and list projects
-	return []*domain.HarborProject{}, nil
+
+	projects, err := client.ListProjects(ctx, public)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*domain.HarborProject, 0, len(projects))
+	for i := range projects {
+		result = append(result, toDomainHarborProject(registryID, &projects[i]))
+	}
+	return result, nil
 }
 
 func (u *harborUsecase) GetProject(ctx context.Context, registryID string, projectID int64) (*domain.HarborProject, error) {
-	if registryID == "" {
-		return nil, errors.New("registry ID is required")
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := client.GetProject(ctx, strconv.FormatInt(projectID, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	result := toDomainHarborProject(registryID, project)
+	if quota, err := client.GetProjectQuota(ctx, projectID); err == nil {
+		result.StorageLimit = quota.Hard["storage"]
 	}
-	// TODO: Connect to Harbor and get project
-	return nil, fmt.Errorf("not implemented")
+	return result, nil
 }
 
 func (u *harborUsecase) CreateProject(ctx context.Context, registryID, name string, public bool, storageLimit int64) (*domain.HarborProject, error) {
 	if registryID == "" || name == "" {
 		return nil, errors.New("registry ID and project name are required")
 	}
-	// TODO: Connect to Harbor and create project
-	return nil, fmt.Errorf("not implemented")
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+
+	req := harbor.ProjectReq{
+		ProjectName: name,
+		Metadata:    map[string]string{"public": strconv.FormatBool(public)},
+	}
+	if storageLimit != 0 {
+		req.StorageLimit = &storageLimit
+	}
+	projectID, err := client.CreateProject(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Older Harbor versions do not return a Location header; fall back to the name
+	ref := name
+	if projectID > 0 {
+		ref = strconv.FormatInt(projectID, 10)
+	}
+	project, err := client.GetProject(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	result := toDomainHarborProject(registryID, project)
+	result.StorageLimit = storageLimit
+	return result, nil
 }
 
+// UpdateProject applies updates with the keys "public" (bool), "metadata" (string map),
+// "cve_allowlist" (list of CVE IDs) and "storage_limit" (bytes, -1 for unlimited)
 func (u *harborUsecase) UpdateProject(ctx context.Context, registryID string, projectID int64, updates map[string]interface{}) error {
-	if registryID == "" {
-		return errors.New("registry ID is required")
-	}
-	// TODO: Connect to Harbor and update project
-	return fmt.Errorf("not implemented")
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return err
+	}
+
+	req := harbor.ProjectReq{Metadata: map[string]string{}}
+	changed := false
+	for key, value := range updates {
+		switch key {
+		case "public":
+			public, ok := value.(bool)
+			if !ok {
+				return errorx.New(errorx.CodeBadRequest, "public must be a boolean")
+			}
+			req.Metadata["public"] = strconv.FormatBool(public)
+			changed = true
+		case "metadata":
+			metadata, ok := value.(map[string]interface{})
+			if !ok {
+				return errorx.New(errorx.CodeBadRequest, "metadata must be an object")
+			}
+			for k, v := range metadata {
+				req.Metadata[k] = fmt.Sprint(v)
+			}
+			changed = true
+		case "cve_allowlist":
+			items, ok := value.([]interface{})
+			if !ok {
+				return errorx.New(errorx.CodeBadRequest, "cve_allowlist must be a list of CVE IDs")
+			}
+			allowlist := &harbor.CVEAllowlist{Items: []harbor.CVEAllowlistItem{}}
+			for _, item := range items {
+				allowlist.Items = append(allowlist.Items, harbor.CVEAllowlistItem{CVEID: fmt.Sprint(item)})
+			}
+			req.CVEAllowlist = allowlist
+			changed = true
+		case "storage_limit":
+			limit, ok := value.(float64)
+			if !ok {
+				return errorx.New(errorx.CodeBadRequest, "storage_limit must be a number")
+			}
+			if err := u.updateQuota(ctx, client, projectID, int64(limit)); err != nil {
+				return err
+			}
+		default:
+			return errorx.New(errorx.CodeBadRequest, fmt.Sprintf("unsupported project field %q", key))
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	if len(req.Metadata) == 0 {
+		req.Metadata = nil
+	}
+	return client.UpdateProject(ctx, strconv.FormatInt(projectID, 10), req)
 }
 
 func (u *harborUsecase) DeleteProject(ctx context.Context, registryID string, projectID int64) error {
-	if registryID == "" {
-		return errors.New("registry ID is required")
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return err
 	}
-	// TODO: Connect to Harbor and delete project
-	return fmt.Errorf("not implemented")
+	return client.DeleteProject(ctx, strconv.FormatInt(projectID, 10))
 }
 
 // Repository Management
 
 func (u *harborUsecase) ListRepositories(ctx context.Context, registryID string, projectName string) ([]*domain.HarborRepository, error) {
-	if registryID == "" {
-		return nil, errors.New("registry ID is required")
+	if projectName == "" {
+		return nil, errors.New("project name is required")
 	}
-	// TODO: Connect to Harbor and list repositories
-	return []*domain.HarborRepository{}, nil
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+
+	repositories, err := client.ListRepositories(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*domain.HarborRepository, 0, len(repositories))
+	for i := range repositories {
+		result = append(result, toDomainHarborRepository(registryID, &repositories[i]))
+	}
+	return result, nil
 }
 
 func (u *harborUsecase) GetRepository(ctx context.Context, registryID, repositoryName string) (*domain.HarborRepository, error) {
 	if registryID == "" || repositoryName == "" {
 		return nil, errors.New("registry ID and repository name are required")
 	}
-	// TODO: Connect to Harbor and get repository
-	return nil, fmt.Errorf("not implemented")
+	project, repo, err := harbor.SplitRepository(repositoryName)
+	if err != nil {
+		return nil, err
+	}
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+
+	repository, err := client.GetRepository(ctx, project, repo)
+	if err != nil {
+		return nil, err
+	}
+	return toDomainHarborRepository(registryID, repository), nil
 }
 
 func (u *harborUsecase) DeleteRepository(ctx context.Context, registryID, repositoryName string) error {
 	if registryID == "" || repositoryName == "" {
 		return errors.New("registry ID and repository name are required")
 	}
-	// TODO: Connect to Harbor and delete repository
-	return fmt.Errorf("not implemented")
+	project, repo, err := harbor.SplitRepository(repositoryName)
+	if err != nil {
+		return err
+	}
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return err
+	}
+	return client.DeleteRepository(ctx, project, repo)
 }
 
 // Artifact Management
@@ -152,32 +329,83 @@ func (u *harborUsecase) ListArtifacts(ctx context.Context, registryID, repositor
 	if registryID == "" || repositoryName == "" {
 		return nil, errors.New("registry ID and repository name are required")
 	}
-	// TODO: Connect to Harbor and list artifacts
-	return []*domain.HarborArtifact{}, nil
+	project, repo, err := harbor.SplitRepository(repositoryName)
+	if err != nil {
+		return nil, err
+	}
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts, err := client.ListArtifacts(ctx, project, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*domain.HarborArtifact, 0, len(artifacts))
+	for i := range artifacts {
+		result = append(result, toDomainHarborArtifact(registryID, repositoryName, &artifacts[i]))
+	}
+	return result, nil
 }
 
 func (u *harborUsecase) GetArtifact(ctx context.Context, registryID, repositoryName, reference string) (*domain.HarborArtifact, error) {
 	if registryID == "" || repositoryName == "" || reference == "" {
 		return nil, errors.New("registry ID, repository name, and reference are required")
 	}
-	// TODO: Connect to Harbor and get artifact
-	return nil, fmt.Errorf("not implemented")
+	project, repo, err := harbor.SplitRepository(repositoryName)
+	if err != nil {
+		return nil, err
+	}
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+
+	artifact, err := client.GetArtifact(ctx, project, repo, reference)
+	if err != nil {
+		return nil, err
+	}
+	return toDomainHarborArtifact(registryID, repositoryName, artifact), nil
 }
 
 func (u *harborUsecase) DeleteArtifact(ctx context.Context, registryID, repositoryName, reference string) error {
 	if registryID == "" || repositoryName == "" || reference == "" {
 		return errors.New("registry ID, repository name, and reference are required")
 	}
-	// TODO: Connect to Harbor and delete artifact
-	return fmt.Errorf("not implemented")
+	project, repo, err := harbor.SplitRepository(repositoryName)
+	if err != nil {
+		return err
+	}
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return err
+	}
+	return client.DeleteArtifact(ctx, project, repo, reference)
 }
 
 func (u *harborUsecase) CopyArtifact(ctx context.Context, registryID, srcRepo, dstRepo, reference string) error {
 	if registryID == "" || srcRepo == "" || dstRepo == "" || reference == "" {
 		return errors.New("registry ID, source repository, destination repository, and reference are required")
 	}
-	// TODO: Connect to Harbor and copy artifact
-	return fmt.Errorf("not implemented")
+	if _, _, err := harbor.SplitRepository(srcRepo); err != nil {
+		return err
+	}
+	project, repo, err := harbor.SplitRepository(dstRepo)
+	if err != nil {
+		return err
+	}
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return err
+	}
+
+	from := srcRepo + ":" + reference
+	if strings.Contains(reference, ":") {
+		from = srcRepo + "@" + reference // Digest
+	}
+	return client.CopyArtifact(ctx, project, repo, from)
 }
 
 // Vulnerability Scanning
@@ -186,82 +414,363 @@ func (u *harborUsecase) ScanArtifact(ctx context.Context, registryID, repository
 	if registryID == "" || repositoryName == "" || reference == "" {
 		return errors.New("registry ID, repository name, and reference are required")
 	}
-	// TODO: Connect to Harbor and trigger scan
-	return fmt.Errorf("not implemented")
+	project, repo, err := harbor.SplitRepository(repositoryName)
+	if err != nil {
+		return err
+	}
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return err
+	}
+	return client.ScanArtifact(ctx, project, repo, reference)
 }
 
 func (u *harborUsecase) GetScanReport(ctx context.Context, registryID, repositoryName, reference string) (*domain.HarborScanOverview, error) {
-	if registryID == "" || repositoryName == "" || reference == "" {
-		return nil, errors.New("registry ID, repository name, and reference are required")
+	artifact, err := u.GetArtifact(ctx, registryID, repositoryName, reference)
+	if err != nil {
+		return nil, err
 	}
-	// TODO: Connect to Harbor and get scan report
-	return nil, fmt.Errorf("not implemented")
+	if artifact.ScanOverview == nil {
+		return nil, errorx.New(errorx.CodeNotFound, fmt.Sprintf("artifact %s@%s has not been scanned", repositoryName, reference))
+	}
+	return artifact.ScanOverview, nil
 }
 
 func (u *harborUsecase) GetVulnerabilities(ctx context.Context, registryID, repositoryName, reference string) ([]*domain.HarborVulnerability, error) {
 	if registryID == "" || repositoryName == "" || reference == "" {
 		return nil, errors.New("registry ID, repository name, and reference are required")
 	}
-	// TODO: Connect to Harbor and get vulnerabilities
-	return []*domain.HarborVulnerability{}, nil
+	project, repo, err := harbor.SplitRepository(repositoryName)
+	if err != nil {
+		return nil, err
+	}
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+
+	reports, err := client.GetVulnerabilities(ctx, project, repo, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*domain.HarborVulnerability{}
+	for _, report := range reports {
+		for _, item := range report.Vulnerabilities {
+			vulnerability := &domain.HarborVulnerability{
+				ID:          item.ID,
+				Package:     item.Package,
+				Version:     item.Version,
+				FixVersion:  item.FixVersion,
+				Severity:    item.Severity,
+				Description: item.Description,
+				Links:       item.Links,
+			}
+			if cvss := item.PreferredCVSS; cvss != nil {
+				if cvss.ScoreV3 != nil {
+					vulnerability.CVSSScore = *cvss.ScoreV3
+				} else if cvss.ScoreV2 != nil {
+					vulnerability.CVSSScore = *cvss.ScoreV2
+				}
+			}
+			result = append(result, vulnerability)
+		}
+		// Harbor returns the same report under one MIME type per scanner; the first is enough
+		break
+	}
+	return result, nil
+}
+
+func (u *harborUsecase) ListArtifactsByCVE(ctx context.Context, registryID, cveID string) ([]*domain.HarborCVEArtifact, error) {
+	if registryID == "" || cveID == "" {
+		return nil, errors.New("registry ID and CVE ID are required")
+	}
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := client.ListArtifactsByCVE(ctx, cveID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*domain.HarborCVEArtifact, 0, len(items))
+	for _, item := range items {
+		result = append(result, &domain.HarborCVEArtifact{
+			RegistryID: registryID,
+			Repository: item.RepositoryName,
+			Digest:     item.Digest,
+			Tags:       item.Tags,
+			Package:    item.Package,
+			Version:    item.Version,
+			FixVersion: item.FixedVersion,
+			Severity:   item.Severity,
+		})
+	}
+	return result, nil
 }
 
 // Label Management
 
 func (u *harborUsecase) ListLabels(ctx context.Context, registryID string, scope string, projectID *int64) ([]*domain.HarborLabel, error) {
-	if registryID == "" {
-		return nil, errors.New("registry ID is required")
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	if projectID != nil {
+		id = *projectID
+	}
+	labels, err := client.ListLabels(ctx, toHarborLabelScope(scope), id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*domain.HarborLabel, 0, len(labels))
+	for i := range labels {
+		result = append(result, toDomainHarborLabel(&labels[i]))
 	}
-	// TODO: Connect to Harbor and list labels
-	return []*domain.HarborLabel{}, nil
+	return result, nil
 }
 
 func (u *harborUsecase) CreateLabel(ctx context.Context, registryID string, label *domain.HarborLabel) error {
-	if registryID == "" {
-		return errors.New("registry ID is required")
-	}
-	// TODO: Connect to Harbor and create label
-	return fmt.Errorf("not implemented")
+	if label == nil || label.Name == "" {
+		return errors.New("label name is required")
+	}
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return err
+	}
+
+	id, err := client.CreateLabel(ctx, harbor.Label{
+		Name:        label.Name,
+		Description: label.Description,
+		Color:       label.Color,
+		Scope:       toHarborLabelScope(label.Scope),
+		ProjectID:   label.ProjectID,
+	})
+	if err != nil {
+		return err
+	}
+	label.ID = id
+	return nil
 }
 
 func (u *harborUsecase) DeleteLabel(ctx context.Context, registryID string, labelID int64) error {
-	if registryID == "" {
-		return errors.New("registry ID is required")
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return err
 	}
-	// TODO: Connect to Harbor and delete label
-	return fmt.Errorf("not implemented")
+	return client.DeleteLabel(ctx, labelID)
 }
 
 func (u *harborUsecase) AddLabelToArtifact(ctx context.Context, registryID, repositoryName, reference string, labelID int64) error {
 	if registryID == "" || repositoryName == "" || reference == "" {
 		return errors.New("registry ID, repository name, and reference are required")
 	}
-	// TODO: Connect to Harbor and add label to artifact
-	return fmt.Errorf("not implemented")
+	project, repo, err := harbor.SplitRepository(repositoryName)
+	if err != nil {
+		return err
+	}
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return err
+	}
+	return client.AddArtifactLabel(ctx, project, repo, reference, labelID)
 }
 
 func (u *harborUsecase) RemoveLabelFromArtifact(ctx context.Context, registryID, repositoryName, reference string, labelID int64) error {
 	if registryID == "" || repositoryName == "" || reference == "" {
 		return errors.New("registry ID, repository name, and reference are required")
 	}
-	// TODO: Connect to Harbor and remove label from artifact
-	return fmt.Errorf("not implemented")
+	project, repo, err := harbor.SplitRepository(repositoryName)
+	if err != nil {
+		return err
+	}
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return err
+	}
+	return client.RemoveArtifactLabel(ctx, project, repo, reference, labelID)
 }
 
 // Quota Management
 
 func (u *harborUsecase) GetProjectQuota(ctx context.Context, registryID string, projectID int64) (*domain.HarborQuota, error) {
-	if registryID == "" {
-		return nil, errors.New("registry ID is required")
-	}
-	// TODO: Connect to Harbor and get project quota
-	return nil, fmt.Errorf("not implemented")
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := client.GetProjectQuota(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.HarborQuota{
+		ID:        quota.ID,
+		Ref:       quota.Ref,
+		Hard:      quota.Hard,
+		Used:      quota.Used,
+		CreatedAt: quota.CreationTime,
+		UpdatedAt: quota.UpdateTime,
+	}, nil
 }
 
 func (u *harborUsecase) UpdateProjectQuota(ctx context.Context, registryID string, projectID int64, storageLimit int64) error {
-	if registryID == "" {
-		return errors.New("registry ID is required")
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return err
+	}
+	return u.updateQuota(ctx, client, projectID, storageLimit)
+}
+
+func (u *harborUsecase) updateQuota(ctx context.Context, client *harbor.Client, projectID, storageLimit int64) error {
+	if storageLimit == 0 || storageLimit < -1 {
+		return errorx.New(errorx.CodeBadRequest, "storage limit must be positive, or -1 for unlimited")
+	}
+	quota, err := client.GetProjectQuota(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	return client.UpdateQuota(ctx, quota.ID, map[string]int64{"storage": storageLimit})
+}
+
+// Garbage Collection
+
+func (u *harborUsecase) RunGarbageCollection(ctx context.Context, registryID string, deleteUntagged bool) (int64, error) {
+	client, err := u.getClient(ctx, registryID)
+	if err != nil {
+		return 0, err
+	}
+	return client.RunGarbageCollection(ctx, deleteUntagged)
+}
+
+// Mapping
+
+func toDomainHarborProject(registryID string, project *harbor.Project) *domain.HarborProject {
+	result := &domain.HarborProject{
+		ID:           project.ProjectID,
+		Name:         project.Name,
+		RegistryID:   registryID,
+		Public:       project.Metadata["public"] == "true",
+		OwnerName:    project.OwnerName,
+		RepoCount:    project.RepoCount,
+		ChartCount:   project.ChartCount,
+		Metadata:     project.Metadata,
+		CVEAllowlist: []string{},
+		CreatedAt:    project.CreationTime,
+		UpdatedAt:    project.UpdateTime,
+	}
+	if project.CVEAllowlist != nil {
+		for _, item := range project.CVEAllowlist.Items {
+			result.CVEAllowlist = append(result.CVEAllowlist, item.CVEID)
+		}
+	}
+	return result
+}
+
+func toDomainHarborRepository(registryID string, repository *harbor.Repository) *domain.HarborRepository {
+	return &domain.HarborRepository{
+		ID:            repository.ID,
+		Name:          repository.Name,
+		ProjectID:     repository.ProjectID,
+		RegistryID:    registryID,
+		Description:   repository.Description,
+		ArtifactCount: repository.ArtifactCount,
+		PullCount:     repository.PullCount,
+		CreatedAt:     repository.CreationTime,
+		UpdatedAt:     repository.UpdateTime,
+	}
+}
+
+func toDomainHarborArtifact(registryID, repositoryName string, artifact *harbor.Artifact) *domain.HarborArtifact {
+	result := &domain.HarborArtifact{
+		ID:                artifact.ID,
+		Digest:            artifact.Digest,
+		RepositoryName:    repositoryName,
+		ProjectID:         artifact.ProjectID,
+		RegistryID:        registryID,
+		Tags:              make([]domain.HarborTag, 0, len(artifact.Tags)),
+		Type:              artifact.Type,
+		Size:              artifact.Size,
+		PushTime:          artifact.PushTime,
+		PullTime:          optionalTime(artifact.PullTime),
+		Labels:            make([]domain.HarborLabel, 0, len(artifact.Labels)),
+		Annotations:       artifact.Annotations,
+		ManifestMediaType: artifact.ManifestMediaType,
+	}
+	for _, tag := range artifact.Tags {
+		result.Tags = append(result.Tags, domain.HarborTag{
+			ID:        tag.ID,
+			Name:      tag.Name,
+			PushTime:  tag.PushTime,
+			PullTime:  optionalTime(tag.PullTime),
+			Immutable: tag.Immutable,
+			Signed:    tag.Signed,
+		})
+	}
+	for i := range artifact.Labels {
+		result.Labels = append(result.Labels, *toDomainHarborLabel(&artifact.Labels[i]))
+	}
+	for _, overview := range artifact.ScanOverview {
+		result.ScanOverview = toDomainHarborScanOverview(&overview)
+		break
+	}
+	return result
+}
+
+func toDomainHarborScanOverview(overview *harbor.NativeReportSummary) *domain.HarborScanOverview {
+	result := &domain.HarborScanOverview{
+		ScanStatus: overview.ScanStatus,
+		Severity:   overview.Severity,
+		Duration:   overview.Duration,
+		StartTime:  overview.StartTime,
+		EndTime:    overview.EndTime,
+	}
+	if overview.Summary != nil {
+		result.Summary = domain.HarborVulnerabilitySummary{
+			Total:    overview.Summary.Total,
+			Critical: overview.Summary.Summary["Critical"],
+			High:     overview.Summary.Summary["High"],
+			Medium:   overview.Summary.Summary["Medium"],
+			Low:      overview.Summary.Summary["Low"],
+			Unknown:  overview.Summary.Summary["Unknown"],
+		}
+	}
+	return result
+}
+
+func toDomainHarborLabel(label *harbor.Label) *domain.HarborLabel {
+	scope := "global"
+	if label.Scope == "p" {
+		scope = "project"
+	}
+	return &domain.HarborLabel{
+		ID:          label.ID,
+		Name:        label.Name,
+		Description: label.Description,
+		Color:       label.Color,
+		Scope:       scope,
+		ProjectID:   label.ProjectID,
+		CreatedAt:   label.CreationTime,
+		UpdatedAt:   label.UpdateTime,
+	}
+}
+
+// toHarborLabelScope maps "global"/"project" to Harbor's "g"/"p"
+func toHarborLabelScope(scope string) string {
+	if scope == "project" || scope == "p" {
+		return "p"
+	}
+	return "g"
+}
+
+// optionalTime returns nil for the zero time Harbor reports for never-pulled artifacts
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
 	}
-	// TODO: Connect to Harbor and update project quota
-	return fmt.Errorf("not implemented")
+	return &t
 }
@@ -0,0 +1,227 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+)
+
+// MockHarborRegistryRepository is a mock implementation of HarborRegistryRepository
+type MockHarborRegistryRepository struct {
+	mock.Mock
+}
+
+func (m *MockHarborRegistryRepository) Create(ctx context.Context, registry *domain.HarborRegistry) error {
+	args := m.Called(ctx, registry)
+	return args.Error(0)
+}
+
+func (m *MockHarborRegistryRepository) GetByID(ctx context.Context, id string) (*domain.HarborRegistry, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.HarborRegistry), args.Error(1)
+}
+
+func (m *MockHarborRegistryRepository) List(ctx context.Context, filter domain.HarborRegistryFilter) ([]*domain.HarborRegistry, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]*domain.HarborRegistry), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockHarborRegistryRepository) Update(ctx context.Context, registry *domain.HarborRegistry) error {
+	args := m.Called(ctx, registry)
+	return args.Error(0)
+}
+
+func (m *MockHarborRegistryRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockHarborRegistryRepository) GetDefault(ctx context.Context) (*domain.HarborRegistry, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.HarborRegistry), args.Error(1)
+}
+
+// newHarborStandIn starts an httptest Harbor API that requires robot account credentials
+func newHarborStandIn(t *testing.T, routes map[string]http.HandlerFunc) (*MockHarborRegistryRepository, domain.HarborUsecase) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "robot$ci" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"errors":[{"code":"UNAUTHORIZED","message":"unauthorized"}]}`))
+			return
+		}
+		// Match on the escaped path so double-encoded repository names stay visible
+		handler, ok := routes[r.Method+" "+r.URL.EscapedPath()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[{"code":"NOT_FOUND","message":"path not found"}]}`))
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	repo := new(MockHarborRegistryRepository)
+	repo.On("GetByID", mock.Anything, "reg-1").Return(&domain.HarborRegistry{
+		ID:       "reg-1",
+		Name:     "harbor",
+		URL:      server.URL,
+		Username: "robot$ci",
+		Password: "secret",
+		IsActive: true,
+	}, nil)
+	return repo, usecase.NewHarborUsecase(repo)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// TestHarborTestRegistryConnection tests reporting version and health
+func TestHarborTestRegistryConnection(t *testing.T) {
+	repo, uc := newHarborStandIn(t, map[string]http.HandlerFunc{
+		"GET /api/v2.0/systeminfo": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]string{"harbor_version": "v2.9.0-5b1a3f1c"})
+		},
+		"GET /api/v2.0/health": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, map[string]interface{}{
+				"status": "unhealthy",
+				"components": []map[string]string{
+					{"name": "core", "status": "healthy"},
+					{"name": "trivy", "status": "unhealthy", "error": "timeout"},
+				},
+			})
+		},
+	})
+	repo.On("Update", mock.Anything, mock.MatchedBy(func(r *domain.HarborRegistry) bool {
+		return r.Version == "v2.9.0-5b1a3f1c"
+	})).Return(nil)
+
+	status, err := uc.TestRegistryConnection(context.Background(), "reg-1")
+
+	require.NoError(t, err)
+	assert.True(t, status.Connected)
+	assert.Equal(t, "v2.9.0-5b1a3f1c", status.Version)
+	assert.Equal(t, "unhealthy", status.Health)
+	assert.Equal(t, map[string]string{"core": "healthy", "trivy": "unhealthy"}, status.Components)
+	repo.AssertExpectations(t)
+}
+
+// TestHarborListProjectsPagination tests that every page is fetched
+func TestHarborListProjectsPagination(t *testing.T) {
+	const total = 150
+	_, uc := newHarborStandIn(t, map[string]http.HandlerFunc{
+		"GET /api/v2.0/projects": func(w http.ResponseWriter, r *http.Request) {
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			size, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+			assert.Equal(t, "true", r.URL.Query().Get("public"))
+
+			projects := []map[string]interface{}{}
+			for id := (page-1)*size + 1; id <= total && id <= page*size; id++ {
+				projects = append(projects, map[string]interface{}{
+					"project_id": id,
+					"name":       fmt.Sprintf("project-%d", id),
+					"metadata":   map[string]string{"public": "true"},
+				})
+			}
+			w.Header().Set("X-Total-Count", strconv.Itoa(total))
+			writeJSON(w, projects)
+		},
+	})
+
+	public := true
+	projects, err := uc.ListProjects(context.Background(), "reg-1", &public)
+
+	require.NoError(t, err)
+	require.Len(t, projects, total)
+	assert.Equal(t, int64(1), projects[0].ID)
+	assert.Equal(t, "project-150", projects[total-1].Name)
+	assert.True(t, projects[0].Public)
+	assert.Equal(t, "reg-1", projects[0].RegistryID)
+}
+
+// TestHarborArtifactScanReport tests nested repository names and scan overview mapping
+func TestHarborArtifactScanReport(t *testing.T) {
+	_, uc := newHarborStandIn(t, map[string]http.HandlerFunc{
+		"GET /api/v2.0/projects/library/repositories/team%252Fapi/artifacts/v1.2.0": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "true", r.URL.Query().Get("with_scan_overview"))
+			writeJSON(w, map[string]interface{}{
+				"id":     7,
+				"digest": "sha256:abc",
+				"tags":   []map[string]interface{}{{"id": 1, "name": "v1.2.0"}},
+				"scan_overview": map[string]interface{}{
+					"application/vnd.security.vulnerability.report; version=1.1": map[string]interface{}{
+						"scan_status": "Success",
+						"severity":    "High",
+						"summary": map[string]interface{}{
+							"total":   3,
+							"summary": map[string]int{"High": 1, "Low": 2},
+						},
+					},
+				},
+			})
+		},
+	})
+
+	report, err := uc.GetScanReport(context.Background(), "reg-1", "library/team/api", "v1.2.0")
+
+	require.NoError(t, err)
+	assert.Equal(t, "Success", report.ScanStatus)
+	assert.Equal(t, "High", report.Severity)
+	assert.Equal(t, domain.HarborVulnerabilitySummary{Total: 3, High: 1, Low: 2}, report.Summary)
+}
+
+// TestHarborCopyArtifact tests the copy source reference for tags and digests
+func TestHarborCopyArtifact(t *testing.T) {
+	var sources []string
+	_, uc := newHarborStandIn(t, map[string]http.HandlerFunc{
+		"POST /api/v2.0/projects/prod/repositories/api/artifacts": func(w http.ResponseWriter, r *http.Request) {
+			sources = append(sources, r.URL.Query().Get("from"))
+			w.WriteHeader(http.StatusCreated)
+		},
+	})
+
+	require.NoError(t, uc.CopyArtifact(context.Background(), "reg-1", "staging/api", "prod/api", "v1.2.0"))
+	require.NoError(t, uc.CopyArtifact(context.Background(), "reg-1", "staging/api", "prod/api", "sha256:abc"))
+
+	assert.Equal(t, []string{"staging/api:v1.2.0", "staging/api@sha256:abc"}, sources)
+}
+
+// TestHarborErrorMapping tests that Harbor errors surface as errorx codes
+func TestHarborErrorMapping(t *testing.T) {
+	_, uc := newHarborStandIn(t, map[string]http.HandlerFunc{
+		"DELETE /api/v2.0/projects/42": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			_, _ = w.Write([]byte(`{"errors":[{"code":"PRECONDITION","message":"the project contains repositories"}]}`))
+		},
+	})
+
+	err := uc.DeleteProject(context.Background(), "reg-1", 42)
+	require.Error(t, err)
+	assert.Equal(t, errorx.CodeConflict, errorx.GetCode(err))
+	assert.True(t, strings.Contains(err.Error(), "the project contains repositories"))
+
+	_, err = uc.GetProject(context.Background(), "reg-1", 7)
+	require.Error(t, err)
+	assert.Equal(t, errorx.CodeNotFound, errorx.GetCode(err))
+}
@@ -0,0 +1,314 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/messaging"
+	"github.com/unitechio/einfra-be/internal/repository"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+	"github.com/unitechio/einfra-be/pkg/harbor"
+)
+
+const (
+	// harborArtifactAuditResource is the audit log resource of Harbor artifact events
+	harborArtifactAuditResource = "harbor_artifact"
+	// harborWebhookRecipientPageSize is the page size used when looking up users to notify
+	harborWebhookRecipientPageSize = 100
+)
+
+// harborWebhookRecipientPermissions grant the right to be notified about Harbor events
+var harborWebhookRecipientPermissions = []string{"harbor.*", "harbor.repository.read"}
+
+type harborWebhookUsecase struct {
+	harborRepo          domain.HarborRegistryRepository
+	userRepo            repository.UserRepository
+	notificationUsecase NotificationUsecase
+	auditUsecase        AuditUsecase
+	publisher           messaging.Publisher
+	topic               string
+}
+
+// NewHarborWebhookUsecase creates a new Harbor webhook use case instance.
+// A nil publisher disables publishing events to the message broker.
+func NewHarborWebhookUsecase(
+	harborRepo domain.HarborRegistryRepository,
+	userRepo repository.UserRepository,
+	notificationUsecase NotificationUsecase,
+	auditUsecase AuditUsecase,
+	publisher messaging.Publisher,
+	topic string,
+) domain.HarborWebhookUsecase {
+	return &harborWebhookUsecase{
+		harborRepo:          harborRepo,
+		userRepo:            userRepo,
+		notificationUsecase: notificationUsecase,
+		auditUsecase:        auditUsecase,
+		publisher:           publisher,
+		topic:               topic,
+	}
+}
+
+func (u *harborWebhookUsecase) HandleWebhook(ctx context.Context, registryID, authorization string, body []byte) ([]*domain.HarborWebhookEvent, error) {
+	// Unknown registries and bad secrets get the same answer so the endpoint can't be used to probe IDs
+	registry, err := u.harborRepo.GetByID(ctx, registryID)
+	if err != nil || !verifyWebhookSecret(registry.WebhookSecret, authorization) {
+		return nil, errorx.New(errorx.CodeUnauthorized, "invalid webhook credentials")
+	}
+
+	var payload harbor.WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errorx.New(errorx.CodeBadRequest, "invalid webhook payload: "+err.Error())
+	}
+
+	switch payload.Type {
+	case harbor.EventTypePushArtifact, harbor.EventTypeScanningCompleted, harbor.EventTypeDeleteArtifact, harbor.EventTypeQuotaExceed:
+	default:
+		return []*domain.HarborWebhookEvent{}, nil
+	}
+
+	events := toHarborWebhookEvents(registry.ID, &payload)
+
+	// Publish first: a failure makes Harbor redeliver the payload, so nothing else may have happened yet
+	if u.publisher != nil {
+		for _, event := range events {
+			if err := u.publish(ctx, event); err != nil {
+				return nil, errorx.Wrap(err, errorx.CodeServiceUnavailable, "failed to publish Harbor event")
+			}
+		}
+	}
+
+	for _, event := range events {
+		u.audit(ctx, registry, event)
+	}
+
+	if u.notificationUsecase != nil {
+		recipients, err := u.recipients(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(recipients) > 0 {
+			for _, event := range events {
+				_ = u.notificationUsecase.SendBulkNotification(ctx, recipients, harborEventNotification(registry, event))
+			}
+		}
+	}
+
+	return events, nil
+}
+
+// publish sends an event to the Harbor events topic
+func (u *harborWebhookUsecase) publish(ctx context.Context, event *domain.HarborWebhookEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return u.publisher.Publish(ctx, messaging.Message{
+		ID:      event.ID,
+		Topic:   u.topic,
+		Payload: payload,
+		Headers: map[string]string{
+			"event_type":  event.Type,
+			"registry_id": event.RegistryID,
+		},
+	})
+}
+
+// audit records an event in the audit log on behalf of the Harbor operator
+func (u *harborWebhookUsecase) audit(ctx context.Context, registry *domain.HarborRegistry, event *domain.HarborWebhookEvent) {
+	if u.auditUsecase == nil {
+		return
+	}
+
+	action := domain.AuditActionUpdate
+	switch event.Type {
+	case harbor.EventTypePushArtifact:
+		action = domain.AuditActionCreate
+	case harbor.EventTypeDeleteArtifact:
+		action = domain.AuditActionDelete
+	}
+
+	resourceID := registry.ID + "/" + event.Repository
+	if event.Digest != "" {
+		resourceID += "@" + event.Digest
+	}
+
+	_ = u.auditUsecase.Log(ctx, &domain.AuditLog{
+		Username:    "harbor:" + event.Operator,
+		Action:      action,
+		Resource:    harborArtifactAuditResource,
+		ResourceID:  &resourceID,
+		Description: harborEventDescription(registry, event),
+		Metadata: map[string]interface{}{
+			"registry_id": registry.ID,
+			"event_id":    event.ID,
+			"event_type":  event.Type,
+			"repository":  event.Repository,
+			"digest":      event.Digest,
+			"tag":         event.Tag,
+		},
+		Success:   true,
+		CreatedAt: time.Now(),
+	})
+}
+
+// recipients returns the active users allowed to see Harbor repositories
+func (u *harborWebhookUsecase) recipients(ctx context.Context) ([]string, error) {
+	active := true
+	filter := domain.UserFilter{IsActive: &active, Page: 1, PageSize: harborWebhookRecipientPageSize}
+
+	var userIDs []string
+	for {
+		users, total, err := u.userRepo.List(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			for _, permission := range harborWebhookRecipientPermissions {
+				if user.HasPermission(permission) {
+					userIDs = append(userIDs, user.ID)
+					break
+				}
+			}
+		}
+		if len(users) == 0 || int64(filter.Page*filter.PageSize) >= total {
+			return userIDs, nil
+		}
+		filter.Page++
+	}
+}
+
+// verifyWebhookSecret compares the auth header Harbor sent with the registry's secret in constant time.
+// Harbor sends the configured auth header verbatim, so a "Bearer " prefix is accepted as well.
+func verifyWebhookSecret(secret, authorization string) bool {
+	if secret == "" || authorization == "" {
+		return false
+	}
+	for _, candidate := range []string{authorization, strings.TrimPrefix(authorization, "Bearer ")} {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(secret)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// toHarborWebhookEvents splits a payload into one event per artifact. Events without
+// resources, such as some quota events, yield a single repository-level event.
+func toHarborWebhookEvents(registryID string, payload *harbor.WebhookPayload) []*domain.HarborWebhookEvent {
+	repositoryName := payload.EventData.Repository.RepoFullName
+	if repositoryName == "" && payload.EventData.Repository.Name != "" {
+		repositoryName = payload.EventData.Repository.Namespace + "/" + payload.EventData.Repository.Name
+	}
+	occurredAt := time.Now()
+	if payload.OccurAt > 0 {
+		occurredAt = time.Unix(payload.OccurAt, 0)
+	}
+
+	newEvent := func() *domain.HarborWebhookEvent {
+		return &domain.HarborWebhookEvent{
+			ID:         uuid.New().String(),
+			RegistryID: registryID,
+			Type:       payload.Type,
+			OccurredAt: occurredAt,
+			Operator:   payload.Operator,
+			Repository: repositoryName,
+			Details:    payload.EventData.CustomAttributes["Details"],
+		}
+	}
+
+	if len(payload.EventData.Resources) == 0 {
+		return []*domain.HarborWebhookEvent{newEvent()}
+	}
+
+	events := make([]*domain.HarborWebhookEvent, 0, len(payload.EventData.Resources))
+	for _, resource := range payload.EventData.Resources {
+		event := newEvent()
+		event.Digest = resource.Digest
+		event.Tag = resource.Tag
+		event.ResourceURL = resource.ResourceURL
+		for _, overview := range resource.ScanOverview {
+			event.ScanOverview = toDomainHarborScanOverview(&overview)
+			break
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// harborEventNotification builds the in-app notification of an event
+func harborEventNotification(registry *domain.HarborRegistry, event *domain.HarborWebhookEvent) *domain.Notification {
+	notification := &domain.Notification{
+		Type:      domain.NotificationTypeInfo,
+		Channel:   domain.NotificationChannelInApp,
+		Priority:  domain.NotificationPriorityNormal,
+		Message:   harborEventDescription(registry, event),
+		Data:      event,
+		ActionURL: fmt.Sprintf("/harbor/registries/%s/artifacts?repository=%s", registry.ID, url.QueryEscape(event.Repository)),
+		Icon:      "harbor",
+	}
+
+	switch event.Type {
+	case harbor.EventTypePushArtifact:
+		notification.Title = "Image pushed"
+		notification.Priority = domain.NotificationPriorityLow
+	case harbor.EventTypeDeleteArtifact:
+		notification.Title = "Image deleted"
+	case harbor.EventTypeScanningCompleted:
+		notification.Title = "Vulnerability scan completed"
+		notification.Type = domain.NotificationTypeSuccess
+		if event.ScanOverview != nil {
+			switch {
+			case event.ScanOverview.Summary.Critical > 0:
+				notification.Type = domain.NotificationTypeError
+				notification.Priority = domain.NotificationPriorityUrgent
+			case event.ScanOverview.Summary.High > 0:
+				notification.Type = domain.NotificationTypeWarning
+				notification.Priority = domain.NotificationPriorityHigh
+			}
+		}
+	case harbor.EventTypeQuotaExceed:
+		notification.Title = "Project quota exceeded"
+		notification.Type = domain.NotificationTypeError
+		notification.Priority = domain.NotificationPriorityHigh
+	}
+	return notification
+}
+
+// harborEventDescription describes an event in one sentence
+func harborEventDescription(registry *domain.HarborRegistry, event *domain.HarborWebhookEvent) string {
+	artifact := event.Repository
+	switch {
+	case event.Tag != "":
+		artifact += ":" + event.Tag
+	case event.Digest != "":
+		artifact += "@" + event.Digest
+	}
+
+	switch event.Type {
+	case harbor.EventTypePushArtifact:
+		return fmt.Sprintf("%s was pushed to %s by %s", artifact, registry.Name, event.Operator)
+	case harbor.EventTypeDeleteArtifact:
+		return fmt.Sprintf("%s was deleted from %s by %s", artifact, registry.Name, event.Operator)
+	case harbor.EventTypeScanningCompleted:
+		if event.ScanOverview == nil {
+			return fmt.Sprintf("Scan of %s in %s completed", artifact, registry.Name)
+		}
+		summary := event.ScanOverview.Summary
+		return fmt.Sprintf("Scan of %s in %s completed with status %s: %d vulnerabilities (%d critical, %d high)",
+			artifact, registry.Name, event.ScanOverview.ScanStatus, summary.Total, summary.Critical, summary.High)
+	case harbor.EventTypeQuotaExceed:
+		description := fmt.Sprintf("Pushing %s to %s exceeded the project quota", artifact, registry.Name)
+		if event.Details != "" {
+			description += ": " + event.Details
+		}
+		return description
+	}
+	return fmt.Sprintf("Harbor event %s for %s in %s", event.Type, artifact, registry.Name)
+}
@@ -0,0 +1,116 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+)
+
+func newHarborWebhookUsecase() domain.HarborWebhookUsecase {
+	repo := new(MockHarborRegistryRepository)
+	repo.On("GetByID", mock.Anything, "reg-1").Return(&domain.HarborRegistry{ID: "reg-1", Name: "prod-harbor", WebhookSecret: "s3cr3t", HasWebhookSecret: true}, nil)
+	repo.On("GetByID", mock.Anything, "reg-2").Return(&domain.HarborRegistry{ID: "reg-2", Name: "dev-harbor"}, nil)
+	repo.On("GetByID", mock.Anything, "missing").Return(nil, errors.New("harbor registry not found"))
+	return usecase.NewHarborWebhookUsecase(repo, nil, nil, nil, nil, "")
+}
+
+// TestHarborWebhookSecret tests that events are only accepted with the registry's secret, sent verbatim
+// or as a bearer token
+func TestHarborWebhookSecret(t *testing.T) {
+	body := []byte(`{"type":"PULL_ARTIFACT"}`)
+	tests := []struct {
+		name          string
+		registryID    string
+		authorization string
+		wantErr       bool
+	}{
+		{name: "Verbatim secret", registryID: "reg-1", authorization: "s3cr3t"},
+		{name: "Bearer secret", registryID: "reg-1", authorization: "Bearer s3cr3t"},
+		{name: "Wrong secret", registryID: "reg-1", authorization: "Bearer guess", wantErr: true},
+		{name: "Secret prefix", registryID: "reg-1", authorization: "s3cr3", wantErr: true},
+		{name: "No authorization", registryID: "reg-1", wantErr: true},
+		{name: "Registry without secret", registryID: "reg-2", authorization: "", wantErr: true},
+		{name: "Unknown registry", registryID: "missing", authorization: "s3cr3t", wantErr: true},
+	}
+
+	uc := newHarborWebhookUsecase()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := uc.HandleWebhook(context.Background(), tt.registryID, tt.authorization, body)
+			if tt.wantErr {
+				assert.Equal(t, errorx.CodeUnauthorized, errorx.GetCode(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Empty(t, events) // Pull events are not supported
+		})
+	}
+}
+
+// TestHarborWebhookEvents tests how payloads are split into events
+func TestHarborWebhookEvents(t *testing.T) {
+	uc := newHarborWebhookUsecase()
+
+	t.Run("One event per artifact", func(t *testing.T) {
+		events, err := uc.HandleWebhook(context.Background(), "reg-1", "s3cr3t", []byte(`{
+			"type": "SCANNING_COMPLETED",
+			"occur_at": 1700000000,
+			"operator": "auto",
+			"event_data": {
+				"resources": [
+					{"digest": "sha256:aaa", "tag": "v1", "resource_url": "harbor.example.com/shop/api:v1",
+					 "scan_overview": {"application/vnd.security.vulnerability.report; version=1.1": {
+						"scan_status": "Success", "severity": "High", "summary": {"total": 3, "summary": {"High": 1, "Low": 2}}}}},
+					{"digest": "sha256:bbb", "tag": "v2", "resource_url": "harbor.example.com/shop/api:v2"}
+				],
+				"repository": {"name": "api", "namespace": "shop", "repo_full_name": "shop/api"}
+			}
+		}`))
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+
+		assert.Equal(t, "reg-1", events[0].RegistryID)
+		assert.Equal(t, "SCANNING_COMPLETED", events[0].Type)
+		assert.Equal(t, "auto", events[0].Operator)
+		assert.Equal(t, "shop/api", events[0].Repository)
+		assert.Equal(t, time.Unix(1700000000, 0), events[0].OccurredAt)
+		assert.Equal(t, "sha256:aaa", events[0].Digest)
+		assert.Equal(t, "v1", events[0].Tag)
+		require.NotNil(t, events[0].ScanOverview)
+		assert.Equal(t, "Success", events[0].ScanOverview.ScanStatus)
+		assert.Equal(t, 1, events[0].ScanOverview.Summary.High)
+		assert.Equal(t, 2, events[0].ScanOverview.Summary.Low)
+
+		assert.Equal(t, "sha256:bbb", events[1].Digest)
+		assert.Nil(t, events[1].ScanOverview)
+		assert.NotEqual(t, events[0].ID, events[1].ID)
+	})
+
+	t.Run("Repository-level event", func(t *testing.T) {
+		events, err := uc.HandleWebhook(context.Background(), "reg-1", "s3cr3t", []byte(`{
+			"type": "QUOTA_EXCEED",
+			"event_data": {
+				"repository": {"name": "api", "namespace": "shop"},
+				"custom_attributes": {"Details": "will exceed the configured upper limit of 10.0 GiB"}
+			}
+		}`))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "shop/api", events[0].Repository)
+		assert.Equal(t, "will exceed the configured upper limit of 10.0 GiB", events[0].Details)
+		assert.Empty(t, events[0].Digest)
+	})
+
+	t.Run("Invalid payload", func(t *testing.T) {
+		_, err := uc.HandleWebhook(context.Background(), "reg-1", "s3cr3t", []byte(`{"type":`))
+		assert.Equal(t, errorx.CodeBadRequest, errorx.GetCode(err))
+	})
+}
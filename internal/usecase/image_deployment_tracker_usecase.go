@@ -0,0 +1,507 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/docker"
+	"github.com/unitechio/einfra-be/pkg/k8s"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// Annotations our API stamps on workloads whose images it changes, read back by the tracker
+	changedByAnnotation = "einfra.io/changed-by"
+	changedAtAnnotation = "einfra.io/changed-at"
+	// changedByLabel attributes Docker containers created through our API
+	changedByLabel = "einfra.io/changed-by"
+
+	// imageChangeAttributionWindow is how long a changed-by annotation attributes image changes;
+	// later changes were made by someone else, as ours stamp a new time
+	imageChangeAttributionWindow = 5 * time.Minute
+	// trackerRefresh is how often the tracker picks up added and removed clusters and Docker hosts
+	trackerRefresh = 5 * time.Minute
+	// trackerRegistryRefresh is how long the Harbor registries used to resolve digests are cached
+	trackerRegistryRefresh = 5 * time.Minute
+	// trackerDockerRetry is the pause before the Docker event stream is reopened
+	trackerDockerRetry = 5 * time.Second
+)
+
+// trackedWorkloads are the workload kinds whose images are tracked, by resource
+var trackedWorkloads = map[schema.GroupVersionResource]string{
+	{Group: "apps", Version: "v1", Resource: "deployments"}:  "Deployment",
+	{Group: "apps", Version: "v1", Resource: "statefulsets"}: "StatefulSet",
+	{Group: "apps", Version: "v1", Resource: "daemonsets"}:   "DaemonSet",
+}
+
+// ImageDeploymentTracker records image deployments from workload and container events
+type ImageDeploymentTracker interface {
+	// Start watches the workloads of every active cluster and the containers of every active Docker
+	// host until ctx is done
+	Start(ctx context.Context)
+}
+
+type imageDeploymentTracker struct {
+	k8sRepo           domain.K8sClusterRepository
+	clients           *k8s.ClientManager
+	deploymentUsecase domain.ImageDeploymentUsecase
+	harborUsecase     domain.HarborUsecase
+	dockerClients     DockerClientRegistry
+
+	mu          sync.Mutex
+	clusters    map[string]chan struct{}      // Stop channels of the watched clusters
+	dockerHosts map[string]context.CancelFunc // Cancels the event watchers of the watched Docker hosts
+
+	registriesMu     sync.Mutex
+	registryByHost   map[string]string
+	registriesLoaded time.Time
+}
+
+// NewImageDeploymentTracker creates a new image deployment tracker
+func NewImageDeploymentTracker(
+	k8sRepo domain.K8sClusterRepository,
+	clients *k8s.ClientManager,
+	deploymentUsecase domain.ImageDeploymentUsecase,
+	harborUsecase domain.HarborUsecase,
+	dockerClients DockerClientRegistry,
+) ImageDeploymentTracker {
+	return &imageDeploymentTracker{
+		k8sRepo:           k8sRepo,
+		clients:           clients,
+		deploymentUsecase: deploymentUsecase,
+		harborUsecase:     harborUsecase,
+		dockerClients:     dockerClients,
+		clusters:          make(map[string]chan struct{}),
+		dockerHosts:       make(map[string]context.CancelFunc),
+	}
+}
+
+// Start watches the tracked workloads of every active cluster and the containers of every active
+// Docker host, picking up cluster and host changes periodically
+func (t *imageDeploymentTracker) Start(ctx context.Context) {
+	t.syncClusters(ctx)
+	t.syncDockerHosts(ctx)
+
+	ticker := time.NewTicker(trackerRefresh)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				t.syncClusters(ctx)
+				t.syncDockerHosts(ctx)
+			case <-ctx.Done():
+				ticker.Stop()
+				t.mu.Lock()
+				for id, stopCh := range t.clusters {
+					close(stopCh)
+					delete(t.clusters, id)
+				}
+				for id, cancel := range t.dockerHosts {
+					cancel()
+					delete(t.dockerHosts, id)
+				}
+				t.mu.Unlock()
+				return
+			}
+		}
+	}()
+}
+
+// Kubernetes
+
+// syncClusters starts informers for new clusters and stops those of removed or deactivated ones
+func (t *imageDeploymentTracker) syncClusters(ctx context.Context) {
+	active := true
+	filter := domain.K8sClusterFilter{IsActive: &active, Page: 1, PageSize: 100}
+	var clusters []*domain.K8sCluster
+	for {
+		page, total, err := t.k8sRepo.List(ctx, filter)
+		if err != nil {
+			log.Printf("Image deployment tracker failed to list clusters: %v", err)
+			return
+		}
+		clusters = append(clusters, page...)
+		if len(page) == 0 || int64(filter.Page*filter.PageSize) >= total {
+			break
+		}
+		filter.Page++
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	current := make(map[string]bool, len(clusters))
+	for _, cluster := range clusters {
+		current[cluster.ID] = true
+		if _, ok := t.clusters[cluster.ID]; ok {
+			continue
+		}
+
+		client, err := getPlatformK8sClient(ctx, t.k8sRepo, t.clients, cluster.ID)
+		if err != nil {
+			log.Printf("Image deployment tracker cannot watch cluster %s: %v", cluster.Name, err)
+			continue
+		}
+		stopCh := make(chan struct{})
+		for gvr, kind := range trackedWorkloads {
+			t.watchWorkloads(client, cluster.ID, gvr, kind, stopCh)
+		}
+		t.clusters[cluster.ID] = stopCh
+	}
+
+	for id, stopCh := range t.clusters {
+		if !current[id] {
+			close(stopCh)
+			delete(t.clusters, id)
+		}
+	}
+}
+
+func (t *imageDeploymentTracker) watchWorkloads(client *k8s.Client, clusterID string, gvr schema.GroupVersionResource, kind string, stopCh chan struct{}) {
+	informer := client.NewInformer(gvr, "")
+	_, _ = informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			reason := "created"
+			if isInInitialList {
+				// Catches up on changes made while the tracker was not running
+				reason = "observed"
+			}
+			t.observeWorkload(clusterID, kind, obj, reason)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldWorkload, ok1 := oldObj.(*unstructured.Unstructured)
+			newWorkload, ok2 := newObj.(*unstructured.Unstructured)
+			if !ok1 || !ok2 || equalContainerImages(workloadImages(oldWorkload), workloadImages(newWorkload)) {
+				return
+			}
+			t.observeWorkload(clusterID, kind, newObj, "image changed")
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			workload, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return
+			}
+			if err := t.deploymentUsecase.RecordWorkloadRemoved(context.Background(), clusterID, workload.GetNamespace(), workload.GetName(), "kubernetes"); err != nil {
+				log.Printf("Failed to record removal of %s %s/%s: %v", kind, workload.GetNamespace(), workload.GetName(), err)
+			}
+		},
+	})
+	go informer.Run(stopCh)
+}
+
+func (t *imageDeploymentTracker) observeWorkload(clusterID, kind string, obj interface{}, reason string) {
+	workload, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	changedBy := workloadChangedBy(workload, time.Now())
+	for container, image := range workloadImages(workload) {
+		observation := domain.ImageObservation{
+			Platform:     domain.ImageDeploymentPlatformKubernetes,
+			ClusterID:    clusterID,
+			Namespace:    workload.GetNamespace(),
+			WorkloadKind: kind,
+			WorkloadName: workload.GetName(),
+			Container:    container,
+			Image:        image,
+			Digest:       t.resolveDigest(ctx, image),
+			ChangedBy:    changedBy,
+			Reason:       reason,
+		}
+		if err := t.deploymentUsecase.RecordObservedImage(ctx, observation); err != nil {
+			log.Printf("Failed to record image of %s %s/%s: %v", kind, workload.GetNamespace(), workload.GetName(), err)
+		}
+	}
+}
+
+// workloadImages maps the containers of a workload's pod template to their images
+func workloadImages(workload *unstructured.Unstructured) map[string]string {
+	images := make(map[string]string)
+	containers, _, _ := unstructured.NestedSlice(workload.Object, "spec", "template", "spec", "containers")
+	for _, container := range containers {
+		c, ok := container.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := c["name"].(string)
+		image, _ := c["image"].(string)
+		if name != "" && image != "" {
+			images[name] = image
+		}
+	}
+	return images
+}
+
+func equalContainerImages(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, image := range a {
+		if b[name] != image {
+			return false
+		}
+	}
+	return true
+}
+
+// workloadChangedBy attributes the latest change of a workload: the user our API stamped if the stamp
+// is recent, otherwise the field manager that last wrote the workload, e.g. "kubectl-client-side-apply"
+func workloadChangedBy(workload *unstructured.Unstructured, now time.Time) string {
+	annotations := workload.GetAnnotations()
+	if user := annotations[changedByAnnotation]; user != "" {
+		if changedAt, err := time.Parse(time.RFC3339, annotations[changedAtAnnotation]); err == nil && now.Sub(changedAt) < imageChangeAttributionWindow {
+			return user
+		}
+	}
+
+	var manager string
+	var latest time.Time
+	for _, entry := range workload.GetManagedFields() {
+		if entry.Subresource != "" || entry.Time == nil {
+			continue
+		}
+		if entry.Time.Time.After(latest) {
+			latest = entry.Time.Time
+			manager = entry.Manager
+		}
+	}
+	if manager == "" {
+		return "kubernetes"
+	}
+	return "k8s:" + manager
+}
+
+// stampImageChange attributes a workload update to the requesting user when it changes images
+func stampImageChange(ctx context.Context, obj, live *unstructured.Unstructured) {
+	if !isTrackedWorkload(obj) {
+		return
+	}
+	if live != nil && equalContainerImages(workloadImages(obj), workloadImages(live)) {
+		return
+	}
+
+	user := requestUser(ctx)
+	if user == "" {
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[changedByAnnotation] = user
+	annotations[changedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	obj.SetAnnotations(annotations)
+}
+
+func isTrackedWorkload(obj *unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	for gvr, kind := range trackedWorkloads {
+		if gvk.Group == gvr.Group && gvk.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// requestUser is the platform user behind a request, preferring the username
+func requestUser(ctx context.Context) string {
+	if username, _ := ctx.Value("username").(string); username != "" {
+		return username
+	}
+	userID, _ := ctx.Value("user_id").(string)
+	return userID
+}
+
+// Docker
+
+// syncDockerHosts starts event watchers for new Docker hosts and cancels those of removed or
+// deactivated ones
+func (t *imageDeploymentTracker) syncDockerHosts(ctx context.Context) {
+	if t.dockerClients == nil {
+		return
+	}
+	hosts, err := t.dockerClients.ActiveHosts(ctx)
+	if err != nil {
+		log.Printf("Image deployment tracker failed to list Docker hosts: %v", err)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	current := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		current[host.ID] = true
+		if _, ok := t.dockerHosts[host.ID]; ok {
+			continue
+		}
+		watchCtx, cancel := context.WithCancel(ctx)
+		go t.watchDockerHost(watchCtx, host.ID)
+		t.dockerHosts[host.ID] = cancel
+	}
+
+	for id, cancel := range t.dockerHosts {
+		if !current[id] {
+			cancel()
+			delete(t.dockerHosts, id)
+		}
+	}
+}
+
+// watchDockerHost records containers as they are created on a Docker host, reconnecting and reopening
+// the event stream on errors, until ctx is done
+func (t *imageDeploymentTracker) watchDockerHost(ctx context.Context, hostID string) {
+	for {
+		client, err := t.dockerClients.Get(ctx, hostID)
+		if err != nil {
+			log.Printf("Image deployment tracker cannot connect to Docker host %s: %v", hostID, err)
+		} else {
+			msgs, errs := client.Events(ctx)
+		stream:
+			for {
+				select {
+				case msg := <-msgs:
+					t.observeContainer(ctx, hostID, client, msg)
+				case err := <-errs:
+					if err != nil && ctx.Err() == nil {
+						log.Printf("Image deployment tracker lost Docker events of host %s: %v", hostID, err)
+					}
+					break stream
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		select {
+		case <-time.After(trackerDockerRetry):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t *imageDeploymentTracker) observeContainer(ctx context.Context, hostID string, client *docker.Client, msg events.Message) {
+	if msg.Type != events.ContainerEventType {
+		return
+	}
+	attributes := msg.Actor.Attributes
+	name := attributes["name"]
+	project := attributes["com.docker.compose.project"]
+
+	switch msg.Action {
+	case events.ActionCreate:
+		image := attributes["image"]
+		if name == "" || image == "" {
+			return
+		}
+		changedBy := attributes[changedByLabel]
+		if changedBy == "" {
+			changedBy = "docker"
+		}
+		digest := t.resolveDigest(ctx, image)
+		if digest == "" {
+			digest = dockerRepoDigest(ctx, client, image)
+		}
+		observation := domain.ImageObservation{
+			Platform:     domain.ImageDeploymentPlatformDocker,
+			ClusterID:    hostID,
+			Namespace:    project,
+			WorkloadKind: "Container",
+			WorkloadName: name,
+			Container:    name,
+			Image:        image,
+			Digest:       digest,
+			ChangedBy:    changedBy,
+			Reason:       "created",
+		}
+		if err := t.deploymentUsecase.RecordObservedImage(ctx, observation); err != nil {
+			log.Printf("Failed to record image of container %s: %v", name, err)
+		}
+	case events.ActionDestroy:
+		if name == "" {
+			return
+		}
+		if err := t.deploymentUsecase.RecordWorkloadRemoved(ctx, hostID, project, name, "docker"); err != nil {
+			log.Printf("Failed to record removal of container %s: %v", name, err)
+		}
+	}
+}
+
+// dockerRepoDigest reads the digest of a pulled image from the Docker host
+func dockerRepoDigest(ctx context.Context, client *docker.Client, image string) string {
+	inspect, err := client.ImageInspect(ctx, image)
+	if err != nil {
+		return ""
+	}
+	repoDigests, _ := inspect["repo_digests"].([]string)
+	repository, _ := splitImageReference(image)
+	sort.Strings(repoDigests)
+	for _, repoDigest := range repoDigests {
+		if name, digest := splitImageReference(repoDigest); name == repository {
+			return digest
+		}
+	}
+	return ""
+}
+
+// Digests
+
+// resolveDigest resolves an image to its digest through the Harbor registry hosting it
+func (t *imageDeploymentTracker) resolveDigest(ctx context.Context, image string) string {
+	host, repository, reference := parseImageReference(image)
+	if strings.HasPrefix(reference, "sha256:") {
+		return reference
+	}
+
+	registryID := t.registryFor(ctx, host)
+	if registryID == "" {
+		return ""
+	}
+	artifact, err := t.harborUsecase.GetArtifact(ctx, registryID, repository, reference)
+	if err != nil {
+		return ""
+	}
+	return artifact.Digest
+}
+
+// registryFor returns the Harbor registry serving a host, from a periodically refreshed cache
+func (t *imageDeploymentTracker) registryFor(ctx context.Context, host string) string {
+	if t.harborUsecase == nil {
+		return ""
+	}
+
+	t.registriesMu.Lock()
+	defer t.registriesMu.Unlock()
+
+	if time.Since(t.registriesLoaded) > trackerRegistryRefresh {
+		active := true
+		registries, _, err := t.harborUsecase.ListRegistries(ctx, domain.HarborRegistryFilter{IsActive: &active})
+		if err != nil {
+			log.Printf("Image deployment tracker failed to load Harbor registries: %v", err)
+			return t.registryByHost[host]
+		}
+		t.registryByHost = make(map[string]string, len(registries))
+		for _, registry := range registries {
+			if parsed, err := url.Parse(registry.URL); err == nil && parsed.Host != "" {
+				t.registryByHost[strings.ToLower(parsed.Host)] = registry.ID
+			}
+		}
+		t.registriesLoaded = time.Now()
+	}
+	return t.registryByHost[host]
+}
@@ -3,44 +3,54 @@ package usecase
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/unitechio/einfra-be/internal/domain"
 )
 
 type imageDeploymentUsecase struct {
-	deploymentRepo domain.ImageDeploymentRepository
-	k8sUsecase     domain.KubernetesUsecase
+	deploymentRepo    domain.ImageDeploymentRepository
+	k8sUsecase        domain.KubernetesUsecase
+	vulnerabilityGate domain.VulnerabilityGateUsecase
 }
 
 // NewImageDeploymentUsecase creates a new image deployment usecase
 func NewImageDeploymentUsecase(
 	deploymentRepo domain.ImageDeploymentRepository,
 	k8sUsecase domain.KubernetesUsecase,
+	vulnerabilityGate domain.VulnerabilityGateUsecase,
 ) domain.ImageDeploymentUsecase {
 	return &imageDeploymentUsecase{
-		deploymentRepo: deploymentRepo,
-		k8sUsecase:     k8sUsecase,
+		deploymentRepo:    deploymentRepo,
+		k8sUsecase:        k8sUsecase,
+		vulnerabilityGate: vulnerabilityGate,
 	}
 }
 
-// TrackDeployment records a new image deployment
-func (u *imageDeploymentUsecase) TrackDeployment(ctx context.Context, clusterID, namespace, deploymentName, containerName, imageRepo, imageTag, user string) error {
+// TrackDeployment records a new image deployment once the image passes the vulnerability policy
+// of the cluster's environment. The gate report is returned when a policy applies.
+func (u *imageDeploymentUsecase) TrackDeployment(ctx context.Context, clusterID, namespace, deploymentName, containerName, imageRepo, imageTag, user string) (*domain.VulnerabilityGateReport, error) {
 	if clusterID == "" || namespace == "" || deploymentName == "" || imageTag == "" {
-		return errors.New("missing required deployment information")
+		return nil, errors.New("missing required deployment information")
+	}
+
+	report, err := u.vulnerabilityGate.CheckCluster(ctx, clusterID, []string{formatImageReference(imageRepo, imageTag)})
+	if err != nil {
+		return report, err
 	}
 
 	// Check if there is an active deployment for this container
 	activeDeployment, err := u.deploymentRepo.GetActiveDeployment(ctx, clusterID, namespace, deploymentName, containerName)
 	if err != nil {
-		return err
+		return report, err
 	}
 
 	// If active deployment exists and tag is different, mark it as replaced
 	if activeDeployment != nil {
 		if activeDeployment.ImageTag != imageTag {
 			if err := u.deploymentRepo.UpdateStatus(ctx, activeDeployment.ID, "replaced"); err != nil {
-				return err
+				return report, err
 			}
 		} else {
 			// Same tag is being re-deployed, just update timestamp or ignore
@@ -61,7 +71,7 @@ func (u *imageDeploymentUsecase) TrackDeployment(ctx context.Context, clusterID,
 		DeployedAt:      time.Now(),
 	}
 
-	return u.deploymentRepo.Create(ctx, newDeployment)
+	return report, u.deploymentRepo.Create(ctx, newDeployment)
 }
 
 // GetDeploymentHistory retrieves the history of deployments for a specific workload
@@ -142,3 +152,186 @@ func (u *imageDeploymentUsecase) SyncDeploymentsFromK8s(ctx context.Context, clu
 
 	return nil
 }
+
+// RecordRolloutAction records a rollout action for every container of a workload. Containers whose
+// image changed get a new active deployment record; the action is added to the change history either way.
+func (u *imageDeploymentUsecase) RecordRolloutAction(ctx context.Context, clusterID, namespace, deploymentName string, containerImages map[string]string, action, user, reason string) error {
+	if clusterID == "" || namespace == "" || deploymentName == "" {
+		return errors.New("cluster ID, namespace, and deployment name are required")
+	}
+	if action == "" {
+		return errors.New("action is required")
+	}
+
+	// Status given to the replaced record when the image changes
+	replacedStatus := "replaced"
+	if action == domain.K8sRolloutActionRollback {
+		replacedStatus = "rolled_back"
+	}
+
+	for containerName, image := range containerImages {
+		imageRepo, imageTag := splitImageReference(image)
+
+		active, err := u.deploymentRepo.GetActiveDeployment(ctx, clusterID, namespace, deploymentName, containerName)
+		if err != nil {
+			return err
+		}
+
+		previousTag := ""
+		deployment := active
+		if active != nil {
+			previousTag = active.ImageTag
+		}
+
+		if active == nil || active.ImageTag != imageTag || active.ImageRepository != imageRepo {
+			if active != nil {
+				if err := u.deploymentRepo.UpdateStatus(ctx, active.ID, replacedStatus); err != nil {
+					return err
+				}
+			}
+			deployment = &domain.ImageDeployment{
+				ClusterID:       clusterID,
+				Namespace:       namespace,
+				DeploymentName:  deploymentName,
+				ContainerName:   containerName,
+				ImageRepository: imageRepo,
+				ImageTag:        imageTag,
+				DeployedBy:      user,
+				Status:          "active",
+				DeployedAt:      time.Now(),
+			}
+			if err := u.deploymentRepo.Create(ctx, deployment); err != nil {
+				return err
+			}
+		}
+
+		historyReason := action
+		if reason != "" {
+			historyReason = action + ": " + reason
+		}
+		history := &domain.ImageDeploymentHistory{
+			DeploymentID: deployment.ID,
+			PreviousTag:  previousTag,
+			NewTag:       imageTag,
+			ChangedAt:    time.Now(),
+			ChangedBy:    user,
+			Reason:       historyReason,
+		}
+		if err := u.deploymentRepo.CreateHistory(ctx, history); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetChangeHistory retrieves the recorded changes of a workload, newest first
+func (u *imageDeploymentUsecase) GetChangeHistory(ctx context.Context, clusterID, namespace, deploymentName string) ([]*domain.ImageDeploymentHistory, error) {
+	deployments, err := u.GetDeploymentHistory(ctx, clusterID, namespace, deploymentName)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(deployments))
+	for _, d := range deployments {
+		ids = append(ids, d.ID)
+	}
+	return u.deploymentRepo.ListHistory(ctx, ids)
+}
+
+// RecordObservedImage records an image the tracker saw running. Images already recorded as active, e.g.
+// by a rollout action of our API, only get their digest filled in.
+func (u *imageDeploymentUsecase) RecordObservedImage(ctx context.Context, observation domain.ImageObservation) error {
+	if observation.ClusterID == "" || observation.WorkloadName == "" || observation.Image == "" {
+		return errors.New("cluster ID, workload name, and image are required")
+	}
+	imageRepo, imageTag := splitImageReference(observation.Image)
+
+	active, err := u.deploymentRepo.GetActiveDeployment(ctx, observation.ClusterID, observation.Namespace, observation.WorkloadName, observation.Container)
+	if err != nil {
+		return err
+	}
+	if active != nil && active.ImageRepository == imageRepo && active.ImageTag == imageTag {
+		if active.ImageDigest == "" && observation.Digest != "" {
+			return u.deploymentRepo.UpdateDigest(ctx, active.ID, observation.Digest)
+		}
+		return nil
+	}
+
+	previousTag := ""
+	if active != nil {
+		previousTag = active.ImageTag
+		if err := u.deploymentRepo.UpdateStatus(ctx, active.ID, "replaced"); err != nil {
+			return err
+		}
+	}
+
+	deployment := &domain.ImageDeployment{
+		Platform:        observation.Platform,
+		ClusterID:       observation.ClusterID,
+		Namespace:       observation.Namespace,
+		WorkloadKind:    observation.WorkloadKind,
+		DeploymentName:  observation.WorkloadName,
+		ContainerName:   observation.Container,
+		ImageRepository: imageRepo,
+		ImageTag:        imageTag,
+		ImageDigest:     observation.Digest,
+		DeployedBy:      observation.ChangedBy,
+		Status:          "active",
+		DeployedAt:      time.Now(),
+	}
+	if err := u.deploymentRepo.Create(ctx, deployment); err != nil {
+		return err
+	}
+
+	return u.deploymentRepo.CreateHistory(ctx, &domain.ImageDeploymentHistory{
+		DeploymentID: deployment.ID,
+		PreviousTag:  previousTag,
+		NewTag:       imageTag,
+		ChangedAt:    time.Now(),
+		ChangedBy:    observation.ChangedBy,
+		Reason:       observation.Reason,
+	})
+}
+
+// RecordWorkloadRemoved marks the active deployments of a deleted workload as removed, so its images
+// are no longer protected as running anywhere
+func (u *imageDeploymentUsecase) RecordWorkloadRemoved(ctx context.Context, clusterID, namespace, workloadName, user string) error {
+	deployments, err := u.deploymentRepo.List(ctx, clusterID, namespace)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deployments {
+		if d.DeploymentName != workloadName || d.Status != "active" {
+			continue
+		}
+		if err := u.deploymentRepo.UpdateStatus(ctx, d.ID, "removed"); err != nil {
+			return err
+		}
+		history := &domain.ImageDeploymentHistory{
+			DeploymentID: d.ID,
+			PreviousTag:  d.ImageTag,
+			NewTag:       d.ImageTag,
+			ChangedAt:    time.Now(),
+			ChangedBy:    user,
+			Reason:       "removed",
+		}
+		if err := u.deploymentRepo.CreateHistory(ctx, history); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitImageReference splits an image reference into repository and tag (or digest).
+// A missing tag defaults to "latest"; a registry port is not mistaken for a tag.
+func splitImageReference(image string) (string, string) {
+	if at := strings.LastIndex(image, "@"); at >= 0 {
+		return image[:at], image[at+1:]
+	}
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		return image[:colon], image[colon+1:]
+	}
+	return image, "latest"
+}
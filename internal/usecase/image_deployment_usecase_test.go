@@ -0,0 +1,67 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+)
+
+// TestRecordObservedImage tests that observed image changes replace the active deployment while
+// re-observing the active image only fills in its digest
+func TestRecordObservedImage(t *testing.T) {
+	observation := domain.ImageObservation{
+		Platform:     domain.ImageDeploymentPlatformKubernetes,
+		ClusterID:    "cluster-1",
+		Namespace:    "shop",
+		WorkloadKind: "Deployment",
+		WorkloadName: "api",
+		Container:    "api",
+		Image:        "harbor.example.com/shop/api:v2",
+		Digest:       "sha256:v2",
+		ChangedBy:    "alice",
+		Reason:       "image changed",
+	}
+
+	t.Run("Image changed", func(t *testing.T) {
+		repo := new(MockImageDeploymentRepository)
+		repo.On("GetActiveDeployment", mock.Anything, "cluster-1", "shop", "api", "api").Return(&domain.ImageDeployment{
+			ID:              "dep-1",
+			ImageRepository: "harbor.example.com/shop/api",
+			ImageTag:        "v1",
+			Status:          "active",
+		}, nil)
+		repo.On("UpdateStatus", mock.Anything, "dep-1", "replaced").Return(nil)
+		repo.On("Create", mock.Anything, mock.MatchedBy(func(d *domain.ImageDeployment) bool {
+			d.ID = "dep-2"
+			return d.ImageTag == "v2" && d.ImageDigest == "sha256:v2" && d.DeployedBy == "alice" && d.WorkloadKind == "Deployment"
+		})).Return(nil)
+		repo.On("CreateHistory", mock.Anything, mock.MatchedBy(func(h *domain.ImageDeploymentHistory) bool {
+			return h.DeploymentID == "dep-2" && h.PreviousTag == "v1" && h.NewTag == "v2" && h.ChangedBy == "alice"
+		})).Return(nil)
+
+		uc := usecase.NewImageDeploymentUsecase(repo, nil, nil)
+		require.NoError(t, uc.RecordObservedImage(context.Background(), observation))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Image already active", func(t *testing.T) {
+		repo := new(MockImageDeploymentRepository)
+		repo.On("GetActiveDeployment", mock.Anything, "cluster-1", "shop", "api", "api").Return(&domain.ImageDeployment{
+			ID:              "dep-2",
+			ImageRepository: "harbor.example.com/shop/api",
+			ImageTag:        "v2",
+			Status:          "active",
+		}, nil)
+		repo.On("UpdateDigest", mock.Anything, "dep-2", "sha256:v2").Return(nil)
+
+		uc := usecase.NewImageDeploymentUsecase(repo, nil, nil)
+		require.NoError(t, uc.RecordObservedImage(context.Background(), observation))
+		repo.AssertExpectations(t)
+		assert.Len(t, repo.Calls, 2)
+	})
+}
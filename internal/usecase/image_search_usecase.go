@@ -0,0 +1,602 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/repository"
+	"github.com/unitechio/einfra-be/pkg/docker"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+	"github.com/unitechio/einfra-be/pkg/excelutil"
+	"github.com/unitechio/einfra-be/pkg/k8s"
+	"github.com/xuri/excelize/v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// imageSearchListLimit bounds the clusters and Docker hosts a search looks at
+	imageSearchListLimit = 500
+	// imageSearchSheet is the sheet of exported image locations
+	imageSearchSheet = "Image Locations"
+)
+
+// ImageSearchUsecase locates deployed images across Kubernetes clusters and Docker hosts
+type ImageSearchUsecase interface {
+	// Search returns the workloads running a matching image, combining live pods and containers with deployment records.
+	// Clusters, hosts and registries that cannot be reached are reported as warnings
+	Search(ctx context.Context, query domain.ImageSearchQuery) (*domain.ImageSearchResult, error)
+	// ExportToExcel runs a search and writes its locations to a spreadsheet
+	ExportToExcel(ctx context.Context, query domain.ImageSearchQuery) (*excelize.File, string, error)
+}
+
+type imageSearchUsecase struct {
+	deploymentRepo  domain.ImageDeploymentRepository
+	k8sRepo         domain.K8sClusterRepository
+	clients         *k8s.ClientManager
+	dockerRepo      domain.DockerHostRepository
+	dockerClients   DockerClientRegistry
+	environmentRepo repository.EnvironmentRepository
+	harborUsecase   domain.HarborUsecase
+}
+
+// NewImageSearchUsecase creates a new image search usecase
+func NewImageSearchUsecase(
+	deploymentRepo domain.ImageDeploymentRepository,
+	k8sRepo domain.K8sClusterRepository,
+	clients *k8s.ClientManager,
+	dockerRepo domain.DockerHostRepository,
+	dockerClients DockerClientRegistry,
+	environmentRepo repository.EnvironmentRepository,
+	harborUsecase domain.HarborUsecase,
+) ImageSearchUsecase {
+	return &imageSearchUsecase{
+		deploymentRepo:  deploymentRepo,
+		k8sRepo:         k8sRepo,
+		clients:         clients,
+		dockerRepo:      dockerRepo,
+		dockerClients:   dockerClients,
+		environmentRepo: environmentRepo,
+		harborUsecase:   harborUsecase,
+	}
+}
+
+// imageTarget is an image searched for; empty fields match any image
+type imageTarget struct {
+	repository string
+	tags       []string
+	digest     string
+}
+
+// matches reports whether an image is the target. Images of unknown digest match a digest target by the tags it was pushed as
+func (t imageTarget) matches(repository, tag, digest string) bool {
+	if t.repository != "" && !imageRepositoryMatches(repository, t.repository) {
+		return false
+	}
+	if t.digest != "" {
+		if digest != "" {
+			return digest == t.digest
+		}
+		return slices.Contains(t.tags, tag)
+	}
+	return len(t.tags) == 0 || slices.Contains(t.tags, tag)
+}
+
+func matchesAnyTarget(targets []imageTarget, repository, tag, digest string) bool {
+	for _, target := range targets {
+		if target.matches(repository, tag, digest) {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *imageSearchUsecase) Search(ctx context.Context, query domain.ImageSearchQuery) (*domain.ImageSearchResult, error) {
+	query.Repository = strings.TrimSpace(query.Repository)
+	query.Tag = strings.TrimSpace(query.Tag)
+	query.Digest = strings.ToLower(strings.TrimSpace(query.Digest))
+	query.CVEID = strings.ToUpper(strings.TrimSpace(query.CVEID))
+	if query.Repository == "" && query.Digest == "" && query.CVEID == "" {
+		return nil, errorx.New(errorx.CodeBadRequest, "repository, digest or CVE ID is required")
+	}
+	if query.Tag != "" && query.Repository == "" {
+		return nil, errorx.New(errorx.CodeBadRequest, "tag requires a repository")
+	}
+
+	result := &domain.ImageSearchResult{
+		Query:      query,
+		Locations:  []*domain.ImageLocation{},
+		SearchedAt: time.Now(),
+	}
+
+	target := imageTarget{repository: query.Repository, digest: query.Digest}
+	if query.Tag != "" {
+		target.tags = []string{query.Tag}
+	}
+	targets := []imageTarget{target}
+	if query.CVEID != "" {
+		var err error
+		if targets, err = u.cveTargets(ctx, query, result); err != nil {
+			return nil, err
+		}
+		if len(targets) == 0 {
+			return result, nil
+		}
+	}
+
+	records, err := u.findRecords(ctx, targets, query.IncludeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search deployment records: %w", err)
+	}
+
+	clusters, _, err := u.k8sRepo.List(ctx, domain.K8sClusterFilter{Page: 1, PageSize: imageSearchListLimit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %w", err)
+	}
+	clusterByID := make(map[string]*domain.K8sCluster, len(clusters))
+	for _, cluster := range clusters {
+		clusterByID[cluster.ID] = cluster
+	}
+	hosts, _, err := u.dockerRepo.List(ctx, domain.DockerHostFilter{Page: 1, PageSize: imageSearchListLimit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Docker hosts: %w", err)
+	}
+	hostByID := make(map[string]*domain.DockerHost, len(hosts))
+	for _, host := range hosts {
+		hostByID[host.ID] = host
+	}
+
+	live := newImageLocations()
+	var wg sync.WaitGroup
+	for _, cluster := range clusters {
+		if !cluster.IsActive {
+			continue
+		}
+		wg.Add(1)
+		go func(cluster *domain.K8sCluster) {
+			defer wg.Done()
+			u.searchCluster(ctx, cluster, targets, live)
+		}(cluster)
+	}
+	for _, host := range hosts {
+		if !host.IsActive || u.dockerClients == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(host *domain.DockerHost) {
+			defer wg.Done()
+			client, err := u.dockerClients.Get(ctx, host.ID)
+			if err != nil {
+				live.warn(fmt.Sprintf("Docker host %s: %v", host.Name, err))
+				return
+			}
+			u.searchDockerHost(ctx, host, client, targets, live)
+		}(host)
+	}
+	wg.Wait()
+
+	result.Locations = live.merge(records, clusterByID, hostByID)
+	result.Warnings = append(result.Warnings, live.warnings...)
+	u.setEnvironments(ctx, result.Locations, clusterByID)
+
+	sort.Slice(result.Locations, func(i, j int) bool {
+		a, b := result.Locations[i], result.Locations[j]
+		if a.EnvironmentName != b.EnvironmentName {
+			return a.EnvironmentName < b.EnvironmentName
+		}
+		if a.ClusterName != b.ClusterName {
+			return a.ClusterName < b.ClusterName
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.WorkloadName != b.WorkloadName {
+			return a.WorkloadName < b.WorkloadName
+		}
+		if a.Container != b.Container {
+			return a.Container < b.Container
+		}
+		return a.DeployedAt.After(b.DeployedAt)
+	})
+	return result, nil
+}
+
+// cveTargets resolves a CVE to the affected artifacts of every active Harbor registry, narrowed down to the searched image
+func (u *imageSearchUsecase) cveTargets(ctx context.Context, query domain.ImageSearchQuery, result *domain.ImageSearchResult) ([]imageTarget, error) {
+	if u.harborUsecase == nil {
+		return nil, errorx.New(errorx.CodeBadRequest, "CVE search requires a Harbor registry")
+	}
+	active := true
+	registries, _, err := u.harborUsecase.ListRegistries(ctx, domain.HarborRegistryFilter{IsActive: &active})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list registries: %w", err)
+	}
+
+	var targets []imageTarget
+	var digests []string
+	seen := make(map[string]bool)
+	for _, registry := range registries {
+		artifacts, err := u.harborUsecase.ListArtifactsByCVE(ctx, registry.ID, query.CVEID)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("registry %s: %v", registry.Name, err))
+			continue
+		}
+		host := ""
+		if parsed, err := url.Parse(registry.URL); err == nil {
+			host = strings.ToLower(parsed.Host)
+		}
+
+		for _, artifact := range artifacts {
+			repository := artifact.Repository
+			if host != "" {
+				repository = host + "/" + repository
+			}
+			if query.Repository != "" && !imageRepositoryMatches(repository, query.Repository) {
+				continue
+			}
+			if query.Tag != "" && !slices.Contains(artifact.Tags, query.Tag) {
+				continue
+			}
+			if query.Digest != "" && artifact.Digest != query.Digest {
+				continue
+			}
+			// The security hub lists an artifact once per vulnerable package
+			if key := repository + "@" + artifact.Digest; !seen[key] {
+				seen[key] = true
+				targets = append(targets, imageTarget{repository: repository, tags: artifact.Tags, digest: artifact.Digest})
+				digests = append(digests, artifact.Digest)
+			}
+		}
+	}
+	result.Digests = sortedUnique(digests)
+	return targets, nil
+}
+
+// findRecords returns the deployment records of the targets, active ones only unless includeInactive
+func (u *imageSearchUsecase) findRecords(ctx context.Context, targets []imageTarget, includeInactive bool) ([]*domain.ImageDeployment, error) {
+	var repositories, digests []string
+	for _, target := range targets {
+		if target.repository != "" {
+			_, path := canonicalImageRepository(target.repository)
+			repositories = append(repositories, strings.TrimPrefix(path, "library/"))
+		}
+		if target.digest != "" {
+			digests = append(digests, target.digest)
+		}
+	}
+
+	var candidates []*domain.ImageDeployment
+	for _, repository := range sortedUnique(repositories) {
+		deployments, err := u.deploymentRepo.ListByImageRepository(ctx, repository)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, deployments...)
+	}
+	if len(digests) > 0 {
+		deployments, err := u.deploymentRepo.ListByImageDigests(ctx, sortedUnique(digests))
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, deployments...)
+	}
+
+	seen := make(map[string]bool, len(candidates))
+	records := make([]*domain.ImageDeployment, 0, len(candidates))
+	for _, deployment := range candidates {
+		if seen[deployment.ID] || (deployment.Status != "active" && !includeInactive) {
+			continue
+		}
+		seen[deployment.ID] = true
+		if matchesAnyTarget(targets, deployment.ImageRepository, deployment.ImageTag, deployment.ImageDigest) {
+			records = append(records, deployment)
+		}
+	}
+	return records, nil
+}
+
+// searchCluster adds the pods of a cluster running a target image
+func (u *imageSearchUsecase) searchCluster(ctx context.Context, cluster *domain.K8sCluster, targets []imageTarget, live *imageLocations) {
+	client, err := getPlatformK8sClient(ctx, u.k8sRepo, u.clients, cluster.ID)
+	if err != nil {
+		live.warn(fmt.Sprintf("cluster %s: %v", cluster.Name, err))
+		return
+	}
+	pods, err := listActivePods(ctx, client)
+	if err != nil {
+		live.warn(fmt.Sprintf("cluster %s: %v", cluster.Name, err))
+		return
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		digests := make(map[string]string, len(pod.Status.ContainerStatuses))
+		for _, status := range pod.Status.ContainerStatuses {
+			// Image IDs are "repo@sha256:..." once pulled; a bare ID is the local image config, not a digest
+			if at := strings.LastIndex(status.ImageID, "@"); at >= 0 {
+				digests[status.Name] = status.ImageID[at+1:]
+			}
+		}
+		kind, name := podWorkload(pod)
+
+		for _, container := range pod.Spec.Containers {
+			repository, tag := splitImageReference(container.Image)
+			digest := digests[container.Name]
+			if digest == "" && strings.HasPrefix(tag, "sha256:") {
+				digest = tag
+			}
+			if !matchesAnyTarget(targets, repository, tag, digest) {
+				continue
+			}
+			live.add(&domain.ImageLocation{
+				Platform:     domain.ImageDeploymentPlatformKubernetes,
+				ClusterID:    cluster.ID,
+				ClusterName:  cluster.Name,
+				Namespace:    pod.Namespace,
+				WorkloadKind: kind,
+				WorkloadName: name,
+				Container:    container.Name,
+				Image:        container.Image,
+				ImageDigest:  digest,
+				DeployedAt:   pod.CreationTimestamp.Time,
+			})
+		}
+	}
+}
+
+// podWorkload returns the kind and name of the workload controlling a pod, resolving ReplicaSets to their Deployment
+func podWorkload(pod *corev1.Pod) (string, string) {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller == nil || !*owner.Controller {
+			continue
+		}
+		if hash := pod.Labels["pod-template-hash"]; owner.Kind == "ReplicaSet" && hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+			return "Deployment", strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+		return owner.Kind, owner.Name
+	}
+	return "Pod", pod.Name
+}
+
+// searchDockerHost adds the running containers of a Docker host with a target image
+func (u *imageSearchUsecase) searchDockerHost(ctx context.Context, host *domain.DockerHost, client *docker.Client, targets []imageTarget, live *imageLocations) {
+	containers, err := client.ContainerList(ctx, false)
+	if err != nil {
+		live.warn(fmt.Sprintf("Docker host %s: %v", host.Name, err))
+		return
+	}
+
+	digests := make(map[string]string)
+	for _, container := range containers {
+		repository, tag := splitImageReference(container.Image)
+		digest, ok := digests[container.Image]
+		if !ok {
+			digest = dockerRepoDigest(ctx, client, container.Image)
+			digests[container.Image] = digest
+		}
+		if !matchesAnyTarget(targets, repository, tag, digest) {
+			continue
+		}
+
+		name := container.ID
+		if len(container.Names) > 0 {
+			name = strings.TrimPrefix(container.Names[0], "/")
+		}
+		live.add(&domain.ImageLocation{
+			Platform:     domain.ImageDeploymentPlatformDocker,
+			ClusterID:    host.ID,
+			ClusterName:  host.Name,
+			Namespace:    container.Labels["com.docker.compose.project"],
+			WorkloadKind: "Container",
+			WorkloadName: name,
+			Container:    name,
+			Image:        container.Image,
+			ImageDigest:  digest,
+			Owner:        container.Labels[changedByLabel],
+			DeployedAt:   time.Unix(container.Created, 0),
+		})
+	}
+}
+
+// setEnvironments fills in the environment of the locations in Kubernetes clusters
+func (u *imageSearchUsecase) setEnvironments(ctx context.Context, locations []*domain.ImageLocation, clusterByID map[string]*domain.K8sCluster) {
+	names := make(map[string]string)
+	for _, location := range locations {
+		cluster, ok := clusterByID[location.ClusterID]
+		if location.Platform != domain.ImageDeploymentPlatformKubernetes || !ok || cluster.EnvironmentID == nil {
+			continue
+		}
+		environmentID := *cluster.EnvironmentID
+		name, ok := names[environmentID]
+		if !ok && u.environmentRepo != nil {
+			if environment, err := u.environmentRepo.GetByID(ctx, environmentID); err == nil {
+				name = environment.Name
+			}
+			names[environmentID] = name
+		}
+		location.EnvironmentID = environmentID
+		location.EnvironmentName = name
+	}
+}
+
+func (u *imageSearchUsecase) ExportToExcel(ctx context.Context, query domain.ImageSearchQuery) (*excelize.File, string, error) {
+	result, err := u.Search(ctx, query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	f, err := excelutil.CreateExcelFile(imageSearchSheet)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create spreadsheet: %w", err)
+	}
+	headers := []string{"Platform", "Cluster / Host", "Environment", "Namespace", "Kind", "Workload", "Container",
+		"Image", "Digest", "Replicas", "Owner", "Deployed At", "Status", "Source"}
+	if err := excelutil.SetHeaderRow(f, imageSearchSheet, headers); err != nil {
+		return nil, "", err
+	}
+	for i, location := range result.Locations {
+		deployedAt := ""
+		if !location.DeployedAt.IsZero() {
+			deployedAt = location.DeployedAt.Format("2006-01-02 15:04:05")
+		}
+		row := []interface{}{
+			location.Platform, location.ClusterName, location.EnvironmentName, location.Namespace, location.WorkloadKind,
+			location.WorkloadName, location.Container, location.Image, location.ImageDigest, location.Replicas,
+			location.Owner, deployedAt, location.Status, location.Source,
+		}
+		if err := excelutil.WriteRow(f, imageSearchSheet, i+2, row); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Unreachable clusters and registries make the export incomplete; say so next to it
+	if len(result.Warnings) > 0 {
+		if _, err := excelutil.AddSheet(f, "Warnings"); err != nil {
+			return nil, "", err
+		}
+		for i, warning := range result.Warnings {
+			if err := excelutil.WriteRow(f, "Warnings", i+1, []interface{}{warning}); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	fileName := fmt.Sprintf("image_locations_%s.xlsx", result.SearchedAt.Format("20060102_150405"))
+	return f, fileName, nil
+}
+
+// imageLocations collects the live locations of a search, one per workload container and image
+type imageLocations struct {
+	mu        sync.Mutex
+	locations map[string]*domain.ImageLocation
+	warnings  []string
+}
+
+func newImageLocations() *imageLocations {
+	return &imageLocations{locations: make(map[string]*domain.ImageLocation)}
+}
+
+// add records a pod or container running an image, counting the replicas of its workload
+func (l *imageLocations) add(location *domain.ImageLocation) {
+	key := strings.Join([]string{location.Platform, location.ClusterID, location.Namespace, location.WorkloadKind,
+		location.WorkloadName, location.Container, location.Image}, "|")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	existing, ok := l.locations[key]
+	if !ok {
+		location.Replicas = 1
+		location.Status = "running"
+		location.Source = domain.ImageLocationSourceLive
+		l.locations[key] = location
+		return
+	}
+	existing.Replicas++
+	if existing.ImageDigest == "" {
+		existing.ImageDigest = location.ImageDigest
+	}
+	if location.DeployedAt.Before(existing.DeployedAt) {
+		existing.DeployedAt = location.DeployedAt
+	}
+}
+
+func (l *imageLocations) warn(warning string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warnings = append(l.warnings, warning)
+}
+
+// merge enriches the live locations with the owner and deploy time of their active records and
+// adds the records not seen running
+func (l *imageLocations) merge(records []*domain.ImageDeployment, clusterByID map[string]*domain.K8sCluster, hostByID map[string]*domain.DockerHost) []*domain.ImageLocation {
+	byWorkload := make(map[string][]*domain.ImageLocation, len(l.locations))
+	result := make([]*domain.ImageLocation, 0, len(l.locations)+len(records))
+	for _, location := range l.locations {
+		key := strings.Join([]string{location.ClusterID, location.Namespace, location.WorkloadName, location.Container}, "|")
+		byWorkload[key] = append(byWorkload[key], location)
+		result = append(result, location)
+	}
+
+	for _, record := range records {
+		if record.Status == "active" {
+			key := strings.Join([]string{record.ClusterID, record.Namespace, record.DeploymentName, record.ContainerName}, "|")
+			if location := recordedLocation(byWorkload[key], record); location != nil {
+				location.Owner = record.DeployedBy
+				location.DeployedAt = record.DeployedAt
+				if location.ImageDigest == "" {
+					location.ImageDigest = record.ImageDigest
+				}
+				continue
+			}
+		}
+
+		platform := record.Platform
+		if platform == "" {
+			platform = domain.ImageDeploymentPlatformKubernetes
+		}
+		location := &domain.ImageLocation{
+			Platform:     platform,
+			ClusterID:    record.ClusterID,
+			Namespace:    record.Namespace,
+			WorkloadKind: record.WorkloadKind,
+			WorkloadName: record.DeploymentName,
+			Container:    record.ContainerName,
+			Image:        formatImageReference(record.ImageRepository, record.ImageTag),
+			ImageDigest:  record.ImageDigest,
+			Owner:        record.DeployedBy,
+			DeployedAt:   record.DeployedAt,
+			Status:       record.Status,
+			Source:       domain.ImageLocationSourceRecord,
+		}
+		if cluster, ok := clusterByID[record.ClusterID]; ok && platform == domain.ImageDeploymentPlatformKubernetes {
+			location.ClusterName = cluster.Name
+		} else if host, ok := hostByID[record.ClusterID]; ok && platform == domain.ImageDeploymentPlatformDocker {
+			location.ClusterName = host.Name
+		}
+		result = append(result, location)
+	}
+	return result
+}
+
+// recordedLocation returns the live location running the image of a deployment record
+func recordedLocation(locations []*domain.ImageLocation, record *domain.ImageDeployment) *domain.ImageLocation {
+	for _, location := range locations {
+		repository, reference := splitImageReference(location.Image)
+		if repository != record.ImageRepository {
+			continue
+		}
+		if reference == record.ImageTag || (record.ImageDigest != "" && location.ImageDigest == record.ImageDigest) {
+			return location
+		}
+	}
+	return nil
+}
+
+// canonicalImageRepository returns the registry host and path of a repository, with Docker Hub
+// official images under "library/"
+func canonicalImageRepository(repository string) (string, string) {
+	host, path, _ := parseImageReference(repository)
+	path = strings.ToLower(path)
+	if host == "docker.io" && !strings.Contains(path, "/") {
+		path = "library/" + path
+	}
+	return host, path
+}
+
+// imageRepositoryMatches reports whether a repository is the searched one. A search without registry
+// host matches the repository on any registry
+func imageRepositoryMatches(repository, search string) bool {
+	host, path := canonicalImageRepository(repository)
+	searchHost, searchPath := canonicalImageRepository(search)
+	if path != searchPath {
+		return false
+	}
+	return host == searchHost || !strings.HasPrefix(strings.ToLower(search), searchHost+"/")
+}
@@ -0,0 +1,124 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+)
+
+// MockK8sClusterRepository is a mock implementation of domain.K8sClusterRepository
+type MockK8sClusterRepository struct {
+	mock.Mock
+}
+
+func (m *MockK8sClusterRepository) Create(ctx context.Context, cluster *domain.K8sCluster) error {
+	args := m.Called(ctx, cluster)
+	return args.Error(0)
+}
+
+func (m *MockK8sClusterRepository) GetByID(ctx context.Context, id string) (*domain.K8sCluster, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.K8sCluster), args.Error(1)
+}
+
+func (m *MockK8sClusterRepository) List(ctx context.Context, filter domain.K8sClusterFilter) ([]*domain.K8sCluster, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]*domain.K8sCluster), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockK8sClusterRepository) Update(ctx context.Context, cluster *domain.K8sCluster) error {
+	args := m.Called(ctx, cluster)
+	return args.Error(0)
+}
+
+func (m *MockK8sClusterRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// MockDockerHostRepository is a mock implementation of domain.DockerHostRepository
+type MockDockerHostRepository struct {
+	mock.Mock
+}
+
+func (m *MockDockerHostRepository) Create(ctx context.Context, host *domain.DockerHost) error {
+	args := m.Called(ctx, host)
+	return args.Error(0)
+}
+
+func (m *MockDockerHostRepository) GetByID(ctx context.Context, id string) (*domain.DockerHost, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DockerHost), args.Error(1)
+}
+
+func (m *MockDockerHostRepository) List(ctx context.Context, filter domain.DockerHostFilter) ([]*domain.DockerHost, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]*domain.DockerHost), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockDockerHostRepository) UpdateHealth(ctx context.Context, id, status, version, lastError string, checkedAt time.Time) error {
+	args := m.Called(ctx, id, status, version, lastError, checkedAt)
+	return args.Error(0)
+}
+
+func (m *MockDockerHostRepository) Update(ctx context.Context, host *domain.DockerHost) error {
+	args := m.Called(ctx, host)
+	return args.Error(0)
+}
+
+func (m *MockDockerHostRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// TestSearchImageLocations tests that a search returns the active deployment records of the repository on any
+// registry, skipping look-alike repositories and inactive deployments
+func TestSearchImageLocations(t *testing.T) {
+	t.Run("Records of repository", func(t *testing.T) {
+		deploymentRepo := new(MockImageDeploymentRepository)
+		deploymentRepo.On("ListByImageRepository", mock.Anything, "shop/api").Return([]*domain.ImageDeployment{
+			{ID: "dep-1", Platform: "kubernetes", ClusterID: "cluster-1", Namespace: "shop", DeploymentName: "api", ContainerName: "api",
+				ImageRepository: "harbor.example.com/shop/api", ImageTag: "v2", DeployedBy: "alice", Status: "active"},
+			{ID: "dep-2", Platform: "kubernetes", ClusterID: "cluster-1", Namespace: "shop", DeploymentName: "api", ContainerName: "api",
+				ImageRepository: "harbor.example.com/shop/api", ImageTag: "v1", Status: "replaced"},
+			{ID: "dep-3", Platform: "kubernetes", ClusterID: "cluster-1", Namespace: "legacy", DeploymentName: "api", ContainerName: "api",
+				ImageRepository: "harbor.example.com/legacy/shop/api", ImageTag: "v1", Status: "active"},
+		}, nil)
+		k8sRepo := new(MockK8sClusterRepository)
+		k8sRepo.On("List", mock.Anything, mock.Anything).Return([]*domain.K8sCluster{
+			{ID: "cluster-1", Name: "prod"},
+		}, int64(1), nil)
+		dockerRepo := new(MockDockerHostRepository)
+		dockerRepo.On("List", mock.Anything, mock.Anything).Return([]*domain.DockerHost{}, int64(0), nil)
+
+		uc := usecase.NewImageSearchUsecase(deploymentRepo, k8sRepo, nil, dockerRepo, nil, nil, nil)
+		result, err := uc.Search(context.Background(), domain.ImageSearchQuery{Repository: "shop/api"})
+		require.NoError(t, err)
+
+		require.Len(t, result.Locations, 1)
+		location := result.Locations[0]
+		assert.Equal(t, "prod", location.ClusterName)
+		assert.Equal(t, "harbor.example.com/shop/api:v2", location.Image)
+		assert.Equal(t, "alice", location.Owner)
+		assert.Equal(t, domain.ImageLocationSourceRecord, location.Source)
+	})
+
+	t.Run("Tag without repository", func(t *testing.T) {
+		uc := usecase.NewImageSearchUsecase(nil, nil, nil, nil, nil, nil, nil)
+		_, err := uc.Search(context.Background(), domain.ImageSearchQuery{Tag: "v2", Digest: "sha256:abc"})
+		assert.Equal(t, errorx.CodeBadRequest, errorx.GetCode(err))
+	})
+}
@@ -1,25 +1,45 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
+	"github.com/pmezard/go-difflib/difflib"
 	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
+// maxPodLogStreams bounds the number of containers aggregated into one log stream
+const maxPodLogStreams = 50
+
 type kubernetesUsecase struct {
-	k8sRepo domain.K8sClusterRepository
-	// TODO: Add Kubernetes client-go
+	k8sRepo           domain.K8sClusterRepository
+	clients           *k8s.ClientManager
+	vulnerabilityGate domain.VulnerabilityGateUsecase
 }
 
 // NewKubernetesUsecase creates a new Kubernetes use case instance
-func NewKubernetesUsecase(k8sRepo domain.K8sClusterRepository) domain.KubernetesUsecase {
+func NewKubernetesUsecase(k8sRepo domain.K8sClusterRepository, clients *k8s.ClientManager, vulnerabilityGate domain.VulnerabilityGateUsecase) domain.KubernetesUsecase {
 	return &kubernetesUsecase{
-		k8sRepo: k8sRepo,
+		k8sRepo:           k8sRepo,
+		clients:           clients,
+		vulnerabilityGate: vulnerabilityGate,
 	}
 }
 
+// getClient gets or creates the Kubernetes client for a cluster
+func (u *kubernetesUsecase) getClient(ctx context.Context, clusterID string) (*k8s.Client, error) {
+	return getK8sClient(ctx, u.k8sRepo, u.clients, clusterID)
+}
+
 // Cluster Management
 
 func (u *kubernetesUsecase) CreateCluster(ctx context.Context, cluster *domain.K8sCluster) error {
@@ -56,14 +76,27 @@ func (u *kubernetesUsecase) UpdateCluster(ctx context.Context, cluster *domain.K
 	if cluster.ID == "" {
 		return errors.New("cluster ID is required")
 	}
-	return u.k8sRepo.Update(ctx, cluster)
+	if err := u.k8sRepo.Update(ctx, cluster); err != nil {
+		return err
+	}
+	// Credentials may have changed; reconnect on next use
+	if u.clients != nil {
+		u.clients.Remove(cluster.ID)
+	}
+	return nil
 }
 
 func (u *kubernetesUsecase) DeleteCluster(ctx context.Context, id string) error {
 	if id == "" {
 		return errors.New("cluster ID is required")
 	}
-	return u.k8sRepo.Delete(ctx, id)
+	if err := u.k8sRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if u.clients != nil {
+		u.clients.Remove(id)
+	}
+	return nil
 }
 
 func (u *kubernetesUsecase) GetClusterInfo(ctx context.Context, clusterID string) (map[string]interface{}, error) {
@@ -74,6 +107,190 @@ func (u *kubernetesUsecase) GetClusterInfo(ctx context.Context, clusterID string
 	return map[string]interface{}{}, fmt.Errorf("not implemented")
 }
 
+// Manifest Management
+
+// ApplyManifest server-side applies every object of a multi-document YAML/JSON manifest.
+// Each object is applied independently so one failure does not stop the rest.
+func (u *kubernetesUsecase) ApplyManifest(ctx context.Context, clusterID string, req domain.K8sApplyRequest) (*domain.K8sApplyResult, error) {
+	if req.Manifest == "" {
+		return nil, errors.New("manifest is required")
+	}
+
+	objects, err := k8s.ParseManifests([]byte(req.Manifest))
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if len(objects) == 0 {
+		return nil, errors.New("manifest contains no objects")
+	}
+
+	gateReport, err := u.checkImages(ctx, clusterID, objects)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := u.getClient(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldManager := req.FieldManager
+	if fieldManager == "" {
+		fieldManager = defaultFieldManager
+	}
+
+	result := &domain.K8sApplyResult{
+		ClusterID:    clusterID,
+		FieldManager: fieldManager,
+		DryRun:       req.DryRun,
+		Objects:      make([]domain.K8sApplyObjectResult, 0, len(objects)),
+
+		VulnerabilityGate: gateReport,
+	}
+
+	opts := k8s.ApplyOptions{
+		FieldManager: fieldManager,
+		DryRun:       req.DryRun,
+		Force:        req.Force,
+	}
+
+	for _, obj := range objects {
+		objResult := u.applyObject(ctx, client, obj, req.Namespace, opts)
+		if objResult.Action == "failed" {
+			result.Failed++
+		} else {
+			result.Succeeded++
+		}
+		result.Objects = append(result.Objects, objResult)
+	}
+
+	return result, nil
+}
+
+// podSpecPaths are the locations of a pod spec in the workload kinds
+var podSpecPaths = [][]string{
+	{"spec"},                     // Pod
+	{"spec", "template", "spec"}, // Deployment, StatefulSet, DaemonSet, ReplicaSet, Job
+	{"spec", "jobTemplate", "spec", "template", "spec"}, // CronJob
+}
+
+// checkImages runs the vulnerability gate of the cluster's environment over the images of a manifest
+func (u *kubernetesUsecase) checkImages(ctx context.Context, clusterID string, objects []*unstructured.Unstructured) (*domain.VulnerabilityGateReport, error) {
+	if u.vulnerabilityGate == nil {
+		return nil, nil
+	}
+	return u.vulnerabilityGate.CheckCluster(ctx, clusterID, manifestImages(objects))
+}
+
+// manifestImages collects the container images referenced by the objects of a manifest
+func manifestImages(objects []*unstructured.Unstructured) []string {
+	var images []string
+	for _, obj := range objects {
+		for _, path := range podSpecPaths {
+			for _, field := range []string{"initContainers", "containers"} {
+				containers, found, _ := unstructured.NestedSlice(obj.Object, append(append([]string{}, path...), field)...)
+				if !found {
+					continue
+				}
+				for _, container := range containers {
+					if c, ok := container.(map[string]interface{}); ok {
+						if image, ok := c["image"].(string); ok && image != "" {
+							images = append(images, image)
+						}
+					}
+				}
+			}
+		}
+	}
+	return images
+}
+
+// applyObject applies a single object and reports what changed compared to the live state
+func (u *kubernetesUsecase) applyObject(ctx context.Context, client *k8s.Client, obj *unstructured.Unstructured, namespace string, opts k8s.ApplyOptions) domain.K8sApplyObjectResult {
+	objResult := domain.K8sApplyObjectResult{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+
+	live, err := client.GetLive(ctx, obj, namespace)
+	if err != nil {
+		objResult.Action = "failed"
+		objResult.Error = err.Error()
+		return objResult
+	}
+	// ResourceFor resolves the namespace, so report the effective one
+	objResult.Namespace = obj.GetNamespace()
+	stampImageChange(ctx, obj, live)
+
+	applied, err := client.Apply(ctx, obj, namespace, opts)
+	if err != nil {
+		objResult.Action = "failed"
+		objResult.Error = err.Error()
+		return objResult
+	}
+
+	liveYAML, _ := k8s.ToYAML(k8s.StripServerFields(live))
+	appliedYAML, _ := k8s.ToYAML(k8s.StripServerFields(applied))
+
+	switch {
+	case live == nil:
+		objResult.Action = "created"
+	case liveYAML == appliedYAML:
+		objResult.Action = "unchanged"
+	default:
+		objResult.Action = "configured"
+	}
+
+	if opts.DryRun {
+		objResult.Object = applied.Object
+		if liveYAML != appliedYAML {
+			objResult.Diff, _ = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(liveYAML),
+				B:        difflib.SplitLines(appliedYAML),
+				FromFile: "live",
+				ToFile:   "dry-run",
+				Context:  3,
+			})
+		}
+	}
+
+	return objResult
+}
+
+// applyTypedObject applies an arbitrary object (typed struct, map or JSON) to a cluster
+func (u *kubernetesUsecase) applyTypedObject(ctx context.Context, clusterID string, object interface{}) error {
+	if object == nil {
+		return errors.New("object is required")
+	}
+
+	var data []byte
+	switch v := object.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode object: %w", err)
+		}
+		data = encoded
+	}
+
+	result, err := u.ApplyManifest(ctx, clusterID, domain.K8sApplyRequest{Manifest: string(data)})
+	if err != nil {
+		return err
+	}
+	for _, obj := range result.Objects {
+		if obj.Action == "failed" {
+			return fmt.Errorf("failed to apply %s %s: %s", obj.Kind, obj.Name, obj.Error)
+		}
+	}
+	return nil
+}
+
 // Namespace Management
 
 func (u *kubernetesUsecase) ListNamespaces(ctx context.Context, clusterID string) ([]*domain.K8sNamespace, error) {
@@ -122,16 +339,14 @@ func (u *kubernetesUsecase) CreateDeployment(ctx context.Context, clusterID stri
 	if clusterID == "" {
 		return errors.New("cluster ID is required")
 	}
-	// TODO: Connect to K8s cluster and create deployment
-	return fmt.Errorf("not implemented")
+	return u.applyTypedObject(ctx, clusterID, deployment)
 }
 
 func (u *kubernetesUsecase) UpdateDeployment(ctx context.Context, clusterID string, deployment interface{}) error {
 	if clusterID == "" {
 		return errors.New("cluster ID is required")
 	}
-	// TODO: Connect to K8s cluster and update deployment
-	return fmt.Errorf("not implemented")
+	return u.applyTypedObject(ctx, clusterID, deployment)
 }
 
 func (u *kubernetesUsecase) DeleteDeployment(ctx context.Context, clusterID, namespace, name string) error {
@@ -172,8 +387,7 @@ func (u *kubernetesUsecase) CreateService(ctx context.Context, clusterID string,
 	if clusterID == "" {
 		return errors.New("cluster ID is required")
 	}
-	// TODO: Connect to K8s cluster and create service
-	return fmt.Errorf("not implemented")
+	return u.applyTypedObject(ctx, clusterID, service)
 }
 
 func (u *kubernetesUsecase) DeleteService(ctx context.Context, clusterID, namespace, name string) error {
@@ -214,34 +428,242 @@ func (u *kubernetesUsecase) GetPodLogs(ctx context.Context, clusterID, namespace
 	if clusterID == "" || namespace == "" || podName == "" {
 		return "", errors.New("cluster ID, namespace, and pod name are required")
 	}
-	// TODO: Connect to K8s cluster and get pod logs
-	return "", fmt.Errorf("not implemented")
+
+	client, err := u.getClient(ctx, clusterID)
+	if err != nil {
+		return "", err
+	}
+
+	var logs strings.Builder
+	opts := k8s.LogOptions{Container: containerName, TailLines: int64(tail)}
+	err = client.StreamPodLogs(ctx, namespace, podName, opts, func(line k8s.LogLine) bool {
+		logs.WriteString(line.Message)
+		logs.WriteByte('\n')
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	return logs.String(), nil
+}
+
+// StreamPodLogs streams logs of one pod or of all pods matching a label selector.
+// Without a container every container of each pod is streamed.
+func (u *kubernetesUsecase) StreamPodLogs(ctx context.Context, clusterID, namespace string, opts domain.K8sPodLogOptions) (<-chan domain.K8sPodLogEntry, <-chan error, error) {
+	if clusterID == "" || namespace == "" {
+		return nil, nil, errors.New("cluster ID and namespace are required")
+	}
+	if opts.PodName == "" && opts.LabelSelector == "" {
+		return nil, nil, errors.New("pod name or label selector is required")
+	}
+
+	client, err := u.getClient(ctx, clusterID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pods, err := client.ListPodContainers(ctx, namespace, opts.PodName, opts.LabelSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	type logTarget struct{ pod, container string }
+	var targets []logTarget
+	for pod, containers := range pods {
+		if opts.Container != "" {
+			containers = []string{opts.Container}
+		}
+		for _, container := range containers {
+			targets = append(targets, logTarget{pod: pod, container: container})
+		}
+	}
+	if len(targets) == 0 {
+		return nil, nil, errors.New("no pods match the selector")
+	}
+	if len(targets) > maxPodLogStreams {
+		return nil, nil, fmt.Errorf("too many log streams (%d), narrow the selector (max %d)", len(targets), maxPodLogStreams)
+	}
+
+	logChan := make(chan domain.K8sPodLogEntry, 100)
+	errChan := make(chan error, len(targets))
+
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target logTarget) {
+			defer wg.Done()
+
+			logOpts := k8s.LogOptions{
+				Container:    target.container,
+				Follow:       opts.Follow,
+				Previous:     opts.Previous,
+				TailLines:    opts.TailLines,
+				SinceSeconds: opts.SinceSeconds,
+			}
+			err := client.StreamPodLogs(ctx, namespace, target.pod, logOpts, func(line k8s.LogLine) bool {
+				select {
+				case logChan <- domain.K8sPodLogEntry{
+					Pod:       line.Pod,
+					Container: line.Container,
+					Timestamp: line.Timestamp,
+					Message:   line.Message,
+				}:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			})
+			if err != nil {
+				errChan <- err
+			}
+		}(target)
+	}
+
+	go func() {
+		wg.Wait()
+		close(logChan)
+		close(errChan)
+	}()
+
+	return logChan, errChan, nil
 }
 
+// ExecPodCommand runs a non-interactive command and returns its combined output
 func (u *kubernetesUsecase) ExecPodCommand(ctx context.Context, clusterID, namespace, podName, containerName string, command []string) (string, error) {
 	if clusterID == "" || namespace == "" || podName == "" {
 		return "", errors.New("cluster ID, namespace, and pod name are required")
 	}
-	// TODO: Connect to K8s cluster and exec command in pod
-	return "", fmt.Errorf("not implemented")
+	if len(command) == 0 {
+		return "", errors.New("command is required")
+	}
+
+	client, err := u.getClient(ctx, clusterID)
+	if err != nil {
+		return "", err
+	}
+
+	var output bytes.Buffer
+	exitCode, err := client.Exec(ctx, k8s.ExecOptions{
+		Namespace: namespace,
+		Pod:       podName,
+		Container: containerName,
+		Command:   command,
+		Stdout:    &output,
+		Stderr:    &output,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to exec in pod: %w", err)
+	}
+	if exitCode != 0 {
+		return output.String(), fmt.Errorf("command exited with code %d", exitCode)
+	}
+	return output.String(), nil
+}
+
+// ExecPodInteractive attaches the session streams to a command in a pod container and
+// blocks until it exits, returning its exit code
+func (u *kubernetesUsecase) ExecPodInteractive(ctx context.Context, clusterID, namespace, podName, containerName string, command []string, session domain.K8sExecSession) (int, error) {
+	if clusterID == "" || namespace == "" || podName == "" {
+		return -1, errors.New("cluster ID, namespace, and pod name are required")
+	}
+	if len(command) == 0 {
+		return -1, errors.New("command is required")
+	}
+
+	client, err := u.getClient(ctx, clusterID)
+	if err != nil {
+		return -1, err
+	}
+
+	var resize chan k8s.TerminalSize
+	if session.TTY && session.Resize != nil {
+		resize = make(chan k8s.TerminalSize)
+		go func() {
+			defer close(resize)
+			for {
+				select {
+				case size, ok := <-session.Resize:
+					if !ok {
+						return
+					}
+					select {
+					case resize <- k8s.TerminalSize{Width: size.Width, Height: size.Height}:
+					case <-ctx.Done():
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	return client.Exec(ctx, k8s.ExecOptions{
+		Namespace: namespace,
+		Pod:       podName,
+		Container: containerName,
+		Command:   command,
+		Stdin:     session.Stdin,
+		Stdout:    session.Stdout,
+		Stderr:    session.Stderr,
+		TTY:       session.TTY,
+		Resize:    resize,
+	})
 }
 
 // Node Management
 
 func (u *kubernetesUsecase) ListNodes(ctx context.Context, clusterID string) ([]*domain.K8sNode, error) {
-	if clusterID == "" {
-		return nil, errors.New("cluster ID is required")
+	client, err := u.getClient(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := client.Clientset().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
 	}
-	// TODO: Connect to K8s cluster and list nodes
-	return []*domain.K8sNode{}, nil
+
+	nodes := make([]*domain.K8sNode, 0, len(list.Items))
+	for i := range list.Items {
+		nodes = append(nodes, toDomainNode(clusterID, &list.Items[i]))
+	}
+	return nodes, nil
 }
 
 func (u *kubernetesUsecase) GetNode(ctx context.Context, clusterID, name string) (*domain.K8sNode, error) {
 	if clusterID == "" || name == "" {
 		return nil, errors.New("cluster ID and node name are required")
 	}
-	// TODO: Connect to K8s cluster and get node
-	return nil, fmt.Errorf("not implemented")
+	client, err := u.getClient(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := client.Clientset().CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node: %w", err)
+	}
+	return toDomainNode(clusterID, node), nil
+}
+
+func toDomainNode(clusterID string, node *corev1.Node) *domain.K8sNode {
+	return &domain.K8sNode{
+		Name:             node.Name,
+		ClusterID:        clusterID,
+		Labels:           node.Labels,
+		Status:           nodeStatus(node),
+		Roles:            nodeRoles(node),
+		KubeletVersion:   node.Status.NodeInfo.KubeletVersion,
+		OSImage:          node.Status.NodeInfo.OSImage,
+		KernelVersion:    node.Status.NodeInfo.KernelVersion,
+		ContainerRuntime: node.Status.NodeInfo.ContainerRuntimeVersion,
+		CPUCapacity:      node.Status.Capacity.Cpu().String(),
+		MemoryCapacity:   node.Status.Capacity.Memory().String(),
+		PodCapacity:      node.Status.Capacity.Pods().String(),
+		Conditions:       nodeConditions(node),
+		CreatedAt:        node.CreationTimestamp.Time,
+	}
 }
 
 // ConfigMap Management
@@ -266,8 +688,7 @@ func (u *kubernetesUsecase) CreateConfigMap(ctx context.Context, clusterID strin
 	if clusterID == "" {
 		return errors.New("cluster ID is required")
 	}
-	// TODO: Connect to K8s cluster and create configmap
-	return fmt.Errorf("not implemented")
+	return u.applyTypedObject(ctx, clusterID, configMap)
 }
 
 func (u *kubernetesUsecase) DeleteConfigMap(ctx context.Context, clusterID, namespace, name string) error {
@@ -300,8 +721,7 @@ func (u *kubernetesUsecase) CreateSecret(ctx context.Context, clusterID string,
 	if clusterID == "" {
 		return errors.New("cluster ID is required")
 	}
-	// TODO: Connect to K8s cluster and create secret
-	return fmt.Errorf("not implemented")
+	return u.applyTypedObject(ctx, clusterID, secret)
 }
 
 func (u *kubernetesUsecase) DeleteSecret(ctx context.Context, clusterID, namespace, name string) error {
@@ -334,8 +754,7 @@ func (u *kubernetesUsecase) CreateIngress(ctx context.Context, clusterID string,
 	if clusterID == "" {
 		return errors.New("cluster ID is required")
 	}
-	// TODO: Connect to K8s cluster and create ingress
-	return fmt.Errorf("not implemented")
+	return u.applyTypedObject(ctx, clusterID, ingress)
 }
 
 func (u *kubernetesUsecase) DeleteIngress(ctx context.Context, clusterID, namespace, name string) error {
@@ -368,8 +787,7 @@ func (u *kubernetesUsecase) CreateStatefulSet(ctx context.Context, clusterID str
 	if clusterID == "" {
 		return errors.New("cluster ID is required")
 	}
-	// TODO: Connect to K8s cluster and create statefulset
-	return fmt.Errorf("not implemented")
+	return u.applyTypedObject(ctx, clusterID, statefulSet)
 }
 
 func (u *kubernetesUsecase) DeleteStatefulSet(ctx context.Context, clusterID, namespace, name string) error {
@@ -402,8 +820,7 @@ func (u *kubernetesUsecase) CreateDaemonSet(ctx context.Context, clusterID strin
 	if clusterID == "" {
 		return errors.New("cluster ID is required")
 	}
-	// TODO: Connect to K8s cluster and create daemonset
-	return fmt.Errorf("not implemented")
+	return u.applyTypedObject(ctx, clusterID, daemonSet)
 }
 
 func (u *kubernetesUsecase) DeleteDaemonSet(ctx context.Context, clusterID, namespace, name string) error {
@@ -436,8 +853,7 @@ func (u *kubernetesUsecase) CreateJob(ctx context.Context, clusterID string, job
 	if clusterID == "" {
 		return errors.New("cluster ID is required")
 	}
-	// TODO: Connect to K8s cluster and create job
-	return fmt.Errorf("not implemented")
+	return u.applyTypedObject(ctx, clusterID, job)
 }
 
 func (u *kubernetesUsecase) DeleteJob(ctx context.Context, clusterID, namespace, name string) error {
@@ -470,8 +886,7 @@ func (u *kubernetesUsecase) CreateCronJob(ctx context.Context, clusterID string,
 	if clusterID == "" {
 		return errors.New("cluster ID is required")
 	}
-	// TODO: Connect to K8s cluster and create cronjob
-	return fmt.Errorf("not implemented")
+	return u.applyTypedObject(ctx, clusterID, cronJob)
 }
 
 func (u *kubernetesUsecase) DeleteCronJob(ctx context.Context, clusterID, namespace, name string) error {
@@ -0,0 +1,290 @@
+package usecase_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var deploymentsGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+// newFakeDynamicK8sCluster registers cluster-1 backed by a fake dynamic client holding objects. Its
+// discovery knows ConfigMaps and Deployments, and apply patches replace the stored object.
+func newFakeDynamicK8sCluster(objects ...runtime.Object) (*MockK8sClusterRepository, *k8s.ClientManager, *dynamicfake.FakeDynamicClient) {
+	clientset := fake.NewSimpleClientset()
+	clientset.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment", Namespaced: true}}},
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...)
+	tracker := dynamicClient.Tracker()
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		if _, err := tracker.Get(patch.GetResource(), patch.GetNamespace(), patch.GetName()); apierrors.IsNotFound(err) {
+			return true, obj, tracker.Create(patch.GetResource(), obj, patch.GetNamespace())
+		}
+		return true, obj, tracker.Update(patch.GetResource(), obj, patch.GetNamespace())
+	})
+
+	clients := k8s.NewClientManager()
+	clients.Set("cluster-1", k8s.NewClientFromInterfaces(clientset, dynamicClient))
+
+	repo := new(MockK8sClusterRepository)
+	repo.On("GetByID", mock.Anything, "cluster-1").Return(&domain.K8sCluster{
+		ID:         "cluster-1",
+		Name:       "prod",
+		IsActive:   true,
+		ConfigPath: "/etc/kubeconfig",
+	}, nil)
+	return repo, clients, dynamicClient
+}
+
+func liveAPIDeployment() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "api", "namespace": "shop"},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "api", "image": "harbor.example.com/shop/api:v2"},
+					},
+				},
+			},
+		},
+	}}
+}
+
+const apiDeploymentManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+        - name: api
+          image: harbor.example.com/shop/api:%s
+`
+
+// TestApplyManifest tests server-side applying manifests and reporting what changed
+func TestApplyManifest(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success - Create and configure", func(t *testing.T) {
+		repo, clients, dynamicClient := newFakeDynamicK8sCluster(liveAPIDeployment())
+		uc := usecase.NewKubernetesUsecase(repo, clients, nil)
+
+		result, err := uc.ApplyManifest(ctx, "cluster-1", domain.K8sApplyRequest{
+			Namespace: "shop",
+			Manifest: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: api-config
+data:
+  MODE: blue
+---
+` + fmt.Sprintf(apiDeploymentManifest, "v3"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "einfra", result.FieldManager)
+		assert.Equal(t, 2, result.Succeeded)
+		assert.Zero(t, result.Failed)
+		require.Len(t, result.Objects, 2)
+		assert.Equal(t, "created", result.Objects[0].Action)
+		assert.Equal(t, "shop", result.Objects[0].Namespace)
+		assert.Equal(t, "configured", result.Objects[1].Action)
+		assert.Empty(t, result.Objects[1].Diff)
+		assert.Nil(t, result.Objects[1].Object)
+
+		live, err := dynamicClient.Resource(deploymentsGVR).Namespace("shop").Get(ctx, "api", metav1.GetOptions{})
+		require.NoError(t, err)
+		containers, _, _ := unstructured.NestedSlice(live.Object, "spec", "template", "spec", "containers")
+		assert.Equal(t, "harbor.example.com/shop/api:v3", containers[0].(map[string]interface{})["image"])
+	})
+
+	t.Run("Success - Dry run diff", func(t *testing.T) {
+		repo, clients, _ := newFakeDynamicK8sCluster(liveAPIDeployment())
+		uc := usecase.NewKubernetesUsecase(repo, clients, nil)
+
+		result, err := uc.ApplyManifest(ctx, "cluster-1", domain.K8sApplyRequest{
+			Namespace: "shop",
+			Manifest:  fmt.Sprintf(apiDeploymentManifest, "v3"),
+			DryRun:    true,
+		})
+		require.NoError(t, err)
+		assert.True(t, result.DryRun)
+		require.Len(t, result.Objects, 1)
+		assert.Equal(t, "configured", result.Objects[0].Action)
+		assert.Contains(t, result.Objects[0].Diff, "--- live\n+++ dry-run\n")
+		assert.Contains(t, result.Objects[0].Diff, "-      - image: harbor.example.com/shop/api:v2\n")
+		assert.Contains(t, result.Objects[0].Diff, "+      - image: harbor.example.com/shop/api:v3\n")
+		assert.NotNil(t, result.Objects[0].Object)
+	})
+
+	t.Run("Success - Dry run without changes", func(t *testing.T) {
+		repo, clients, _ := newFakeDynamicK8sCluster(liveAPIDeployment())
+		uc := usecase.NewKubernetesUsecase(repo, clients, nil)
+
+		result, err := uc.ApplyManifest(ctx, "cluster-1", domain.K8sApplyRequest{
+			Namespace: "shop",
+			Manifest:  fmt.Sprintf(apiDeploymentManifest, "v2"),
+			DryRun:    true,
+		})
+		require.NoError(t, err)
+		require.Len(t, result.Objects, 1)
+		assert.Equal(t, "unchanged", result.Objects[0].Action)
+		assert.Empty(t, result.Objects[0].Diff)
+	})
+
+	t.Run("Error - Unknown kind fails alone", func(t *testing.T) {
+		repo, clients, _ := newFakeDynamicK8sCluster()
+		uc := usecase.NewKubernetesUsecase(repo, clients, nil)
+
+		result, err := uc.ApplyManifest(ctx, "cluster-1", domain.K8sApplyRequest{
+			Manifest: `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: gadget
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: api-config
+`,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Succeeded)
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, "failed", result.Objects[0].Action)
+		assert.Contains(t, result.Objects[0].Error, "example.com/v1, Kind=Widget")
+		assert.Equal(t, "created", result.Objects[1].Action)
+		assert.Equal(t, "default", result.Objects[1].Namespace)
+	})
+
+	for name, manifest := range map[string]string{
+		"Empty manifest":  "",
+		"Missing kind":    "apiVersion: v1\nmetadata:\n  name: api\n",
+		"Only separators": "---\n---\n",
+	} {
+		t.Run("Error - "+name, func(t *testing.T) {
+			repo, clients, _ := newFakeDynamicK8sCluster()
+			uc := usecase.NewKubernetesUsecase(repo, clients, nil)
+
+			_, err := uc.ApplyManifest(ctx, "cluster-1", domain.K8sApplyRequest{Manifest: manifest})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func labeledPod(name, app string, containers ...string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Labels: map[string]string{"app": app}}}
+	for _, container := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: container})
+	}
+	return pod
+}
+
+// collectPodLogs drains a log stream and returns the streamed pod/container pairs
+func collectPodLogs(t *testing.T, logs <-chan domain.K8sPodLogEntry, errs <-chan error) []string {
+	t.Helper()
+	var streams []string
+	for entry := range logs {
+		assert.Equal(t, "fake logs", entry.Message)
+		streams = append(streams, entry.Pod+"/"+entry.Container)
+	}
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	return streams
+}
+
+// TestStreamPodLogs tests aggregating the logs of the pods matching a selector
+func TestStreamPodLogs(t *testing.T) {
+	ctx := context.Background()
+	pods := []runtime.Object{
+		labeledPod("api-1", "api", "api", "proxy"),
+		labeledPod("api-2", "api", "api"),
+		labeledPod("worker-1", "worker", "worker"),
+	}
+
+	t.Run("Success - All containers of matching pods", func(t *testing.T) {
+		repo, clients, _ := newFakeK8sCluster(pods...)
+		uc := usecase.NewKubernetesUsecase(repo, clients, nil)
+
+		logs, errs, err := uc.StreamPodLogs(ctx, "cluster-1", "shop", domain.K8sPodLogOptions{LabelSelector: "app=api"})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"api-1/api", "api-1/proxy", "api-2/api"}, collectPodLogs(t, logs, errs))
+	})
+
+	t.Run("Success - Single container of each pod", func(t *testing.T) {
+		repo, clients, _ := newFakeK8sCluster(pods...)
+		uc := usecase.NewKubernetesUsecase(repo, clients, nil)
+
+		logs, errs, err := uc.StreamPodLogs(ctx, "cluster-1", "shop", domain.K8sPodLogOptions{LabelSelector: "app=api", Container: "api"})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"api-1/api", "api-2/api"}, collectPodLogs(t, logs, errs))
+	})
+
+	t.Run("Success - Single pod", func(t *testing.T) {
+		repo, clients, _ := newFakeK8sCluster(pods...)
+		uc := usecase.NewKubernetesUsecase(repo, clients, nil)
+
+		logs, errs, err := uc.StreamPodLogs(ctx, "cluster-1", "shop", domain.K8sPodLogOptions{PodName: "worker-1"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"worker-1/worker"}, collectPodLogs(t, logs, errs))
+	})
+
+	t.Run("Error - No pods match the selector", func(t *testing.T) {
+		repo, clients, _ := newFakeK8sCluster(pods...)
+		uc := usecase.NewKubernetesUsecase(repo, clients, nil)
+
+		_, _, err := uc.StreamPodLogs(ctx, "cluster-1", "shop", domain.K8sPodLogOptions{LabelSelector: "app=web"})
+		assert.ErrorContains(t, err, "no pods match")
+	})
+
+	t.Run("Error - Too many log streams", func(t *testing.T) {
+		var many []runtime.Object
+		for i := 0; i < 26; i++ {
+			many = append(many, labeledPod(fmt.Sprintf("web-%d", i), "web", "web", "proxy"))
+		}
+		repo, clients, _ := newFakeK8sCluster(many...)
+		uc := usecase.NewKubernetesUsecase(repo, clients, nil)
+
+		_, _, err := uc.StreamPodLogs(ctx, "cluster-1", "shop", domain.K8sPodLogOptions{LabelSelector: "app=web"})
+		assert.ErrorContains(t, err, "too many log streams (52)")
+	})
+
+	t.Run("Error - Missing pod name and selector", func(t *testing.T) {
+		uc := usecase.NewKubernetesUsecase(new(MockK8sClusterRepository), nil, nil)
+
+		_, _, err := uc.StreamPodLogs(ctx, "cluster-1", "shop", domain.K8sPodLogOptions{})
+		assert.Error(t, err)
+	})
+}
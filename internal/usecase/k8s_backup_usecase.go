@@ -2,31 +2,65 @@ package usecase
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/robfig/cron/v3"
 	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
 )
 
+// defaultBackupKeepLast is the number of backups a schedule keeps when none is given
+const defaultBackupKeepLast = 7
+
 type k8sBackupUsecase struct {
-	backupRepo domain.K8sBackupRepository
-	k8sRepo    domain.K8sClusterRepository
-	// In a real implementation, we would inject a K8s client factory here
+	backupRepo   domain.K8sBackupRepository
+	scheduleRepo domain.K8sBackupScheduleRepository
+	k8sRepo      domain.K8sClusterRepository
+	clients      *k8s.ClientManager
 }
 
 // NewK8sBackupUsecase creates a new K8s backup usecase
 func NewK8sBackupUsecase(
 	backupRepo domain.K8sBackupRepository,
+	scheduleRepo domain.K8sBackupScheduleRepository,
 	k8sRepo domain.K8sClusterRepository,
+	clients *k8s.ClientManager,
 ) domain.K8sBackupUsecase {
 	return &k8sBackupUsecase{
-		backupRepo: backupRepo,
-		k8sRepo:    k8sRepo,
+		backupRepo:   backupRepo,
+		scheduleRepo: scheduleRepo,
+		k8sRepo:      k8sRepo,
+		clients:      clients,
 	}
 }
 
 // BackupNamespace creates a backup of all resources in a namespace
 func (u *k8sBackupUsecase) BackupNamespace(ctx context.Context, clusterID, namespace, name, description, user string) (*domain.K8sBackup, error) {
+	backup, err := u.createBackup(ctx, getK8sClient, clusterID, namespace, name, description, user, nil)
+	if err != nil {
+		return nil, err
+	}
+	redactBackupSecrets(backup)
+	return backup, nil
+}
+
+// createBackup captures the resources of a namespace, or of every namespace when empty, into a
+// backup, optionally owned by a schedule, reading the cluster with the client connect gets
+func (u *k8sBackupUsecase) createBackup(ctx context.Context, connect k8sClientGetter, clusterID, namespace, name, description, user string, scheduleID *string) (*domain.K8sBackup, error) {
 	if clusterID == "" {
 		return nil, errors.New("cluster ID is required")
 	}
@@ -34,42 +68,208 @@ func (u *k8sBackupUsecase) BackupNamespace(ctx context.Context, clusterID, names
 		return nil, errors.New("backup name is required")
 	}
 
-	// Verify cluster exists
-	cluster, err := u.k8sRepo.GetByID(ctx, clusterID)
+	client, err := connect(ctx, u.k8sRepo, u.clients, clusterID)
 	if err != nil {
 		return nil, err
 	}
-	if cluster == nil {
-		return nil, errors.New("cluster not found")
+	resources, err := captureBackupResources(ctx, client.Clientset(), namespace)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create backup object
 	backup := &domain.K8sBackup{
-		ClusterID:   clusterID,
-		Namespace:   namespace,
-		Name:        name,
-		Description: description,
-		Status:      "pending",
-		CreatedAt:   time.Now(),
-		// CreatedBy:   user, // Assuming field exists or will be added
+		ClusterID:     clusterID,
+		Namespace:     namespace,
+		Name:          name,
+		Description:   description,
+		ResourceCount: len(resources),
+		Status:        "completed",
+		ScheduleID:    scheduleID,
+		CreatedBy:     user,
+		CreatedAt:     time.Now(),
+		Resources:     resources,
+	}
+	for _, resource := range resources {
+		backup.SizeBytes += int64(len(resource.Manifest))
 	}
 
-	// Save backup to repository (this would typically trigger the actual backup process)
 	if err := u.backupRepo.Create(ctx, backup); err != nil {
 		return nil, err
 	}
-
-	// In a real implementation, we would now trigger the actual K8s backup operation
-	// For this example, we'll just return the created backup object
 	return backup, nil
 }
 
+// backupKind is a kind of namespaced resource captured by backups
+type backupKind struct {
+	kind       domain.K8sResourceType
+	apiVersion string
+	list       func(ctx context.Context, clientset kubernetes.Interface, namespace string) (runtime.Object, error)
+}
+
+// backupKinds are the kinds a backup captures. Pods and ReplicaSets are left to their controllers.
+var backupKinds = []backupKind{
+	{domain.K8sResourceConfigMap, "v1", func(ctx context.Context, cs kubernetes.Interface, ns string) (runtime.Object, error) {
+		return cs.CoreV1().ConfigMaps(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{domain.K8sResourceSecret, "v1", func(ctx context.Context, cs kubernetes.Interface, ns string) (runtime.Object, error) {
+		return cs.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{domain.K8sResourceService, "v1", func(ctx context.Context, cs kubernetes.Interface, ns string) (runtime.Object, error) {
+		return cs.CoreV1().Services(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{domain.K8sResourcePVC, "v1", func(ctx context.Context, cs kubernetes.Interface, ns string) (runtime.Object, error) {
+		return cs.CoreV1().PersistentVolumeClaims(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{domain.K8sResourceDeployment, "apps/v1", func(ctx context.Context, cs kubernetes.Interface, ns string) (runtime.Object, error) {
+		return cs.AppsV1().Deployments(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{domain.K8sResourceStatefulSet, "apps/v1", func(ctx context.Context, cs kubernetes.Interface, ns string) (runtime.Object, error) {
+		return cs.AppsV1().StatefulSets(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{domain.K8sResourceDaemonSet, "apps/v1", func(ctx context.Context, cs kubernetes.Interface, ns string) (runtime.Object, error) {
+		return cs.AppsV1().DaemonSets(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{domain.K8sResourceJob, "batch/v1", func(ctx context.Context, cs kubernetes.Interface, ns string) (runtime.Object, error) {
+		return cs.BatchV1().Jobs(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{domain.K8sResourceCronJob, "batch/v1", func(ctx context.Context, cs kubernetes.Interface, ns string) (runtime.Object, error) {
+		return cs.BatchV1().CronJobs(ns).List(ctx, metav1.ListOptions{})
+	}},
+	{domain.K8sResourceIngress, "networking.k8s.io/v1", func(ctx context.Context, cs kubernetes.Interface, ns string) (runtime.Object, error) {
+		return cs.NetworkingV1().Ingresses(ns).List(ctx, metav1.ListOptions{})
+	}},
+}
+
+// captureBackupResources lists the resources of a namespace as manifests that can be applied again:
+// server-managed fields and status are dropped, and objects created by controllers are skipped
+func captureBackupResources(ctx context.Context, clientset kubernetes.Interface, namespace string) ([]domain.K8sBackupResource, error) {
+	var resources []domain.K8sBackupResource
+	for _, kind := range backupKinds {
+		list, err := kind.list(ctx, clientset, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", kind.kind, err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s list: %w", kind.kind, err)
+		}
+
+		for _, item := range items {
+			object, err := meta.Accessor(item)
+			if err != nil {
+				return nil, err
+			}
+			if skipBackupObject(item, object) {
+				continue
+			}
+			manifest, err := backupManifest(kind, item)
+			if err != nil {
+				return nil, fmt.Errorf("failed to serialize %s %s/%s: %w", kind.kind, object.GetNamespace(), object.GetName(), err)
+			}
+			resources = append(resources, domain.K8sBackupResource{
+				Kind:      kind.kind,
+				Namespace: object.GetNamespace(),
+				Name:      object.GetName(),
+				Manifest:  manifest,
+			})
+		}
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		return resourceRefLess(
+			domain.K8sBackupResourceRef{Kind: resources[i].Kind, Namespace: resources[i].Namespace, Name: resources[i].Name},
+			domain.K8sBackupResourceRef{Kind: resources[j].Kind, Namespace: resources[j].Namespace, Name: resources[j].Name},
+		)
+	})
+	return resources, nil
+}
+
+// skipBackupObject reports objects the cluster recreates by itself
+func skipBackupObject(item runtime.Object, object metav1.Object) bool {
+	if metav1.GetControllerOf(object) != nil {
+		return true
+	}
+	switch typed := item.(type) {
+	case *corev1.Secret:
+		return typed.Type == corev1.SecretTypeServiceAccountToken
+	case *corev1.ConfigMap:
+		return typed.Name == "kube-root-ca.crt"
+	}
+	return false
+}
+
+// backupManifest renders an object as YAML without the fields the API server sets
+func backupManifest(kind backupKind, item runtime.Object) (string, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(item)
+	if err != nil {
+		return "", err
+	}
+	content["apiVersion"] = kind.apiVersion
+	content["kind"] = string(kind.kind)
+	delete(content, "status")
+	if metadata, ok := content["metadata"].(map[string]interface{}); ok {
+		for _, field := range []string{"uid", "resourceVersion", "generation", "creationTimestamp", "managedFields", "selfLink"} {
+			delete(metadata, field)
+		}
+	}
+	if kind.kind == domain.K8sResourceService {
+		// Cluster IPs are allocated again when the Service is recreated
+		if spec, ok := content["spec"].(map[string]interface{}); ok && spec["clusterIP"] != corev1.ClusterIPNone {
+			delete(spec, "clusterIP")
+			delete(spec, "clusterIPs")
+		}
+	}
+
+	data, err := yaml.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// redactBackupSecrets replaces the values of the Secrets of a backup with digests, so they can be
+// compared without being disclosed
+func redactBackupSecrets(backup *domain.K8sBackup) {
+	for i, resource := range backup.Resources {
+		if resource.Kind == domain.K8sResourceSecret {
+			backup.Resources[i].Manifest = redactSecretManifest(resource.Manifest)
+		}
+	}
+}
+
+func redactSecretManifest(manifest string) string {
+	var content map[string]interface{}
+	if err := yaml.Unmarshal([]byte(manifest), &content); err != nil {
+		return ""
+	}
+	for _, field := range []string{"data", "stringData"} {
+		values, ok := content[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, value := range values {
+			sum := sha256.Sum256([]byte(fmt.Sprint(value)))
+			values[key] = "<redacted sha256:" + hex.EncodeToString(sum[:])[:12] + ">"
+		}
+	}
+	data, err := yaml.Marshal(content)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
 // GetBackup retrieves a backup by its ID
 func (u *k8sBackupUsecase) GetBackup(ctx context.Context, id string) (*domain.K8sBackup, error) {
 	if id == "" {
 		return nil, errors.New("backup ID is required")
 	}
-	return u.backupRepo.GetByID(ctx, id)
+	backup, err := u.backupRepo.GetByID(ctx, id)
+	if err != nil || backup == nil {
+		return backup, err
+	}
+	redactBackupSecrets(backup)
+	return backup, nil
 }
 
 // RestoreBackup restores a backup to the cluster
@@ -109,3 +309,344 @@ func (u *k8sBackupUsecase) DeleteBackup(ctx context.Context, id string) error {
 	}
 	return u.backupRepo.Delete(ctx, id)
 }
+
+// DiffBackups compares two backups resource by resource
+func (u *k8sBackupUsecase) DiffBackups(ctx context.Context, fromID, toID string) (*domain.K8sBackupDiff, error) {
+	if fromID == "" || toID == "" {
+		return nil, errors.New("both backup IDs are required")
+	}
+
+	from, err := u.backupRepo.GetByID(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	if from == nil {
+		return nil, fmt.Errorf("backup %s not found", fromID)
+	}
+
+	to, err := u.backupRepo.GetByID(ctx, toID)
+	if err != nil {
+		return nil, err
+	}
+	if to == nil {
+		return nil, fmt.Errorf("backup %s not found", toID)
+	}
+
+	if from.ClusterID != to.ClusterID {
+		return nil, errors.New("backups belong to different clusters")
+	}
+
+	// Secret values show up as changed digests
+	redactBackupSecrets(from)
+	redactBackupSecrets(to)
+
+	return diffBackups(from, to), nil
+}
+
+// diffBackups builds the added/removed/changed view between two backups
+func diffBackups(from, to *domain.K8sBackup) *domain.K8sBackupDiff {
+	result := &domain.K8sBackupDiff{
+		FromBackupID: from.ID,
+		ToBackupID:   to.ID,
+		FromTime:     from.CreatedAt,
+		ToTime:       to.CreatedAt,
+		Added:        []domain.K8sBackupResourceRef{},
+		Removed:      []domain.K8sBackupResourceRef{},
+		Changed:      []domain.K8sBackupResourceChange{},
+	}
+
+	oldResources := indexBackupResources(from.Resources)
+	newResources := indexBackupResources(to.Resources)
+
+	for key, newRes := range newResources {
+		oldRes, ok := oldResources[key]
+		if !ok {
+			result.Added = append(result.Added, key)
+			continue
+		}
+
+		oldManifest := normalizeManifest(oldRes.Manifest)
+		newManifest := normalizeManifest(newRes.Manifest)
+		if oldManifest == newManifest {
+			result.Unchanged++
+			continue
+		}
+
+		diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(oldManifest),
+			B:        difflib.SplitLines(newManifest),
+			FromFile: from.Name,
+			ToFile:   to.Name,
+			Context:  3,
+		})
+		result.Changed = append(result.Changed, domain.K8sBackupResourceChange{
+			K8sBackupResourceRef: key,
+			Diff:                 diff,
+		})
+	}
+
+	for key := range oldResources {
+		if _, ok := newResources[key]; !ok {
+			result.Removed = append(result.Removed, key)
+		}
+	}
+
+	sortResourceRefs(result.Added)
+	sortResourceRefs(result.Removed)
+	sort.Slice(result.Changed, func(i, j int) bool {
+		return resourceRefLess(result.Changed[i].K8sBackupResourceRef, result.Changed[j].K8sBackupResourceRef)
+	})
+
+	return result
+}
+
+func indexBackupResources(resources []domain.K8sBackupResource) map[domain.K8sBackupResourceRef]domain.K8sBackupResource {
+	index := make(map[domain.K8sBackupResourceRef]domain.K8sBackupResource, len(resources))
+	for _, res := range resources {
+		index[domain.K8sBackupResourceRef{Kind: res.Kind, Namespace: res.Namespace, Name: res.Name}] = res
+	}
+	return index
+}
+
+// normalizeManifest strips trailing whitespace so formatting noise does not show up as a change
+func normalizeManifest(manifest string) string {
+	lines := strings.Split(strings.TrimSpace(manifest), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func sortResourceRefs(refs []domain.K8sBackupResourceRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		return resourceRefLess(refs[i], refs[j])
+	})
+}
+
+func resourceRefLess(a, b domain.K8sBackupResourceRef) bool {
+	if a.Kind != b.Kind {
+		return a.Kind < b.Kind
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+// Schedule Management
+
+// CreateSchedule creates a new backup schedule, keeping the last 7 backups and active unless the
+// request says otherwise
+func (u *k8sBackupUsecase) CreateSchedule(ctx context.Context, clusterID string, req domain.K8sBackupScheduleRequest, user string) (*domain.K8sBackupSchedule, error) {
+	if clusterID == "" {
+		return nil, errors.New("cluster ID is required")
+	}
+
+	cluster, err := u.k8sRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	if cluster == nil {
+		return nil, errors.New("cluster not found")
+	}
+
+	schedule := &domain.K8sBackupSchedule{
+		ClusterID: clusterID,
+		KeepLast:  defaultBackupKeepLast,
+		IsActive:  true,
+		CreatedBy: user,
+	}
+	if err := applyBackupScheduleRequest(schedule, req); err != nil {
+		return nil, err
+	}
+	if err := u.scheduleRepo.Create(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// applyBackupScheduleRequest validates a request and copies it into a schedule, leaving the
+// retention and state alone when the request omits them
+func applyBackupScheduleRequest(schedule *domain.K8sBackupSchedule, req domain.K8sBackupScheduleRequest) error {
+	if req.Name == "" {
+		return errors.New("schedule name is required")
+	}
+	if req.KeepLast != nil && *req.KeepLast < 0 {
+		return errors.New("keep_last must not be negative")
+	}
+	nextRun, err := nextBackupRun(req.CronExpression, time.Now())
+	if err != nil {
+		return err
+	}
+
+	schedule.Name = req.Name
+	schedule.Namespace = req.Namespace
+	schedule.CronExpression = req.CronExpression
+	if req.KeepLast != nil {
+		schedule.KeepLast = *req.KeepLast
+	}
+	if req.IsActive != nil {
+		schedule.IsActive = *req.IsActive
+	}
+	schedule.NextRunAt = &nextRun
+	return nil
+}
+
+// GetSchedule retrieves a backup schedule by ID
+func (u *k8sBackupUsecase) GetSchedule(ctx context.Context, id string) (*domain.K8sBackupSchedule, error) {
+	if id == "" {
+		return nil, errors.New("schedule ID is required")
+	}
+	schedule, err := u.scheduleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return nil, errors.New("schedule not found")
+	}
+	return schedule, nil
+}
+
+// ListSchedules lists all backup schedules for a cluster
+func (u *k8sBackupUsecase) ListSchedules(ctx context.Context, clusterID string) ([]*domain.K8sBackupSchedule, error) {
+	if clusterID == "" {
+		return nil, errors.New("cluster ID is required")
+	}
+	return u.scheduleRepo.List(ctx, clusterID)
+}
+
+// UpdateSchedule updates a backup schedule and recalculates its next run
+func (u *k8sBackupUsecase) UpdateSchedule(ctx context.Context, id string, req domain.K8sBackupScheduleRequest) (*domain.K8sBackupSchedule, error) {
+	schedule, err := u.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyBackupScheduleRequest(schedule, req); err != nil {
+		return nil, err
+	}
+	if err := u.scheduleRepo.Update(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// DeleteSchedule deletes a backup schedule; backups it already produced are kept
+func (u *k8sBackupUsecase) DeleteSchedule(ctx context.Context, id string) error {
+	if id == "" {
+		return errors.New("schedule ID is required")
+	}
+	return u.scheduleRepo.Delete(ctx, id)
+}
+
+// RunSchedule runs a backup schedule immediately, as the requesting user, and applies its retention
+func (u *k8sBackupUsecase) RunSchedule(ctx context.Context, id string) (*domain.K8sBackup, error) {
+	schedule, err := u.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	backup, err := u.runSchedule(ctx, getK8sClient, schedule)
+	if err != nil {
+		return nil, err
+	}
+	redactBackupSecrets(backup)
+	return backup, nil
+}
+
+// StartScheduler starts the background job that runs due backup schedules
+func (u *k8sBackupUsecase) StartScheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				u.runDueSchedules(ctx)
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+func (u *k8sBackupUsecase) runDueSchedules(ctx context.Context) {
+	schedules, err := u.scheduleRepo.ListDue(ctx, time.Now())
+	if err != nil {
+		log.Printf("Error listing due backup schedules: %v", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		if _, err := u.runSchedule(ctx, getPlatformK8sClient, schedule); err != nil {
+			log.Printf("Backup schedule %s (%s) failed: %v", schedule.Name, schedule.ID, err)
+		}
+	}
+}
+
+func (u *k8sBackupUsecase) runSchedule(ctx context.Context, connect k8sClientGetter, schedule *domain.K8sBackupSchedule) (*domain.K8sBackup, error) {
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s", schedule.Name, now.UTC().Format("20060102-150405"))
+	description := fmt.Sprintf("Scheduled backup (%s)", schedule.CronExpression)
+
+	backup, runErr := u.createBackup(ctx, connect, schedule.ClusterID, schedule.Namespace, name, description, "scheduler", &schedule.ID)
+
+	schedule.LastRunAt = &now
+	if runErr != nil {
+		schedule.LastStatus = "failed"
+		schedule.LastError = runErr.Error()
+	} else {
+		schedule.LastStatus = "completed"
+		schedule.LastError = ""
+	}
+	if nextRun, err := nextBackupRun(schedule.CronExpression, now); err == nil {
+		schedule.NextRunAt = &nextRun
+	}
+	if err := u.scheduleRepo.Update(ctx, schedule); err != nil {
+		return backup, fmt.Errorf("failed to update schedule: %w", err)
+	}
+	if runErr != nil {
+		return nil, runErr
+	}
+
+	if err := u.applyRetention(ctx, schedule); err != nil {
+		log.Printf("Error applying retention for backup schedule %s: %v", schedule.ID, err)
+	}
+
+	return backup, nil
+}
+
+// applyRetention deletes the oldest scheduled backups beyond the schedule's keep-last count
+func (u *k8sBackupUsecase) applyRetention(ctx context.Context, schedule *domain.K8sBackupSchedule) error {
+	if schedule.KeepLast <= 0 {
+		return nil
+	}
+
+	backups, err := u.backupRepo.ListBySchedule(ctx, schedule.ID)
+	if err != nil {
+		return err
+	}
+	if len(backups) <= schedule.KeepLast {
+		return nil
+	}
+
+	for _, backup := range backups[schedule.KeepLast:] {
+		if err := u.backupRepo.Delete(ctx, backup.ID); err != nil {
+			return fmt.Errorf("failed to delete backup %s: %w", backup.ID, err)
+		}
+	}
+	return nil
+}
+
+// nextBackupRun validates a cron expression and returns its next run after the given time
+func nextBackupRun(expression string, after time.Time) (time.Time, error) {
+	if expression == "" {
+		return time.Time{}, errors.New("cron expression is required")
+	}
+
+	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	schedule, err := parser.Parse(expression)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cron expression: %w", err)
+	}
+
+	return schedule.Next(after), nil
+}
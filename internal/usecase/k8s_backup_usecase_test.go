@@ -0,0 +1,298 @@
+package usecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MockK8sBackupRepository is a mock implementation of domain.K8sBackupRepository
+type MockK8sBackupRepository struct {
+	mock.Mock
+}
+
+func (m *MockK8sBackupRepository) Create(ctx context.Context, backup *domain.K8sBackup) error {
+	args := m.Called(ctx, backup)
+	return args.Error(0)
+}
+
+func (m *MockK8sBackupRepository) GetByID(ctx context.Context, id string) (*domain.K8sBackup, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.K8sBackup), args.Error(1)
+}
+
+func (m *MockK8sBackupRepository) List(ctx context.Context, clusterID, namespace string) ([]*domain.K8sBackup, error) {
+	args := m.Called(ctx, clusterID, namespace)
+	return args.Get(0).([]*domain.K8sBackup), args.Error(1)
+}
+
+func (m *MockK8sBackupRepository) ListBySchedule(ctx context.Context, scheduleID string) ([]*domain.K8sBackup, error) {
+	args := m.Called(ctx, scheduleID)
+	return args.Get(0).([]*domain.K8sBackup), args.Error(1)
+}
+
+func (m *MockK8sBackupRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// MockK8sBackupScheduleRepository is a mock implementation of domain.K8sBackupScheduleRepository
+type MockK8sBackupScheduleRepository struct {
+	mock.Mock
+}
+
+func (m *MockK8sBackupScheduleRepository) Create(ctx context.Context, schedule *domain.K8sBackupSchedule) error {
+	args := m.Called(ctx, schedule)
+	return args.Error(0)
+}
+
+func (m *MockK8sBackupScheduleRepository) GetByID(ctx context.Context, id string) (*domain.K8sBackupSchedule, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.K8sBackupSchedule), args.Error(1)
+}
+
+func (m *MockK8sBackupScheduleRepository) List(ctx context.Context, clusterID string) ([]*domain.K8sBackupSchedule, error) {
+	args := m.Called(ctx, clusterID)
+	return args.Get(0).([]*domain.K8sBackupSchedule), args.Error(1)
+}
+
+func (m *MockK8sBackupScheduleRepository) ListDue(ctx context.Context, now time.Time) ([]*domain.K8sBackupSchedule, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]*domain.K8sBackupSchedule), args.Error(1)
+}
+
+func (m *MockK8sBackupScheduleRepository) Update(ctx context.Context, schedule *domain.K8sBackupSchedule) error {
+	args := m.Called(ctx, schedule)
+	return args.Error(0)
+}
+
+func (m *MockK8sBackupScheduleRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// TestBackupNamespaceCapturesResources tests that a backup holds re-appliable manifests of the
+// namespace, without the objects controllers or the cluster recreate
+func TestBackupNamespaceCapturesResources(t *testing.T) {
+	isController := true
+	clusterRepo, clients, _ := newFakeK8sCluster(
+		apiDeployment(),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-config", Namespace: "shop", ResourceVersion: "42", UID: "cm-uid"},
+			Data:       map[string]string{"LOG_LEVEL": "info"},
+		},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt", Namespace: "shop"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other-config", Namespace: "other"}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "shop"},
+			Data:       map[string][]byte{"password": []byte("hunter2")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "default-token", Namespace: "shop"},
+			Type:       corev1.SecretTypeServiceAccountToken,
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.0.0.12", Ports: []corev1.ServicePort{{Port: 80}}},
+		},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Name:            "report-28000",
+			Namespace:       "shop",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "CronJob", Name: "report", Controller: &isController}},
+		}},
+	)
+	backupRepo := new(MockK8sBackupRepository)
+	var stored *domain.K8sBackup
+	backupRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.K8sBackup")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*domain.K8sBackup)
+		// Keep what reached the repository, before the response is redacted
+		copied := *stored
+		copied.Resources = append([]domain.K8sBackupResource(nil), stored.Resources...)
+		stored = &copied
+	}).Return(nil)
+
+	uc := usecase.NewK8sBackupUsecase(backupRepo, new(MockK8sBackupScheduleRepository), clusterRepo, clients)
+	backup, err := uc.BackupNamespace(context.Background(), "cluster-1", "shop", "before-upgrade", "", "alice")
+	require.NoError(t, err)
+
+	var refs []string
+	manifests := map[string]string{}
+	for _, resource := range stored.Resources {
+		ref := string(resource.Kind) + "/" + resource.Name
+		refs = append(refs, ref)
+		manifests[ref] = resource.Manifest
+	}
+	assert.Equal(t, []string{"ConfigMap/api-config", "Deployment/api", "Secret/db", "Service/api"}, refs)
+	assert.Equal(t, "completed", stored.Status)
+	assert.Equal(t, 4, stored.ResourceCount)
+	assert.Equal(t, "alice", stored.CreatedBy)
+	assert.Positive(t, stored.SizeBytes)
+
+	assert.Contains(t, manifests["ConfigMap/api-config"], "apiVersion: v1\n")
+	assert.Contains(t, manifests["ConfigMap/api-config"], "kind: ConfigMap\n")
+	assert.Contains(t, manifests["ConfigMap/api-config"], "LOG_LEVEL: info")
+	assert.NotContains(t, manifests["ConfigMap/api-config"], "resourceVersion")
+	assert.NotContains(t, manifests["ConfigMap/api-config"], "uid")
+	assert.Contains(t, manifests["Deployment/api"], "apiVersion: apps/v1\n")
+	assert.NotContains(t, manifests["Deployment/api"], "status:")
+	assert.NotContains(t, manifests["Service/api"], "10.0.0.12")
+	assert.Contains(t, manifests["Secret/db"], "aHVudGVyMg==")
+
+	// The response does not disclose secret values
+	for _, resource := range backup.Resources {
+		if resource.Kind == domain.K8sResourceSecret {
+			assert.NotContains(t, resource.Manifest, "aHVudGVyMg==")
+			assert.Contains(t, resource.Manifest, "<redacted sha256:")
+		}
+	}
+}
+
+// TestBackupScheduleRetention tests that running a schedule keeps only its last backups
+func TestBackupScheduleRetention(t *testing.T) {
+	tests := []struct {
+		name     string
+		keepLast int
+		backups  int
+		deleted  []string
+	}{
+		{name: "Prunes the oldest", keepLast: 2, backups: 4, deleted: []string{"backup-3", "backup-4"}},
+		{name: "Within the limit", keepLast: 5, backups: 4},
+		{name: "Keep all", keepLast: 0, backups: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusterRepo, clients, _ := newFakeK8sCluster(apiDeployment(), &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "shop"},
+				Data:       map[string][]byte{"password": []byte("hunter2")},
+			})
+			schedule := &domain.K8sBackupSchedule{ID: "schedule-1", ClusterID: "cluster-1", Namespace: "shop", Name: "nightly", CronExpression: "0 2 * * *", KeepLast: tt.keepLast, IsActive: true}
+
+			scheduleRepo := new(MockK8sBackupScheduleRepository)
+			scheduleRepo.On("GetByID", mock.Anything, "schedule-1").Return(schedule, nil)
+			scheduleRepo.On("Update", mock.Anything, schedule).Return(nil)
+
+			backupRepo := new(MockK8sBackupRepository)
+			backupRepo.On("Create", mock.Anything, mock.MatchedBy(func(b *domain.K8sBackup) bool {
+				return *b.ScheduleID == "schedule-1" && b.ResourceCount == 2 && strings.HasPrefix(b.Name, "nightly-")
+			})).Return(nil)
+			var existing []*domain.K8sBackup
+			for i := 1; i <= tt.backups; i++ {
+				existing = append(existing, &domain.K8sBackup{ID: "backup-" + string(rune('0'+i))})
+			}
+			if tt.keepLast > 0 {
+				backupRepo.On("ListBySchedule", mock.Anything, "schedule-1").Return(existing, nil)
+			}
+			for _, id := range tt.deleted {
+				backupRepo.On("Delete", mock.Anything, id).Return(nil).Once()
+			}
+
+			uc := usecase.NewK8sBackupUsecase(backupRepo, scheduleRepo, clusterRepo, clients)
+			backup, err := uc.RunSchedule(context.Background(), "schedule-1")
+			require.NoError(t, err)
+			for _, resource := range backup.Resources {
+				assert.NotContains(t, resource.Manifest, "aHVudGVyMg==")
+			}
+			backupRepo.AssertExpectations(t)
+			backupRepo.AssertNumberOfCalls(t, "Delete", len(tt.deleted))
+			assert.Equal(t, "completed", schedule.LastStatus)
+			require.NotNil(t, schedule.NextRunAt)
+			assert.True(t, schedule.NextRunAt.After(time.Now()))
+		})
+	}
+}
+
+// TestCreateBackupScheduleDefaults tests that omitted retention and state take their defaults while
+// explicit zero values are kept
+func TestCreateBackupScheduleDefaults(t *testing.T) {
+	keepAll, inactive := 0, false
+	tests := []struct {
+		name     string
+		req      domain.K8sBackupScheduleRequest
+		keepLast int
+		isActive bool
+		wantErr  bool
+	}{
+		{name: "Defaults", req: domain.K8sBackupScheduleRequest{Name: "nightly", CronExpression: "0 2 * * *"}, keepLast: 7, isActive: true},
+		{name: "Keep all, paused", req: domain.K8sBackupScheduleRequest{Name: "nightly", CronExpression: "0 2 * * *", KeepLast: &keepAll, IsActive: &inactive}, keepLast: 0, isActive: false},
+		{name: "Invalid cron", req: domain.K8sBackupScheduleRequest{Name: "nightly", CronExpression: "every night"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusterRepo, clients, _ := newFakeK8sCluster()
+			scheduleRepo := new(MockK8sBackupScheduleRepository)
+			scheduleRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.K8sBackupSchedule")).Return(nil)
+
+			uc := usecase.NewK8sBackupUsecase(new(MockK8sBackupRepository), scheduleRepo, clusterRepo, clients)
+			schedule, err := uc.CreateSchedule(context.Background(), "cluster-1", tt.req, "user-1")
+			if tt.wantErr {
+				assert.Error(t, err)
+				scheduleRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.keepLast, schedule.KeepLast)
+			assert.Equal(t, tt.isActive, schedule.IsActive)
+			assert.Equal(t, "user-1", schedule.CreatedBy)
+			assert.NotNil(t, schedule.NextRunAt)
+		})
+	}
+}
+
+// TestDiffBackups tests the resource-by-resource comparison of two backups
+func TestDiffBackups(t *testing.T) {
+	from := &domain.K8sBackup{ID: "from", ClusterID: "cluster-1", Name: "monday", Resources: []domain.K8sBackupResource{
+		{Kind: domain.K8sResourceConfigMap, Namespace: "shop", Name: "api-config", Manifest: "data:\n  LOG_LEVEL: info\n"},
+		{Kind: domain.K8sResourceService, Namespace: "shop", Name: "api", Manifest: "spec:\n  ports:\n  - port: 80\n"},
+		{Kind: domain.K8sResourceConfigMap, Namespace: "shop", Name: "legacy", Manifest: "data: {}\n"},
+		{Kind: domain.K8sResourceSecret, Namespace: "shop", Name: "db", Manifest: "data:\n  password: aHVudGVyMg==\n"},
+	}}
+	to := &domain.K8sBackup{ID: "to", ClusterID: "cluster-1", Name: "tuesday", Resources: []domain.K8sBackupResource{
+		{Kind: domain.K8sResourceConfigMap, Namespace: "shop", Name: "api-config", Manifest: "data:\n  LOG_LEVEL: debug\n"},
+		{Kind: domain.K8sResourceService, Namespace: "shop", Name: "api", Manifest: "spec:\n  ports:\n  - port: 80   \n"},
+		{Kind: domain.K8sResourceDeployment, Namespace: "shop", Name: "worker", Manifest: "spec: {}\n"},
+		{Kind: domain.K8sResourceSecret, Namespace: "shop", Name: "db", Manifest: "data:\n  password: c3dvcmRmaXNo\n"},
+	}}
+	backupRepo := new(MockK8sBackupRepository)
+	backupRepo.On("GetByID", mock.Anything, "from").Return(from, nil)
+	backupRepo.On("GetByID", mock.Anything, "to").Return(to, nil)
+
+	uc := usecase.NewK8sBackupUsecase(backupRepo, new(MockK8sBackupScheduleRepository), new(MockK8sClusterRepository), nil)
+	diff, err := uc.DiffBackups(context.Background(), "from", "to")
+	require.NoError(t, err)
+
+	assert.Equal(t, []domain.K8sBackupResourceRef{{Kind: domain.K8sResourceDeployment, Namespace: "shop", Name: "worker"}}, diff.Added)
+	assert.Equal(t, []domain.K8sBackupResourceRef{{Kind: domain.K8sResourceConfigMap, Namespace: "shop", Name: "legacy"}}, diff.Removed)
+	require.Len(t, diff.Changed, 2)
+	assert.Equal(t, "api-config", diff.Changed[0].Name)
+	assert.Contains(t, diff.Changed[0].Diff, "-  LOG_LEVEL: info")
+	assert.Contains(t, diff.Changed[0].Diff, "+  LOG_LEVEL: debug")
+	// Secret changes show without their values
+	assert.Equal(t, "db", diff.Changed[1].Name)
+	assert.Contains(t, diff.Changed[1].Diff, "<redacted sha256:")
+	assert.NotContains(t, diff.Changed[1].Diff, "aHVudGVyMg==")
+	assert.NotContains(t, diff.Changed[1].Diff, "c3dvcmRmaXNo")
+	// Trailing whitespace is not a change
+	assert.Equal(t, 1, diff.Unchanged)
+
+	backupRepo.On("GetByID", mock.Anything, "elsewhere").Return(&domain.K8sBackup{ID: "elsewhere", ClusterID: "cluster-2"}, nil)
+	_, err = uc.DiffBackups(context.Background(), "from", "elsewhere")
+	assert.Error(t, err)
+}
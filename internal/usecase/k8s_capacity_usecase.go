@@ -0,0 +1,314 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/cache"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// capacityCacheTTL keeps overviews short-lived so the dashboard stays close to live state
+	capacityCacheTTL = 30 * time.Second
+	// capacityPageSize bounds pod list pages on large clusters
+	capacityPageSize = 500
+	// nodeRoleLabelPrefix is the label prefix kubectl derives node roles from
+	nodeRoleLabelPrefix = "node-role.kubernetes.io/"
+)
+
+var (
+	nodeMetricsGVR = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "nodes"}
+	podMetricsGVR  = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+)
+
+type k8sCapacityUsecase struct {
+	k8sRepo domain.K8sClusterRepository
+	clients *k8s.ClientManager
+	cache   cache.Cache
+}
+
+// NewK8sCapacityUsecase creates a new cluster capacity use case instance
+func NewK8sCapacityUsecase(k8sRepo domain.K8sClusterRepository, clients *k8s.ClientManager, c cache.Cache) domain.K8sCapacityUsecase {
+	return &k8sCapacityUsecase{
+		k8sRepo: k8sRepo,
+		clients: clients,
+		cache:   c,
+	}
+}
+
+// GetOverview returns the capacity overview of a cluster, served from cache when fresh
+func (u *k8sCapacityUsecase) GetOverview(ctx context.Context, clusterID string, refresh bool) (*domain.K8sClusterOverview, error) {
+	if clusterID == "" {
+		return nil, errors.New("cluster ID is required")
+	}
+
+	if u.cache == nil {
+		return u.computeOverview(ctx, clusterID)
+	}
+
+	key := "k8s:overview:" + clusterID
+	if refresh {
+		_ = u.cache.Delete(ctx, key)
+	}
+	return cache.Fetch(ctx, u.cache, key, capacityCacheTTL, func() (*domain.K8sClusterOverview, error) {
+		return u.computeOverview(ctx, clusterID)
+	})
+}
+
+func (u *k8sCapacityUsecase) computeOverview(ctx context.Context, clusterID string) (*domain.K8sClusterOverview, error) {
+	// The overview is shared between users, so it is computed with the platform identity
+	client, err := getPlatformK8sClient(ctx, u.k8sRepo, u.clients, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := client.Clientset().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	pods, err := listActivePods(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &domain.K8sClusterOverview{
+		ClusterID:       clusterID,
+		NodeCount:       len(nodes.Items),
+		KubeletVersions: make(map[string]int),
+		Nodes:           make([]domain.K8sNodeCapacity, 0, len(nodes.Items)),
+		Namespaces:      []domain.K8sNamespaceCapacity{},
+		GeneratedAt:     time.Now(),
+	}
+
+	nodeIndex := make(map[string]int, len(nodes.Items))
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		summary := domain.K8sResourceSummary{
+			CPUAllocatable:    node.Status.Allocatable.Cpu().MilliValue(),
+			MemoryAllocatable: node.Status.Allocatable.Memory().Value(),
+			PodCapacity:       node.Status.Allocatable.Pods().Value(),
+		}
+		status := nodeStatus(node)
+		if status == "Ready" {
+			overview.ReadyNodes++
+		}
+		overview.KubeletVersions[node.Status.NodeInfo.KubeletVersion]++
+
+		taints := make([]domain.K8sTaint, 0, len(node.Spec.Taints))
+		for _, taint := range node.Spec.Taints {
+			taints = append(taints, domain.K8sTaint{Key: taint.Key, Value: taint.Value, Effect: string(taint.Effect)})
+		}
+
+		nodeIndex[node.Name] = len(overview.Nodes)
+		overview.Nodes = append(overview.Nodes, domain.K8sNodeCapacity{
+			Name:             node.Name,
+			Status:           status,
+			Roles:            nodeRoles(node),
+			Unschedulable:    node.Spec.Unschedulable,
+			KubeletVersion:   node.Status.NodeInfo.KubeletVersion,
+			ContainerRuntime: node.Status.NodeInfo.ContainerRuntimeVersion,
+			Taints:           taints,
+			Conditions:       nodeConditions(node),
+			Resources:        summary,
+		})
+
+		overview.Totals.CPUAllocatable += summary.CPUAllocatable
+		overview.Totals.MemoryAllocatable += summary.MemoryAllocatable
+		overview.Totals.PodCapacity += summary.PodCapacity
+	}
+
+	namespaces := make(map[string]*domain.K8sResourceSummary)
+	for i := range pods {
+		pod := &pods[i]
+		cpuReq, memReq, cpuLim, memLim := podResources(pod)
+
+		ns, ok := namespaces[pod.Namespace]
+		if !ok {
+			ns = &domain.K8sResourceSummary{}
+			namespaces[pod.Namespace] = ns
+		}
+		addPodResources(ns, cpuReq, memReq, cpuLim, memLim)
+
+		// Pending pods are not bound to a node yet and only count towards their namespace
+		if idx, ok := nodeIndex[pod.Spec.NodeName]; ok {
+			addPodResources(&overview.Nodes[idx].Resources, cpuReq, memReq, cpuLim, memLim)
+		}
+		addPodResources(&overview.Totals, cpuReq, memReq, cpuLim, memLim)
+	}
+
+	overview.MetricsAvailable = u.addUsage(ctx, client, overview, nodeIndex, namespaces)
+
+	for name, summary := range namespaces {
+		overview.Namespaces = append(overview.Namespaces, domain.K8sNamespaceCapacity{Name: name, Resources: *summary})
+	}
+	sort.Slice(overview.Namespaces, func(i, j int) bool {
+		return overview.Namespaces[i].Name < overview.Namespaces[j].Name
+	})
+	sort.Slice(overview.Nodes, func(i, j int) bool {
+		return overview.Nodes[i].Name < overview.Nodes[j].Name
+	})
+
+	return overview, nil
+}
+
+// addUsage fills actual usage from metrics-server and reports whether it is available
+func (u *k8sCapacityUsecase) addUsage(ctx context.Context, client *k8s.Client, overview *domain.K8sClusterOverview, nodeIndex map[string]int, namespaces map[string]*domain.K8sResourceSummary) bool {
+	nodeMetrics, err := client.Dynamic().Resource(nodeMetricsGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false
+	}
+
+	var totalCPU, totalMemory int64
+	for _, item := range nodeMetrics.Items {
+		cpu, memory := metricsUsage(item.Object, "usage")
+		if idx, ok := nodeIndex[item.GetName()]; ok {
+			overview.Nodes[idx].Resources.CPUUsage = &cpu
+			overview.Nodes[idx].Resources.MemoryUsage = &memory
+		}
+		totalCPU += cpu
+		totalMemory += memory
+	}
+	overview.Totals.CPUUsage = &totalCPU
+	overview.Totals.MemoryUsage = &totalMemory
+
+	// Namespace usage is best effort; node usage alone is enough to report metrics as available
+	podMetrics, err := client.Dynamic().Resource(podMetricsGVR).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return true
+	}
+	for _, item := range podMetrics.Items {
+		ns, ok := namespaces[item.GetNamespace()]
+		if !ok {
+			continue
+		}
+		containers, _, _ := unstructured.NestedSlice(item.Object, "containers")
+		for _, container := range containers {
+			obj, ok := container.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			cpu, memory := metricsUsage(obj, "usage")
+			if ns.CPUUsage == nil {
+				ns.CPUUsage, ns.MemoryUsage = new(int64), new(int64)
+			}
+			*ns.CPUUsage += cpu
+			*ns.MemoryUsage += memory
+		}
+	}
+	return true
+}
+
+// listActivePods lists pods that hold resources, page by page
+func listActivePods(ctx context.Context, client *k8s.Client) ([]corev1.Pod, error) {
+	var pods []corev1.Pod
+	opts := metav1.ListOptions{
+		FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+		Limit:         capacityPageSize,
+	}
+	for {
+		list, err := client.Clientset().CoreV1().Pods(metav1.NamespaceAll).List(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods: %w", err)
+		}
+		pods = append(pods, list.Items...)
+		if list.Continue == "" {
+			return pods, nil
+		}
+		opts.Continue = list.Continue
+	}
+}
+
+// podResources returns the effective CPU (millicores) and memory (bytes) requests and limits of a pod,
+// computed like the scheduler: max(sum of containers, largest init container) plus pod overhead
+func podResources(pod *corev1.Pod) (cpuReq, memReq, cpuLim, memLim int64) {
+	for _, container := range pod.Spec.Containers {
+		cpuReq += container.Resources.Requests.Cpu().MilliValue()
+		memReq += container.Resources.Requests.Memory().Value()
+		cpuLim += container.Resources.Limits.Cpu().MilliValue()
+		memLim += container.Resources.Limits.Memory().Value()
+	}
+	for _, container := range pod.Spec.InitContainers {
+		cpuReq = max(cpuReq, container.Resources.Requests.Cpu().MilliValue())
+		memReq = max(memReq, container.Resources.Requests.Memory().Value())
+		cpuLim = max(cpuLim, container.Resources.Limits.Cpu().MilliValue())
+		memLim = max(memLim, container.Resources.Limits.Memory().Value())
+	}
+	if pod.Spec.Overhead != nil {
+		cpuReq += pod.Spec.Overhead.Cpu().MilliValue()
+		memReq += pod.Spec.Overhead.Memory().Value()
+	}
+	return cpuReq, memReq, cpuLim, memLim
+}
+
+func addPodResources(summary *domain.K8sResourceSummary, cpuReq, memReq, cpuLim, memLim int64) {
+	summary.Pods++
+	summary.CPURequests += cpuReq
+	summary.MemoryRequests += memReq
+	summary.CPULimits += cpuLim
+	summary.MemoryLimits += memLim
+}
+
+// metricsUsage reads the cpu and memory quantities of a metrics.k8s.io usage block
+func metricsUsage(obj map[string]interface{}, field string) (cpu, memory int64) {
+	usage, _, _ := unstructured.NestedStringMap(obj, field)
+	if q, err := resource.ParseQuantity(usage["cpu"]); err == nil {
+		cpu = q.MilliValue()
+	}
+	if q, err := resource.ParseQuantity(usage["memory"]); err == nil {
+		memory = q.Value()
+	}
+	return cpu, memory
+}
+
+// nodeStatus reports Ready, NotReady or Unknown from the node's Ready condition
+func nodeStatus(node *corev1.Node) string {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type != corev1.NodeReady {
+			continue
+		}
+		switch condition.Status {
+		case corev1.ConditionTrue:
+			return "Ready"
+		case corev1.ConditionFalse:
+			return "NotReady"
+		}
+	}
+	return "Unknown"
+}
+
+// nodeRoles derives node roles from node-role.kubernetes.io/<role> labels
+func nodeRoles(node *corev1.Node) []string {
+	roles := []string{}
+	for label := range node.Labels {
+		if role, ok := strings.CutPrefix(label, nodeRoleLabelPrefix); ok && role != "" {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+func nodeConditions(node *corev1.Node) []domain.K8sCondition {
+	conditions := make([]domain.K8sCondition, 0, len(node.Status.Conditions))
+	for _, condition := range node.Status.Conditions {
+		conditions = append(conditions, domain.K8sCondition{
+			Type:               string(condition.Type),
+			Status:             string(condition.Status),
+			LastTransitionTime: condition.LastTransitionTime.Time,
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+		})
+	}
+	return conditions
+}
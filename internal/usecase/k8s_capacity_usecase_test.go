@@ -0,0 +1,223 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/cache"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var (
+	nodeMetricsGVR = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "nodes"}
+	podMetricsGVR  = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+)
+
+// newFakeCapacityCluster registers cluster-1 holding objects; with metrics it serves metrics-server
+// usage of both nodes and of the web-1 pod, otherwise the metrics API is not installed
+func newFakeCapacityCluster(t *testing.T, metrics bool, objects ...runtime.Object) (*MockK8sClusterRepository, *k8s.ClientManager, *fake.Clientset) {
+	repo, _, clientset := newFakeK8sCluster(objects...)
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		nodeMetricsGVR: "NodeMetricsList",
+		podMetricsGVR:  "PodMetricsList",
+	})
+	if metrics {
+		tracker := dynamicClient.Tracker()
+		for name, usage := range map[string]map[string]interface{}{
+			"node-1": {"cpu": "250m", "memory": "1Gi"},
+			"node-2": {"cpu": "500m", "memory": "2Gi"},
+		} {
+			require.NoError(t, tracker.Create(nodeMetricsGVR, &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "metrics.k8s.io/v1beta1",
+				"kind":       "NodeMetrics",
+				"metadata":   map[string]interface{}{"name": name},
+				"usage":      usage,
+			}}, ""))
+		}
+		require.NoError(t, tracker.Create(podMetricsGVR, &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "metrics.k8s.io/v1beta1",
+			"kind":       "PodMetrics",
+			"metadata":   map[string]interface{}{"name": "web-1", "namespace": "shop"},
+			"containers": []interface{}{
+				map[string]interface{}{"name": "app", "usage": map[string]interface{}{"cpu": "100m", "memory": "100Mi"}},
+				map[string]interface{}{"name": "sidecar", "usage": map[string]interface{}{"cpu": "10m", "memory": "10Mi"}},
+			},
+		}}, "shop"))
+	} else {
+		dynamicClient.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewNotFound(action.GetResource().GroupResource(), "")
+		})
+	}
+
+	clients := k8s.NewClientManager()
+	clients.Set("cluster-1", k8s.NewClientFromInterfaces(clientset, dynamicClient))
+	return repo, clients, clientset
+}
+
+func capacityNode(name string, ready corev1.ConditionStatus, cpu, memory, kubelet string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+				corev1.ResourcePods:   resource.MustParse("110"),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+			NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: kubelet},
+		},
+	}
+}
+
+func capacityContainer(name, cpuReq, memReq, cpuLim, memLim string) corev1.Container {
+	container := corev1.Container{Name: name, Resources: corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpuReq), corev1.ResourceMemory: resource.MustParse(memReq)},
+	}}
+	if cpuLim != "" {
+		container.Resources.Limits = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpuLim), corev1.ResourceMemory: resource.MustParse(memLim)}
+	}
+	return container
+}
+
+func capacityObjects() []runtime.Object {
+	controlPlane := capacityNode("node-1", corev1.ConditionTrue, "4", "8Gi", "v1.30.2")
+	controlPlane.Labels = map[string]string{"node-role.kubernetes.io/control-plane": ""}
+	controlPlane.Spec.Taints = []corev1.Taint{{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectNoSchedule}}
+
+	return []runtime.Object{
+		controlPlane,
+		capacityNode("node-2", corev1.ConditionFalse, "2", "4Gi", "v1.29.5"),
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "shop"},
+			Spec: corev1.PodSpec{
+				NodeName: "node-1",
+				Containers: []corev1.Container{
+					capacityContainer("app", "500m", "256Mi", "1", "512Mi"),
+					capacityContainer("sidecar", "100m", "64Mi", "", ""),
+				},
+				// The init container needs more CPU than all app containers together
+				InitContainers: []corev1.Container{capacityContainer("migrate", "1", "128Mi", "", "")},
+				Overhead:       corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m"), corev1.ResourceMemory: resource.MustParse("16Mi")},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-2", Namespace: "shop"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{capacityContainer("app", "200m", "100Mi", "", "")}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-1", Namespace: "billing"},
+			Spec: corev1.PodSpec{
+				NodeName:   "node-2",
+				Containers: []corev1.Container{capacityContainer("postgres", "1", "2Gi", "2", "4Gi")},
+			},
+		},
+	}
+}
+
+const mebibyte = int64(1024 * 1024)
+
+// TestGetClusterOverview tests accounting node and pod resources into the capacity overview
+func TestGetClusterOverview(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success - Requests, limits and usage per node and namespace", func(t *testing.T) {
+		repo, clients, _ := newFakeCapacityCluster(t, true, capacityObjects()...)
+		uc := usecase.NewK8sCapacityUsecase(repo, clients, nil)
+
+		overview, err := uc.GetOverview(ctx, "cluster-1", false)
+		require.NoError(t, err)
+		assert.Equal(t, 2, overview.NodeCount)
+		assert.Equal(t, 1, overview.ReadyNodes)
+		assert.Equal(t, map[string]int{"v1.30.2": 1, "v1.29.5": 1}, overview.KubeletVersions)
+		assert.True(t, overview.MetricsAvailable)
+
+		require.Len(t, overview.Nodes, 2)
+		node := overview.Nodes[0]
+		assert.Equal(t, "node-1", node.Name)
+		assert.Equal(t, "Ready", node.Status)
+		assert.Equal(t, []string{"control-plane"}, node.Roles)
+		assert.Equal(t, []domain.K8sTaint{{Key: "node-role.kubernetes.io/control-plane", Effect: "NoSchedule"}}, node.Taints)
+		assert.Equal(t, 1, node.Resources.Pods)
+		assert.Equal(t, int64(4000), node.Resources.CPUAllocatable)
+		assert.Equal(t, int64(1050), node.Resources.CPURequests)
+		assert.Equal(t, 336*mebibyte, node.Resources.MemoryRequests)
+		assert.Equal(t, int64(1000), node.Resources.CPULimits)
+		assert.Equal(t, 512*mebibyte, node.Resources.MemoryLimits)
+		require.NotNil(t, node.Resources.CPUUsage)
+		assert.Equal(t, int64(250), *node.Resources.CPUUsage)
+		assert.Equal(t, "NotReady", overview.Nodes[1].Status)
+
+		require.Len(t, overview.Namespaces, 2)
+		billing, shop := overview.Namespaces[0], overview.Namespaces[1]
+		assert.Equal(t, "billing", billing.Name)
+		assert.Nil(t, billing.Resources.CPUUsage)
+		assert.Equal(t, "shop", shop.Name)
+		// The pending pod counts towards its namespace but not towards a node
+		assert.Equal(t, 2, shop.Resources.Pods)
+		assert.Equal(t, int64(1250), shop.Resources.CPURequests)
+		require.NotNil(t, shop.Resources.CPUUsage)
+		assert.Equal(t, int64(110), *shop.Resources.CPUUsage)
+		assert.Equal(t, 110*mebibyte, *shop.Resources.MemoryUsage)
+
+		assert.Equal(t, 3, overview.Totals.Pods)
+		assert.Equal(t, int64(6000), overview.Totals.CPUAllocatable)
+		assert.Equal(t, int64(2250), overview.Totals.CPURequests)
+		assert.Equal(t, int64(220), overview.Totals.PodCapacity)
+		assert.Equal(t, int64(750), *overview.Totals.CPUUsage)
+	})
+
+	t.Run("Success - Without metrics-server", func(t *testing.T) {
+		repo, clients, _ := newFakeCapacityCluster(t, false, capacityObjects()...)
+		uc := usecase.NewK8sCapacityUsecase(repo, clients, nil)
+
+		overview, err := uc.GetOverview(ctx, "cluster-1", false)
+		require.NoError(t, err)
+		assert.False(t, overview.MetricsAvailable)
+		assert.Nil(t, overview.Totals.CPUUsage)
+		assert.Equal(t, int64(2250), overview.Totals.CPURequests)
+	})
+
+	t.Run("Success - Cached until refreshed", func(t *testing.T) {
+		repo, clients, clientset := newFakeCapacityCluster(t, true, capacityObjects()...)
+		lru, err := cache.NewLRUCache(10)
+		require.NoError(t, err)
+		uc := usecase.NewK8sCapacityUsecase(repo, clients, lru)
+
+		overview, err := uc.GetOverview(ctx, "cluster-1", false)
+		require.NoError(t, err)
+		assert.Equal(t, 2, overview.NodeCount)
+
+		_, err = clientset.CoreV1().Nodes().Create(ctx, capacityNode("node-3", corev1.ConditionTrue, "2", "4Gi", "v1.30.2"), metav1.CreateOptions{})
+		require.NoError(t, err)
+
+		overview, err = uc.GetOverview(ctx, "cluster-1", false)
+		require.NoError(t, err)
+		assert.Equal(t, 2, overview.NodeCount)
+
+		overview, err = uc.GetOverview(ctx, "cluster-1", true)
+		require.NoError(t, err)
+		assert.Equal(t, 3, overview.NodeCount)
+	})
+
+	t.Run("Error - Missing cluster ID", func(t *testing.T) {
+		uc := usecase.NewK8sCapacityUsecase(new(MockK8sClusterRepository), nil, nil)
+
+		_, err := uc.GetOverview(ctx, "", false)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/k8s"
+)
+
+const (
+	// defaultFieldManager is the server-side apply field manager used when callers do not set one
+	defaultFieldManager = "einfra"
+	// k8sUserPrefix prefixes platform usernames to form Kubernetes user names
+	k8sUserPrefix = "einfra:"
+	// k8sUsersGroup is the Kubernetes group every impersonated platform user belongs to
+	k8sUsersGroup = "einfra:users"
+)
+
+// k8sUserName returns the Kubernetes user name of a platform user
+func k8sUserName(username string) string {
+	return k8sUserPrefix + username
+}
+
+// k8sClientGetter gets the client of a registered cluster: getK8sClient for work done on behalf
+// of a user, getPlatformK8sClient for background work
+type k8sClientGetter func(ctx context.Context, k8sRepo domain.K8sClusterRepository, clients *k8s.ClientManager, clusterID string) (*k8s.Client, error)
+
+// getK8sClient gets the Kubernetes client for a registered cluster. When the cluster has
+// impersonation enabled, the client impersonates the user carried by ctx; without one it fails
+// rather than falling back to the platform's own access.
+func getK8sClient(ctx context.Context, k8sRepo domain.K8sClusterRepository, clients *k8s.ClientManager, clusterID string) (*k8s.Client, error) {
+	cluster, client, err := connectK8sCluster(ctx, k8sRepo, clients, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cluster.ImpersonateUsers {
+		return client, nil
+	}
+	username, _ := ctx.Value("username").(string)
+	if username == "" {
+		return nil, fmt.Errorf("cluster %s impersonates users but the request has no authenticated user", cluster.Name)
+	}
+
+	client, err = clients.GetForUser(cluster.ID, k8sClusterConfig(cluster), k8sUserName(username), []string{k8sUsersGroup})
+	if err != nil {
+		return nil, fmt.Errorf("failed to impersonate %s on cluster %s: %w", username, cluster.Name, err)
+	}
+	return client, nil
+}
+
+// getPlatformK8sClient gets the client acting as the platform itself, for work shared
+// between users such as informers and scheduled jobs
+func getPlatformK8sClient(ctx context.Context, k8sRepo domain.K8sClusterRepository, clients *k8s.ClientManager, clusterID string) (*k8s.Client, error) {
+	_, client, err := connectK8sCluster(ctx, k8sRepo, clients, clusterID)
+	return client, err
+}
+
+func connectK8sCluster(ctx context.Context, k8sRepo domain.K8sClusterRepository, clients *k8s.ClientManager, clusterID string) (*domain.K8sCluster, *k8s.Client, error) {
+	if clusterID == "" {
+		return nil, nil, errors.New("cluster ID is required")
+	}
+	if clients == nil {
+		return nil, nil, errors.New("kubernetes client manager is not configured")
+	}
+
+	cluster, err := k8sRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cluster == nil {
+		return nil, nil, errors.New("cluster not found")
+	}
+	if !cluster.IsActive {
+		return nil, nil, fmt.Errorf("cluster %s is not active", cluster.Name)
+	}
+	if cluster.ConfigPath == "" {
+		return nil, nil, fmt.Errorf("cluster %s has no kubeconfig configured", cluster.Name)
+	}
+
+	client, err := clients.Get(cluster.ID, k8sClusterConfig(cluster))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to cluster %s: %w", cluster.Name, err)
+	}
+	return cluster, client, nil
+}
+
+func k8sClusterConfig(cluster *domain.K8sCluster) k8s.Config {
+	return k8s.Config{
+		APIServer:      cluster.APIServer,
+		KubeconfigPath: cluster.ConfigPath,
+	}
+}
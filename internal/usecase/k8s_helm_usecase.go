@@ -0,0 +1,373 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	storage "github.com/unitechio/einfra-be/internal/infrastructure/filestorage"
+	"github.com/unitechio/einfra-be/pkg/helm"
+	"github.com/unitechio/einfra-be/pkg/k8s"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// helmChartEntityType groups uploaded chart archives in object storage
+	helmChartEntityType = "helm_chart"
+	// helmAuditResource is the audit log resource of Helm actions
+	helmAuditResource = "helm_release"
+	// maxHelmChartSize bounds uploaded chart archives
+	maxHelmChartSize = 20 << 20
+)
+
+type k8sHelmUsecase struct {
+	k8sRepo      domain.K8sClusterRepository
+	clients      *k8s.ClientManager
+	storage      storage.IStorage
+	auditUsecase AuditUsecase
+}
+
+// NewK8sHelmUsecase creates a new Helm release management use case instance
+func NewK8sHelmUsecase(
+	k8sRepo domain.K8sClusterRepository,
+	clients *k8s.ClientManager,
+	fileStorage storage.IStorage,
+	auditUsecase AuditUsecase,
+) domain.K8sHelmUsecase {
+	return &k8sHelmUsecase{
+		k8sRepo:      k8sRepo,
+		clients:      clients,
+		storage:      fileStorage,
+		auditUsecase: auditUsecase,
+	}
+}
+
+func (u *k8sHelmUsecase) getHelmClient(ctx context.Context, clusterID string) (*helm.Client, error) {
+	client, err := getK8sClient(ctx, u.k8sRepo, u.clients, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return helm.NewClient(client), nil
+}
+
+// ListReleases lists the latest revision of each release
+func (u *k8sHelmUsecase) ListReleases(ctx context.Context, clusterID, namespace string) ([]*domain.HelmRelease, error) {
+	client, err := u.getHelmClient(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	releases, err := client.List(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list releases: %w", err)
+	}
+
+	result := make([]*domain.HelmRelease, 0, len(releases))
+	for _, rel := range releases {
+		result = append(result, toDomainHelmRelease(rel))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// GetRelease returns a release revision with its values and manifest
+func (u *k8sHelmUsecase) GetRelease(ctx context.Context, clusterID, namespace, name string, revision int, allValues bool) (*domain.HelmReleaseDetail, error) {
+	if namespace == "" || name == "" {
+		return nil, errors.New("namespace and release name are required")
+	}
+	client, err := u.getHelmClient(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := client.Get(namespace, name, revision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release: %w", err)
+	}
+	return toDomainHelmReleaseDetail(rel, allValues)
+}
+
+// GetHistory returns all revisions of a release, newest first
+func (u *k8sHelmUsecase) GetHistory(ctx context.Context, clusterID, namespace, name string) ([]*domain.HelmRelease, error) {
+	if namespace == "" || name == "" {
+		return nil, errors.New("namespace and release name are required")
+	}
+	client, err := u.getHelmClient(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	releases, err := client.History(namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release history: %w", err)
+	}
+
+	result := make([]*domain.HelmRelease, 0, len(releases))
+	for _, rel := range releases {
+		result = append(result, toDomainHelmRelease(rel))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Revision > result[j].Revision
+	})
+	return result, nil
+}
+
+// Install installs a chart as a new release
+func (u *k8sHelmUsecase) Install(ctx context.Context, clusterID string, req domain.HelmInstallRequest) (*domain.HelmReleaseDetail, error) {
+	if req.ReleaseName == "" || req.Namespace == "" {
+		return nil, errors.New("release name and namespace are required")
+	}
+
+	detail, err := u.installOrUpgrade(ctx, clusterID, req, false)
+	u.audit(ctx, domain.AuditActionCreate, clusterID, req.Namespace, req.ReleaseName, "install", chartDescription(req.HelmChartSource), req.DryRun, err)
+	return detail, err
+}
+
+// Upgrade upgrades an existing release
+func (u *k8sHelmUsecase) Upgrade(ctx context.Context, clusterID, namespace, name string, req domain.HelmInstallRequest) (*domain.HelmReleaseDetail, error) {
+	if namespace == "" || name == "" {
+		return nil, errors.New("namespace and release name are required")
+	}
+	req.Namespace = namespace
+	req.ReleaseName = name
+
+	detail, err := u.installOrUpgrade(ctx, clusterID, req, true)
+	u.audit(ctx, domain.AuditActionUpdate, clusterID, namespace, name, "upgrade", chartDescription(req.HelmChartSource), req.DryRun, err)
+	return detail, err
+}
+
+func (u *k8sHelmUsecase) installOrUpgrade(ctx context.Context, clusterID string, req domain.HelmInstallRequest, upgrade bool) (*domain.HelmReleaseDetail, error) {
+	client, err := u.getHelmClient(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	chrt, err := u.loadChart(ctx, client, req.HelmChartSource)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := mergeHelmValues(req.Values, req.ValuesYAML)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := helm.InstallOptions{
+		ReleaseName:     req.ReleaseName,
+		Namespace:       req.Namespace,
+		Values:          values,
+		CreateNamespace: req.CreateNamespace,
+		Wait:            req.Wait,
+		Atomic:          req.Atomic,
+		Timeout:         time.Duration(req.TimeoutSeconds) * time.Second,
+		DryRun:          req.DryRun,
+		ReuseValues:     req.ReuseValues,
+		Description:     req.Description,
+	}
+
+	var rel *release.Release
+	if upgrade {
+		rel, err = client.Upgrade(ctx, chrt, opts)
+	} else {
+		rel, err = client.Install(ctx, chrt, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toDomainHelmReleaseDetail(rel, false)
+}
+
+// Rollback rolls a release back to a previous revision
+func (u *k8sHelmUsecase) Rollback(ctx context.Context, clusterID, namespace, name string, req domain.HelmRollbackRequest) (*domain.HelmRelease, error) {
+	if namespace == "" || name == "" {
+		return nil, errors.New("namespace and release name are required")
+	}
+	client, err := u.getHelmClient(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.Rollback(namespace, name, req.Revision, req.Wait, time.Duration(req.TimeoutSeconds)*time.Second)
+	target := "previous revision"
+	if req.Revision > 0 {
+		target = fmt.Sprintf("revision %d", req.Revision)
+	}
+	u.audit(ctx, domain.AuditActionUpdate, clusterID, namespace, name, "rollback", target, false, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll back release: %w", err)
+	}
+
+	rel, err := client.Get(namespace, name, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release: %w", err)
+	}
+	return toDomainHelmRelease(rel), nil
+}
+
+// Uninstall removes a release
+func (u *k8sHelmUsecase) Uninstall(ctx context.Context, clusterID, namespace, name string, keepHistory bool) error {
+	if namespace == "" || name == "" {
+		return errors.New("namespace and release name are required")
+	}
+	client, err := u.getHelmClient(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Uninstall(namespace, name, keepHistory)
+	u.audit(ctx, domain.AuditActionDelete, clusterID, namespace, name, "uninstall", "", false, err)
+	if err != nil {
+		return fmt.Errorf("failed to uninstall release: %w", err)
+	}
+	return nil
+}
+
+// UploadChart validates a packaged chart and stores it in object storage
+func (u *k8sHelmUsecase) UploadChart(ctx context.Context, filename string, content io.Reader) (string, error) {
+	if u.storage == nil {
+		return "", errors.New("object storage is not configured")
+	}
+	if ext := strings.ToLower(filepath.Ext(filename)); ext != ".tgz" && !strings.HasSuffix(strings.ToLower(filename), ".tar.gz") {
+		return "", errors.New("chart archive must be a .tgz file")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, maxHelmChartSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read chart archive: %w", err)
+	}
+	if len(data) > maxHelmChartSize {
+		return "", fmt.Errorf("chart archive exceeds %d MB", maxHelmChartSize>>20)
+	}
+	if _, err := helm.LoadArchive(data); err != nil {
+		return "", err
+	}
+
+	return u.storage.UploadFileFromBytes(ctx, data, filepath.Base(filename), helmChartEntityType, 0)
+}
+
+// loadChart loads a chart from object storage or a chart repository
+func (u *k8sHelmUsecase) loadChart(ctx context.Context, client *helm.Client, src domain.HelmChartSource) (*chart.Chart, error) {
+	if src.ArchivePath != "" {
+		if u.storage == nil {
+			return nil, errors.New("object storage is not configured")
+		}
+		data, err := u.storage.DownloadFile(ctx, src.ArchivePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download chart archive: %w", err)
+		}
+		return helm.LoadArchive(data)
+	}
+
+	if src.Chart == "" {
+		return nil, errors.New("either archive_path or chart is required")
+	}
+	return client.LocateChart(helm.ChartSource{
+		RepoURL:  src.RepoURL,
+		Chart:    src.Chart,
+		Version:  src.Version,
+		Username: src.Username,
+		Password: src.Password,
+	})
+}
+
+// audit records a Helm action in the audit log as the user carried by ctx
+func (u *k8sHelmUsecase) audit(ctx context.Context, action domain.AuditAction, clusterID, namespace, name, operation, detail string, dryRun bool, opErr error) {
+	if u.auditUsecase == nil || dryRun {
+		return
+	}
+
+	resourceID := clusterID + "/" + namespace + "/" + name
+	description := fmt.Sprintf("Helm %s of release %s in namespace %s", operation, name, namespace)
+	if detail != "" {
+		description += " (" + detail + ")"
+	}
+
+	entry := contextAuditLog(ctx, action, helmAuditResource, resourceID, description, opErr)
+	entry.Metadata = map[string]interface{}{
+		"cluster_id": clusterID,
+		"namespace":  namespace,
+		"release":    name,
+		"operation":  operation,
+	}
+	_ = u.auditUsecase.Log(ctx, entry)
+}
+
+// mergeHelmValues merges values given as a map over values given as YAML
+func mergeHelmValues(values map[string]interface{}, valuesYAML string) (map[string]interface{}, error) {
+	merged := map[string]interface{}{}
+	if strings.TrimSpace(valuesYAML) != "" {
+		if err := yaml.Unmarshal([]byte(valuesYAML), &merged); err != nil {
+			return nil, fmt.Errorf("invalid values YAML: %w", err)
+		}
+	}
+	if len(values) == 0 {
+		return merged, nil
+	}
+	return chartutil.CoalesceTables(chartutil.Values(values).AsMap(), merged), nil
+}
+
+func chartDescription(src domain.HelmChartSource) string {
+	switch {
+	case src.ArchivePath != "":
+		return "chart archive " + filepath.Base(src.ArchivePath)
+	case src.Version != "":
+		return "chart " + src.Chart + " " + src.Version
+	default:
+		return "chart " + src.Chart
+	}
+}
+
+func toDomainHelmRelease(rel *release.Release) *domain.HelmRelease {
+	result := &domain.HelmRelease{
+		Name:      rel.Name,
+		Namespace: rel.Namespace,
+		Revision:  rel.Version,
+	}
+	if rel.Info != nil {
+		result.Status = rel.Info.Status.String()
+		result.Description = rel.Info.Description
+		result.UpdatedAt = rel.Info.LastDeployed.Time
+	}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		result.Chart = rel.Chart.Metadata.Name
+		result.ChartVersion = rel.Chart.Metadata.Version
+		result.AppVersion = rel.Chart.Metadata.AppVersion
+	}
+	return result
+}
+
+func toDomainHelmReleaseDetail(rel *release.Release, allValues bool) (*domain.HelmReleaseDetail, error) {
+	detail := &domain.HelmReleaseDetail{
+		HelmRelease: *toDomainHelmRelease(rel),
+		Values:      rel.Config,
+		Manifest:    rel.Manifest,
+	}
+	if rel.Info != nil {
+		detail.Notes = rel.Info.Notes
+	}
+	if allValues && rel.Chart != nil {
+		values, err := chartutil.CoalesceValues(rel.Chart, rel.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute values: %w", err)
+		}
+		detail.Values = values
+	}
+	if detail.Values == nil {
+		detail.Values = map[string]interface{}{}
+	}
+	return detail, nil
+}
@@ -0,0 +1,153 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	storage "github.com/unitechio/einfra-be/internal/infrastructure/filestorage"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// MockAuditUsecase mocks writing audit log entries
+type MockAuditUsecase struct {
+	usecase.AuditUsecase
+	mock.Mock
+}
+
+func (m *MockAuditUsecase) Log(ctx context.Context, entry *domain.AuditLog) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+// MockFileStorage mocks the object storage operations used for chart archives
+type MockFileStorage struct {
+	storage.IStorage
+	mock.Mock
+}
+
+func (m *MockFileStorage) UploadFileFromBytes(ctx context.Context, content []byte, filename string, entityType string, entityID uint) (string, error) {
+	args := m.Called(ctx, content, filename, entityType, entityID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockFileStorage) DownloadFile(ctx context.Context, storagePath string) ([]byte, error) {
+	args := m.Called(ctx, storagePath)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+// helmChartArchive packages a minimal chart
+func helmChartArchive(t *testing.T) []byte {
+	dir := t.TempDir()
+	path, err := chartutil.Save(&chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "demo", Version: "0.1.0"},
+	}, dir)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+// TestHelmUploadChart tests validating and storing packaged charts
+func TestHelmUploadChart(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success - Stores a valid chart", func(t *testing.T) {
+		archive := helmChartArchive(t)
+		fileStorage := new(MockFileStorage)
+		fileStorage.On("UploadFileFromBytes", ctx, archive, "demo-0.1.0.tgz", "helm_chart", uint(0)).Return("helm_chart/demo-0.1.0.tgz", nil)
+		uc := usecase.NewK8sHelmUsecase(nil, nil, fileStorage, nil)
+
+		path, err := uc.UploadChart(ctx, "uploads/demo-0.1.0.tgz", bytes.NewReader(archive))
+		require.NoError(t, err)
+		assert.Equal(t, "helm_chart/demo-0.1.0.tgz", path)
+		fileStorage.AssertExpectations(t)
+	})
+
+	t.Run("Error - Not a chart archive", func(t *testing.T) {
+		fileStorage := new(MockFileStorage)
+		uc := usecase.NewK8sHelmUsecase(nil, nil, fileStorage, nil)
+
+		_, err := uc.UploadChart(ctx, "demo.tgz", bytes.NewReader([]byte("not a tarball")))
+		assert.Error(t, err)
+		fileStorage.AssertNotCalled(t, "UploadFileFromBytes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error - Wrong extension", func(t *testing.T) {
+		uc := usecase.NewK8sHelmUsecase(nil, nil, new(MockFileStorage), nil)
+
+		_, err := uc.UploadChart(ctx, "demo.zip", bytes.NewReader(helmChartArchive(t)))
+		assert.ErrorContains(t, err, ".tgz")
+	})
+
+	t.Run("Error - Storage not configured", func(t *testing.T) {
+		uc := usecase.NewK8sHelmUsecase(nil, nil, nil, nil)
+
+		_, err := uc.UploadChart(ctx, "demo.tgz", bytes.NewReader(helmChartArchive(t)))
+		assert.Error(t, err)
+	})
+}
+
+// TestHelmInstallAudit tests that Helm operations are audited as the requesting user
+func TestHelmInstallAudit(t *testing.T) {
+	ctx := context.WithValue(context.WithValue(context.Background(), "user_id", "user-1"), "username", "alice")
+	archivePath := filepath.Join("helm_chart", "demo-0.1.0.tgz")
+
+	t.Run("Failure is audited", func(t *testing.T) {
+		repo, clients, _ := newFakeK8sCluster()
+		fileStorage := new(MockFileStorage)
+		fileStorage.On("DownloadFile", ctx, archivePath).Return(nil, errors.New("object not found"))
+		audit := new(MockAuditUsecase)
+		audit.On("Log", ctx, mock.MatchedBy(func(entry *domain.AuditLog) bool {
+			return entry.Action == domain.AuditActionCreate &&
+				entry.Resource == "helm_release" &&
+				*entry.ResourceID == "cluster-1/shop/api" &&
+				*entry.UserID == "user-1" &&
+				entry.Username == "alice" &&
+				entry.Description == "Helm install of release api in namespace shop (chart archive demo-0.1.0.tgz)" &&
+				entry.Metadata.(map[string]interface{})["operation"] == "install" &&
+				!entry.Success &&
+				entry.ErrorMessage != ""
+		})).Return(nil).Once()
+		uc := usecase.NewK8sHelmUsecase(repo, clients, fileStorage, audit)
+
+		_, err := uc.Install(ctx, "cluster-1", domain.HelmInstallRequest{
+			ReleaseName:     "api",
+			Namespace:       "shop",
+			HelmChartSource: domain.HelmChartSource{ArchivePath: archivePath},
+		})
+		assert.ErrorContains(t, err, "object not found")
+		audit.AssertExpectations(t)
+	})
+
+	t.Run("Dry run is not audited", func(t *testing.T) {
+		repo, clients, _ := newFakeK8sCluster()
+		audit := new(MockAuditUsecase)
+		uc := usecase.NewK8sHelmUsecase(repo, clients, nil, audit)
+
+		_, err := uc.Upgrade(ctx, "cluster-1", "shop", "api", domain.HelmInstallRequest{DryRun: true})
+		assert.ErrorContains(t, err, "either archive_path or chart is required")
+		audit.AssertNotCalled(t, "Log", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error - Missing release name", func(t *testing.T) {
+		audit := new(MockAuditUsecase)
+		uc := usecase.NewK8sHelmUsecase(new(MockK8sClusterRepository), nil, nil, audit)
+
+		_, err := uc.Install(ctx, "cluster-1", domain.HelmInstallRequest{Namespace: "shop"})
+		assert.Error(t, err)
+		audit.AssertNotCalled(t, "Log", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,536 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/repository"
+	"github.com/unitechio/einfra-be/pkg/k8s"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// rbacManagedLabel marks RBAC objects owned by the sync; unlabeled objects are never touched
+	rbacManagedLabel = "einfra.io/rbac-sync"
+	// rbacUserLabel records the platform user an object grants access to
+	rbacUserLabel = "einfra.io/user-id"
+	// rbacSyncInterval is how often all clusters are reconciled in the background
+	rbacSyncInterval = 10 * time.Minute
+	// k8sPermissionResource is the Permission.Resource of role permissions that apply to Kubernetes
+	k8sPermissionResource = "k8s"
+)
+
+// k8sActionVerbs maps platform actions to Kubernetes verbs
+var k8sActionVerbs = map[string][]string{
+	"read":   {"get", "list", "watch"},
+	"view":   {"get", "list", "watch"},
+	"list":   {"get", "list", "watch"},
+	"create": {"create"},
+	"update": {"update", "patch"},
+	"delete": {"delete", "deletecollection"},
+	"manage": {"*"},
+	"*":      {"*"},
+}
+
+// k8sSpecialActionRules maps platform actions that are not plain verbs to dedicated rules
+var k8sSpecialActionRules = map[string][]domain.K8sRBACRule{
+	"exec": {
+		{APIGroups: []string{""}, Resources: []string{"pods/exec", "pods/attach"}, Verbs: []string{"create", "get"}},
+	},
+	"logs": {
+		{APIGroups: []string{""}, Resources: []string{"pods/log"}, Verbs: []string{"get"}},
+	},
+	"scale": {
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments/scale", "statefulsets/scale"}, Verbs: []string{"get", "update", "patch"}},
+	},
+}
+
+// k8sSubResources maps Permission.SubResource values to API groups and resources
+var k8sSubResources = map[string]struct {
+	apiGroups []string
+	resources []string
+}{
+	"deployment":  {[]string{"apps"}, []string{"deployments", "deployments/scale"}},
+	"statefulset": {[]string{"apps"}, []string{"statefulsets", "statefulsets/scale"}},
+	"daemonset":   {[]string{"apps"}, []string{"daemonsets"}},
+	"replicaset":  {[]string{"apps"}, []string{"replicasets"}},
+	"pod":         {[]string{""}, []string{"pods", "pods/log"}},
+	"service":     {[]string{""}, []string{"services"}},
+	"configmap":   {[]string{""}, []string{"configmaps"}},
+	"secret":      {[]string{""}, []string{"secrets"}},
+	"pvc":         {[]string{""}, []string{"persistentvolumeclaims"}},
+	"ingress":     {[]string{"networking.k8s.io"}, []string{"ingresses"}},
+	"job":         {[]string{"batch"}, []string{"jobs"}},
+	"cronjob":     {[]string{"batch"}, []string{"cronjobs"}},
+	"namespace":   {[]string{""}, []string{"namespaces"}},
+	"node":        {[]string{""}, []string{"nodes"}},
+}
+
+type k8sRBACUsecase struct {
+	authRepo repository.AuthorizationRepository
+	k8sRepo  domain.K8sClusterRepository
+	clients  *k8s.ClientManager
+}
+
+// NewK8sRBACUsecase creates a new Kubernetes RBAC sync use case instance
+func NewK8sRBACUsecase(
+	authRepo repository.AuthorizationRepository,
+	k8sRepo domain.K8sClusterRepository,
+	clients *k8s.ClientManager,
+) domain.K8sRBACUsecase {
+	return &k8sRBACUsecase{
+		authRepo: authRepo,
+		k8sRepo:  k8sRepo,
+		clients:  clients,
+	}
+}
+
+// StartSync periodically reconciles RBAC on all active clusters
+func (u *k8sRBACUsecase) StartSync(ctx context.Context) {
+	ticker := time.NewTicker(rbacSyncInterval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if err := u.SyncAll(ctx); err != nil {
+					log.Printf("Error syncing Kubernetes RBAC: %v", err)
+				}
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// SyncAll reconciles RBAC on every active cluster
+func (u *k8sRBACUsecase) SyncAll(ctx context.Context) error {
+	active := true
+	filter := domain.K8sClusterFilter{IsActive: &active, Page: 1, PageSize: 100}
+	for {
+		clusters, total, err := u.k8sRepo.List(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to list clusters: %w", err)
+		}
+
+		for _, cluster := range clusters {
+			result, err := u.Sync(ctx, cluster.ID, false)
+			if err != nil {
+				log.Printf("Error syncing RBAC for cluster %s: %v", cluster.Name, err)
+				continue
+			}
+			for _, syncErr := range result.Errors {
+				log.Printf("RBAC sync for cluster %s: %s", cluster.Name, syncErr)
+			}
+		}
+
+		if len(clusters) == 0 || int64(filter.Page*filter.PageSize) >= total {
+			return nil
+		}
+		filter.Page++
+	}
+}
+
+// Sync computes the desired RBAC of a cluster from platform grants and reconciles the managed objects
+func (u *k8sRBACUsecase) Sync(ctx context.Context, clusterID string, dryRun bool) (*domain.K8sRBACSyncResult, error) {
+	if clusterID == "" {
+		return nil, errors.New("cluster ID is required")
+	}
+
+	cluster, err := u.k8sRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	bindings, err := u.desiredBindings(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.K8sRBACSyncResult{
+		ClusterID: clusterID,
+		DryRun:    dryRun,
+		Bindings:  bindings,
+		Created:   []string{},
+		Updated:   []string{},
+		Deleted:   []string{},
+		SyncedAt:  time.Now(),
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	// RBAC objects are managed with the platform identity, never an impersonated user
+	client, err := getPlatformK8sClient(ctx, u.k8sRepo, u.clients, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	u.reconcile(ctx, client, bindings, result)
+	return result, nil
+}
+
+// desiredBindings collects every grant that applies to the cluster, grouped by user and namespace
+func (u *k8sRBACUsecase) desiredBindings(ctx context.Context, cluster *domain.K8sCluster) ([]domain.K8sRBACBinding, error) {
+	bindings := make(map[string]*domain.K8sRBACBinding)
+	add := func(user *domain.User, namespace, source string, rules []domain.K8sRBACRule) {
+		if user == nil || !user.IsActive || user.Username == "" || len(rules) == 0 {
+			return
+		}
+		key := user.ID + "/" + namespace
+		binding, ok := bindings[key]
+		if !ok {
+			binding = &domain.K8sRBACBinding{
+				UserID:    user.ID,
+				Username:  user.Username,
+				Subject:   k8sUserName(user.Username),
+				Namespace: namespace,
+			}
+			bindings[key] = binding
+		}
+		binding.Rules = append(binding.Rules, rules...)
+		binding.Sources = append(binding.Sources, source)
+	}
+
+	// Global roles apply to every cluster
+	users, err := u.authRepo.ListUsersWithGlobalRole(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user roles: %w", err)
+	}
+	for _, user := range users {
+		if user.Role != nil && user.Role.IsActive {
+			add(user, "", "role:"+user.Role.Name, rulesFromPermissions(user.Role.Permissions))
+		}
+	}
+
+	// Environment roles apply to clusters of their environment, or all clusters without one
+	envRoles, err := u.authRepo.ListEnvironmentRoleAssignments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list environment roles: %w", err)
+	}
+	for _, envRole := range envRoles {
+		if envRole.Role == nil || !envRole.Role.IsActive || !environmentMatches(envRole.EnvironmentID, cluster.EnvironmentID) {
+			continue
+		}
+		add(envRole.User, "", "environment_role:"+envRole.Role.Name, rulesFromPermissions(envRole.Role.Permissions))
+	}
+
+	// Resource permissions on the cluster itself
+	clusterPerms, err := u.authRepo.GetResourcePermissions(ctx, domain.ResourceTypeK8sCluster, cluster.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster permissions: %w", err)
+	}
+	for _, perm := range clusterPerms {
+		if perm.IsExpired() || !environmentMatches(perm.EnvironmentID, cluster.EnvironmentID) {
+			continue
+		}
+		add(perm.User, "", "resource_permission:"+perm.ID, rulesFromActions(perm.Actions, []string{"*"}, []string{"*"}))
+	}
+
+	// Resource permissions on namespaces, identified as "<clusterID>/<namespace>"
+	namespacePerms, err := u.authRepo.ListResourcePermissionsByType(ctx, domain.ResourceTypeK8sNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespace permissions: %w", err)
+	}
+	for _, perm := range namespacePerms {
+		namespace, ok := strings.CutPrefix(perm.ResourceID, cluster.ID+"/")
+		if !ok || namespace == "" || perm.IsExpired() || !environmentMatches(perm.EnvironmentID, cluster.EnvironmentID) {
+			continue
+		}
+		add(perm.User, namespace, "resource_permission:"+perm.ID, rulesFromActions(perm.Actions, []string{"*"}, []string{"*"}))
+	}
+
+	result := make([]domain.K8sRBACBinding, 0, len(bindings))
+	for _, binding := range bindings {
+		binding.Rules = normalizeRules(binding.Rules)
+		sort.Strings(binding.Sources)
+		result = append(result, *binding)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Username != result[j].Username {
+			return result[i].Username < result[j].Username
+		}
+		return result[i].Namespace < result[j].Namespace
+	})
+	return result, nil
+}
+
+// reconcile creates or updates the desired objects and deletes managed objects that are no longer wanted
+func (u *k8sRBACUsecase) reconcile(ctx context.Context, client *k8s.Client, bindings []domain.K8sRBACBinding, result *domain.K8sRBACSyncResult) {
+	rbac := client.Clientset().RbacV1()
+	wanted := make(map[string]bool)
+
+	for _, binding := range bindings {
+		name := rbacObjectName(binding.UserID)
+		labels := map[string]string{rbacManagedLabel: "true", rbacUserLabel: binding.UserID}
+		subjects := []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: binding.Subject}}
+		rules := toPolicyRules(binding.Rules)
+
+		if binding.Namespace == "" {
+			wanted["ClusterRole/"+name] = true
+			wanted["ClusterRoleBinding/"+name] = true
+
+			u.record(result, "ClusterRole/"+name, func() (bool, bool, error) {
+				existing, err := rbac.ClusterRoles().Get(ctx, name, metav1.GetOptions{})
+				if apierrors.IsNotFound(err) {
+					_, err = rbac.ClusterRoles().Create(ctx, &rbacv1.ClusterRole{
+						ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+						Rules:      rules,
+					}, metav1.CreateOptions{FieldManager: defaultFieldManager})
+					return true, false, err
+				}
+				if err != nil || reflect.DeepEqual(existing.Rules, rules) {
+					return false, false, err
+				}
+				existing.Rules = rules
+				existing.Labels = labels
+				_, err = rbac.ClusterRoles().Update(ctx, existing, metav1.UpdateOptions{FieldManager: defaultFieldManager})
+				return false, true, err
+			})
+
+			u.record(result, "ClusterRoleBinding/"+name, func() (bool, bool, error) {
+				roleRef := rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: name}
+				existing, err := rbac.ClusterRoleBindings().Get(ctx, name, metav1.GetOptions{})
+				if apierrors.IsNotFound(err) {
+					_, err = rbac.ClusterRoleBindings().Create(ctx, &rbacv1.ClusterRoleBinding{
+						ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+						Subjects:   subjects,
+						RoleRef:    roleRef,
+					}, metav1.CreateOptions{FieldManager: defaultFieldManager})
+					return true, false, err
+				}
+				if err != nil || reflect.DeepEqual(existing.Subjects, subjects) {
+					return false, false, err
+				}
+				existing.Subjects = subjects
+				existing.Labels = labels
+				_, err = rbac.ClusterRoleBindings().Update(ctx, existing, metav1.UpdateOptions{FieldManager: defaultFieldManager})
+				return false, true, err
+			})
+			continue
+		}
+
+		namespace := binding.Namespace
+		wanted["Role/"+namespace+"/"+name] = true
+		wanted["RoleBinding/"+namespace+"/"+name] = true
+
+		u.record(result, "Role/"+namespace+"/"+name, func() (bool, bool, error) {
+			existing, err := rbac.Roles(namespace).Get(ctx, name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				_, err = rbac.Roles(namespace).Create(ctx, &rbacv1.Role{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+					Rules:      rules,
+				}, metav1.CreateOptions{FieldManager: defaultFieldManager})
+				return true, false, err
+			}
+			if err != nil || reflect.DeepEqual(existing.Rules, rules) {
+				return false, false, err
+			}
+			existing.Rules = rules
+			existing.Labels = labels
+			_, err = rbac.Roles(namespace).Update(ctx, existing, metav1.UpdateOptions{FieldManager: defaultFieldManager})
+			return false, true, err
+		})
+
+		u.record(result, "RoleBinding/"+namespace+"/"+name, func() (bool, bool, error) {
+			roleRef := rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name}
+			existing, err := rbac.RoleBindings(namespace).Get(ctx, name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				_, err = rbac.RoleBindings(namespace).Create(ctx, &rbacv1.RoleBinding{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+					Subjects:   subjects,
+					RoleRef:    roleRef,
+				}, metav1.CreateOptions{FieldManager: defaultFieldManager})
+				return true, false, err
+			}
+			if err != nil || reflect.DeepEqual(existing.Subjects, subjects) {
+				return false, false, err
+			}
+			existing.Subjects = subjects
+			existing.Labels = labels
+			_, err = rbac.RoleBindings(namespace).Update(ctx, existing, metav1.UpdateOptions{FieldManager: defaultFieldManager})
+			return false, true, err
+		})
+	}
+
+	u.deleteStale(ctx, client, wanted, result)
+}
+
+// deleteStale removes managed objects whose grants were revoked
+func (u *k8sRBACUsecase) deleteStale(ctx context.Context, client *k8s.Client, wanted map[string]bool, result *domain.K8sRBACSyncResult) {
+	rbac := client.Clientset().RbacV1()
+	selector := metav1.ListOptions{LabelSelector: rbacManagedLabel + "=true"}
+	deleteOpts := metav1.DeleteOptions{}
+
+	// Bindings go first so access is revoked before the roles disappear
+	if list, err := rbac.ClusterRoleBindings().List(ctx, selector); err != nil {
+		result.Errors = append(result.Errors, "list ClusterRoleBindings: "+err.Error())
+	} else {
+		for _, item := range list.Items {
+			if key := "ClusterRoleBinding/" + item.Name; !wanted[key] {
+				u.recordDelete(result, key, rbac.ClusterRoleBindings().Delete(ctx, item.Name, deleteOpts))
+			}
+		}
+	}
+	if list, err := rbac.RoleBindings("").List(ctx, selector); err != nil {
+		result.Errors = append(result.Errors, "list RoleBindings: "+err.Error())
+	} else {
+		for _, item := range list.Items {
+			if key := "RoleBinding/" + item.Namespace + "/" + item.Name; !wanted[key] {
+				u.recordDelete(result, key, rbac.RoleBindings(item.Namespace).Delete(ctx, item.Name, deleteOpts))
+			}
+		}
+	}
+	if list, err := rbac.ClusterRoles().List(ctx, selector); err != nil {
+		result.Errors = append(result.Errors, "list ClusterRoles: "+err.Error())
+	} else {
+		for _, item := range list.Items {
+			if key := "ClusterRole/" + item.Name; !wanted[key] {
+				u.recordDelete(result, key, rbac.ClusterRoles().Delete(ctx, item.Name, deleteOpts))
+			}
+		}
+	}
+	if list, err := rbac.Roles("").List(ctx, selector); err != nil {
+		result.Errors = append(result.Errors, "list Roles: "+err.Error())
+	} else {
+		for _, item := range list.Items {
+			if key := "Role/" + item.Namespace + "/" + item.Name; !wanted[key] {
+				u.recordDelete(result, key, rbac.Roles(item.Namespace).Delete(ctx, item.Name, deleteOpts))
+			}
+		}
+	}
+}
+
+// record runs one create-or-update step and files its outcome in the result
+func (u *k8sRBACUsecase) record(result *domain.K8sRBACSyncResult, key string, apply func() (created, updated bool, err error)) {
+	created, updated, err := apply()
+	switch {
+	case err != nil:
+		result.Errors = append(result.Errors, key+": "+err.Error())
+	case created:
+		result.Created = append(result.Created, key)
+	case updated:
+		result.Updated = append(result.Updated, key)
+	}
+}
+
+func (u *k8sRBACUsecase) recordDelete(result *domain.K8sRBACSyncResult, key string, err error) {
+	if err != nil && !apierrors.IsNotFound(err) {
+		result.Errors = append(result.Errors, key+": "+err.Error())
+		return
+	}
+	result.Deleted = append(result.Deleted, key)
+}
+
+// rulesFromPermissions converts the Kubernetes permissions of a role, e.g. "k8s.deployment.create"
+func rulesFromPermissions(permissions []domain.Permission) []domain.K8sRBACRule {
+	var rules []domain.K8sRBACRule
+	for _, perm := range permissions {
+		if perm.Resource != k8sPermissionResource {
+			continue
+		}
+
+		apiGroups, resources := []string{"*"}, []string{"*"}
+		if perm.SubResource != "" && perm.SubResource != "*" {
+			sub, ok := k8sSubResources[strings.ToLower(perm.SubResource)]
+			if !ok {
+				continue
+			}
+			apiGroups, resources = sub.apiGroups, sub.resources
+		}
+		rules = append(rules, rulesFromActions([]string{perm.Action}, apiGroups, resources)...)
+	}
+	return rules
+}
+
+// rulesFromActions maps platform actions on the given resources to policy rules
+func rulesFromActions(actions []string, apiGroups, resources []string) []domain.K8sRBACRule {
+	var rules []domain.K8sRBACRule
+	var verbs []string
+	for _, action := range actions {
+		action = strings.ToLower(action)
+		if special, ok := k8sSpecialActionRules[action]; ok {
+			rules = append(rules, special...)
+			continue
+		}
+		verbs = append(verbs, k8sActionVerbs[action]...)
+	}
+	if len(verbs) > 0 {
+		rules = append(rules, domain.K8sRBACRule{APIGroups: apiGroups, Resources: resources, Verbs: verbs})
+	}
+	return rules
+}
+
+// normalizeRules sorts and de-duplicates rules so unchanged grants produce identical objects
+func normalizeRules(rules []domain.K8sRBACRule) []domain.K8sRBACRule {
+	seen := make(map[string]bool)
+	var result []domain.K8sRBACRule
+	for _, rule := range rules {
+		rule.APIGroups = sortedUnique(rule.APIGroups)
+		rule.Resources = sortedUnique(rule.Resources)
+		rule.Verbs = sortedUnique(rule.Verbs)
+		key := strings.Join(rule.APIGroups, ",") + "|" + strings.Join(rule.Resources, ",") + "|" + strings.Join(rule.Verbs, ",")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, rule)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if x, y := strings.Join(a.APIGroups, ","), strings.Join(b.APIGroups, ","); x != y {
+			return x < y
+		}
+		if x, y := strings.Join(a.Resources, ","), strings.Join(b.Resources, ","); x != y {
+			return x < y
+		}
+		return strings.Join(a.Verbs, ",") < strings.Join(b.Verbs, ",")
+	})
+	return result
+}
+
+func toPolicyRules(rules []domain.K8sRBACRule) []rbacv1.PolicyRule {
+	policyRules := make([]rbacv1.PolicyRule, 0, len(rules))
+	for _, rule := range rules {
+		policyRules = append(policyRules, rbacv1.PolicyRule{
+			APIGroups: rule.APIGroups,
+			Resources: rule.Resources,
+			Verbs:     rule.Verbs,
+		})
+	}
+	return policyRules
+}
+
+// environmentMatches reports whether a grant scoped to grantEnv applies to a cluster in clusterEnv
+func environmentMatches(grantEnv, clusterEnv *string) bool {
+	if grantEnv == nil {
+		return true
+	}
+	return clusterEnv != nil && *grantEnv == *clusterEnv
+}
+
+func rbacObjectName(userID string) string {
+	return "einfra:user:" + userID
+}
+
+func sortedUnique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,236 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/repository"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MockAuthorizationRepository mocks the grant listings the RBAC sync reads
+type MockAuthorizationRepository struct {
+	repository.AuthorizationRepository
+	mock.Mock
+}
+
+func (m *MockAuthorizationRepository) ListUsersWithGlobalRole(ctx context.Context) ([]*domain.User, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*domain.User), args.Error(1)
+}
+
+func (m *MockAuthorizationRepository) ListEnvironmentRoleAssignments(ctx context.Context) ([]*domain.UserEnvironmentRole, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*domain.UserEnvironmentRole), args.Error(1)
+}
+
+func (m *MockAuthorizationRepository) GetResourcePermissions(ctx context.Context, resourceType domain.ResourceType, resourceID string) ([]*domain.ResourcePermission, error) {
+	args := m.Called(ctx, resourceType, resourceID)
+	return args.Get(0).([]*domain.ResourcePermission), args.Error(1)
+}
+
+func (m *MockAuthorizationRepository) ListResourcePermissionsByType(ctx context.Context, resourceType domain.ResourceType) ([]*domain.ResourcePermission, error) {
+	args := m.Called(ctx, resourceType)
+	return args.Get(0).([]*domain.ResourcePermission), args.Error(1)
+}
+
+// newRBACGrants registers grants of every kind, some of which do not apply to cluster-1 in env-prod
+func newRBACGrants() *MockAuthorizationRepository {
+	prod, staging := "env-prod", "env-staging"
+	expired := time.Now().Add(-time.Hour)
+	alice := &domain.User{ID: "u-alice", Username: "alice", IsActive: true, Role: &domain.Role{
+		Name:     "developer",
+		IsActive: true,
+		Permissions: []domain.Permission{
+			{Resource: "k8s", SubResource: "deployment", Action: "create"},
+			{Resource: "k8s", SubResource: "pod", Action: "logs"},
+			{Resource: "k8s", SubResource: "unknown", Action: "read"},
+			{Resource: "server", Action: "create"},
+		},
+	}}
+	readOnly := &domain.Role{Name: "viewer", IsActive: true, Permissions: []domain.Permission{{Resource: "k8s", Action: "read"}}}
+	bob := &domain.User{ID: "u-bob", Username: "bob", IsActive: true}
+	carol := &domain.User{ID: "u-carol", Username: "carol", IsActive: true}
+	dave := &domain.User{ID: "u-dave", Username: "dave", IsActive: true}
+	erin := &domain.User{ID: "u-erin", Username: "erin"} // Deactivated
+
+	authRepo := new(MockAuthorizationRepository)
+	authRepo.On("ListUsersWithGlobalRole", mock.Anything).Return([]*domain.User{alice}, nil)
+	authRepo.On("ListEnvironmentRoleAssignments", mock.Anything).Return([]*domain.UserEnvironmentRole{
+		{User: bob, EnvironmentID: &staging, Role: readOnly},
+		{User: carol, EnvironmentID: &prod, Role: readOnly},
+		{User: erin, EnvironmentID: &prod, Role: readOnly},
+	}, nil)
+	authRepo.On("GetResourcePermissions", mock.Anything, domain.ResourceTypeK8sCluster, "cluster-1").Return([]*domain.ResourcePermission{
+		{ID: "perm-expired", User: bob, Actions: []string{"manage"}, ExpiresAt: &expired},
+		{ID: "perm-erin", User: erin, Actions: []string{"manage"}},
+	}, nil)
+	authRepo.On("ListResourcePermissionsByType", mock.Anything, domain.ResourceTypeK8sNamespace).Return([]*domain.ResourcePermission{
+		{ID: "perm-shop", User: dave, ResourceID: "cluster-1/shop", Actions: []string{"read", "exec"}},
+		{ID: "perm-other", User: dave, ResourceID: "cluster-2/shop", Actions: []string{"manage"}},
+	}, nil)
+	return authRepo
+}
+
+func prodK8sClusterRepo() *MockK8sClusterRepository {
+	prod := "env-prod"
+	repo := new(MockK8sClusterRepository)
+	repo.On("GetByID", mock.Anything, "cluster-1").Return(&domain.K8sCluster{
+		ID:            "cluster-1",
+		Name:          "prod",
+		IsActive:      true,
+		ConfigPath:    "/etc/kubeconfig",
+		EnvironmentID: &prod,
+	}, nil)
+	return repo
+}
+
+// TestK8sRBACSync tests mapping platform grants to Kubernetes RBAC objects
+func TestK8sRBACSync(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success - Dry run maps grants to bindings", func(t *testing.T) {
+		uc := usecase.NewK8sRBACUsecase(newRBACGrants(), prodK8sClusterRepo(), nil)
+
+		result, err := uc.Sync(ctx, "cluster-1", true)
+		require.NoError(t, err)
+		assert.True(t, result.DryRun)
+		assert.Empty(t, result.Created)
+		assert.Equal(t, []domain.K8sRBACBinding{
+			{
+				UserID:   "u-alice",
+				Username: "alice",
+				Subject:  "einfra:alice",
+				Rules: []domain.K8sRBACRule{
+					{APIGroups: []string{""}, Resources: []string{"pods/log"}, Verbs: []string{"get"}},
+					{APIGroups: []string{"apps"}, Resources: []string{"deployments", "deployments/scale"}, Verbs: []string{"create"}},
+				},
+				Sources: []string{"role:developer"},
+			},
+			{
+				UserID:   "u-carol",
+				Username: "carol",
+				Subject:  "einfra:carol",
+				Rules: []domain.K8sRBACRule{
+					{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"get", "list", "watch"}},
+				},
+				Sources: []string{"environment_role:viewer"},
+			},
+			{
+				UserID:    "u-dave",
+				Username:  "dave",
+				Subject:   "einfra:dave",
+				Namespace: "shop",
+				Rules: []domain.K8sRBACRule{
+					{APIGroups: []string{""}, Resources: []string{"pods/attach", "pods/exec"}, Verbs: []string{"create", "get"}},
+					{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"get", "list", "watch"}},
+				},
+				Sources: []string{"resource_permission:perm-shop"},
+			},
+		}, result.Bindings)
+	})
+
+	t.Run("Success - Reconciles managed objects only", func(t *testing.T) {
+		managed := map[string]string{"einfra.io/rbac-sync": "true"}
+		_, clients, clientset := newFakeK8sCluster(
+			&rbacv1.Role{
+				ObjectMeta: metav1.ObjectMeta{Name: "einfra:user:u-dave", Namespace: "shop", Labels: managed},
+				Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+			},
+			&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "einfra:user:u-gone", Labels: managed}},
+			&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "einfra:user:u-gone", Labels: managed}},
+			&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "cluster-admin"}},
+		)
+		uc := usecase.NewK8sRBACUsecase(newRBACGrants(), prodK8sClusterRepo(), clients)
+
+		result, err := uc.Sync(ctx, "cluster-1", false)
+		require.NoError(t, err)
+		assert.Empty(t, result.Errors)
+		assert.Equal(t, []string{
+			"ClusterRole/einfra:user:u-alice",
+			"ClusterRoleBinding/einfra:user:u-alice",
+			"ClusterRole/einfra:user:u-carol",
+			"ClusterRoleBinding/einfra:user:u-carol",
+			"RoleBinding/shop/einfra:user:u-dave",
+		}, result.Created)
+		assert.Equal(t, []string{"Role/shop/einfra:user:u-dave"}, result.Updated)
+		assert.Equal(t, []string{"ClusterRoleBinding/einfra:user:u-gone", "ClusterRole/einfra:user:u-gone"}, result.Deleted)
+
+		binding, err := clientset.RbacV1().RoleBindings("shop").Get(ctx, "einfra:user:u-dave", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "einfra:dave", binding.Subjects[0].Name)
+		assert.Equal(t, rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "einfra:user:u-dave"}, binding.RoleRef)
+		assert.Equal(t, "u-dave", binding.Labels["einfra.io/user-id"])
+
+		_, err = clientset.RbacV1().ClusterRoles().Get(ctx, "cluster-admin", metav1.GetOptions{})
+		assert.NoError(t, err)
+
+		// A second sync without grant changes leaves everything in place
+		result, err = uc.Sync(ctx, "cluster-1", false)
+		require.NoError(t, err)
+		assert.Empty(t, result.Created)
+		assert.Empty(t, result.Updated)
+		assert.Empty(t, result.Deleted)
+	})
+
+	t.Run("Error - Missing cluster ID", func(t *testing.T) {
+		uc := usecase.NewK8sRBACUsecase(new(MockAuthorizationRepository), new(MockK8sClusterRepository), nil)
+
+		_, err := uc.Sync(ctx, "", true)
+		assert.Error(t, err)
+	})
+}
+
+// TestK8sRBACSyncAll tests that every page of active clusters is reconciled
+func TestK8sRBACSyncAll(t *testing.T) {
+	ctx := context.Background()
+	active := true
+	page := func(n, from, to int) ([]*domain.K8sCluster, domain.K8sClusterFilter) {
+		var clusters []*domain.K8sCluster
+		for i := from; i < to; i++ {
+			clusters = append(clusters, &domain.K8sCluster{ID: fmt.Sprintf("cluster-%d", i), Name: fmt.Sprintf("c%d", i)})
+		}
+		return clusters, domain.K8sClusterFilter{IsActive: &active, Page: n, PageSize: 100}
+	}
+
+	clusterRepo := new(MockK8sClusterRepository)
+	first, firstFilter := page(1, 0, 100)
+	second, secondFilter := page(2, 100, 150)
+	clusterRepo.On("List", ctx, firstFilter).Return(first, int64(150), nil).Once()
+	clusterRepo.On("List", ctx, secondFilter).Return(second, int64(150), nil).Once()
+	// Each cluster is looked up by its sync; the lookup failing only skips that cluster
+	clusterRepo.On("GetByID", ctx, mock.AnythingOfType("string")).Return(nil, errors.New("gone"))
+
+	uc := usecase.NewK8sRBACUsecase(new(MockAuthorizationRepository), clusterRepo, nil)
+	require.NoError(t, uc.SyncAll(ctx))
+	clusterRepo.AssertExpectations(t)
+	clusterRepo.AssertCalled(t, "GetByID", ctx, "cluster-149")
+	clusterRepo.AssertNumberOfCalls(t, "GetByID", 150)
+}
+
+// TestImpersonatedClusterWithoutUser tests that clusters impersonating users refuse requests
+// that carry no user instead of acting as the platform
+func TestImpersonatedClusterWithoutUser(t *testing.T) {
+	_, clients, _ := newFakeK8sCluster(apiDeployment())
+	clusterRepo := new(MockK8sClusterRepository)
+	clusterRepo.On("GetByID", mock.Anything, "cluster-1").Return(&domain.K8sCluster{
+		ID:               "cluster-1",
+		Name:             "prod",
+		IsActive:         true,
+		ConfigPath:       "/etc/kubeconfig",
+		ImpersonateUsers: true,
+	}, nil)
+	uc := usecase.NewK8sRolloutUsecase(clusterRepo, clients, nil)
+
+	_, err := uc.GetHistory(context.Background(), "cluster-1", "shop", "api")
+	assert.ErrorContains(t, err, "no authenticated user")
+}
@@ -0,0 +1,395 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// Annotations maintained by the Deployment controller and kubectl
+	revisionAnnotation    = "deployment.kubernetes.io/revision"
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+	// rolloutStatusPollInterval is how often the rollout status is checked while waiting
+	rolloutStatusPollInterval = 2 * time.Second
+	// maxRolloutStatusTimeout bounds how long a status request may wait
+	maxRolloutStatusTimeout = 10 * time.Minute
+)
+
+type k8sRolloutUsecase struct {
+	k8sRepo           domain.K8sClusterRepository
+	clients           *k8s.ClientManager
+	deploymentUsecase domain.ImageDeploymentUsecase
+}
+
+// NewK8sRolloutUsecase creates a new Deployment rollout use case instance
+func NewK8sRolloutUsecase(
+	k8sRepo domain.K8sClusterRepository,
+	clients *k8s.ClientManager,
+	deploymentUsecase domain.ImageDeploymentUsecase,
+) domain.K8sRolloutUsecase {
+	return &k8sRolloutUsecase{
+		k8sRepo:           k8sRepo,
+		clients:           clients,
+		deploymentUsecase: deploymentUsecase,
+	}
+}
+
+// GetHistory lists the ReplicaSet revisions of a Deployment, newest first
+func (u *k8sRolloutUsecase) GetHistory(ctx context.Context, clusterID, namespace, name string) ([]*domain.K8sRolloutRevision, error) {
+	client, deployment, err := u.getDeployment(ctx, clusterID, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	replicaSets, err := u.listReplicaSets(ctx, client, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	currentRevision := parseRevision(deployment.Annotations)
+	revisions := make([]*domain.K8sRolloutRevision, 0, len(replicaSets))
+	var previousTemplate string
+	for _, rs := range replicaSets {
+		template := podTemplateYAML(rs.Spec.Template)
+		revision := &domain.K8sRolloutRevision{
+			Revision:    parseRevision(rs.Annotations),
+			ReplicaSet:  rs.Name,
+			Images:      containerImages(rs.Spec.Template.Spec),
+			ChangeCause: rs.Annotations[changeCauseAnnotation],
+			CreatedAt:   rs.CreationTimestamp.Time,
+		}
+		if rs.Spec.Replicas != nil {
+			revision.Replicas = *rs.Spec.Replicas
+		}
+		revision.Current = revision.Revision == currentRevision
+		if previousTemplate != "" {
+			revision.Diff, _ = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(previousTemplate),
+				B:        difflib.SplitLines(template),
+				FromFile: "previous",
+				ToFile:   "revision " + strconv.FormatInt(revision.Revision, 10),
+				Context:  3,
+			})
+		}
+		previousTemplate = template
+		revisions = append(revisions, revision)
+	}
+
+	// Built oldest first for the diffs, returned newest first
+	for i, j := 0, len(revisions)-1; i < j; i, j = i+1, j-1 {
+		revisions[i], revisions[j] = revisions[j], revisions[i]
+	}
+	return revisions, nil
+}
+
+// Rollback replaces the Deployment's pod template with the one of an earlier revision
+func (u *k8sRolloutUsecase) Rollback(ctx context.Context, clusterID, namespace, name string, req domain.K8sRollbackRequest, user string) (*domain.K8sRolloutRevision, error) {
+	client, deployment, err := u.getDeployment(ctx, clusterID, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if deployment.Spec.Paused {
+		return nil, errors.New("cannot roll back a paused deployment, resume it first")
+	}
+
+	replicaSets, err := u.listReplicaSets(ctx, client, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	currentRevision := parseRevision(deployment.Annotations)
+	var target *appsv1.ReplicaSet
+	for i := len(replicaSets) - 1; i >= 0; i-- {
+		revision := parseRevision(replicaSets[i].Annotations)
+		if req.Revision == 0 && revision < currentRevision {
+			target = replicaSets[i]
+			break
+		}
+		if req.Revision != 0 && revision == req.Revision {
+			target = replicaSets[i]
+			break
+		}
+	}
+	if target == nil {
+		if req.Revision == 0 {
+			return nil, errors.New("no previous revision to roll back to")
+		}
+		return nil, fmt.Errorf("revision %d not found", req.Revision)
+	}
+	if parseRevision(target.Annotations) == currentRevision {
+		return nil, fmt.Errorf("revision %d is already the current revision", currentRevision)
+	}
+
+	template := target.Spec.Template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+
+	operations := []map[string]interface{}{
+		{"op": "replace", "path": "/spec/template", "value": template},
+	}
+	if user != "" {
+		// Lets the image deployment tracker attribute the image change to the user
+		if deployment.Annotations == nil {
+			operations = append(operations, map[string]interface{}{"op": "add", "path": "/metadata/annotations", "value": map[string]string{}})
+		}
+		operations = append(operations,
+			map[string]interface{}{"op": "add", "path": "/metadata/annotations/" + jsonPointerEscape(changedByAnnotation), "value": user},
+			map[string]interface{}{"op": "add", "path": "/metadata/annotations/" + jsonPointerEscape(changedAtAnnotation), "value": time.Now().UTC().Format(time.RFC3339)},
+		)
+	}
+	patch, err := json.Marshal(operations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build rollback patch: %w", err)
+	}
+	if _, err := client.Clientset().AppsV1().Deployments(namespace).Patch(ctx, name, types.JSONPatchType, patch, metav1.PatchOptions{FieldManager: defaultFieldManager}); err != nil {
+		return nil, fmt.Errorf("failed to roll back deployment: %w", err)
+	}
+
+	images := containerImages(template.Spec)
+	revision := &domain.K8sRolloutRevision{
+		Revision:    parseRevision(target.Annotations),
+		ReplicaSet:  target.Name,
+		Images:      images,
+		ChangeCause: target.Annotations[changeCauseAnnotation],
+		CreatedAt:   target.CreationTimestamp.Time,
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = "to revision " + strconv.FormatInt(revision.Revision, 10)
+	}
+	if err := u.deploymentUsecase.RecordRolloutAction(ctx, clusterID, namespace, name, images, domain.K8sRolloutActionRollback, user, reason); err != nil {
+		return revision, fmt.Errorf("rolled back but failed to record history: %w", err)
+	}
+	return revision, nil
+}
+
+// Restart triggers a rolling restart by stamping the pod template, like kubectl rollout restart
+func (u *k8sRolloutUsecase) Restart(ctx context.Context, clusterID, namespace, name, user string) error {
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						restartedAtAnnotation: time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	}
+	return u.patchAndRecord(ctx, clusterID, namespace, name, patch, domain.K8sRolloutActionRestart, user)
+}
+
+// Pause stops the Deployment controller from rolling out template changes
+func (u *k8sRolloutUsecase) Pause(ctx context.Context, clusterID, namespace, name, user string) error {
+	patch := map[string]interface{}{"spec": map[string]interface{}{"paused": true}}
+	return u.patchAndRecord(ctx, clusterID, namespace, name, patch, domain.K8sRolloutActionPause, user)
+}
+
+// Resume continues a paused rollout
+func (u *k8sRolloutUsecase) Resume(ctx context.Context, clusterID, namespace, name, user string) error {
+	patch := map[string]interface{}{"spec": map[string]interface{}{"paused": nil}}
+	return u.patchAndRecord(ctx, clusterID, namespace, name, patch, domain.K8sRolloutActionResume, user)
+}
+
+// GetStatus reports the rollout progress, polling until it settles when timeout is positive
+func (u *k8sRolloutUsecase) GetStatus(ctx context.Context, clusterID, namespace, name string, timeout time.Duration) (*domain.K8sRolloutStatus, error) {
+	client, deployment, err := u.getDeployment(ctx, clusterID, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	status := rolloutStatus(deployment)
+	if timeout <= 0 || status.Complete || status.Failed || status.Paused {
+		return status, nil
+	}
+	if timeout > maxRolloutStatusTimeout {
+		timeout = maxRolloutStatusTimeout
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(rolloutStatusPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			status.TimedOut = true
+			status.Message = fmt.Sprintf("timed out waiting for rollout: %s", status.Message)
+			return status, nil
+		case <-ticker.C:
+			deployment, err = client.Clientset().AppsV1().Deployments(namespace).Get(waitCtx, name, metav1.GetOptions{})
+			if err != nil {
+				if waitCtx.Err() != nil {
+					continue
+				}
+				return nil, fmt.Errorf("failed to get deployment: %w", err)
+			}
+			status = rolloutStatus(deployment)
+			if status.Complete || status.Failed || status.Paused {
+				return status, nil
+			}
+		}
+	}
+}
+
+// patchAndRecord applies a strategic merge patch to a Deployment and records the action
+func (u *k8sRolloutUsecase) patchAndRecord(ctx context.Context, clusterID, namespace, name string, patch map[string]interface{}, action, user string) error {
+	client, _, err := u.getDeployment(ctx, clusterID, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("failed to build patch: %w", err)
+	}
+	deployment, err := client.Clientset().AppsV1().Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, data, metav1.PatchOptions{FieldManager: defaultFieldManager})
+	if err != nil {
+		return fmt.Errorf("failed to %s deployment: %w", action, err)
+	}
+
+	if err := u.deploymentUsecase.RecordRolloutAction(ctx, clusterID, namespace, name, containerImages(deployment.Spec.Template.Spec), action, user, ""); err != nil {
+		return fmt.Errorf("deployment updated but failed to record history: %w", err)
+	}
+	return nil
+}
+
+func (u *k8sRolloutUsecase) getDeployment(ctx context.Context, clusterID, namespace, name string) (*k8s.Client, *appsv1.Deployment, error) {
+	if clusterID == "" || namespace == "" || name == "" {
+		return nil, nil, errors.New("cluster ID, namespace, and deployment name are required")
+	}
+
+	client, err := getK8sClient(ctx, u.k8sRepo, u.clients, clusterID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deployment, err := client.Clientset().AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+	return client, deployment, nil
+}
+
+// listReplicaSets returns the ReplicaSets controlled by a Deployment, oldest revision first
+func (u *k8sRolloutUsecase) listReplicaSets(ctx context.Context, client *k8s.Client, deployment *appsv1.Deployment) ([]*appsv1.ReplicaSet, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deployment selector: %w", err)
+	}
+
+	list, err := client.Clientset().AppsV1().ReplicaSets(deployment.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replica sets: %w", err)
+	}
+
+	var replicaSets []*appsv1.ReplicaSet
+	for i := range list.Items {
+		rs := &list.Items[i]
+		if owner := metav1.GetControllerOf(rs); owner != nil && owner.UID == deployment.UID {
+			replicaSets = append(replicaSets, rs)
+		}
+	}
+	sort.Slice(replicaSets, func(i, j int) bool {
+		return parseRevision(replicaSets[i].Annotations) < parseRevision(replicaSets[j].Annotations)
+	})
+	return replicaSets, nil
+}
+
+// rolloutStatus evaluates a Deployment the same way kubectl rollout status does
+func rolloutStatus(deployment *appsv1.Deployment) *domain.K8sRolloutStatus {
+	status := &domain.K8sRolloutStatus{
+		Namespace:         deployment.Namespace,
+		Name:              deployment.Name,
+		Revision:          parseRevision(deployment.Annotations),
+		Paused:            deployment.Spec.Paused,
+		Replicas:          deployment.Status.Replicas,
+		UpdatedReplicas:   deployment.Status.UpdatedReplicas,
+		AvailableReplicas: deployment.Status.AvailableReplicas,
+	}
+
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+
+	switch {
+	case deployment.Generation > deployment.Status.ObservedGeneration:
+		status.Message = "waiting for deployment spec update to be observed"
+	case hasProgressDeadlineExceeded(deployment):
+		status.Failed = true
+		status.Message = fmt.Sprintf("deployment %q exceeded its progress deadline", deployment.Name)
+	case deployment.Spec.Paused:
+		status.Message = fmt.Sprintf("deployment %q is paused", deployment.Name)
+	case deployment.Status.UpdatedReplicas < desired:
+		status.Message = fmt.Sprintf("%d out of %d new replicas have been updated", deployment.Status.UpdatedReplicas, desired)
+	case deployment.Status.Replicas > deployment.Status.UpdatedReplicas:
+		status.Message = fmt.Sprintf("%d old replicas are pending termination", deployment.Status.Replicas-deployment.Status.UpdatedReplicas)
+	case deployment.Status.AvailableReplicas < deployment.Status.UpdatedReplicas:
+		status.Message = fmt.Sprintf("%d of %d updated replicas are available", deployment.Status.AvailableReplicas, deployment.Status.UpdatedReplicas)
+	default:
+		status.Complete = true
+		status.Message = fmt.Sprintf("deployment %q successfully rolled out", deployment.Name)
+	}
+	return status
+}
+
+func hasProgressDeadlineExceeded(deployment *appsv1.Deployment) bool {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRevision(annotations map[string]string) int64 {
+	revision, _ := strconv.ParseInt(annotations[revisionAnnotation], 10, 64)
+	return revision
+}
+
+func containerImages(spec corev1.PodSpec) map[string]string {
+	images := make(map[string]string, len(spec.Containers))
+	for _, container := range spec.Containers {
+		images[container.Name] = container.Image
+	}
+	return images
+}
+
+// podTemplateYAML renders a pod template for diffing, without the per-ReplicaSet hash label
+func podTemplateYAML(template corev1.PodTemplateSpec) string {
+	clean := template.DeepCopy()
+	delete(clean.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	data, err := yaml.Marshal(clean)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// jsonPointerEscape escapes a key for use in a JSON patch path
+func jsonPointerEscape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
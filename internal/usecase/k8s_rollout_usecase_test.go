@@ -0,0 +1,229 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newFakeK8sCluster registers cluster-1 backed by a fake clientset holding objects
+func newFakeK8sCluster(objects ...runtime.Object) (*MockK8sClusterRepository, *k8s.ClientManager, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(objects...)
+	clients := k8s.NewClientManager()
+	clients.Set("cluster-1", k8s.NewClientFromInterfaces(clientset, nil))
+
+	repo := new(MockK8sClusterRepository)
+	repo.On("GetByID", mock.Anything, "cluster-1").Return(&domain.K8sCluster{
+		ID:         "cluster-1",
+		Name:       "prod",
+		IsActive:   true,
+		ConfigPath: "/etc/kubeconfig",
+	}, nil)
+	return repo, clients, clientset
+}
+
+func apiDeployment() *appsv1.Deployment {
+	replicas := int32(2)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api",
+			Namespace:   "shop",
+			UID:         "deploy-uid",
+			Annotations: map[string]string{"deployment.kubernetes.io/revision": "2"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "api"}},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "api", Image: "harbor.example.com/shop/api:v2"},
+				}},
+			},
+		},
+	}
+}
+
+// apiReplicaSet returns a ReplicaSet of the api Deployment for a revision running image
+func apiReplicaSet(revision, image string, replicas int32, ownerUID string) *appsv1.ReplicaSet {
+	controller := true
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api-" + revision + "-" + ownerUID,
+			Namespace: "shop",
+			Labels:    map[string]string{"app": "api", appsv1.DefaultDeploymentUniqueLabelKey: "hash-" + revision},
+			Annotations: map[string]string{
+				"deployment.kubernetes.io/revision": revision,
+				"kubernetes.io/change-cause":        "deploy " + image,
+			},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "apps/v1", Kind: "Deployment", Name: "api", UID: types.UID(ownerUID), Controller: &controller,
+			}},
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "api", appsv1.DefaultDeploymentUniqueLabelKey: "hash-" + revision}},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "api", Image: image},
+				}},
+			},
+		},
+	}
+}
+
+func apiRolloutObjects() []runtime.Object {
+	return []runtime.Object{
+		apiDeployment(),
+		apiReplicaSet("1", "harbor.example.com/shop/api:v1", 0, "deploy-uid"),
+		apiReplicaSet("2", "harbor.example.com/shop/api:v2", 2, "deploy-uid"),
+		// Same labels but controlled by another Deployment
+		apiReplicaSet("7", "harbor.example.com/shop/api:v7", 1, "other-uid"),
+	}
+}
+
+// TestGetRolloutHistory tests listing the revisions of a Deployment with pod template diffs
+func TestGetRolloutHistory(t *testing.T) {
+	clusterRepo, clients, _ := newFakeK8sCluster(apiRolloutObjects()...)
+	uc := usecase.NewK8sRolloutUsecase(clusterRepo, clients, nil)
+
+	revisions, err := uc.GetHistory(context.Background(), "cluster-1", "shop", "api")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+
+	assert.Equal(t, int64(2), revisions[0].Revision)
+	assert.True(t, revisions[0].Current)
+	assert.Equal(t, int32(2), revisions[0].Replicas)
+	assert.Equal(t, map[string]string{"api": "harbor.example.com/shop/api:v2"}, revisions[0].Images)
+	assert.Equal(t, "deploy harbor.example.com/shop/api:v2", revisions[0].ChangeCause)
+	assert.Contains(t, revisions[0].Diff, "--- previous\n+++ revision 2\n")
+	assert.Contains(t, revisions[0].Diff, "-  - image: harbor.example.com/shop/api:v1\n")
+	assert.Contains(t, revisions[0].Diff, "+  - image: harbor.example.com/shop/api:v2\n")
+
+	assert.Equal(t, int64(1), revisions[1].Revision)
+	assert.False(t, revisions[1].Current)
+	assert.Empty(t, revisions[1].Diff)
+}
+
+// TestRollback tests rolling a Deployment back to an earlier revision
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success - Previous revision", func(t *testing.T) {
+		clusterRepo, clients, clientset := newFakeK8sCluster(apiRolloutObjects()...)
+		deploymentRepo := new(MockImageDeploymentRepository)
+		deploymentRepo.On("GetActiveDeployment", mock.Anything, "cluster-1", "shop", "api", "api").Return(&domain.ImageDeployment{
+			ID:              "dep-2",
+			ImageRepository: "harbor.example.com/shop/api",
+			ImageTag:        "v2",
+			Status:          "active",
+		}, nil)
+		deploymentRepo.On("UpdateStatus", mock.Anything, "dep-2", "rolled_back").Return(nil).Once()
+		deploymentRepo.On("Create", mock.Anything, mock.MatchedBy(func(d *domain.ImageDeployment) bool {
+			return d.ImageTag == "v1" && d.DeployedBy == "alice"
+		})).Return(nil).Once()
+		deploymentRepo.On("CreateHistory", mock.Anything, mock.MatchedBy(func(h *domain.ImageDeploymentHistory) bool {
+			return h.PreviousTag == "v2" && h.NewTag == "v1" && h.ChangedBy == "alice" && h.Reason == "rollback: to revision 1"
+		})).Return(nil).Once()
+
+		uc := usecase.NewK8sRolloutUsecase(clusterRepo, clients, usecase.NewImageDeploymentUsecase(deploymentRepo, nil, nil))
+		revision, err := uc.Rollback(ctx, "cluster-1", "shop", "api", domain.K8sRollbackRequest{}, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), revision.Revision)
+		assert.Equal(t, map[string]string{"api": "harbor.example.com/shop/api:v1"}, revision.Images)
+		deploymentRepo.AssertExpectations(t)
+
+		deployment, err := clientset.AppsV1().Deployments("shop").Get(ctx, "api", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "harbor.example.com/shop/api:v1", deployment.Spec.Template.Spec.Containers[0].Image)
+		assert.NotContains(t, deployment.Spec.Template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+		assert.Equal(t, "alice", deployment.Annotations["einfra.io/changed-by"])
+	})
+
+	t.Run("Error - Current revision", func(t *testing.T) {
+		clusterRepo, clients, _ := newFakeK8sCluster(apiRolloutObjects()...)
+		uc := usecase.NewK8sRolloutUsecase(clusterRepo, clients, nil)
+
+		_, err := uc.Rollback(ctx, "cluster-1", "shop", "api", domain.K8sRollbackRequest{Revision: 2}, "alice")
+		assert.ErrorContains(t, err, "already the current revision")
+	})
+
+	t.Run("Error - Revision of another Deployment", func(t *testing.T) {
+		clusterRepo, clients, _ := newFakeK8sCluster(apiRolloutObjects()...)
+		uc := usecase.NewK8sRolloutUsecase(clusterRepo, clients, nil)
+
+		_, err := uc.Rollback(ctx, "cluster-1", "shop", "api", domain.K8sRollbackRequest{Revision: 7}, "alice")
+		assert.ErrorContains(t, err, "revision 7 not found")
+	})
+
+	t.Run("Error - Paused deployment", func(t *testing.T) {
+		deployment := apiDeployment()
+		deployment.Spec.Paused = true
+		clusterRepo, clients, _ := newFakeK8sCluster(deployment)
+		uc := usecase.NewK8sRolloutUsecase(clusterRepo, clients, nil)
+
+		_, err := uc.Rollback(ctx, "cluster-1", "shop", "api", domain.K8sRollbackRequest{}, "alice")
+		assert.ErrorContains(t, err, "paused")
+	})
+}
+
+// TestRolloutActionRecordsHistory tests that restarting, pausing and resuming a Deployment writes a
+// history row for its image
+func TestRolloutActionRecordsHistory(t *testing.T) {
+	for _, action := range []string{domain.K8sRolloutActionRestart, domain.K8sRolloutActionPause, domain.K8sRolloutActionResume} {
+		t.Run(action, func(t *testing.T) {
+			clusterRepo, clients, clientset := newFakeK8sCluster(apiDeployment())
+			deploymentRepo := new(MockImageDeploymentRepository)
+			deploymentRepo.On("GetActiveDeployment", mock.Anything, "cluster-1", "shop", "api", "api").Return(&domain.ImageDeployment{
+				ID:              "dep-1",
+				ImageRepository: "harbor.example.com/shop/api",
+				ImageTag:        "v2",
+				Status:          "active",
+			}, nil)
+			deploymentRepo.On("CreateHistory", mock.Anything, mock.MatchedBy(func(h *domain.ImageDeploymentHistory) bool {
+				return h.DeploymentID == "dep-1" && h.PreviousTag == "v2" && h.NewTag == "v2" && h.ChangedBy == "alice" && h.Reason == action
+			})).Return(nil).Once()
+
+			uc := usecase.NewK8sRolloutUsecase(clusterRepo, clients, usecase.NewImageDeploymentUsecase(deploymentRepo, nil, nil))
+			var err error
+			switch action {
+			case domain.K8sRolloutActionRestart:
+				err = uc.Restart(context.Background(), "cluster-1", "shop", "api", "alice")
+			case domain.K8sRolloutActionPause:
+				err = uc.Pause(context.Background(), "cluster-1", "shop", "api", "alice")
+			case domain.K8sRolloutActionResume:
+				err = uc.Resume(context.Background(), "cluster-1", "shop", "api", "alice")
+			}
+			require.NoError(t, err)
+			deploymentRepo.AssertExpectations(t)
+
+			deployment, err := clientset.AppsV1().Deployments("shop").Get(context.Background(), "api", metav1.GetOptions{})
+			require.NoError(t, err)
+			switch action {
+			case domain.K8sRolloutActionRestart:
+				assert.NotEmpty(t, deployment.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"])
+			case domain.K8sRolloutActionPause:
+				assert.True(t, deployment.Spec.Paused)
+			case domain.K8sRolloutActionResume:
+				assert.False(t, deployment.Spec.Paused)
+			}
+		})
+	}
+}
+
+// TestImageDeploymentHistoryTable tests that history rows go to the table migration 017 created
+func TestImageDeploymentHistoryTable(t *testing.T) {
+	assert.Equal(t, "image_deployment_history", domain.ImageDeploymentHistory{}.TableName())
+}
@@ -0,0 +1,430 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/pkg/k8s"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// watchEventBufferSize is the number of recent events kept per informer for resume
+	watchEventBufferSize = 1000
+	// watchSubscriberBufferSize is the number of events queued per subscriber before it is dropped
+	watchSubscriberBufferSize = 512
+	// watchIdleTimeout keeps an informer running after its last subscriber leaves so reconnects can resume
+	watchIdleTimeout = 2 * time.Minute
+	// watchSyncTimeout bounds the initial list of an informer
+	watchSyncTimeout = 30 * time.Second
+	// watchPermissionAction is the resource permission action required to receive events
+	watchPermissionAction = "read"
+)
+
+type k8sWatchUsecase struct {
+	k8sRepo     domain.K8sClusterRepository
+	clients     *k8s.ClientManager
+	authUsecase domain.AuthorizationUsecase
+
+	watchers map[string]*k8sResourceWatcher
+	mu       sync.Mutex
+}
+
+// NewK8sWatchUsecase creates a new Kubernetes watch use case instance
+func NewK8sWatchUsecase(
+	k8sRepo domain.K8sClusterRepository,
+	clients *k8s.ClientManager,
+	authUsecase domain.AuthorizationUsecase,
+) domain.K8sWatchUsecase {
+	return &k8sWatchUsecase{
+		k8sRepo:     k8sRepo,
+		clients:     clients,
+		authUsecase: authUsecase,
+		watchers:    make(map[string]*k8sResourceWatcher),
+	}
+}
+
+// Watch subscribes the user to changes of the requested resources
+func (u *k8sWatchUsecase) Watch(ctx context.Context, userID, clusterID string, req domain.K8sWatchRequest) (<-chan domain.K8sWatchEvent, error) {
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+	if len(req.Resources) == 0 {
+		return nil, errors.New("at least one resource is required")
+	}
+
+	client, err := getPlatformK8sClient(ctx, u.k8sRepo, u.clients, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Explicitly requested namespaces must all be readable
+	for _, namespace := range req.Namespaces {
+		allowed, err := u.authUsecase.CheckNamespacePermission(ctx, userID, clusterID, namespace, watchPermissionAction)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check namespace permission: %w", err)
+		}
+		if !allowed {
+			return nil, fmt.Errorf("%w: %s", domain.ErrK8sNamespaceForbidden, namespace)
+		}
+	}
+
+	namespaces := req.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+
+	var resources []*k8s.ResolvedResource
+	for _, name := range req.Resources {
+		resource, err := client.ResolveResource(name)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+
+	sub := &k8sWatchSubscriber{
+		events: make(chan domain.K8sWatchEvent, watchSubscriberBufferSize),
+		lagged: make(chan struct{}),
+	}
+
+	var watchers []*k8sResourceWatcher
+	var initial []domain.K8sWatchEvent
+	unsubscribe := func() {
+		for _, w := range watchers {
+			u.release(w, sub)
+		}
+	}
+
+	for _, resource := range resources {
+		watchNamespaces := namespaces
+		if !resource.Namespaced {
+			watchNamespaces = []string{""}
+		}
+		for _, namespace := range uniqueStrings(watchNamespaces) {
+			w, err := u.acquire(ctx, client, clusterID, resource, namespace)
+			if err != nil {
+				unsubscribe()
+				return nil, err
+			}
+			watchers = append(watchers, w)
+			initial = append(initial, w.subscribe(sub, req.ResourceVersion)...)
+		}
+	}
+	initial = append(initial, domain.K8sWatchEvent{ClusterID: clusterID, Type: domain.K8sWatchEventSynced})
+
+	out := make(chan domain.K8sWatchEvent, watchSubscriberBufferSize)
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+
+		permissions := make(map[string]bool)
+		send := func(event domain.K8sWatchEvent) bool {
+			if event.Name != "" && !u.canRead(ctx, permissions, userID, clusterID, event.Namespace) {
+				return true
+			}
+			select {
+			case out <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		// Snapshot or replay first; live events queue up in the subscriber meanwhile
+		for _, event := range initial {
+			if !send(event) {
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sub.lagged:
+				log.Printf("k8s watch subscriber for user %s on cluster %s fell behind, closing", userID, clusterID)
+				return
+			case event := <-sub.events:
+				if !send(event) {
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// canRead checks and caches the user's read permission on a namespace for the lifetime of a subscription
+func (u *k8sWatchUsecase) canRead(ctx context.Context, cache map[string]bool, userID, clusterID, namespace string) bool {
+	if allowed, ok := cache[namespace]; ok {
+		return allowed
+	}
+	allowed, err := u.authUsecase.CheckNamespacePermission(ctx, userID, clusterID, namespace, watchPermissionAction)
+	if err != nil {
+		log.Printf("Failed to check namespace permission for user %s on %s/%s: %v", userID, clusterID, namespace, err)
+		return false
+	}
+	cache[namespace] = allowed
+	return allowed
+}
+
+// acquire returns a running, synced watcher for the resource, starting one if needed
+func (u *k8sWatchUsecase) acquire(ctx context.Context, client *k8s.Client, clusterID string, resource *k8s.ResolvedResource, namespace string) (*k8sResourceWatcher, error) {
+	key := clusterID + "|" + resource.GVR.String() + "|" + namespace
+
+	u.mu.Lock()
+	w, ok := u.watchers[key]
+	if !ok {
+		w = newK8sResourceWatcher(client, clusterID, resource, namespace)
+		u.watchers[key] = w
+		go w.informer.Run(w.stopCh)
+	}
+	w.refs++
+	if w.idleTimer != nil {
+		w.idleTimer.Stop()
+		w.idleTimer = nil
+	}
+	u.mu.Unlock()
+
+	syncCtx, cancel := context.WithTimeout(ctx, watchSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), w.registration.HasSynced) {
+		u.mu.Lock()
+		w.refs--
+		if w.refs == 0 {
+			u.stopLocked(key, w)
+		}
+		u.mu.Unlock()
+		return nil, fmt.Errorf("timed out waiting for %s to sync", resource.GVR.Resource)
+	}
+
+	w.markSynced()
+	return w, nil
+}
+
+// release unsubscribes from a watcher and schedules it to stop once idle
+func (u *k8sWatchUsecase) release(w *k8sResourceWatcher, sub *k8sWatchSubscriber) {
+	w.unsubscribe(sub)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	w.refs--
+	if w.refs > 0 {
+		return
+	}
+	w.idleTimer = time.AfterFunc(watchIdleTimeout, func() {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		if w.refs == 0 {
+			u.stopLocked(w.key, w)
+		}
+	})
+}
+
+// stopLocked stops a watcher; callers must hold u.mu
+func (u *k8sWatchUsecase) stopLocked(key string, w *k8sResourceWatcher) {
+	if u.watchers[key] == w {
+		delete(u.watchers, key)
+	}
+	w.stopOnce.Do(func() { close(w.stopCh) })
+}
+
+// k8sWatchSubscriber receives events from one or more watchers
+type k8sWatchSubscriber struct {
+	events     chan domain.K8sWatchEvent
+	lagged     chan struct{}
+	laggedOnce sync.Once
+}
+
+// push queues an event without blocking; a full queue marks the subscriber as lagged
+func (s *k8sWatchSubscriber) push(event domain.K8sWatchEvent) {
+	select {
+	case s.events <- event:
+	default:
+		s.laggedOnce.Do(func() { close(s.lagged) })
+	}
+}
+
+// k8sResourceWatcher runs a shared informer for one resource and namespace
+// and keeps a buffer of recent events so subscribers can resume
+type k8sResourceWatcher struct {
+	key          string
+	clusterID    string
+	resource     *k8s.ResolvedResource
+	informer     cache.SharedIndexInformer
+	registration cache.ResourceEventHandlerRegistration
+	stopCh       chan struct{}
+	stopOnce     sync.Once
+
+	// Guarded by k8sWatchUsecase.mu
+	refs      int
+	idleTimer *time.Timer
+
+	mu          sync.Mutex
+	synced      bool
+	bufferStart uint64 // Events after this resourceVersion are all in buffer
+	buffer      []domain.K8sWatchEvent
+	subscribers map[*k8sWatchSubscriber]struct{}
+}
+
+func newK8sResourceWatcher(client *k8s.Client, clusterID string, resource *k8s.ResolvedResource, namespace string) *k8sResourceWatcher {
+	w := &k8sResourceWatcher{
+		key:         clusterID + "|" + resource.GVR.String() + "|" + namespace,
+		clusterID:   clusterID,
+		resource:    resource,
+		informer:    client.NewInformer(resource.GVR, namespace),
+		stopCh:      make(chan struct{}),
+		subscribers: make(map[*k8sWatchSubscriber]struct{}),
+	}
+
+	// Objects from the initial list are served from the informer store instead of the buffer
+	w.registration, _ = w.informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if !isInInitialList {
+				w.record(domain.K8sWatchEventAdded, obj)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			w.record(domain.K8sWatchEventModified, obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			w.record(domain.K8sWatchEventDeleted, obj)
+		},
+	})
+
+	return w
+}
+
+// markSynced records where the event buffer starts once the initial list is complete
+func (w *k8sResourceWatcher) markSynced() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.synced {
+		return
+	}
+	w.synced = true
+	w.bufferStart, _ = parseResourceVersion(w.informer.LastSyncResourceVersion())
+}
+
+// record appends an event to the buffer and fans it out to subscribers
+func (w *k8sResourceWatcher) record(eventType domain.K8sWatchEventType, obj interface{}) {
+	event, ok := w.toEvent(eventType, obj)
+	if !ok {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buffer = append(w.buffer, event)
+	if len(w.buffer) > watchEventBufferSize {
+		dropped := w.buffer[0]
+		w.buffer = w.buffer[1:]
+		if rv, ok := parseResourceVersion(dropped.ResourceVersion); ok && rv > w.bufferStart {
+			w.bufferStart = rv
+		}
+	}
+
+	for sub := range w.subscribers {
+		sub.push(event)
+	}
+}
+
+// subscribe registers a subscriber and returns either the missed events after
+// resourceVersion or, when that is not possible, a snapshot of the informer store.
+// Later events are pushed to the subscriber, so nothing is lost in between.
+func (w *k8sResourceWatcher) subscribe(sub *k8sWatchSubscriber, resourceVersion string) []domain.K8sWatchEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subscribers[sub] = struct{}{}
+
+	var events []domain.K8sWatchEvent
+	if resourceVersion != "" {
+		if rv, ok := parseResourceVersion(resourceVersion); ok && w.synced && w.bufferStart > 0 && rv >= w.bufferStart {
+			for _, event := range w.buffer {
+				if eventRV, ok := parseResourceVersion(event.ResourceVersion); ok && eventRV > rv {
+					events = append(events, event)
+				}
+			}
+			return events
+		}
+		events = append(events, domain.K8sWatchEvent{
+			ClusterID: w.clusterID,
+			Type:      domain.K8sWatchEventResync,
+			Resource:  w.resource.GVR.Resource,
+			Kind:      w.resource.GVK.Kind,
+		})
+	}
+
+	for _, obj := range w.informer.GetStore().List() {
+		if event, ok := w.toEvent(domain.K8sWatchEventAdded, obj); ok {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (w *k8sResourceWatcher) unsubscribe(sub *k8sWatchSubscriber) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.subscribers, sub)
+}
+
+func (w *k8sResourceWatcher) toEvent(eventType domain.K8sWatchEventType, obj interface{}) (domain.K8sWatchEvent, bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return domain.K8sWatchEvent{}, false
+	}
+
+	clean := u.DeepCopy()
+	unstructured.RemoveNestedField(clean.Object, "metadata", "managedFields")
+	return domain.K8sWatchEvent{
+		ClusterID:       w.clusterID,
+		Type:            eventType,
+		Resource:        w.resource.GVR.Resource,
+		Kind:            w.resource.GVK.Kind,
+		Namespace:       u.GetNamespace(),
+		Name:            u.GetName(),
+		ResourceVersion: u.GetResourceVersion(),
+		Object:          clean.Object,
+	}, true
+}
+
+// parseResourceVersion parses an etcd-backed resourceVersion; other formats cannot be compared
+func parseResourceVersion(resourceVersion string) (uint64, bool) {
+	rv, err := strconv.ParseUint(resourceVersion, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return rv, true
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		result = append(result, value)
+	}
+	return result
+}
@@ -0,0 +1,133 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// MockAuthorizationUsecase mocks the namespace permission checks of the authorization use case
+type MockAuthorizationUsecase struct {
+	domain.AuthorizationUsecase
+	mock.Mock
+}
+
+func (m *MockAuthorizationUsecase) CheckNamespacePermission(ctx context.Context, userID, clusterID, namespace, action string) (bool, error) {
+	args := m.Called(ctx, userID, clusterID, namespace, action)
+	return args.Bool(0), args.Error(1)
+}
+
+func watchedDeployment(namespace, name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+	}}
+}
+
+// nextWatchEvent waits for the next event on a watch stream
+func nextWatchEvent(t *testing.T, events <-chan domain.K8sWatchEvent) domain.K8sWatchEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "watch stream closed")
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a watch event")
+		return domain.K8sWatchEvent{}
+	}
+}
+
+// TestK8sWatch tests streaming resource changes filtered by namespace permissions
+func TestK8sWatch(t *testing.T) {
+	t.Run("Success - Events from unreadable namespaces are dropped", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		repo, clients, dynamicClient := newFakeDynamicK8sCluster(
+			watchedDeployment("shop", "api"),
+			watchedDeployment("billing", "ledger"),
+		)
+		authUsecase := new(MockAuthorizationUsecase)
+		authUsecase.On("CheckNamespacePermission", mock.Anything, "user-1", "cluster-1", "shop", "read").Return(true, nil)
+		authUsecase.On("CheckNamespacePermission", mock.Anything, "user-1", "cluster-1", "billing", "read").Return(false, nil)
+		uc := usecase.NewK8sWatchUsecase(repo, clients, authUsecase)
+
+		events, err := uc.Watch(ctx, "user-1", "cluster-1", domain.K8sWatchRequest{Resources: []string{"deployments"}})
+		require.NoError(t, err)
+
+		event := nextWatchEvent(t, events)
+		assert.Equal(t, domain.K8sWatchEventAdded, event.Type)
+		assert.Equal(t, "shop", event.Namespace)
+		assert.Equal(t, "api", event.Name)
+		assert.Equal(t, "Deployment", event.Kind)
+		assert.Equal(t, domain.K8sWatchEventSynced, nextWatchEvent(t, events).Type)
+
+		_, err = dynamicClient.Resource(deploymentsGVR).Namespace("billing").Create(ctx, watchedDeployment("billing", "invoices"), metav1.CreateOptions{})
+		require.NoError(t, err)
+		_, err = dynamicClient.Resource(deploymentsGVR).Namespace("shop").Create(ctx, watchedDeployment("shop", "web"), metav1.CreateOptions{})
+		require.NoError(t, err)
+
+		event = nextWatchEvent(t, events)
+		assert.Equal(t, domain.K8sWatchEventAdded, event.Type)
+		assert.Equal(t, "shop", event.Namespace)
+		assert.Equal(t, "web", event.Name)
+
+		// Permissions are checked once per namespace for the whole subscription
+		authUsecase.AssertNumberOfCalls(t, "CheckNamespacePermission", 2)
+	})
+
+	t.Run("Success - Stream closes when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		repo, clients, _ := newFakeDynamicK8sCluster(watchedDeployment("shop", "api"))
+		authUsecase := new(MockAuthorizationUsecase)
+		authUsecase.On("CheckNamespacePermission", mock.Anything, "user-1", "cluster-1", "shop", "read").Return(true, nil)
+		uc := usecase.NewK8sWatchUsecase(repo, clients, authUsecase)
+
+		events, err := uc.Watch(ctx, "user-1", "cluster-1", domain.K8sWatchRequest{
+			Resources:  []string{"deployments"},
+			Namespaces: []string{"shop"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "api", nextWatchEvent(t, events).Name)
+		assert.Equal(t, domain.K8sWatchEventSynced, nextWatchEvent(t, events).Type)
+
+		cancel()
+		select {
+		case _, ok := <-events:
+			assert.False(t, ok)
+		case <-time.After(5 * time.Second):
+			t.Fatal("watch stream was not closed")
+		}
+	})
+
+	t.Run("Error - Forbidden namespace", func(t *testing.T) {
+		repo, clients, _ := newFakeDynamicK8sCluster()
+		authUsecase := new(MockAuthorizationUsecase)
+		authUsecase.On("CheckNamespacePermission", mock.Anything, "user-1", "cluster-1", "billing", "read").Return(false, nil)
+		uc := usecase.NewK8sWatchUsecase(repo, clients, authUsecase)
+
+		events, err := uc.Watch(context.Background(), "user-1", "cluster-1", domain.K8sWatchRequest{
+			Resources:  []string{"deployments"},
+			Namespaces: []string{"billing"},
+		})
+		assert.ErrorIs(t, err, domain.ErrK8sNamespaceForbidden)
+		assert.Nil(t, events)
+	})
+
+	t.Run("Error - Missing resources", func(t *testing.T) {
+		uc := usecase.NewK8sWatchUsecase(new(MockK8sClusterRepository), nil, new(MockAuthorizationUsecase))
+
+		_, err := uc.Watch(context.Background(), "user-1", "cluster-1", domain.K8sWatchRequest{})
+		assert.Error(t, err)
+	})
+}
@@ -4,28 +4,27 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/unitechio/einfra-be/pkg/docker"
 	"github.com/unitechio/einfra-be/pkg/logstream"
 )
 
 // LogUsecase handles log operations
 type LogUsecase interface {
-	StreamContainerLogs(ctx context.Context, containerID string, tail string) (<-chan logstream.LogMessage, <-chan error, error)
+	StreamContainerLogs(ctx context.Context, hostID, containerID string, tail string) (<-chan logstream.LogMessage, <-chan error, error)
 }
 
 type logUsecase struct {
-	dockerClient *docker.Client
+	clients DockerClientRegistry
 }
 
 // NewLogUsecase creates a new log usecase
-func NewLogUsecase(dockerClient *docker.Client) LogUsecase {
+func NewLogUsecase(clients DockerClientRegistry) LogUsecase {
 	return &logUsecase{
-		dockerClient: dockerClient,
+		clients: clients,
 	}
 }
 
 // StreamContainerLogs streams logs from a container
-func (u *logUsecase) StreamContainerLogs(ctx context.Context, containerID string, tail string) (<-chan logstream.LogMessage, <-chan error, error) {
+func (u *logUsecase) StreamContainerLogs(ctx context.Context, hostID, containerID string, tail string) (<-chan logstream.LogMessage, <-chan error, error) {
 	if containerID == "" {
 		return nil, nil, fmt.Errorf("container ID is required")
 	}
@@ -34,5 +33,10 @@ func (u *logUsecase) StreamContainerLogs(ctx context.Context, containerID string
 		tail = "100"
 	}
 
-	return u.dockerClient.ContainerLogsStream(ctx, containerID, tail, true)
+	client, err := u.clients.Get(ctx, hostID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return client.ContainerLogsStream(ctx, containerID, tail, true)
 }
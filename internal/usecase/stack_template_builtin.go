@@ -0,0 +1,146 @@
+package usecase
+
+import "github.com/unitechio/einfra-be/internal/domain"
+
+// builtinStackTemplates ship with the server and cannot be changed through the API. Their ID is their
+// name; bump Version whenever a template changes so stacks show which revision they were rendered from.
+var builtinStackTemplates = []*domain.StackTemplate{
+	{
+		ID:          "nginx",
+		Name:        "nginx",
+		Description: "NGINX web server serving static files from a named volume",
+		Category:    "web",
+		Version:     1,
+		BuiltIn:     true,
+		Parameters: []domain.StackTemplateParameter{
+			{Name: "tag", Label: "Image tag", Type: domain.StackTemplateParamString, Default: "1.27-alpine"},
+			{Name: "http_port", Label: "HTTP port", Type: domain.StackTemplateParamPort, Required: true, Default: "8080"},
+		},
+		ComposeTemplate: `services:
+  nginx:
+    image: {{ quote (printf "nginx:%s" .tag) }}
+    restart: unless-stopped
+    ports:
+      - "{{ .http_port }}:80"
+    volumes:
+      - html:/usr/share/nginx/html:ro
+volumes:
+  html: {}
+`,
+	},
+	{
+		ID:          "postgres",
+		Name:        "postgres",
+		Description: "PostgreSQL database with a persistent data volume",
+		Category:    "database",
+		Version:     1,
+		BuiltIn:     true,
+		Parameters: []domain.StackTemplateParameter{
+			{Name: "version", Label: "PostgreSQL version", Type: domain.StackTemplateParamSelect, Default: "17", Options: []string{"15", "16", "17"}},
+			{Name: "database", Label: "Database", Type: domain.StackTemplateParamString, Required: true, Default: "app"},
+			{Name: "user", Label: "User", Type: domain.StackTemplateParamString, Required: true, Default: "app"},
+			{Name: "password", Label: "Password", Description: "Generated when left empty", Type: domain.StackTemplateParamSecret},
+			{Name: "expose", Label: "Publish port on the host", Type: domain.StackTemplateParamBool},
+			{Name: "port", Label: "Host port", Type: domain.StackTemplateParamPort, Default: "5432"},
+		},
+		ComposeTemplate: `services:
+  postgres:
+    image: postgres:{{ .version }}-alpine
+    restart: unless-stopped
+    environment:
+      POSTGRES_DB: {{ quote .database }}
+      POSTGRES_USER: {{ quote .user }}
+      POSTGRES_PASSWORD: {{ quote .password }}
+{{- if .expose }}
+    ports:
+      - "{{ .port }}:5432"
+{{- end }}
+    volumes:
+      - data:/var/lib/postgresql/data
+    healthcheck:
+      test: ["CMD-SHELL", "pg_isready -U \"$$POSTGRES_USER\" -d \"$$POSTGRES_DB\""]
+      interval: 10s
+      timeout: 5s
+      retries: 5
+volumes:
+  data: {}
+`,
+	},
+	{
+		ID:          "redis",
+		Name:        "redis",
+		Description: "Redis with password authentication and optional append-only persistence",
+		Category:    "database",
+		Version:     1,
+		BuiltIn:     true,
+		Parameters: []domain.StackTemplateParameter{
+			{Name: "password", Label: "Password", Description: "Generated when left empty", Type: domain.StackTemplateParamSecret},
+			{Name: "persistence", Label: "Append-only persistence", Type: domain.StackTemplateParamBool, Default: "true"},
+			{Name: "port", Label: "Host port", Type: domain.StackTemplateParamPort, Required: true, Default: "6379"},
+		},
+		ComposeTemplate: `services:
+  redis:
+    image: redis:7-alpine
+    restart: unless-stopped
+    command: ["redis-server", "--appendonly", "{{ if .persistence }}yes{{ else }}no{{ end }}", "--requirepass", {{ quote .password }}]
+    ports:
+      - "{{ .port }}:6379"
+    volumes:
+      - data:/data
+volumes:
+  data: {}
+`,
+	},
+	{
+		ID:          "wordpress",
+		Name:        "wordpress",
+		Description: "WordPress with a MariaDB database",
+		Category:    "cms",
+		Version:     1,
+		BuiltIn:     true,
+		Parameters: []domain.StackTemplateParameter{
+			{Name: "http_port", Label: "HTTP port", Type: domain.StackTemplateParamPort, Required: true, Default: "8080"},
+			{Name: "db_password", Label: "Database password", Description: "Generated when left empty", Type: domain.StackTemplateParamSecret},
+			{Name: "db_root_password", Label: "Database root password", Description: "Generated when left empty", Type: domain.StackTemplateParamSecret},
+		},
+		ComposeTemplate: `services:
+  wordpress:
+    image: wordpress:6-apache
+    restart: unless-stopped
+    depends_on:
+      - db
+    ports:
+      - "{{ .http_port }}:80"
+    environment:
+      WORDPRESS_DB_HOST: db
+      WORDPRESS_DB_NAME: wordpress
+      WORDPRESS_DB_USER: wordpress
+      WORDPRESS_DB_PASSWORD: {{ quote .db_password }}
+    volumes:
+      - wordpress:/var/www/html
+  db:
+    image: mariadb:11
+    restart: unless-stopped
+    environment:
+      MARIADB_DATABASE: wordpress
+      MARIADB_USER: wordpress
+      MARIADB_PASSWORD: {{ quote .db_password }}
+      MARIADB_ROOT_PASSWORD: {{ quote .db_root_password }}
+    volumes:
+      - db:/var/lib/mysql
+volumes:
+  wordpress: {}
+  db: {}
+`,
+	},
+}
+
+// builtinStackTemplate returns the built-in template with the given ID, nil when there is none
+func builtinStackTemplate(id string) *domain.StackTemplate {
+	for _, tpl := range builtinStackTemplates {
+		if tpl.ID == id {
+			return tpl
+		}
+	}
+	return nil
+}
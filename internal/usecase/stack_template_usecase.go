@@ -0,0 +1,420 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/repository"
+	"github.com/unitechio/einfra-be/pkg/docker"
+	"gorm.io/gorm"
+)
+
+// StackTemplateUsecase handles the catalog of stack templates
+type StackTemplateUsecase interface {
+	// ListTemplates returns the built-in templates followed by the user-defined ones
+	ListTemplates(ctx context.Context, filter domain.StackTemplateFilter) ([]*domain.StackTemplate, error)
+	GetTemplate(ctx context.Context, id string) (*domain.StackTemplate, error)
+	CreateTemplate(ctx context.Context, req domain.StackTemplateRequest, userID string) (*domain.StackTemplate, error)
+	UpdateTemplate(ctx context.Context, id string, req domain.StackTemplateRequest) (*domain.StackTemplate, error)
+	DeleteTemplate(ctx context.Context, id string) error
+	// DeployTemplate renders a template with the given parameters and deploys it as a new stack
+	DeployTemplate(ctx context.Context, id string, req domain.StackTemplateDeployRequest, userID string) (*domain.DockerStack, error)
+}
+
+const (
+	// maxStackTemplateValueLength bounds string and secret parameter values
+	maxStackTemplateValueLength = 4096
+	// stackTemplateSecretBytes is the entropy of generated secrets
+	stackTemplateSecretBytes = 24
+)
+
+// stackTemplateParamName keeps parameter names usable as {{ .name }} in templates
+var stackTemplateParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// stackTemplateFuncs are available to compose templates besides the text/template builtins
+var stackTemplateFuncs = template.FuncMap{
+	// quote renders a value as a YAML string that compose does not interpolate, except for the
+	// references to secret variables
+	"quote": func(value interface{}) string {
+		quoted, _ := json.Marshal(fmt.Sprint(value))
+		if _, ok := value.(stackTemplateSecretRef); ok {
+			return string(quoted)
+		}
+		return strings.ReplaceAll(string(quoted), "$", "$$")
+	},
+}
+
+// stackTemplateSecretRef is rendered in place of a secret parameter: a reference to the stack
+// variable holding its value, which keeps the value out of the compose file
+type stackTemplateSecretRef string
+
+// stackTemplateSecretVar names the stack variable holding the value of a secret parameter
+func stackTemplateSecretVar(param domain.StackTemplateParameter) string {
+	return strings.ToUpper(param.Name)
+}
+
+type stackTemplateUsecase struct {
+	templateRepo repository.StackTemplateRepository
+	stackUsecase DockerStackUsecase
+}
+
+// NewStackTemplateUsecase creates a new stack template usecase
+func NewStackTemplateUsecase(templateRepo repository.StackTemplateRepository, stackUsecase DockerStackUsecase) StackTemplateUsecase {
+	return &stackTemplateUsecase{
+		templateRepo: templateRepo,
+		stackUsecase: stackUsecase,
+	}
+}
+
+// ListTemplates returns the built-in templates followed by the user-defined ones
+func (u *stackTemplateUsecase) ListTemplates(ctx context.Context, filter domain.StackTemplateFilter) ([]*domain.StackTemplate, error) {
+	var templates []*domain.StackTemplate
+	search := strings.ToLower(filter.Search)
+	for _, tpl := range builtinStackTemplates {
+		if filter.Category != "" && tpl.Category != filter.Category {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(tpl.Name+" "+tpl.Description), search) {
+			continue
+		}
+		templates = append(templates, tpl)
+	}
+
+	custom, err := u.templateRepo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	return append(templates, custom...), nil
+}
+
+// GetTemplate returns a built-in template by name or a user-defined one by ID
+func (u *stackTemplateUsecase) GetTemplate(ctx context.Context, id string) (*domain.StackTemplate, error) {
+	if tpl := builtinStackTemplate(id); tpl != nil {
+		return tpl, nil
+	}
+	tpl, err := u.templateRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("template not found: %w", err)
+	}
+	return tpl, nil
+}
+
+// CreateTemplate validates and stores a user-defined template
+func (u *stackTemplateUsecase) CreateTemplate(ctx context.Context, req domain.StackTemplateRequest, userID string) (*domain.StackTemplate, error) {
+	if err := u.checkTemplateName(ctx, req.Name, ""); err != nil {
+		return nil, err
+	}
+
+	tpl := &domain.StackTemplate{
+		Name:            req.Name,
+		Description:     req.Description,
+		Category:        req.Category,
+		Version:         1,
+		ComposeTemplate: req.ComposeTemplate,
+		Parameters:      req.Parameters,
+		CreatedBy:       optionalUser(userID),
+	}
+	if err := validateStackTemplate(ctx, tpl); err != nil {
+		return nil, err
+	}
+
+	if err := u.templateRepo.Create(ctx, tpl); err != nil {
+		return nil, fmt.Errorf("failed to create template: %w", err)
+	}
+	return tpl, nil
+}
+
+// UpdateTemplate replaces a user-defined template and bumps its version
+func (u *stackTemplateUsecase) UpdateTemplate(ctx context.Context, id string, req domain.StackTemplateRequest) (*domain.StackTemplate, error) {
+	if builtinStackTemplate(id) != nil {
+		return nil, fmt.Errorf("built-in template %s cannot be changed", id)
+	}
+	tpl, err := u.templateRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("template not found: %w", err)
+	}
+	if err := u.checkTemplateName(ctx, req.Name, tpl.ID); err != nil {
+		return nil, err
+	}
+
+	tpl.Name = req.Name
+	tpl.Description = req.Description
+	tpl.Category = req.Category
+	tpl.ComposeTemplate = req.ComposeTemplate
+	tpl.Parameters = req.Parameters
+	tpl.Version++
+	if err := validateStackTemplate(ctx, tpl); err != nil {
+		return nil, err
+	}
+
+	if err := u.templateRepo.Update(ctx, tpl); err != nil {
+		return nil, fmt.Errorf("failed to update template: %w", err)
+	}
+	return tpl, nil
+}
+
+// DeleteTemplate deletes a user-defined template; stacks deployed from it are kept
+func (u *stackTemplateUsecase) DeleteTemplate(ctx context.Context, id string) error {
+	if builtinStackTemplate(id) != nil {
+		return fmt.Errorf("built-in template %s cannot be deleted", id)
+	}
+	if _, err := u.templateRepo.GetByID(ctx, id); err != nil {
+		return fmt.Errorf("template not found: %w", err)
+	}
+	return u.templateRepo.Delete(ctx, id)
+}
+
+// DeployTemplate renders a template with the given parameters and deploys it as a new stack
+func (u *stackTemplateUsecase) DeployTemplate(ctx context.Context, id string, req domain.StackTemplateDeployRequest, userID string) (*domain.DockerStack, error) {
+	tpl, err := u.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	composeFile, secrets, err := renderStackTemplate(tpl, req.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	for name := range secrets {
+		if _, ok := req.EnvVars[name]; ok {
+			return nil, fmt.Errorf("variable %s is reserved for a secret parameter", name)
+		}
+	}
+
+	templateID := tpl.ID
+	return u.stackUsecase.DeployStack(ctx, domain.StackDeployRequest{
+		Name:            req.Name,
+		ComposeFile:     composeFile,
+		EnvVars:         req.EnvVars,
+		DockerHost:      req.DockerHost,
+		EnvironmentID:   req.EnvironmentID,
+		TemplateID:      &templateID,
+		TemplateVersion: tpl.Version,
+		SecretEnvVars:   secrets,
+	}, userID)
+}
+
+// checkTemplateName rejects names taken by a built-in or another user-defined template
+func (u *stackTemplateUsecase) checkTemplateName(ctx context.Context, name, id string) error {
+	if name == "" {
+		return fmt.Errorf("template name is required")
+	}
+	if builtinStackTemplate(name) != nil {
+		return fmt.Errorf("template name %s is reserved for a built-in template", name)
+	}
+	existing, err := u.templateRepo.GetByName(ctx, name)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check template name: %w", err)
+	}
+	if err == nil && existing != nil && existing.ID != id {
+		return fmt.Errorf("template with name %s already exists", name)
+	}
+	return nil
+}
+
+// validateStackTemplate checks the parameter schema of a template and that it renders a valid compose
+// file when every parameter holds its default or a sample value
+func validateStackTemplate(ctx context.Context, tpl *domain.StackTemplate) error {
+	seen := make(map[string]bool, len(tpl.Parameters))
+	secretVars := make(map[string]bool)
+	samples := make(map[string]interface{}, len(tpl.Parameters))
+	for _, param := range tpl.Parameters {
+		if !stackTemplateParamName.MatchString(param.Name) {
+			return fmt.Errorf("invalid parameter name %q: use letters, digits and underscores", param.Name)
+		}
+		if seen[param.Name] {
+			return fmt.Errorf("duplicate parameter %s", param.Name)
+		}
+		seen[param.Name] = true
+
+		switch param.Type {
+		case domain.StackTemplateParamString, domain.StackTemplateParamPort, domain.StackTemplateParamBool:
+		case domain.StackTemplateParamSecret:
+			if param.Default != "" {
+				return fmt.Errorf("secret parameter %s cannot have a default", param.Name)
+			}
+			if secretVars[stackTemplateSecretVar(param)] {
+				return fmt.Errorf("secret parameter %s clashes with another one in variable %s", param.Name, stackTemplateSecretVar(param))
+			}
+			secretVars[stackTemplateSecretVar(param)] = true
+		case domain.StackTemplateParamSelect:
+			if len(param.Options) == 0 {
+				return fmt.Errorf("select parameter %s needs options", param.Name)
+			}
+		default:
+			return fmt.Errorf("parameter %s has unknown type %q", param.Name, param.Type)
+		}
+		if param.Default != "" {
+			if _, err := stackTemplateValue(param, param.Default); err != nil {
+				return fmt.Errorf("invalid default: %w", err)
+			}
+		}
+
+		if param.Required && param.Default == "" {
+			samples[param.Name] = sampleStackTemplateValue(param)
+		}
+	}
+
+	composeFile, secrets, err := renderStackTemplate(tpl, samples)
+	if err != nil {
+		return err
+	}
+	if _, err := docker.LoadComposeProject(ctx, "template", composeFile, secrets); err != nil {
+		return fmt.Errorf("template does not render a valid compose file: %w", err)
+	}
+	return nil
+}
+
+// renderStackTemplate validates parameter values against the schema of a template and renders its
+// compose file. Missing values fall back to defaults; optional secrets are generated. Secrets are
+// rendered as ${NAME} references and returned as the variables to deploy the stack with.
+func renderStackTemplate(tpl *domain.StackTemplate, values map[string]interface{}) (string, map[string]string, error) {
+	params := make(map[string]bool, len(tpl.Parameters))
+	for _, param := range tpl.Parameters {
+		params[param.Name] = true
+	}
+	unknown := make([]string, 0)
+	for name := range values {
+		if !params[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", nil, fmt.Errorf("unknown parameters: %s", strings.Join(unknown, ", "))
+	}
+
+	data := make(map[string]interface{}, len(tpl.Parameters))
+	secrets := make(map[string]string)
+	for _, param := range tpl.Parameters {
+		raw, err := stackTemplateString(values[param.Name])
+		if err != nil {
+			return "", nil, fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
+		if raw == "" {
+			raw = param.Default
+		}
+		if raw == "" {
+			switch {
+			case param.Required:
+				return "", nil, fmt.Errorf("parameter %s is required", param.Name)
+			case param.Type == domain.StackTemplateParamSecret:
+				if raw, err = generateStackTemplateSecret(); err != nil {
+					return "", nil, err
+				}
+			case param.Type == domain.StackTemplateParamBool:
+				raw = "false"
+			default:
+				// Optional values left empty render as nothing and are false in {{ if }}
+				data[param.Name] = ""
+				continue
+			}
+		}
+
+		value, err := stackTemplateValue(param, raw)
+		if err != nil {
+			return "", nil, err
+		}
+		if param.Type == domain.StackTemplateParamSecret {
+			name := stackTemplateSecretVar(param)
+			secrets[name] = raw
+			value = stackTemplateSecretRef("${" + name + "}")
+		}
+		data[param.Name] = value
+	}
+
+	t, err := template.New(tpl.Name).Option("missingkey=error").Funcs(stackTemplateFuncs).Parse(tpl.ComposeTemplate)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid compose template: %w", err)
+	}
+	var out bytes.Buffer
+	if err := t.Execute(&out, data); err != nil {
+		return "", nil, fmt.Errorf("failed to render compose template: %w", err)
+	}
+	return out.String(), secrets, nil
+}
+
+// stackTemplateValue checks a value against the type of a parameter and converts it for rendering
+func stackTemplateValue(param domain.StackTemplateParameter, raw string) (interface{}, error) {
+	switch param.Type {
+	case domain.StackTemplateParamPort:
+		port, err := strconv.Atoi(raw)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("parameter %s must be a port between 1 and 65535", param.Name)
+		}
+		return port, nil
+	case domain.StackTemplateParamBool:
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s must be true or false", param.Name)
+		}
+		return enabled, nil
+	case domain.StackTemplateParamSelect:
+		for _, option := range param.Options {
+			if raw == option {
+				return raw, nil
+			}
+		}
+		return nil, fmt.Errorf("parameter %s must be one of %s", param.Name, strings.Join(param.Options, ", "))
+	default:
+		// Line breaks would let a value add keys to the compose file
+		if strings.ContainsAny(raw, "\r\n") {
+			return nil, fmt.Errorf("parameter %s must be a single line", param.Name)
+		}
+		if len(raw) > maxStackTemplateValueLength {
+			return nil, fmt.Errorf("parameter %s exceeds %d characters", param.Name, maxStackTemplateValueLength)
+		}
+		return raw, nil
+	}
+}
+
+// stackTemplateString converts a JSON value of a deploy request to its string form
+func stackTemplateString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		return "", fmt.Errorf("must be a string, number or boolean")
+	}
+}
+
+// sampleStackTemplateValue fills a required parameter when checking that a template renders
+func sampleStackTemplateValue(param domain.StackTemplateParameter) string {
+	switch param.Type {
+	case domain.StackTemplateParamPort:
+		return "8080"
+	case domain.StackTemplateParamBool:
+		return "true"
+	case domain.StackTemplateParamSelect:
+		return param.Options[0]
+	default:
+		return "sample"
+	}
+}
+
+func generateStackTemplateSecret() (string, error) {
+	secret := make([]byte, stackTemplateSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
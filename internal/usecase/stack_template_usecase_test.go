@@ -0,0 +1,247 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+	"github.com/unitechio/einfra-be/pkg/docker"
+	"gorm.io/gorm"
+)
+
+// MockStackTemplateRepository is a mock implementation of StackTemplateRepository
+type MockStackTemplateRepository struct {
+	mock.Mock
+}
+
+func (m *MockStackTemplateRepository) Create(ctx context.Context, template *domain.StackTemplate) error {
+	args := m.Called(ctx, template)
+	return args.Error(0)
+}
+
+func (m *MockStackTemplateRepository) GetByID(ctx context.Context, id string) (*domain.StackTemplate, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.StackTemplate), args.Error(1)
+}
+
+func (m *MockStackTemplateRepository) GetByName(ctx context.Context, name string) (*domain.StackTemplate, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.StackTemplate), args.Error(1)
+}
+
+func (m *MockStackTemplateRepository) List(ctx context.Context, filter domain.StackTemplateFilter) ([]*domain.StackTemplate, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.StackTemplate), args.Error(1)
+}
+
+func (m *MockStackTemplateRepository) Update(ctx context.Context, template *domain.StackTemplate) error {
+	args := m.Called(ctx, template)
+	return args.Error(0)
+}
+
+func (m *MockStackTemplateRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+const testStackTemplate = `services:
+  web:
+    image: nginx:{{ .tag }}
+    environment:
+      API_KEY: {{ quote .api_key }}
+{{- if .public }}
+    ports:
+      - "{{ .port }}:80"
+{{- end }}
+`
+
+func testTemplateParameters() []domain.StackTemplateParameter {
+	return []domain.StackTemplateParameter{
+		{Name: "tag", Type: domain.StackTemplateParamSelect, Default: "1.27", Options: []string{"1.26", "1.27"}},
+		{Name: "api_key", Type: domain.StackTemplateParamSecret, Required: true},
+		{Name: "public", Type: domain.StackTemplateParamBool},
+		{Name: "port", Type: domain.StackTemplateParamPort, Default: "8080"},
+	}
+}
+
+// TestCreateTemplate tests validating user-defined templates
+func TestCreateTemplate(t *testing.T) {
+	mockRepo := new(MockStackTemplateRepository)
+	u := usecase.NewStackTemplateUsecase(mockRepo, nil)
+	ctx := context.Background()
+
+	t.Run("Success - Create template", func(t *testing.T) {
+		req := domain.StackTemplateRequest{Name: "web", ComposeTemplate: testStackTemplate, Parameters: testTemplateParameters()}
+
+		mockRepo.On("GetByName", ctx, req.Name).Return(nil, gorm.ErrRecordNotFound).Once()
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*domain.StackTemplate")).Return(nil).Once()
+
+		tpl, err := u.CreateTemplate(ctx, req, "user-123")
+		require.NoError(t, err)
+		assert.Equal(t, 1, tpl.Version)
+		assert.False(t, tpl.BuiltIn)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Error - Name of a built-in template", func(t *testing.T) {
+		_, err := u.CreateTemplate(ctx, domain.StackTemplateRequest{Name: "postgres", ComposeTemplate: testStackTemplate}, "user-123")
+		assert.ErrorContains(t, err, "reserved")
+	})
+
+	for name, tc := range map[string]struct {
+		composeTemplate string
+		parameters      []domain.StackTemplateParameter
+		err             string
+	}{
+		"Unknown type": {
+			composeTemplate: "services: {}\n",
+			parameters:      []domain.StackTemplateParameter{{Name: "size", Type: "int"}},
+			err:             "unknown type",
+		},
+		"Select without options": {
+			composeTemplate: "services: {}\n",
+			parameters:      []domain.StackTemplateParameter{{Name: "tier", Type: domain.StackTemplateParamSelect}},
+			err:             "needs options",
+		},
+		"Invalid default": {
+			composeTemplate: "services: {}\n",
+			parameters:      []domain.StackTemplateParameter{{Name: "port", Type: domain.StackTemplateParamPort, Default: "99999"}},
+			err:             "between 1 and 65535",
+		},
+		"Invalid parameter name": {
+			composeTemplate: "services: {}\n",
+			parameters:      []domain.StackTemplateParameter{{Name: "http-port", Type: domain.StackTemplateParamPort}},
+			err:             "invalid parameter name",
+		},
+		"Clashing secrets": {
+			composeTemplate: "services: {}\n",
+			parameters: []domain.StackTemplateParameter{
+				{Name: "token", Type: domain.StackTemplateParamSecret},
+				{Name: "TOKEN", Type: domain.StackTemplateParamSecret},
+			},
+			err: "clashes with another one",
+		},
+		"Undeclared parameter": {
+			composeTemplate: "services:\n  web:\n    image: nginx:{{ .tag }}\n",
+			err:             "failed to render",
+		},
+		"Not a compose file": {
+			composeTemplate: "services:\n  web:\n    ports: [{{ .port }}\n",
+			parameters:      []domain.StackTemplateParameter{{Name: "port", Type: domain.StackTemplateParamPort, Default: "80"}},
+			err:             "valid compose file",
+		},
+	} {
+		t.Run("Error - "+name, func(t *testing.T) {
+			mockRepo.On("GetByName", ctx, "broken").Return(nil, gorm.ErrRecordNotFound).Once()
+
+			_, err := u.CreateTemplate(ctx, domain.StackTemplateRequest{Name: "broken", ComposeTemplate: tc.composeTemplate, Parameters: tc.parameters}, "user-123")
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+// TestDeployTemplate tests rendering templates into stacks
+func TestDeployTemplate(t *testing.T) {
+	mockRepo := new(MockStackTemplateRepository)
+	mockStackRepo := new(MockDockerStackRepository)
+	mockClients := new(MockDockerClientRegistry)
+	u := usecase.NewStackTemplateUsecase(mockRepo, usecase.NewDockerStackUsecase(mockStackRepo, nil, nil, mockClients))
+	ctx := context.Background()
+
+	// Nothing listens there, the background deploy fails and marks the stack failed
+	unreachable, err := docker.NewClient("tcp://127.0.0.1:1")
+	require.NoError(t, err)
+	defer unreachable.Close()
+
+	tpl := &domain.StackTemplate{ID: "tpl-1", Name: "web", Version: 3, ComposeTemplate: testStackTemplate, Parameters: testTemplateParameters()}
+	expectDeploy := func(name string) {
+		mockStackRepo.On("GetByName", ctx, name).Return(nil, nil).Once()
+		mockClients.On("Get", ctx, "").Return(unreachable, nil).Once()
+		mockStackRepo.On("Create", ctx, mock.AnythingOfType("*domain.DockerStack")).Return(nil).Once()
+		mockStackRepo.On("CreateRevision", ctx, mock.AnythingOfType("*domain.StackRevision")).Return(nil).Once()
+		mockStackRepo.On("UpdateRevisionStatus", mock.Anything, mock.Anything, 1, mock.Anything, mock.Anything).Return(nil).Maybe()
+		mockStackRepo.On("UpdateStatus", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	}
+
+	t.Run("Success - Render parameters", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, tpl.ID).Return(tpl, nil).Once()
+		expectDeploy("shop")
+
+		stack, err := u.DeployTemplate(ctx, tpl.ID, domain.StackTemplateDeployRequest{
+			Name:       "shop",
+			Parameters: map[string]interface{}{"api_key": "pa$$word", "public": true, "port": float64(9090)},
+		}, "user-123")
+		require.NoError(t, err)
+		require.NotNil(t, stack.TemplateID)
+		assert.Equal(t, tpl.ID, *stack.TemplateID)
+		assert.Equal(t, 3, stack.TemplateVersion)
+		assert.Equal(t, `services:
+  web:
+    image: nginx:1.27
+    environment:
+      API_KEY: "${API_KEY}"
+    ports:
+      - "9090:80"
+`, stack.ComposeFile)
+		// Secrets stay out of the compose file and its revisions
+		assert.Equal(t, map[string]string{"API_KEY": "pa$$word"}, stack.SecretEnvVars)
+		assert.NotContains(t, stack.ComposeFile, "pa$$word")
+
+		mockRepo.AssertExpectations(t)
+		mockStackRepo.AssertExpectations(t)
+	})
+
+	for name, tc := range map[string]struct {
+		parameters map[string]interface{}
+		envVars    map[string]string
+		err        string
+	}{
+		"Missing required":   {parameters: map[string]interface{}{}, err: "api_key is required"},
+		"Unknown parameter":  {parameters: map[string]interface{}{"api_key": "k", "replicas": 2}, err: "unknown parameters: replicas"},
+		"Invalid port":       {parameters: map[string]interface{}{"api_key": "k", "port": "http"}, err: "between 1 and 65535"},
+		"Invalid select":     {parameters: map[string]interface{}{"api_key": "k", "tag": "latest"}, err: "one of 1.26, 1.27"},
+		"Invalid bool":       {parameters: map[string]interface{}{"api_key": "k", "public": "maybe"}, err: "true or false"},
+		"Multi-line value":   {parameters: map[string]interface{}{"api_key": "k\n    privileged: true"}, err: "single line"},
+		"Unsupported values": {parameters: map[string]interface{}{"api_key": []interface{}{"k"}}, err: "string, number or boolean"},
+		"Reserved variable":  {parameters: map[string]interface{}{"api_key": "k"}, envVars: map[string]string{"API_KEY": "plain"}, err: "reserved for a secret parameter"},
+	} {
+		t.Run("Error - "+name, func(t *testing.T) {
+			mockRepo.On("GetByID", ctx, tpl.ID).Return(tpl, nil).Once()
+
+			_, err := u.DeployTemplate(ctx, tpl.ID, domain.StackTemplateDeployRequest{Name: "shop", Parameters: tc.parameters, EnvVars: tc.envVars}, "user-123")
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+
+	t.Run("Success - Built-in templates with defaults", func(t *testing.T) {
+		templates := []*domain.StackTemplate{}
+		mockRepo.On("List", ctx, domain.StackTemplateFilter{}).Return(templates, nil).Once()
+
+		builtins, err := u.ListTemplates(ctx, domain.StackTemplateFilter{})
+		require.NoError(t, err)
+		require.NotEmpty(t, builtins)
+
+		for _, builtin := range builtins {
+			assert.True(t, builtin.BuiltIn)
+			expectDeploy(builtin.Name)
+
+			stack, err := u.DeployTemplate(ctx, builtin.ID, domain.StackTemplateDeployRequest{Name: builtin.Name}, "user-123")
+			require.NoError(t, err, builtin.Name)
+			assert.Equal(t, builtin.Version, stack.TemplateVersion)
+		}
+	})
+}
@@ -0,0 +1,526 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/repository"
+	"github.com/unitechio/einfra-be/pkg/errorx"
+)
+
+const (
+	// defaultScanTimeout is how long a deployment waits for a scan it triggered
+	defaultScanTimeout = 20 * time.Second
+	// maxScanTimeout keeps the wait below the 30s request timeout
+	maxScanTimeout   = 25 * time.Second
+	scanPollInterval = 2 * time.Second
+)
+
+type vulnerabilityGateUsecase struct {
+	policyRepo           domain.VulnerabilityPolicyRepository
+	envRepo              repository.EnvironmentRepository
+	k8sRepo              domain.K8sClusterRepository
+	harborUsecase        domain.HarborUsecase
+	authorizationUsecase domain.AuthorizationUsecase
+}
+
+// NewVulnerabilityGateUsecase creates a new vulnerability gate usecase
+func NewVulnerabilityGateUsecase(
+	policyRepo domain.VulnerabilityPolicyRepository,
+	envRepo repository.EnvironmentRepository,
+	k8sRepo domain.K8sClusterRepository,
+	harborUsecase domain.HarborUsecase,
+	authorizationUsecase domain.AuthorizationUsecase,
+) domain.VulnerabilityGateUsecase {
+	return &vulnerabilityGateUsecase{
+		policyRepo:           policyRepo,
+		envRepo:              envRepo,
+		k8sRepo:              k8sRepo,
+		harborUsecase:        harborUsecase,
+		authorizationUsecase: authorizationUsecase,
+	}
+}
+
+// Policy Management
+
+func (u *vulnerabilityGateUsecase) GetPolicy(ctx context.Context, environmentID string) (*domain.VulnerabilityPolicy, error) {
+	policy, err := u.policyRepo.GetPolicyByEnvironment(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, errorx.New(errorx.CodeNotFound, "no vulnerability policy for this environment")
+	}
+	return policy, nil
+}
+
+func (u *vulnerabilityGateUsecase) ListPolicies(ctx context.Context) ([]*domain.VulnerabilityPolicy, error) {
+	return u.policyRepo.ListPolicies(ctx)
+}
+
+func (u *vulnerabilityGateUsecase) SavePolicy(ctx context.Context, policy *domain.VulnerabilityPolicy, userID string) error {
+	if policy.EnvironmentID == "" {
+		return errorx.New(errorx.CodeBadRequest, "environment ID is required")
+	}
+	if _, err := u.envRepo.GetByID(ctx, policy.EnvironmentID); err != nil {
+		return errorx.Wrap(err, errorx.CodeNotFound, "environment not found")
+	}
+
+	switch policy.Mode {
+	case "":
+		policy.Mode = domain.VulnerabilityGateModeEnforce
+	case domain.VulnerabilityGateModeEnforce, domain.VulnerabilityGateModeWarn:
+	default:
+		return errorx.New(errorx.CodeBadRequest, "mode must be enforce or warn")
+	}
+
+	for _, limit := range []*int{policy.MaxCritical, policy.MaxHigh, policy.MaxMedium} {
+		if limit != nil && *limit < 0 {
+			return errorx.New(errorx.CodeBadRequest, "vulnerability limits must not be negative")
+		}
+	}
+
+	timeout := time.Duration(policy.ScanTimeoutSeconds) * time.Second
+	if timeout < 0 || timeout > maxScanTimeout {
+		return errorx.New(errorx.CodeBadRequest, fmt.Sprintf("scan timeout must be between 0 and %d seconds", int(maxScanTimeout.Seconds())))
+	}
+	if timeout == 0 {
+		policy.ScanTimeoutSeconds = int(defaultScanTimeout.Seconds())
+	}
+
+	// The environment identifies the policy; an existing one is replaced
+	policy.ID = ""
+	policy.UpdatedBy = userID
+	policy.UpdatedAt = time.Now()
+	return u.policyRepo.SavePolicy(ctx, policy)
+}
+
+func (u *vulnerabilityGateUsecase) DeletePolicy(ctx context.Context, environmentID string) error {
+	return u.policyRepo.DeletePolicy(ctx, environmentID)
+}
+
+// CVE Exceptions
+
+// RequestException files a time-boxed CVE exception; it has no effect until an admin approves it
+func (u *vulnerabilityGateUsecase) RequestException(ctx context.Context, exception *domain.CVEException, userID string) error {
+	exception.CVEID = strings.ToUpper(strings.TrimSpace(exception.CVEID))
+	if exception.CVEID == "" {
+		return errorx.New(errorx.CodeBadRequest, "CVE ID is required")
+	}
+	if strings.TrimSpace(exception.Reason) == "" {
+		return errorx.New(errorx.CodeBadRequest, "reason is required")
+	}
+
+	now := time.Now()
+	if !exception.ExpiresAt.After(now) {
+		return errorx.New(errorx.CodeBadRequest, "expiry must be in the future")
+	}
+	if exception.ExpiresAt.Sub(now) > domain.MaxCVEExceptionDuration {
+		return errorx.New(errorx.CodeBadRequest, fmt.Sprintf("exceptions may last at most %d days", int(domain.MaxCVEExceptionDuration.Hours()/24)))
+	}
+
+	if exception.EnvironmentID != nil {
+		if _, err := u.envRepo.GetByID(ctx, *exception.EnvironmentID); err != nil {
+			return errorx.Wrap(err, errorx.CodeNotFound, "environment not found")
+		}
+	}
+
+	exception.ID = ""
+	exception.Status = domain.CVEExceptionStatusPending
+	exception.RequestedBy = userID
+	exception.ReviewedBy = nil
+	exception.ReviewedAt = nil
+	return u.policyRepo.CreateException(ctx, exception)
+}
+
+func (u *vulnerabilityGateUsecase) ApproveException(ctx context.Context, id, userID string) (*domain.CVEException, error) {
+	return u.reviewException(ctx, id, userID, domain.CVEExceptionStatusApproved)
+}
+
+func (u *vulnerabilityGateUsecase) RejectException(ctx context.Context, id, userID string) (*domain.CVEException, error) {
+	return u.reviewException(ctx, id, userID, domain.CVEExceptionStatusRejected)
+}
+
+// reviewException moves a pending exception to approved or rejected on behalf of an admin
+func (u *vulnerabilityGateUsecase) reviewException(ctx context.Context, id, userID string, status domain.CVEExceptionStatus) (*domain.CVEException, error) {
+	if err := u.requireApprover(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	exception, err := u.policyRepo.GetException(ctx, id)
+	if err != nil {
+		return nil, errorx.Wrap(err, errorx.CodeNotFound, "failed to get CVE exception")
+	}
+	if exception.Status != domain.CVEExceptionStatusPending {
+		return nil, errorx.New(errorx.CodeConflict, fmt.Sprintf("CVE exception is already %s", exception.Status))
+	}
+
+	now := time.Now()
+	if status == domain.CVEExceptionStatusApproved && !exception.ExpiresAt.After(now) {
+		return nil, errorx.New(errorx.CodeConflict, "CVE exception has already expired")
+	}
+
+	exception.Status = status
+	exception.ReviewedBy = &userID
+	exception.ReviewedAt = &now
+	if err := u.policyRepo.UpdateException(ctx, exception); err != nil {
+		return nil, err
+	}
+	return exception, nil
+}
+
+// RevokeException withdraws an exception; the requester or an admin may revoke it
+func (u *vulnerabilityGateUsecase) RevokeException(ctx context.Context, id, userID string) (*domain.CVEException, error) {
+	exception, err := u.policyRepo.GetException(ctx, id)
+	if err != nil {
+		return nil, errorx.Wrap(err, errorx.CodeNotFound, "failed to get CVE exception")
+	}
+	if userID == "" || exception.RequestedBy != userID {
+		if err := u.requireApprover(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	if exception.Status != domain.CVEExceptionStatusPending && exception.Status != domain.CVEExceptionStatusApproved {
+		return nil, errorx.New(errorx.CodeConflict, fmt.Sprintf("CVE exception is already %s", exception.Status))
+	}
+
+	now := time.Now()
+	exception.Status = domain.CVEExceptionStatusRevoked
+	exception.ReviewedBy = &userID
+	exception.ReviewedAt = &now
+	if err := u.policyRepo.UpdateException(ctx, exception); err != nil {
+		return nil, err
+	}
+	return exception, nil
+}
+
+func (u *vulnerabilityGateUsecase) ListExceptions(ctx context.Context, filter domain.CVEExceptionFilter) ([]*domain.CVEException, error) {
+	return u.policyRepo.ListExceptions(ctx, filter)
+}
+
+func (u *vulnerabilityGateUsecase) requireApprover(ctx context.Context, userID string) error {
+	if userID == "" {
+		return errorx.New(errorx.CodeUnauthorized, "user not authenticated")
+	}
+	allowed, err := u.authorizationUsecase.CheckPermission(ctx, userID, domain.PermissionApproveCVEException)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errorx.New(errorx.CodeForbidden, "approving CVE exceptions requires the "+domain.PermissionApproveCVEException+" permission")
+	}
+	return nil
+}
+
+// Evaluation
+
+func (u *vulnerabilityGateUsecase) Evaluate(ctx context.Context, environmentID string, images []string) (*domain.VulnerabilityGateReport, error) {
+	if len(images) == 0 {
+		return nil, errorx.New(errorx.CodeBadRequest, "at least one image is required")
+	}
+	policy, err := u.GetPolicy(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	return u.evaluate(ctx, policy, images)
+}
+
+func (u *vulnerabilityGateUsecase) CheckEnvironment(ctx context.Context, environmentID string, images []string) (*domain.VulnerabilityGateReport, error) {
+	if environmentID == "" || len(images) == 0 {
+		return nil, nil
+	}
+	policy, err := u.policyRepo.GetPolicyByEnvironment(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil || !policy.Enabled {
+		return nil, nil
+	}
+
+	report, err := u.evaluate(ctx, policy, images)
+	if err != nil {
+		return nil, err
+	}
+	if report.Decision == domain.VulnerabilityGateDecisionBlock {
+		return report, &domain.VulnerabilityGateError{Report: report}
+	}
+	return report, nil
+}
+
+func (u *vulnerabilityGateUsecase) CheckCluster(ctx context.Context, clusterID string, images []string) (*domain.VulnerabilityGateReport, error) {
+	if len(images) == 0 {
+		return nil, nil
+	}
+	cluster, err := u.k8sRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.EnvironmentID == nil {
+		return nil, nil
+	}
+	return u.CheckEnvironment(ctx, *cluster.EnvironmentID, images)
+}
+
+// evaluate checks every image against the policy and the active exceptions of its environment
+func (u *vulnerabilityGateUsecase) evaluate(ctx context.Context, policy *domain.VulnerabilityPolicy, images []string) (*domain.VulnerabilityGateReport, error) {
+	exceptions, err := u.policyRepo.ListExceptions(ctx, domain.CVEExceptionFilter{
+		EnvironmentID: policy.EnvironmentID,
+		ActiveOnly:    true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CVE exceptions: %w", err)
+	}
+
+	active := true
+	registries, _, err := u.harborUsecase.ListRegistries(ctx, domain.HarborRegistryFilter{IsActive: &active})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Harbor registries: %w", err)
+	}
+	registryByHost := make(map[string]string, len(registries))
+	for _, registry := range registries {
+		if parsed, err := url.Parse(registry.URL); err == nil && parsed.Host != "" {
+			registryByHost[strings.ToLower(parsed.Host)] = registry.ID
+		}
+	}
+
+	report := &domain.VulnerabilityGateReport{
+		EnvironmentID: policy.EnvironmentID,
+		PolicyID:      policy.ID,
+		Mode:          policy.Mode,
+		Decision:      domain.VulnerabilityGateDecisionPass,
+		EvaluatedAt:   time.Now(),
+	}
+
+	// Images are evaluated in parallel and share one deadline for the scans they trigger, so a
+	// deployment waits for the slowest scan rather than for every scan in turn
+	timeout := time.Duration(policy.ScanTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultScanTimeout
+	}
+	scanDeadline := time.Now().Add(timeout)
+
+	var unique []string
+	for _, image := range sortedUnique(images) {
+		if image != "" {
+			unique = append(unique, image)
+		}
+	}
+	results := make([]domain.VulnerabilityGateImageResult, len(unique))
+	var wg sync.WaitGroup
+	for i, image := range unique {
+		wg.Add(1)
+		go func(i int, image string) {
+			defer wg.Done()
+			results[i] = u.evaluateImage(ctx, policy, image, registryByHost, exceptions, scanDeadline)
+		}(i, image)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if decisionRank(result.Decision) > decisionRank(report.Decision) {
+			report.Decision = result.Decision
+		}
+		report.Images = append(report.Images, result)
+	}
+	return report, nil
+}
+
+func (u *vulnerabilityGateUsecase) evaluateImage(ctx context.Context, policy *domain.VulnerabilityPolicy, image string, registryByHost map[string]string, exceptions []*domain.CVEException, scanDeadline time.Time) domain.VulnerabilityGateImageResult {
+	result := domain.VulnerabilityGateImageResult{Image: image, Decision: domain.VulnerabilityGateDecisionPass}
+	violate := func(format string, args ...interface{}) {
+		result.Violations = append(result.Violations, fmt.Sprintf(format, args...))
+		if policy.Mode == domain.VulnerabilityGateModeWarn {
+			result.Decision = domain.VulnerabilityGateDecisionWarn
+		} else {
+			result.Decision = domain.VulnerabilityGateDecisionBlock
+		}
+	}
+
+	host, repository, reference := parseImageReference(image)
+	registryID, managed := registryByHost[host]
+	if !managed {
+		if !policy.AllowUnmanagedRegistries {
+			violate("image is not from a managed Harbor registry")
+		}
+		return result
+	}
+	result.RegistryID = registryID
+	result.Repository = repository
+	result.Reference = reference
+
+	artifact, err := u.harborUsecase.GetArtifact(ctx, registryID, repository, reference)
+	if err != nil {
+		violate("failed to look up artifact: %v", err)
+		return result
+	}
+
+	overview := artifact.ScanOverview
+	if overview == nil {
+		if err := u.harborUsecase.ScanArtifact(ctx, registryID, repository, reference); err != nil {
+			violate("failed to trigger vulnerability scan: %v", err)
+			return result
+		}
+		overview = u.waitForScan(ctx, registryID, repository, reference, scanDeadline)
+	}
+
+	result.ScanStatus = "Not Scanned"
+	if overview != nil {
+		result.ScanStatus = overview.ScanStatus
+	}
+	if result.ScanStatus != "Success" {
+		if policy.BlockUnscanned {
+			violate("no completed vulnerability scan (status: %s)", result.ScanStatus)
+		}
+		return result
+	}
+
+	vulnerabilities, err := u.harborUsecase.GetVulnerabilities(ctx, registryID, repository, reference)
+	if err != nil {
+		violate("failed to load vulnerabilities: %v", err)
+		return result
+	}
+
+	bySeverity := map[string][]*domain.HarborVulnerability{}
+	var exempted []string
+	for _, vulnerability := range vulnerabilities {
+		if policy.FixableOnly && vulnerability.FixVersion == "" {
+			continue
+		}
+		if isExempted(exceptions, vulnerability.ID, repository) {
+			exempted = append(exempted, vulnerability.ID)
+			continue
+		}
+		severity := normalizeSeverity(vulnerability.Severity)
+		bySeverity[severity] = append(bySeverity[severity], vulnerability)
+	}
+	if len(exempted) > 0 {
+		result.ExemptedCVEs = sortedUnique(exempted)
+	}
+
+	result.Summary = domain.HarborVulnerabilitySummary{
+		Critical: len(bySeverity["Critical"]),
+		High:     len(bySeverity["High"]),
+		Medium:   len(bySeverity["Medium"]),
+		Low:      len(bySeverity["Low"]),
+		Unknown:  len(bySeverity["Unknown"]),
+	}
+	result.Summary.Total = result.Summary.Critical + result.Summary.High + result.Summary.Medium + result.Summary.Low + result.Summary.Unknown
+
+	limits := []struct {
+		severity string
+		max      *int
+	}{
+		{"Critical", policy.MaxCritical},
+		{"High", policy.MaxHigh},
+		{"Medium", policy.MaxMedium},
+	}
+	for _, limit := range limits {
+		found := bySeverity[limit.severity]
+		if limit.max == nil || len(found) <= *limit.max {
+			continue
+		}
+		violate("%d %s vulnerabilities exceed the limit of %d", len(found), limit.severity, *limit.max)
+		result.Vulnerabilities = append(result.Vulnerabilities, found...)
+	}
+	sort.SliceStable(result.Vulnerabilities, func(i, j int) bool {
+		return result.Vulnerabilities[i].CVSSScore > result.Vulnerabilities[j].CVSSScore
+	})
+
+	return result
+}
+
+// waitForScan polls the scan overview of an artifact until the scan finishes or the deadline passes
+func (u *vulnerabilityGateUsecase) waitForScan(ctx context.Context, registryID, repository, reference string, scanDeadline time.Time) *domain.HarborScanOverview {
+	deadline := time.NewTimer(time.Until(scanDeadline))
+	defer deadline.Stop()
+	ticker := time.NewTicker(scanPollInterval)
+	defer ticker.Stop()
+
+	var overview *domain.HarborScanOverview
+	for {
+		select {
+		case <-ctx.Done():
+			return overview
+		case <-deadline.C:
+			return overview
+		case <-ticker.C:
+			current, err := u.harborUsecase.GetScanReport(ctx, registryID, repository, reference)
+			if err != nil {
+				continue
+			}
+			overview = current
+			switch current.ScanStatus {
+			case "Success", "Error", "Stopped":
+				return overview
+			}
+		}
+	}
+}
+
+// parseImageReference splits an image into its registry host, repository path and tag or digest
+func parseImageReference(image string) (string, string, string) {
+	name, reference := splitImageReference(image)
+	host := "docker.io"
+	if slash := strings.Index(name, "/"); slash >= 0 {
+		first := name[:slash]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			host = first
+			name = name[slash+1:]
+		}
+	}
+	return strings.ToLower(host), name, reference
+}
+
+func isExempted(exceptions []*domain.CVEException, cveID, repository string) bool {
+	now := time.Now()
+	for _, exception := range exceptions {
+		if exception.IsActive(now) && exception.Covers(cveID, repository) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeSeverity maps Harbor severities onto the buckets of HarborVulnerabilitySummary
+func normalizeSeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "Critical"
+	case "high":
+		return "High"
+	case "medium":
+		return "Medium"
+	case "low", "negligible":
+		return "Low"
+	default:
+		return "Unknown"
+	}
+}
+
+func decisionRank(decision domain.VulnerabilityGateDecision) int {
+	switch decision {
+	case domain.VulnerabilityGateDecisionBlock:
+		return 2
+	case domain.VulnerabilityGateDecisionWarn:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// formatImageReference joins a repository and a tag or digest back into an image reference
+func formatImageReference(repository, reference string) string {
+	if reference == "" {
+		return repository
+	}
+	if strings.Contains(reference, ":") {
+		return repository + "@" + reference
+	}
+	return repository + ":" + reference
+}
@@ -0,0 +1,357 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/unitechio/einfra-be/internal/domain"
+	"github.com/unitechio/einfra-be/internal/usecase"
+)
+
+// MockVulnerabilityPolicyRepository is a mock implementation of domain.VulnerabilityPolicyRepository
+type MockVulnerabilityPolicyRepository struct {
+	mock.Mock
+}
+
+func (m *MockVulnerabilityPolicyRepository) GetPolicyByEnvironment(ctx context.Context, environmentID string) (*domain.VulnerabilityPolicy, error) {
+	args := m.Called(ctx, environmentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.VulnerabilityPolicy), args.Error(1)
+}
+
+func (m *MockVulnerabilityPolicyRepository) ListPolicies(ctx context.Context) ([]*domain.VulnerabilityPolicy, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*domain.VulnerabilityPolicy), args.Error(1)
+}
+
+func (m *MockVulnerabilityPolicyRepository) SavePolicy(ctx context.Context, policy *domain.VulnerabilityPolicy) error {
+	args := m.Called(ctx, policy)
+	return args.Error(0)
+}
+
+func (m *MockVulnerabilityPolicyRepository) DeletePolicy(ctx context.Context, environmentID string) error {
+	args := m.Called(ctx, environmentID)
+	return args.Error(0)
+}
+
+func (m *MockVulnerabilityPolicyRepository) CreateException(ctx context.Context, exception *domain.CVEException) error {
+	args := m.Called(ctx, exception)
+	return args.Error(0)
+}
+
+func (m *MockVulnerabilityPolicyRepository) GetException(ctx context.Context, id string) (*domain.CVEException, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CVEException), args.Error(1)
+}
+
+func (m *MockVulnerabilityPolicyRepository) UpdateException(ctx context.Context, exception *domain.CVEException) error {
+	args := m.Called(ctx, exception)
+	return args.Error(0)
+}
+
+func (m *MockVulnerabilityPolicyRepository) ListExceptions(ctx context.Context, filter domain.CVEExceptionFilter) ([]*domain.CVEException, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]*domain.CVEException), args.Error(1)
+}
+
+// MockHarborUsecase mocks the Harbor calls of the vulnerability gate. The other methods of
+// domain.HarborUsecase are left to the embedded nil interface and panic when called.
+type MockHarborUsecase struct {
+	mock.Mock
+	domain.HarborUsecase
+}
+
+func (m *MockHarborUsecase) ListRegistries(ctx context.Context, filter domain.HarborRegistryFilter) ([]*domain.HarborRegistry, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]*domain.HarborRegistry), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockHarborUsecase) GetArtifact(ctx context.Context, registryID, repositoryName, reference string) (*domain.HarborArtifact, error) {
+	args := m.Called(ctx, registryID, repositoryName, reference)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.HarborArtifact), args.Error(1)
+}
+
+func (m *MockHarborUsecase) ScanArtifact(ctx context.Context, registryID, repositoryName, reference string) error {
+	args := m.Called(ctx, registryID, repositoryName, reference)
+	return args.Error(0)
+}
+
+func (m *MockHarborUsecase) GetScanReport(ctx context.Context, registryID, repositoryName, reference string) (*domain.HarborScanOverview, error) {
+	args := m.Called(ctx, registryID, repositoryName, reference)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.HarborScanOverview), args.Error(1)
+}
+
+func (m *MockHarborUsecase) GetVulnerabilities(ctx context.Context, registryID, repositoryName, reference string) ([]*domain.HarborVulnerability, error) {
+	args := m.Called(ctx, registryID, repositoryName, reference)
+	return args.Get(0).([]*domain.HarborVulnerability), args.Error(1)
+}
+
+const gateImage = "harbor.example.com/shop/api:v2"
+
+// newGateHarbor serves shop/api:v2 from the registry at harbor.example.com with a scan in the given
+// status and the given vulnerabilities
+func newGateHarbor(scanStatus string, vulnerabilities []*domain.HarborVulnerability) *MockHarborUsecase {
+	harbor := new(MockHarborUsecase)
+	harbor.On("ListRegistries", mock.Anything, mock.Anything).Return([]*domain.HarborRegistry{
+		{ID: "reg-1", URL: "https://harbor.example.com"},
+	}, int64(1), nil)
+	harbor.On("GetArtifact", mock.Anything, "reg-1", "shop/api", "v2").Return(&domain.HarborArtifact{
+		Digest:       "sha256:abc",
+		ScanOverview: &domain.HarborScanOverview{ScanStatus: scanStatus},
+	}, nil)
+	harbor.On("GetVulnerabilities", mock.Anything, "reg-1", "shop/api", "v2").Return(vulnerabilities, nil)
+	return harbor
+}
+
+func gateLimit(n int) *int {
+	return &n
+}
+
+// TestVulnerabilityGateEvaluate tests policy thresholds, modes, filters and CVE exceptions
+func TestVulnerabilityGateEvaluate(t *testing.T) {
+	vulnerabilities := []*domain.HarborVulnerability{
+		{ID: "CVE-2024-0001", Severity: "Critical", FixVersion: "1.2.3", CVSSScore: 9.8},
+		{ID: "CVE-2024-0002", Severity: "High", CVSSScore: 7.5},
+		{ID: "CVE-2024-0003", Severity: "High", FixVersion: "2.0.0", CVSSScore: 8.1},
+		{ID: "CVE-2024-0004", Severity: "negligible"},
+	}
+	expires := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name       string
+		policy     domain.VulnerabilityPolicy
+		image      string
+		scanStatus string
+		exceptions []*domain.CVEException
+		decision   domain.VulnerabilityGateDecision
+		violations int
+		exempted   []string
+	}{
+		{
+			name:     "Within limits",
+			policy:   domain.VulnerabilityPolicy{Mode: domain.VulnerabilityGateModeEnforce, MaxCritical: gateLimit(1), MaxHigh: gateLimit(2)},
+			decision: domain.VulnerabilityGateDecisionPass,
+		},
+		{
+			name:       "Critical over the limit blocks",
+			policy:     domain.VulnerabilityPolicy{Mode: domain.VulnerabilityGateModeEnforce, MaxCritical: gateLimit(0)},
+			decision:   domain.VulnerabilityGateDecisionBlock,
+			violations: 1,
+		},
+		{
+			name:       "Every exceeded limit is reported",
+			policy:     domain.VulnerabilityPolicy{Mode: domain.VulnerabilityGateModeEnforce, MaxCritical: gateLimit(0), MaxHigh: gateLimit(1)},
+			decision:   domain.VulnerabilityGateDecisionBlock,
+			violations: 2,
+		},
+		{
+			name:       "Warn mode only warns",
+			policy:     domain.VulnerabilityPolicy{Mode: domain.VulnerabilityGateModeWarn, MaxCritical: gateLimit(0)},
+			decision:   domain.VulnerabilityGateDecisionWarn,
+			violations: 1,
+		},
+		{
+			name:     "Fixable only ignores CVEs without a fix",
+			policy:   domain.VulnerabilityPolicy{Mode: domain.VulnerabilityGateModeEnforce, MaxHigh: gateLimit(1), FixableOnly: true},
+			decision: domain.VulnerabilityGateDecisionPass,
+		},
+		{
+			name:   "Approved exception exempts the CVE",
+			policy: domain.VulnerabilityPolicy{Mode: domain.VulnerabilityGateModeEnforce, MaxCritical: gateLimit(0)},
+			exceptions: []*domain.CVEException{
+				{CVEID: "cve-2024-0001", Repository: "shop/api", Status: domain.CVEExceptionStatusApproved, ExpiresAt: expires},
+			},
+			decision: domain.VulnerabilityGateDecisionPass,
+			exempted: []string{"CVE-2024-0001"},
+		},
+		{
+			name:   "Exception of another repository does not apply",
+			policy: domain.VulnerabilityPolicy{Mode: domain.VulnerabilityGateModeEnforce, MaxCritical: gateLimit(0)},
+			exceptions: []*domain.CVEException{
+				{CVEID: "CVE-2024-0001", Repository: "shop/web", Status: domain.CVEExceptionStatusApproved, ExpiresAt: expires},
+			},
+			decision:   domain.VulnerabilityGateDecisionBlock,
+			violations: 1,
+		},
+		{
+			name:   "Expired exception does not apply",
+			policy: domain.VulnerabilityPolicy{Mode: domain.VulnerabilityGateModeEnforce, MaxCritical: gateLimit(0)},
+			exceptions: []*domain.CVEException{
+				{CVEID: "CVE-2024-0001", Status: domain.CVEExceptionStatusApproved, ExpiresAt: time.Now().Add(-time.Hour)},
+			},
+			decision:   domain.VulnerabilityGateDecisionBlock,
+			violations: 1,
+		},
+		{
+			name:       "Failed scan blocks",
+			policy:     domain.VulnerabilityPolicy{Mode: domain.VulnerabilityGateModeEnforce, BlockUnscanned: true},
+			scanStatus: "Error",
+			decision:   domain.VulnerabilityGateDecisionBlock,
+			violations: 1,
+		},
+		{
+			name:       "Failed scan passes when unscanned images are allowed",
+			policy:     domain.VulnerabilityPolicy{Mode: domain.VulnerabilityGateModeEnforce},
+			scanStatus: "Error",
+			decision:   domain.VulnerabilityGateDecisionPass,
+		},
+		{
+			name:       "Unmanaged registry blocks",
+			policy:     domain.VulnerabilityPolicy{Mode: domain.VulnerabilityGateModeEnforce},
+			image:      "docker.io/library/nginx:1.25",
+			decision:   domain.VulnerabilityGateDecisionBlock,
+			violations: 1,
+		},
+		{
+			name:     "Unmanaged registry allowed",
+			policy:   domain.VulnerabilityPolicy{Mode: domain.VulnerabilityGateModeEnforce, AllowUnmanagedRegistries: true},
+			image:    "nginx:1.25",
+			decision: domain.VulnerabilityGateDecisionPass,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.image == "" {
+				tt.image = gateImage
+			}
+			if tt.scanStatus == "" {
+				tt.scanStatus = "Success"
+			}
+			policy := tt.policy
+			policy.EnvironmentID = "env-prod"
+
+			policyRepo := new(MockVulnerabilityPolicyRepository)
+			policyRepo.On("GetPolicyByEnvironment", mock.Anything, "env-prod").Return(&policy, nil)
+			policyRepo.On("ListExceptions", mock.Anything, domain.CVEExceptionFilter{EnvironmentID: "env-prod", ActiveOnly: true}).Return(tt.exceptions, nil)
+
+			uc := usecase.NewVulnerabilityGateUsecase(policyRepo, nil, nil, newGateHarbor(tt.scanStatus, vulnerabilities), nil)
+			report, err := uc.Evaluate(context.Background(), "env-prod", []string{tt.image})
+			require.NoError(t, err)
+			require.Len(t, report.Images, 1)
+
+			result := report.Images[0]
+			assert.Equal(t, tt.decision, report.Decision)
+			assert.Equal(t, tt.decision, result.Decision)
+			assert.Len(t, result.Violations, tt.violations)
+			assert.Equal(t, tt.exempted, result.ExemptedCVEs)
+		})
+	}
+}
+
+// TestVulnerabilityGateCheckEnvironment tests that deployments are only gated by the enabled policy
+// of their environment, and that a block surfaces as a VulnerabilityGateError
+func TestVulnerabilityGateCheckEnvironment(t *testing.T) {
+	enforce := &domain.VulnerabilityPolicy{EnvironmentID: "env-prod", Enabled: true, Mode: domain.VulnerabilityGateModeEnforce, MaxCritical: gateLimit(0)}
+	disabled := &domain.VulnerabilityPolicy{EnvironmentID: "env-dev", Mode: domain.VulnerabilityGateModeEnforce, MaxCritical: gateLimit(0)}
+
+	policyRepo := new(MockVulnerabilityPolicyRepository)
+	policyRepo.On("GetPolicyByEnvironment", mock.Anything, "env-prod").Return(enforce, nil)
+	policyRepo.On("GetPolicyByEnvironment", mock.Anything, "env-dev").Return(disabled, nil)
+	policyRepo.On("GetPolicyByEnvironment", mock.Anything, "env-test").Return(nil, nil)
+	policyRepo.On("ListExceptions", mock.Anything, mock.Anything).Return([]*domain.CVEException(nil), nil)
+
+	devEnvironment := "env-dev"
+	prodEnvironment := "env-prod"
+	clusterRepo := new(MockK8sClusterRepository)
+	clusterRepo.On("GetByID", mock.Anything, "cluster-unassigned").Return(&domain.K8sCluster{ID: "cluster-unassigned"}, nil)
+	clusterRepo.On("GetByID", mock.Anything, "cluster-dev").Return(&domain.K8sCluster{ID: "cluster-dev", EnvironmentID: &devEnvironment}, nil)
+	clusterRepo.On("GetByID", mock.Anything, "cluster-prod").Return(&domain.K8sCluster{ID: "cluster-prod", EnvironmentID: &prodEnvironment}, nil)
+
+	harbor := newGateHarbor("Success", []*domain.HarborVulnerability{{ID: "CVE-2024-0001", Severity: "Critical"}})
+	uc := usecase.NewVulnerabilityGateUsecase(policyRepo, nil, clusterRepo, harbor, nil)
+	images := []string{gateImage}
+
+	t.Run("No environment", func(t *testing.T) {
+		report, err := uc.CheckEnvironment(context.Background(), "", images)
+		assert.NoError(t, err)
+		assert.Nil(t, report)
+	})
+
+	t.Run("No policy", func(t *testing.T) {
+		report, err := uc.CheckEnvironment(context.Background(), "env-test", images)
+		assert.NoError(t, err)
+		assert.Nil(t, report)
+	})
+
+	t.Run("Disabled policy", func(t *testing.T) {
+		report, err := uc.CheckEnvironment(context.Background(), "env-dev", images)
+		assert.NoError(t, err)
+		assert.Nil(t, report)
+	})
+
+	t.Run("Enforced policy blocks", func(t *testing.T) {
+		report, err := uc.CheckEnvironment(context.Background(), "env-prod", images)
+		var gateErr *domain.VulnerabilityGateError
+		require.True(t, errors.As(err, &gateErr))
+		assert.Equal(t, domain.VulnerabilityGateDecisionBlock, report.Decision)
+		assert.Same(t, report, gateErr.Report)
+	})
+
+	t.Run("Cluster without environment", func(t *testing.T) {
+		report, err := uc.CheckCluster(context.Background(), "cluster-unassigned", images)
+		assert.NoError(t, err)
+		assert.Nil(t, report)
+	})
+
+	t.Run("Cluster of a disabled environment", func(t *testing.T) {
+		report, err := uc.CheckCluster(context.Background(), "cluster-dev", images)
+		assert.NoError(t, err)
+		assert.Nil(t, report)
+	})
+
+	t.Run("Cluster of an enforced environment", func(t *testing.T) {
+		_, err := uc.CheckCluster(context.Background(), "cluster-prod", images)
+		var gateErr *domain.VulnerabilityGateError
+		assert.True(t, errors.As(err, &gateErr))
+	})
+}
+
+// TestVulnerabilityGateWaitsForScansInParallel tests that images whose scans were triggered share one
+// deadline instead of each waiting the full timeout
+func TestVulnerabilityGateWaitsForScansInParallel(t *testing.T) {
+	policy := &domain.VulnerabilityPolicy{EnvironmentID: "env-prod", Mode: domain.VulnerabilityGateModeEnforce, BlockUnscanned: true, ScanTimeoutSeconds: 1}
+	policyRepo := new(MockVulnerabilityPolicyRepository)
+	policyRepo.On("GetPolicyByEnvironment", mock.Anything, "env-prod").Return(policy, nil)
+	policyRepo.On("ListExceptions", mock.Anything, mock.Anything).Return([]*domain.CVEException(nil), nil)
+
+	harbor := new(MockHarborUsecase)
+	harbor.On("ListRegistries", mock.Anything, mock.Anything).Return([]*domain.HarborRegistry{
+		{ID: "reg-1", URL: "https://harbor.example.com"},
+	}, int64(1), nil)
+	images := []string{"harbor.example.com/shop/api:v2", "harbor.example.com/shop/web:v2", "harbor.example.com/shop/worker:v2"}
+	for _, repository := range []string{"shop/api", "shop/web", "shop/worker"} {
+		harbor.On("GetArtifact", mock.Anything, "reg-1", repository, "v2").Return(&domain.HarborArtifact{Digest: "sha256:abc"}, nil)
+		harbor.On("ScanArtifact", mock.Anything, "reg-1", repository, "v2").Return(nil).Once()
+	}
+
+	uc := usecase.NewVulnerabilityGateUsecase(policyRepo, nil, nil, harbor, nil)
+	start := time.Now()
+	report, err := uc.Evaluate(context.Background(), "env-prod", images)
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(start), 2*time.Second)
+	harbor.AssertExpectations(t)
+	require.Len(t, report.Images, 3)
+	assert.Equal(t, "harbor.example.com/shop/api:v2", report.Images[0].Image)
+	for _, result := range report.Images {
+		assert.Equal(t, "Not Scanned", result.ScanStatus)
+		assert.Equal(t, domain.VulnerabilityGateDecisionBlock, result.Decision)
+	}
+}
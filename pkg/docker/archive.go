@@ -0,0 +1,40 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// ContainerStatPath describes a path inside a container; symlinks are reported with their target
+func (c *Client) ContainerStatPath(ctx context.Context, containerID, path string) (container.PathStat, error) {
+	return c.cli.ContainerStatPath(ctx, containerID, path)
+}
+
+// CopyFromContainer returns a tar archive of a file or directory inside a container. The container
+// does not need to be running.
+func (c *Client) CopyFromContainer(ctx context.Context, containerID, path string) (io.ReadCloser, container.PathStat, error) {
+	return c.cli.CopyFromContainer(ctx, containerID, path)
+}
+
+// CopyToContainer extracts a tar archive into a directory of a container. Extracted files keep the
+// owners recorded in the archive, or belong to the user the container runs as when copyUIDGID is set.
+func (c *Client) CopyToContainer(ctx context.Context, containerID, dir string, archive io.Reader, copyUIDGID bool) error {
+	return c.cli.CopyToContainer(ctx, containerID, dir, archive, container.CopyToContainerOptions{CopyUIDGID: copyUIDGID})
+}
+
+// ContainerWait waits for a container to exit and returns its exit code
+func (c *Client) ContainerWait(ctx context.Context, containerID string) (int64, error) {
+	resultC, errC := c.cli.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
+	select {
+	case result := <-resultC:
+		if result.Error != nil && result.Error.Message != "" {
+			return result.StatusCode, errors.New(result.Error.Message)
+		}
+		return result.StatusCode, nil
+	case err := <-errC:
+		return 0, err
+	}
+}
@@ -1,27 +1,266 @@
 package docker
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/client"
+	"github.com/unitechio/einfra-be/pkg/ssh"
+)
+
+const (
+	// defaultSocketPath is the daemon socket used on hosts reached over SSH when the endpoint names none
+	defaultSocketPath = "/var/run/docker.sock"
+	// retireInterval is how often a retired client checks whether its connections are done
+	retireInterval = 10 * time.Second
 )
 
+// errClientClosed is returned when a retired client is asked for a new connection after closing
+var errClientClosed = errors.New("docker client is closed")
+
+// Config describes how to reach a Docker daemon
+type Config struct {
+	Host       string      // unix://, tcp:// or ssh:// endpoint; an ssh:// path names the remote socket
+	TLSEnabled bool        // Use TLS for tcp:// endpoints
+	CertPath   string      // Directory with ca.pem, cert.pem and key.pem; system roots are trusted when empty
+	SSH        *ssh.Config // Credentials for ssh:// endpoints
+}
+
 type Client struct {
 	cli *client.Client
+	ssh *ssh.Client // Tunnel of ssh:// endpoints
+
+	mu     sync.Mutex
+	conns  int  // Open connections through the tunnel
+	closed bool // Set once a retired client closed its tunnel
 }
 
 func NewClient(host string) (*Client, error) {
-	cli, err := client.NewClientWithOpts(
-		client.WithHost(host),
-		client.WithAPIVersionNegotiation(),
-	)
+	return NewClientFromConfig(Config{Host: host})
+}
+
+// NewClientFromConfig creates a client for a unix socket, TCP (optionally TLS) or SSH endpoint
+func NewClientFromConfig(cfg Config) (*Client, error) {
+	endpoint, err := url.Parse(cfg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker endpoint %q: %w", cfg.Host, err)
+	}
+
+	c := &Client{}
+	opts := []client.Opt{client.WithAPIVersionNegotiation()}
+	var tunnel *ssh.Client
+	switch endpoint.Scheme {
+	case "ssh":
+		if cfg.SSH == nil {
+			return nil, errors.New("ssh endpoints require SSH credentials")
+		}
+		tunnel, err = ssh.NewClient(*cfg.SSH)
+		if err != nil {
+			return nil, err
+		}
+		socket := endpoint.Path
+		if socket == "" || socket == "/" {
+			socket = defaultSocketPath
+		}
+		// The host is a placeholder; every connection is dialed through the tunnel
+		opts = append(opts,
+			client.WithHost("http://docker"),
+			client.WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return c.dialTunnel(tunnel, socket)
+			}),
+		)
+	case "tcp":
+		if cfg.TLSEnabled {
+			if cfg.CertPath != "" {
+				opts = append(opts, client.WithTLSClientConfig(
+					filepath.Join(cfg.CertPath, "ca.pem"),
+					filepath.Join(cfg.CertPath, "cert.pem"),
+					filepath.Join(cfg.CertPath, "key.pem"),
+				))
+			} else {
+				opts = append(opts, client.WithHTTPClient(&http.Client{
+					Transport: &http.Transport{TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12}},
+				}))
+			}
+		}
+		opts = append(opts, client.WithHost(cfg.Host))
+	default:
+		opts = append(opts, client.WithHost(cfg.Host))
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		if tunnel != nil {
+			tunnel.Close()
+		}
+		return nil, err
+	}
+
+	c.cli = cli
+	c.ssh = tunnel
+	return c, nil
+}
+
+// dialTunnel opens a connection to the daemon socket through the tunnel and counts it until closed
+func (c *Client) dialTunnel(tunnel *ssh.Client, socket string) (net.Conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, errClientClosed
+	}
+	conn, err := tunnel.Dial("unix", socket)
 	if err != nil {
 		return nil, err
 	}
+	c.conns++
+	return &tunnelConn{Conn: conn, client: c}, nil
+}
+
+// tunnelConn releases its slot in the client's connection count once closed
+type tunnelConn struct {
+	net.Conn
+	client *Client
+	once   sync.Once
+}
 
-	return &Client{
-		cli: cli,
-	}, nil
+func (t *tunnelConn) Close() error {
+	err := t.Conn.Close()
+	t.once.Do(func() {
+		t.client.mu.Lock()
+		t.client.conns--
+		t.client.mu.Unlock()
+	})
+	return err
 }
 
 func (c *Client) Close() error {
-	return c.cli.Close()
+	err := c.cli.Close()
+	if c.ssh != nil {
+		if sshErr := c.ssh.Close(); err == nil {
+			err = sshErr
+		}
+	}
+	return err
+}
+
+// retire closes the client once no connection to its daemon is open anymore, so requests and streams
+// already in flight finish on it. Clients without a tunnel hold nothing beyond their connections and close at once.
+func (c *Client) retire() {
+	if c.ssh == nil {
+		c.Close()
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(retireInterval)
+		defer ticker.Stop()
+		for {
+			// Drops idle keep-alive connections; connections in use stay open
+			c.cli.Close()
+
+			c.mu.Lock()
+			if c.conns == 0 {
+				c.closed = true
+				c.mu.Unlock()
+				c.ssh.Close()
+				return
+			}
+			c.mu.Unlock()
+			<-ticker.C
+		}
+	}()
+}
+
+// IsNotFound reports whether the daemon answered that an object does not exist
+func IsNotFound(err error) bool {
+	return cerrdefs.IsNotFound(err)
+}
+
+// ServerVersion pings the daemon and returns its version
+func (c *Client) ServerVersion(ctx context.Context) (string, error) {
+	version, err := c.cli.ServerVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+	return version.Version, nil
+}
+
+// ClientManager caches one client per Docker host
+type ClientManager struct {
+	clients map[string]*Client
+	mu      sync.RWMutex
+}
+
+// NewClientManager creates a new client manager
+func NewClientManager() *ClientManager {
+	return &ClientManager{
+		clients: make(map[string]*Client),
+	}
+}
+
+// Lookup returns the cached client for a host, if any
+func (m *ClientManager) Lookup(hostID string) (*Client, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.clients[hostID]
+	return c, ok
+}
+
+// Get returns the cached client for a host or creates one from cfg
+func (m *ClientManager) Get(hostID string, cfg Config) (*Client, error) {
+	m.mu.RLock()
+	c, ok := m.clients[hostID]
+	m.mu.RUnlock()
+	if ok {
+		return c, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c, ok := m.clients[hostID]; ok {
+		return c, nil
+	}
+
+	c, err := NewClientFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	m.clients[hostID] = c
+	return c, nil
+}
+
+// Remove drops the cached client for a host, e.g. after its endpoint changes. The client is closed
+// once the requests and streams still using it are done.
+func (m *ClientManager) Remove(hostID string) {
+	m.mu.Lock()
+	c, ok := m.clients[hostID]
+	delete(m.clients, hostID)
+	m.mu.Unlock()
+
+	if ok {
+		c.retire()
+	}
+}
+
+// CloseAll closes every cached client
+func (m *ClientManager) CloseAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hostID, c := range m.clients {
+		c.Close()
+		delete(m.clients, hostID)
+	}
 }
@@ -0,0 +1,776 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/graph"
+	"github.com/compose-spec/compose-go/v2/loader"
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/go-connections/nat"
+	"sigs.k8s.io/yaml"
+)
+
+// Labels Docker Compose stamps on the resources of a project, so stacks stay manageable with the compose CLI
+const (
+	ComposeProjectLabel         = "com.docker.compose.project"
+	ComposeServiceLabel         = "com.docker.compose.service"
+	ComposeContainerNumberLabel = "com.docker.compose.container-number"
+	ComposeOneoffLabel          = "com.docker.compose.oneoff"
+	ComposeConfigHashLabel      = "com.docker.compose.config-hash"
+	ComposeNetworkLabel         = "com.docker.compose.network"
+	ComposeVolumeLabel          = "com.docker.compose.volume"
+)
+
+// composeWorkingDir is where relative paths of a compose file resolve to, per project, on the Docker host
+const composeWorkingDir = "/srv/einfra/stacks"
+
+// composeHealthPollInterval is how often a recreated container is inspected during a rolling update
+var composeHealthPollInterval = time.Second
+
+// LoadComposeProject parses a compose file with variables interpolated from env. Attributes that read
+// files of this server (include, env_file, extends from another file) and build sections are rejected,
+// as the project is deployed to a remote daemon.
+func LoadComposeProject(ctx context.Context, projectName, composeFile string, env map[string]string) (*types.Project, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal([]byte(composeFile), &raw); err != nil {
+		return nil, fmt.Errorf("invalid compose file: %w", err)
+	}
+	if _, ok := raw["include"]; ok {
+		return nil, errors.New("compose include is not supported")
+	}
+	services, _ := raw["services"].(map[string]interface{})
+	for name, s := range services {
+		service, _ := s.(map[string]interface{})
+		if _, ok := service["env_file"]; ok {
+			return nil, fmt.Errorf("service %s: env_file is not supported, use stack variables", name)
+		}
+		if _, ok := service["build"]; ok {
+			return nil, fmt.Errorf("service %s: build is not supported, reference a pushed image", name)
+		}
+		if extends, ok := service["extends"].(map[string]interface{}); ok && extends["file"] != nil {
+			return nil, fmt.Errorf("service %s: extends from another file is not supported", name)
+		}
+	}
+
+	project, err := loader.LoadWithContext(ctx, types.ConfigDetails{
+		WorkingDir:  path.Join(composeWorkingDir, projectName),
+		ConfigFiles: []types.ConfigFile{{Filename: "docker-compose.yml", Content: []byte(composeFile)}},
+		Environment: types.Mapping(env),
+	}, func(o *loader.Options) {
+		o.SetProjectName(projectName, true)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid compose file: %w", err)
+	}
+	for name, service := range project.Services {
+		if service.Image == "" {
+			return nil, fmt.Errorf("service %s has no image", name)
+		}
+	}
+	return project, nil
+}
+
+// ComposeUp creates the networks, volumes and containers of a project and starts them in dependency
+// order. Containers whose configuration is unchanged are kept; changed ones are recreated and
+// containers of services no longer in the project are removed.
+func (c *Client) ComposeUp(ctx context.Context, project *types.Project) error {
+	return c.composeUp(ctx, project, 0)
+}
+
+// ComposeRollingUpdate converges a project like ComposeUp, but recreates one container at a time and
+// waits up to healthTimeout for each to pass its healthcheck, or to keep running when it has none,
+// before touching the next. It stops at the first container that fails.
+func (c *Client) ComposeRollingUpdate(ctx context.Context, project *types.Project, healthTimeout time.Duration) error {
+	if healthTimeout <= 0 {
+		return errors.New("health timeout must be positive")
+	}
+	return c.composeUp(ctx, project, healthTimeout)
+}
+
+// composeUp converges a project; services are handled concurrently unless healthTimeout asks for a rolling update
+func (c *Client) composeUp(ctx context.Context, project *types.Project, healthTimeout time.Duration) error {
+	networks, err := c.ensureComposeNetworks(ctx, project)
+	if err != nil {
+		return err
+	}
+	if err := c.ensureComposeVolumes(ctx, project); err != nil {
+		return err
+	}
+
+	existing, err := c.ComposeContainers(ctx, project.Name, "")
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]container.Summary, len(existing))
+	for _, ctr := range existing {
+		if len(ctr.Names) > 0 {
+			byName[strings.TrimPrefix(ctr.Names[0], "/")] = ctr
+		}
+	}
+
+	var mu, rolling sync.Mutex
+	handled := make(map[string]bool)
+	err = graph.InDependencyOrder(ctx, project, func(ctx context.Context, name string, service types.ServiceConfig) error {
+		if healthTimeout > 0 {
+			rolling.Lock()
+			defer rolling.Unlock()
+		}
+		ids, err := c.composeServiceUp(ctx, project, service, networks, byName, healthTimeout)
+		if err != nil {
+			return fmt.Errorf("service %s: %w", name, err)
+		}
+		mu.Lock()
+		for _, id := range ids {
+			handled[id] = true
+		}
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Remove containers of dropped services and scaled-down replicas
+	for _, ctr := range existing {
+		if handled[ctr.ID] {
+			continue
+		}
+		if err := c.cli.ContainerRemove(ctx, ctr.ID, container.RemoveOptions{Force: true}); err != nil {
+			return fmt.Errorf("failed to remove orphan container %s: %w", ctr.ID, err)
+		}
+	}
+	return nil
+}
+
+// composeServiceUp converges the replicas of a service and returns the IDs of the containers it kept,
+// created or replaced. Changed containers are replaced start-first unless the service publishes host
+// ports. New containers are waited on when healthTimeout is set.
+func (c *Client) composeServiceUp(ctx context.Context, project *types.Project, service types.ServiceConfig, networks map[string]string, existing map[string]container.Summary, healthTimeout time.Duration) ([]string, error) {
+	if err := c.composePull(ctx, service); err != nil {
+		return nil, err
+	}
+
+	hash, err := ComposeConfigHash(service)
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := service.GetScale()
+	if service.ContainerName != "" && replicas > 1 {
+		return nil, fmt.Errorf("container_name %s cannot be used with %d replicas", service.ContainerName, replicas)
+	}
+
+	var ids []string
+	for number := 1; number <= replicas; number++ {
+		name := service.ContainerName
+		if name == "" {
+			name = fmt.Sprintf("%s-%s-%d", project.Name, service.Name, number)
+		}
+
+		if current, ok := existing[name]; ok {
+			if current.Labels[ComposeConfigHashLabel] == hash {
+				if current.State != "running" {
+					if err := c.cli.ContainerStart(ctx, current.ID, container.StartOptions{}); err != nil {
+						return nil, fmt.Errorf("failed to start container %s: %w", name, err)
+					}
+				}
+				ids = append(ids, current.ID)
+				continue
+			}
+			ids = append(ids, current.ID)
+			if !composePublishesPorts(service) {
+				id, err := c.composeReplaceContainer(ctx, project, service, current, name, number, hash, networks, healthTimeout)
+				if id != "" {
+					ids = append(ids, id)
+				}
+				if err != nil {
+					return ids, err
+				}
+				continue
+			}
+			// Host ports cannot be bound twice, the old container goes first and the service is
+			// down until the new one started
+			if err := c.cli.ContainerRemove(ctx, current.ID, container.RemoveOptions{Force: true}); err != nil {
+				return nil, fmt.Errorf("failed to replace container %s: %w", name, err)
+			}
+		}
+
+		id, err := c.composeCreateContainer(ctx, project, service, name, number, hash, networks)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		if err := c.cli.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+			return ids, fmt.Errorf("failed to start container %s: %w", name, err)
+		}
+		if healthTimeout > 0 {
+			if err := c.waitHealthy(ctx, id, healthTimeout); err != nil {
+				return ids, fmt.Errorf("container %s: %w", name, err)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// composeReplaceContainer starts the new container of a replica before removing the old one, which is
+// moved aside under a temporary name meanwhile. When the new container fails to start or to become
+// healthy it is removed and the old one keeps serving under its name.
+func (c *Client) composeReplaceContainer(ctx context.Context, project *types.Project, service types.ServiceConfig, current container.Summary, name string, number int, hash string, networks map[string]string, healthTimeout time.Duration) (string, error) {
+	aside := fmt.Sprintf("%s-replaced-%.12s", name, current.ID)
+	if err := c.cli.ContainerRename(ctx, current.ID, aside); err != nil {
+		return "", fmt.Errorf("failed to replace container %s: %w", name, err)
+	}
+
+	id, err := c.composeCreateContainer(ctx, project, service, name, number, hash, networks)
+	if err == nil {
+		if err = c.cli.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+			err = fmt.Errorf("failed to start container %s: %w", name, err)
+		} else if healthTimeout > 0 {
+			if err = c.waitHealthy(ctx, id, healthTimeout); err != nil {
+				err = fmt.Errorf("container %s: %w", name, err)
+			}
+		}
+	}
+	if err != nil {
+		if id != "" {
+			if rmErr := c.cli.ContainerRemove(ctx, id, container.RemoveOptions{Force: true}); rmErr != nil {
+				return id, fmt.Errorf("%w; failed to remove it: %v", err, rmErr)
+			}
+		}
+		if renameErr := c.cli.ContainerRename(ctx, current.ID, name); renameErr != nil {
+			return "", fmt.Errorf("%w; failed to restore the previous container: %v", err, renameErr)
+		}
+		return "", err
+	}
+
+	if err := c.cli.ContainerRemove(ctx, current.ID, container.RemoveOptions{Force: true}); err != nil {
+		return id, fmt.Errorf("failed to remove replaced container %s: %w", aside, err)
+	}
+	return id, nil
+}
+
+// composePublishesPorts reports whether a service binds host ports, which its old and new
+// containers cannot hold at the same time
+func composePublishesPorts(service types.ServiceConfig) bool {
+	for _, port := range service.Ports {
+		if port.Published != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// waitHealthy waits until a container reports healthy. Containers without a healthcheck pass once they
+// are still running one poll after they started.
+func (c *Client) waitHealthy(ctx context.Context, containerID string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(composeHealthPollInterval)
+	defer ticker.Stop()
+
+	polls := 0
+	for {
+		inspect, err := c.cli.ContainerInspect(ctx, containerID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("not healthy within %s", timeout)
+			}
+			return fmt.Errorf("failed to inspect container: %w", err)
+		}
+
+		state := inspect.State
+		switch {
+		case state == nil:
+		case !state.Running || state.Restarting:
+			return fmt.Errorf("exited with code %d", state.ExitCode)
+		case state.Health == nil:
+			if polls > 0 {
+				return nil
+			}
+		case state.Health.Status == container.Healthy:
+			return nil
+		case state.Health.Status == container.Unhealthy:
+			return errors.New("failed its healthcheck")
+		}
+		polls++
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("not healthy within %s", timeout)
+		}
+	}
+}
+
+// composePull pulls the image of a service as its pull policy asks, by default only when missing
+func (c *Client) composePull(ctx context.Context, service types.ServiceConfig) error {
+	switch service.PullPolicy {
+	case types.PullPolicyNever:
+		return nil
+	case types.PullPolicyAlways:
+	default:
+		if _, err := c.cli.ImageInspect(ctx, service.Image); err == nil {
+			return nil
+		}
+	}
+
+	return c.pullImage(ctx, service.Image)
+}
+
+// ImagePullIfMissing pulls an image unless the host already has it
+func (c *Client) ImagePullIfMissing(ctx context.Context, ref string) error {
+	if _, err := c.cli.ImageInspect(ctx, ref); err == nil {
+		return nil
+	}
+	return c.pullImage(ctx, ref)
+}
+
+// pullImage pulls an image anonymously and waits for the pull to finish
+func (c *Client) pullImage(ctx context.Context, ref string) error {
+	progress, err := c.cli.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", ref, err)
+	}
+	defer progress.Close()
+
+	// Failures of a pull are reported inside its progress stream
+	decoder := json.NewDecoder(progress)
+	for {
+		var message struct {
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&message); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to pull image %s: %w", ref, err)
+		}
+		if message.Error != "" {
+			return fmt.Errorf("failed to pull image %s: %s", ref, message.Error)
+		}
+	}
+}
+
+func (c *Client) composeCreateContainer(ctx context.Context, project *types.Project, service types.ServiceConfig, name string, number int, hash string, networks map[string]string) (string, error) {
+	labels := map[string]string{}
+	for k, v := range service.Labels {
+		labels[k] = v
+	}
+	labels[ComposeProjectLabel] = project.Name
+	labels[ComposeServiceLabel] = service.Name
+	labels[ComposeContainerNumberLabel] = strconv.Itoa(number)
+	labels[ComposeOneoffLabel] = "False"
+	labels[ComposeConfigHashLabel] = hash
+
+	var env []string
+	for k, v := range service.Environment {
+		if v != nil {
+			env = append(env, k+"="+*v)
+		}
+	}
+	sort.Strings(env)
+
+	exposed := nat.PortSet{}
+	bindings := nat.PortMap{}
+	for _, expose := range service.Expose {
+		proto, port := nat.SplitProtoPort(expose)
+		p, err := nat.NewPort(proto, port)
+		if err != nil {
+			return "", fmt.Errorf("invalid exposed port %q: %w", expose, err)
+		}
+		exposed[p] = struct{}{}
+	}
+	for _, port := range service.Ports {
+		proto := port.Protocol
+		if proto == "" {
+			proto = "tcp"
+		}
+		p, err := nat.NewPort(proto, strconv.Itoa(int(port.Target)))
+		if err != nil {
+			return "", fmt.Errorf("invalid port %d: %w", port.Target, err)
+		}
+		exposed[p] = struct{}{}
+		bindings[p] = append(bindings[p], nat.PortBinding{HostIP: port.HostIP, HostPort: port.Published})
+	}
+
+	config := &container.Config{
+		Hostname:     service.Hostname,
+		User:         service.User,
+		WorkingDir:   service.WorkingDir,
+		Image:        service.Image,
+		Cmd:          strslice.StrSlice(service.Command),
+		Entrypoint:   strslice.StrSlice(service.Entrypoint),
+		Env:          env,
+		Labels:       labels,
+		ExposedPorts: exposed,
+		Tty:          service.Tty,
+		OpenStdin:    service.StdinOpen,
+		Healthcheck:  composeHealthcheck(service.HealthCheck),
+	}
+
+	restart, err := composeRestartPolicy(service.Restart)
+	if err != nil {
+		return "", err
+	}
+	hostConfig := &container.HostConfig{
+		PortBindings:   bindings,
+		RestartPolicy:  restart,
+		Privileged:     service.Privileged,
+		CapAdd:         service.CapAdd,
+		CapDrop:        service.CapDrop,
+		ReadonlyRootfs: service.ReadOnly,
+		Init:           service.Init,
+		ExtraHosts:     service.ExtraHosts.AsList(":"),
+		NetworkMode:    container.NetworkMode(service.NetworkMode),
+	}
+	if service.Deploy != nil && service.Deploy.Resources.Limits != nil {
+		limits := service.Deploy.Resources.Limits
+		hostConfig.Memory = int64(limits.MemoryBytes)
+		hostConfig.NanoCPUs = int64(limits.NanoCPUs.Value() * 1e9)
+		if limits.Pids > 0 {
+			pids := limits.Pids
+			hostConfig.PidsLimit = &pids
+		}
+	}
+	for _, v := range service.Volumes {
+		switch v.Type {
+		case types.VolumeTypeBind:
+			bind := v.Source + ":" + v.Target
+			if v.ReadOnly {
+				bind += ":ro"
+			}
+			hostConfig.Binds = append(hostConfig.Binds, bind)
+		case types.VolumeTypeVolume:
+			source := v.Source
+			if declared, ok := project.Volumes[v.Source]; ok {
+				source = declared.Name
+			}
+			hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{Type: mount.TypeVolume, Source: source, Target: v.Target, ReadOnly: v.ReadOnly})
+		case types.VolumeTypeTmpfs:
+			hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{Type: mount.TypeTmpfs, Target: v.Target})
+		default:
+			return "", fmt.Errorf("volume type %s is not supported", v.Type)
+		}
+	}
+
+	// The container is created on its first network; the others are connected before it starts
+	var networkingConfig *network.NetworkingConfig
+	serviceNetworks := service.NetworksByPriority()
+	if service.NetworkMode == "" && len(serviceNetworks) > 0 {
+		first := serviceNetworks[0]
+		networkingConfig = &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{
+			networks[first]: composeEndpoint(service, first),
+		}}
+	}
+
+	resp, err := c.cli.ContainerCreate(ctx, config, hostConfig, networkingConfig, nil, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to create container %s: %w", name, err)
+	}
+	if networkingConfig != nil {
+		for _, key := range serviceNetworks[1:] {
+			if err := c.cli.NetworkConnect(ctx, networks[key], resp.ID, composeEndpoint(service, key)); err != nil {
+				return "", fmt.Errorf("failed to connect container %s to network %s: %w", name, networks[key], err)
+			}
+		}
+	}
+	return resp.ID, nil
+}
+
+// composeEndpoint makes a container reachable by its service name on a network
+func composeEndpoint(service types.ServiceConfig, key string) *network.EndpointSettings {
+	endpoint := &network.EndpointSettings{Aliases: []string{service.Name}}
+	if cfg := service.Networks[key]; cfg != nil {
+		endpoint.Aliases = append(endpoint.Aliases, cfg.Aliases...)
+		if cfg.Ipv4Address != "" || cfg.Ipv6Address != "" {
+			endpoint.IPAMConfig = &network.EndpointIPAMConfig{IPv4Address: cfg.Ipv4Address, IPv6Address: cfg.Ipv6Address}
+		}
+	}
+	return endpoint
+}
+
+// ensureComposeNetworks creates the missing networks of a project and returns network names by compose key
+func (c *Client) ensureComposeNetworks(ctx context.Context, project *types.Project) (map[string]string, error) {
+	existing, err := c.cli.NetworkList(ctx, network.ListOptions{Filters: composeFilter(project.Name, "")})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+	present := make(map[string]bool, len(existing))
+	for _, n := range existing {
+		present[n.Name] = true
+	}
+
+	names := make(map[string]string, len(project.Networks))
+	for key, n := range project.Networks {
+		names[key] = n.Name
+		if bool(n.External) || present[n.Name] {
+			continue
+		}
+
+		labels := map[string]string{}
+		for k, v := range n.Labels {
+			labels[k] = v
+		}
+		labels[ComposeProjectLabel] = project.Name
+		labels[ComposeNetworkLabel] = key
+
+		options := network.CreateOptions{
+			Driver:     n.Driver,
+			Options:    n.DriverOpts,
+			Internal:   n.Internal,
+			Attachable: n.Attachable,
+			Labels:     labels,
+		}
+		if n.Ipam.Driver != "" || len(n.Ipam.Config) > 0 {
+			options.IPAM = &network.IPAM{Driver: n.Ipam.Driver}
+			for _, pool := range n.Ipam.Config {
+				options.IPAM.Config = append(options.IPAM.Config, network.IPAMConfig{
+					Subnet:     pool.Subnet,
+					Gateway:    pool.Gateway,
+					IPRange:    pool.IPRange,
+					AuxAddress: pool.AuxiliaryAddresses,
+				})
+			}
+		}
+		if _, err := c.cli.NetworkCreate(ctx, n.Name, options); err != nil {
+			return nil, fmt.Errorf("failed to create network %s: %w", n.Name, err)
+		}
+	}
+	return names, nil
+}
+
+// ensureComposeVolumes creates the missing named volumes of a project
+func (c *Client) ensureComposeVolumes(ctx context.Context, project *types.Project) error {
+	existing, err := c.cli.VolumeList(ctx, volume.ListOptions{Filters: composeFilter(project.Name, "")})
+	if err != nil {
+		return fmt.Errorf("failed to list volumes: %w", err)
+	}
+	present := make(map[string]bool, len(existing.Volumes))
+	for _, v := range existing.Volumes {
+		present[v.Name] = true
+	}
+
+	for key, v := range project.Volumes {
+		if bool(v.External) || present[v.Name] {
+			continue
+		}
+
+		labels := map[string]string{}
+		for k, val := range v.Labels {
+			labels[k] = val
+		}
+		labels[ComposeProjectLabel] = project.Name
+		labels[ComposeVolumeLabel] = key
+
+		if _, err := c.cli.VolumeCreate(ctx, volume.CreateOptions{
+			Name:       v.Name,
+			Driver:     v.Driver,
+			DriverOpts: v.DriverOpts,
+			Labels:     labels,
+		}); err != nil {
+			return fmt.Errorf("failed to create volume %s: %w", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// ComposeContainers lists the containers of a project, optionally of one service, stopped ones included
+func (c *Client) ComposeContainers(ctx context.Context, projectName, service string) ([]container.Summary, error) {
+	containers, err := c.cli.ContainerList(ctx, container.ListOptions{All: true, Filters: composeFilter(projectName, service)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	sort.Slice(containers, func(i, j int) bool {
+		a, b := containers[i].Labels, containers[j].Labels
+		if a[ComposeServiceLabel] != b[ComposeServiceLabel] {
+			return a[ComposeServiceLabel] < b[ComposeServiceLabel]
+		}
+		ai, _ := strconv.Atoi(a[ComposeContainerNumberLabel])
+		bi, _ := strconv.Atoi(b[ComposeContainerNumberLabel])
+		return ai < bi
+	})
+	return containers, nil
+}
+
+// ComposeStart starts the stopped containers of a project
+func (c *Client) ComposeStart(ctx context.Context, projectName string) error {
+	containers, err := c.ComposeContainers(ctx, projectName, "")
+	if err != nil {
+		return err
+	}
+	for _, ctr := range containers {
+		if ctr.State == "running" {
+			continue
+		}
+		if err := c.cli.ContainerStart(ctx, ctr.ID, container.StartOptions{}); err != nil {
+			return fmt.Errorf("failed to start container %s: %w", ctr.ID, err)
+		}
+	}
+	return nil
+}
+
+// ComposeStop stops the running containers of a project, killing them after timeout seconds
+func (c *Client) ComposeStop(ctx context.Context, projectName string, timeout int) error {
+	containers, err := c.ComposeContainers(ctx, projectName, "")
+	if err != nil {
+		return err
+	}
+	for _, ctr := range containers {
+		if ctr.State != "running" {
+			continue
+		}
+		if err := c.cli.ContainerStop(ctx, ctr.ID, container.StopOptions{Timeout: &timeout}); err != nil {
+			return fmt.Errorf("failed to stop container %s: %w", ctr.ID, err)
+		}
+	}
+	return nil
+}
+
+// ComposeDown removes the containers and networks of a project, and its volumes when removeVolumes is set
+func (c *Client) ComposeDown(ctx context.Context, projectName string, removeVolumes bool) error {
+	containers, err := c.ComposeContainers(ctx, projectName, "")
+	if err != nil {
+		return err
+	}
+	for _, ctr := range containers {
+		if err := c.cli.ContainerRemove(ctx, ctr.ID, container.RemoveOptions{Force: true}); err != nil {
+			return fmt.Errorf("failed to remove container %s: %w", ctr.ID, err)
+		}
+	}
+
+	networks, err := c.cli.NetworkList(ctx, network.ListOptions{Filters: composeFilter(projectName, "")})
+	if err != nil {
+		return fmt.Errorf("failed to list networks: %w", err)
+	}
+	for _, n := range networks {
+		if err := c.cli.NetworkRemove(ctx, n.ID); err != nil {
+			return fmt.Errorf("failed to remove network %s: %w", n.Name, err)
+		}
+	}
+
+	if !removeVolumes {
+		return nil
+	}
+	volumes, err := c.cli.VolumeList(ctx, volume.ListOptions{Filters: composeFilter(projectName, "")})
+	if err != nil {
+		return fmt.Errorf("failed to list volumes: %w", err)
+	}
+	for _, v := range volumes.Volumes {
+		if err := c.cli.VolumeRemove(ctx, v.Name, true); err != nil {
+			return fmt.Errorf("failed to remove volume %s: %w", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// ComposeLogs returns the last lines of every container of a project, optionally of one service,
+// each line prefixed with its container like the compose CLI does
+func (c *Client) ComposeLogs(ctx context.Context, projectName, service, tail string) (string, error) {
+	containers, err := c.ComposeContainers(ctx, projectName, service)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	for _, ctr := range containers {
+		logs, err := c.ContainerLogs(ctx, ctr.ID, tail)
+		if err != nil {
+			return "", err
+		}
+		if logs == "" {
+			continue
+		}
+		prefix := ctr.Labels[ComposeServiceLabel] + "-" + ctr.Labels[ComposeContainerNumberLabel] + " | "
+		for _, line := range strings.Split(logs, "\n") {
+			out.WriteString(prefix)
+			out.WriteString(line)
+			out.WriteString("\n")
+		}
+	}
+	return out.String(), nil
+}
+
+func composeFilter(projectName, service string) filters.Args {
+	args := filters.NewArgs(filters.Arg("label", ComposeProjectLabel+"="+projectName))
+	if service != "" {
+		args.Add("label", ComposeServiceLabel+"="+service)
+	}
+	return args
+}
+
+// ComposeConfigHash fingerprints the configuration of a service, ignoring its scale. Containers whose
+// hash differs from their service are recreated by ComposeUp.
+func ComposeConfigHash(service types.ServiceConfig) (string, error) {
+	service.Scale = nil
+	if service.Deploy != nil {
+		deploy := *service.Deploy
+		deploy.Replicas = nil
+		service.Deploy = &deploy
+	}
+	data, err := json.Marshal(service)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash service %s: %w", service.Name, err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// composeRestartPolicy parses restart values such as "unless-stopped" or "on-failure:3"
+func composeRestartPolicy(restart string) (container.RestartPolicy, error) {
+	if restart == "" {
+		return container.RestartPolicy{}, nil
+	}
+	mode, retries, _ := strings.Cut(restart, ":")
+	policy := container.RestartPolicy{Name: container.RestartPolicyMode(mode)}
+	if retries != "" {
+		count, err := strconv.Atoi(retries)
+		if err != nil {
+			return policy, fmt.Errorf("invalid restart policy %q", restart)
+		}
+		policy.MaximumRetryCount = count
+	}
+	return policy, nil
+}
+
+func composeHealthcheck(check *types.HealthCheckConfig) *container.HealthConfig {
+	if check == nil {
+		return nil
+	}
+	if check.Disable {
+		return &container.HealthConfig{Test: []string{"NONE"}}
+	}
+	config := &container.HealthConfig{Test: check.Test}
+	if check.Interval != nil {
+		config.Interval = time.Duration(*check.Interval)
+	}
+	if check.Timeout != nil {
+		config.Timeout = time.Duration(*check.Timeout)
+	}
+	if check.StartPeriod != nil {
+		config.StartPeriod = time.Duration(*check.StartPeriod)
+	}
+	if check.Retries != nil {
+		config.Retries = int(*check.Retries)
+	}
+	return config
+}
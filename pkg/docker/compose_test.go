@@ -0,0 +1,486 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeContainer is a container known to fakeDaemon
+type fakeContainer struct {
+	ID         string
+	Name       string
+	Config     container.Config
+	HostConfig container.HostConfig
+	Networks   []string
+	State      string
+}
+
+// fakeDaemon is an in-memory Docker Engine API covering the calls of the compose engine
+type fakeDaemon struct {
+	mu         sync.Mutex
+	calls      []string
+	images     map[string]bool
+	networks   map[string]network.CreateRequest
+	volumes    map[string]volume.CreateOptions
+	containers map[string]*fakeContainer
+	created    []string
+	nextID     int
+	unhealthy  map[string]bool // Images whose containers fail their healthcheck
+}
+
+var apiVersionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+func newFakeDaemon(t *testing.T) (*fakeDaemon, *Client) {
+	d := &fakeDaemon{
+		images:     map[string]bool{},
+		networks:   map[string]network.CreateRequest{},
+		volumes:    map[string]volume.CreateOptions{},
+		containers: map[string]*fakeContainer{},
+		unhealthy:  map[string]bool{},
+	}
+	server := httptest.NewServer(d)
+	t.Cleanup(server.Close)
+
+	client, err := NewClient("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return d, client
+}
+
+func (d *fakeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path := apiVersionPrefix.ReplaceAllString(r.URL.Path, "")
+	d.calls = append(d.calls, r.Method+" "+path)
+	w.Header().Set("Api-Version", "1.47")
+	w.Header().Set("Content-Type", "application/json")
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case path == "/_ping":
+		w.Write([]byte("OK"))
+
+	case r.Method == http.MethodGet && path == "/networks":
+		var list []network.Summary
+		for name, n := range d.networks {
+			if d.matches(r, n.Labels) {
+				list = append(list, network.Summary{ID: name, Name: name, Labels: n.Labels})
+			}
+		}
+		d.json(w, list)
+	case r.Method == http.MethodPost && path == "/networks/create":
+		var req network.CreateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		d.networks[req.Name] = req
+		d.json(w, network.CreateResponse{ID: req.Name})
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "networks" && parts[2] == "connect":
+		var req network.ConnectOptions
+		json.NewDecoder(r.Body).Decode(&req)
+		ctr := d.containers[req.Container]
+		ctr.Networks = append(ctr.Networks, parts[1])
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == "networks":
+		delete(d.networks, parts[1])
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodGet && path == "/volumes":
+		resp := volume.ListResponse{Volumes: []*volume.Volume{}}
+		for name, v := range d.volumes {
+			if d.matches(r, v.Labels) {
+				resp.Volumes = append(resp.Volumes, &volume.Volume{Name: name, Labels: v.Labels})
+			}
+		}
+		d.json(w, resp)
+	case r.Method == http.MethodPost && path == "/volumes/create":
+		var req volume.CreateOptions
+		json.NewDecoder(r.Body).Decode(&req)
+		d.volumes[req.Name] = req
+		d.json(w, volume.Volume{Name: req.Name, Labels: req.Labels})
+	case r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == "volumes":
+		delete(d.volumes, parts[1])
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodGet && parts[0] == "images" && parts[len(parts)-1] == "json":
+		name := strings.Join(parts[1:len(parts)-1], "/")
+		if !d.images[name] {
+			d.error(w, http.StatusNotFound, "No such image: "+name)
+			return
+		}
+		d.json(w, map[string]string{"Id": "sha256:" + name})
+	case r.Method == http.MethodPost && path == "/images/create":
+		name := strings.TrimPrefix(r.URL.Query().Get("fromImage"), "docker.io/library/")
+		d.images[name+":"+r.URL.Query().Get("tag")] = true
+		w.Write([]byte(`{"status":"Pulling"}` + "\n" + `{"status":"Downloaded newer image"}` + "\n"))
+
+	case r.Method == http.MethodGet && path == "/containers/json":
+		list := []container.Summary{}
+		for _, ctr := range d.containers {
+			if d.matches(r, ctr.Config.Labels) {
+				list = append(list, container.Summary{
+					ID:     ctr.ID,
+					Names:  []string{"/" + ctr.Name},
+					Image:  ctr.Config.Image,
+					Labels: ctr.Config.Labels,
+					State:  ctr.State,
+				})
+			}
+		}
+		d.json(w, list)
+	case r.Method == http.MethodPost && path == "/containers/create":
+		var req struct {
+			container.Config
+			HostConfig       container.HostConfig
+			NetworkingConfig network.NetworkingConfig
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, existing := range d.containers {
+			if existing.Name == r.URL.Query().Get("name") {
+				d.error(w, http.StatusConflict, "Conflict. The container name is already in use")
+				return
+			}
+		}
+		d.nextID++
+		ctr := &fakeContainer{
+			ID:         fmt.Sprintf("c%d", d.nextID),
+			Name:       r.URL.Query().Get("name"),
+			Config:     req.Config,
+			HostConfig: req.HostConfig,
+			State:      "created",
+		}
+		for name := range req.NetworkingConfig.EndpointsConfig {
+			ctr.Networks = append(ctr.Networks, name)
+		}
+		d.containers[ctr.ID] = ctr
+		d.created = append(d.created, ctr.Name)
+		d.json(w, container.CreateResponse{ID: ctr.ID})
+	case len(parts) >= 2 && parts[0] == "containers":
+		ctr, ok := d.containers[parts[1]]
+		if !ok {
+			d.error(w, http.StatusNotFound, "No such container: "+parts[1])
+			return
+		}
+		switch {
+		case r.Method == http.MethodDelete:
+			delete(d.containers, ctr.ID)
+			w.WriteHeader(http.StatusNoContent)
+		case parts[2] == "start":
+			ctr.State = "running"
+			w.WriteHeader(http.StatusNoContent)
+		case parts[2] == "stop":
+			ctr.State = "exited"
+			w.WriteHeader(http.StatusNoContent)
+		case parts[2] == "rename":
+			ctr.Name = r.URL.Query().Get("name")
+			w.WriteHeader(http.StatusNoContent)
+		case parts[2] == "json":
+			state := &container.State{Status: ctr.State, Running: ctr.State == "running"}
+			if ctr.Config.Healthcheck != nil {
+				state.Health = &container.Health{Status: container.Healthy}
+				if d.unhealthy[ctr.Config.Image] {
+					state.Health.Status = container.Unhealthy
+				}
+			}
+			d.json(w, container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{ID: ctr.ID, Name: "/" + ctr.Name, State: state},
+				Config:            &ctr.Config,
+			})
+		case parts[2] == "logs":
+			w.Header().Set("Content-Type", "application/vnd.docker.multiplexed-stream")
+			fmt.Fprintf(stdcopy.NewStdWriter(w, stdcopy.Stdout), "hello from %s\n", ctr.Name)
+		default:
+			http.NotFound(w, r)
+		}
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// matches applies the label filters of a list request
+func (d *fakeDaemon) matches(r *http.Request, labels map[string]string) bool {
+	args, err := filters.FromJSON(r.URL.Query().Get("filters"))
+	if err != nil {
+		return false
+	}
+	for _, label := range args.Get("label") {
+		key, value, _ := strings.Cut(label, "=")
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *fakeDaemon) json(w http.ResponseWriter, v interface{}) {
+	json.NewEncoder(w).Encode(v)
+}
+
+func (d *fakeDaemon) error(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	d.json(w, map[string]string{"message": message})
+}
+
+func (d *fakeDaemon) container(name string) *fakeContainer {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, ctr := range d.containers {
+		if ctr.Name == name {
+			return ctr
+		}
+	}
+	return nil
+}
+
+func (d *fakeDaemon) count(call string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for _, c := range d.calls {
+		if c == call {
+			n++
+		}
+	}
+	return n
+}
+
+const testComposeFile = `services:
+  db:
+    image: postgres:16
+    environment:
+      POSTGRES_PASSWORD: ${DB_PASSWORD}
+    volumes:
+      - data:/var/lib/postgresql/data
+    restart: on-failure:3
+  web:
+    image: nginx:${TAG:-latest}
+    depends_on:
+      - db
+    deploy:
+      replicas: 2
+      resources:
+        limits:
+          cpus: "0.5"
+          memory: 256M
+    networks:
+      - front
+      - default
+    expose:
+      - "9000"
+networks:
+  front: {}
+volumes:
+  data: {}
+`
+
+func TestLoadComposeProject(t *testing.T) {
+	ctx := context.Background()
+
+	project, err := LoadComposeProject(ctx, "demo", testComposeFile, map[string]string{"TAG": "1.27", "DB_PASSWORD": "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, "demo", project.Name)
+	assert.Equal(t, "nginx:1.27", project.Services["web"].Image)
+	assert.Equal(t, "secret", *project.Services["db"].Environment["POSTGRES_PASSWORD"])
+	assert.Equal(t, "demo_data", project.Volumes["data"].Name)
+
+	rejected := map[string]string{
+		"env_file": "services:\n  app:\n    image: nginx\n    env_file: /etc/passwd\n",
+		"build":    "services:\n  app:\n    build: .\n",
+		"include":  "include:\n  - other.yml\nservices:\n  app:\n    image: nginx\n",
+		"extends":  "services:\n  app:\n    extends:\n      file: base.yml\n      service: app\n",
+	}
+	for name, composeFile := range rejected {
+		_, err := LoadComposeProject(ctx, "demo", composeFile, nil)
+		assert.Error(t, err, name)
+		assert.Contains(t, err.Error(), "not supported", name)
+	}
+
+	_, err = LoadComposeProject(ctx, "demo", "services: [", nil)
+	assert.Error(t, err)
+}
+
+func TestComposeLifecycle(t *testing.T) {
+	ctx := context.Background()
+	daemon, client := newFakeDaemon(t)
+
+	project, err := LoadComposeProject(ctx, "demo", testComposeFile, map[string]string{"DB_PASSWORD": "secret"})
+	require.NoError(t, err)
+
+	require.NoError(t, client.ComposeUp(ctx, project))
+
+	// Networks and volumes carry compose labels
+	require.Contains(t, daemon.networks, "demo_front")
+	require.Contains(t, daemon.networks, "demo_default")
+	assert.Equal(t, "demo", daemon.networks["demo_front"].Labels[ComposeProjectLabel])
+	assert.Equal(t, "front", daemon.networks["demo_front"].Labels[ComposeNetworkLabel])
+	require.Contains(t, daemon.volumes, "demo_data")
+	assert.Equal(t, "data", daemon.volumes["demo_data"].Labels[ComposeVolumeLabel])
+
+	// Missing images are pulled, dependencies are created first
+	assert.True(t, daemon.images["postgres:16"])
+	assert.True(t, daemon.images["nginx:latest"])
+	assert.Equal(t, "demo-db-1", daemon.created[0])
+	created := append([]string(nil), daemon.created...)
+	sort.Strings(created)
+	assert.Equal(t, []string{"demo-db-1", "demo-web-1", "demo-web-2"}, created)
+
+	db := daemon.container("demo-db-1")
+	require.NotNil(t, db)
+	assert.Equal(t, "running", db.State)
+	assert.Equal(t, "demo", db.Config.Labels[ComposeProjectLabel])
+	assert.Equal(t, "db", db.Config.Labels[ComposeServiceLabel])
+	assert.Equal(t, "1", db.Config.Labels[ComposeContainerNumberLabel])
+	assert.Contains(t, db.Config.Env, "POSTGRES_PASSWORD=secret")
+	assert.Equal(t, container.RestartPolicyOnFailure, db.HostConfig.RestartPolicy.Name)
+	assert.Equal(t, 3, db.HostConfig.RestartPolicy.MaximumRetryCount)
+	require.Len(t, db.HostConfig.Mounts, 1)
+	assert.Equal(t, "demo_data", db.HostConfig.Mounts[0].Source)
+
+	web := daemon.container("demo-web-2")
+	require.NotNil(t, web)
+	assert.Equal(t, "2", web.Config.Labels[ComposeContainerNumberLabel])
+	assert.Equal(t, int64(500000000), web.HostConfig.NanoCPUs)
+	assert.Equal(t, int64(256*1024*1024), web.HostConfig.Memory)
+	assert.ElementsMatch(t, []string{"demo_default", "demo_front"}, web.Networks)
+
+	// Unchanged services are left alone
+	require.NoError(t, client.ComposeUp(ctx, project))
+	assert.Len(t, daemon.created, 3)
+	assert.Equal(t, 2, daemon.count("POST /images/create"))
+
+	// A changed service is recreated, scaled-down replicas are removed
+	updated, err := LoadComposeProject(ctx, "demo", strings.Replace(testComposeFile, "replicas: 2", "replicas: 1", 1), map[string]string{"DB_PASSWORD": "secret", "TAG": "1.27"})
+	require.NoError(t, err)
+	require.NoError(t, client.ComposeUp(ctx, updated))
+	assert.Len(t, daemon.created, 4)
+	assert.Nil(t, daemon.container("demo-web-2"))
+	assert.Equal(t, "nginx:1.27", daemon.container("demo-web-1").Config.Image)
+
+	containers, err := client.ComposeContainers(ctx, "demo", "")
+	require.NoError(t, err)
+	assert.Len(t, containers, 2)
+
+	require.NoError(t, client.ComposeStop(ctx, "demo", 5))
+	assert.Equal(t, "exited", daemon.container("demo-db-1").State)
+	require.NoError(t, client.ComposeStart(ctx, "demo"))
+	assert.Equal(t, "running", daemon.container("demo-db-1").State)
+
+	logs, err := client.ComposeLogs(ctx, "demo", "web", "10")
+	require.NoError(t, err)
+	assert.Equal(t, "web-1 | hello from demo-web-1\n", logs)
+
+	require.NoError(t, client.ComposeDown(ctx, "demo", true))
+	assert.Empty(t, daemon.containers)
+	assert.Empty(t, daemon.networks)
+	assert.Empty(t, daemon.volumes)
+}
+
+// TestComposeReplaceStartFirst tests that a changed container is replaced by starting the new one
+// first, unless the service publishes host ports
+func TestComposeReplaceStartFirst(t *testing.T) {
+	ctx := context.Background()
+	daemon, client := newFakeDaemon(t)
+
+	compose := `services:
+  api:
+    image: api:${TAG}
+  proxy:
+    image: nginx:${TAG}
+    ports:
+      - "8080:80"
+`
+	current, err := LoadComposeProject(ctx, "demo", compose, map[string]string{"TAG": "1"})
+	require.NoError(t, err)
+	require.NoError(t, client.ComposeUp(ctx, current))
+	oldAPI := daemon.container("demo-api-1").ID
+	oldProxy := daemon.container("demo-proxy-1").ID
+
+	next, err := LoadComposeProject(ctx, "demo", compose, map[string]string{"TAG": "2"})
+	require.NoError(t, err)
+	daemon.calls = nil
+	require.NoError(t, client.ComposeUp(ctx, next))
+
+	newAPI := daemon.container("demo-api-1").ID
+	proxy := daemon.container("demo-proxy-1").ID
+	callIndex := func(call string) int {
+		for i, c := range daemon.calls {
+			if c == call {
+				return i
+			}
+		}
+		return -1
+	}
+
+	// Without host ports the old container is moved aside and removed after its replacement started
+	assert.Less(t, callIndex("POST /containers/"+oldAPI+"/rename"), callIndex("POST /containers/"+newAPI+"/start"))
+	assert.Less(t, callIndex("POST /containers/"+newAPI+"/start"), callIndex("DELETE /containers/"+oldAPI))
+	assert.Equal(t, "api:2", daemon.container("demo-api-1").Config.Image)
+
+	// Published ports are released first
+	assert.Equal(t, "nginx:2", daemon.container("demo-proxy-1").Config.Image)
+	assert.Equal(t, -1, callIndex("POST /containers/"+oldProxy+"/rename"))
+	assert.Less(t, callIndex("DELETE /containers/"+oldProxy), callIndex("POST /containers/"+proxy+"/start"))
+	assert.Len(t, daemon.containers, 2)
+}
+
+func TestComposeRollingUpdate(t *testing.T) {
+	ctx := context.Background()
+	daemon, client := newFakeDaemon(t)
+	composeHealthPollInterval = 10 * time.Millisecond
+	defer func() { composeHealthPollInterval = time.Second }()
+
+	withHealthcheck := strings.Replace(testComposeFile, "    expose:", `    healthcheck:
+      test: ["CMD", "curl", "-f", "http://localhost"]
+    expose:`, 1)
+	env := map[string]string{"DB_PASSWORD": "secret"}
+
+	current, err := LoadComposeProject(ctx, "demo", withHealthcheck, env)
+	require.NoError(t, err)
+	require.NoError(t, client.ComposeRollingUpdate(ctx, current, time.Second))
+	require.Len(t, daemon.created, 3)
+
+	// Only the changed service is recreated, its containers one after the other
+	next, err := LoadComposeProject(ctx, "demo", withHealthcheck, map[string]string{"DB_PASSWORD": "secret", "TAG": "1.27"})
+	require.NoError(t, err)
+	require.NoError(t, client.ComposeRollingUpdate(ctx, next, time.Second))
+	assert.Equal(t, []string{"demo-web-1", "demo-web-2"}, daemon.created[3:])
+	assert.Equal(t, "nginx:1.27", daemon.container("demo-web-2").Config.Image)
+
+	// The first unhealthy container stops the rollout; it is removed and the replica it was to
+	// replace keeps serving, the second replica is left untouched
+	daemon.unhealthy["nginx:broken"] = true
+	broken, err := LoadComposeProject(ctx, "demo", withHealthcheck, map[string]string{"DB_PASSWORD": "secret", "TAG": "broken"})
+	require.NoError(t, err)
+	err = client.ComposeRollingUpdate(ctx, broken, time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "demo-web-1: failed its healthcheck")
+	assert.Equal(t, "nginx:1.27", daemon.container("demo-web-1").Config.Image)
+	assert.Equal(t, "running", daemon.container("demo-web-1").State)
+	assert.Equal(t, "nginx:1.27", daemon.container("demo-web-2").Config.Image)
+	assert.Len(t, daemon.containers, 3)
+
+	// Rolling the previous project out again leaves it as it is
+	require.NoError(t, client.ComposeRollingUpdate(ctx, next, time.Second))
+	assert.Equal(t, []string{"demo-web-1"}, daemon.created[5:])
+
+	// Containers without a healthcheck must keep running
+	require.NoError(t, client.ComposeStop(ctx, "demo", 1))
+	assert.Error(t, client.waitHealthy(ctx, daemon.container("demo-db-1").ID, time.Second))
+}
@@ -0,0 +1,119 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+)
+
+// ContainerCreateConfig represents configuration for creating a container
+type ContainerCreateConfig struct {
+	Name          string            `json:"name"`
+	Image         string            `json:"image"`
+	Cmd           []string          `json:"cmd,omitempty"`
+	Env           []string          `json:"env,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Ports         map[string]string `json:"ports,omitempty"` // Container port ("80" or "53/udp") to host port
+	Binds         []string          `json:"binds,omitempty"` // "source:target[:mode]"
+	RestartPolicy string            `json:"restart_policy,omitempty"`
+}
+
+// ContainerList lists the containers of the host; all includes stopped containers
+func (c *Client) ContainerList(ctx context.Context, all bool) ([]container.Summary, error) {
+	return c.cli.ContainerList(ctx, container.ListOptions{All: all})
+}
+
+// ContainerInspect returns the details of a container
+func (c *Client) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	return c.cli.ContainerInspect(ctx, containerID)
+}
+
+// ContainerCreate creates a container and returns its ID
+func (c *Client) ContainerCreate(ctx context.Context, config ContainerCreateConfig) (string, error) {
+	exposed := nat.PortSet{}
+	bindings := nat.PortMap{}
+	for containerPort, hostPort := range config.Ports {
+		proto, port := nat.SplitProtoPort(containerPort)
+		p, err := nat.NewPort(proto, port)
+		if err != nil {
+			return "", fmt.Errorf("invalid port %q: %w", containerPort, err)
+		}
+		exposed[p] = struct{}{}
+		bindings[p] = append(bindings[p], nat.PortBinding{HostPort: hostPort})
+	}
+
+	containerConfig := &container.Config{
+		Image:        config.Image,
+		Cmd:          config.Cmd,
+		Env:          config.Env,
+		Labels:       config.Labels,
+		ExposedPorts: exposed,
+	}
+	hostConfig := &container.HostConfig{
+		Binds:         config.Binds,
+		PortBindings:  bindings,
+		RestartPolicy: container.RestartPolicy{Name: container.RestartPolicyMode(config.RestartPolicy)},
+	}
+
+	resp, err := c.cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, config.Name)
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// ContainerStart starts a container
+func (c *Client) ContainerStart(ctx context.Context, containerID string) error {
+	return c.cli.ContainerStart(ctx, containerID, container.StartOptions{})
+}
+
+// ContainerStop stops a container, killing it after timeout seconds
+func (c *Client) ContainerStop(ctx context.Context, containerID string, timeout int) error {
+	return c.cli.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout})
+}
+
+// ContainerRestart restarts a container, killing it after timeout seconds
+func (c *Client) ContainerRestart(ctx context.Context, containerID string, timeout int) error {
+	return c.cli.ContainerRestart(ctx, containerID, container.StopOptions{Timeout: &timeout})
+}
+
+// ContainerRemove removes a container; force removes it while running
+func (c *Client) ContainerRemove(ctx context.Context, containerID string, force bool) error {
+	return c.cli.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: force})
+}
+
+// ContainerLogs returns the last lines of a container's output
+func (c *Client) ContainerLogs(ctx context.Context, containerID string, tail string) (string, error) {
+	inspect, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", err
+	}
+
+	reader, err := c.cli.ContainerLogs(ctx, containerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       tail,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get container logs: %w", err)
+	}
+	defer reader.Close()
+
+	// Output of containers without a TTY is multiplexed into stdout and stderr frames
+	var output bytes.Buffer
+	if inspect.Config != nil && inspect.Config.Tty {
+		_, err = io.Copy(&output, reader)
+	} else {
+		_, err = stdcopy.StdCopy(&output, &output, reader)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read container logs: %w", err)
+	}
+	return strings.TrimRight(output.String(), "\n"), nil
+}
@@ -60,7 +60,7 @@ func (c *Client) ContainerExec(ctx context.Context, config ExecConfig) (string,
 
 // ExecStart starts an exec instance
 func (c *Client) ExecStart(ctx context.Context, execID string, config ExecStartConfig) (io.ReadCloser, error) {
-	startConfig := types.ExecStartCheck{
+	startConfig := container.ExecStartOptions{
 		Detach: config.Detach,
 		Tty:    config.Tty,
 	}
@@ -70,7 +70,21 @@ func (c *Client) ExecStart(ctx context.Context, execID string, config ExecStartC
 		return nil, err
 	}
 
-	return resp.Reader, nil
+	return hijackedReader{resp}, nil
+}
+
+// hijackedReader reads the output of an attached exec and closes its connection
+type hijackedReader struct {
+	resp types.HijackedResponse
+}
+
+func (r hijackedReader) Read(p []byte) (int, error) {
+	return r.resp.Reader.Read(p)
+}
+
+func (r hijackedReader) Close() error {
+	r.resp.Close()
+	return nil
 }
 
 // ExecInspect returns information about an exec instance
@@ -156,3 +156,8 @@ func encodeAuthToBase64(authConfig AuthConfig) (string, error) {
 	}
 	return base64.URLEncoding.EncodeToString(authJSON), nil
 }
+
+// ImageList lists the images of the host
+func (c *Client) ImageList(ctx context.Context) ([]image.Summary, error) {
+	return c.cli.ImageList(ctx, image.ListOptions{})
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
 )
 
 // NetworkConnect connects a container to a network
@@ -19,7 +19,7 @@ func (c *Client) NetworkDisconnect(ctx context.Context, networkID, containerID s
 
 // NetworkCreate creates a new network
 func (c *Client) NetworkCreate(ctx context.Context, name, driver string) (string, error) {
-	options := types.NetworkCreate{
+	options := network.CreateOptions{
 		Driver: driver,
 	}
 
@@ -38,7 +38,7 @@ func (c *Client) NetworkRemove(ctx context.Context, networkID string) error {
 
 // NetworkInspect inspects a network
 func (c *Client) NetworkInspect(ctx context.Context, networkID string) (map[string]interface{}, error) {
-	resource, err := c.cli.NetworkInspect(ctx, networkID, types.NetworkInspectOptions{})
+	resource, err := c.cli.NetworkInspect(ctx, networkID, network.InspectOptions{})
 	if err != nil {
 		return nil, err
 	}
@@ -60,3 +60,8 @@ func (c *Client) NetworkInspect(ctx context.Context, networkID string) (map[stri
 
 	return result, nil
 }
+
+// NetworkList lists the networks of the host
+func (c *Client) NetworkList(ctx context.Context) ([]network.Summary, error) {
+	return c.cli.NetworkList(ctx, network.ListOptions{})
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types/container"
@@ -18,7 +19,12 @@ type ContainerStats struct {
 		Current uint64 `json:"current"`
 	} `json:"pids_stats"`
 	BlkioStats struct {
-		IoServiceBytesRecursive []interface{} `json:"io_service_bytes_recursive"`
+		IoServiceBytesRecursive []struct {
+			Major uint64 `json:"major"`
+			Minor uint64 `json:"minor"`
+			Op    string `json:"op"`
+			Value uint64 `json:"value"`
+		} `json:"io_service_bytes_recursive"`
 	} `json:"blkio_stats"`
 	NumProcs     uint32   `json:"num_procs"`
 	StorageStats struct{} `json:"storage_stats"`
@@ -105,6 +111,60 @@ type ContainerStats struct {
 	} `json:"networks"`
 }
 
+// CPUPercent returns the CPU usage between the sample and the previous one, where 100 is one full core:
+// (cpu_delta / system_cpu_delta) * number_cpus * 100
+func (s *ContainerStats) CPUPercent() float64 {
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PrecpuStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemCPUUsage) - float64(s.PrecpuStats.SystemCPUUsage)
+	onlineCPUs := float64(s.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0.0 {
+		onlineCPUs = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	if systemDelta > 0.0 && cpuDelta > 0.0 {
+		return (cpuDelta / systemDelta) * onlineCPUs * 100.0
+	}
+	return 0
+}
+
+// MemoryUsage returns the memory used by the container without the page cache, as the Docker CLI does
+func (s *ContainerStats) MemoryUsage() uint64 {
+	if s.MemoryStats.Stats.Cache > 0 && s.MemoryStats.Stats.Cache < s.MemoryStats.Usage {
+		return s.MemoryStats.Usage - s.MemoryStats.Stats.Cache
+	}
+	return s.MemoryStats.Usage
+}
+
+// MemoryPercent returns MemoryUsage as a percentage of the memory limit; 0 without a limit
+func (s *ContainerStats) MemoryPercent() float64 {
+	if s.MemoryStats.Limit == 0 {
+		return 0
+	}
+	return float64(s.MemoryUsage()) / float64(s.MemoryStats.Limit) * 100.0
+}
+
+// NetworkIO returns the bytes received and sent on all interfaces since the container started
+func (s *ContainerStats) NetworkIO() (rx, tx uint64) {
+	for _, network := range s.Networks {
+		rx += network.RxBytes
+		tx += network.TxBytes
+	}
+	return rx, tx
+}
+
+// BlockIO returns the bytes read from and written to block devices since the container started
+func (s *ContainerStats) BlockIO() (read, write uint64) {
+	for _, entry := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			read += entry.Value
+		case "write":
+			write += entry.Value
+		}
+	}
+	return read, write
+}
+
 // ContainerCommitConfig represents configuration for committing a container
 type ContainerCommitConfig struct {
 	ContainerID string
@@ -0,0 +1,31 @@
+package docker
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types/volume"
+)
+
+// VolumeList lists the volumes of the host
+func (c *Client) VolumeList(ctx context.Context) ([]*volume.Volume, error) {
+	resp, err := c.cli.VolumeList(ctx, volume.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Volumes, nil
+}
+
+// VolumeInspect returns the details of a volume
+func (c *Client) VolumeInspect(ctx context.Context, name string) (volume.Volume, error) {
+	return c.cli.VolumeInspect(ctx, name)
+}
+
+// VolumeCreate creates a volume
+func (c *Client) VolumeCreate(ctx context.Context, name, driver string) (volume.Volume, error) {
+	return c.cli.VolumeCreate(ctx, volume.CreateOptions{Name: name, Driver: driver})
+}
+
+// VolumeRemove removes a volume; force removes it while in use
+func (c *Client) VolumeRemove(ctx context.Context, name string, force bool) error {
+	return c.cli.VolumeRemove(ctx, name, force)
+}
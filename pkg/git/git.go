@@ -0,0 +1,153 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// MaxFileSize bounds the files read from repositories
+const MaxFileSize = 1 << 20
+
+// scpURL matches scp-like ssh remotes such as git@github.com:org/repo.git
+var scpURL = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[^/].*$`)
+
+// Source is a file in a branch of a remote repository
+type Source struct {
+	URL       string
+	Branch    string
+	Path      string
+	DeployKey string // PEM private key used for ssh remotes
+}
+
+// ValidateURL accepts https, ssh and scp-like remotes; local paths and other transports are refused
+// so that users cannot read repositories from the API server's disk
+func ValidateURL(remote string) error {
+	if scpURL.MatchString(remote) {
+		return nil
+	}
+	u, err := url.Parse(remote)
+	if err != nil {
+		return fmt.Errorf("invalid repository URL: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "ssh") || u.Host == "" {
+		return errors.New("repository URL must be an https, ssh or user@host:path remote")
+	}
+	return nil
+}
+
+// CleanPath resolves a path relative to the repository root; ".." cannot climb above the root
+func CleanPath(path string) (string, error) {
+	cleaned := strings.TrimPrefix(filepath.Clean("/"+path), "/")
+	if cleaned == "" {
+		return "", fmt.Errorf("invalid file path %q", path)
+	}
+	return cleaned, nil
+}
+
+// Head returns the commit the branch of a source points to
+func Head(ctx context.Context, src Source) (string, error) {
+	out, err := run(ctx, src, "", "ls-remote", "--heads", "--", src.URL, "refs/heads/"+src.Branch)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("branch %s not found", src.Branch)
+	}
+	return fields[0], nil
+}
+
+// ReadFile clones the branch of a source shallowly and returns its file and the commit it was read at
+func ReadFile(ctx context.Context, src Source) ([]byte, string, error) {
+	path, err := CleanPath(src.Path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	dir, err := os.MkdirTemp("", "einfra-git-")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := run(ctx, src, "", "clone", "--quiet", "--depth", "1", "--single-branch", "--no-tags",
+		"--branch", src.Branch, "--", src.URL, dir); err != nil {
+		return nil, "", err
+	}
+	commit, err := run(ctx, src, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Symlinks in the repository must not lead out of the checkout
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	file, err := filepath.EvalSymlinks(filepath.Join(dir, path))
+	if err != nil {
+		return nil, "", fmt.Errorf("file %s not found in %s", path, src.Branch)
+	}
+	if !strings.HasPrefix(file, root+string(filepath.Separator)) {
+		return nil, "", fmt.Errorf("file %s points outside the repository", path)
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, "", err
+	}
+	if info.IsDir() {
+		return nil, "", fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > MaxFileSize {
+		return nil, "", fmt.Errorf("file %s exceeds %d bytes", path, MaxFileSize)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, strings.TrimSpace(commit), nil
+}
+
+// run runs git non-interactively, authenticating ssh remotes with the deploy key of the source
+func run(ctx context.Context, src Source, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_CONFIG_NOSYSTEM=1")
+
+	if src.DeployKey != "" {
+		keyDir, err := os.MkdirTemp("", "einfra-git-key-")
+		if err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(keyDir)
+
+		keyFile := filepath.Join(keyDir, "id")
+		key := strings.TrimSpace(src.DeployKey) + "\n"
+		if err := os.WriteFile(keyFile, []byte(key), 0o600); err != nil {
+			return "", err
+		}
+		cmd.Env = append(cmd.Env, fmt.Sprintf(
+			"GIT_SSH_COMMAND=ssh -i %s -o IdentitiesOnly=yes -o BatchMode=yes -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=%s",
+			keyFile, filepath.Join(keyDir, "known_hosts")))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,121 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBareRepo creates a bare repository with a main branch and returns it with a work tree pushing to it
+func newBareRepo(t *testing.T) (string, func(files map[string]string) string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	bare := filepath.Join(root, "remote.git")
+	work := filepath.Join(root, "work")
+	gitCmd := func(dir string, args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_CONFIG_NOSYSTEM=1")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return string(out)
+	}
+	gitCmd(root, "init", "--quiet", "--bare", "--initial-branch=main", bare)
+	gitCmd(root, "init", "--quiet", "--initial-branch=main", work)
+
+	commit := func(files map[string]string) string {
+		for name, content := range files {
+			path := filepath.Join(work, name)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		}
+		gitCmd(work, "add", "-A")
+		gitCmd(work, "commit", "--quiet", "-m", "update")
+		gitCmd(work, "push", "--quiet", bare, "HEAD:main")
+		return gitCmd(work, "rev-parse", "HEAD")[:40]
+	}
+	return bare, commit
+}
+
+func TestHeadAndReadFile(t *testing.T) {
+	ctx := context.Background()
+	bare, commit := newBareRepo(t)
+
+	first := commit(map[string]string{"deploy/docker-compose.yml": "services: {}\n"})
+	src := Source{URL: bare, Branch: "main", Path: "deploy/docker-compose.yml"}
+
+	head, err := Head(ctx, src)
+	require.NoError(t, err)
+	assert.Equal(t, first, head)
+
+	content, sha, err := ReadFile(ctx, src)
+	require.NoError(t, err)
+	assert.Equal(t, "services: {}\n", string(content))
+	assert.Equal(t, first, sha)
+
+	second := commit(map[string]string{"deploy/docker-compose.yml": "services:\n  web:\n    image: nginx\n"})
+	head, err = Head(ctx, src)
+	require.NoError(t, err)
+	assert.Equal(t, second, head)
+
+	content, sha, err = ReadFile(ctx, src)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "nginx")
+	assert.Equal(t, second, sha)
+
+	_, err = Head(ctx, Source{URL: bare, Branch: "missing"})
+	assert.Error(t, err)
+
+	_, _, err = ReadFile(ctx, Source{URL: bare, Branch: "main", Path: "missing.yml"})
+	assert.Error(t, err)
+}
+
+func TestReadFileStaysInRepository(t *testing.T) {
+	ctx := context.Background()
+	bare, commit := newBareRepo(t)
+
+	outside := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(outside, []byte("secret"), 0o644))
+	commit(map[string]string{"compose.yml": "services: {}\n"})
+
+	// A symlink committed to the repository pointing at a file of the server
+	work := filepath.Join(filepath.Dir(bare), "work")
+	require.NoError(t, os.Symlink(outside, filepath.Join(work, "link.yml")))
+	commit(nil)
+
+	_, _, err := ReadFile(ctx, Source{URL: bare, Branch: "main", Path: "link.yml"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "outside the repository")
+
+	content, _, err := ReadFile(ctx, Source{URL: bare, Branch: "main", Path: "../../compose.yml"})
+	require.NoError(t, err)
+	assert.Equal(t, "services: {}\n", string(content))
+}
+
+func TestValidateURL(t *testing.T) {
+	for _, remote := range []string{
+		"https://github.com/org/repo.git",
+		"ssh://git@github.com/org/repo.git",
+		"git@github.com:org/repo.git",
+	} {
+		assert.NoError(t, ValidateURL(remote), remote)
+	}
+	for _, remote := range []string{
+		"/srv/repos/app.git",
+		"file:///srv/repos/app.git",
+		"http://github.com/org/repo.git",
+		"ext::sh -c touch% /tmp/pwned",
+		"--upload-pack=touch /tmp/pwned",
+	} {
+		assert.Error(t, ValidateURL(remote), remote)
+	}
+}
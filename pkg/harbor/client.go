@@ -0,0 +1,422 @@
+package harbor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unitechio/einfra-be/pkg/errorx"
+)
+
+const (
+	// apiPrefix is the path of the Harbor v2 REST API
+	apiPrefix = "/api/v2.0"
+	// pageSize is the page size used when listing all items of a collection
+	pageSize = 100
+	// defaultTimeout bounds a single API request
+	defaultTimeout = 30 * time.Second
+)
+
+// Client is a Harbor v2 REST API client. Robot accounts authenticate like users,
+// with their full name (e.g. "robot$ci") as username and their secret as password.
+type Client struct {
+	baseURL  string
+	username string
+	password string
+	http     *http.Client
+}
+
+// NewClient creates a client for the Harbor instance at baseURL.
+// A nil httpClient uses a default client with a request timeout.
+func NewClient(baseURL, username, password string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	return &Client{
+		baseURL:  strings.TrimRight(baseURL, "/") + apiPrefix,
+		username: username,
+		password: password,
+		http:     httpClient,
+	}
+}
+
+// SplitRepository splits a full repository name such as "library/nginx" into project and repository
+func SplitRepository(fullName string) (project, repository string, err error) {
+	project, repository, ok := strings.Cut(strings.Trim(fullName, "/"), "/")
+	if !ok || project == "" || repository == "" {
+		return "", "", errorx.New(errorx.CodeBadRequest, fmt.Sprintf("invalid repository name %q, expected <project>/<repository>", fullName))
+	}
+	return project, repository, nil
+}
+
+// System
+
+// SystemInfo returns general information about the Harbor instance
+func (c *Client) SystemInfo(ctx context.Context) (*SystemInfo, error) {
+	var info SystemInfo
+	if _, err := c.do(ctx, http.MethodGet, "/systeminfo", nil, nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Health returns the health of the Harbor instance and its components
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	var health Health
+	if _, err := c.do(ctx, http.MethodGet, "/health", nil, nil, &health); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// Projects
+
+// ListProjects lists all projects visible to the client, optionally filtered by visibility
+func (c *Client) ListProjects(ctx context.Context, public *bool) ([]Project, error) {
+	query := url.Values{}
+	if public != nil {
+		query.Set("public", strconv.FormatBool(*public))
+	}
+	return listAll[Project](ctx, c, "/projects", query)
+}
+
+// GetProject returns a project by name or numeric ID
+func (c *Client) GetProject(ctx context.Context, nameOrID string) (*Project, error) {
+	var project Project
+	if _, err := c.do(ctx, http.MethodGet, "/projects/"+url.PathEscape(nameOrID), nil, nil, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// CreateProject creates a project and returns its ID
+func (c *Client) CreateProject(ctx context.Context, req ProjectReq) (int64, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/projects", nil, req, nil)
+	if err != nil {
+		return 0, err
+	}
+	return idFromLocation(resp), nil
+}
+
+// UpdateProject updates a project's metadata, allowlist or limits
+func (c *Client) UpdateProject(ctx context.Context, nameOrID string, req ProjectReq) error {
+	_, err := c.do(ctx, http.MethodPut, "/projects/"+url.PathEscape(nameOrID), nil, req, nil)
+	return err
+}
+
+// DeleteProject deletes an empty project
+func (c *Client) DeleteProject(ctx context.Context, nameOrID string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/projects/"+url.PathEscape(nameOrID), nil, nil, nil)
+	return err
+}
+
+// Repositories
+
+// ListRepositories lists all repositories of a project
+func (c *Client) ListRepositories(ctx context.Context, project string) ([]Repository, error) {
+	return listAll[Repository](ctx, c, "/projects/"+url.PathEscape(project)+"/repositories", nil)
+}
+
+// GetRepository returns a repository of a project
+func (c *Client) GetRepository(ctx context.Context, project, repository string) (*Repository, error) {
+	var repo Repository
+	if _, err := c.do(ctx, http.MethodGet, repositoryPath(project, repository), nil, nil, &repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// DeleteRepository deletes a repository and all its artifacts
+func (c *Client) DeleteRepository(ctx context.Context, project, repository string) error {
+	_, err := c.do(ctx, http.MethodDelete, repositoryPath(project, repository), nil, nil, nil)
+	return err
+}
+
+// Artifacts
+
+// ListArtifacts lists all artifacts of a repository with tags, labels and scan overviews
+func (c *Client) ListArtifacts(ctx context.Context, project, repository string) ([]Artifact, error) {
+	return listAll[Artifact](ctx, c, repositoryPath(project, repository)+"/artifacts", artifactQuery())
+}
+
+// GetArtifact returns an artifact by tag or digest
+func (c *Client) GetArtifact(ctx context.Context, project, repository, reference string) (*Artifact, error) {
+	var artifact Artifact
+	if _, err := c.do(ctx, http.MethodGet, artifactPath(project, repository, reference), artifactQuery(), nil, &artifact); err != nil {
+		return nil, err
+	}
+	return &artifact, nil
+}
+
+// DeleteArtifact deletes an artifact by tag or digest
+func (c *Client) DeleteArtifact(ctx context.Context, project, repository, reference string) error {
+	_, err := c.do(ctx, http.MethodDelete, artifactPath(project, repository, reference), nil, nil, nil)
+	return err
+}
+
+// CopyArtifact copies an artifact into a repository; from is "<project>/<repository>:<tag>"
+// or "<project>/<repository>@<digest>"
+func (c *Client) CopyArtifact(ctx context.Context, project, repository, from string) error {
+	query := url.Values{"from": {from}}
+	_, err := c.do(ctx, http.MethodPost, repositoryPath(project, repository)+"/artifacts", query, nil, nil)
+	return err
+}
+
+// ScanArtifact triggers a vulnerability scan of an artifact
+func (c *Client) ScanArtifact(ctx context.Context, project, repository, reference string) error {
+	_, err := c.do(ctx, http.MethodPost, artifactPath(project, repository, reference)+"/scan", nil, nil, nil)
+	return err
+}
+
+// GetVulnerabilities returns the vulnerability reports of an artifact, keyed by report MIME type
+func (c *Client) GetVulnerabilities(ctx context.Context, project, repository, reference string) (map[string]VulnerabilityReport, error) {
+	reports := map[string]VulnerabilityReport{}
+	if _, err := c.do(ctx, http.MethodGet, artifactPath(project, repository, reference)+"/additions/vulnerabilities", nil, nil, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// ListArtifactsByCVE searches all projects for the artifacts affected by a CVE through the security hub
+func (c *Client) ListArtifactsByCVE(ctx context.Context, cveID string) ([]ArtifactVulnerability, error) {
+	query := url.Values{
+		"q":        {"cve_id=" + cveID},
+		"with_tag": {"true"},
+	}
+	return listAll[ArtifactVulnerability](ctx, c, "/vul/vulnerabilities", query)
+}
+
+// AddArtifactLabel attaches a label to an artifact
+func (c *Client) AddArtifactLabel(ctx context.Context, project, repository, reference string, labelID int64) error {
+	_, err := c.do(ctx, http.MethodPost, artifactPath(project, repository, reference)+"/labels", nil, map[string]int64{"id": labelID}, nil)
+	return err
+}
+
+// RemoveArtifactLabel detaches a label from an artifact
+func (c *Client) RemoveArtifactLabel(ctx context.Context, project, repository, reference string, labelID int64) error {
+	p := artifactPath(project, repository, reference) + "/labels/" + strconv.FormatInt(labelID, 10)
+	_, err := c.do(ctx, http.MethodDelete, p, nil, nil, nil)
+	return err
+}
+
+// Labels
+
+// ListLabels lists global labels (scope "g") or the labels of a project (scope "p")
+func (c *Client) ListLabels(ctx context.Context, scope string, projectID int64) ([]Label, error) {
+	query := url.Values{"scope": {scope}}
+	if projectID > 0 {
+		query.Set("project_id", strconv.FormatInt(projectID, 10))
+	}
+	return listAll[Label](ctx, c, "/labels", query)
+}
+
+// CreateLabel creates a label and returns its ID
+func (c *Client) CreateLabel(ctx context.Context, label Label) (int64, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/labels", nil, label, nil)
+	if err != nil {
+		return 0, err
+	}
+	return idFromLocation(resp), nil
+}
+
+// DeleteLabel deletes a label
+func (c *Client) DeleteLabel(ctx context.Context, labelID int64) error {
+	_, err := c.do(ctx, http.MethodDelete, "/labels/"+strconv.FormatInt(labelID, 10), nil, nil, nil)
+	return err
+}
+
+// Quotas
+
+// GetProjectQuota returns the quota of a project
+func (c *Client) GetProjectQuota(ctx context.Context, projectID int64) (*Quota, error) {
+	query := url.Values{"reference": {"project"}, "reference_id": {strconv.FormatInt(projectID, 10)}}
+	quotas, err := listAll[Quota](ctx, c, "/quotas", query)
+	if err != nil {
+		return nil, err
+	}
+	if len(quotas) == 0 {
+		return nil, errorx.New(errorx.CodeNotFound, fmt.Sprintf("harbor: no quota for project %d", projectID))
+	}
+	return &quotas[0], nil
+}
+
+// UpdateQuota sets the hard limits of a quota; -1 means unlimited
+func (c *Client) UpdateQuota(ctx context.Context, quotaID int64, hard map[string]int64) error {
+	body := map[string]interface{}{"hard": hard}
+	_, err := c.do(ctx, http.MethodPut, "/quotas/"+strconv.FormatInt(quotaID, 10), nil, body, nil)
+	return err
+}
+
+// System
+
+// RunGarbageCollection starts a manual registry garbage collection and returns its job ID.
+// It requires a system administrator account.
+func (c *Client) RunGarbageCollection(ctx context.Context, deleteUntagged bool) (int64, error) {
+	body := map[string]interface{}{
+		"schedule":   map[string]string{"type": "Manual"},
+		"parameters": map[string]interface{}{"delete_untagged": deleteUntagged},
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/system/gc/schedule", nil, body, nil)
+	if err != nil {
+		return 0, err
+	}
+	return idFromLocation(resp), nil
+}
+
+// Transport
+
+// apiError is the error body returned by Harbor
+type apiError struct {
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// do sends a request to path (relative to the API prefix) and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, p string, query url.Values, body, out interface{}) (*http.Response, error) {
+	target := c.baseURL + p
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, errorx.Wrap(err, errorx.CodeBadRequest, "harbor: failed to encode request")
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, errorx.Wrap(err, errorx.CodeBadRequest, "harbor: invalid request")
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, errorx.Wrap(err, errorx.CodeServiceUnavailable, "harbor: request failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, responseError(resp)
+	}
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+			return nil, errorx.Wrap(err, errorx.CodeInternalError, "harbor: failed to decode response")
+		}
+	}
+	return resp, nil
+}
+
+// responseError maps a Harbor error response to an errorx error with a matching code
+func responseError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	message := strings.TrimSpace(string(data))
+	var body apiError
+	if json.Unmarshal(data, &body) == nil && len(body.Errors) > 0 {
+		messages := make([]string, 0, len(body.Errors))
+		for _, e := range body.Errors {
+			messages = append(messages, e.Message)
+		}
+		message = strings.Join(messages, "; ")
+	}
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+
+	var code int
+	switch resp.StatusCode {
+	case http.StatusBadRequest:
+		code = errorx.CodeBadRequest
+	case http.StatusUnauthorized:
+		code = errorx.CodeUnauthorized
+	case http.StatusForbidden:
+		code = errorx.CodeForbidden
+	case http.StatusNotFound:
+		code = errorx.CodeNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		code = errorx.CodeConflict
+	case http.StatusUnprocessableEntity:
+		code = errorx.CodeUnprocessableEntity
+	case http.StatusTooManyRequests:
+		code = errorx.CodeTooManyRequests
+	case http.StatusGatewayTimeout:
+		code = errorx.CodeGatewayTimeout
+	default:
+		if resp.StatusCode >= http.StatusInternalServerError {
+			code = errorx.CodeServiceUnavailable
+		} else {
+			code = errorx.CodeBadRequest
+		}
+	}
+	return errorx.New(code, "harbor: "+message)
+}
+
+// listAll fetches every page of a collection
+func listAll[T any](ctx context.Context, c *Client, p string, query url.Values) ([]T, error) {
+	q := url.Values{}
+	for key, values := range query {
+		q[key] = values
+	}
+	q.Set("page_size", strconv.Itoa(pageSize))
+
+	var items []T
+	for page := 1; ; page++ {
+		q.Set("page", strconv.Itoa(page))
+
+		var batch []T
+		resp, err := c.do(ctx, http.MethodGet, p, q, nil, &batch)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, batch...)
+
+		total, err := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+		if len(batch) < pageSize || (err == nil && len(items) >= total) {
+			return items, nil
+		}
+	}
+}
+
+// repositoryPath builds the path of a repository; Harbor requires slashes in
+// repository names to be encoded twice
+func repositoryPath(project, repository string) string {
+	return "/projects/" + url.PathEscape(project) + "/repositories/" + url.PathEscape(url.PathEscape(repository))
+}
+
+func artifactPath(project, repository, reference string) string {
+	return repositoryPath(project, repository) + "/artifacts/" + url.PathEscape(reference)
+}
+
+func artifactQuery() url.Values {
+	return url.Values{
+		"with_tag":           {"true"},
+		"with_label":         {"true"},
+		"with_scan_overview": {"true"},
+	}
+}
+
+// idFromLocation extracts the ID of a created resource from the Location header
+func idFromLocation(resp *http.Response) int64 {
+	id, _ := strconv.ParseInt(path.Base(resp.Header.Get("Location")), 10, 64)
+	return id
+}
@@ -0,0 +1,169 @@
+package harbor
+
+import "time"
+
+// SystemInfo is the subset of /systeminfo the platform uses
+type SystemInfo struct {
+	HarborVersion string `json:"harbor_version"`
+}
+
+// Health is the overall and per-component health of a Harbor instance
+type Health struct {
+	Status     string            `json:"status"`
+	Components []ComponentHealth `json:"components"`
+}
+
+// ComponentHealth is the health of a single Harbor component
+type ComponentHealth struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Project is a Harbor project
+type Project struct {
+	ProjectID    int64             `json:"project_id"`
+	Name         string            `json:"name"`
+	OwnerName    string            `json:"owner_name"`
+	RepoCount    int64             `json:"repo_count"`
+	ChartCount   int64             `json:"chart_count"`
+	Metadata     map[string]string `json:"metadata"`
+	CVEAllowlist *CVEAllowlist     `json:"cve_allowlist,omitempty"`
+	CreationTime time.Time         `json:"creation_time"`
+	UpdateTime   time.Time         `json:"update_time"`
+}
+
+// CVEAllowlist lists CVEs ignored by scan-based policies
+type CVEAllowlist struct {
+	Items []CVEAllowlistItem `json:"items"`
+}
+
+// CVEAllowlistItem is a single allowlisted CVE
+type CVEAllowlistItem struct {
+	CVEID string `json:"cve_id"`
+}
+
+// ProjectReq creates or updates a project
+type ProjectReq struct {
+	ProjectName  string            `json:"project_name,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	StorageLimit *int64            `json:"storage_limit,omitempty"`
+	CVEAllowlist *CVEAllowlist     `json:"cve_allowlist,omitempty"`
+}
+
+// Repository is a repository within a project; Name includes the project, e.g. "library/nginx"
+type Repository struct {
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	ProjectID     int64     `json:"project_id"`
+	Description   string    `json:"description"`
+	ArtifactCount int64     `json:"artifact_count"`
+	PullCount     int64     `json:"pull_count"`
+	CreationTime  time.Time `json:"creation_time"`
+	UpdateTime    time.Time `json:"update_time"`
+}
+
+// Artifact is an image or other OCI artifact
+type Artifact struct {
+	ID                int64                          `json:"id"`
+	Digest            string                         `json:"digest"`
+	ProjectID         int64                          `json:"project_id"`
+	RepositoryID      int64                          `json:"repository_id"`
+	RepositoryName    string                         `json:"repository_name"`
+	Type              string                         `json:"type"`
+	Size              int64                          `json:"size"`
+	PushTime          time.Time                      `json:"push_time"`
+	PullTime          time.Time                      `json:"pull_time"`
+	Tags              []Tag                          `json:"tags"`
+	Labels            []Label                        `json:"labels"`
+	Annotations       map[string]string              `json:"annotations"`
+	ManifestMediaType string                         `json:"manifest_media_type"`
+	ScanOverview      map[string]NativeReportSummary `json:"scan_overview,omitempty"` // Keyed by report MIME type
+}
+
+// Tag is an artifact tag
+type Tag struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	PushTime  time.Time `json:"push_time"`
+	PullTime  time.Time `json:"pull_time"`
+	Immutable bool      `json:"immutable"`
+	Signed    bool      `json:"signed"`
+}
+
+// NativeReportSummary is the scan overview of an artifact
+type NativeReportSummary struct {
+	ReportID   string                `json:"report_id"`
+	ScanStatus string                `json:"scan_status"`
+	Severity   string                `json:"severity"`
+	Duration   int64                 `json:"duration"`
+	StartTime  time.Time             `json:"start_time"`
+	EndTime    time.Time             `json:"end_time"`
+	Summary    *VulnerabilitySummary `json:"summary,omitempty"`
+}
+
+// VulnerabilitySummary counts vulnerabilities, with Summary keyed by severity
+type VulnerabilitySummary struct {
+	Total   int            `json:"total"`
+	Fixable int            `json:"fixable"`
+	Summary map[string]int `json:"summary"`
+}
+
+// VulnerabilityReport is the detailed vulnerability report of an artifact
+type VulnerabilityReport struct {
+	Severity        string              `json:"severity"`
+	Vulnerabilities []VulnerabilityItem `json:"vulnerabilities"`
+}
+
+// VulnerabilityItem is a single vulnerability found in an artifact
+type VulnerabilityItem struct {
+	ID            string   `json:"id"`
+	Package       string   `json:"package"`
+	Version       string   `json:"version"`
+	FixVersion    string   `json:"fix_version"`
+	Severity      string   `json:"severity"`
+	Description   string   `json:"description"`
+	Links         []string `json:"links"`
+	PreferredCVSS *CVSS    `json:"preferred_cvss,omitempty"`
+}
+
+// ArtifactVulnerability is a vulnerability of an artifact as listed by the security hub
+type ArtifactVulnerability struct {
+	ProjectID      int64    `json:"project_id"`
+	RepositoryName string   `json:"repository_name"`
+	Digest         string   `json:"digest"`
+	Tags           []string `json:"tags"`
+	CVEID          string   `json:"cve_id"`
+	Severity       string   `json:"severity"`
+	Package        string   `json:"package"`
+	Version        string   `json:"version"`
+	FixedVersion   string   `json:"fixed_version"`
+}
+
+// CVSS holds the CVSS scores of a vulnerability
+type CVSS struct {
+	ScoreV3 *float64 `json:"score_v3,omitempty"`
+	ScoreV2 *float64 `json:"score_v2,omitempty"`
+}
+
+// Label is a global or project label
+type Label struct {
+	ID           int64     `json:"id,omitempty"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description,omitempty"`
+	Color        string    `json:"color,omitempty"`
+	Scope        string    `json:"scope"` // "g" for global, "p" for project
+	ProjectID    int64     `json:"project_id,omitempty"`
+	CreationTime time.Time `json:"creation_time,omitempty"`
+	UpdateTime   time.Time `json:"update_time,omitempty"`
+}
+
+// Quota is the resource quota of a project
+type Quota struct {
+	ID           int64                  `json:"id"`
+	Ref          map[string]interface{} `json:"ref"`
+	Hard         map[string]int64       `json:"hard"`
+	Used         map[string]int64       `json:"used"`
+	CreationTime time.Time              `json:"creation_time"`
+	UpdateTime   time.Time              `json:"update_time"`
+}
@@ -0,0 +1,47 @@
+package harbor
+
+// Webhook event types sent by Harbor
+const (
+	EventTypePushArtifact      = "PUSH_ARTIFACT"
+	EventTypeDeleteArtifact    = "DELETE_ARTIFACT"
+	EventTypeScanningCompleted = "SCANNING_COMPLETED"
+	EventTypeScanningFailed    = "SCANNING_FAILED"
+	EventTypeQuotaExceed       = "QUOTA_EXCEED"
+	EventTypeQuotaWarning      = "QUOTA_WARNING"
+	EventTypePullArtifact      = "PULL_ARTIFACT"
+	EventTypeReplication       = "REPLICATION"
+	EventTypeTagRetention      = "TAG_RETENTION"
+	EventTypeScanningStopped   = "SCANNING_STOPPED"
+)
+
+// WebhookPayload is the default (Harbor format) payload of a webhook policy
+type WebhookPayload struct {
+	Type      string           `json:"type"`
+	OccurAt   int64            `json:"occur_at"` // Unix seconds
+	Operator  string           `json:"operator"`
+	EventData WebhookEventData `json:"event_data"`
+}
+
+// WebhookEventData describes the repository and artifacts an event is about
+type WebhookEventData struct {
+	Resources        []WebhookResource `json:"resources"`
+	Repository       WebhookRepository `json:"repository"`
+	CustomAttributes map[string]string `json:"custom_attributes,omitempty"` // e.g. "Details" for quota events
+}
+
+// WebhookResource is an artifact affected by an event
+type WebhookResource struct {
+	Digest       string                         `json:"digest"`
+	Tag          string                         `json:"tag"`
+	ResourceURL  string                         `json:"resource_url"`
+	ScanOverview map[string]NativeReportSummary `json:"scan_overview,omitempty"` // Keyed by report MIME type
+}
+
+// WebhookRepository is the repository an event is about
+type WebhookRepository struct {
+	DateCreated  int64  `json:"date_created"`
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+	RepoFullName string `json:"repo_full_name"`
+	RepoType     string `json:"repo_type"` // public or private
+}
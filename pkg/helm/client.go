@@ -0,0 +1,225 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/unitechio/einfra-be/pkg/k8s"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// storageDriver keeps release records in Secrets, the Helm 3 default
+const storageDriver = "secrets"
+
+// DefaultTimeout bounds install, upgrade, rollback and uninstall operations
+const DefaultTimeout = 5 * time.Minute
+
+// Client runs Helm actions against a single cluster
+type Client struct {
+	k8s      *k8s.Client
+	settings *cli.EnvSettings
+}
+
+// InstallOptions configures an install or upgrade
+type InstallOptions struct {
+	ReleaseName     string
+	Namespace       string
+	Values          map[string]interface{}
+	CreateNamespace bool
+	Wait            bool
+	Atomic          bool
+	Timeout         time.Duration
+	DryRun          bool
+	ReuseValues     bool // Upgrade only: merge values into the current release values
+	Description     string
+}
+
+// ChartSource locates a chart in a chart repository or an OCI registry
+type ChartSource struct {
+	RepoURL  string // Chart repository URL; empty for oci:// references
+	Chart    string // Chart name in the repository, or an oci:// reference
+	Version  string // Version constraint; latest when empty
+	Username string
+	Password string
+}
+
+// NewClient creates a Helm client on top of a Kubernetes client. Repository indexes
+// and downloaded charts are cached under the system temp directory.
+func NewClient(k8sClient *k8s.Client) *Client {
+	home := filepath.Join(os.TempDir(), "einfra-helm")
+	settings := cli.New()
+	settings.RepositoryCache = filepath.Join(home, "cache")
+	settings.RepositoryConfig = filepath.Join(home, "repositories.yaml")
+	settings.RegistryConfig = filepath.Join(home, "registry.json")
+	return &Client{k8s: k8sClient, settings: settings}
+}
+
+func (c *Client) actionConfig(namespace string) (*action.Configuration, error) {
+	cfg := new(action.Configuration)
+	if err := cfg.Init(c.k8s.RESTClientGetter(namespace), namespace, storageDriver, log.Printf); err != nil {
+		return nil, fmt.Errorf("failed to initialize helm: %w", err)
+	}
+	return cfg, nil
+}
+
+// List returns the latest revision of every release; an empty namespace lists all namespaces
+func (c *Client) List(namespace string) ([]*release.Release, error) {
+	cfg, err := c.actionConfig(namespace)
+	if err != nil {
+		return nil, err
+	}
+	list := action.NewList(cfg)
+	list.AllNamespaces = namespace == ""
+	list.StateMask = action.ListAll
+	return list.Run()
+}
+
+// Get returns a release revision; revision 0 is the latest
+func (c *Client) Get(namespace, name string, revision int) (*release.Release, error) {
+	cfg, err := c.actionConfig(namespace)
+	if err != nil {
+		return nil, err
+	}
+	get := action.NewGet(cfg)
+	get.Version = revision
+	return get.Run(name)
+}
+
+// History returns all stored revisions of a release
+func (c *Client) History(namespace, name string) ([]*release.Release, error) {
+	cfg, err := c.actionConfig(namespace)
+	if err != nil {
+		return nil, err
+	}
+	return action.NewHistory(cfg).Run(name)
+}
+
+// Install installs a chart as a new release
+func (c *Client) Install(ctx context.Context, chrt *chart.Chart, opts InstallOptions) (*release.Release, error) {
+	cfg, err := c.actionConfig(opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	install := action.NewInstall(cfg)
+	install.ReleaseName = opts.ReleaseName
+	install.Namespace = opts.Namespace
+	install.CreateNamespace = opts.CreateNamespace
+	install.Wait = opts.Wait
+	install.Atomic = opts.Atomic
+	install.Timeout = timeoutOrDefault(opts.Timeout)
+	install.Description = opts.Description
+	if opts.DryRun {
+		install.DryRun = true
+		install.DryRunOption = "server"
+	}
+	return install.RunWithContext(ctx, chrt, opts.Values)
+}
+
+// Upgrade upgrades an existing release to chrt
+func (c *Client) Upgrade(ctx context.Context, chrt *chart.Chart, opts InstallOptions) (*release.Release, error) {
+	cfg, err := c.actionConfig(opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	upgrade := action.NewUpgrade(cfg)
+	upgrade.Namespace = opts.Namespace
+	upgrade.Wait = opts.Wait
+	upgrade.Atomic = opts.Atomic
+	upgrade.Timeout = timeoutOrDefault(opts.Timeout)
+	upgrade.ReuseValues = opts.ReuseValues
+	upgrade.Description = opts.Description
+	if opts.DryRun {
+		upgrade.DryRun = true
+		upgrade.DryRunOption = "server"
+	}
+	return upgrade.RunWithContext(ctx, opts.ReleaseName, chrt, opts.Values)
+}
+
+// Rollback rolls a release back to revision; revision 0 is the previous one
+func (c *Client) Rollback(namespace, name string, revision int, wait bool, timeout time.Duration) error {
+	cfg, err := c.actionConfig(namespace)
+	if err != nil {
+		return err
+	}
+	rollback := action.NewRollback(cfg)
+	rollback.Version = revision
+	rollback.Wait = wait
+	rollback.Timeout = timeoutOrDefault(timeout)
+	return rollback.Run(name)
+}
+
+// Uninstall removes a release; with keepHistory its records are kept for a later rollback
+func (c *Client) Uninstall(namespace, name string, keepHistory bool) (*release.UninstallReleaseResponse, error) {
+	cfg, err := c.actionConfig(namespace)
+	if err != nil {
+		return nil, err
+	}
+	uninstall := action.NewUninstall(cfg)
+	uninstall.KeepHistory = keepHistory
+	uninstall.Timeout = DefaultTimeout
+	return uninstall.Run(name)
+}
+
+// LocateChart downloads a chart from a chart repository or OCI registry and loads it
+func (c *Client) LocateChart(src ChartSource) (*chart.Chart, error) {
+	var registryClient *registry.Client
+	if registry.IsOCI(src.Chart) {
+		var err error
+		registryClient, err = registry.NewClient(registry.ClientOptCredentialsFile(c.settings.RegistryConfig))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create registry client: %w", err)
+		}
+		if src.Username != "" {
+			u, err := url.Parse(src.Chart)
+			if err != nil {
+				return nil, fmt.Errorf("invalid chart reference %q: %w", src.Chart, err)
+			}
+			if err := registryClient.Login(u.Host, registry.LoginOptBasicAuth(src.Username, src.Password)); err != nil {
+				return nil, fmt.Errorf("failed to log in to %s: %w", u.Host, err)
+			}
+		}
+	} else if src.RepoURL == "" {
+		return nil, fmt.Errorf("a repository URL is required for chart %q", src.Chart)
+	}
+
+	// The install action only carries the chart path options and registry client here
+	pull := action.NewInstall(&action.Configuration{RegistryClient: registryClient})
+	pull.RepoURL = src.RepoURL
+	pull.Version = src.Version
+	pull.Username = src.Username
+	pull.Password = src.Password
+
+	path, err := pull.ChartPathOptions.LocateChart(strings.TrimSpace(src.Chart), c.settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate chart %q: %w", src.Chart, err)
+	}
+	return loader.Load(path)
+}
+
+// LoadArchive loads a chart from a packaged .tgz archive
+func LoadArchive(data []byte) (*chart.Chart, error) {
+	chrt, err := loader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart archive: %w", err)
+	}
+	return chrt, nil
+}
+
+func timeoutOrDefault(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return DefaultTimeout
+	}
+	return timeout
+}
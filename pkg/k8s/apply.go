@@ -0,0 +1,188 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// ApplyOptions controls a server-side apply
+type ApplyOptions struct {
+	FieldManager string
+	DryRun       bool // Server-side dry run: nothing is persisted
+	Force        bool // Take ownership of fields managed by other field managers
+}
+
+// ParseManifests decodes multi-document YAML or JSON into unstructured objects.
+// Empty documents are skipped and "List" kinds are flattened into their items.
+func ParseManifests(data []byte) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+
+	var objects []*unstructured.Unstructured
+	for index := 0; ; index++ {
+		var raw map[string]interface{}
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("document %d: %w", index, err)
+		}
+		if len(raw) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{Object: raw}
+		if obj.IsList() {
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, fmt.Errorf("document %d: %w", index, err)
+			}
+			for i := range list.Items {
+				objects = append(objects, &list.Items[i])
+			}
+			continue
+		}
+
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" {
+			return nil, fmt.Errorf("document %d: apiVersion and kind are required", index)
+		}
+		if obj.GetName() == "" && obj.GetGenerateName() == "" {
+			return nil, fmt.Errorf("document %d: metadata.name is required", index)
+		}
+		objects = append(objects, obj)
+	}
+
+	return objects, nil
+}
+
+// ResourceFor resolves the resource client for an object through discovery.
+// Cluster-scoped kinds ignore the namespace; namespaced kinds fall back to defaultNamespace.
+func (c *Client) ResourceFor(obj *unstructured.Unstructured, defaultNamespace string) (dynamic.ResourceInterface, *meta.RESTMapping, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil && meta.IsNoMatchError(err) {
+		// The kind may come from a CRD installed after discovery was cached
+		c.mapper.Reset()
+		mapping, err = c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to resolve %s: %w", gvk.String(), err)
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		obj.SetNamespace("")
+		return c.dynamic.Resource(mapping.Resource), mapping, nil
+	}
+
+	if obj.GetNamespace() == "" {
+		if defaultNamespace == "" {
+			defaultNamespace = metav1.NamespaceDefault
+		}
+		obj.SetNamespace(defaultNamespace)
+	}
+	return c.dynamic.Resource(mapping.Resource).Namespace(obj.GetNamespace()), mapping, nil
+}
+
+// Apply performs a server-side apply of obj and returns the resulting object
+func (c *Client) Apply(ctx context.Context, obj *unstructured.Unstructured, defaultNamespace string, opts ApplyOptions) (*unstructured.Unstructured, error) {
+	if opts.FieldManager == "" {
+		return nil, errors.New("field manager is required for server-side apply")
+	}
+
+	resource, _, err := c.ResourceFor(obj, defaultNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode object: %w", err)
+	}
+
+	force := opts.Force
+	patchOpts := metav1.PatchOptions{
+		FieldManager: opts.FieldManager,
+		Force:        &force,
+	}
+	if opts.DryRun {
+		patchOpts.DryRun = []string{metav1.DryRunAll}
+	}
+
+	return resource.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, patchOpts)
+}
+
+// GetLive fetches the current state of obj from the cluster; it returns nil when the object does not exist
+func (c *Client) GetLive(ctx context.Context, obj *unstructured.Unstructured, defaultNamespace string) (*unstructured.Unstructured, error) {
+	resource, _, err := c.ResourceFor(obj, defaultNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	live, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return live, nil
+}
+
+// Delete removes an object from the cluster
+func (c *Client) Delete(ctx context.Context, gvk schema.GroupVersionKind, namespace, name string) error {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+
+	resource, _, err := c.ResourceFor(obj, namespace)
+	if err != nil {
+		return err
+	}
+	return resource.Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// StripServerFields removes fields owned by the API server so two objects can be compared
+func StripServerFields(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	if obj == nil {
+		return nil
+	}
+	clean := obj.DeepCopy()
+	unstructured.RemoveNestedField(clean.Object, "metadata", "managedFields")
+	unstructured.RemoveNestedField(clean.Object, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(clean.Object, "metadata", "generation")
+	unstructured.RemoveNestedField(clean.Object, "metadata", "uid")
+	unstructured.RemoveNestedField(clean.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(clean.Object, "metadata", "selfLink")
+	unstructured.RemoveNestedField(clean.Object, "metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration")
+	unstructured.RemoveNestedField(clean.Object, "status")
+	if len(clean.GetAnnotations()) == 0 {
+		unstructured.RemoveNestedField(clean.Object, "metadata", "annotations")
+	}
+	return clean
+}
+
+// ToYAML renders an object as YAML; a nil object renders as an empty string
+func ToYAML(obj *unstructured.Unstructured) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	data, err := sigsyaml.Marshal(obj.Object)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,216 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Config describes how to reach a Kubernetes API server
+type Config struct {
+	APIServer      string // Overrides the server from the kubeconfig when set
+	KubeconfigPath string // Path to a kubeconfig file
+	KubeconfigData []byte // Raw kubeconfig content, used when no path is given
+	Context        string // Kubeconfig context to use (default: current-context)
+}
+
+// Client wraps the typed, dynamic and discovery Kubernetes clients for a single cluster
+type Client struct {
+	restConfig *rest.Config
+	clientset  kubernetes.Interface
+	dynamic    dynamic.Interface
+	discovery  discovery.CachedDiscoveryInterface
+	mapper     *restmapper.DeferredDiscoveryRESTMapper
+}
+
+// NewClient creates a client from the given configuration
+func NewClient(cfg Config) (*Client, error) {
+	restConfig, err := buildRestConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientFromRestConfig(restConfig)
+}
+
+// NewClientFromRestConfig creates a client from an existing REST config
+func NewClientFromRestConfig(restConfig *rest.Config) (*Client, error) {
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes clientset: %w", err)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+
+	cachedDiscovery := memory.NewMemCacheClient(discoveryClient)
+	return &Client{
+		restConfig: restConfig,
+		clientset:  clientset,
+		dynamic:    dynamicClient,
+		discovery:  cachedDiscovery,
+		mapper:     restmapper.NewDeferredDiscoveryRESTMapper(cachedDiscovery),
+	}, nil
+}
+
+// NewClientFromInterfaces creates a client around existing typed and dynamic clients, e.g. fakes in
+// tests. It has no REST config, so it cannot exec into pods or impersonate users.
+func NewClientFromInterfaces(clientset kubernetes.Interface, dynamicClient dynamic.Interface) *Client {
+	cachedDiscovery := memory.NewMemCacheClient(clientset.Discovery())
+	return &Client{
+		clientset: clientset,
+		dynamic:   dynamicClient,
+		discovery: cachedDiscovery,
+		mapper:    restmapper.NewDeferredDiscoveryRESTMapper(cachedDiscovery),
+	}
+}
+
+func buildRestConfig(cfg Config) (*rest.Config, error) {
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: cfg.Context}
+	if cfg.APIServer != "" {
+		overrides.ClusterInfo.Server = cfg.APIServer
+	}
+
+	switch {
+	case cfg.KubeconfigPath != "":
+		loader := &clientcmd.ClientConfigLoadingRules{ExplicitPath: cfg.KubeconfigPath}
+		return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loader, overrides).ClientConfig()
+	case len(cfg.KubeconfigData) > 0:
+		rawConfig, err := clientcmd.Load(cfg.KubeconfigData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+		}
+		return clientcmd.NewDefaultClientConfig(*rawConfig, overrides).ClientConfig()
+	default:
+		return nil, errors.New("no kubeconfig provided")
+	}
+}
+
+// RestConfig returns a copy of the REST config used by the client
+func (c *Client) RestConfig() *rest.Config {
+	return rest.CopyConfig(c.restConfig)
+}
+
+// Clientset returns the typed Kubernetes clientset
+func (c *Client) Clientset() kubernetes.Interface {
+	return c.clientset
+}
+
+// Dynamic returns the dynamic client
+func (c *Client) Dynamic() dynamic.Interface {
+	return c.dynamic
+}
+
+// Impersonate returns a client that acts as the given user and groups.
+// The platform identity needs the "impersonate" verb on users and groups.
+func (c *Client) Impersonate(user string, groups []string) (*Client, error) {
+	if c.restConfig == nil {
+		return nil, errors.New("client has no REST config to impersonate with")
+	}
+	restConfig := rest.CopyConfig(c.restConfig)
+	restConfig.Impersonate = rest.ImpersonationConfig{
+		UserName: user,
+		Groups:   groups,
+	}
+	return NewClientFromRestConfig(restConfig)
+}
+
+// ResetMapper drops cached discovery data, e.g. after new CRDs are installed
+func (c *Client) ResetMapper() {
+	c.mapper.Reset()
+}
+
+// ClientManager caches one client per cluster
+type ClientManager struct {
+	clients     map[string]*Client
+	userClients map[string]map[string]*Client // clusterID -> impersonated user -> client
+	mu          sync.RWMutex
+}
+
+// NewClientManager creates a new client manager
+func NewClientManager() *ClientManager {
+	return &ClientManager{
+		clients:     make(map[string]*Client),
+		userClients: make(map[string]map[string]*Client),
+	}
+}
+
+// Get returns the cached client for a cluster or creates one from cfg
+func (m *ClientManager) Get(clusterID string, cfg Config) (*Client, error) {
+	m.mu.RLock()
+	client, ok := m.clients[clusterID]
+	m.mu.RUnlock()
+	if ok {
+		return client, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if client, ok := m.clients[clusterID]; ok {
+		return client, nil
+	}
+
+	client, err := NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	m.clients[clusterID] = client
+	return client, nil
+}
+
+// GetForUser returns a cached client for a cluster that impersonates user
+func (m *ClientManager) GetForUser(clusterID string, cfg Config, user string, groups []string) (*Client, error) {
+	m.mu.RLock()
+	client, ok := m.userClients[clusterID][user]
+	m.mu.RUnlock()
+	if ok {
+		return client, nil
+	}
+
+	base, err := m.Get(clusterID, cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err = base.Impersonate(user, groups)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.userClients[clusterID] == nil {
+		m.userClients[clusterID] = make(map[string]*Client)
+	}
+	m.userClients[clusterID][user] = client
+	return client, nil
+}
+
+// Set registers a client for a cluster, replacing any cached one
+func (m *ClientManager) Set(clusterID string, client *Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients[clusterID] = client
+}
+
+// Remove drops the cached client for a cluster, e.g. after its credentials change
+func (m *ClientManager) Remove(clusterID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.clients, clusterID)
+	delete(m.userClients, clusterID)
+}
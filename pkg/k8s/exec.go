@@ -0,0 +1,110 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// TerminalSize is the size of an interactive terminal
+type TerminalSize = remotecommand.TerminalSize
+
+// ExecOptions configures a command executed in a pod container
+type ExecOptions struct {
+	Namespace string
+	Pod       string
+	Container string
+	Command   []string
+	Stdin     io.Reader
+	Stdout    io.Writer
+	Stderr    io.Writer // Ignored with TTY, which merges stderr into stdout
+	TTY       bool
+	Resize    <-chan TerminalSize // Terminal size changes, only used with TTY
+}
+
+// Exec runs a command in a pod container and streams its I/O. The WebSocket protocol is
+// tried first, falling back to SPDY for API servers that do not support it.
+// It returns the command exit code; a non-zero exit code is not an error.
+func (c *Client) Exec(ctx context.Context, opts ExecOptions) (int, error) {
+	if len(opts.Command) == 0 {
+		return -1, errors.New("command is required")
+	}
+
+	execOpts := &corev1.PodExecOptions{
+		Container: opts.Container,
+		Command:   opts.Command,
+		Stdin:     opts.Stdin != nil,
+		Stdout:    opts.Stdout != nil,
+		Stderr:    opts.Stderr != nil && !opts.TTY,
+		TTY:       opts.TTY,
+	}
+
+	req := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(opts.Namespace).
+		Name(opts.Pod).
+		SubResource("exec").
+		VersionedParams(execOpts, scheme.ParameterCodec)
+
+	spdyExec, err := remotecommand.NewSPDYExecutor(c.restConfig, "POST", req.URL())
+	if err != nil {
+		return -1, fmt.Errorf("failed to create SPDY executor: %w", err)
+	}
+	wsExec, err := remotecommand.NewWebSocketExecutor(c.restConfig, "GET", req.URL().String())
+	if err != nil {
+		return -1, fmt.Errorf("failed to create WebSocket executor: %w", err)
+	}
+	executor, err := remotecommand.NewFallbackExecutor(wsExec, spdyExec, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	streamOpts := remotecommand.StreamOptions{
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Tty:    opts.TTY,
+	}
+	if execOpts.Stderr {
+		streamOpts.Stderr = opts.Stderr
+	}
+	if opts.TTY && opts.Resize != nil {
+		streamOpts.TerminalSizeQueue = &terminalSizeQueue{ctx: ctx, sizes: opts.Resize}
+	}
+
+	err = executor.StreamWithContext(ctx, streamOpts)
+	if err != nil {
+		var exitErr utilexec.ExitError
+		if errors.As(err, &exitErr) && exitErr.Exited() {
+			return exitErr.ExitStatus(), nil
+		}
+		return -1, err
+	}
+	return 0, nil
+}
+
+// terminalSizeQueue adapts a channel of sizes to remotecommand.TerminalSizeQueue
+type terminalSizeQueue struct {
+	ctx   context.Context
+	sizes <-chan TerminalSize
+}
+
+func (q *terminalSizeQueue) Next() *TerminalSize {
+	select {
+	case size, ok := <-q.sizes:
+		if !ok {
+			return nil
+		}
+		return &size
+	case <-q.ctx.Done():
+		return nil
+	}
+}
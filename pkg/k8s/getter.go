@@ -0,0 +1,42 @@
+package k8s
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// RESTClientGetter adapts a Client to the getter interface used by kubectl-style
+// libraries such as the Helm SDK, sharing its REST config and discovery cache
+type RESTClientGetter struct {
+	client    *Client
+	namespace string
+}
+
+// RESTClientGetter returns a getter bound to namespace
+func (c *Client) RESTClientGetter(namespace string) *RESTClientGetter {
+	return &RESTClientGetter{client: c, namespace: namespace}
+}
+
+// ToRESTConfig returns a copy of the client's REST config
+func (g *RESTClientGetter) ToRESTConfig() (*rest.Config, error) {
+	return g.client.RestConfig(), nil
+}
+
+// ToDiscoveryClient returns the client's cached discovery client
+func (g *RESTClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	return g.client.discovery, nil
+}
+
+// ToRESTMapper returns the client's discovery-backed REST mapper
+func (g *RESTClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	return g.client.mapper, nil
+}
+
+// ToRawKubeConfigLoader returns a client config that only carries the namespace
+func (g *RESTClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	overrides := &clientcmd.ConfigOverrides{Context: clientcmdapi.Context{Namespace: g.namespace}}
+	return clientcmd.NewDefaultClientConfig(*clientcmdapi.NewConfig(), overrides)
+}
@@ -0,0 +1,104 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxLogLineSize bounds a single log line read from the API server
+const maxLogLineSize = 1024 * 1024
+
+// LogOptions configures a pod log stream
+type LogOptions struct {
+	Container    string
+	Follow       bool
+	Previous     bool  // Logs of the previous, terminated container instance
+	TailLines    int64 // 0 returns all lines
+	SinceSeconds int64 // 0 returns all lines
+}
+
+// LogLine is a single line of container logs
+type LogLine struct {
+	Pod       string
+	Container string
+	Timestamp time.Time
+	Message   string
+}
+
+// StreamPodLogs reads logs of a pod container line by line and passes them to handle.
+// It blocks until the stream ends, ctx is done or handle returns false.
+func (c *Client) StreamPodLogs(ctx context.Context, namespace, pod string, opts LogOptions, handle func(LogLine) bool) error {
+	podLogOpts := &corev1.PodLogOptions{
+		Container:  opts.Container,
+		Follow:     opts.Follow,
+		Previous:   opts.Previous,
+		Timestamps: true,
+	}
+	if opts.TailLines > 0 {
+		podLogOpts.TailLines = &opts.TailLines
+	}
+	if opts.SinceSeconds > 0 {
+		podLogOpts.SinceSeconds = &opts.SinceSeconds
+	}
+
+	stream, err := c.clientset.CoreV1().Pods(namespace).GetLogs(pod, podLogOpts).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open logs of %s/%s: %w", pod, opts.Container, err)
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLineSize)
+	for scanner.Scan() {
+		line := LogLine{Pod: pod, Container: opts.Container, Message: scanner.Text()}
+
+		// Timestamps are prefixed as "2006-01-02T15:04:05.999999999Z message"
+		if ts, msg, ok := strings.Cut(line.Message, " "); ok {
+			if parsed, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				line.Timestamp = parsed
+				line.Message = msg
+			}
+		}
+
+		if !handle(line) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// ListPodContainers returns the names of a pod's containers, or of the pods matching
+// labelSelector when pod is empty, keyed by pod name
+func (c *Client) ListPodContainers(ctx context.Context, namespace, pod, labelSelector string) (map[string][]string, error) {
+	var pods []corev1.Pod
+	if pod != "" {
+		p, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, pod, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, *p)
+	} else {
+		list, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+		if err != nil {
+			return nil, err
+		}
+		pods = list.Items
+	}
+
+	result := make(map[string][]string, len(pods))
+	for _, p := range pods {
+		for _, container := range p.Spec.Containers {
+			result[p.Name] = append(result[p.Name], container.Name)
+		}
+	}
+	return result, nil
+}
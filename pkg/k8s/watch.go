@@ -0,0 +1,61 @@
+package k8s
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ResolvedResource is a resource name resolved through discovery
+type ResolvedResource struct {
+	GVR        schema.GroupVersionResource
+	GVK        schema.GroupVersionKind
+	Namespaced bool
+}
+
+// ResolveResource resolves a user supplied resource name such as "pods", "pod", "deploy"
+// or "deployments.apps" to its preferred group/version through discovery
+func (c *Client) ResolveResource(resource string) (*ResolvedResource, error) {
+	resource = strings.ToLower(strings.TrimSpace(resource))
+	if resource == "" {
+		return nil, fmt.Errorf("resource name is required")
+	}
+
+	mapper := restmapper.NewShortcutExpander(c.mapper, c.discovery, nil)
+	partial := schema.ParseGroupResource(resource).WithVersion("")
+
+	gvr, err := mapper.ResourceFor(partial)
+	if err != nil && meta.IsNoMatchError(err) {
+		c.mapper.Reset()
+		gvr, err = mapper.ResourceFor(partial)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve resource %q: %w", resource, err)
+	}
+
+	gvk, err := mapper.KindFor(gvr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve kind for %q: %w", resource, err)
+	}
+
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve mapping for %q: %w", resource, err)
+	}
+
+	return &ResolvedResource{
+		GVR:        gvr,
+		GVK:        gvk,
+		Namespaced: mapping.Scope.Name() == meta.RESTScopeNameNamespace,
+	}, nil
+}
+
+// NewInformer creates an unstarted informer for a resource; an empty namespace watches all namespaces
+func (c *Client) NewInformer(gvr schema.GroupVersionResource, namespace string) cache.SharedIndexInformer {
+	return dynamicinformer.NewFilteredDynamicInformer(c.dynamic, gvr, namespace, 0, cache.Indexers{}, nil).Informer()
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
+	"os"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -15,6 +18,7 @@ type Client struct {
 	host   string
 	port   int
 	client *ssh.Client
+	mu     sync.Mutex // Guards client, which Dial and ExecuteCommand connect lazily
 }
 
 // Config represents SSH connection configuration
@@ -77,6 +81,16 @@ func NewClient(cfg Config) (*Client, error) {
 
 // Connect establishes an SSH connection
 func (c *Client) Connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client != nil {
+		return nil
+	}
+	return c.connectLocked()
+}
+
+func (c *Client) connectLocked() error {
 	addr := fmt.Sprintf("%s:%d", c.host, c.port)
 	client, err := ssh.Dial("tcp", addr, c.config)
 	if err != nil {
@@ -86,23 +100,49 @@ func (c *Client) Connect() error {
 	return nil
 }
 
+// conn returns the SSH connection, establishing it on first use
+func (c *Client) conn() (*ssh.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client == nil {
+		if err := c.connectLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return c.client, nil
+}
+
 // Close closes the SSH connection
 func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.client != nil {
-		return c.client.Close()
+		err := c.client.Close()
+		c.client = nil
+		return err
 	}
 	return nil
 }
 
+// Dial opens a connection from the remote server, e.g. to a unix socket on it
+func (c *Client) Dial(network, addr string) (net.Conn, error) {
+	client, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+	return client.Dial(network, addr)
+}
+
 // ExecuteCommand executes a command on the remote server
 func (c *Client) ExecuteCommand(ctx context.Context, command string) (*CommandResult, error) {
-	if c.client == nil {
-		if err := c.Connect(); err != nil {
-			return nil, err
-		}
+	client, err := c.conn()
+	if err != nil {
+		return nil, err
 	}
 
-	session, err := c.client.NewSession()
+	session, err := client.NewSession()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
@@ -191,9 +231,11 @@ func (c *Client) FileExists(ctx context.Context, path string) (bool, error) {
 	return result.Stdout == "exists\n", nil
 }
 
-// loadPrivateKey loads a private key from file
+// loadPrivateKey loads an unencrypted private key from file
 func loadPrivateKey(path string) (ssh.Signer, error) {
-	// This is a placeholder - in production, implement proper key loading
-	// with support for encrypted keys
-	return nil, fmt.Errorf("key loading not implemented yet")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(data)
 }
@@ -167,7 +167,7 @@ func (t *Tunnel) handleConnection(localConn net.Conn) {
 	t.mu.Unlock()
 
 	// Dial remote address through SSH
-	remoteConn, err := t.client.client.Dial("tcp", t.remoteAddr)
+	remoteConn, err := t.client.Dial("tcp", t.remoteAddr)
 	if err != nil {
 		return
 	}